@@ -0,0 +1,197 @@
+// Package bench keeps baselines of the client's benchmark suite —
+// hashing, chunking, serialization and RPC round trips, the paths whose
+// speed bulk uploads and busy agents depend on — and compares later runs
+// against them. The benchmarks are the Benchmark functions in
+// bench_test.go, run with `go test -bench`; Parse reads their output into
+// a Report, the form baselines are saved in.
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultThreshold is the slowdown Compare reports as a regression
+const DefaultThreshold = 0.10
+
+// Result is one benchmark's measurement
+type Result struct {
+	Name        string  `json:"name"`
+	N           int     `json:"n"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	MBPerSec    float64 `json:"mb_per_sec,omitempty"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+	// Failed is set when the benchmark stopped with an error
+	Failed bool `json:"failed,omitempty"`
+}
+
+// Report is a run of the suite, the form baselines are saved in
+type Report struct {
+	GOOS   string `json:"goos"`
+	GOARCH string `json:"goarch"`
+	// CPU is the processor model go test reports
+	CPU string `json:"cpu,omitempty"`
+	// CPUs is the GOMAXPROCS the benchmarks ran with
+	CPUs    int       `json:"cpus"`
+	Time    time.Time `json:"time"`
+	Results []Result  `json:"results"`
+}
+
+// benchLine matches a result line of `go test -bench` output: the name
+// with its GOMAXPROCS suffix, the iterations and the measurements
+var benchLine = regexp.MustCompile(`^Benchmark(\S+?)(?:-(\d+))?\s+(\d+)\s+(.*)$`)
+
+// failLine matches the line go test prints for a benchmark that failed
+var failLine = regexp.MustCompile(`^\s*--- FAIL: Benchmark(\S+?)(?:-\d+)?(?:\s|$)`)
+
+// Parse reads the output of `go test -bench`, with -benchmem or
+// b.ReportAllocs for the allocation counts, into a Report. Benchmarks run
+// more than once, with -count, are averaged; those that failed are
+// reported with Failed set. Lines that are not benchmark results, such
+// as test logs, are skipped.
+func Parse(r io.Reader) (Report, error) {
+	report := Report{Time: time.Now().UTC(), CPUs: 1}
+	runs := make(map[string]int)
+	index := make(map[string]int)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if k, v, ok := strings.Cut(line, ": "); ok && !strings.HasPrefix(k, " ") {
+			switch k {
+			case "goos":
+				report.GOOS = v
+				continue
+			case "goarch":
+				report.GOARCH = v
+				continue
+			case "cpu":
+				report.CPU = v
+				continue
+			}
+		}
+		if m := failLine.FindStringSubmatch(line); m != nil {
+			name := strings.TrimSuffix(m[1], ":")
+			if i, ok := index[name]; ok {
+				report.Results[i].Failed = true
+			} else {
+				index[name] = len(report.Results)
+				report.Results = append(report.Results, Result{Name: name, Failed: true})
+			}
+			continue
+		}
+		m := benchLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		res, err := parseResult(m[1], m[3], m[4])
+		if err != nil {
+			return Report{}, err
+		}
+		if m[2] != "" {
+			report.CPUs, _ = strconv.Atoi(m[2])
+		}
+		i, ok := index[res.Name]
+		if !ok {
+			index[res.Name] = len(report.Results)
+			report.Results = append(report.Results, res)
+			runs[res.Name] = 1
+			continue
+		}
+		// Average the runs of a benchmark run with -count
+		prev := &report.Results[i]
+		n := float64(runs[res.Name])
+		prev.N = res.N
+		prev.NsPerOp = (prev.NsPerOp*n + res.NsPerOp) / (n + 1)
+		prev.MBPerSec = (prev.MBPerSec*n + res.MBPerSec) / (n + 1)
+		prev.BytesPerOp = max(prev.BytesPerOp, res.BytesPerOp)
+		prev.AllocsPerOp = max(prev.AllocsPerOp, res.AllocsPerOp)
+		runs[res.Name]++
+	}
+	if err := sc.Err(); err != nil {
+		return Report{}, fmt.Errorf("bench: %w", err)
+	}
+	if len(report.Results) == 0 {
+		return Report{}, fmt.Errorf("bench: no benchmark results in the input")
+	}
+	return report, nil
+}
+
+// parseResult parses the iterations and "value unit" measurements of a
+// result line
+func parseResult(name, n, measurements string) (Result, error) {
+	res := Result{Name: name}
+	var err error
+	if res.N, err = strconv.Atoi(n); err != nil {
+		return Result{}, fmt.Errorf("bench: %s: %w", name, err)
+	}
+	fields := strings.Fields(measurements)
+	for i := 0; i+1 < len(fields); i += 2 {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return Result{}, fmt.Errorf("bench: %s: %w", name, err)
+		}
+		switch fields[i+1] {
+		case "ns/op":
+			res.NsPerOp = v
+		case "MB/s":
+			res.MBPerSec = v
+		case "B/op":
+			res.BytesPerOp = int64(v)
+		case "allocs/op":
+			res.AllocsPerOp = int64(v)
+		}
+	}
+	return res, nil
+}
+
+// Change compares a benchmark's results in two reports
+type Change struct {
+	Name string `json:"name"`
+	// Old and New are the time per operation, in nanoseconds; Old is 0
+	// for a benchmark the baseline lacks
+	Old float64 `json:"old_ns_per_op"`
+	New float64 `json:"new_ns_per_op"`
+	// Delta is the relative change in time per operation, e.g. 0.25 for
+	// 25% slower
+	Delta     float64 `json:"delta"`
+	OldAllocs int64   `json:"old_allocs_per_op"`
+	NewAllocs int64   `json:"new_allocs_per_op"`
+	// Regressed is set when the benchmark got slower by more than the
+	// threshold, allocates more per operation, or failed
+	Regressed bool `json:"regressed"`
+	Failed    bool `json:"failed,omitempty"`
+}
+
+// Compare matches the benchmarks of current against a baseline, flagging
+// those more than threshold slower (DefaultThreshold if not positive),
+// allocating more, or failing. Benchmarks only in the baseline are left
+// out; the changes are sorted by name.
+func Compare(baseline, current Report, threshold float64) []Change {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	old := make(map[string]Result, len(baseline.Results))
+	for _, r := range baseline.Results {
+		old[r.Name] = r
+	}
+	changes := make([]Change, 0, len(current.Results))
+	for _, r := range current.Results {
+		c := Change{Name: r.Name, New: r.NsPerOp, NewAllocs: r.AllocsPerOp, Failed: r.Failed, Regressed: r.Failed}
+		if o, ok := old[r.Name]; ok && o.NsPerOp > 0 && !r.Failed {
+			c.Old, c.OldAllocs = o.NsPerOp, o.AllocsPerOp
+			c.Delta = r.NsPerOp/o.NsPerOp - 1
+			c.Regressed = c.Delta > threshold || r.AllocsPerOp > o.AllocsPerOp
+		}
+		changes = append(changes, c)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
@@ -0,0 +1,212 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"example.com/ipfs_kit_py/chunker"
+	"example.com/ipfs_kit_py/cidutil"
+	"example.com/ipfs_kit_py/erasure"
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// randomBytes returns n bytes that are the same on every run
+func randomBytes(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(1)).Read(data)
+	return data
+}
+
+func BenchmarkHash(b *testing.B) {
+	for _, h := range []struct {
+		name string
+		code uint64
+	}{{"sha2-256", cidutil.SHA2_256}, {"blake3", cidutil.BLAKE3}} {
+		b.Run(h.name+"/1MiB", func(b *testing.B) {
+			data := randomBytes(1 << 20)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := cidutil.Sum(h.code, data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseCID(b *testing.B) {
+	mh, err := cidutil.Sum(cidutil.SHA2_256, []byte("bench"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	s := cidutil.NewCIDv1(cidutil.Raw, mh).String()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cidutil.ParseCID(s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkChunk(b *testing.B) {
+	for _, spec := range []string{"size-262144", "rabin"} {
+		b.Run(spec+"/16MiB", func(b *testing.B) {
+			data := randomBytes(16 << 20)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s, err := chunker.FromString(bytes.NewReader(data), spec)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := chunker.Split(s, func(int64, []byte) error { return nil }); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// benchMetadata is metadata of the size uploads typically carry
+var benchMetadata = map[string]interface{}{
+	"bucket":      "datasets",
+	"extension":   ".parquet",
+	"mtime":       "2026-01-02T03:04:05Z",
+	"size_bucket": "large",
+	"tier":        "hot",
+	"owner":       "agent-7",
+}
+
+func BenchmarkSerialize(b *testing.B) {
+	b.Run("metadata-struct", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := structpb.NewStruct(benchMetadata); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("select-request", func(b *testing.B) {
+		metadata, err := structpb.NewStruct(benchMetadata)
+		if err != nil {
+			b.Fatal(err)
+		}
+		req := &pb.SelectBackendRequest{
+			ContentType:  "application/vnd.apache.parquet",
+			ContentSize:  3 << 30,
+			ContentHash:  fmt.Sprintf("%064x", 1),
+			Metadata:     metadata,
+			Strategy:     "hybrid",
+			RequestId:    "go-client-bench",
+			Timestamp:    timestamppb.Now(),
+			StrategyType: routingclient.StrategyHybrid,
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			data, err := proto.Marshal(req)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := proto.Unmarshal(data, &pb.SelectBackendRequest{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("stripe-manifest", func(b *testing.B) {
+		m := erasure.Manifest{
+			Version:      erasure.ManifestVersion,
+			Scheme:       erasure.Scheme,
+			DataShards:   4,
+			ParityShards: 2,
+			ShardSize:    16 << 20,
+			Size:         64 << 20,
+			SHA256:       fmt.Sprintf("%064x", 1),
+		}
+		for i := 0; i < 6; i++ {
+			m.Shards = append(m.Shards, erasure.Shard{
+				Index:     i,
+				BackendID: fmt.Sprintf("s3-region-%d", i),
+				Location:  fmt.Sprintf("s3://bucket/stripes/%064x/%d", 1, i),
+				SHA256:    fmt.Sprintf("%064x", i),
+			})
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			data, err := json.Marshal(&m)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var out erasure.Manifest
+			if err := json.Unmarshal(data, &out); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// fakeRouter serves the HTTP routing API's select and record endpoints
+// with fixed answers, so RPC benchmarks measure the client and loopback
+// transport rather than a router's decisions
+func fakeRouter() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/select-backend", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"backend":"ipfs","confidence":0.9,"reasoning":"bench","timestamp":%q}`, time.Now().UTC().Format(time.RFC3339))
+	})
+	mux.HandleFunc("/api/v1/record-outcome", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"success":true,"message":"recorded","timestamp":%q}`, time.Now().UTC().Format(time.RFC3339))
+	})
+	return httptest.NewServer(mux)
+}
+
+// benchContent is the content RPC benchmarks route
+var benchContent = routingclient.ContentInfo{
+	ContentType: "application/vnd.apache.parquet",
+	ContentSize: 3 << 30,
+	ContentHash: fmt.Sprintf("%064x", 1),
+	Filename:    "part-0000.parquet",
+	Metadata:    map[string]string{"bucket": "datasets", "size_bucket": "very_large"},
+}
+
+func BenchmarkRPC(b *testing.B) {
+	srv := fakeRouter()
+	defer srv.Close()
+	client := routingclient.NewClient(routingclient.NewRESTConn(srv.URL, srv.Client()))
+	ctx := context.Background()
+	b.Run("select-backend", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := client.SelectBackend(ctx, benchContent, "hybrid"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("record-outcome", func(b *testing.B) {
+		outcome := routingclient.Outcome{BackendID: "ipfs", Success: true, Duration: 1200 * time.Millisecond, Bytes: 3 << 30}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := client.RecordOutcome(ctx, benchContent, outcome); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
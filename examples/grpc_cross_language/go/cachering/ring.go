@@ -0,0 +1,133 @@
+// Package cachering routes IPFS reads across a fleet of leecher cache nodes.
+//
+// CIDs are mapped onto the fleet with a consistent hash ring, so each CID has
+// a stable owning node and adding or removing a node only moves the CIDs on
+// its arcs. Reads go to the owner first, then its successors, and finally to
+// the origin gateway, which keeps each hot object cached on a single node
+// while read throughput scales with the size of the fleet.
+package cachering
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultReplicas is the number of virtual points each node places on the ring
+const DefaultReplicas = 128
+
+// Ring is a consistent hash ring of cache node addresses
+type Ring struct {
+	mu       sync.RWMutex
+	replicas int
+	points   []uint64
+	owners   map[uint64]string
+	nodes    map[string]bool
+}
+
+// NewRing creates a ring with the given number of virtual points per node
+// (DefaultReplicas if <= 0) and adds nodes to it
+func NewRing(replicas int, nodes ...string) *Ring {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	r := &Ring{
+		replicas: replicas,
+		owners:   make(map[uint64]string),
+		nodes:    make(map[string]bool),
+	}
+	for _, n := range nodes {
+		r.Add(n)
+	}
+	return r
+}
+
+func hashKey(key string) uint64 {
+	sum := sha256.Sum256([]byte(key))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// Add places node on the ring. Adding an existing node is a no-op.
+func (r *Ring) Add(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.nodes[node] {
+		return
+	}
+	r.nodes[node] = true
+	for i := 0; i < r.replicas; i++ {
+		h := hashKey(node + "#" + strconv.Itoa(i))
+		if _, taken := r.owners[h]; taken {
+			continue
+		}
+		r.owners[h] = node
+		r.points = append(r.points, h)
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+}
+
+// Remove takes node off the ring; its CIDs move to the next node clockwise
+func (r *Ring) Remove(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.nodes[node] {
+		return
+	}
+	delete(r.nodes, node)
+	kept := r.points[:0]
+	for _, h := range r.points {
+		if r.owners[h] == node {
+			delete(r.owners, h)
+			continue
+		}
+		kept = append(kept, h)
+	}
+	r.points = kept
+}
+
+// Nodes returns the nodes currently on the ring, sorted
+func (r *Ring) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.nodes))
+	for n := range r.nodes {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Owner returns the node owning cid, or "" if the ring is empty
+func (r *Ring) Owner(cid string) string {
+	if owners := r.Owners(cid, 1); len(owners) > 0 {
+		return owners[0]
+	}
+	return ""
+}
+
+// Owners returns up to n distinct nodes for cid in preference order: the
+// owner followed by its successors clockwise around the ring
+func (r *Ring) Owners(cid string, n int) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.points) == 0 || n <= 0 {
+		return nil
+	}
+	if n > len(r.nodes) {
+		n = len(r.nodes)
+	}
+	h := hashKey(cid)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	out := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for j := 0; len(out) < n && j < len(r.points); j++ {
+		node := r.owners[r.points[(i+j)%len(r.points)]]
+		if !seen[node] {
+			seen[node] = true
+			out = append(out, node)
+		}
+	}
+	return out
+}
@@ -0,0 +1,250 @@
+package cachering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// Read-ahead window bounds for streams
+const (
+	MinReadAhead = 256 * 1024
+	MaxReadAhead = 8 * 1024 * 1024
+)
+
+// StreamStats are the buffer metrics of a Stream
+type StreamStats struct {
+	// Fetches is the number of range requests made, including read-ahead
+	Fetches      int   `json:"fetches"`
+	BytesFetched int64 `json:"bytes_fetched"`
+	BytesRead    int64 `json:"bytes_read"`
+	// BufferHits counts reads served entirely from buffered data;
+	// PrefetchHits counts windows that were already fetched ahead when
+	// playback reached them
+	BufferHits   int `json:"buffer_hits"`
+	PrefetchHits int `json:"prefetch_hits"`
+	// PrefetchWasted counts read-ahead windows discarded after a seek
+	PrefetchWasted int `json:"prefetch_wasted"`
+	Seeks          int `json:"seeks"`
+	// ReadAhead is the current window size in bytes
+	ReadAhead int `json:"read_ahead"`
+}
+
+// Stream is a seekable reader over a CID, for media players and other
+// sequential readers. It fetches the content in ranges through the cache
+// fleet and reads the next window ahead in the background. The window
+// doubles while reads stay sequential, up to MaxReadAhead, and falls back
+// to MinReadAhead after a seek. A Stream is not safe for concurrent use.
+type Stream struct {
+	f      *Fetcher
+	cid    string
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	size   int64
+	pos    int64
+	buf    []byte
+	bufOff int64
+	next   *window
+	window int
+	stats  StreamStats
+}
+
+// window is a range fetched in the background
+type window struct {
+	off  int64
+	done chan struct{}
+	data []byte
+	err  error
+}
+
+// OpenStream opens cid, or an /ipns/ path or dnslink: reference as for
+// Get, for streaming. The
+// first window is fetched before returning, which also determines the
+// content size.
+func (f *Fetcher) OpenStream(ctx context.Context, cid string) (*Stream, error) {
+	cid, err := f.resolve(ctx, cid)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Stream{f: f, cid: cid, ctx: ctx, cancel: cancel, window: MinReadAhead}
+
+	if codec, data, ok := cidutil.InlineData(cid); ok && codec == cidutil.Raw {
+		s.buf, s.size = data, int64(len(data))
+		return s, nil
+	}
+	data, size, err := s.fetch(0, s.window)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	s.buf, s.size = data, size
+	s.prefetch()
+	return s, nil
+}
+
+// Size returns the length of the content in bytes
+func (s *Stream) Size() int64 {
+	return s.size
+}
+
+// Stats returns the stream's buffer metrics
+func (s *Stream) Stats() StreamStats {
+	st := s.stats
+	st.ReadAhead = s.window
+	return st
+}
+
+// Read implements io.Reader
+func (s *Stream) Read(p []byte) (int, error) {
+	if s.pos >= s.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if s.pos >= s.bufOff && s.pos < s.bufOff+int64(len(s.buf)) {
+		s.stats.BufferHits++
+	} else if err := s.load(); err != nil {
+		return 0, err
+	}
+	n := copy(p, s.buf[s.pos-s.bufOff:])
+	s.pos += int64(n)
+	s.stats.BytesRead += int64(n)
+	return n, nil
+}
+
+// load fills the buffer with the window starting at pos, from the
+// read-ahead if it covers pos
+func (s *Stream) load() error {
+	sequential := s.pos == s.bufOff+int64(len(s.buf))
+	if next := s.next; next != nil && next.off == s.pos {
+		s.next = nil
+		select {
+		case <-next.done:
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		}
+		if next.err == nil {
+			s.stats.PrefetchHits++
+			s.stats.BytesFetched += int64(len(next.data))
+			s.buf, s.bufOff = next.data, next.off
+			s.grow(sequential)
+			s.prefetch()
+			return nil
+		}
+	}
+	s.discardPrefetch()
+	data, _, err := s.fetch(s.pos, s.window)
+	if err != nil {
+		return err
+	}
+	s.buf, s.bufOff = data, s.pos
+	s.grow(sequential)
+	s.prefetch()
+	return nil
+}
+
+// grow doubles the window after sequential reads and resets it otherwise
+func (s *Stream) grow(sequential bool) {
+	if !sequential {
+		s.window = MinReadAhead
+		return
+	}
+	s.window = min(s.window*2, MaxReadAhead)
+}
+
+// prefetch starts fetching the window after the buffer
+func (s *Stream) prefetch() {
+	off := s.bufOff + int64(len(s.buf))
+	if off >= s.size || s.next != nil {
+		return
+	}
+	w := &window{off: off, done: make(chan struct{})}
+	s.next = w
+	s.stats.Fetches++
+	length := s.window
+	go func() {
+		defer close(w.done)
+		w.data, _, w.err = s.get(off, length)
+	}()
+}
+
+func (s *Stream) discardPrefetch() {
+	if s.next != nil {
+		s.stats.PrefetchWasted++
+		s.next = nil
+	}
+}
+
+// fetch reads length bytes at off in the foreground
+func (s *Stream) fetch(off int64, length int) ([]byte, int64, error) {
+	s.stats.Fetches++
+	data, size, err := s.get(off, length)
+	if err == nil {
+		s.stats.BytesFetched += int64(len(data))
+	}
+	return data, size, err
+}
+
+// get requests a range and returns its data and the total content size
+func (s *Stream) get(off int64, length int) ([]byte, int64, error) {
+	rng := fmt.Sprintf("bytes=%d-%d", off, off+int64(length)-1)
+	resp, err := s.f.fetch(s.ctx, s.cid, rng)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	size, err := totalSize(resp.Range)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cachering: %s: %w", resp.Node, err)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(length)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("cachering: %s: %w", resp.Node, err)
+	}
+	return data, size, nil
+}
+
+// totalSize parses the complete length from a Content-Range header
+func totalSize(contentRange string) (int64, error) {
+	_, total, ok := strings.Cut(contentRange, "/")
+	if !ok || total == "*" {
+		return 0, fmt.Errorf("missing content length in Content-Range %q", contentRange)
+	}
+	return strconv.ParseInt(total, 10, 64)
+}
+
+// Seek implements io.Seeker. Seeking outside the buffered data resets the
+// read-ahead window.
+func (s *Stream) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.pos
+	case io.SeekEnd:
+		offset += s.size
+	default:
+		return 0, errors.New("cachering: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("cachering: negative position")
+	}
+	if offset != s.pos {
+		s.stats.Seeks++
+	}
+	s.pos = offset
+	return offset, nil
+}
+
+// Close cancels any read-ahead in flight
+func (s *Stream) Close() error {
+	s.cancel()
+	return nil
+}
@@ -0,0 +1,167 @@
+// Package car reads and writes Content Addressable aRchives. CARv2 indexes
+// are used to find blocks without scanning the archive, so tools can list
+// or extract part of a CAR much larger than memory.
+package car
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// maxHeaderSize bounds CARv1 headers, which only list roots
+const maxHeaderSize = 1 << 20
+
+// ErrNotFound is returned for blocks a CAR does not contain
+var ErrNotFound = errors.New("car: block not found")
+
+// Header is a CARv1 header
+type Header struct {
+	Version uint64
+	Roots   []cidutil.CID
+}
+
+// ReadHeader reads a CARv1 header, leaving r at the first block. A CARv2
+// pragma reads as a header with Version 2 and no roots.
+func ReadHeader(r *bufio.Reader) (Header, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Header{}, fmt.Errorf("car: read header length: %w", err)
+	}
+	if n == 0 || n > maxHeaderSize {
+		return Header{}, fmt.Errorf("car: invalid header length %d", n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return Header{}, fmt.Errorf("car: read header: %w", err)
+	}
+
+	v, _, err := decodeCBOR(buf)
+	if err != nil {
+		return Header{}, fmt.Errorf("car: decode header: %w", err)
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return Header{}, errors.New("car: header is not a map")
+	}
+	h := Header{}
+	h.Version, _ = m["version"].(uint64)
+	if h.Version != 1 && h.Version != 2 {
+		return Header{}, fmt.Errorf("car: unsupported version %v", m["version"])
+	}
+	list, _ := m["roots"].([]interface{})
+	for _, item := range list {
+		c, ok := item.(cidutil.CID)
+		if !ok {
+			return Header{}, errors.New("car: root is not a CID")
+		}
+		h.Roots = append(h.Roots, c)
+	}
+	return h, nil
+}
+
+// WriteHeader writes a CARv1 header with the given roots
+func WriteHeader(w io.Writer, roots ...cidutil.CID) error {
+	hdr := []byte{0xa2, 0x65} // map(2), text(5)
+	hdr = append(hdr, "roots"...)
+	hdr = appendCBORHead(hdr, 4, len(roots))
+	for _, root := range roots {
+		link := append([]byte{0x00}, root.Bytes()...) // CIDs in DAG-CBOR carry a 0x00 prefix
+		hdr = append(hdr, 0xd8, 0x2a)                 // tag(42)
+		hdr = appendCBORHead(hdr, 2, len(link))
+		hdr = append(hdr, link...)
+	}
+	hdr = append(hdr, 0x67) // text(7)
+	hdr = append(hdr, "version"...)
+	hdr = append(hdr, 0x01) // uint(1)
+
+	if _, err := w.Write(binary.AppendUvarint(nil, uint64(len(hdr)))); err != nil {
+		return err
+	}
+	_, err := w.Write(hdr)
+	return err
+}
+
+// WriteBlock writes one CARv1 section: length, CID and data
+func WriteBlock(w io.Writer, c cidutil.CID, data []byte) error {
+	key := c.Bytes()
+	if _, err := w.Write(binary.AppendUvarint(nil, uint64(len(key)+len(data)))); err != nil {
+		return err
+	}
+	if _, err := w.Write(key); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// BlockInfo locates a block in a CAR's data payload
+type BlockInfo struct {
+	CID cidutil.CID
+	// Offset is where the block's section starts, relative to the CARv1
+	// payload, as CARv2 indexes record it
+	Offset int64
+	// DataOffset and Size locate the block's bytes, also payload-relative
+	DataOffset int64
+	Size       int64
+}
+
+// readSection reads the head of the section at off in the payload: its
+// CID and where its data lies
+func readSection(payload io.ReaderAt, off int64) (BlockInfo, error) {
+	// Section heads are a varint and a CID; identity CIDs can be longer
+	// than one buffer, which bufio refills transparently
+	r := bufio.NewReaderSize(io.NewSectionReader(payload, off, 1<<62), 256)
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return BlockInfo{}, err
+	}
+	if n == 0 {
+		// Zero padding after the last section
+		return BlockInfo{}, io.EOF
+	}
+	head := int64(len(binary.AppendUvarint(nil, n)))
+	c, err := readCID(r)
+	if err != nil {
+		return BlockInfo{}, fmt.Errorf("car: section at %d: %w", off, err)
+	}
+	keyLen := int64(len(c.Bytes()))
+	if uint64(keyLen) > n {
+		return BlockInfo{}, fmt.Errorf("car: section at %d shorter than its CID", off)
+	}
+	return BlockInfo{CID: c, Offset: off, DataOffset: off + head + keyLen, Size: int64(n) - keyLen}, nil
+}
+
+// readCID reads a binary CID from the front of r
+func readCID(r *bufio.Reader) (cidutil.CID, error) {
+	if b, err := r.Peek(2); err == nil && b[0] == cidutil.SHA2_256 && b[1] == 32 {
+		v0 := make([]byte, 34)
+		if _, err := io.ReadFull(r, v0); err != nil {
+			return cidutil.CID{}, err
+		}
+		return cidutil.CastCID(v0)
+	}
+	var buf []byte
+	for i := 0; i < 4; i++ { // version, codec, hash code, digest length
+		v, err := binary.ReadUvarint(r)
+		if err != nil {
+			return cidutil.CID{}, err
+		}
+		buf = binary.AppendUvarint(buf, v)
+		if i == 3 {
+			if v > maxHeaderSize {
+				return cidutil.CID{}, fmt.Errorf("digest length %d", v)
+			}
+			d := make([]byte, v)
+			if _, err := io.ReadFull(r, d); err != nil {
+				return cidutil.CID{}, err
+			}
+			buf = append(buf, d...)
+		}
+	}
+	return cidutil.CastCID(buf)
+}
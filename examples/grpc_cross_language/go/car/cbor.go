@@ -0,0 +1,186 @@
+package car
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// appendCBORHead appends a CBOR major type and argument
+func appendCBORHead(buf []byte, major byte, n int) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(buf, m|byte(n))
+	case n <= 0xff:
+		return append(buf, m|24, byte(n))
+	case n <= 0xffff:
+		return append(buf, m|25, byte(n>>8), byte(n))
+	default:
+		return append(buf, m|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+}
+
+// maxCBORDepth bounds how deeply arrays, maps and tags may nest, so a
+// hostile block cannot exhaust the stack
+const maxCBORDepth = 256
+
+// decodeCBOR decodes DAG-CBOR: integers, byte and text strings, arrays,
+// maps with string keys, tag 42 links (as cidutil.CID), floats, booleans
+// and null. It returns the value and the number of bytes consumed.
+func decodeCBOR(data []byte) (interface{}, int, error) {
+	return decodeCBORDepth(data, 0)
+}
+
+// decodeCBORDepth decodes a value nested depth levels deep
+func decodeCBORDepth(data []byte, depth int) (interface{}, int, error) {
+	if depth > maxCBORDepth {
+		return nil, 0, errors.New("CBOR nested too deeply")
+	}
+	if len(data) == 0 {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	if major == 7 {
+		return decodeCBORSimple(data, info)
+	}
+	arg, n, err := cborArg(data, info)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch major {
+	case 0:
+		return arg, n, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, 0, errors.New("CBOR negative integer out of range")
+		}
+		return -1 - int64(arg), n, nil
+	case 2, 3:
+		end := n + int(arg)
+		if arg > uint64(len(data)) || end > len(data) {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		if major == 2 {
+			return data[n:end], end, nil
+		}
+		return string(data[n:end]), end, nil
+	case 4:
+		list := make([]interface{}, 0, int(min(arg, 64)))
+		for i := uint64(0); i < arg; i++ {
+			v, m, err := decodeCBORDepth(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			list = append(list, v)
+			n += m
+		}
+		return list, n, nil
+	case 5:
+		m := make(map[string]interface{}, int(min(arg, 64)))
+		for i := uint64(0); i < arg; i++ {
+			k, kn, err := decodeCBORDepth(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errors.New("non-string map key")
+			}
+			n += kn
+			v, vn, err := decodeCBORDepth(data[n:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key] = v
+			n += vn
+		}
+		return m, n, nil
+	default: // 6
+		v, m, err := decodeCBORDepth(data[n:], depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		b, ok := v.([]byte)
+		if arg != 42 || !ok || len(b) == 0 || b[0] != 0x00 {
+			return nil, 0, fmt.Errorf("unsupported CBOR tag %d", arg)
+		}
+		c, err := cidutil.CastCID(b[1:])
+		if err != nil {
+			return nil, 0, err
+		}
+		return c, n + m, nil
+	}
+}
+
+// decodeCBORSimple decodes major type 7: false, true, null and floats
+func decodeCBORSimple(data []byte, info byte) (interface{}, int, error) {
+	switch info {
+	case 20:
+		return false, 1, nil
+	case 21:
+		return true, 1, nil
+	case 22:
+		return nil, 1, nil
+	case 25, 26, 27:
+		size := 1 << (info - 24)
+		if len(data) < 1+size {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		b := data[1 : 1+size]
+		switch size {
+		case 2:
+			return halfFloat(binary.BigEndian.Uint16(b)), 3, nil
+		case 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), 5, nil
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), 9, nil
+	}
+	return nil, 0, fmt.Errorf("unsupported CBOR simple value %d", info)
+}
+
+// halfFloat converts an IEEE 754 half-precision float
+func halfFloat(h uint16) float64 {
+	exp, frac := int(h>>10&0x1f), float64(h&0x3ff)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(frac, -24)
+	case 31:
+		v = math.Inf(1)
+		if frac != 0 {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(frac+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		v = -v
+	}
+	return v
+}
+
+// cborArg decodes the argument following a CBOR initial byte
+func cborArg(data []byte, info byte) (uint64, int, error) {
+	switch {
+	case info < 24:
+		return uint64(info), 1, nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(data) < 1+size {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		var v uint64
+		for _, b := range data[1 : 1+size] {
+			v = v<<8 | uint64(b)
+		}
+		return v, 1 + size, nil
+	default:
+		return 0, 0, fmt.Errorf("unsupported CBOR additional info %d", info)
+	}
+}
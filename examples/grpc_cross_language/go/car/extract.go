@@ -0,0 +1,202 @@
+package car
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// ErrUnsupportedCodec is returned when walking a block whose codec's links
+// cannot be decoded
+var ErrUnsupportedCodec = errors.New("car: unsupported codec")
+
+// Links returns the CIDs a block links to, for raw, dag-pb, dag-cbor and
+// dag-json blocks
+func Links(c cidutil.CID, data []byte) ([]cidutil.CID, error) {
+	switch c.Codec {
+	case cidutil.Raw:
+		return nil, nil
+	case cidutil.DagPB:
+		return dagPBLinks(data)
+	case cidutil.DagCBOR:
+		v, _, err := decodeCBOR(data)
+		if err != nil {
+			return nil, err
+		}
+		return collectLinks(v, nil), nil
+	case cidutil.DagJSON:
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return dagJSONLinks(v, nil)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedCodec, cidutil.CodecName(c.Codec))
+}
+
+// Extract writes root and every block reachable from it to w as a CARv1
+// with root as its only root, in depth-first order. Blocks are read
+// through the index, so the rest of the archive is never touched.
+// Identity-hashed links carry their content and are not written.
+func (rd *Reader) Extract(w io.Writer, root cidutil.CID) error {
+	if err := WriteHeader(w, root); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	stack := []cidutil.CID{root}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[string(c.Bytes())] {
+			continue
+		}
+		seen[string(c.Bytes())] = true
+
+		data, err := rd.Block(c)
+		if err != nil {
+			return err
+		}
+		if c.Hash.Code() != cidutil.Identity {
+			if err := WriteBlock(w, c, data); err != nil {
+				return err
+			}
+		}
+		links, err := Links(c, data)
+		if err != nil {
+			return fmt.Errorf("car: links of %s: %w", c, err)
+		}
+		// Push in reverse so links are visited in order
+		for i := len(links) - 1; i >= 0; i-- {
+			stack = append(stack, links[i])
+		}
+	}
+	return nil
+}
+
+// dagPBLinks decodes the Hash of each PBLink in a PBNode: links are field 2
+// of the node, and a link's hash field 1
+func dagPBLinks(data []byte) ([]cidutil.CID, error) {
+	var links []cidutil.CID
+	err := protoFields(data, func(field uint64, value []byte) error {
+		if field != 2 {
+			return nil
+		}
+		return protoFields(value, func(field uint64, value []byte) error {
+			if field != 1 {
+				return nil
+			}
+			c, err := cidutil.CastCID(value)
+			if err != nil {
+				return err
+			}
+			links = append(links, c)
+			return nil
+		})
+	})
+	return links, err
+}
+
+// protoFields calls fn with each length-delimited field of a protobuf
+// message, skipping fields of other wire types
+func protoFields(data []byte, fn func(field uint64, value []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errors.New("invalid protobuf field key")
+		}
+		data = data[n:]
+		switch key & 7 {
+		case 0:
+			if _, n = binary.Uvarint(data); n <= 0 {
+				return errors.New("invalid protobuf varint")
+			}
+		case 1:
+			n = 8
+		case 5:
+			n = 4
+		case 2:
+			size, m := binary.Uvarint(data)
+			if m <= 0 || size > uint64(len(data)-m) {
+				return errors.New("invalid protobuf length")
+			}
+			if err := fn(key>>3, data[m:m+int(size)]); err != nil {
+				return err
+			}
+			n = m + int(size)
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", key&7)
+		}
+		if n > len(data) {
+			return io.ErrUnexpectedEOF
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// collectLinks appends the CIDs found in a decoded DAG-CBOR value
+func collectLinks(v interface{}, links []cidutil.CID) []cidutil.CID {
+	switch v := v.(type) {
+	case cidutil.CID:
+		links = append(links, v)
+	case []interface{}:
+		for _, item := range v {
+			links = collectLinks(item, links)
+		}
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v, true) {
+			links = collectLinks(v[k], links)
+		}
+	}
+	return links
+}
+
+// dagJSONLinks appends the CIDs found in a decoded DAG-JSON value, where a
+// link is a map whose only key is "/" holding a CID string
+func dagJSONLinks(v interface{}, links []cidutil.CID) ([]cidutil.CID, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			var err error
+			if links, err = dagJSONLinks(item, links); err != nil {
+				return nil, err
+			}
+		}
+	case map[string]interface{}:
+		if s, ok := v["/"].(string); ok && len(v) == 1 {
+			c, err := cidutil.ParseCID(s)
+			if err != nil {
+				return nil, err
+			}
+			return append(links, c), nil
+		}
+		for _, k := range sortedKeys(v, false) {
+			var err error
+			if links, err = dagJSONLinks(v[k], links); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return links, nil
+}
+
+// sortedKeys returns m's keys in canonical order: DAG-CBOR sorts shorter
+// keys first, DAG-JSON bytewise
+func sortedKeys(m map[string]interface{}, cbor bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if cbor && len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
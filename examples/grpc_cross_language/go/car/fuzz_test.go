@@ -0,0 +1,68 @@
+package car_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"example.com/ipfs_kit_py/car"
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// carOf returns a CARv1 holding blocks, rooted at the first
+func carOf(f *testing.F, blocks ...[]byte) []byte {
+	var buf bytes.Buffer
+	var cids []cidutil.CID
+	for i, data := range blocks {
+		codec := uint64(cidutil.Raw)
+		if i == 0 && len(blocks) > 1 {
+			codec = cidutil.DagCBOR
+		}
+		mh, err := cidutil.Sum(cidutil.SHA2_256, data)
+		if err != nil {
+			f.Fatal(err)
+		}
+		cids = append(cids, cidutil.NewCIDv1(codec, mh))
+	}
+	if err := car.WriteHeader(&buf, cids[0]); err != nil {
+		f.Fatal(err)
+	}
+	for i, data := range blocks {
+		if err := car.WriteBlock(&buf, cids[i], data); err != nil {
+			f.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// FuzzCAR reads its input as a CAR file, visiting every block and
+// extracting each root; no input may make the reader panic
+func FuzzCAR(f *testing.F) {
+	leaf := []byte("fuzz leaf")
+	mh, err := cidutil.Sum(cidutil.SHA2_256, leaf)
+	if err != nil {
+		f.Fatal(err)
+	}
+	// A DAG-CBOR node {"l": <leaf>}, linking to the leaf
+	link := append([]byte{0x00}, cidutil.NewCIDv1(cidutil.Raw, mh).Bytes()...)
+	node := append([]byte{0xa1, 0x61, 'l', 0xd8, 0x2a, 0x58, byte(len(link))}, link...)
+	f.Add(carOf(f, leaf))
+	f.Add(carOf(f, node, leaf))
+	f.Add(carOf(f, node))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		rd, err := car.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		rd.Index()
+		rd.Blocks(func(b car.BlockInfo) error {
+			rd.Block(b.CID)
+			return nil
+		})
+		for _, root := range rd.Roots {
+			rd.Extract(io.Discard, root)
+		}
+	})
+}
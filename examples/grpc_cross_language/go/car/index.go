@@ -0,0 +1,184 @@
+package car
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// CARv2 index formats, identified by multicodec
+const (
+	// IndexSorted keys blocks by digest alone
+	IndexSorted = 0x0400
+	// MultihashIndexSorted keys blocks by hash function and digest
+	MultihashIndexSorted = 0x0401
+)
+
+// maxIndexBucket bounds a single index bucket read into memory
+const maxIndexBucket = 1 << 34
+
+// Index maps block multihashes to the offsets of their sections in the
+// CARv1 payload. It is held as the CARv2 on-disk layout: per hash function
+// and digest width, records of digest and little-endian offset sorted by
+// digest, searched in place.
+type Index struct {
+	buckets []bucket
+}
+
+// bucket holds records of one width; code is -1 for IndexSorted, which
+// does not record hash functions
+type bucket struct {
+	code  int64
+	width int
+	data  []byte
+}
+
+type record struct {
+	code   uint64
+	digest []byte
+	offset uint64
+}
+
+func digest(mh cidutil.Multihash) []byte {
+	_, d, _ := cidutil.DecodeMultihash(mh)
+	return d
+}
+
+// newIndex builds a MultihashIndexSorted-style index from records
+func newIndex(records []record) *Index {
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.code != b.code {
+			return a.code < b.code
+		}
+		if len(a.digest) != len(b.digest) {
+			return len(a.digest) < len(b.digest)
+		}
+		return bytes.Compare(a.digest, b.digest) < 0
+	})
+	idx := &Index{}
+	for _, r := range records {
+		width := len(r.digest) + 8
+		if n := len(idx.buckets); n == 0 || idx.buckets[n-1].code != int64(r.code) || idx.buckets[n-1].width != width {
+			idx.buckets = append(idx.buckets, bucket{code: int64(r.code), width: width})
+		}
+		b := &idx.buckets[len(idx.buckets)-1]
+		b.data = append(b.data, r.digest...)
+		b.data = binary.LittleEndian.AppendUint64(b.data, r.offset)
+	}
+	return idx
+}
+
+// ReadIndex reads a CARv2 index: its multicodec, then an IndexSorted or
+// MultihashIndexSorted body
+func ReadIndex(r io.Reader) (*Index, error) {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		return nil, errors.New("car: ReadIndex needs an io.ByteReader")
+	}
+	codec, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("car: read index codec: %w", err)
+	}
+	idx := &Index{}
+	switch codec {
+	case IndexSorted:
+		err = idx.readWidths(r, -1)
+	case MultihashIndexSorted:
+		var codes int32
+		if err = binary.Read(r, binary.LittleEndian, &codes); err != nil {
+			break
+		}
+		for i := int32(0); i < codes && err == nil; i++ {
+			var code uint64
+			if err = binary.Read(r, binary.LittleEndian, &code); err == nil {
+				err = idx.readWidths(r, int64(code))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("car: unsupported index codec 0x%x", codec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("car: read index: %w", err)
+	}
+	return idx, nil
+}
+
+// readWidths reads one multi-width index: a count of buckets, each a
+// uint32 record width, a uint64 byte length and the records
+func (idx *Index) readWidths(r io.Reader, code int64) error {
+	var widths int32
+	if err := binary.Read(r, binary.LittleEndian, &widths); err != nil {
+		return err
+	}
+	for i := int32(0); i < widths; i++ {
+		var width uint32
+		var size uint64
+		if err := binary.Read(r, binary.LittleEndian, &width); err != nil {
+			return err
+		}
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return err
+		}
+		if width <= 8 || size%uint64(width) != 0 || size > maxIndexBucket {
+			return fmt.Errorf("invalid bucket: width %d, %d bytes", width, size)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+		idx.buckets = append(idx.buckets, bucket{code: code, width: int(width), data: data})
+	}
+	return nil
+}
+
+// Len returns the number of indexed blocks
+func (idx *Index) Len() int {
+	n := 0
+	for _, b := range idx.buckets {
+		n += len(b.data) / b.width
+	}
+	return n
+}
+
+// Lookup returns the section offsets recorded for mh. An IndexSorted
+// index can return several, for blocks whose digests match under
+// different hash functions.
+func (idx *Index) Lookup(mh cidutil.Multihash) []uint64 {
+	code, d, err := cidutil.DecodeMultihash(mh)
+	if err != nil {
+		return nil
+	}
+	var offsets []uint64
+	for _, b := range idx.buckets {
+		if b.width != len(d)+8 || (b.code >= 0 && uint64(b.code) != code) {
+			continue
+		}
+		n := len(b.data) / b.width
+		i := sort.Search(n, func(i int) bool {
+			return bytes.Compare(b.data[i*b.width:i*b.width+len(d)], d) >= 0
+		})
+		for ; i < n && bytes.Equal(b.data[i*b.width:i*b.width+len(d)], d); i++ {
+			offsets = append(offsets, binary.LittleEndian.Uint64(b.data[i*b.width+len(d):]))
+		}
+	}
+	return offsets
+}
+
+// ForEach calls fn with each record's digest and section offset
+func (idx *Index) ForEach(fn func(digest []byte, offset uint64) error) error {
+	for _, b := range idx.buckets {
+		for i := 0; i+b.width <= len(b.data); i += b.width {
+			rec := b.data[i : i+b.width]
+			if err := fn(rec[:b.width-8], binary.LittleEndian.Uint64(rec[b.width-8:])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
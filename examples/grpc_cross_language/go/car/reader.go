@@ -0,0 +1,174 @@
+package car
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// v2Pragma starts every CARv2: a CARv1 header reading {"version": 2}
+var v2Pragma = []byte{0x0a, 0xa1, 0x67, 'v', 'e', 'r', 's', 'i', 'o', 'n', 0x02}
+
+// v2HeaderSize is the size of the fixed CARv2 header after the pragma
+const v2HeaderSize = 40
+
+// Reader gives random access to a CARv1 or CARv2 archive
+type Reader struct {
+	// Version is the archive's CAR version, 1 or 2
+	Version uint64
+	// Roots are the roots of the CARv1 payload
+	Roots []cidutil.CID
+
+	payload     *io.SectionReader
+	blocksStart int64 // first section, relative to payload
+	indexOffset int64 // absolute; 0 if the CARv2 has no index
+	ra          io.ReaderAt
+	index       *Index
+}
+
+// NewReader opens the CAR in ra, size bytes long. Only the headers are
+// read.
+func NewReader(ra io.ReaderAt, size int64) (*Reader, error) {
+	rd := &Reader{Version: 1, ra: ra, payload: io.NewSectionReader(ra, 0, size)}
+	pragma := make([]byte, len(v2Pragma))
+	if _, err := ra.ReadAt(pragma, 0); err == nil && bytes.Equal(pragma, v2Pragma) {
+		hdr := make([]byte, v2HeaderSize)
+		if _, err := ra.ReadAt(hdr, int64(len(v2Pragma))); err != nil {
+			return nil, fmt.Errorf("car: read CARv2 header: %w", err)
+		}
+		// 16 bytes of characteristics, then data offset, data size and
+		// index offset, little-endian
+		dataOffset := int64(binary.LittleEndian.Uint64(hdr[16:]))
+		dataSize := int64(binary.LittleEndian.Uint64(hdr[24:]))
+		indexOffset := int64(binary.LittleEndian.Uint64(hdr[32:]))
+		if dataOffset < int64(len(v2Pragma)+v2HeaderSize) || dataSize < 0 || dataOffset+dataSize > size || indexOffset < 0 || indexOffset > size {
+			return nil, errors.New("car: CARv2 header offsets out of range")
+		}
+		rd.Version = 2
+		rd.payload = io.NewSectionReader(ra, dataOffset, dataSize)
+		rd.indexOffset = indexOffset
+	}
+
+	h, err := ReadHeader(bufio.NewReader(rd.payload))
+	if err != nil {
+		return nil, err
+	}
+	if h.Version != 1 {
+		return nil, fmt.Errorf("car: payload has version %d, want 1", h.Version)
+	}
+	rd.Roots = h.Roots
+	// Blocks start after the header and its length prefix
+	prefix := make([]byte, binary.MaxVarintLen64)
+	n, _ := rd.payload.ReadAt(prefix, 0)
+	hdrLen, k := binary.Uvarint(prefix[:n])
+	rd.blocksStart = int64(k) + int64(hdrLen)
+	return rd, nil
+}
+
+// Index returns the archive's block index: the CARv2 index if it has one,
+// otherwise one built by reading each section's head. Building skips over
+// block data, so it costs one small read per block.
+func (rd *Reader) Index() (*Index, error) {
+	if rd.index != nil {
+		return rd.index, nil
+	}
+	var err error
+	if rd.indexOffset > 0 {
+		r := bufio.NewReader(io.NewSectionReader(rd.ra, rd.indexOffset, 1<<62))
+		rd.index, err = ReadIndex(r)
+	} else {
+		rd.index, err = rd.buildIndex()
+	}
+	return rd.index, err
+}
+
+func (rd *Reader) buildIndex() (*Index, error) {
+	var records []record
+	err := rd.scan(func(b BlockInfo) error {
+		records = append(records, record{code: b.CID.Hash.Code(), digest: digest(b.CID.Hash), offset: uint64(b.Offset)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newIndex(records), nil
+}
+
+// scan calls fn with every section in payload order
+func (rd *Reader) scan(fn func(BlockInfo) error) error {
+	for off := rd.blocksStart; off < rd.payload.Size(); {
+		b, err := readSection(rd.payload, off)
+		if err != nil {
+			// Zero padding may follow the payload's last section
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+		off = b.DataOffset + b.Size
+	}
+	return nil
+}
+
+// Blocks calls fn with each block's location, in index order. Only section
+// heads are read.
+func (rd *Reader) Blocks(fn func(BlockInfo) error) error {
+	idx, err := rd.Index()
+	if err != nil {
+		return err
+	}
+	return idx.ForEach(func(_ []byte, offset uint64) error {
+		b, err := readSection(rd.payload, int64(offset))
+		if err != nil {
+			return err
+		}
+		return fn(b)
+	})
+}
+
+// Stat returns the location of the block c, or ErrNotFound
+func (rd *Reader) Stat(c cidutil.CID) (BlockInfo, error) {
+	idx, err := rd.Index()
+	if err != nil {
+		return BlockInfo{}, err
+	}
+	offsets := idx.Lookup(c.Hash)
+	for _, off := range offsets {
+		b, err := readSection(rd.payload, int64(off))
+		if err != nil {
+			return BlockInfo{}, err
+		}
+		// Indexes may key by digest alone; the section's CID settles it
+		if bytes.Equal(b.CID.Hash, c.Hash) {
+			return b, nil
+		}
+	}
+	return BlockInfo{}, fmt.Errorf("%w: %s", ErrNotFound, c)
+}
+
+// Block returns the data of the block c, verified against its multihash
+func (rd *Reader) Block(c cidutil.CID) ([]byte, error) {
+	if code, data, err := cidutil.DecodeMultihash(c.Hash); err == nil && code == cidutil.Identity {
+		return data, nil
+	}
+	b, err := rd.Stat(c)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, b.Size)
+	if _, err := rd.payload.ReadAt(data, b.DataOffset); err != nil {
+		return nil, fmt.Errorf("car: read %s: %w", c, err)
+	}
+	if err := c.VerifyBlock(data); err != nil && !errors.Is(err, cidutil.ErrUnknownHash) {
+		return nil, fmt.Errorf("car: block %s: %w", c, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,140 @@
+// Package chunker splits content into blocks the way Kubo does, so the Go
+// client sees the same chunk boundaries, and so the same block CIDs, as the
+// Python and IPFS side. Routing and dedup decisions can then be made per
+// chunk before anything is uploaded.
+package chunker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DefaultBlockSize is Kubo's default chunk size, used by "size" and as the
+// average of "rabin"
+const DefaultBlockSize = 256 << 10
+
+// MaxBlockSize is the largest chunk Kubo accepts; bigger blocks are not
+// transferred by Bitswap
+const MaxBlockSize = 1 << 20
+
+// Splitter returns successive chunks of a stream. NextBytes returns io.EOF
+// after the last chunk; an empty stream has no chunks.
+type Splitter interface {
+	NextBytes() ([]byte, error)
+}
+
+// FromString returns the splitter Kubo uses for spec, its --chunker
+// option: "" or "default", "size-<bytes>", "rabin", "rabin-<avg>",
+// "rabin-<min>-<avg>-<max>" or "buzhash".
+func FromString(r io.Reader, spec string) (Splitter, error) {
+	switch {
+	case spec == "" || spec == "default":
+		return NewSizeSplitter(r, DefaultBlockSize), nil
+
+	case strings.HasPrefix(spec, "size-"):
+		size, err := strconv.ParseInt(strings.TrimPrefix(spec, "size-"), 10, 64)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("chunker: bad size in %q", spec)
+		}
+		if size > MaxBlockSize {
+			return nil, fmt.Errorf("chunker: %q exceeds the %d byte block limit", spec, MaxBlockSize)
+		}
+		return NewSizeSplitter(r, size), nil
+
+	case spec == "rabin" || strings.HasPrefix(spec, "rabin-"):
+		min, avg, max, err := parseRabin(spec)
+		if err != nil {
+			return nil, err
+		}
+		return NewRabinMinMax(r, min, avg, max), nil
+
+	case spec == "buzhash":
+		return NewBuzhash(r), nil
+	}
+	return nil, fmt.Errorf("chunker: unrecognised chunker %q", spec)
+}
+
+// parseRabin reads the sizes of a "rabin" spec. A lone average gives
+// Kubo's derived bounds: min avg/3, max avg*1.5.
+func parseRabin(spec string) (min, avg, max int, err error) {
+	parts := strings.Split(spec, "-")[1:]
+	sizes := make([]int, len(parts))
+	for i, p := range parts {
+		if sizes[i], err = strconv.Atoi(p); err != nil || sizes[i] <= 0 {
+			return 0, 0, 0, fmt.Errorf("chunker: bad size in %q", spec)
+		}
+	}
+	switch len(sizes) {
+	case 0:
+		avg = DefaultBlockSize
+		min, max = avg/3, avg+avg/2
+	case 1:
+		avg = sizes[0]
+		min, max = avg/3, avg+avg/2
+	case 3:
+		min, avg, max = sizes[0], sizes[1], sizes[2]
+	default:
+		return 0, 0, 0, fmt.Errorf("chunker: %q is not rabin-<avg> or rabin-<min>-<avg>-<max>", spec)
+	}
+	if min < windowSize || min >= avg || avg >= max {
+		return 0, 0, 0, fmt.Errorf("chunker: %q needs %d <= min < avg < max", spec, windowSize)
+	}
+	if max > MaxBlockSize {
+		return 0, 0, 0, fmt.Errorf("chunker: %q exceeds the %d byte block limit", spec, MaxBlockSize)
+	}
+	return min, avg, max, nil
+}
+
+// Split calls fn with each chunk from s and its offset. The slice passed to
+// fn is only valid until it returns.
+func Split(s Splitter, fn func(offset int64, chunk []byte) error) error {
+	var offset int64
+	for {
+		chunk, err := s.NextBytes()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(offset, chunk); err != nil {
+			return err
+		}
+		offset += int64(len(chunk))
+	}
+}
+
+// SizeSplitter cuts a stream into chunks of a fixed size; the last one
+// may be shorter
+type SizeSplitter struct {
+	r    io.Reader
+	size int64
+	err  error
+}
+
+// NewSizeSplitter returns a splitter cutting r every size bytes, Kubo's
+// "size-<size>" chunker
+func NewSizeSplitter(r io.Reader, size int64) *SizeSplitter {
+	return &SizeSplitter{r: r, size: size}
+}
+
+// NextBytes returns the next chunk
+func (s *SizeSplitter) NextBytes() ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	buf := make([]byte, s.size)
+	n, err := io.ReadFull(s.r, buf)
+	switch {
+	case err == nil:
+		return buf, nil
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		s.err = io.EOF
+		return buf[:n], nil
+	}
+	s.err = err
+	return nil, err
+}
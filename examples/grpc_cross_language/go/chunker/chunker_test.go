@@ -0,0 +1,70 @@
+package chunker_test
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"example.com/ipfs_kit_py/chunker"
+)
+
+// seededData returns the bytes go-ipfs-util's NewSeededRand(seed) reads,
+// the test data of Kubo's importer tests
+func seededData(seed int64, n int) []byte {
+	r := rand.New(rand.NewSource(seed))
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(r.Intn(255))
+	}
+	return b
+}
+
+// TestBoundaries checks the chunk ends against go-ipfs-chunker, the
+// chunker `ipfs add --chunker=<spec>` runs
+func TestBoundaries(t *testing.T) {
+	data := seededData(0xdeadbeef, 1<<20)
+	tests := []struct {
+		spec string
+		size int
+		ends []int64
+	}{
+		{"rabin", 1 << 20, []int64{220662, 613878, 910505, 1048576}},
+		{"rabin-16-32-64", 1000, []int64{19, 69, 133, 160, 224, 244, 305, 344, 362, 426, 484, 525, 589, 632, 694, 738, 755, 776, 820, 879, 936, 957, 1000}},
+		{"buzhash", 1 << 20, []int64{221651, 515386, 665482, 807967, 1048576}},
+		{"buzhash", 100 << 10, []int64{100 << 10}},
+		{"size-300000", 1 << 20, []int64{300000, 600000, 900000, 1048576}},
+		{"rabin", 0, nil},
+		{"buzhash", 0, nil},
+	}
+	for _, tt := range tests {
+		s, err := chunker.FromString(bytes.NewReader(data[:tt.size]), tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ends []int64
+		var joined []byte
+		err = chunker.Split(s, func(offset int64, chunk []byte) error {
+			ends = append(ends, offset+int64(len(chunk)))
+			joined = append(joined, chunk...)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ends, tt.ends) {
+			t.Errorf("%s over %d bytes: chunks end at %v, want %v", tt.spec, tt.size, ends, tt.ends)
+		}
+		if !bytes.Equal(joined, data[:tt.size]) {
+			t.Errorf("%s over %d bytes: chunks do not join back to the input", tt.spec, tt.size)
+		}
+	}
+}
+
+func TestFromStringRejects(t *testing.T) {
+	for _, spec := range []string{"size-0", "size-2000000", "rabin-8-32-64", "rabin-32-32-64", "rabin-16-64-32", "rabin-1-2", "rabin-1000000", "fastcdc"} {
+		if _, err := chunker.FromString(bytes.NewReader(nil), spec); err == nil {
+			t.Errorf("%q accepted", spec)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package chunker
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math/bits"
+	"sync"
+)
+
+// IPFSPolynomial is the irreducible polynomial of Kubo's Rabin chunker
+const IPFSPolynomial Pol = 17437180132763653
+
+// windowSize is the number of bytes the Rabin fingerprint covers, 16 in
+// the chunker package Kubo uses
+const windowSize = 16
+
+// Pol is a polynomial over GF(2), one bit per coefficient
+type Pol uint64
+
+// Deg returns the degree of x, or -1 for the zero polynomial
+func (x Pol) Deg() int {
+	return bits.Len64(uint64(x)) - 1
+}
+
+// Mod returns x modulo d
+func (x Pol) Mod(d Pol) Pol {
+	for x.Deg() >= d.Deg() {
+		x ^= d << uint(x.Deg()-d.Deg())
+	}
+	return x
+}
+
+// rabinTables holds the precomputed tables for sliding a byte out of the
+// window and reducing the fingerprint modulo the polynomial
+type rabinTables struct {
+	out   [256]uint64
+	mod   [256]uint64
+	shift uint
+}
+
+var (
+	ipfsTablesOnce sync.Once
+	ipfsTables     *rabinTables
+)
+
+func newRabinTables(pol Pol) *rabinTables {
+	t := &rabinTables{shift: uint(pol.Deg() - 8)}
+	for b := 0; b < 256; b++ {
+		// out[b] is the fingerprint of b followed by windowSize-1 zero
+		// bytes; adding it removes b from the front of the window
+		h := appendByte(0, byte(b), pol)
+		for i := 0; i < windowSize-1; i++ {
+			h = appendByte(h, 0, pol)
+		}
+		t.out[b] = uint64(h)
+
+		// mod[b] reduces the 8 bits shifted above the polynomial's degree
+		// and clears them, in one XOR
+		k := uint(pol.Deg())
+		t.mod[b] = uint64((Pol(b) << k).Mod(pol) | Pol(b)<<k)
+	}
+	return t
+}
+
+func appendByte(h Pol, b byte, pol Pol) Pol {
+	return (h<<8 | Pol(b)).Mod(pol)
+}
+
+// Rabin cuts a stream where the Rabin fingerprint of the last 16 bytes has
+// its low log2(avg) bits clear, within min and max bytes of the previous
+// cut. Boundaries depend only on content, so an insertion shifts just the
+// chunks around it. This is Kubo's "rabin" chunker.
+type Rabin struct {
+	r        *bufio.Reader
+	tables   *rabinTables
+	min, max int
+	mask     uint64
+	err      error
+}
+
+// NewRabin returns Kubo's "rabin-<avg>" chunker, with min avg/3 and max
+// avg*1.5
+func NewRabin(r io.Reader, avg int) *Rabin {
+	return NewRabinMinMax(r, avg/3, avg, avg+avg/2)
+}
+
+// NewRabinMinMax returns Kubo's "rabin-<min>-<avg>-<max>" chunker. min
+// must be at least 16, the fingerprint window.
+func NewRabinMinMax(r io.Reader, min, avg, max int) *Rabin {
+	ipfsTablesOnce.Do(func() { ipfsTables = newRabinTables(IPFSPolynomial) })
+	return &Rabin{
+		r:      bufio.NewReaderSize(r, max),
+		tables: ipfsTables,
+		min:    min,
+		max:    max,
+		mask:   1<<uint(bits.Len(uint(avg))-1) - 1,
+	}
+}
+
+// NextBytes returns the next chunk
+func (c *Rabin) NextBytes() ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	chunk := make([]byte, 0, c.max)
+	var window [windowSize]byte
+	var digest uint64
+	wpos := 0
+	slide := func(b byte) {
+		digest ^= c.tables.out[window[wpos]]
+		window[wpos] = b
+		wpos = (wpos + 1) % windowSize
+		index := digest >> c.tables.shift
+		digest = (digest<<8 | uint64(b)) ^ c.tables.mod[index]
+	}
+	// Kubo's chunker resets the fingerprint for each chunk by sliding a 1
+	// byte into an empty window; boundaries differ without it
+	slide(1)
+	for len(chunk) < c.max {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				c.err = err
+				return nil, err
+			}
+			c.err = io.EOF
+			if len(chunk) == 0 {
+				return nil, io.EOF
+			}
+			return chunk, nil
+		}
+		chunk = append(chunk, b)
+
+		// Bytes more than a window before min cannot affect the first
+		// fingerprint checked
+		if len(chunk) <= c.min-windowSize {
+			continue
+		}
+		slide(b)
+		if len(chunk) >= c.min && digest&c.mask == 0 {
+			break
+		}
+	}
+	return chunk, nil
+}
@@ -0,0 +1,167 @@
+package cidutil
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CID is a decoded content identifier
+type CID struct {
+	Version uint64
+	Codec   uint64
+	Hash    Multihash
+}
+
+// ErrInvalidCID is returned for strings that are not CIDs this package
+// can decode
+var ErrInvalidCID = errors.New("cidutil: invalid CID")
+
+// ErrNoV0 is returned by ToV0 for CIDs that have no CIDv0 form: only
+// dag-pb content hashed with sha2-256 does
+var ErrNoV0 = errors.New("cidutil: CID has no v0 form")
+
+// NewCIDv0 returns the CIDv0 of a dag-pb node with sha2-256 multihash mh
+func NewCIDv0(mh Multihash) (CID, error) {
+	return CID{Version: 1, Codec: DagPB, Hash: mh}.ToV0()
+}
+
+// NewCIDv1 returns the CIDv1 of content with the given codec and multihash
+func NewCIDv1(codec uint64, mh Multihash) CID {
+	return CID{Version: 1, Codec: codec, Hash: mh}
+}
+
+// ParseCID decodes a CID string: a CIDv0 ("Qm...") or a CIDv1 in any
+// supported multibase, with or without an /ipfs/ prefix. It rejects
+// malformed multihashes, so it also serves to validate CIDs.
+func ParseCID(s string) (CID, error) {
+	s = strings.TrimPrefix(s, "/ipfs/")
+	if len(s) == 46 && strings.HasPrefix(s, "Qm") {
+		mh, err := decodeBaseX(s, base58Alphabet)
+		if err != nil {
+			return CID{}, fmt.Errorf("%w %q: %v", ErrInvalidCID, s, err)
+		}
+		c, err := CastCID(mh)
+		if err != nil {
+			return CID{}, fmt.Errorf("%w %q: %v", ErrInvalidCID, s, err)
+		}
+		return c, nil
+	}
+	_, raw, err := MultibaseDecode(s)
+	if err != nil {
+		return CID{}, fmt.Errorf("%w %q: %v", ErrInvalidCID, s, err)
+	}
+	c, err := CastCID(raw)
+	if err != nil || c.Version == 0 {
+		// A multibase-wrapped CIDv0 is not a valid CID string
+		return CID{}, fmt.Errorf("%w %q", ErrInvalidCID, s)
+	}
+	return c, nil
+}
+
+// CastCID decodes a binary CID: a bare sha2-256 multihash for v0, or
+// version, codec and multihash varints for v1
+func CastCID(b []byte) (CID, error) {
+	if len(b) == 34 && b[0] == SHA2_256 && b[1] == 32 {
+		return CID{Version: 0, Codec: DagPB, Hash: Multihash(b)}, nil
+	}
+	version, n := binary.Uvarint(b)
+	if n <= 0 || version != 1 {
+		return CID{}, fmt.Errorf("%w: unsupported version", ErrInvalidCID)
+	}
+	codec, m := binary.Uvarint(b[n:])
+	if m <= 0 {
+		return CID{}, fmt.Errorf("%w: invalid codec", ErrInvalidCID)
+	}
+	mh := Multihash(b[n+m:])
+	if _, _, err := DecodeMultihash(mh); err != nil {
+		return CID{}, fmt.Errorf("%w: %v", ErrInvalidCID, err)
+	}
+	return CID{Version: 1, Codec: codec, Hash: mh}, nil
+}
+
+// ValidateCID checks that s parses and that its digest has the length
+// its hash function produces
+func ValidateCID(s string) error {
+	c, err := ParseCID(s)
+	if err != nil {
+		return err
+	}
+	code, digest, _ := DecodeMultihash(c.Hash)
+	if h, err := NewHash(code); err == nil && h.Size() != len(digest) {
+		return fmt.Errorf("%w %q: %s digest of %d bytes, want %d", ErrInvalidCID, s, HashName(code), len(digest), h.Size())
+	}
+	return nil
+}
+
+// Bytes returns the binary form of c
+func (c CID) Bytes() []byte {
+	if c.Version == 0 {
+		return append([]byte(nil), c.Hash...)
+	}
+	buf := binary.AppendUvarint(nil, 1)
+	buf = binary.AppendUvarint(buf, c.Codec)
+	return append(buf, c.Hash...)
+}
+
+// String returns c in its canonical form: base58btc for v0, base32 for v1
+func (c CID) String() string {
+	if c.Version == 0 {
+		return encodeBaseX(c.Hash, base58Alphabet)
+	}
+	s, _ := MultibaseEncode(Base32, c.Bytes())
+	return s
+}
+
+// Encode returns c in the given multibase. CIDv0 is only written in
+// base58btc, without a prefix.
+func (c CID) Encode(b Multibase) (string, error) {
+	if c.Version == 0 {
+		if b != Base58BTC {
+			return "", fmt.Errorf("cidutil: CIDv0 cannot be encoded in %s", b)
+		}
+		return c.String(), nil
+	}
+	return MultibaseEncode(b, c.Bytes())
+}
+
+// ToV1 returns c as a CIDv1, which every CID has
+func (c CID) ToV1() CID {
+	return CID{Version: 1, Codec: c.Codec, Hash: c.Hash}
+}
+
+// ToV0 returns c as a CIDv0, or ErrNoV0 if it is not a dag-pb CID with a
+// sha2-256 multihash
+func (c CID) ToV0() (CID, error) {
+	code, digest, err := DecodeMultihash(c.Hash)
+	if err != nil {
+		return CID{}, err
+	}
+	if c.Codec != DagPB || code != SHA2_256 || len(digest) != 32 {
+		return CID{}, fmt.Errorf("%w (%s, %s)", ErrNoV0, CodecName(c.Codec), HashName(code))
+	}
+	return CID{Version: 0, Codec: DagPB, Hash: c.Hash}, nil
+}
+
+// VerifyBlock checks that block is the data c addresses. For raw CIDs the
+// block is the content itself; for dag-pb it is the encoded node, not the
+// file it describes.
+func (c CID) VerifyBlock(block []byte) error {
+	return c.Hash.Verify(block)
+}
+
+var codecNames = map[uint64]string{
+	Raw:     "raw",
+	DagPB:   "dag-pb",
+	DagCBOR: "dag-cbor",
+	DagJSON: "dag-json",
+}
+
+// CodecName returns the name of an IPLD codec, or "0x<code>" if unknown
+func CodecName(codec uint64) string {
+	if name, ok := codecNames[codec]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", codec)
+}
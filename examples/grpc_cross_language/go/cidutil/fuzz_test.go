@@ -0,0 +1,55 @@
+package cidutil_test
+
+import (
+	"bytes"
+	"testing"
+
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// FuzzCID parses its input as a CID in string and binary form, and as a
+// multibase string and multihash. A CID that parses must print as a string
+// that parses back to the same CID.
+func FuzzCID(f *testing.F) {
+	mh, err := cidutil.Sum(cidutil.SHA2_256, []byte("fuzz"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	v0, err := cidutil.NewCIDv0(mh)
+	if err != nil {
+		f.Fatal(err)
+	}
+	v1 := cidutil.NewCIDv1(cidutil.Raw, mh)
+	inline := cidutil.NewCIDv1(cidutil.Raw, cidutil.EncodeMultihash(cidutil.Identity, []byte("hello")))
+	f.Add([]byte(v0.String()))
+	f.Add([]byte(v1.String()))
+	f.Add([]byte("/ipfs/" + v1.String()))
+	f.Add([]byte(inline.String()))
+	f.Add(v1.Bytes())
+	f.Add([]byte(mh))
+	for _, b := range []cidutil.Multibase{cidutil.Base16, cidutil.Base36, cidutil.Base58BTC, cidutil.Base64, cidutil.Base64URL} {
+		if s, err := v1.Encode(b); err == nil {
+			f.Add([]byte(s))
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if c, err := cidutil.ParseCID(string(data)); err == nil {
+			again, err := cidutil.ParseCID(c.String())
+			if err != nil {
+				t.Fatalf("CID %s does not reparse: %v", c, err)
+			}
+			if !bytes.Equal(again.Bytes(), c.Bytes()) {
+				t.Fatalf("CID %s reparses as %s", c, again)
+			}
+		}
+		if c, err := cidutil.CastCID(data); err == nil {
+			if _, err := cidutil.ParseCID(c.String()); err != nil {
+				t.Fatalf("binary CID %s does not reparse: %v", c, err)
+			}
+		}
+		cidutil.MultibaseDecode(string(data))
+		cidutil.DecodeMultihash(data)
+		cidutil.InlineData(string(data))
+	})
+}
@@ -0,0 +1,33 @@
+package cidutil
+
+// IPLD codec codes used in CIDs
+const (
+	Raw     = 0x55
+	DagPB   = 0x70
+	DagCBOR = 0x71
+	DagJSON = 0x0129
+)
+
+// DefaultInlineLimit is the largest content, in bytes, inlined into an
+// identity CID by default. It matches Kubo's --inline-limit default.
+const DefaultInlineLimit = 32
+
+// InlineCID returns a CIDv1 that carries data itself in an identity
+// multihash, so the content needs no block of its own
+func InlineCID(codec uint64, data []byte) string {
+	return NewCIDv1(codec, EncodeMultihash(Identity, data)).String()
+}
+
+// InlineData returns the content carried by an identity CID and its codec.
+// ok is false for CIDs that hash their content, which must be fetched.
+func InlineData(cid string) (codec uint64, data []byte, ok bool) {
+	c, err := ParseCID(cid)
+	if err != nil {
+		return 0, nil, false
+	}
+	code, digest, _ := DecodeMultihash(c.Hash)
+	if code != Identity {
+		return 0, nil, false
+	}
+	return c.Codec, digest, true
+}
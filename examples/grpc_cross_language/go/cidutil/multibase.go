@@ -0,0 +1,182 @@
+package cidutil
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Multibase is the prefix character naming the encoding of a multibase
+// string
+type Multibase byte
+
+// Multibase encodings supported by MultibaseEncode and MultibaseDecode
+const (
+	Base16      Multibase = 'f'
+	Base32      Multibase = 'b'
+	Base32Upper Multibase = 'B'
+	Base36      Multibase = 'k'
+	Base58BTC   Multibase = 'z'
+	Base64      Multibase = 'm'
+	Base64URL   Multibase = 'u'
+)
+
+// ErrUnknownBase is returned for multibase prefixes and names this package
+// does not support
+var ErrUnknownBase = errors.New("cidutil: unsupported multibase")
+
+var multibaseNames = map[Multibase]string{
+	Base16:      "base16",
+	Base32:      "base32",
+	Base32Upper: "base32upper",
+	Base36:      "base36",
+	Base58BTC:   "base58btc",
+	Base64:      "base64",
+	Base64URL:   "base64url",
+}
+
+const (
+	base36Alphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+)
+
+var (
+	base32Lower = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+	base32Upper = base32.StdEncoding.WithPadding(base32.NoPadding)
+)
+
+// ParseMultibase returns the encoding with the given name, e.g. "base58btc"
+func ParseMultibase(name string) (Multibase, error) {
+	for b, n := range multibaseNames {
+		if n == name {
+			return b, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownBase, name)
+}
+
+// String returns the encoding's name, or its prefix if unknown
+func (b Multibase) String() string {
+	if name, ok := multibaseNames[b]; ok {
+		return name
+	}
+	return fmt.Sprintf("%q", rune(b))
+}
+
+// MultibaseEncode encodes data with b, prefix included
+func MultibaseEncode(b Multibase, data []byte) (string, error) {
+	var s string
+	switch b {
+	case Base16:
+		s = hex.EncodeToString(data)
+	case Base32:
+		s = base32Lower.EncodeToString(data)
+	case Base32Upper:
+		s = base32Upper.EncodeToString(data)
+	case Base36:
+		s = encodeBaseX(data, base36Alphabet)
+	case Base58BTC:
+		s = encodeBaseX(data, base58Alphabet)
+	case Base64:
+		s = base64.RawStdEncoding.EncodeToString(data)
+	case Base64URL:
+		s = base64.RawURLEncoding.EncodeToString(data)
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnknownBase, b)
+	}
+	return string(b) + s, nil
+}
+
+// MultibaseDecode decodes a multibase string, returning its encoding
+func MultibaseDecode(s string) (Multibase, []byte, error) {
+	if s == "" {
+		return 0, nil, errors.New("cidutil: empty multibase string")
+	}
+	b, body := Multibase(s[0]), s[1:]
+	var data []byte
+	var err error
+	switch b {
+	case Base16, 'F':
+		data, err = hex.DecodeString(body)
+	case Base32:
+		data, err = base32Lower.DecodeString(body)
+	case Base32Upper:
+		data, err = base32Upper.DecodeString(body)
+	case Base36, 'K':
+		data, err = decodeBaseX(strings.ToLower(body), base36Alphabet)
+	case Base58BTC:
+		data, err = decodeBaseX(body, base58Alphabet)
+	case Base64:
+		data, err = base64.RawStdEncoding.DecodeString(body)
+	case Base64URL:
+		data, err = base64.RawURLEncoding.DecodeString(body)
+	default:
+		return 0, nil, fmt.Errorf("%w: prefix %q", ErrUnknownBase, s[0])
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("cidutil: invalid %s: %v", b, err)
+	}
+	return b, data, nil
+}
+
+// encodeBaseX encodes data in the base of alphabet, each leading zero byte
+// becoming a leading zero digit, as base58btc and base36 do
+func encodeBaseX(data []byte, alphabet string) string {
+	base := len(alphabet)
+	// Little-endian digits of the big-endian number in data
+	var digits []byte
+	for _, b := range data {
+		carry := int(b)
+		for i := range digits {
+			carry += int(digits[i]) << 8
+			digits[i] = byte(carry % base)
+			carry /= base
+		}
+		for ; carry > 0; carry /= base {
+			digits = append(digits, byte(carry%base))
+		}
+	}
+	var sb strings.Builder
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		sb.WriteByte(alphabet[0])
+	}
+	for i := len(digits) - 1; i >= 0; i-- {
+		sb.WriteByte(alphabet[digits[i]])
+	}
+	return sb.String()
+}
+
+// decodeBaseX reverses encodeBaseX
+func decodeBaseX(s, alphabet string) ([]byte, error) {
+	base := len(alphabet)
+	// Little-endian base-256 accumulator
+	var num []byte
+	for _, r := range s {
+		digit := strings.IndexRune(alphabet, r)
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid character %q", r)
+		}
+		carry := digit
+		for i := range num {
+			carry += int(num[i]) * base
+			num[i] = byte(carry)
+			carry >>= 8
+		}
+		for ; carry > 0; carry >>= 8 {
+			num = append(num, byte(carry))
+		}
+	}
+	// Each leading zero digit is a leading zero byte
+	zeros := len(s) - len(strings.TrimLeft(s, alphabet[:1]))
+	out := make([]byte, zeros, zeros+len(num))
+	for i := len(num) - 1; i >= 0; i-- {
+		out = append(out, num[i])
+	}
+	return out, nil
+}
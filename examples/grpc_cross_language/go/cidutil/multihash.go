@@ -0,0 +1,191 @@
+// Package cidutil provides CID and multihash helpers without pulling in
+// the full IPFS dependency tree.
+package cidutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"sort"
+
+	"github.com/zeebo/blake3"
+)
+
+// Multihash function codes
+const (
+	Identity = 0x00
+	SHA2_256 = 0x12
+	SHA2_512 = 0x13
+	SHA3_512 = 0x14
+	SHA3_384 = 0x15
+	SHA3_256 = 0x16
+	SHA3_224 = 0x17
+	BLAKE3   = 0x1e
+)
+
+type hashFunc struct {
+	name string
+	new  func() hash.Hash
+}
+
+var hashes = map[uint64]hashFunc{
+	SHA2_256: {"sha2-256", sha256.New},
+	SHA2_512: {"sha2-512", sha512.New},
+	SHA3_224: {"sha3-224", func() hash.Hash { return sha3.New224() }},
+	SHA3_256: {"sha3-256", func() hash.Hash { return sha3.New256() }},
+	SHA3_384: {"sha3-384", func() hash.Hash { return sha3.New384() }},
+	SHA3_512: {"sha3-512", func() hash.Hash { return sha3.New512() }},
+	BLAKE3:   {"blake3", func() hash.Hash { return blake3.New() }},
+}
+
+// ErrUnknownHash is returned for multihash codes this package cannot compute
+var ErrUnknownHash = errors.New("cidutil: unsupported hash function")
+
+// ErrDigestMismatch is returned by Verify when content does not match
+var ErrDigestMismatch = errors.New("cidutil: digest mismatch")
+
+// HashCode returns the multihash code for a hash name such as "blake3"
+func HashCode(name string) (uint64, error) {
+	if name == "identity" {
+		return Identity, nil
+	}
+	for code, h := range hashes {
+		if h.name == name {
+			return code, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownHash, name)
+}
+
+// HashName returns the name of a multihash code, or "0x<code>" if unknown
+func HashName(code uint64) string {
+	if code == Identity {
+		return "identity"
+	}
+	if h, ok := hashes[code]; ok {
+		return h.name
+	}
+	return fmt.Sprintf("0x%x", code)
+}
+
+// HashNames lists the hash functions Sum supports, sorted
+func HashNames() []string {
+	names := []string{"identity"}
+	for _, h := range hashes {
+		names = append(names, h.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewHash returns a hash.Hash for a multihash code. Identity is not
+// supported since it has no fixed-size digest.
+func NewHash(code uint64) (hash.Hash, error) {
+	h, ok := hashes[code]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownHash, HashName(code))
+	}
+	return h.new(), nil
+}
+
+// Multihash is a self-describing digest: varint code, varint length, digest
+type Multihash []byte
+
+// EncodeMultihash wraps digest in a multihash header
+func EncodeMultihash(code uint64, digest []byte) Multihash {
+	buf := binary.AppendUvarint(nil, code)
+	buf = binary.AppendUvarint(buf, uint64(len(digest)))
+	return append(buf, digest...)
+}
+
+// Sum hashes data with the given function and returns the multihash
+func Sum(code uint64, data []byte) (Multihash, error) {
+	if code == Identity {
+		return EncodeMultihash(Identity, data), nil
+	}
+	h, err := NewHash(code)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(data)
+	return EncodeMultihash(code, h.Sum(nil)), nil
+}
+
+// DecodeMultihash splits mh into its function code and digest
+func DecodeMultihash(mh []byte) (code uint64, digest []byte, err error) {
+	code, n := binary.Uvarint(mh)
+	if n <= 0 {
+		return 0, nil, errors.New("cidutil: invalid multihash code")
+	}
+	size, m := binary.Uvarint(mh[n:])
+	if m <= 0 {
+		return 0, nil, errors.New("cidutil: invalid multihash length")
+	}
+	digest = mh[n+m:]
+	if uint64(len(digest)) != size {
+		return 0, nil, fmt.Errorf("cidutil: multihash length %d, digest has %d bytes", size, len(digest))
+	}
+	return code, digest, nil
+}
+
+// Code returns the hash function code of mh (0 if malformed)
+func (mh Multihash) Code() uint64 {
+	code, _, _ := DecodeMultihash(mh)
+	return code
+}
+
+// Verify checks data against mh using mh's own hash function, so content
+// from repositories mixing hash functions verifies correctly
+func (mh Multihash) Verify(data []byte) error {
+	code, digest, err := DecodeMultihash(mh)
+	if err != nil {
+		return err
+	}
+	got, err := Sum(code, data)
+	if err != nil {
+		return err
+	}
+	_, gotDigest, _ := DecodeMultihash(got)
+	if !bytes.Equal(gotDigest, digest) {
+		return fmt.Errorf("%w (%s)", ErrDigestMismatch, HashName(code))
+	}
+	return nil
+}
+
+// ParseMultihash decodes a textual multihash: hex, as Python's
+// multihash.to_hex_string writes, or base58btc, as in a CIDv0
+func ParseMultihash(s string) (Multihash, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		if b, err = decodeBaseX(s, base58Alphabet); err != nil {
+			return nil, fmt.Errorf("cidutil: multihash %q is neither hex nor base58btc", s)
+		}
+	}
+	if _, _, err := DecodeMultihash(b); err != nil {
+		return nil, err
+	}
+	return Multihash(b), nil
+}
+
+// HexString returns mh in hex
+func (mh Multihash) HexString() string {
+	return hex.EncodeToString(mh)
+}
+
+// B58String returns mh in base58btc
+func (mh Multihash) B58String() string {
+	return encodeBaseX(mh, base58Alphabet)
+}
+
+// Key returns a string suitable as a map key for deduplication. It
+// includes the hash function, so the same content hashed two ways is two
+// distinct blocks, matching how blockstores key by multihash.
+func (mh Multihash) Key() string {
+	return string(mh)
+}
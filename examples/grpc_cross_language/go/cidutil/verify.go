@@ -0,0 +1,57 @@
+package cidutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// VerifyingReader passes content through while hashing it, and fails the
+// read that reaches EOF with ErrDigestMismatch if the content does not
+// match the expected multihash. Callers must read to EOF for the check to
+// happen.
+type VerifyingReader struct {
+	r      io.Reader
+	h      hash.Hash
+	code   uint64
+	digest []byte
+	done   bool
+}
+
+// NewVerifyingReader returns a reader checking r against mh. Identity
+// multihashes are compared directly.
+func NewVerifyingReader(r io.Reader, mh Multihash) (*VerifyingReader, error) {
+	code, digest, err := DecodeMultihash(mh)
+	if err != nil {
+		return nil, err
+	}
+	v := &VerifyingReader{r: r, code: code, digest: digest}
+	if code == Identity {
+		v.h = &identityHash{}
+	} else if v.h, err = NewHash(code); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Read implements io.Reader
+func (v *VerifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.h.Write(p[:n])
+	if errors.Is(err, io.EOF) && !v.done {
+		v.done = true
+		if got := v.h.Sum(nil); !bytes.Equal(got, v.digest) {
+			return n, fmt.Errorf("%w (%s)", ErrDigestMismatch, HashName(v.code))
+		}
+	}
+	return n, err
+}
+
+// identityHash "hashes" content to itself, for identity multihashes
+type identityHash struct{ bytes.Buffer }
+
+func (h *identityHash) Sum(b []byte) []byte { return append(b, h.Bytes()...) }
+func (h *identityHash) Size() int           { return h.Len() }
+func (h *identityHash) BlockSize() int      { return 1 }
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// runAdmin implements `routing-cli admin <subcommand>`
+func runAdmin(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprintf(os.Stderr, "Usage: routing-cli admin weights get [flags]\n       routing-cli admin weights set [flags] factor=weight...\n")
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	if args[0] == "weights" && len(args) > 1 {
+		switch args[1] {
+		case "get":
+			return runAdminWeights(args[2:], false)
+		case "set":
+			return runAdminWeights(args[2:], true)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "admin: unknown subcommand %q\n", strings.Join(args[:min(len(args), 2)], " "))
+	return exitUsage
+}
+
+// runAdminWeights implements `routing-cli admin weights get|set`. set
+// takes factor=weight arguments; factors not named keep their weight.
+func runAdminWeights(args []string, set bool) int {
+	name := "admin weights get"
+	if set {
+		name = "admin weights set"
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	server := fs.String("server", "localhost:50051", "gRPC server address: host:port, unix:///path, or a gRPC-Web gateway URL")
+	token := fs.String("token", os.Getenv(routingclient.AdminTokenEnv), "admin token (default $"+routingclient.AdminTokenEnv+")")
+	timeout := fs.Duration("timeout", 10*time.Second, "deadline for the call")
+	output := outputFlag(fs)
+	fs.Usage = func() {
+		if set {
+			fmt.Fprintf(fs.Output(), "Usage: routing-cli %s [flags] factor=weight...\n\n", name)
+		} else {
+			fmt.Fprintf(fs.Output(), "Usage: routing-cli %s [flags]\n\n", name)
+		}
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if set != (fs.NArg() > 0) || !checkOutput(output, false) {
+		fs.Usage()
+		return exitUsage
+	}
+	weights, err := parseWeights(fs.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+		return exitUsage
+	}
+
+	var opts []routingclient.Option
+	if *token != "" {
+		opts = append(opts, routingclient.WithToken(*token))
+	}
+	client, err := newClient(*server, opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+		return exitUnreachable
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if !set {
+		current, err := client.FactorWeights(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+			return exitUnreachable
+		}
+		return showWeights(*output, current, nil)
+	}
+	resp, err := client.SetFactorWeights(ctx, weights)
+	var rerr *routingclient.Error
+	switch {
+	case errors.Is(err, routingclient.ErrUnauthorized):
+		fmt.Fprintf(os.Stderr, "admin: %v (set -token or $%s)\n", err, routingclient.AdminTokenEnv)
+		return exitUsage
+	case errors.As(err, &rerr) && rerr.Reason != "":
+		fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+		return exitUsage
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+		return exitUnreachable
+	}
+	return showWeights(*output, resp.Weights, resp.PreviousWeights)
+}
+
+// weightRow is a factor's weight as admin weights prints it in CSV
+type weightRow struct {
+	Factor   string   `json:"factor"`
+	Weight   float64  `json:"weight"`
+	Previous *float64 `json:"previous,omitempty"`
+}
+
+// showWeights prints weights, and previous when non-nil, in format
+func showWeights(format string, weights, previous map[string]float64) int {
+	if format == formatText {
+		printWeights(weights, previous)
+		return exitOK
+	}
+	var v any = weights
+	if previous != nil {
+		v = map[string]map[string]float64{"weights": weights, "previous_weights": previous}
+	}
+	factors := make([]string, 0, len(weights))
+	for f := range weights {
+		factors = append(factors, f)
+	}
+	sort.Strings(factors)
+	rows := make([]weightRow, len(factors))
+	for i, f := range factors {
+		rows[i] = weightRow{Factor: f, Weight: weights[f]}
+		if prev, ok := previous[f]; ok {
+			rows[i].Previous = &prev
+		}
+	}
+	if err := printStructured(format, v, rows); err != nil {
+		fmt.Fprintf(os.Stderr, "admin: %v\n", err)
+		return exitUnhealthy
+	}
+	return exitOK
+}
+
+// parseWeights parses factor=weight arguments
+func parseWeights(args []string) (map[string]float64, error) {
+	weights := make(map[string]float64, len(args))
+	for _, arg := range args {
+		factor, value, ok := strings.Cut(arg, "=")
+		if !ok || factor == "" {
+			return nil, fmt.Errorf("%q is not factor=weight", arg)
+		}
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("weight of %s must be a non-negative number, not %q", factor, value)
+		}
+		weights[factor] = w
+	}
+	return weights, nil
+}
+
+// printWeights prints weights by factor, with the previous weight when
+// previous is non-nil
+func printWeights(weights, previous map[string]float64) {
+	factors := make([]string, 0, len(weights))
+	for f := range weights {
+		factors = append(factors, f)
+	}
+	sort.Strings(factors)
+	for _, f := range factors {
+		if previous != nil {
+			fmt.Printf("%-22s %.3f (was %.3f)\n", f, weights[f], previous[f])
+		} else {
+			fmt.Printf("%-22s %.3f\n", f, weights[f])
+		}
+	}
+}
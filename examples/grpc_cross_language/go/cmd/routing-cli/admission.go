@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"example.com/ipfs_kit_py/executor"
+	"example.com/ipfs_kit_py/mcpclient"
+)
+
+// admissionFlags are the checks a command moving content makes before
+// each upload starts
+type admissionFlags struct {
+	enabled  bool
+	headroom rateFlag
+	mcpURL   string
+}
+
+// register adds the -admit, -min-headroom and -bucket-quotas flags to fs
+func (a *admissionFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&a.enabled, "admit", false, "check each upload against its target's quota, its bucket's quota and the disk it is staged on before it starts, rejecting it rather than failing midway")
+	fs.Var(&a.headroom, "min-headroom", "with -admit, disk space that must stay free where backends that stage uploads locally stage them, e.g. 10GiB")
+	fs.StringVar(&a.mcpURL, "bucket-quotas", "", "with -admit, MCP dashboard base URL to read bucket quotas from, using $MCP_API_TOKEN (empty to skip)")
+}
+
+// apply enables admission control on registry if -admit is set
+func (a *admissionFlags) apply(registry *executor.Registry) {
+	if !a.enabled {
+		return
+	}
+	admission := &executor.Admission{MinHeadroom: int64(a.headroom)}
+	if a.mcpURL != "" {
+		admission.BucketQuota = executor.MCPBucketQuota(mcpclient.NewClient(a.mcpURL, os.Getenv("MCP_API_TOKEN")))
+	}
+	registry.SetAdmission(admission)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"example.com/ipfs_kit_py/export"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// runBackends implements `routing-cli backends <subcommand>`
+func runBackends(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprintf(os.Stderr, "Usage: routing-cli backends list [flags]\n       routing-cli backends stats [flags] <backend>\n")
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	switch args[0] {
+	case "list":
+		return runBackendsList(args[1:])
+	case "stats":
+		return runBackendsStats(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "backends: unknown subcommand %q\n", args[0])
+	return exitUsage
+}
+
+// runBackendsList implements `routing-cli backends list`, printing the
+// registered backends and their capabilities
+func runBackendsList(args []string) int {
+	fs := flag.NewFlagSet("backends list", flag.ContinueOnError)
+	server := fs.String("server", "localhost:50051", "gRPC server address: host:port, unix:///path, or a gRPC-Web gateway URL")
+	contentType := fs.String("content-type", "", "only list backends accepting this MIME type")
+	size := fs.Int64("size", 0, "only list backends accepting objects of this many bytes")
+	timeout := fs.Duration("timeout", 10*time.Second, "deadline for the call")
+	jsonOut := fs.Bool("json", false, "print the backends as JSON")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if !checkOutput(output, *jsonOut) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	client, err := newClient(*server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backends: %v\n", err)
+		return exitUnreachable
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	backends, err := client.ListBackends(ctx, routingclient.ContentInfo{ContentType: *contentType, ContentSize: *size})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backends: %v\n", err)
+		return exitUnreachable
+	}
+
+	if *output != formatText {
+		records := protoRecords(backends)
+		if err := printStructured(*output, records, records); err != nil {
+			fmt.Fprintf(os.Stderr, "backends: %v\n", err)
+			return exitUnhealthy
+		}
+		return exitOK
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BACKEND\tSTATE\tREGION\tPRICING\tMAX SIZE\tCONTENT TYPES")
+	for _, b := range backends {
+		types := strings.Join(b.ContentTypes, ",")
+		if types == "" {
+			types = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", b.BackendId,
+			enumLabel(b.State.String(), "BACKEND_STATE_"),
+			b.Region,
+			enumLabel(b.PricingClass.String(), "PRICING_CLASS_"),
+			sizeLabel(b.MaxObjectSize),
+			types)
+	}
+	tw.Flush()
+	return exitOK
+}
+
+// runBackendsStats implements `routing-cli backends stats <backend>`,
+// printing one backend's success rate, latency, throughput and errors, and
+// the transfer statistics of its node when one reports them
+func runBackendsStats(args []string) int {
+	fs := flag.NewFlagSet("backends stats", flag.ContinueOnError)
+	server := fs.String("server", "localhost:50051", "gRPC server address: host:port, unix:///path, or a gRPC-Web gateway URL")
+	window := fs.Duration("window", time.Hour, "window to summarise, in whole minutes")
+	timeout := fs.Duration("timeout", 10*time.Second, "deadline for the call")
+	jsonOut := fs.Bool("json", false, "print the statistics as JSON")
+	output := outputFlag(fs, export.FormatArrow)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli backends stats [flags] <backend>\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 1 || !checkOutput(output, *jsonOut, export.FormatArrow) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	client, err := newClient(*server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backends: %v\n", err)
+		return exitUnreachable
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	stats, err := client.GetBackendStats(ctx, fs.Arg(0), *window)
+	if errors.Is(err, routingclient.ErrUnknownBackend) {
+		fmt.Fprintf(os.Stderr, "backends: unknown backend %q\n", fs.Arg(0))
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backends: %v\n", err)
+		return exitUnreachable
+	}
+
+	switch *output {
+	case formatJSON:
+		data, _ := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(stats)
+		fmt.Println(string(data))
+		return exitOK
+	case formatYAML, formatCSV:
+		if err := printStructured(*output, stats, stats); err != nil {
+			fmt.Fprintf(os.Stderr, "backends: %v\n", err)
+			return exitUnhealthy
+		}
+		return exitOK
+	case export.FormatArrow:
+		if err := export.WriteBackendStats(os.Stdout, stats); err != nil {
+			fmt.Fprintf(os.Stderr, "backends: %v\n", err)
+			return exitUnhealthy
+		}
+		return exitOK
+	}
+	lat := stats.GetLatency()
+	fmt.Printf("backend      %s (%s)\n", stats.BackendId, enumLabel(stats.State.String(), "BACKEND_STATE_"))
+	fmt.Printf("window       %dm\n", stats.WindowMinutes)
+	fmt.Printf("requests     %d (%.1f/min)\n", stats.Requests, stats.RequestsPerMinute)
+	fmt.Printf("success      %d (%.1f%%)\n", stats.Successes, 100*stats.SuccessRate)
+	fmt.Printf("latency      p50 %.0fms  p90 %.0fms  p99 %.0fms  max %.0fms  mean %.0fms\n",
+		lat.GetP50Ms(), lat.GetP90Ms(), lat.GetP99Ms(), lat.GetMaxMs(), lat.GetMeanMs())
+	fmt.Printf("throughput   %s/s\n", sizeLabel(int64(stats.ThroughputBytesPerSecond)))
+	if n := stats.GetNode(); n != nil {
+		fmt.Printf("node         %d peers, %d wanted, in %s, out %s\n", n.Peers, n.WantlistLength, rateLabel(n.RateInBytesPerSecond), rateLabel(n.RateOutBytesPerSecond))
+		if n.BlocksReceived > 0 {
+			fmt.Printf("             %d blocks received (%.1f%% duplicate), %d sent\n",
+				n.BlocksReceived, 100*float64(n.DuplicateBlocksReceived)/float64(n.BlocksReceived), n.BlocksSent)
+		}
+	}
+	if len(stats.Errors) > 0 {
+		categories := make([]string, 0, len(stats.Errors))
+		for c := range stats.Errors {
+			categories = append(categories, c)
+		}
+		sort.Slice(categories, func(i, j int) bool { return stats.Errors[categories[i]] > stats.Errors[categories[j]] })
+		fmt.Println("errors")
+		for _, c := range categories {
+			fmt.Printf("  %-12s %d\n", c, stats.Errors[c])
+		}
+	}
+	return exitOK
+}
+
+// enumLabel turns an enum value name into a short lower-case label
+func enumLabel(name, prefix string) string {
+	label := strings.ToLower(strings.TrimPrefix(name, prefix))
+	if label == "unspecified" {
+		return "-"
+	}
+	return label
+}
+
+// rateLabel formats a transfer rate with a binary unit
+func rateLabel(bytesPerSecond float64) string {
+	if bytesPerSecond < 1 {
+		return "0 B/s"
+	}
+	return sizeLabel(int64(bytesPerSecond)) + "/s"
+}
+
+// sizeLabel formats a byte limit with a binary unit; 0 is unlimited
+func sizeLabel(n int64) string {
+	if n <= 0 {
+		return "unlimited"
+	}
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	v, i := float64(n), 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.4g %s", v, units[i])
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/ipfs_kit_py/progress"
+)
+
+// barWidth is the number of cells in a progress bar
+const barWidth = 30
+
+// progressBar draws the progress of transfers on one terminal line,
+// redrawn in place with the latest update; a finished transfer keeps its
+// line
+type progressBar struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// newProgressBar returns a bar drawing on stderr, or nil if it is not a
+// terminal, so redirected output is not filled with redraws
+func newProgressBar(enabled bool) *progressBar {
+	if !enabled || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return &progressBar{w: os.Stderr}
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+// update draws u; it is a progress.Func
+func (b *progressBar) update(u progress.Update) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	line := u.Name + " " + formatBytes(u.Done)
+	if f := u.Fraction(); f >= 0 {
+		cells := int(f * barWidth)
+		line = fmt.Sprintf("%s [%s%s] %3.0f%% %s/%s", u.Name,
+			strings.Repeat("=", cells), strings.Repeat(" ", barWidth-cells), f*100,
+			formatBytes(u.Done), formatBytes(u.Total))
+	}
+	line += "  " + formatBytes(int64(u.Rate)) + "/s"
+	switch {
+	case u.Err != nil:
+		line += "  failed"
+	case u.Finished:
+		line += "  in " + u.Elapsed.Round(time.Second).String()
+	case u.ETA > 0:
+		line += "  ETA " + u.ETA.Round(time.Second).String()
+	}
+	// Clear what is left of a longer previous line
+	fmt.Fprintf(b.w, "\r%s\033[K", line)
+	if u.Finished {
+		fmt.Fprintln(b.w)
+	}
+}
+
+// onProgress returns b's update, or nil for no bar
+func (b *progressBar) onProgress() progress.Func {
+	if b == nil {
+		return nil
+	}
+	return b.update
+}
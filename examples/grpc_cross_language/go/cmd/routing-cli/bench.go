@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"example.com/ipfs_kit_py/bench"
+)
+
+// runBench implements `routing-cli bench`: it reads the output of the
+// client's benchmark suite, run with `go test -bench . ./bench`,
+// optionally saving the results as a baseline, and with -compare reports
+// the benchmarks that regressed against an earlier baseline
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	input := fs.String("i", "-", "go test -bench output to read, - for stdin")
+	output := fs.String("o", "", "save the results to this file, as a baseline for -compare")
+	compare := fs.String("compare", "", "baseline file to compare the results against")
+	threshold := fs.Float64("threshold", bench.DefaultThreshold, "slowdown, as a fraction, reported as a regression")
+	jsonOut := fs.Bool("json", false, "print the results, or the comparison, as JSON")
+	format := outputFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: go test -run '^$' -bench . ./bench | routing-cli bench [flags]\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 0 || !checkOutput(format, *jsonOut) {
+		fs.Usage()
+		return exitUsage
+	}
+	var baseline bench.Report
+	if *compare != "" {
+		data, err := os.ReadFile(*compare)
+		if err == nil {
+			err = json.Unmarshal(data, &baseline)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "bench: baseline: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	var in io.Reader = os.Stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+			return exitUsage
+		}
+		defer f.Close()
+		in = f
+	}
+	report, err := bench.Parse(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+		return exitUsage
+	}
+	if *output != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		if err := os.WriteFile(*output, append(data, '\n'), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+			return exitUnhealthy
+		}
+	}
+
+	if *compare == "" {
+		failed := false
+		for _, r := range report.Results {
+			failed = failed || r.Failed
+		}
+		if *format != formatText {
+			if err := printStructured(*format, report, report.Results); err != nil {
+				fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+				return exitUnhealthy
+			}
+		} else {
+			printBenchResults(report.Results)
+		}
+		if failed {
+			return exitUnhealthy
+		}
+		return exitOK
+	}
+
+	changes := bench.Compare(baseline, report, *threshold)
+	regressed := 0
+	for _, c := range changes {
+		if c.Regressed {
+			regressed++
+		}
+	}
+	if *format != formatText {
+		if err := printStructured(*format, changes, changes); err != nil {
+			fmt.Fprintf(os.Stderr, "bench: %v\n", err)
+			return exitUnhealthy
+		}
+	} else {
+		printBenchChanges(changes)
+	}
+	if baseline.CPU != report.CPU || baseline.GOARCH != report.GOARCH || baseline.CPUs != report.CPUs {
+		fmt.Fprintf(os.Stderr, "bench: baseline ran on %s/%s %q with %d CPUs, this run on %s/%s %q with %d; differences may not be the client's\n",
+			baseline.GOOS, baseline.GOARCH, baseline.CPU, baseline.CPUs, report.GOOS, report.GOARCH, report.CPU, report.CPUs)
+	}
+	if regressed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d benchmarks regressed\n", regressed, len(changes))
+		return exitUnhealthy
+	}
+	return exitOK
+}
+
+// printBenchResults prints benchmark results as a table
+func printBenchResults(results []bench.Result) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "BENCHMARK\tN\tNS/OP\tMB/S\tB/OP\tALLOCS/OP")
+	for _, r := range results {
+		if r.Failed {
+			fmt.Fprintf(tw, "%s\tfailed\t\t\t\t\n", r.Name)
+			continue
+		}
+		mbps := "-"
+		if r.MBPerSec > 0 {
+			mbps = fmt.Sprintf("%.1f", r.MBPerSec)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.0f\t%s\t%d\t%d\n", r.Name, r.N, r.NsPerOp, mbps, r.BytesPerOp, r.AllocsPerOp)
+	}
+	tw.Flush()
+}
+
+// printBenchChanges prints a comparison against a baseline as a table
+func printBenchChanges(changes []bench.Change) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "BENCHMARK\tOLD NS/OP\tNEW NS/OP\tDELTA\tALLOCS/OP\t")
+	for _, c := range changes {
+		mark := ""
+		if c.Regressed {
+			mark = "REGRESSED"
+		}
+		switch {
+		case c.Failed:
+			fmt.Fprintf(tw, "%s\t%.0f\t-\t-\t-\tFAILED\n", c.Name, c.Old)
+		case c.Old == 0:
+			fmt.Fprintf(tw, "%s\t-\t%.0f\tnew\t%d\t\n", c.Name, c.New, c.NewAllocs)
+		default:
+			fmt.Fprintf(tw, "%s\t%.0f\t%.0f\t%+.1f%%\t%d -> %d\t%s\n", c.Name, c.Old, c.New, c.Delta*100, c.OldAllocs, c.NewAllocs, mark)
+		}
+	}
+	tw.Flush()
+}
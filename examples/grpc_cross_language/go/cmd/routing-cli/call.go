@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// runCall implements `routing-cli call`: it discovers the server's RPCs
+// through gRPC server reflection and calls them with JSON requests, so
+// methods added after this binary was built can still be used.
+//
+//	routing-cli call -list
+//	routing-cli call -describe ipfs_kit_py.routing.RoutingService
+//	routing-cli call ipfs_kit_py.routing.RoutingService/GetInsights '{"time_window_hours": 6}'
+//
+// A request of "-" is read from stdin; an omitted one is empty.
+func runCall(args []string) int {
+	fs := flag.NewFlagSet("call", flag.ContinueOnError)
+	server := fs.String("server", "localhost:50051", "gRPC server address: host:port or unix:///path")
+	list := fs.Bool("list", false, "list services and their methods")
+	describe := fs.String("describe", "", "print the methods of a service, or the request and response fields of a method")
+	timeout := fs.Duration("timeout", 30*time.Second, "deadline for the call, including reflection")
+	compact := fs.Bool("compact", false, "print one response per line instead of indented JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli call [flags] [pkg.Service/Method [json|-]]\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if !*list && *describe == "" && fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	conn, err := routingclient.Dial(*server, grpc.WithTransportCredentials(routingclient.TransportCredentials(*server)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "call: %v\n", err)
+		return exitUnreachable
+	}
+	defer conn.Close()
+	dc := routingclient.NewDynamicClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch {
+	case *list:
+		err = listServices(ctx, dc)
+	case *describe != "":
+		err = describeSymbol(ctx, dc, *describe)
+	default:
+		err = callMethod(ctx, dc, fs.Arg(0), fs.Arg(1), *compact)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "call: %v\n", err)
+		return exitUnhealthy
+	}
+	return exitOK
+}
+
+func listServices(ctx context.Context, dc *routingclient.DynamicClient) error {
+	names, err := dc.Services(ctx)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		fmt.Println(name)
+		if strings.HasPrefix(name, "grpc.reflection.") {
+			continue
+		}
+		sd, err := dc.Service(ctx, name)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < sd.Methods().Len(); i++ {
+			fmt.Printf("  %s\n", methodSignature(sd.Methods().Get(i)))
+		}
+	}
+	return nil
+}
+
+func describeSymbol(ctx context.Context, dc *routingclient.DynamicClient, name string) error {
+	if sd, err := dc.Service(ctx, name); err == nil {
+		fmt.Printf("service %s\n", sd.FullName())
+		for i := 0; i < sd.Methods().Len(); i++ {
+			fmt.Printf("  %s\n", methodSignature(sd.Methods().Get(i)))
+		}
+		return nil
+	}
+	md, err := dc.Method(ctx, name)
+	if err != nil {
+		return err
+	}
+	fmt.Println(methodSignature(md))
+	printFields("request", md.Input())
+	printFields("response", md.Output())
+	return nil
+}
+
+func methodSignature(md protoreflect.MethodDescriptor) string {
+	in, out := string(md.Input().FullName()), string(md.Output().FullName())
+	if md.IsStreamingClient() {
+		in = "stream " + in
+	}
+	if md.IsStreamingServer() {
+		out = "stream " + out
+	}
+	return fmt.Sprintf("rpc %s(%s) returns (%s)", md.Name(), in, out)
+}
+
+func printFields(label string, msg protoreflect.MessageDescriptor) {
+	fmt.Printf("%s %s {\n", label, msg.FullName())
+	for i := 0; i < msg.Fields().Len(); i++ {
+		f := msg.Fields().Get(i)
+		typ := f.Kind().String()
+		switch {
+		case f.IsMap():
+			typ = fmt.Sprintf("map<%s, %s>", f.MapKey().Kind(), f.MapValue().Kind())
+		case f.Message() != nil:
+			typ = string(f.Message().FullName())
+		case f.Enum() != nil:
+			typ = string(f.Enum().FullName())
+		}
+		if f.IsList() {
+			typ = "repeated " + typ
+		}
+		fmt.Printf("  %s %s = %d;\n", typ, f.JSONName(), f.Number())
+	}
+	fmt.Println("}")
+}
+
+func callMethod(ctx context.Context, dc *routingclient.DynamicClient, method, request string, compact bool) error {
+	in := []byte(request)
+	if request == "-" {
+		var err error
+		if in, err = io.ReadAll(os.Stdin); err != nil {
+			return err
+		}
+	}
+	return dc.Call(ctx, method, in, func(out []byte) error {
+		if !compact {
+			var buf bytes.Buffer
+			if err := json.Indent(&buf, out, "", "  "); err == nil {
+				out = buf.Bytes()
+			}
+		}
+		_, err := fmt.Println(string(out))
+		return err
+	})
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// runCID implements `routing-cli cid`, describing a CID and converting it
+// between versions and multibases
+func runCID(args []string) int {
+	fs := flag.NewFlagSet("cid", flag.ContinueOnError)
+	to := fs.String("to", "", "convert to CID version v0 or v1")
+	base := fs.String("base", "", "encode in this multibase (base32, base58btc, base36, base16, base64, ...); implies v1")
+	jsonOut := fs.Bool("json", false, "print the description as JSON")
+	output := outputFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli cid [flags] <cid>...\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() == 0 || !checkOutput(output, *jsonOut) {
+		fs.Usage()
+		return exitUsage
+	}
+	var mb cidutil.Multibase
+	if *base != "" {
+		var err error
+		if mb, err = cidutil.ParseMultibase(*base); err != nil {
+			fmt.Fprintf(os.Stderr, "cid: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	code := exitOK
+	// CSV has a row per CID, so it is printed once all are described;
+	// the other formats print one document per CID
+	var rows []map[string]interface{}
+	printed := 0
+	for _, arg := range fs.Args() {
+		c, err := cidutil.ParseCID(arg)
+		if err == nil {
+			err = cidutil.ValidateCID(arg)
+		}
+		if err == nil {
+			c, err = convertCID(c, *to, *base != "")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cid: %v\n", err)
+			code = exitUsage
+			continue
+		}
+		if *output == formatText && *to == "" && *base == "" {
+			describeCID(c)
+			continue
+		}
+		s := c.String()
+		if *base != "" {
+			if s, err = c.Encode(mb); err != nil {
+				fmt.Fprintf(os.Stderr, "cid: %v\n", err)
+				code = exitUsage
+				continue
+			}
+		}
+		if *output == formatText {
+			fmt.Println(s)
+			continue
+		}
+		_, digest, _ := cidutil.DecodeMultihash(c.Hash)
+		desc := map[string]interface{}{
+			"cid":       s,
+			"version":   c.Version,
+			"codec":     cidutil.CodecName(c.Codec),
+			"hash":      cidutil.HashName(c.Hash.Code()),
+			"digest":    hex.EncodeToString(digest),
+			"multihash": c.Hash.B58String(),
+		}
+		switch {
+		case *output == formatCSV:
+			rows = append(rows, desc)
+			continue
+		case *output == formatYAML && printed > 0:
+			fmt.Println("---")
+		}
+		if err := printStructured(*output, desc, nil); err != nil {
+			fmt.Fprintf(os.Stderr, "cid: %v\n", err)
+			code = exitUnhealthy
+		}
+		printed++
+	}
+	if len(rows) > 0 {
+		if err := printStructured(formatCSV, nil, rows); err != nil {
+			fmt.Fprintf(os.Stderr, "cid: %v\n", err)
+			code = exitUnhealthy
+		}
+	}
+	return code
+}
+
+// convertCID applies -to; a multibase other than base58btc needs v1
+func convertCID(c cidutil.CID, to string, rebase bool) (cidutil.CID, error) {
+	switch to {
+	case "":
+		if rebase {
+			return c.ToV1(), nil
+		}
+		return c, nil
+	case "v1":
+		return c.ToV1(), nil
+	case "v0":
+		return c.ToV0()
+	}
+	return c, fmt.Errorf("-to must be v0 or v1, not %q", to)
+}
+
+func describeCID(c cidutil.CID) {
+	_, digest, _ := cidutil.DecodeMultihash(c.Hash)
+	fmt.Printf("%s\n  version:   %d\n  codec:     %s\n  hash:      %s\n  digest:    %x\n", c, c.Version, cidutil.CodecName(c.Codec), cidutil.HashName(c.Hash.Code()), digest)
+	if c.Version == 0 {
+		fmt.Printf("  v1:        %s\n", c.ToV1())
+	} else if v0, err := c.ToV0(); err == nil {
+		fmt.Printf("  v0:        %s\n", v0)
+	}
+}
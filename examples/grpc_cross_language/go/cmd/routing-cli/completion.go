@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// completeCommand is the hidden command the completion scripts call with
+// the words typed so far, the one being completed last; it prints the
+// candidates for that word, one per line, or nothing to have the shell
+// complete file names
+const completeCommand = "__complete"
+
+// completeTimeout bounds fetching backend IDs from the server, so a
+// server that is down does not hang the shell
+const completeTimeout = time.Second
+
+// completionScripts are the completion scripts for each shell. Each passes
+// the words before the cursor, and the one being completed, to
+// routing-cli __complete.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for routing-cli
+# Load with: source <(routing-cli completion bash)
+_routing_cli() {
+    local IFS=$'\n'
+    COMPREPLY=($("${COMP_WORDS[0]}" __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _routing_cli routing-cli
+`,
+	"zsh": `#compdef routing-cli
+# zsh completion for routing-cli
+# Load with: source <(routing-cli completion zsh)
+_routing_cli() {
+    local -a candidates
+    candidates=("${(@f)$(${words[1]} __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ -z ${candidates[1]} ]]; then
+        _files
+    else
+        compadd -a candidates
+    fi
+}
+if [[ $funcstack[1] == _routing_cli ]]; then
+    _routing_cli "$@"
+else
+    compdef _routing_cli routing-cli
+fi
+`,
+	"fish": `# fish completion for routing-cli
+# Load with: routing-cli completion fish | source
+function __routing_cli_complete
+    set -l words (commandline -opc)
+    set -l out (routing-cli __complete $words[2..-1] (commandline -ct | string collect --allow-empty) 2>/dev/null)
+    if test (count $out) -eq 0
+        __fish_complete_path (commandline -ct)
+    else
+        printf '%s\n' $out
+    end
+end
+complete -c routing-cli -f -a '(__routing_cli_complete)'
+`,
+	"powershell": `# PowerShell completion for routing-cli
+# Load with: routing-cli completion powershell | Out-String | Invoke-Expression
+Register-ArgumentCompleter -Native -CommandName routing-cli -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 |
+        Where-Object { $_.Extent.EndOffset -le $cursorPosition } | ForEach-Object { $_.ToString() })
+    if ($wordToComplete -eq '') { $words += '""' }
+    & routing-cli __complete @words 2>$null | Where-Object { $_ } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`,
+}
+
+// runCompletion implements `routing-cli completion <shell>`
+func runCompletion(args []string) int {
+	fs := flag.NewFlagSet("completion", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli completion bash|zsh|fish|powershell\n\n"+
+			"Prints a script completing commands, subcommands, flags, strategies, and\n"+
+			"backend IDs, which are fetched from the -server given on the command line\n"+
+			"(default localhost:50051).\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	script, ok := completionScripts[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "completion: unknown shell %q\n", fs.Arg(0))
+		return exitUsage
+	}
+	fmt.Print(script)
+	return exitOK
+}
+
+// runComplete implements the hidden __complete command
+func runComplete(words []string) int {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	// PowerShell 5 drops empty arguments, so its script passes "" quoted
+	if words[len(words)-1] == `""` {
+		words[len(words)-1] = ""
+	}
+	for _, c := range complete(words) {
+		fmt.Println(c)
+	}
+	return exitOK
+}
+
+// complete returns the candidates for the last of words, the arguments
+// typed after routing-cli
+func complete(words []string) []string {
+	cur := words[len(words)-1]
+	prev := words[:len(words)-1]
+
+	// global flags come before the command
+	global := flag.NewFlagSet("routing-cli", flag.ContinueOnError)
+	globalFlags(global)
+	i := 0
+	for ; i < len(prev) && strings.HasPrefix(prev[i], "-"); i++ {
+		if f := global.Lookup(flagName(prev[i])); f != nil && !strings.Contains(prev[i], "=") {
+			i++ // skip the value
+		}
+	}
+	if i >= len(prev) {
+		if i > len(prev) {
+			return nil // a global flag's file name
+		}
+		if strings.HasPrefix(cur, "-") {
+			return matching(cur, dashed(cur, flagNames(global)))
+		}
+		var names []string
+		for _, c := range commands {
+			names = append(names, c.name)
+		}
+		return matching(cur, names)
+	}
+
+	name, args := prev[i], prev[i+1:]
+	if name == "completion" {
+		if len(args) == 0 {
+			return matching(cur, []string{"bash", "fish", "powershell", "zsh"})
+		}
+		return nil
+	}
+	var run func([]string) int
+	for _, c := range commands {
+		if c.name == name {
+			run = c.run
+		}
+	}
+	if run == nil {
+		return nil
+	}
+
+	// commands with subcommands list them in their usage, as admin does
+	// its subcommands' own
+	var sub []string
+	usage := commandHelp(run, nil)
+	help := usage
+	for {
+		subs := subcommands(append([]string{name}, sub...), usage)
+		if len(subs) == 0 {
+			break
+		}
+		if len(args) == 0 {
+			return matching(cur, subs)
+		}
+		sub = append(sub, args[0])
+		args = args[1:]
+		help = commandHelp(run, sub)
+	}
+	flags := parseFlags(help)
+
+	// the value of a flag, given after it or after =
+	if flagArg, value, ok := strings.Cut(cur, "="); ok && strings.HasPrefix(flagArg, "-") {
+		values := flagValues(flagName(flagArg), words)
+		for i, v := range values {
+			values[i] = flagArg + "=" + v
+		}
+		return matching(flagArg+"="+value, values)
+	}
+	if n := len(args); n > 0 && strings.HasPrefix(args[n-1], "-") && !strings.Contains(args[n-1], "=") {
+		if takesValue, known := flags[flagName(args[n-1])]; known && takesValue {
+			return matching(cur, flagValues(flagName(args[n-1]), words))
+		}
+	}
+	if strings.HasPrefix(cur, "-") {
+		var names []string
+		for f := range flags {
+			names = append(names, f)
+		}
+		sort.Strings(names)
+		return matching(cur, dashed(cur, names))
+	}
+	// backends stats takes a backend ID
+	if name == "backends" && len(sub) == 1 && sub[0] == "stats" {
+		return matching(cur, backendIDs(words))
+	}
+	return nil
+}
+
+// flagName returns the name of the flag in arg, e.g. "server" for
+// "--server=x"
+func flagName(arg string) string {
+	name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	return name
+}
+
+// flagNames returns the names of fs's flags
+func flagNames(fs *flag.FlagSet) []string {
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	return names
+}
+
+// dashed spells flag names with the dashes cur starts with
+func dashed(cur string, names []string) []string {
+	dashes := "-"
+	if strings.HasPrefix(cur, "--") {
+		dashes = "--"
+	}
+	out := make([]string, len(names))
+	for i, name := range names {
+		out[i] = dashes + name
+	}
+	return out
+}
+
+// matching returns the candidates starting with prefix
+func matching(prefix string, candidates []string) []string {
+	out := []string{}
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// commandHelp returns the help a command prints for -h after args
+func commandHelp(run func([]string) int, args []string) string {
+	stdout, stderr := os.Stdout, os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		return ""
+	}
+	os.Stdout, os.Stderr = w, w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	run(append(append([]string{}, args...), "-h"))
+	os.Stdout, os.Stderr = stdout, stderr
+	w.Close()
+	return string(<-done)
+}
+
+// subcommands returns the subcommands listed after path, a command and
+// the subcommands already given, in its usage
+func subcommands(path []string, help string) []string {
+	pattern := regexp.MustCompile(`routing-cli ` + regexp.QuoteMeta(strings.Join(path, " ")) + ` ([a-z][a-z-]*)`)
+	var subs []string
+	seen := make(map[string]bool)
+	for _, m := range pattern.FindAllStringSubmatch(help, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			subs = append(subs, m[1])
+		}
+	}
+	return subs
+}
+
+// flagPattern matches a flag in flag.PrintDefaults output: its name and,
+// unless it is a boolean, the name of its value
+var flagPattern = regexp.MustCompile(`^  -(\S+)( \S+)?`)
+
+// parseFlags returns the flags listed in help, and whether each takes a
+// value
+func parseFlags(help string) map[string]bool {
+	flags := make(map[string]bool)
+	sc := bufio.NewScanner(strings.NewReader(help))
+	for sc.Scan() {
+		if m := flagPattern.FindStringSubmatch(sc.Text()); m != nil {
+			flags[m[1]] = m[2] != ""
+		}
+	}
+	return flags
+}
+
+// flagValues returns the values the flag name can take, where they can be
+// listed
+func flagValues(name string, words []string) []string {
+	switch name {
+	case "strategy":
+		return strategyNames()
+	case "backend", "target":
+		return backendIDs(words)
+	}
+	return nil
+}
+
+// strategyNames returns the routing strategies' names, e.g. "cost"
+func strategyNames() []string {
+	var names []string
+	for v := range pb.RoutingStrategy_name {
+		if name := routingclient.StrategyName(pb.RoutingStrategy(v)); name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// backendIDs returns the IDs of the backends of the server named by -server
+// in words, or the default server
+func backendIDs(words []string) []string {
+	server := "localhost:50051"
+	for i, w := range words {
+		if flagName(w) != "server" || !strings.HasPrefix(w, "-") {
+			continue
+		}
+		if _, v, ok := strings.Cut(w, "="); ok {
+			server = v
+		} else if i+1 < len(words)-1 {
+			server = words[i+1]
+		}
+	}
+	client, err := routingclient.New(server)
+	if err != nil {
+		return nil
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), completeTimeout)
+	defer cancel()
+	backends, err := client.ListBackends(ctx, routingclient.ContentInfo{})
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, b := range backends {
+		ids = append(ids, b.BackendId)
+	}
+	sort.Strings(ids)
+	return ids
+}
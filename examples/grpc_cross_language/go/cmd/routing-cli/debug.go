@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"example.com/ipfs_kit_py/diag"
+)
+
+// debugState is what the global --debug-addr listener reports, told of
+// every client newClient creates
+var debugState *diag.Diagnostics
+
+// serveDebug starts serving pprof, expvar and the routing snapshot on
+// addr, if set; the returned function stops serving them
+func serveDebug(addr string) (func(), error) {
+	if addr == "" {
+		return func() {}, nil
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	debugState = diag.New()
+	srv := &http.Server{Handler: debugState.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln)
+	return func() { srv.Close() }, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"example.com/ipfs_kit_py/cidutil"
+	"example.com/ipfs_kit_py/kubo"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// runDHT implements `routing-cli dht <subcommand>`
+func runDHT(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprintf(os.Stderr, "Usage: routing-cli dht provide [flags] <cid>\n       routing-cli dht findprovs [flags] <cid>\n")
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	switch args[0] {
+	case "provide":
+		return runDHTProvide(args[1:])
+	case "findprovs":
+		return runDHTFindProvs(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "dht: unknown subcommand %q\n", args[0])
+	return exitUsage
+}
+
+// parseCIDArg parses the flags and single CID argument of a dht
+// subcommand; if that fails it returns false and the exit code
+func parseCIDArg(fs *flag.FlagSet, args []string) (string, int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return "", exitOK, false
+		}
+		return "", exitUsage, false
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", exitUsage, false
+	}
+	if _, err := cidutil.ParseCID(fs.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "dht: %v\n", err)
+		return "", exitUsage, false
+	}
+	return fs.Arg(0), exitOK, true
+}
+
+// runDHTProvide implements `routing-cli dht provide`, announcing that the
+// local node provides a CID it holds, typically a routed upload
+func runDHTProvide(args []string) int {
+	fs := flag.NewFlagSet("dht provide", flag.ContinueOnError)
+	apiURL := fs.String("ipfs-api", "", "Kubo RPC API URL (default $IPFS_API_URL or "+kubo.DefaultAPIURL+")")
+	recursive := fs.Bool("recursive", true, "also announce every block the CID links to")
+	timeout := fs.Duration("timeout", 2*time.Minute, "deadline for the announcement")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli dht provide [flags] <cid>\n\n")
+		fs.PrintDefaults()
+	}
+	cid, code, ok := parseCIDArg(fs, args)
+	if !ok {
+		return code
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	start := time.Now()
+	if err := kubo.NewClient(*apiURL).Provide(ctx, cid, *recursive); err != nil {
+		fmt.Fprintf(os.Stderr, "dht: provide: %v\n", err)
+		return exitUnhealthy
+	}
+	fmt.Printf("announced %s in %s\n", cid, time.Since(start).Round(time.Millisecond))
+	return exitOK
+}
+
+// runDHTFindProvs implements `routing-cli dht findprovs`, listing the peers
+// providing a CID. With -server the count is sent with a dry-run
+// selection, showing where the router would read the content from.
+func runDHTFindProvs(args []string) int {
+	fs := flag.NewFlagSet("dht findprovs", flag.ContinueOnError)
+	apiURL := fs.String("ipfs-api", "", "Kubo RPC API URL (default $IPFS_API_URL or "+kubo.DefaultAPIURL+")")
+	limit := fs.Int("n", kubo.DefaultProviderLimit, "stop after finding this many providers")
+	server := fs.String("server", "", "gRPC routing server to ask where to read the content from (empty to skip)")
+	strategy := fs.String("strategy", "hybrid", "routing strategy for -server")
+	timeout := fs.Duration("timeout", time.Minute, "deadline for the lookup")
+	jsonOut := fs.Bool("json", false, "print the providers as JSON")
+	output := outputFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli dht findprovs [flags] <cid>\n\n")
+		fs.PrintDefaults()
+	}
+	cid, code, ok := parseCIDArg(fs, args)
+	if !ok {
+		return code
+	}
+	if !checkOutput(output, *jsonOut) {
+		fs.Usage()
+		return exitUsage
+	}
+	s, err := routingclient.ParseStrategy(*strategy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dht: %v\n", err)
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	providers, err := kubo.NewClient(*apiURL).FindProviders(ctx, cid, *limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dht: findprovs: %v\n", err)
+		return exitUnhealthy
+	}
+	if *output != formatText {
+		if err := printStructured(*output, providers, providers); err != nil {
+			fmt.Fprintf(os.Stderr, "dht: %v\n", err)
+			return exitUnhealthy
+		}
+	} else {
+		for _, p := range providers {
+			fmt.Printf("%s\t%d addresses\n", p.ID, len(p.Addrs))
+		}
+		fmt.Fprintf(os.Stderr, "%d providers\n", len(providers))
+	}
+	if *server == "" {
+		return exitOK
+	}
+
+	client, err := newClient(*server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dht: %v\n", err)
+		return exitUnreachable
+	}
+	defer client.Close()
+	info := routingclient.ContentInfo{Providers: &routingclient.Providers{Count: len(providers), Limit: *limit}}
+	resp, err := client.DryRun(ctx, info, s)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dht: select: %v\n", err)
+		return exitUnreachable
+	}
+	fmt.Fprintf(os.Stderr, "read from %s: %s\n", resp.BackendId, resp.Reasoning)
+	return exitOK
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"example.com/ipfs_kit_py/cachering"
+	"example.com/ipfs_kit_py/cidutil"
+	"example.com/ipfs_kit_py/kgclient"
+	"example.com/ipfs_kit_py/kubo"
+	"example.com/ipfs_kit_py/routingclient"
+	"example.com/ipfs_kit_py/unixfs"
+)
+
+// e2eStep is the outcome of one stage of the end-to-end pipeline
+type e2eStep struct {
+	Name     string  `json:"name"`
+	OK       bool    `json:"ok"`
+	Detail   string  `json:"detail,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_ms"`
+}
+
+// runE2E implements `routing-cli e2e`: it routes a piece of generated
+// content, uploads and pins it in Kubo, optionally announces it on the DHT
+// and publishes an IPNS name for it, links it into the knowledge graph,
+// reads it back through a gateway (resolving the name, if published) and
+// reports the outcome to the router.
+// It exits with exitUnhealthy if any stage fails.
+func runE2E(args []string) int {
+	fs := flag.NewFlagSet("e2e", flag.ContinueOnError)
+	server := fs.String("server", "", "gRPC routing server address: host:port, unix:///path, or a gRPC-Web gateway URL")
+	routingHTTP := fs.String("routing-http", "", "HTTP routing API base URL, used when -server is empty")
+	apiURL := fs.String("ipfs-api", "", "Kubo RPC API URL (default $IPFS_API_URL or "+kubo.DefaultAPIURL+")")
+	gateway := fs.String("gateway", "http://127.0.0.1:8080", "IPFS gateway used to verify retrieval")
+	kgURL := fs.String("kg", kgclient.DefaultBaseURL, "knowledge graph API base URL (empty to skip)")
+	mfsDir := fs.String("mfs", "", "MFS directory to file the upload under, as bucket views do (empty to skip)")
+	ipnsKey := fs.String("ipns-key", "", "key to publish the upload's IPNS name under, created if missing; retrieval then resolves the name (empty to skip)")
+	provide := fs.Bool("provide", false, "announce the upload on the DHT after pinning it")
+	size := fs.Int("size", 64<<10, "size of the generated content in bytes")
+	timeout := fs.Duration("timeout", time.Minute, "deadline for the whole run")
+	jsonOut := fs.Bool("json", false, "print results as JSON")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if !checkOutput(output, *jsonOut) {
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	content := make([]byte, *size)
+	rand.Read(content)
+	sum := sha256.Sum256(content)
+	info := routingclient.ContentInfo{
+		ContentType: "application/octet-stream",
+		ContentSize: int64(len(content)),
+		ContentHash: hex.EncodeToString(sum[:]),
+		Filename:    fmt.Sprintf("e2e-%d.bin", time.Now().Unix()),
+		Metadata:    map[string]string{"source": "routing-cli-e2e"},
+	}
+
+	var router e2eRouter
+	switch {
+	case *server != "":
+		conn, err := routingclient.Dial(*server, grpc.WithTransportCredentials(routingclient.TransportCredentials(*server)))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "e2e: %v\n", err)
+			return exitUnreachable
+		}
+		conn = trafficConn(conn)
+		defer conn.Close()
+		router = grpcRouter{routingclient.NewClient(conn)}
+	case *routingHTTP != "":
+		router = httpRouter{strings.TrimRight(*routingHTTP, "/")}
+	}
+
+	var steps []e2eStep
+	failed := false
+	step := func(name string, fn func() (string, error)) {
+		if failed {
+			steps = append(steps, e2eStep{Name: name, Detail: "skipped"})
+			return
+		}
+		start := time.Now()
+		detail, err := fn()
+		s := e2eStep{Name: name, OK: err == nil, Detail: detail, Duration: float64(time.Since(start).Microseconds()) / 1000}
+		if err != nil {
+			s.Error = err.Error()
+			failed = true
+		}
+		steps = append(steps, s)
+	}
+
+	ipfs := kubo.NewClient(*apiURL)
+	var backendID, cid string
+	var uploadTime time.Duration
+	var verifyErr error
+	// ref is what the retrieve step reads: the CID, or its IPNS name
+	var ref string
+
+	if router != nil {
+		step("route", func() (string, error) {
+			var err error
+			backendID, err = router.selectBackend(ctx, info)
+			return backendID, err
+		})
+	}
+	// The CID Kubo should assign, built locally with the same options
+	local, localErr := unixfs.NewBuilder(1).File(bytes.NewReader(content))
+	step("upload", func() (string, error) {
+		start := time.Now()
+		res, err := ipfs.Add(ctx, bytes.NewReader(content), &kubo.AddOptions{CIDVersion: 1, RawLeaves: true, Pin: true})
+		uploadTime = time.Since(start)
+		if err != nil {
+			return "", err
+		}
+		cid, ref = res.Hash, res.Hash
+		if localErr == nil && cid != local.CID.String() {
+			return cid, fmt.Errorf("Kubo returned %s, local DAG build gives %s", cid, local.CID)
+		}
+		return cid, nil
+	})
+	step("pin", func() (string, error) {
+		var out struct {
+			Keys map[string]struct{ Type string } `json:"Keys"`
+		}
+		err := ipfs.Call(ctx, "pin/ls", url.Values{"arg": {cid}, "type": {"recursive"}}, nil, &out)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := out.Keys[cid]; !ok {
+			return "", fmt.Errorf("%s is not pinned", cid)
+		}
+		return "recursive", nil
+	})
+	if *provide {
+		step("provide", func() (string, error) {
+			if err := ipfs.Provide(ctx, cid, true); err != nil {
+				return "", err
+			}
+			providers, err := ipfs.FindProviders(ctx, cid, 1)
+			if err != nil {
+				return "", err
+			}
+			if len(providers) == 0 {
+				return "", fmt.Errorf("no providers found for %s after announcing it", cid)
+			}
+			return "announced", nil
+		})
+	}
+	if *mfsDir != "" {
+		step("mfs", func() (string, error) {
+			dst := path.Join(*mfsDir, info.Filename)
+			if err := ipfs.FilesCp(ctx, "/ipfs/"+cid, dst, true); err != nil {
+				return "", err
+			}
+			st, err := ipfs.FilesStat(ctx, dst)
+			if err != nil {
+				return "", err
+			}
+			if st.Hash != cid {
+				return "", fmt.Errorf("%s has CID %s, want %s", dst, st.Hash, cid)
+			}
+			return dst, nil
+		})
+	}
+	if *ipnsKey != "" {
+		step("publish", func() (string, error) {
+			if _, err := ipfs.EnsureKey(ctx, *ipnsKey); err != nil {
+				return "", err
+			}
+			entry, err := ipfs.NamePublish(ctx, "/ipfs/"+cid, &kubo.NamePublishOptions{Key: *ipnsKey, AllowOffline: true})
+			if err != nil {
+				return "", err
+			}
+			ref = "/ipns/" + entry.Name
+			return ref, nil
+		})
+	}
+	if *kgURL != "" {
+		kg := kgclient.NewClient(*kgURL)
+		kg.SetToken(os.Getenv(kgclient.TokenEnv))
+		step("link", func() (string, error) {
+			runID := "e2e-run:" + info.ContentHash[:16]
+			if _, err := kg.AddEntity(ctx, kgclient.Entity{ID: runID, Type: "e2e_run", Properties: map[string]interface{}{"backend": backendID}}); err != nil {
+				return "", err
+			}
+			contentID := "content:" + cid
+			if _, err := kg.AddEntity(ctx, kgclient.Entity{ID: contentID, Type: "content", Properties: map[string]interface{}{"cid": cid, "name": info.Filename}}); err != nil && !errors.Is(err, kgclient.ErrExists) {
+				return "", err
+			}
+			relID, _, err := kg.AddRelationship(ctx, kgclient.Relationship{From: runID, To: contentID, Type: "uploaded"})
+			if err != nil {
+				return "", err
+			}
+			neighbors, err := kg.Neighbors(ctx, runID, "uploaded", kgclient.Outgoing)
+			if err != nil {
+				return "", err
+			}
+			for _, n := range neighbors {
+				if n.EntityID == contentID {
+					return relID, nil
+				}
+			}
+			return "", fmt.Errorf("relationship %s not returned by neighbor query", relID)
+		})
+	}
+	step("retrieve", func() (string, error) {
+		f := &cachering.Fetcher{Ring: cachering.NewRing(0), Origin: *gateway, Verify: true}
+		f.Resolve = func(ctx context.Context, name string) (string, error) {
+			// The record was just published; a cached one may be stale
+			return ipfs.NameResolve(ctx, name, true)
+		}
+		resp, err := f.Get(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.Resolved != "" && resp.Resolved != cid {
+			return "", fmt.Errorf("%s resolved to %s, want %s", ref, resp.Resolved, cid)
+		}
+		got, err := io.ReadAll(resp.Body)
+		if err == nil {
+			err = routingclient.VerifyContent(info, cid, got)
+		}
+		if errors.Is(err, cidutil.ErrDigestMismatch) {
+			// Recorded against the backend below so corruption is learned from
+			verifyErr = err
+		}
+		if err != nil {
+			return "", err
+		}
+		if !bytes.Equal(got, content) {
+			return "", fmt.Errorf("retrieved %d bytes that do not match the %d uploaded", len(got), len(content))
+		}
+		return fmt.Sprintf("%d bytes from %s", len(got), resp.Node), nil
+	})
+	if router != nil && backendID != "" {
+		// Report the measured upload even if a later stage failed
+		failed = false
+		step("record-outcome", func() (string, error) {
+			outcome := routingclient.Outcome{BackendID: backendID, Success: cid != "" && verifyErr == nil, Duration: uploadTime, Err: verifyErr}
+			return outcome.ErrorClass(), router.recordOutcome(ctx, info, outcome)
+		})
+	}
+
+	code := exitOK
+	for _, s := range steps {
+		if !s.OK && s.Error != "" {
+			code = exitUnhealthy
+		}
+	}
+	if *output != formatText {
+		out := map[string]interface{}{"ok": code == exitOK, "cid": cid, "steps": steps}
+		if err := printStructured(*output, out, steps); err != nil {
+			fmt.Fprintf(os.Stderr, "e2e: %v\n", err)
+			return exitUnhealthy
+		}
+	} else {
+		for _, s := range steps {
+			status := "OK"
+			switch {
+			case s.Error != "":
+				status = "FAIL"
+			case !s.OK:
+				status = "SKIP"
+			}
+			line := fmt.Sprintf("%-16s %-5s %8.1fms  %s", s.Name, status, s.Duration, s.Detail)
+			if s.Error != "" {
+				line += s.Error
+			}
+			fmt.Println(strings.TrimRight(line, " "))
+		}
+	}
+	return code
+}
+
+// e2eRouter is the routing API exercised by the e2e pipeline, over gRPC or
+// the HTTP routing API
+type e2eRouter interface {
+	selectBackend(ctx context.Context, info routingclient.ContentInfo) (string, error)
+	recordOutcome(ctx context.Context, info routingclient.ContentInfo, outcome routingclient.Outcome) error
+}
+
+type grpcRouter struct {
+	client *routingclient.Client
+}
+
+func (r grpcRouter) selectBackend(ctx context.Context, info routingclient.ContentInfo) (string, error) {
+	resp, err := r.client.SelectBackend(ctx, info, "hybrid")
+	if err != nil {
+		return "", err
+	}
+	return resp.BackendId, nil
+}
+
+func (r grpcRouter) recordOutcome(ctx context.Context, info routingclient.ContentInfo, outcome routingclient.Outcome) error {
+	_, err := r.client.RecordOutcome(ctx, info, outcome)
+	return err
+}
+
+// httpRouter calls the HTTP routing API (ipfs_kit_py/routing/http_server.py)
+type httpRouter struct {
+	baseURL string
+}
+
+func (r httpRouter) post(ctx context.Context, path string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: HTTP %s: %s", path, resp.Status, bytes.TrimSpace(msg))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (r httpRouter) selectBackend(ctx context.Context, info routingclient.ContentInfo) (string, error) {
+	var out struct {
+		Backend string `json:"backend"`
+	}
+	body := map[string]interface{}{"content_type": info.ContentType, "content_size": info.ContentSize, "strategy": "hybrid"}
+	if err := r.post(ctx, "/api/v1/select-backend", body, &out); err != nil {
+		return "", err
+	}
+	return out.Backend, nil
+}
+
+func (r httpRouter) recordOutcome(ctx context.Context, info routingclient.ContentInfo, outcome routingclient.Outcome) error {
+	body := map[string]interface{}{
+		"backend":      outcome.BackendID,
+		"success":      outcome.Success,
+		"duration_ms":  outcome.Duration.Milliseconds(),
+		"content_type": info.ContentType,
+		"content_size": info.ContentSize,
+	}
+	if outcome.Err != nil {
+		body["error_message"] = outcome.Err.Error()
+	}
+	if class := outcome.ErrorClass(); class != "" {
+		body["error_class"] = class
+	}
+	var out struct {
+		Success bool `json:"success"`
+	}
+	return r.post(ctx, "/api/v1/record-outcome", body, &out)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"example.com/ipfs_kit_py/kubo"
+	"example.com/ipfs_kit_py/pubsub"
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// runEvents implements `routing-cli events <subcommand>`
+func runEvents(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprintf(os.Stderr, "Usage: routing-cli events watch [flags]\n       routing-cli events relay [flags]\n")
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	switch args[0] {
+	case "watch":
+		return runEventsWatch(args[1:])
+	case "relay":
+		return runEventsRelay(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "events: unknown subcommand %q\n", args[0])
+	return exitUsage
+}
+
+// runEventsWatch implements `routing-cli events watch`, printing the
+// routing events peers publish until interrupted
+func runEventsWatch(args []string) int {
+	fs := flag.NewFlagSet("events watch", flag.ContinueOnError)
+	apiURL := fs.String("ipfs-api", "", "Kubo RPC API URL (default $IPFS_API_URL or "+kubo.DefaultAPIURL+")")
+	namespace := fs.String("namespace", pubsub.DefaultNamespace, "deployment namespace of the topics")
+	kinds := fs.String("kind", "", "comma-separated kinds to watch: decisions, outcomes, backends (default all)")
+	jsonOut := fs.Bool("json", false, "print each event as a line of JSON")
+	output := outputFlag(fs)
+	count := fs.Int("count", 0, "exit after printing this many events (0 to watch until interrupted)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if !checkOutput(output, *jsonOut) {
+		return exitUsage
+	}
+	var watch []pb.RoutingEventKind
+	for _, name := range strings.Split(*kinds, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		kind, err := pubsub.ParseKind(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "events: %v\n", err)
+			return exitUsage
+		}
+		watch = append(watch, kind)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Events are printed as they arrive, so YAML is a document per event
+	// and CSV has fixed columns, the event itself as JSON
+	var cw *csv.Writer
+	if *output == formatCSV {
+		cw = csv.NewWriter(os.Stdout)
+		cw.Write([]string{"from", "kind", "event"})
+		cw.Flush()
+	}
+	var mu sync.Mutex
+	printed := 0
+	sub := pubsub.NewSubscriber(kubo.NewClient(*apiURL), *namespace, watch...)
+	sub.OnEvent = func(ev pubsub.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		if *count > 0 && printed >= *count {
+			return
+		}
+		var event bytes.Buffer
+		data, _ := protojson.Marshal(ev.RoutingEvent)
+		json.Compact(&event, data)
+		record := map[string]any{"from": ev.From, "kind": pubsub.KindName(ev.Kind), "event": json.RawMessage(event.Bytes())}
+		switch *output {
+		case formatJSON:
+			line, _ := json.Marshal(record)
+			fmt.Println(string(line))
+		case formatYAML:
+			if printed > 0 {
+				fmt.Println("---")
+			}
+			if err := printStructured(formatYAML, record, nil); err != nil {
+				fmt.Fprintf(os.Stderr, "events: %v\n", err)
+			}
+		case formatCSV:
+			cw.Write([]string{ev.From, pubsub.KindName(ev.Kind), event.String()})
+			cw.Flush()
+		default:
+			fmt.Println(eventLine(ev))
+		}
+		if printed++; printed == *count {
+			cancel()
+		}
+	}
+	sub.OnInvalid = func(from string, kind pb.RoutingEventKind, err error) {
+		fmt.Fprintf(os.Stderr, "events: invalid %s message from %s: %v\n", pubsub.KindName(kind), from, err)
+	}
+	sub.Run(ctx)
+	return exitOK
+}
+
+// eventLine summarises an event on one line
+func eventLine(ev pubsub.Event) string {
+	sender := ev.Sender
+	if sender == "" {
+		sender = ev.From
+	}
+	at := ev.GetTimestamp().AsTime().Local().Format(time.TimeOnly)
+	switch e := ev.Event.(type) {
+	case *pb.RoutingEvent_Decision:
+		req, resp := e.Decision.GetRequest(), e.Decision.GetResponse()
+		size := "size unknown"
+		if req.GetContentSize() > 0 {
+			size = sizeLabel(req.GetContentSize())
+		}
+		return fmt.Sprintf("%s decision %s: %s (score %.2f) for %s, %s", at, sender,
+			resp.GetBackendId(), resp.GetScore(), req.GetContentType(), size)
+	case *pb.RoutingEvent_Outcome:
+		o := e.Outcome
+		result := "ok"
+		if !o.Success {
+			result = "failed: " + o.Error
+		}
+		return fmt.Sprintf("%s outcome  %s: %s in %dms, %s", at, sender, o.BackendId, o.DurationMs, result)
+	case *pb.RoutingEvent_Backend:
+		b := e.Backend
+		return fmt.Sprintf("%s backend  %s: %s is %s (was %s): %s", at, sender, b.BackendId,
+			enumLabel(b.State.String(), "BACKEND_STATE_"), enumLabel(b.PreviousState.String(), "BACKEND_STATE_"), b.Reason)
+	}
+	return fmt.Sprintf("%s %s: empty event", at, sender)
+}
+
+// runEventsRelay implements `routing-cli events relay`: it follows the
+// routing server's backend health stream and republishes every change on
+// pubsub, for peers that cannot reach the server themselves
+func runEventsRelay(args []string) int {
+	fs := flag.NewFlagSet("events relay", flag.ContinueOnError)
+	server := fs.String("server", "localhost:50051", "gRPC server address: host:port, unix:///path, or a gRPC-Web gateway URL")
+	apiURL := fs.String("ipfs-api", "", "Kubo RPC API URL (default $IPFS_API_URL or "+kubo.DefaultAPIURL+")")
+	namespace := fs.String("namespace", pubsub.DefaultNamespace, "deployment namespace of the topics")
+	sender := fs.String("sender", "", "sender ID put in events (default the host name)")
+	verbose := fs.Bool("v", false, "print each relayed event on stderr")
+	metricsAddr := metricsAddrFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if *sender == "" {
+		*sender, _ = os.Hostname()
+	}
+
+	stopMetrics, err := serveAgentMetrics(*metricsAddr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "events: %v\n", err)
+		return exitUsage
+	}
+	defer stopMetrics()
+	client, err := newClient(*server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "events: %v\n", err)
+		return exitUnreachable
+	}
+	defer client.Close()
+	pub := pubsub.NewPublisher(kubo.NewClient(*apiURL), *namespace)
+	pub.Sender = *sender
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	w := client.NewBackendWatcher()
+	if agentMetrics != nil {
+		agentMetrics.Client(client)
+		agentMetrics.Watcher(w)
+	}
+	w.OnEvent = func(ev *pb.BackendEvent) {
+		// Current states sent on (re)connecting are not changes
+		if ev.PreviousState == pb.BackendState_BACKEND_STATE_UNSPECIFIED {
+			return
+		}
+		if err := pub.Backend(ctx, ev); err != nil {
+			fmt.Fprintf(os.Stderr, "events: %v\n", err)
+			return
+		}
+		if *verbose {
+			fmt.Fprintf(os.Stderr, "relayed %s: %s\n", ev.BackendId, enumLabel(ev.State.String(), "BACKEND_STATE_"))
+		}
+	}
+	if err := w.Run(ctx); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "events: %v\n", err)
+		return exitUnreachable
+	}
+	return exitOK
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"example.com/ipfs_kit_py/executor"
+	"example.com/ipfs_kit_py/ipfscluster"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// runGC implements `routing-cli gc`: it reports which placements are
+// expired, superseded by a migration, replicated beyond -copies or no
+// longer pinned, and with -apply removes them and reports the bytes freed
+func runGC(args []string) int {
+	fs := flag.NewFlagSet("gc", flag.ContinueOnError)
+	nodes := nodeFlag{}
+	fs.Var(nodes, "node", "IPFS backend as backend=api-url, repeatable (default ipfs=$IPFS_API_URL)")
+	clusters := nodeFlag{}
+	fs.Var(clusters, "cluster", "IPFS Cluster backend as backend=rest-api-url, repeatable")
+	services := nodeFlag{}
+	fs.Var(services, "service", "remote pinning service as backend=endpoint, repeatable")
+	stores := nodeFlag{}
+	fs.Var(stores, "store", "backend content is deleted from, as for migrate -to, repeatable")
+	copies := fs.Int("copies", 0, "most placements one CID should have; later ones are redundant (0 for no limit)")
+	var superseded []string
+	fs.Func("superseded", "migrate progress file whose moved-from placements are garbage, repeatable", func(s string) error {
+		superseded = append(superseded, s)
+		return nil
+	})
+	server := fs.String("server", "", "gRPC routing server to record unpins with (empty to skip)")
+	apply := fs.Bool("apply", false, "carry out the cleanups instead of only proposing them")
+	timeout := fs.Duration("timeout", 30*time.Minute, "deadline for the whole run")
+	jsonOut := fs.Bool("json", false, "print the plan, or with -apply the report, as JSON")
+	output := outputFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli gc [flags] <manifest>...\n\nManifests are read as by migrate.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() == 0 || !checkOutput(output, *jsonOut) {
+		fs.Usage()
+		return exitUsage
+	}
+	registry, err := targetRegistry(stores, nil, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gc: %v\n", err)
+		return exitUsage
+	}
+
+	var records []executor.PlacementRecord
+	for _, path := range fs.Args() {
+		recs, err := loadPlacements(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gc: %s: %v\n", path, err)
+			return exitUsage
+		}
+		records = append(records, recs...)
+	}
+	policy := executor.GCPolicy{Copies: *copies}
+	for _, path := range superseded {
+		moves, err := loadMoves(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gc: %v\n", err)
+			return exitUsage
+		}
+		for _, m := range moves {
+			policy.Superseded = append(policy.Superseded, m.From)
+		}
+	}
+
+	var client *routingclient.Client
+	if *server != "" {
+		if client, err = newClient(*server); err != nil {
+			fmt.Fprintf(os.Stderr, "gc: %v\n", err)
+			return exitUnreachable
+		}
+		defer client.Close()
+	}
+	pinner := executor.NewPinner(client, nodes.clients(clusters, services)).
+		WithClusters(clusters.clusterClients(), ipfscluster.PinOptions{}).
+		WithServices(services.serviceClients())
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	plan, err := executor.PlanGC(ctx, registry, pinner, records, policy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitUnreachable
+	}
+	if !*apply {
+		if *output != formatText {
+			if err := printStructured(*output, plan, plan.Cleanups); err != nil {
+				fmt.Fprintf(os.Stderr, "gc: %v\n", err)
+				return exitUnhealthy
+			}
+			return exitOK
+		}
+		printCleanups(plan.Cleanups)
+		fmt.Fprintf(os.Stderr, "%d placements checked, %d to clean up, about %s to reclaim; rerun with -apply\n",
+			plan.Checked, len(plan.Cleanups), formatBytes(sumBytes(plan.Bytes)))
+		return exitOK
+	}
+
+	report := executor.ApplyGC(ctx, registry, pinner, plan)
+	code := exitOK
+	if len(report.Failed) > 0 {
+		code = exitUnhealthy
+	}
+	if *output != formatText {
+		if err := printStructured(*output, report, gcResults(report)); err != nil {
+			fmt.Fprintf(os.Stderr, "gc: %v\n", err)
+			return exitUnhealthy
+		}
+		return code
+	}
+	printCleanups(report.Applied)
+	for _, f := range report.Failed {
+		fmt.Fprintf(os.Stderr, "gc: %s %s on %s: %s\n", f.Action, f.Location, f.BackendID, f.Error)
+	}
+	for _, c := range report.Skipped {
+		fmt.Fprintf(os.Stderr, "gc: %s on %s is %s but cannot be removed from here\n", c.Location, c.BackendID, c.Reason)
+	}
+	ids := make([]string, 0, len(report.Reclaimed))
+	for id := range report.Reclaimed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Fprintf(os.Stderr, "reclaimed %s on %s\n", formatBytes(report.Reclaimed[id]), id)
+	}
+	fmt.Fprintf(os.Stderr, "%d cleanups applied, %d failed, %d skipped, %s reclaimed\n",
+		len(report.Applied), len(report.Failed), len(report.Skipped), formatBytes(sumBytes(report.Reclaimed)))
+	return code
+}
+
+// gcResult is a cleanup and what became of it, a row of gc -apply's CSV
+type gcResult struct {
+	executor.Cleanup
+	Result string `json:"result"` // "applied", "failed" or "skipped"
+	Error  string `json:"error,omitempty"`
+}
+
+// gcResults lists the cleanups of report, applied, failed, then skipped
+func gcResults(report *executor.GCReport) []gcResult {
+	var results []gcResult
+	for _, c := range report.Applied {
+		results = append(results, gcResult{Cleanup: c, Result: "applied"})
+	}
+	for _, f := range report.Failed {
+		results = append(results, gcResult{Cleanup: f.Cleanup, Result: "failed", Error: f.Error})
+	}
+	for _, c := range report.Skipped {
+		results = append(results, gcResult{Cleanup: c, Result: "skipped"})
+	}
+	return results
+}
+
+// printCleanups prints cleanups as a table
+func printCleanups(cleanups []executor.Cleanup) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "LOCATION\tBACKEND\tREASON\tACTION\tBYTES")
+	for _, c := range cleanups {
+		action := c.Action
+		if action == "" {
+			action = "manual"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n", c.Location, c.BackendID, c.Reason, action, c.Bytes)
+	}
+	tw.Flush()
+}
+
+func sumBytes(perBackend map[string]int64) int64 {
+	var total int64
+	for _, n := range perBackend {
+		total += n
+	}
+	return total
+}
+
+// formatBytes formats an amount of data with a binary unit
+func formatBytes(n int64) string {
+	if n <= 0 {
+		return "0 B"
+	}
+	return sizeLabel(n)
+}
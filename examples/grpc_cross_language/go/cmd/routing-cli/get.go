@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"example.com/ipfs_kit_py/kubo"
+	"example.com/ipfs_kit_py/nodestats"
+	"example.com/ipfs_kit_py/p2p"
+	"example.com/ipfs_kit_py/retrieval"
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// defaultGateways are the public gateways get falls back to
+var defaultGateways = []string{"https://ipfs.io", "https://dweb.link"}
+
+// gatewayFlag collects repeated -gateway [id=]url flags
+type gatewayFlag []string
+
+func (g *gatewayFlag) String() string { return strings.Join(*g, ",") }
+
+func (g *gatewayFlag) Set(s string) error {
+	*g = append(*g, s)
+	return nil
+}
+
+// sources returns a Source per gateway; a gateway may be given as
+// backend=url to choose the backend ID its outcomes are recorded under,
+// and as a multiaddr ending in /p2p/<peer ID> to reach it over libp2p
+func (g gatewayFlag) sources() ([]retrieval.Source, error) {
+	if len(g) == 0 {
+		g = defaultGateways
+	}
+	sources := make([]retrieval.Source, len(g))
+	for i, s := range g {
+		id, u, ok := strings.Cut(s, "=")
+		if !ok {
+			id, u = "", s
+		}
+		if !p2p.IsAddr(u) {
+			sources[i] = retrieval.Gateway(id, u)
+			continue
+		}
+		h, err := p2p.Default()
+		if err != nil {
+			return nil, err
+		}
+		hc, base, err := h.HTTPClient(u, p2p.GatewayProtocol)
+		if err != nil {
+			return nil, err
+		}
+		sources[i] = retrieval.HTTPGateway(id, base, hc)
+	}
+	return sources, nil
+}
+
+// getReport is what get prints about a read with -output json, yaml or csv
+type getReport struct {
+	Ref    string `json:"ref"`
+	Source string `json:"source"`
+	Bytes  int64  `json:"bytes"`
+	// Failed are the sources that failed before Source served the read
+	Failed []string `json:"failed,omitempty"`
+}
+
+// runGet implements `routing-cli get`: it reads a CID from the local node
+// and a list of gateways, racing a slow source against the next (or, with
+// -race, several from the start) and failing over past broken ones, and
+// reports every attempt to the router
+// along with the local node's transfer statistics over the read
+func runGet(args []string) int {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	gateways := gatewayFlag{}
+	fs.Var(&gateways, "gateway", "gateway as [backend=]url or [backend=]multiaddr/p2p/<peer ID>, repeatable, tried after the local node (default "+strings.Join(defaultGateways, ", ")+")")
+	local := fs.Bool("local", true, "try the local Kubo node first")
+	apiURL := fs.String("ipfs-api", "", "Kubo RPC API URL (default $IPFS_API_URL or "+kubo.DefaultAPIURL+")")
+	server := fs.String("server", "", "gRPC routing server to record attempts with (empty to skip)")
+	hedge := fs.Duration("hedge", retrieval.DefaultHedgeDelay, "how long to wait on a source before racing the next; negative waits for failure")
+	race := fs.Int("race", 0, "query this many of the best sources at once and read from the first to respond, recording how far behind the rest were (0 to hedge instead, -1 for all)")
+	verify := fs.Bool("verify", true, "verify raw-codec content against its CID")
+	output := fs.String("o", "", "write the content to this file (default stdout)")
+	format := outputFlag(fs)
+	timeout := fs.Duration("timeout", 5*time.Minute, "deadline for the read")
+	verbose := fs.Bool("v", false, "report each attempt on stderr")
+	var rate rateFlag
+	fs.Var(&rate, "max-download-rate", "limit on the bandwidth of the read, e.g. 50MiB (default unlimited)")
+	bar := fs.Bool("bar", true, "draw a progress bar on stderr while reading, when it is a terminal and the content is not written to one")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli get [flags] <cid[/path]>\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 1 || !checkOutput(format, false) {
+		fs.Usage()
+		return exitUsage
+	}
+	// The content goes to stdout unless -o is given, leaving it for a
+	// report of the read
+	if *format != formatText && *output == "" {
+		fmt.Fprintf(os.Stderr, "get: -output %s needs -o\n", *format)
+		return exitUsage
+	}
+	ref := strings.TrimPrefix(fs.Arg(0), "/ipfs/")
+
+	r := &retrieval.Retriever{HedgeDelay: *hedge, Verify: *verify, Width: max(*race, 0)}
+	r.Throttle = throttleLimits(rate, 0)
+	r.OnProgress = newProgressBar(*bar && (*output != "" || !isTerminal(os.Stdout))).onProgress()
+	node := kubo.NewClient(*apiURL)
+	if *local {
+		r.Sources = append(r.Sources, retrieval.Local("", node))
+	}
+	sources, err := gateways.sources()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "get: %v\n", err)
+		return exitUsage
+	}
+	r.Sources = append(r.Sources, sources...)
+	var client *routingclient.Client
+	if *server != "" {
+		client, err = newClient(*server)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "get: %v\n", err)
+			return exitUnreachable
+		}
+		defer client.Close()
+		queue := client.NewOutcomeQueue(routingclient.BatchConfig{})
+		if debugState != nil {
+			debugState.AddQueue("get", queue)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := queue.Close(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "get: record outcomes: %v\n", err)
+			}
+		}()
+		r.Recorder = queue
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	// The local node's statistics over the read are reported too
+	var scraper *nodestats.Scraper
+	if *local && client != nil {
+		scraper = nodestats.New(node, "")
+		scraper.Sample(ctx)
+	}
+	start := time.Now()
+	get := r.Get
+	if *race != 0 {
+		get = r.Fetch
+	}
+	res, err := get(ctx, ref)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "get: %v\n", err)
+		return exitUnhealthy
+	}
+	defer res.Body.Close()
+	if *verbose {
+		for _, a := range res.Attempts {
+			if a.Err != nil {
+				fmt.Fprintf(os.Stderr, "%s: failed after %s: %v\n", a.Source, a.Duration.Round(time.Millisecond), a.Err)
+			}
+		}
+		for _, a := range res.Outrun {
+			fmt.Fprintf(os.Stderr, "%s: outrun, still waiting after %s\n", a.Source, a.Duration.Round(time.Millisecond))
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "get: %v\n", err)
+			return exitUsage
+		}
+		defer f.Close()
+		out = f
+	}
+	n, err := io.Copy(out, res.Body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "get: %s: %v\n", res.Source, err)
+		return exitUnhealthy
+	}
+	if *format != formatText {
+		report := getReport{Ref: ref, Source: res.Source, Bytes: n}
+		for _, a := range res.Attempts {
+			if a.Err != nil {
+				report.Failed = append(report.Failed, a.Source)
+			}
+		}
+		if err := printStructured(*format, report, report); err != nil {
+			fmt.Fprintf(os.Stderr, "get: %v\n", err)
+			return exitUnhealthy
+		}
+	} else if *verbose || *output != "" {
+		fmt.Fprintf(os.Stderr, "read %d bytes from %s in %s\n", n, res.Source, time.Since(start).Round(time.Millisecond))
+	}
+	if scraper != nil {
+		if _, err := client.NewProber().Report(ctx, []*pb.MetricSample{scraper.Sample(ctx)}); err != nil {
+			fmt.Fprintf(os.Stderr, "get: report node statistics: %v\n", err)
+		}
+	}
+	return exitOK
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"example.com/ipfs_kit_py/cidutil"
+	"example.com/ipfs_kit_py/history"
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+// goldenCase is a routing-cli invocation whose standard output is kept in
+// testdata/golden, once per output format: <name>.golden for text and
+// <name>-<format>.golden for the others
+type goldenCase struct {
+	name string
+	// args are the command and its flags, and operands its arguments; the
+	// -output flag goes between them
+	args, operands []string
+	// formats are the -output formats to run, all of outputFormats if nil
+	formats []string
+	code    int
+	// protoJSON marks JSON written with protojson, whose whitespace
+	// varies between builds on purpose, so it is compared re-indented
+	protoJSON bool
+	// scrub matches output that differs from run to run, such as
+	// timings, which is replaced with "_"
+	scrub *regexp.Regexp
+}
+
+// TestGolden runs the commands that can run against local files, a fake
+// router and a fake Kubo node in every output format they offer, and
+// checks their output is unchanged, so scripts parsing it do not break
+// silently. Run with -update after a deliberate change and review the diff
+// of testdata/golden. Arrow output, which backends stats and insights
+// offer, is checked by the export package; e2e, soak, call and the
+// interactive commands need live services.
+func TestGolden(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = local })
+	server := fakeRouter(t)
+	node := fakeKubo(t)
+	gateway := fakeGateway(t)
+	dir := t.TempDir()
+	carFile := filepath.Join(dir, "tree.car")
+	db := goldenHistory(t, filepath.Join(dir, "history.db"))
+	// the AWS credentials only let the s3 -to target be configured; the
+	// migrate cases are dry runs and never reach S3
+	t.Setenv("AWS_ACCESS_KEY_ID", "golden")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "golden")
+	migrate := []string{"migrate", "-server", server, "-dry-run", "-bar=false",
+		"-to", "ipfs=http://127.0.0.1:1", "-to", "s3=s3://golden",
+		"-progress", filepath.Join(dir, "migrate.progress.jsonl"), "-checkpoints", ""}
+	get := []string{"get", "-local=false", "-bar=false", "-gateway", "broken=" + gateway + "/broken", "-gateway", "golden=" + gateway}
+	text := []string{formatText}
+	timings := regexp.MustCompile(`[0-9]+(\.[0-9]+)?(e-?[0-9]+)?`)
+
+	cases := []goldenCase{
+		{name: "cid", args: []string{"cid"}, operands: []string{"QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG", "bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy"}},
+		{name: "cid-convert", args: []string{"cid", "-to", "v1", "-base", "base36"}, operands: []string{"QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"}},
+		{name: "unixfs", args: []string{"unixfs"}, operands: []string{"testdata/tree"}},
+		{name: "unixfs-car", args: []string{"unixfs", "-cid-version", "1", "-car", carFile}, operands: []string{"testdata/tree"}, formats: text},
+		{name: "car-ls", args: []string{"car", "ls"}, operands: []string{carFile}},
+		{name: "backends-list", args: []string{"backends", "list", "-server", server}, protoJSON: true},
+		{name: "backends-stats", args: []string{"backends", "stats", "-server", server}, operands: []string{"s3-east"}, protoJSON: true},
+		{name: "insights", args: []string{"insights", "-server", server}, protoJSON: true},
+		{name: "admin-weights", args: []string{"admin", "weights", "get", "-server", server}},
+		{name: "admin-weights-set", args: []string{"admin", "weights", "set", "-server", server, "-token", "golden"}, operands: []string{"cost=0.5"}},
+		{name: "health", args: []string{"health", "-server", server}, scrub: timings},
+		{name: "history-list", args: []string{"history", "list", "-db", db}},
+		{name: "history-where", args: []string{"history", "where", "-db", db}, operands: []string{"report.pdf"}},
+		{name: "pins-ls", args: []string{"pins", "ls", "-node", "ipfs=" + node}, operands: []string{"QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG", "bafkreigh2akiscaildcqabsyg3dfr6chu3fgpregiymsck7e7aqa4s52zy"}},
+		{name: "dht-findprovs", args: []string{"dht", "findprovs", "-ipfs-api", node}, operands: []string{"QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"}},
+		{name: "name-publish", args: []string{"name", "publish", "-ipfs-api", node}, operands: []string{"QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG"}},
+		{name: "get", args: get, operands: []string{goldenCID}, formats: text},
+		{name: "get-report", args: append(get, "-o", filepath.Join(dir, "hello.txt")), operands: []string{goldenCID}, formats: outputFormats[1:]},
+		{name: "events-watch", args: []string{"events", "watch", "-ipfs-api", node, "-kind", "outcomes", "-count", "2"}},
+		{name: "migrate-plan", args: migrate, operands: []string{"testdata/placements.jsonl"}},
+		{name: "gc", args: []string{"gc", "-node", "ipfs=" + node}, operands: []string{"testdata/placements.jsonl"}},
+		{name: "tiers-plan", args: []string{"tiers", "-plan", "-state", "testdata/tiers.json"}},
+		{name: "bench", args: []string{"bench", "-i", "testdata/bench.txt"}, scrub: regexp.MustCompile(`\d{4}-\d\d-\d\dT[0-9:.]+Z`)},
+		{name: "bench-compare", args: []string{"bench", "-i", "testdata/bench.txt", "-compare", "testdata/bench-base.json"}, code: exitUnhealthy},
+	}
+	// the fake servers' addresses change from run to run
+	addrs := strings.NewReplacer(server, "router", node, "http://kubo", gateway, "http://gateway")
+	// car ls reads the CAR unixfs-car writes, so the cases run in order
+	for _, c := range cases {
+		formats := c.formats
+		if formats == nil {
+			formats = outputFormats
+		}
+		for _, format := range formats {
+			name, args := c.name, append([]string(nil), c.args...)
+			if format != formatText {
+				name += "-" + format
+				args = append(args, "-output", format)
+			}
+			args = append(args, c.operands...)
+			t.Run(name, func(t *testing.T) {
+				code, out := runCaptured(t, args)
+				if code != c.code {
+					t.Fatalf("exit code %d, want %d", code, c.code)
+				}
+				out = []byte(addrs.Replace(string(out)))
+				if c.protoJSON && format == formatJSON {
+					out = indentJSON(t, out)
+				}
+				if c.scrub != nil {
+					out = c.scrub.ReplaceAll(out, []byte("_"))
+				}
+				checkGolden(t, name, args, out)
+			})
+		}
+	}
+}
+
+// checkGolden compares out with testdata/golden/<name>.golden, or with
+// -update rewrites the file
+func checkGolden(t *testing.T, name string, args []string, out []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, out, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(out, want) {
+		t.Errorf("output of routing-cli %v changed\n--- got\n%s--- want\n%s", args, out, want)
+	}
+}
+
+// runCaptured runs a routing-cli command and returns its exit code and
+// what it printed on standard output
+func runCaptured(t *testing.T, args []string) (int, []byte) {
+	t.Helper()
+	var run func([]string) int
+	for _, c := range commands {
+		if c.name == args[0] {
+			run = c.run
+		}
+	}
+	if run == nil {
+		t.Fatalf("unknown command %q", args[0])
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	code := run(args[1:])
+	os.Stdout = stdout
+	w.Close()
+	return code, <-done
+}
+
+// indentJSON re-indents each JSON value of out in a fixed layout
+func indentJSON(t *testing.T, out []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			t.Fatalf("output is not JSON: %v\n%s", err, out)
+		}
+		if err := json.Indent(&buf, raw, "", "  "); err != nil {
+			t.Fatal(err)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// goldenRouter answers the routing RPCs the golden cases make with fixed
+// data. Timestamps are left unset so the output does not change.
+type goldenRouter struct {
+	pb.UnimplementedRoutingServiceServer
+}
+
+// goldenPrices are the storage prices goldenRouter estimates with, per GiB
+var goldenPrices = map[string]float64{"ipfs": 0.01, "s3": 0.023, "filecoin": 0.05}
+
+func (goldenRouter) ListBackends(ctx context.Context, req *pb.ListBackendsRequest) (*pb.ListBackendsResponse, error) {
+	return &pb.ListBackendsResponse{Backends: []*pb.BackendInfo{
+		{BackendId: "filecoin", Region: "global", PricingClass: pb.PricingClass_PRICING_CLASS_LOW, State: pb.BackendState_BACKEND_STATE_HEALTHY},
+		{BackendId: "ipfs", Region: "global", PricingClass: pb.PricingClass_PRICING_CLASS_FREE, State: pb.BackendState_BACKEND_STATE_HEALTHY, MaxObjectSize: 1 << 30},
+		{BackendId: "s3-east", Region: "us-east-1", PricingClass: pb.PricingClass_PRICING_CLASS_MEDIUM, State: pb.BackendState_BACKEND_STATE_DEGRADED, ContentTypes: []string{"application/*", "image/*"}},
+	}}, nil
+}
+
+func (goldenRouter) GetBackendStats(ctx context.Context, req *pb.GetBackendStatsRequest) (*pb.BackendStats, error) {
+	return &pb.BackendStats{
+		BackendId:                req.BackendId,
+		WindowMinutes:            req.WindowMinutes,
+		Requests:                 1200,
+		Successes:                1140,
+		SuccessRate:              0.95,
+		Latency:                  &pb.BackendStats_Latency{P50Ms: 120, P90Ms: 480, P99Ms: 1900, MaxMs: 5200, MeanMs: 210},
+		ThroughputBytesPerSecond: 48 << 20,
+		RequestsPerMinute:        20,
+		Errors:                   map[string]int64{"timeout": 42, "unavailable": 18, "outrun": 7},
+		State:                    pb.BackendState_BACKEND_STATE_DEGRADED,
+		Node:                     &pb.NodeStats{Peers: 64, WantlistLength: 3, BlocksReceived: 1000, DuplicateBlocksReceived: 125, BlocksSent: 400, RateInBytesPerSecond: 2 << 20, RateOutBytesPerSecond: 512 << 10},
+	}, nil
+}
+
+func (goldenRouter) GetInsights(ctx context.Context, req *pb.GetInsightsRequest) (*pb.GetInsightsResponse, error) {
+	weights, _ := structpb.NewStruct(map[string]interface{}{"cost": 0.3, "latency": 0.25, "reliability": 0.35, "locality": 0.1})
+	rates, _ := structpb.NewStruct(map[string]interface{}{"filecoin": 0.97, "ipfs": 0.99, "s3": 0.6})
+	scores, _ := structpb.NewStruct(map[string]interface{}{"filecoin": 0.62, "ipfs": 0.81, "s3": 0.44})
+	return &pb.GetInsightsResponse{FactorWeights: weights, BackendScores: scores, BackendSuccessRates: rates}, nil
+}
+
+func (goldenRouter) SetFactorWeights(ctx context.Context, req *pb.SetFactorWeightsRequest) (*pb.SetFactorWeightsResponse, error) {
+	previous := map[string]float64{"cost": 0.3, "latency": 0.25, "reliability": 0.35, "locality": 0.1}
+	weights := make(map[string]float64, len(previous))
+	for f, w := range previous {
+		weights[f] = w
+	}
+	for f, w := range req.Weights {
+		weights[f] = w
+	}
+	return &pb.SetFactorWeightsResponse{Weights: weights, PreviousWeights: previous}, nil
+}
+
+func (goldenRouter) EstimateCost(ctx context.Context, req *pb.EstimateCostRequest) (*pb.EstimateCostResponse, error) {
+	resp := &pb.EstimateCostResponse{Currency: "USD"}
+	gib := float64(req.ContentSize) / (1 << 30)
+	for _, id := range req.Backends {
+		price := goldenPrices[id] * gib
+		resp.Estimates = append(resp.Estimates, &pb.CostEstimate{BackendId: id, StorageCost: price, TotalCost: price, StorageCostPerGbMonth: goldenPrices[id]})
+	}
+	sort.SliceStable(resp.Estimates, func(i, j int) bool { return resp.Estimates[i].TotalCost < resp.Estimates[j].TotalCost })
+	return resp, nil
+}
+
+// fakeRouter serves goldenRouter on a loopback port for the duration of
+// the test and returns its address
+func fakeRouter(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterRoutingServiceServer(srv, goldenRouter{})
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+// goldenCID is the raw CIDv1 of "hello", which fakeGateway serves
+const goldenCID = "bafkreibm6jg3ux5qumhcn2b3flc3tyu6dmlb4xa7u5bf44yegnrjhc4yeq"
+
+// goldenPins are the pins fakeKubo has
+var goldenPins = map[string]string{
+	"QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG":              "recursive",
+	"bafybeie5nqv6kd3qnfjupgvz34woh3oksc3iau6abmyajn7qvtf6d2ho34": "indirect",
+}
+
+// fakeKubo serves the Kubo RPC calls the golden cases make with fixed data
+// and returns its URL
+func fakeKubo(t *testing.T) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v0/pin/ls", func(w http.ResponseWriter, r *http.Request) {
+		keys := map[string]map[string]string{}
+		for cid, typ := range goldenPins {
+			if arg := r.URL.Query().Get("arg"); arg == "" || arg == cid {
+				keys[cid] = map[string]string{"Type": typ}
+			}
+		}
+		if arg := r.URL.Query().Get("arg"); arg != "" && len(keys) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]interface{}{"Message": "path '" + arg + "' is not pinned", "Code": 0, "Type": "error"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Keys": keys})
+	})
+	mux.HandleFunc("/api/v0/routing/findprovs", func(w http.ResponseWriter, r *http.Request) {
+		enc := json.NewEncoder(w)
+		enc.Encode(map[string]interface{}{"Type": 0, "ID": "12D3KooWQuery"})
+		enc.Encode(map[string]interface{}{"Type": 4, "Responses": []map[string]interface{}{
+			{"ID": "12D3KooWProviderA", "Addrs": []string{"/ip4/203.0.113.7/tcp/4001", "/ip4/203.0.113.7/udp/4001/quic-v1"}},
+			{"ID": "12D3KooWProviderB", "Addrs": []string{"/dns4/b.example/tcp/4001"}},
+		}})
+	})
+	mux.HandleFunc("/api/v0/name/publish", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"Name": "k51qzi5uqu5dgolden", "Value": r.URL.Query().Get("arg")})
+	})
+	mux.HandleFunc("/api/v0/pubsub/sub", func(w http.ResponseWriter, r *http.Request) {
+		at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		events := []*pb.RoutingEvent{
+			{Sender: "agent-1", Timestamp: timestamppb.New(at), Event: &pb.RoutingEvent_Outcome{Outcome: &pb.RecordOutcomeRequest{BackendId: "ipfs", Success: true, ContentHash: "sha256-golden", DurationMs: 120}}},
+			{Sender: "agent-2", Timestamp: timestamppb.New(at.Add(time.Minute)), Event: &pb.RoutingEvent_Outcome{Outcome: &pb.RecordOutcomeRequest{BackendId: "s3", DurationMs: 3000, Error: "timeout"}}},
+		}
+		enc := json.NewEncoder(w)
+		for _, ev := range events {
+			data, _ := proto.Marshal(ev)
+			encoded, _ := cidutil.MultibaseEncode(cidutil.Base64URL, data)
+			enc.Encode(map[string]interface{}{"from": "12D3KooWRelay", "data": encoded, "topicIDs": []string{}})
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// fakeGateway serves "hello" as goldenCID, and fails under /broken
+func fakeGateway(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ipfs/"+goldenCID {
+			http.Error(w, "unavailable", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// goldenHistory writes a history database at path with a decision, an
+// outcome and a placement, and returns path
+func goldenHistory(t *testing.T, path string) string {
+	t.Helper()
+	store, err := history.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	expires := at.Add(30 * 24 * time.Hour)
+	err = store.Add(
+		&history.Entry{Kind: history.KindDecision, Time: at, ContentHash: "sha256-report", Filename: "report.pdf", ContentType: "application/pdf", Size: 1 << 20, BackendID: "s3", Strategy: "hybrid", Score: 0.82, Reasoning: "cheapest healthy backend"},
+		&history.Entry{Kind: history.KindOutcome, Time: at.Add(time.Second), ContentHash: "sha256-report", Filename: "report.pdf", BackendID: "s3", Success: true, DurationMS: 840, Bytes: 1 << 20},
+		&history.Entry{Kind: history.KindPlacement, Time: at.Add(2 * time.Second), ContentHash: "sha256-report", Filename: "report.pdf", BackendID: "s3", Location: "s3://golden/report.pdf", Bytes: 1 << 20, ExpiresAt: &expires},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
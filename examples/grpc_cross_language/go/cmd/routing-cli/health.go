@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"example.com/ipfs_kit_py/mcpclient"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// probeResult is the outcome of a single health probe
+type probeResult struct {
+	Target   string  `json:"target"`
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_ms"`
+	code     int
+}
+
+// runHealth implements `routing-cli health`. The exit code is exitOK when
+// every probe passes, exitUnhealthy when a server answered but reported
+// itself unhealthy, and exitUnreachable when a server could not be reached.
+func runHealth(args []string) int {
+	fs := flag.NewFlagSet("health", flag.ContinueOnError)
+	server := fs.String("server", "localhost:50051", "gRPC server address: host:port, unix:///path, or a gRPC-Web gateway URL (empty to skip)")
+	service := fs.String("service", "", "gRPC health service name (empty for overall server health)")
+	mcpURL := fs.String("mcp", "", "MCP dashboard base URL to probe (empty to skip)")
+	token := fs.String("token", os.Getenv("MCP_API_TOKEN"), "MCP API token")
+	ready := fs.Bool("ready", false, "readiness mode: also require the MCP JSON-RPC endpoint to answer ping")
+	timeout := fs.Duration("timeout", 5*time.Second, "timeout per probe")
+	jsonOut := fs.Bool("json", false, "print results as JSON")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if !checkOutput(output, *jsonOut) {
+		return exitUsage
+	}
+	if *server == "" && *mcpURL == "" {
+		fmt.Fprintln(os.Stderr, "health: nothing to probe; set -server and/or -mcp")
+		return exitUsage
+	}
+
+	var results []probeResult
+	check := func(probe func() probeResult) {
+		start := time.Now()
+		r := probe()
+		r.Duration = float64(time.Since(start).Microseconds()) / 1000
+		results = append(results, r)
+	}
+	if *server != "" {
+		check(func() probeResult { return probeGRPC(*server, *service, *timeout) })
+	}
+	if *mcpURL != "" {
+		check(func() probeResult { return probeHTTP(*mcpURL, *timeout) })
+		if *ready {
+			check(func() probeResult { return probeMCP(*mcpURL, *token, *timeout) })
+		}
+	}
+
+	code := exitOK
+	for _, r := range results {
+		if r.code > code {
+			code = r.code
+		}
+	}
+
+	if *output != formatText {
+		out := map[string]interface{}{"healthy": code == exitOK, "checks": results}
+		if err := printStructured(*output, out, results); err != nil {
+			fmt.Fprintf(os.Stderr, "health: %v\n", err)
+			return exitUnhealthy
+		}
+	} else {
+		for _, r := range results {
+			line := fmt.Sprintf("%-40s %-12s %6.1fms", r.Target, r.Status, r.Duration)
+			if r.Error != "" {
+				line += "  " + r.Error
+			}
+			fmt.Println(line)
+		}
+	}
+	return code
+}
+
+// probeGRPC calls grpc.health.v1.Health/Check on addr
+func probeGRPC(addr, service string, timeout time.Duration) probeResult {
+	r := probeResult{Target: "grpc://" + addr}
+	if service != "" {
+		r.Target += "/" + service
+	}
+
+	conn, err := routingclient.Dial(addr, grpc.WithTransportCredentials(routingclient.TransportCredentials(addr)))
+	if err != nil {
+		return r.fail("UNREACHABLE", exitUnreachable, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unavailable, codes.DeadlineExceeded:
+			return r.fail("UNREACHABLE", exitUnreachable, err)
+		case codes.NotFound:
+			return r.fail("SERVICE_UNKNOWN", exitUnhealthy, err)
+		default:
+			return r.fail("ERROR", exitUnhealthy, err)
+		}
+	}
+	r.Status = resp.GetStatus().String()
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		r.code = exitUnhealthy
+	}
+	return r
+}
+
+// probeHTTP checks the MCP dashboard's /healthz endpoint
+func probeHTTP(baseURL string, timeout time.Duration) probeResult {
+	u := strings.TrimRight(baseURL, "/") + "/healthz"
+	r := probeResult{Target: u}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return r.fail("ERROR", exitUsage, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return r.fail("UNREACHABLE", exitUnreachable, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return r.fail("NOT_SERVING", exitUnhealthy, fmt.Errorf("HTTP %s", resp.Status))
+	}
+	r.Status = "SERVING"
+	return r
+}
+
+// probeMCP checks that the JSON-RPC endpoint answers ping
+func probeMCP(baseURL, token string, timeout time.Duration) probeResult {
+	client := mcpclient.NewClient(baseURL, token)
+	r := probeResult{Target: strings.TrimRight(baseURL, "/") + "/mcp"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		return r.fail("NOT_READY", exitUnhealthy, err)
+	}
+	r.Status = "READY"
+	return r
+}
+
+func (r probeResult) fail(state string, code int, err error) probeResult {
+	r.Status = state
+	r.code = code
+	r.Error = err.Error()
+	return r
+}
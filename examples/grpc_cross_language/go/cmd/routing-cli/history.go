@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"example.com/ipfs_kit_py/export"
+	"example.com/ipfs_kit_py/history"
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// historyStore is the history database set up by the global --history
+// flag, which records the decisions and outcomes of every client newClient
+// creates and the placements of migrate and tiers
+var historyStore *history.Store
+
+// openHistory opens the history database at path, if set; the returned
+// function closes it
+func openHistory(path string) (func(), error) {
+	if path == "" {
+		return func() {}, nil
+	}
+	var err error
+	if historyStore, err = history.Open(path); err != nil {
+		return nil, err
+	}
+	historyStore.OnError = func(err error) {
+		fmt.Fprintf(os.Stderr, "routing-cli: %v\n", err)
+	}
+	return func() { historyStore.Close() }, nil
+}
+
+// runHistory implements `routing-cli history <subcommand>`
+func runHistory(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprintf(os.Stderr, "Usage: routing-cli history list [flags] [content]\n       routing-cli history where [flags] <content>\n       routing-cli history export [flags]\n       routing-cli history prune [flags]\n")
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	switch args[0] {
+	case "list":
+		return runHistoryList(args[1:])
+	case "where":
+		return runHistoryWhere(args[1:])
+	case "export":
+		return runHistoryExport(args[1:])
+	case "prune":
+		return runHistoryPrune(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "history: unknown subcommand %q\n", args[0])
+	return exitUsage
+}
+
+// historyDB adds the -db flag to fs
+func historyDB(fs *flag.FlagSet) *string {
+	path, _ := history.DefaultPath()
+	return fs.String("db", path, "history database (default $"+history.Env+", or routing-history.db in the user config directory)")
+}
+
+// runHistoryList implements `routing-cli history list [content]`, printing
+// the recorded decisions, outcomes and placements, the most recent first
+func runHistoryList(args []string) int {
+	fs := flag.NewFlagSet("history list", flag.ContinueOnError)
+	db := historyDB(fs)
+	kind := fs.String("kind", "", "only list entries of this kind: decision, outcome or placement")
+	backend := fs.String("backend", "", "only list entries for this backend")
+	since := fs.Duration("since", 0, "only list entries from this long ago (0 for all)")
+	limit := fs.Int("limit", 50, "most entries to list (0 for all)")
+	jsonOut := fs.Bool("json", false, "print the entries as JSON lines")
+	output := outputFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli history list [flags] [content]\n\ncontent is a content hash, CID, file name or location.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 1 || !checkOutput(output, *jsonOut) {
+		fs.Usage()
+		return exitUsage
+	}
+	switch *kind {
+	case "", history.KindDecision, history.KindOutcome, history.KindPlacement:
+	default:
+		fmt.Fprintf(os.Stderr, "history: unknown kind %q\n", *kind)
+		return exitUsage
+	}
+
+	q := history.Query{Content: fs.Arg(0), Kind: *kind, BackendID: *backend, Limit: *limit}
+	if *since > 0 {
+		q.Since = time.Now().Add(-*since)
+	}
+	store, err := history.Open(*db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "history: %v\n", err)
+		return exitUsage
+	}
+	defer store.Close()
+	entries, err := store.Find(q)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "history: %v\n", err)
+		return exitUnhealthy
+	}
+
+	switch *output {
+	case formatJSON:
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			enc.Encode(e)
+		}
+		return exitOK
+	case formatYAML, formatCSV:
+		if err := printStructured(*output, entries, entries); err != nil {
+			fmt.Fprintf(os.Stderr, "history: %v\n", err)
+			return exitUnhealthy
+		}
+		return exitOK
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tKIND\tBACKEND\tCONTENT\tDETAIL")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Time.Local().Format(time.DateTime), e.Kind, e.BackendID, contentLabel(&e), detailLabel(&e))
+	}
+	tw.Flush()
+	return exitOK
+}
+
+// runHistoryWhere implements `routing-cli history where <content>`,
+// answering where content went: every recorded placement of it, the most
+// recent first
+func runHistoryWhere(args []string) int {
+	fs := flag.NewFlagSet("history where", flag.ContinueOnError)
+	db := historyDB(fs)
+	jsonOut := fs.Bool("json", false, "print the placements as JSON lines")
+	output := outputFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli history where [flags] <content>\n\ncontent is a content hash, CID, file name or location.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 1 || !checkOutput(output, *jsonOut) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	store, err := history.Open(*db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "history: %v\n", err)
+		return exitUsage
+	}
+	defer store.Close()
+	placements, err := store.Where(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "history: %v\n", err)
+		return exitUnhealthy
+	}
+	if len(placements) == 0 {
+		fmt.Fprintf(os.Stderr, "history: no placements of %s recorded\n", fs.Arg(0))
+		return exitUnhealthy
+	}
+
+	switch *output {
+	case formatJSON:
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range placements {
+			enc.Encode(e)
+		}
+		return exitOK
+	case formatYAML, formatCSV:
+		if err := printStructured(*output, placements, placements); err != nil {
+			fmt.Fprintf(os.Stderr, "history: %v\n", err)
+			return exitUnhealthy
+		}
+		return exitOK
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STORED\tBACKEND\tLOCATION\tCID\tSIZE\tEXPIRES")
+	for _, e := range placements {
+		expires := "-"
+		if e.ExpiresAt != nil {
+			expires = e.ExpiresAt.Local().Format(time.DateTime)
+		}
+		cid := e.CID
+		if cid == "" {
+			cid = "-"
+		}
+		backend := e.BackendID
+		if e.From != "" {
+			backend += " (from " + e.From + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Time.Local().Format(time.DateTime), backend, e.Location, cid, sizeLabel(e.Bytes), expires)
+	}
+	tw.Flush()
+	return exitOK
+}
+
+// runHistoryExport implements `routing-cli history export`, writing the
+// local store's entries, the service's kept decisions and outcomes, or
+// both, oldest first, as CSV or Parquet
+func runHistoryExport(args []string) int {
+	fs := flag.NewFlagSet("history export", flag.ContinueOnError)
+	db := historyDB(fs)
+	server := fs.String("server", "localhost:50051", "gRPC routing server address, for -source server or all")
+	source := fs.String("source", export.SourceLocal, "history to export: local, server or all")
+	format := fs.String("format", "", "csv, parquet or arrow, an Apache Arrow IPC stream (default from the -o extension, else csv)")
+	out := fs.String("o", "", "file to write (default stdout)")
+	kind := fs.String("kind", "", "only export records of this kind: decision, outcome or placement")
+	backend := fs.String("backend", "", "only export records for this backend")
+	since := fs.Duration("since", 0, "only export records from this long ago (0 for all)")
+	timeout := fs.Duration("timeout", time.Minute, "deadline for fetching the server's history")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	local, remote := *source == export.SourceLocal || *source == "all", *source == export.SourceServer || *source == "all"
+	if !local && !remote {
+		fmt.Fprintf(os.Stderr, "history: unknown source %q\n", *source)
+		return exitUsage
+	}
+	serverKind := pb.HistoryRecordKind_HISTORY_RECORD_KIND_UNSPECIFIED
+	switch *kind {
+	case "":
+	case history.KindDecision:
+		serverKind = pb.HistoryRecordKind_HISTORY_RECORD_KIND_DECISION
+	case history.KindOutcome:
+		serverKind = pb.HistoryRecordKind_HISTORY_RECORD_KIND_OUTCOME
+	case history.KindPlacement:
+		// the service keeps no placements
+		remote = false
+	default:
+		fmt.Fprintf(os.Stderr, "history: unknown kind %q\n", *kind)
+		return exitUsage
+	}
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(*out)) {
+		case ".parquet":
+			*format = export.FormatParquet
+		case ".arrow", ".arrows":
+			*format = export.FormatArrow
+		default:
+			*format = export.FormatCSV
+		}
+	}
+	var from time.Time
+	if *since > 0 {
+		from = time.Now().Add(-*since)
+	}
+
+	var entries []history.Entry
+	if local {
+		store, err := history.Open(*db)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "history: %v\n", err)
+			return exitUsage
+		}
+		entries, err = store.Find(history.Query{Kind: *kind, BackendID: *backend, Since: from})
+		store.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "history: %v\n", err)
+			return exitUnhealthy
+		}
+	}
+	var client *routingclient.Client
+	if remote {
+		var err error
+		if client, err = newClient(*server); err != nil {
+			fmt.Fprintf(os.Stderr, "history: %v\n", err)
+			return exitUnreachable
+		}
+		defer client.Close()
+	}
+
+	dst := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "history: %v\n", err)
+			return exitUsage
+		}
+		defer f.Close()
+		dst = f
+	}
+	w, err := export.NewWriter(dst, *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "history: %v\n", err)
+		return exitUsage
+	}
+	n := 0
+	// Find returns the newest first
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := w.Write(export.FromEntry(&entries[i])); err != nil {
+			fmt.Fprintf(os.Stderr, "history: %v\n", err)
+			return exitUnhealthy
+		}
+		n++
+	}
+	if remote {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		q := routingclient.HistoryQuery{Since: from, Kind: serverKind, BackendID: *backend}
+		err := client.History(ctx, q, func(r *pb.HistoryRecord) error {
+			n++
+			return w.Write(export.FromHistoryRecord(r))
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "history: server: %v\n", err)
+			w.Close()
+			return exitUnreachable
+		}
+	}
+	if err := w.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "history: %v\n", err)
+		return exitUnhealthy
+	}
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "wrote %d records to %s\n", n, *out)
+	}
+	return exitOK
+}
+
+// runHistoryPrune implements `routing-cli history prune`, removing old
+// entries
+func runHistoryPrune(args []string) int {
+	fs := flag.NewFlagSet("history prune", flag.ContinueOnError)
+	db := historyDB(fs)
+	olderThan := fs.Duration("older-than", 90*24*time.Hour, "remove entries recorded longer ago than this")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	store, err := history.Open(*db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "history: %v\n", err)
+		return exitUsage
+	}
+	defer store.Close()
+	n, err := store.Prune(time.Now().Add(-*olderThan))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "history: %v\n", err)
+		return exitUnhealthy
+	}
+	fmt.Printf("removed %d entries\n", n)
+	return exitOK
+}
+
+// contentLabel names an entry's content by the first of its file name,
+// CID and content hash it has
+func contentLabel(e *history.Entry) string {
+	for _, s := range []string{e.Filename, e.CID, e.ContentHash} {
+		if s != "" {
+			return s
+		}
+	}
+	return "-"
+}
+
+// detailLabel summarises what an entry of each kind says
+func detailLabel(e *history.Entry) string {
+	switch e.Kind {
+	case history.KindDecision:
+		detail := fmt.Sprintf("%s score %.3f", e.Strategy, e.Score)
+		if r := strings.Join(strings.Fields(e.Reasoning), " "); r != "" {
+			if rs := []rune(r); len(rs) > 60 {
+				r = string(rs[:59]) + "…"
+			}
+			detail += ": " + r
+		}
+		return detail
+	case history.KindOutcome:
+		if !e.Success {
+			return fmt.Sprintf("failed after %dms: %s", e.DurationMS, e.Error)
+		}
+		return fmt.Sprintf("ok in %dms", e.DurationMS)
+	case history.KindPlacement:
+		detail := e.Location
+		if e.From != "" {
+			detail += " (from " + e.From + ")"
+		}
+		return detail
+	}
+	return ""
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"example.com/ipfs_kit_py/export"
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// insightsSnapshot is the part of an insights response insights prints
+// and diffs, as -append writes it
+type insightsSnapshot struct {
+	Time         time.Time          `json:"time"`
+	Weights      map[string]float64 `json:"weights"`
+	Scores       map[string]float64 `json:"scores"`
+	SuccessRates map[string]float64 `json:"success_rates"`
+	// Changes are the differences from the previous snapshot in watch mode
+	Changes []insightsChange `json:"changes,omitempty"`
+}
+
+// insightsChange is a factor weight, backend score or success rate that
+// moved between two snapshots. From is nil for a value that appeared and
+// To for one that disappeared.
+type insightsChange struct {
+	Kind string   `json:"kind"` // "weight", "score" or "success_rate"
+	Name string   `json:"name"` // the factor or backend
+	From *float64 `json:"from,omitempty"`
+	To   *float64 `json:"to,omitempty"`
+}
+
+// runInsights implements `routing-cli insights`, printing the factor
+// weights and each backend's score and success rate; with -watch it keeps
+// refreshing them and prints what moved
+func runInsights(args []string) int {
+	fs := flag.NewFlagSet("insights", flag.ContinueOnError)
+	server := fs.String("server", "localhost:50051", "gRPC routing server address")
+	window := fs.Duration("window", 0, "period to report on, rounded up to whole hours (default the server's, 24h)")
+	timeout := fs.Duration("timeout", 10*time.Second, "deadline for each call")
+	watch := fs.Bool("watch", false, "keep refreshing and print the weights, scores and success rates that change")
+	interval := fs.Duration("interval", 10*time.Second, "time between refreshes with -watch")
+	minChange := fs.Float64("min-change", 0.001, "smallest movement -watch reports")
+	appendPath := fs.String("append", "", "append each refresh, with its changes, to this file as a line of JSON")
+	jsonOut := fs.Bool("json", false, "print the response as JSON")
+	output := outputFlag(fs, export.FormatArrow)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 0 || *interval <= 0 || !checkOutput(output, *jsonOut, export.FormatArrow) || (*watch && *output != formatText && *output != export.FormatArrow) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	client, err := newClient(*server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "insights: %v\n", err)
+		return exitUnreachable
+	}
+	defer client.Close()
+	var enc *json.Encoder
+	if *appendPath != "" {
+		f, err := os.OpenFile(*appendPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "insights: %v\n", err)
+			return exitUsage
+		}
+		defer f.Close()
+		enc = json.NewEncoder(f)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fetch := func() (*pb.GetInsightsResponse, error) {
+		ctx, cancel := context.WithTimeout(ctx, *timeout)
+		defer cancel()
+		return client.GetInsights(ctx, *window)
+	}
+	resp, err := fetch()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "insights: %v\n", err)
+		return exitUnreachable
+	}
+	prev := snapshotInsights(resp)
+	if enc != nil {
+		if err := enc.Encode(prev); err != nil {
+			fmt.Fprintf(os.Stderr, "insights: %v\n", err)
+			return exitUnhealthy
+		}
+	}
+	var aw *export.InsightsWriter
+	switch *output {
+	case formatJSON:
+		data, _ := protojson.MarshalOptions{Multiline: true}.Marshal(resp)
+		fmt.Println(string(data))
+		return exitOK
+	case formatYAML, formatCSV:
+		if err := printStructured(*output, resp, insightsRows(prev)); err != nil {
+			fmt.Fprintf(os.Stderr, "insights: %v\n", err)
+			return exitUnhealthy
+		}
+		return exitOK
+	case export.FormatArrow:
+		// every refresh is a record batch of the one stream
+		aw = export.NewInsightsWriter(os.Stdout)
+		defer aw.Close()
+		if err := aw.Write(prev.Time, prev.Weights, prev.Scores, prev.SuccessRates); err != nil {
+			fmt.Fprintf(os.Stderr, "insights: %v\n", err)
+			return exitUnhealthy
+		}
+	default:
+		printInsights(prev)
+	}
+	if !*watch {
+		return exitOK
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return exitOK
+		case <-ticker.C:
+		}
+		resp, err := fetch()
+		if err != nil {
+			if ctx.Err() != nil {
+				return exitOK
+			}
+			fmt.Fprintf(os.Stderr, "insights: %v\n", err)
+			continue
+		}
+		cur := snapshotInsights(resp)
+		cur.Changes = diffInsights(prev, cur, *minChange)
+		if aw != nil {
+			if err := aw.Write(cur.Time, cur.Weights, cur.Scores, cur.SuccessRates); err != nil {
+				fmt.Fprintf(os.Stderr, "insights: %v\n", err)
+				return exitUnhealthy
+			}
+		} else {
+			printChanges(cur)
+		}
+		if enc != nil {
+			if err := enc.Encode(cur); err != nil {
+				fmt.Fprintf(os.Stderr, "insights: %v\n", err)
+			}
+		}
+		prev = cur
+	}
+}
+
+// snapshotInsights takes the weights, scores and success rates from resp
+func snapshotInsights(resp *pb.GetInsightsResponse) insightsSnapshot {
+	s := insightsSnapshot{
+		Time:         time.Now().UTC(),
+		Weights:      make(map[string]float64),
+		Scores:       make(map[string]float64),
+		SuccessRates: routingclient.SuccessRates(resp),
+	}
+	if ts := resp.GetTimestamp(); ts != nil {
+		s.Time = ts.AsTime()
+	}
+	for f, v := range resp.GetFactorWeights().GetFields() {
+		s.Weights[f] = v.GetNumberValue()
+	}
+	for id, v := range resp.GetBackendScores().GetFields() {
+		s.Scores[id] = v.GetNumberValue()
+	}
+	return s
+}
+
+// insightsRow is a weight, score or success rate, as insights prints them
+// as CSV and export writes them as Arrow
+type insightsRow struct {
+	Kind  string  `json:"kind"`
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// insightsRows lists the weights, then the scores, then the success rates
+// of s, each by name
+func insightsRows(s insightsSnapshot) []insightsRow {
+	var rows []insightsRow
+	add := func(kind string, values map[string]float64) {
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			rows = append(rows, insightsRow{Kind: kind, Name: name, Value: values[name]})
+		}
+	}
+	add("weight", s.Weights)
+	add("score", s.Scores)
+	add("success_rate", s.SuccessRates)
+	return rows
+}
+
+// printInsights prints the factor weights, then each backend's score and
+// success rate
+func printInsights(s insightsSnapshot) {
+	printWeights(s.Weights, nil)
+	fmt.Println()
+	ids := make([]string, 0, len(s.Scores))
+	for id := range s.Scores {
+		ids = append(ids, id)
+	}
+	for id := range s.SuccessRates {
+		if _, ok := s.Scores[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BACKEND\tSCORE\tSUCCESS")
+	for _, id := range ids {
+		score, rate := "-", "-"
+		if v, ok := s.Scores[id]; ok {
+			score = fmt.Sprintf("%.3f", v)
+		}
+		if v, ok := s.SuccessRates[id]; ok {
+			rate = fmt.Sprintf("%.1f%%", v*100)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", id, score, rate)
+	}
+	tw.Flush()
+}
+
+// diffInsights returns what moved by at least minChange from prev to cur,
+// weights first, then scores, then success rates
+func diffInsights(prev, cur insightsSnapshot, minChange float64) []insightsChange {
+	var changes []insightsChange
+	diff := func(kind string, from, to map[string]float64) {
+		names := make([]string, 0, len(from)+len(to))
+		for name := range from {
+			names = append(names, name)
+		}
+		for name := range to {
+			if _, ok := from[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			f, hadFrom := from[name]
+			t, hasTo := to[name]
+			if hadFrom && hasTo && math.Abs(t-f) < minChange {
+				continue
+			}
+			c := insightsChange{Kind: kind, Name: name}
+			if hadFrom {
+				c.From = &f
+			}
+			if hasTo {
+				c.To = &t
+			}
+			changes = append(changes, c)
+		}
+	}
+	diff("weight", prev.Weights, cur.Weights)
+	diff("score", prev.Scores, cur.Scores)
+	diff("success_rate", prev.SuccessRates, cur.SuccessRates)
+	return changes
+}
+
+// printChanges prints a refresh's changes, one per line. Rising scores and
+// success rates are shown in green and falling ones in red; weight changes
+// are highlighted in yellow.
+func printChanges(s insightsSnapshot) {
+	at := s.Time.Local().Format(time.TimeOnly)
+	for _, c := range s.Changes {
+		value := func(v *float64) string {
+			switch {
+			case v == nil:
+				return "-"
+			case c.Kind == "success_rate":
+				return fmt.Sprintf("%.1f%%", *v*100)
+			}
+			return fmt.Sprintf("%.3f", *v)
+		}
+		line := fmt.Sprintf("%-12s %-22s %s -> %s", c.Kind, c.Name, value(c.From), value(c.To))
+		switch {
+		case c.From == nil || c.To == nil:
+		case c.Kind == "success_rate":
+			line += fmt.Sprintf(" (%+.1f points)", (*c.To-*c.From)*100)
+		default:
+			line += fmt.Sprintf(" (%+.3f)", *c.To-*c.From)
+		}
+		switch {
+		case c.Kind == "weight":
+			line = tuiWarn.Render(line)
+		case c.From == nil || c.To != nil && *c.To > *c.From:
+			line = tuiGood.Render(line)
+		default:
+			line = tuiBad.Render(line)
+		}
+		fmt.Printf("%s %s\n", at, line)
+	}
+}
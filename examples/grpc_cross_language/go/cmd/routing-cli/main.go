@@ -0,0 +1,191 @@
+// routing-cli is a command-line front end for the routing service and the
+// MCP dashboard.
+//
+// Usage:
+//
+//	routing-cli [-http-api url] [-record file | -replay file] [-offline dir] [-history db] [-push url] [-metrics url] [-debug-addr addr] <command> [flags]
+//
+// Run `routing-cli help` for the list of commands.
+//
+// Any -server flag also accepts a libp2p multiaddr ending in
+// /p2p/<peer ID>, dialed with the client key at $ROUTING_P2P_KEY; see
+// `routing-cli p2p`.
+//
+// -http-api <url>, by default $ROUTING_HTTP_API, is the HTTP routing API
+// serving the RPCs the Python gRPC servicer lacks, such as ListBackends
+// and GetHistory; see routingclient.SplitConn.
+// -record <file> before the command writes every RPC it makes to the
+// routing service to file; -replay <file> serves the command's RPCs from
+// such a recording instead of the server. -offline <dir> keeps commands
+// working while the server is unreachable; see routingclient.Offline.
+// -history <db>, by default $ROUTING_HISTORY, records decisions, outcomes
+// and placements for `routing-cli history`. -push <url>, by default
+// $ROUTING_PUSHGATEWAY, pushes the objects the command routed, the
+// outcomes, bytes and durations it recorded per backend, and its run time
+// and exit code to a Prometheus Pushgateway when it exits, for batch jobs
+// too short-lived to be scraped. -metrics <url>, by default
+// $ROUTING_METRICS, sends the same to a StatsD or DogStatsD agent as they
+// happen: statsd://host:8125 or dogstatsd://host:8125, with optional
+// ?prefix=, repeated &tag=key:value and &flush= parameters.
+// -debug-addr <addr>, by default $ROUTING_DEBUG_ADDR, serves pprof, expvar
+// and a /debug/routing snapshot of the command's connections, cached
+// decisions and pending outcomes while it runs; see package diag.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"example.com/ipfs_kit_py/diag"
+	"example.com/ipfs_kit_py/history"
+	"example.com/ipfs_kit_py/metrics"
+	"example.com/ipfs_kit_py/p2p"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// Exit codes shared by all commands so scripts can tell failures apart
+const (
+	exitOK          = 0
+	exitUnhealthy   = 1
+	exitUsage       = 2
+	exitUnreachable = 3
+)
+
+// command is a routing-cli subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
+
+var commands = []command{
+	{"health", "check gRPC and MCP server health", runHealth},
+	{"soak", "run a randomized soak test against a deployment", runSoak},
+	{"bench", "save benchmark results as a baseline, or compare them with one", runBench},
+	{"e2e", "run the end-to-end route/upload/pin/link/retrieve pipeline", runE2E},
+	{"call", "call any RPC the server exposes, discovered through reflection", runCall},
+	{"backends", "list backends and their capabilities, or show one backend's statistics", runBackends},
+	{"insights", "show factor weights, backend scores and success rates, or watch them change", runInsights},
+	{"admin", "view or update the service's scoring factor weights", runAdmin},
+	{"cid", "inspect CIDs and convert them between versions and multibases", runCID},
+	{"car", "list a CAR's blocks from its index, or extract one root's subgraph", runCAR},
+	{"unixfs", "compute the CID ipfs add would give a path, optionally writing its CAR", runUnixFS},
+	{"pins", "pin and unpin on routed IPFS backends, or audit what is pinned where", runPins},
+	{"name", "publish an IPNS name for a dataset root, or resolve one", runName},
+	{"dht", "announce a CID on the DHT, or find its providers and where the router would read it from", runDHT},
+	{"get", "read a CID from the local node or the fastest working gateway", runGet},
+	{"reconstruct", "reassemble an erasure-coded striped object from its manifest", runReconstruct},
+	{"migrate", "move content off degraded or expensive backends, with resumable progress", runMigrate},
+	{"gc", "propose or apply cleanup of expired, superseded, redundant or unpinned content", runGC},
+	{"tiers", "run the daemon moving content between hot, warm, cold and archive tiers", runTiers},
+	{"nodestats", "report the local node's bitswap and bandwidth statistics to the router", runNodeStats},
+	{"events", "watch routing events peers publish over pubsub, or relay backend health to them", runEvents},
+	{"p2p", "show this client's peer ID, or expose local services over libp2p", runP2P},
+	{"history", "list recorded decisions, outcomes and placements, or say where content went", runHistory},
+	{"tui", "show live decisions, backend scores, success rates and factor weights in the terminal", runTUI},
+	{"repl", "run select, record and insights at a prompt over one connection", runRepl},
+	{"completion", "print a bash, zsh, fish or PowerShell completion script", runCompletion},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: routing-cli [-http-api url] [-record file | -replay file] [-offline dir] [-history db] [-push url] [-metrics url] [-debug-addr addr] <command> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-11s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'routing-cli <command> -h' for command flags.\n")
+}
+
+// globalOptions are the flags given before the command
+type globalOptions struct {
+	httpAPI, record, replay, offline *string
+	history, push, pushJob, metrics  *string
+	debug                            *string
+}
+
+// globalFlags defines the flags given before the command on fs
+func globalFlags(fs *flag.FlagSet) *globalOptions {
+	return &globalOptions{
+		httpAPI: fs.String("http-api", os.Getenv(routingclient.HTTPAPIEnv), "HTTP routing API base URL for the RPCs the gRPC server lacks (default $"+routingclient.HTTPAPIEnv+")"),
+		record:  fs.String("record", "", "record every routing RPC to this file"),
+		replay:  fs.String("replay", "", "serve routing RPCs from a file written by -record instead of the server"),
+		offline: fs.String("offline", "", "directory keeping last known decisions and queued outcomes, used while the server is unreachable"),
+		history: fs.String("history", os.Getenv(history.Env), "history database to record decisions, outcomes and placements in (default $"+history.Env+")"),
+		push:    fs.String("push", os.Getenv(metrics.PushgatewayEnv), "Prometheus Pushgateway URL to push the command's metrics to when it exits (default $"+metrics.PushgatewayEnv+")"),
+		pushJob: fs.String("push-job", "routing-cli", "job name the metrics are pushed under"),
+		metrics: fs.String("metrics", os.Getenv(metrics.ConfigEnv), "StatsD or DogStatsD sink to send metrics to, as statsd://host:port or dogstatsd://host:port (default $"+metrics.ConfigEnv+")"),
+		debug:   fs.String("debug-addr", os.Getenv(diag.Env), "address to serve pprof, expvar and /debug/routing on while the command runs, e.g. localhost:6060 (default $"+diag.Env+")"),
+	}
+}
+
+func main() {
+	fs := flag.NewFlagSet("routing-cli", flag.ContinueOnError)
+	fs.Usage = usage
+	g := globalFlags(fs)
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			os.Exit(exitOK)
+		}
+		os.Exit(exitUsage)
+	}
+	if fs.NArg() < 1 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	name := fs.Arg(0)
+	if name == "help" {
+		usage()
+		os.Exit(exitOK)
+	}
+	// the shell completion scripts call routing-cli __complete <words>
+	if name == completeCommand {
+		os.Exit(runComplete(fs.Args()[1:]))
+	}
+	p2p.Register()
+	for _, c := range commands {
+		if c.name == name {
+			httpAPI = *g.httpAPI
+			done, err := openTraffic(*g.record, *g.replay)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "routing-cli: %v\n", err)
+				os.Exit(exitUsage)
+			}
+			closeOffline, err := openOffline(*g.offline)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "routing-cli: %v\n", err)
+				os.Exit(exitUsage)
+			}
+			// history opens the database itself
+			if name == "history" {
+				*g.history = ""
+			}
+			closeHistory, err := openHistory(*g.history)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "routing-cli: %v\n", err)
+				os.Exit(exitUsage)
+			}
+			closeDebug, err := serveDebug(*g.debug)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "routing-cli: %v\n", err)
+				os.Exit(exitUsage)
+			}
+			closeMetrics, err := openMetrics(*g.metrics)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "routing-cli: %v\n", err)
+				os.Exit(exitUsage)
+			}
+			pushMetrics := openPush(*g.push, *g.pushJob, name)
+			code := c.run(fs.Args()[1:])
+			pushMetrics(code)
+			closeMetrics()
+			closeHistory()
+			closeDebug()
+			closeOffline()
+			done()
+			os.Exit(code)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "routing-cli: unknown command %q\n\n", name)
+	usage()
+	os.Exit(exitUsage)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"example.com/ipfs_kit_py/metrics"
+)
+
+// pushTimeout bounds pushing a job's metrics on exit
+const pushTimeout = 10 * time.Second
+
+// jobMetrics collects the command's metrics for the global --push flag,
+// counting the calls of every client newClient creates
+var jobMetrics *metrics.Job
+
+// openPush starts collecting metrics to push to the Pushgateway at url, if
+// set, as job, grouped by the command and host; the returned function
+// pushes them with the command's exit code
+func openPush(url, job, command string) func(code int) {
+	if url == "" {
+		return func(int) {}
+	}
+	jobMetrics = metrics.NewJob()
+	grouping := map[string]string{"command": command}
+	if host, err := os.Hostname(); err == nil {
+		grouping["instance"] = host
+	}
+	return func(code int) {
+		ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+		defer cancel()
+		if err := jobMetrics.Push(ctx, url, job, grouping, code); err != nil {
+			fmt.Fprintf(os.Stderr, "routing-cli: %v\n", err)
+		}
+	}
+}
+
+// metricsSink is the sink set up by the global --metrics flag, told of
+// the selections and outcomes of every client newClient creates
+var metricsSink metrics.Sink
+
+// openMetrics opens the metrics sink the URL spec describes, if set; the
+// returned function flushes and closes it
+func openMetrics(spec string) (func(), error) {
+	if spec == "" {
+		return func() {}, nil
+	}
+	cfg, err := metrics.ParseConfig(spec)
+	if err != nil {
+		return nil, err
+	}
+	if metricsSink, err = metrics.Open(cfg); err != nil {
+		return nil, err
+	}
+	if s, ok := metricsSink.(*metrics.StatsD); ok {
+		s.OnError = func(err error) {
+			fmt.Fprintf(os.Stderr, "routing-cli: %v\n", err)
+		}
+	}
+	return func() {
+		if err := metricsSink.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "routing-cli: %v\n", err)
+		}
+	}, nil
+}
+
+// agentMetrics is the self-metrics a daemon serves with -metrics-addr,
+// which count the streams of every client newClient creates
+var agentMetrics *metrics.Agent
+
+// metricsAddrFlag defines a daemon's -metrics-addr flag
+func metricsAddrFlag(fs *flag.FlagSet) *string {
+	return fs.String("metrics-addr", "", "address to serve this daemon's own metrics on at /metrics in the OpenMetrics format, e.g. :9464 (empty to not serve them)")
+}
+
+// serveAgentMetrics starts serving a daemon's own metrics on addr, if set,
+// before the daemon creates its client; the returned function stops
+// serving them
+func serveAgentMetrics(addr string) (func(), error) {
+	if addr == "" {
+		return func() {}, nil
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	agentMetrics = metrics.NewAgent()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", agentMetrics.Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln)
+	return func() { srv.Close() }, nil
+}
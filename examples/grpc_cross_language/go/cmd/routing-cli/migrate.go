@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"example.com/ipfs_kit_py/executor"
+	"example.com/ipfs_kit_py/kubo"
+	"example.com/ipfs_kit_py/lotus"
+	"example.com/ipfs_kit_py/retrieval"
+	"example.com/ipfs_kit_py/routingclient"
+	"example.com/ipfs_kit_py/workpool"
+)
+
+// migrated is a line of the migrate progress file
+type migrated struct {
+	From   executor.PlacementRecord `json:"from"`
+	To     executor.PlacementRecord `json:"to"`
+	Reason string                   `json:"reason"`
+	At     time.Time                `json:"at"`
+}
+
+// runMigrate implements `routing-cli migrate`: it plans moving the content
+// of placement manifests off degraded backends and onto cheaper ones, and
+// unless -dry-run copies it there. Completed moves are appended to the
+// progress file, so an interrupted run picks up where it stopped.
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	server := fs.String("server", "localhost:50051", "gRPC routing server address")
+	targets := nodeFlag{}
+	fs.Var(targets, "to", targetUsage)
+	gateways := gatewayFlag{}
+	fs.Var(&gateways, "gateway", "gateway to read content by CID from, as for get, repeatable (default "+strings.Join(defaultGateways, ", ")+")")
+	local := fs.Bool("local", true, "try the local Kubo node first when reading content")
+	apiURL := fs.String("ipfs-api", "", "Kubo RPC API URL (default $IPFS_API_URL or "+kubo.DefaultAPIURL+")")
+	minSuccess := fs.Float64("min-success", 0.9, "move content off backends whose success rate is below this")
+	minSavings := fs.Float64("min-savings", 0.2, "move content when another backend is cheaper by at least this fraction")
+	window := fs.Duration("window", 24*time.Hour, "window the success rates are taken over")
+	retention := fs.Duration("retention", 30*24*time.Hour, "how much longer the content is kept, for cost estimates")
+	retrievals := fs.Float64("retrievals", 0, "full reads expected over the retention period, for cost estimates")
+	progress := fs.String("progress", "migrate.progress.jsonl", "file recording completed moves; placements already in it are skipped")
+	checkpoints := fs.String("checkpoints", "migrate.checkpoints", "directory saving the progress of uploads, so a rerun resumes interrupted ones rather than restarting them (empty to keep none)")
+	dryRun := fs.Bool("dry-run", false, "only report what would move")
+	dealWait := fs.Duration("deal-wait", 24*time.Hour, "how long to wait for Filecoin deals to activate before exiting; deals still pending are listed")
+	timeout := fs.Duration("timeout", 6*time.Hour, "deadline for the whole migration")
+	jsonOut := fs.Bool("json", false, "print the plan as JSON")
+	output := outputFlag(fs)
+	bar := fs.Bool("bar", true, "draw a progress bar on stderr for each move, when it is a terminal")
+	var throttles throttleFlags
+	throttles.register(fs)
+	var admission admissionFlags
+	admission.register(fs)
+	var workers poolFlags
+	workers.register(fs, 4)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli migrate [flags] <manifest>...\n\nManifests are replication manifests or placement records, as a JSON array or one per line.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() == 0 || len(targets) == 0 || !checkOutput(output, *jsonOut) {
+		fs.Usage()
+		return exitUsage
+	}
+	client, err := newClient(*server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return exitUnreachable
+	}
+	defer client.Close()
+	registry, err := targetRegistry(targets, checkpointStore(*checkpoints), client)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return exitUsage
+	}
+	defer waitForDeals(registry, *dealWait)
+	admission.apply(registry)
+	pool, err := workers.pool()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return exitUsage
+	}
+	defer pool.Close()
+
+	var records []executor.PlacementRecord
+	for _, path := range fs.Args() {
+		recs, err := loadPlacements(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate: %s: %v\n", path, err)
+			return exitUsage
+		}
+		records = append(records, recs...)
+	}
+	done, err := loadProgress(*progress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: progress: %v\n", err)
+		return exitUsage
+	}
+	pending := records[:0]
+	for _, rec := range records {
+		if !done[placementKey(rec)] {
+			pending = append(pending, rec)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	plan, err := executor.PlanMigration(ctx, client, registry, pending, executor.MigrationPolicy{
+		MinSuccessRate: *minSuccess,
+		MinSavings:     *minSavings,
+		Window:         *window,
+		Retention:      *retention,
+		Retrievals:     *retrievals,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return exitUnreachable
+	}
+
+	if *output != formatText {
+		if err := printStructured(*output, plan, plan.Steps); err != nil {
+			fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+			return exitUnhealthy
+		}
+	} else {
+		printMigrationPlan(plan, len(records)-len(pending))
+	}
+	code := exitOK
+	if len(plan.Stranded) > 0 {
+		code = exitUnhealthy
+	}
+	if *dryRun || len(plan.Steps) == 0 {
+		return code
+	}
+
+	r := &retrieval.Retriever{Verify: true, Throttle: throttles.downloads()}
+	if *local {
+		r.Sources = append(r.Sources, retrieval.Local("", kubo.NewClient(*apiURL)))
+	}
+	sources, err := gateways.sources()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return exitUsage
+	}
+	r.Sources = append(r.Sources, sources...)
+
+	log, err := os.OpenFile(*progress, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: progress: %v\n", err)
+		return exitUsage
+	}
+	defer log.Close()
+	enc := json.NewEncoder(log)
+	if limits := throttles.uploads(); limits != nil {
+		ctx = executor.WithThrottle(ctx, limits)
+	}
+	if historyStore != nil {
+		ctx = executor.WithPlacementLog(ctx, historyStore)
+	}
+	if fn := newProgressBar(*bar).onProgress(); fn != nil {
+		ctx = executor.WithProgress(ctx, fn)
+	}
+	// Moves run on the pool, each holding a slot on its target backend
+	// from reading the content until it is stored
+	var mu sync.Mutex
+	moved := 0
+	for _, step := range plan.Steps {
+		step := step
+		err := pool.Go(ctx, func(ctx context.Context) {
+			// Moves still queued when the migration is cut short are
+			// left for a rerun
+			if ctx.Err() != nil {
+				return
+			}
+			res, err := migrateStep(ctx, client, registry, r, pool, step)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "migrate: %s on %s: %v\n", step.Location, step.BackendID, err)
+				code = exitUnhealthy
+				return
+			}
+			to := executor.RecordFor(step.Bucket, res)
+			if err := enc.Encode(migrated{From: step.PlacementRecord, To: to, Reason: step.Reason, At: time.Now().UTC()}); err != nil {
+				fmt.Fprintf(os.Stderr, "migrate: progress: %v\n", err)
+				code = exitUnhealthy
+				cancel()
+				return
+			}
+			moved++
+			fmt.Fprintf(os.Stderr, "moved %s from %s to %s (%s)\n", step.Location, step.BackendID, to.Location, step.Reason)
+		})
+		if err != nil {
+			break
+		}
+	}
+	pool.Close()
+	printPoolStats(os.Stderr, pool.Stats())
+	fmt.Fprintf(os.Stderr, "%d of %d moves done; the original copies are left in place\n", moved, len(plan.Steps))
+	return code
+}
+
+// migrateStep reads a placement's content and copies it to the step's
+// target, once the target has a free slot in pool
+func migrateStep(ctx context.Context, client *routingclient.Client, registry *executor.Registry, r *retrieval.Retriever, pool *workpool.Pool, step executor.MigrationStep) (*executor.Result, error) {
+	release, err := pool.Acquire(ctx, step.Target)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	body, err := openStored(ctx, r, step.CID, step.Location)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	defer body.Close()
+	return executor.Migrate(ctx, client, registry, step, body)
+}
+
+// printMigrationPlan prints a plan as a table
+func printMigrationPlan(plan *executor.MigrationPlan, skipped int) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "LOCATION\tFROM\tTO\tREASON\tCOST\tNEW COST")
+	for _, s := range plan.Steps {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.4f\t%.4f\n", s.Location, s.BackendID, s.Target, s.Reason, s.Cost, s.TargetCost)
+	}
+	tw.Flush()
+	for _, rec := range plan.Stranded {
+		fmt.Fprintf(os.Stderr, "stranded: %s on degraded %s has no permitted backend to move to\n", rec.Location, rec.BackendID)
+	}
+	fmt.Fprintf(os.Stderr, "%d placements checked, %d already migrated, %d to move, saving %.4f %s\n",
+		plan.Checked, skipped, len(plan.Steps), plan.Savings, plan.Currency)
+}
+
+// targetUsage describes the backends targetRegistry accepts
+const targetUsage = "backend content may move to, as ipfs=<Kubo RPC API URL>, s3=s3://bucket[/prefix][?region=&endpoint=&quota=] or filecoin=<Lotus API URL>?miner=<id>, repeatable; S3 credentials come from $AWS_ACCESS_KEY_ID and $AWS_SECRET_ACCESS_KEY, the Lotus token from $LOTUS_TOKEN; an s3 quota such as 500GiB caps the bytes under the prefix, for -admit"
+
+// checkpointStore returns the upload checkpoints kept in dir; nil if dir
+// is empty
+func checkpointStore(dir string) *executor.Checkpoints {
+	if dir == "" {
+		return nil
+	}
+	return &executor.Checkpoints{Dir: dir}
+}
+
+// targetRegistry builds executors for the -to backends, saving the
+// progress of their uploads in checkpoints if it is not nil. Filecoin
+// deals report their activation to client if it is not nil.
+func targetRegistry(targets nodeFlag, checkpoints *executor.Checkpoints, client *routingclient.Client) (*executor.Registry, error) {
+	registry := executor.NewRegistry()
+	for id, target := range targets {
+		switch executor.BackendClass(id) {
+		case executor.IPFSClass:
+			exec, err := executor.NewIPFSExecutor(executor.IPFSConfig{
+				Node:        kubo.NewClient(target),
+				Checkpoints: checkpoints,
+			})
+			if err != nil {
+				return nil, err
+			}
+			registry.Register(exec)
+		case "s3":
+			u, err := url.Parse(target)
+			if err != nil || u.Scheme != "s3" || u.Host == "" {
+				return nil, fmt.Errorf("%s: want s3://bucket[/prefix], got %q", id, target)
+			}
+			var quota rateFlag
+			if q := u.Query().Get("quota"); q != "" {
+				if err := quota.Set(q); err != nil {
+					return nil, fmt.Errorf("%s: quota: %w", id, err)
+				}
+			}
+			exec, err := executor.NewS3Executor(executor.S3Config{
+				Bucket:      u.Host,
+				Prefix:      strings.TrimPrefix(u.Path, "/"),
+				Region:      u.Query().Get("region"),
+				Endpoint:    u.Query().Get("endpoint"),
+				Checkpoints: checkpoints,
+				QuotaBytes:  int64(quota),
+			})
+			if err != nil {
+				return nil, err
+			}
+			registry.Register(exec)
+		case "filecoin":
+			u, err := url.Parse(target)
+			if err != nil || u.Query()["miner"] == nil {
+				return nil, fmt.Errorf("%s: want <Lotus API URL>?miner=<id>[&miner=<id>...], got %q", id, target)
+			}
+			miners := u.Query()["miner"]
+			u.RawQuery = ""
+			cfg := executor.FilecoinConfig{
+				Lotus:  lotus.NewClient(u.String(), ""),
+				Miners: miners,
+			}
+			if client != nil {
+				cfg.OnDealActive = executor.RecordDealOutcomes(client, id)
+			}
+			exec, err := executor.NewFilecoinExecutor(cfg)
+			if err != nil {
+				return nil, err
+			}
+			registry.Register(exec)
+		default:
+			return nil, fmt.Errorf("%s: content can only be moved to ipfs, s3 and filecoin backends", id)
+		}
+	}
+	return registry, nil
+}
+
+// waitForDeals waits up to timeout for the Filecoin deals made through
+// registry to activate, so their outcomes are recorded before the command
+// exits, and lists the ones still pending after that
+func waitForDeals(registry *executor.Registry, timeout time.Duration) {
+	exec, _ := registry.Lookup("filecoin")
+	fc, ok := exec.(*executor.FilecoinExecutor)
+	if !ok || len(fc.Pending()) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "waiting up to %s for %d Filecoin deals to activate\n", timeout, len(fc.Pending()))
+	done := make(chan struct{})
+	go func() {
+		fc.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		for _, proposal := range fc.Pending() {
+			fmt.Fprintf(os.Stderr, "deal %s has not activated yet; check it with lotus client get-deal\n", proposal)
+		}
+	}
+}
+
+// loadPlacements reads the placement records in a file: a replication
+// manifest, a JSON array of records, or one record per line
+func loadPlacements(path string) ([]executor.PlacementRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		var records []executor.PlacementRecord
+		return records, json.Unmarshal(data, &records)
+	}
+	var records []executor.PlacementRecord
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, err
+		}
+		var m executor.ReplicationManifest
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		if m.Replicas != nil {
+			records = append(records, m.Records()...)
+			continue
+		}
+		var rec executor.PlacementRecord
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+}
+
+// loadProgress returns the placements a progress file records as moved;
+// a missing file means nothing has been
+func loadProgress(path string) (map[string]bool, error) {
+	moves, err := loadMoves(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]bool{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	done := make(map[string]bool, len(moves))
+	for _, m := range moves {
+		done[placementKey(m.From)] = true
+	}
+	return done, nil
+}
+
+// loadMoves reads the moves recorded in a progress file
+func loadMoves(path string) ([]migrated, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var moves []migrated
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var m migrated
+		// A line cut short by an interrupted run is ignored
+		if json.Unmarshal(sc.Bytes(), &m) == nil {
+			moves = append(moves, m)
+		}
+	}
+	return moves, sc.Err()
+}
+
+// placementKey identifies a placement in the progress file
+func placementKey(rec executor.PlacementRecord) string {
+	return rec.BackendID + " " + rec.Location
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"example.com/ipfs_kit_py/cidutil"
+	"example.com/ipfs_kit_py/dnslink"
+	"example.com/ipfs_kit_py/kubo"
+)
+
+// runName implements `routing-cli name <subcommand>`
+func runName(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprintf(os.Stderr, "Usage: routing-cli name publish [flags] <cid|/ipfs/path>\n       routing-cli name resolve [flags] <name|dnslink:domain>\n")
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	switch args[0] {
+	case "publish":
+		return runNamePublish(args[1:])
+	case "resolve":
+		return runNameResolve(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "name: unknown subcommand %q\n", args[0])
+	return exitUsage
+}
+
+// runNamePublish implements `routing-cli name publish`, pointing an IPNS
+// name at a dataset root, typically the CID of a routed upload. Naming a
+// key that does not exist yet creates it, so each dataset can have its own
+// stable name.
+func runNamePublish(args []string) int {
+	fs := flag.NewFlagSet("name publish", flag.ContinueOnError)
+	apiURL := fs.String("ipfs-api", "", "Kubo RPC API URL (default $IPFS_API_URL or "+kubo.DefaultAPIURL+")")
+	key := fs.String("key", "", "keystore key to publish under, created if missing (default the node's own key)")
+	lifetime := fs.Duration("lifetime", 0, "how long the record stays valid (default Kubo's)")
+	ttl := fs.Duration("ttl", 0, "how long resolvers may cache the record (default Kubo's)")
+	allowOffline := fs.Bool("allow-offline", false, "store the record locally if the node is offline")
+	timeout := fs.Duration("timeout", 2*time.Minute, "deadline for publishing; DHT puts can be slow")
+	jsonOut := fs.Bool("json", false, "print the published record as JSON")
+	output := outputFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli name publish [flags] <cid|/ipfs/path>\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 1 || !checkOutput(output, *jsonOut) {
+		fs.Usage()
+		return exitUsage
+	}
+	target := fs.Arg(0)
+	if !strings.HasPrefix(target, "/ipfs/") {
+		if _, err := cidutil.ParseCID(target); err != nil {
+			fmt.Fprintf(os.Stderr, "name: %v\n", err)
+			return exitUsage
+		}
+		target = "/ipfs/" + target
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	ipfs := kubo.NewClient(*apiURL)
+	if *key != "" && *key != "self" {
+		if _, err := ipfs.EnsureKey(ctx, *key); err != nil {
+			fmt.Fprintf(os.Stderr, "name: key %s: %v\n", *key, err)
+			return exitUnreachable
+		}
+	}
+	entry, err := ipfs.NamePublish(ctx, target, &kubo.NamePublishOptions{
+		Key:          *key,
+		Lifetime:     *lifetime,
+		TTL:          *ttl,
+		AllowOffline: *allowOffline,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "name: publish: %v\n", err)
+		return exitUnhealthy
+	}
+	if *output != formatText {
+		if err := printStructured(*output, entry, entry); err != nil {
+			fmt.Fprintf(os.Stderr, "name: %v\n", err)
+			return exitUnhealthy
+		}
+		return exitOK
+	}
+	fmt.Printf("published %s at /ipns/%s\n", entry.Value, entry.Name)
+	return exitOK
+}
+
+// runNameResolve implements `routing-cli name resolve`, printing the /ipfs/
+// path an IPNS name points to. dnslink:<domain> references are resolved
+// locally from DNS, leaving only a linked IPNS key to Kubo.
+func runNameResolve(args []string) int {
+	fs := flag.NewFlagSet("name resolve", flag.ContinueOnError)
+	apiURL := fs.String("ipfs-api", "", "Kubo RPC API URL (default $IPFS_API_URL or "+kubo.DefaultAPIURL+")")
+	nocache := fs.Bool("nocache", false, "look the record up afresh rather than from the node's cache")
+	doh := fs.String("doh", "", "DNS-over-HTTPS JSON endpoint for dnslink: lookups, e.g. https://cloudflare-dns.com/dns-query (default the system resolver)")
+	timeout := fs.Duration("timeout", time.Minute, "deadline for resolving")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli name resolve [flags] <name|dnslink:domain>\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	name := fs.Arg(0)
+	if domain, ok := dnslink.ParseRef(name); ok {
+		var lookup dnslink.LookupFunc
+		if *doh != "" {
+			lookup = dnslink.DoHLookup(*doh)
+		}
+		p, err := dnslink.NewResolver(lookup).Resolve(ctx, domain)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "name: resolve: %v\n", err)
+			return exitUnhealthy
+		}
+		if !strings.HasPrefix(p, "/ipns/") {
+			fmt.Println(p)
+			return exitOK
+		}
+		name = p
+	}
+	p, err := kubo.NewClient(*apiURL).NameResolve(ctx, name, *nocache)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "name: resolve: %v\n", err)
+		return exitUnhealthy
+	}
+	fmt.Println(p)
+	return exitOK
+}
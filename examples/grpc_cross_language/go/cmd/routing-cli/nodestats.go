@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"example.com/ipfs_kit_py/kubo"
+	"example.com/ipfs_kit_py/nodestats"
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// runNodeStats implements `routing-cli nodestats`: it scrapes the local
+// Kubo node's bitswap and bandwidth statistics every interval and reports
+// them to the routing service until interrupted, so routing to the node
+// follows its health
+func runNodeStats(args []string) int {
+	fs := flag.NewFlagSet("nodestats", flag.ContinueOnError)
+	server := fs.String("server", "localhost:50051", "gRPC server address: host:port, unix:///path, or a gRPC-Web gateway URL")
+	apiURL := fs.String("ipfs-api", "", "Kubo RPC API URL (default $IPFS_API_URL or "+kubo.DefaultAPIURL+")")
+	backend := fs.String("backend", nodestats.DefaultBackendID, "backend ID the node's samples are reported under")
+	interval := fs.Duration("interval", 30*time.Second, "time between scrapes")
+	minPeers := fs.Int("min-peers", 1, "fewest bitswap peers a healthy node has")
+	reporter := fs.String("reporter", "", "reporter ID sent with samples (default the host name)")
+	verbose := fs.Bool("v", false, "print each sample on stderr")
+	metricsAddr := metricsAddrFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli nodestats [flags]\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 0 || *interval <= 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if *reporter == "" {
+		*reporter, _ = os.Hostname()
+	}
+
+	stopMetrics, err := serveAgentMetrics(*metricsAddr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nodestats: %v\n", err)
+		return exitUsage
+	}
+	defer stopMetrics()
+	client, err := newClient(*server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nodestats: %v\n", err)
+		return exitUnreachable
+	}
+	defer client.Close()
+	if agentMetrics != nil {
+		agentMetrics.Client(client)
+	}
+	scraper := nodestats.New(kubo.NewClient(*apiURL), *backend)
+	scraper.MinPeers = *minPeers
+	prober := client.NewProber()
+	prober.Interval = *interval
+	prober.ReporterID = *reporter
+	prober.Samplers = []routingclient.Sampler{scraper.Sample}
+	if *verbose {
+		prober.OnSample = printNodeSample
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := prober.Run(ctx); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "nodestats: %v\n", err)
+		return exitUnreachable
+	}
+	return exitOK
+}
+
+// printNodeSample reports a node sample on stderr
+func printNodeSample(s *pb.MetricSample) {
+	if !s.Success {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", s.BackendId, s.Error)
+		return
+	}
+	n := s.GetNode()
+	fmt.Fprintf(os.Stderr, "%s: %d peers, %d wanted, %d blocks received (%d duplicate), %s useful, in %s, out %s\n",
+		s.BackendId, n.GetPeers(), n.GetWantlistLength(), n.GetBlocksReceived(), n.GetDuplicateBlocksReceived(),
+		rateLabel(s.ThroughputBytesPerSecond), rateLabel(n.GetRateInBytesPerSecond()), rateLabel(n.GetRateOutBytesPerSecond()))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// offline is the offline state set up by the global --offline flag, used
+// by every client newClient creates
+var offline *routingclient.Offline
+
+// openOffline opens the offline state in dir, if set; the returned
+// function closes it, reporting outcomes still waiting to be replayed
+func openOffline(dir string) (func(), error) {
+	if dir == "" {
+		return func() {}, nil
+	}
+	var err error
+	if offline, err = routingclient.OpenOffline(dir); err != nil {
+		return nil, err
+	}
+	offline.OnOffline = func(err error) {
+		fmt.Fprintf(os.Stderr, "routing-cli: routing service unreachable, working offline: %v\n", err)
+	}
+	offline.OnReplay = func(sent int, err error) {
+		if sent > 0 {
+			fmt.Fprintf(os.Stderr, "routing-cli: replayed %d queued outcomes\n", sent)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "routing-cli: replaying queued outcomes: %v\n", err)
+		}
+	}
+	return func() {
+		err := offline.Close()
+		if n := offline.Stats().Pending; n > 0 {
+			fmt.Fprintf(os.Stderr, "routing-cli: %d outcomes queued in %s for when the service is reachable\n", n, dir)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "routing-cli: %v\n", err)
+		}
+	}, nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"sigs.k8s.io/yaml"
+)
+
+// Formats -output offers. text is the command's own table or report; the
+// others print the same result for scripts.
+const (
+	formatText = "text"
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatCSV  = "csv"
+)
+
+// outputFormats are the formats every command printing a result offers
+var outputFormats = []string{formatText, formatJSON, formatYAML, formatCSV}
+
+// outputFlag defines -output, the format a command prints its result in:
+// one of outputFormats or of the extra formats the command offers, such as
+// arrow
+func outputFlag(fs *flag.FlagSet, extra ...string) *string {
+	formats := append(outputFormats[:len(outputFormats):len(outputFormats)], extra...)
+	return fs.String("output", formatText, "output format: "+strings.Join(formats, ", "))
+}
+
+// checkOutput resolves -output against -json, the same as -output json,
+// and reports whether the format is one outputFlag offered
+func checkOutput(output *string, jsonOut bool, extra ...string) bool {
+	if jsonOut {
+		*output = formatJSON
+	}
+	formats := append(outputFormats[:len(outputFormats):len(outputFormats)], extra...)
+	for _, f := range formats {
+		if *output == f {
+			return true
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown output format %q (want %s)\n", *output, strings.Join(formats, ", "))
+	return false
+}
+
+// printStructured prints a command's result on stdout: v as -output json
+// or yaml, and rows, the records v holds, as -output csv. Both may be proto
+// messages, raw JSON or anything encoding/json marshals.
+func printStructured(format string, v, rows any) error {
+	if format == formatCSV {
+		data, err := marshalJSON(rows)
+		if err != nil {
+			return err
+		}
+		return writeCSV(os.Stdout, data)
+	}
+	data, err := marshalJSON(v)
+	if err != nil {
+		return err
+	}
+	var out []byte
+	switch format {
+	case formatJSON:
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, "", "  "); err != nil {
+			return err
+		}
+		out = append(buf.Bytes(), '\n')
+	case formatYAML:
+		if out, err = yaml.JSONToYAML(data); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("cannot print %s", format)
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// marshalJSON encodes v as JSON, proto messages as protojson does
+func marshalJSON(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case json.RawMessage:
+		return v, nil
+	case proto.Message:
+		return protojson.Marshal(v)
+	}
+	return json.Marshal(v)
+}
+
+// protoRecords encodes messages as protojson does, for printStructured
+func protoRecords[M proto.Message](messages []M) []json.RawMessage {
+	out := make([]json.RawMessage, len(messages))
+	for i, m := range messages {
+		out[i], _ = protojson.Marshal(m)
+	}
+	return out
+}
+
+// writeCSV writes the JSON records in data as CSV: the elements of an
+// array, or else data itself, are the rows, and the fields they have the
+// columns, in the order they first appear. Nested objects are flattened
+// into dotted column names; arrays of scalars are joined with ";" and
+// other arrays kept as JSON.
+func writeCSV(w io.Writer, data []byte) error {
+	data = bytes.TrimSpace(data)
+	var records []json.RawMessage
+	switch {
+	case bytes.Equal(data, []byte("null")):
+	case len(data) > 0 && data[0] == '[':
+		if err := json.Unmarshal(data, &records); err != nil {
+			return err
+		}
+	default:
+		records = []json.RawMessage{data}
+	}
+	var header []string
+	columns := make(map[string]int)
+	rows := make([][]csvField, len(records))
+	for i, rec := range records {
+		row, err := flattenJSON(rec, "", nil)
+		if err != nil {
+			return err
+		}
+		for _, f := range row {
+			if _, ok := columns[f.name]; !ok {
+				columns[f.name] = len(header)
+				header = append(header, f.name)
+			}
+		}
+		rows[i] = row
+	}
+	if len(header) == 0 {
+		return nil
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for _, row := range rows {
+		rec := make([]string, len(header))
+		for _, f := range row {
+			rec[columns[f.name]] = f.value
+		}
+		cw.Write(rec)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvField is a column of a CSV row
+type csvField struct{ name, value string }
+
+// flattenJSON appends the columns of the JSON value data to row, naming
+// them by their dotted path under prefix
+func flattenJSON(data json.RawMessage, prefix string, row []csvField) ([]csvField, error) {
+	data = bytes.TrimSpace(data)
+	name := prefix
+	if name == "" {
+		name = "value"
+	}
+	if len(data) == 0 {
+		return row, nil
+	}
+	switch data[0] {
+	case '{':
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.Token()
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := t.(string)
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			var v json.RawMessage
+			if err := dec.Decode(&v); err != nil {
+				return nil, err
+			}
+			if row, err = flattenJSON(v, key, row); err != nil {
+				return nil, err
+			}
+		}
+		return row, nil
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		values := make([]string, len(items))
+		for i, item := range items {
+			v, ok := csvScalar(item)
+			if !ok {
+				var buf bytes.Buffer
+				json.Compact(&buf, data)
+				return append(row, csvField{name, buf.String()}), nil
+			}
+			values[i] = v
+		}
+		return append(row, csvField{name, strings.Join(values, ";")}), nil
+	}
+	v, _ := csvScalar(data)
+	return append(row, csvField{name, v}), nil
+}
+
+// csvScalar returns the CSV value of a JSON string, number, boolean or
+// null, and false for an object or array
+func csvScalar(data json.RawMessage) (string, bool) {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0 || data[0] == '{' || data[0] == '[':
+		return "", false
+	case data[0] == '"':
+		var s string
+		json.Unmarshal(data, &s)
+		return s, true
+	case string(data) == "null":
+		return "", true
+	}
+	return string(data), true
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"example.com/ipfs_kit_py/p2p"
+)
+
+// listenFlag collects repeated -listen multiaddrs
+type listenFlag []string
+
+func (l *listenFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listenFlag) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// runP2P implements `routing-cli p2p <subcommand>`
+func runP2P(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprintf(os.Stderr, "Usage: routing-cli p2p id [flags]\n       routing-cli p2p serve [flags]\n")
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	switch args[0] {
+	case "id":
+		return runP2PID(args[1:])
+	case "serve":
+		return runP2PServe(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "p2p: unknown subcommand %q\n", args[0])
+	return exitUsage
+}
+
+// keyFlag registers -key, the private key file a command identifies with
+func keyFlag(fs *flag.FlagSet) *string {
+	return fs.String("key", "", "private key file, created if missing (default $"+p2p.KeyEnv+" or routing-p2p.key in the user config directory)")
+}
+
+// keyHost starts a host with the key at path, or DefaultKeyPath if empty
+func keyHost(path string, listen ...string) (*p2p.Host, error) {
+	if path == "" {
+		var err error
+		if path, err = p2p.DefaultKeyPath(); err != nil {
+			return nil, err
+		}
+	}
+	key, err := p2p.LoadKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return p2p.NewHost(key, listen...)
+}
+
+// runP2PID implements `routing-cli p2p id`, printing the peer ID this
+// client identifies itself with over libp2p
+func runP2PID(args []string) int {
+	fs := flag.NewFlagSet("p2p id", flag.ContinueOnError)
+	key := keyFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	h, err := keyHost(*key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "p2p: %v\n", err)
+		return exitUnhealthy
+	}
+	defer h.Close()
+	fmt.Println(h.ID())
+	return exitOK
+}
+
+// runP2PServe implements `routing-cli p2p serve`: it exposes a routing
+// server and an IPFS gateway that only listen locally over libp2p, for
+// clients to dial by the printed multiaddrs, until interrupted
+func runP2PServe(args []string) int {
+	fs := flag.NewFlagSet("p2p serve", flag.ContinueOnError)
+	key := keyFlag(fs)
+	listen := listenFlag{}
+	fs.Var(&listen, "listen", "multiaddr to listen on, repeatable (default /ip4/0.0.0.0/tcp/4011 and /ip4/0.0.0.0/udp/4011/quic-v1)")
+	routing := fs.String("routing", "localhost:50051", "routing gRPC server to forward "+string(p2p.RoutingProtocol)+" to, host:port or unix:/path (empty to skip)")
+	gateway := fs.String("gateway", "", "IPFS gateway to forward "+string(p2p.GatewayProtocol)+" to, host:port (empty to skip)")
+	verbose := fs.Bool("v", false, "log each forwarded stream on stderr")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if *routing == "" && *gateway == "" {
+		fmt.Fprintln(os.Stderr, "p2p: nothing to serve; set -routing and/or -gateway")
+		return exitUsage
+	}
+	if len(listen) == 0 {
+		listen = listenFlag{"/ip4/0.0.0.0/tcp/4011", "/ip4/0.0.0.0/udp/4011/quic-v1"}
+	}
+
+	h, err := keyHost(*key, listen...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "p2p: %v\n", err)
+		return exitUnreachable
+	}
+	defer h.Close()
+	logStreams := func(name string) func(string, error) {
+		if !*verbose {
+			return nil
+		}
+		return func(peer string, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s from %s: %v\n", name, peer, err)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "%s from %s\n", name, peer)
+		}
+	}
+	if *routing != "" {
+		h.Forward(p2p.RoutingProtocol, *routing, logStreams("routing"))
+	}
+	if *gateway != "" {
+		h.Forward(p2p.GatewayProtocol, *gateway, logStreams("gateway"))
+	}
+	for _, a := range h.Multiaddrs() {
+		fmt.Println(a)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	<-ctx.Done()
+	return exitOK
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"example.com/ipfs_kit_py/executor"
+	"example.com/ipfs_kit_py/ipfscluster"
+	"example.com/ipfs_kit_py/kubo"
+	"example.com/ipfs_kit_py/pinning"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// nodeFlag collects repeated -node backend=url flags
+type nodeFlag map[string]string
+
+func (n nodeFlag) String() string {
+	parts := make([]string, 0, len(n))
+	for id, u := range n {
+		parts = append(parts, id+"="+u)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (n nodeFlag) Set(s string) error {
+	id, u, ok := strings.Cut(s, "=")
+	if !ok || id == "" || u == "" {
+		return fmt.Errorf("want backend=url, got %q", s)
+	}
+	n[id] = u
+	return nil
+}
+
+// clients returns a Kubo client per backend. If neither n nor any of the
+// other backend flags are set, it defaults to a single "ipfs" backend at
+// $IPFS_API_URL.
+func (n nodeFlag) clients(others ...nodeFlag) map[string]*kubo.Client {
+	configured := len(n)
+	for _, o := range others {
+		configured += len(o)
+	}
+	if configured == 0 {
+		return map[string]*kubo.Client{executor.IPFSClass: kubo.NewClient("")}
+	}
+	nodes := make(map[string]*kubo.Client, len(n))
+	for id, u := range n {
+		nodes[id] = kubo.NewClient(u)
+	}
+	return nodes
+}
+
+// clusterClients returns an IPFS Cluster client per backend
+func (n nodeFlag) clusterClients() map[string]*ipfscluster.Client {
+	clusters := make(map[string]*ipfscluster.Client, len(n))
+	for id, u := range n {
+		clusters[id] = ipfscluster.NewClient(u)
+	}
+	return clusters
+}
+
+// serviceClients returns a Pinning Service API client per backend
+func (n nodeFlag) serviceClients() map[string]*pinning.Client {
+	services := make(map[string]*pinning.Client, len(n))
+	for id, u := range n {
+		services[id] = pinning.NewClient(u, "")
+	}
+	return services
+}
+
+// runPins implements `routing-cli pins <subcommand>`
+func runPins(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Fprintf(os.Stderr, "Usage: routing-cli pins ls [flags] [cid...]\n       routing-cli pins add [flags] <cid>\n       routing-cli pins rm [flags] -backend <id> <cid>\n")
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+	switch args[0] {
+	case "ls":
+		return runPinsList(args[1:])
+	case "add", "rm":
+		return runPinsChange(args[0], args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "pins: unknown subcommand %q\n", args[0])
+	return exitUsage
+}
+
+// runPinsList implements `routing-cli pins ls`, auditing which backends
+// pin what: every recursive pin, or the given CIDs on every backend
+func runPinsList(args []string) int {
+	fs := flag.NewFlagSet("pins ls", flag.ContinueOnError)
+	nodes := nodeFlag{}
+	fs.Var(nodes, "node", "IPFS backend as backend=api-url, repeatable (default ipfs=$IPFS_API_URL)")
+	clusters := nodeFlag{}
+	fs.Var(clusters, "cluster", "IPFS Cluster backend as backend=rest-api-url, repeatable; user:pass@ in the URL is sent as basic auth")
+	services := nodeFlag{}
+	fs.Var(services, "service", "remote pinning service as backend=endpoint, repeatable; token@ in the URL is sent as the bearer token")
+	timeout := fs.Duration("timeout", 30*time.Second, "deadline for the audit")
+	jsonOut := fs.Bool("json", false, "print pins as JSON")
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if !checkOutput(output, *jsonOut) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	pinner := executor.NewPinner(nil, nodes.clients(clusters, services)).
+		WithClusters(clusters.clusterClients(), ipfscluster.PinOptions{}).
+		WithServices(services.serviceClients())
+	type pin struct {
+		CID     string `json:"cid"`
+		Backend string `json:"backend"`
+		Type    string `json:"type"`
+	}
+	var pins []pin
+	code := exitOK
+	for _, id := range pinner.Backends() {
+		found, err := pinner.List(ctx, id, fs.Args()...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "pins: %s: %v\n", id, err)
+			code = exitUnreachable
+			continue
+		}
+		for cid, typ := range found {
+			// Listing everything, indirect pins would drown out the roots
+			if fs.NArg() == 0 && typ == "indirect" {
+				continue
+			}
+			pins = append(pins, pin{CID: cid, Backend: id, Type: typ})
+		}
+	}
+	sort.Slice(pins, func(i, j int) bool {
+		if pins[i].CID != pins[j].CID {
+			return pins[i].CID < pins[j].CID
+		}
+		return pins[i].Backend < pins[j].Backend
+	})
+
+	if *output != formatText {
+		if err := printStructured(*output, pins, pins); err != nil {
+			fmt.Fprintf(os.Stderr, "pins: %v\n", err)
+			return exitUnhealthy
+		}
+		return code
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CID\tBACKEND\tTYPE")
+	for _, p := range pins {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", p.CID, p.Backend, p.Type)
+	}
+	// Requested CIDs pinned nowhere are what an audit is looking for
+	for _, cid := range fs.Args() {
+		pinned := false
+		for _, p := range pins {
+			pinned = pinned || p.CID == cid
+		}
+		if !pinned {
+			fmt.Fprintf(tw, "%s\t-\tnot pinned\n", cid)
+		}
+	}
+	tw.Flush()
+	return code
+}
+
+// runPinsChange implements `routing-cli pins add` and `pins rm`. add pins
+// on the IPFS, cluster or pinning service backend the router selects, or on
+// -backend; rm unpins from -backend. Both record the outcome with the routing service.
+func runPinsChange(name string, args []string) int {
+	fs := flag.NewFlagSet("pins "+name, flag.ContinueOnError)
+	server := fs.String("server", "localhost:50051", "gRPC server address: host:port, unix:///path, or a gRPC-Web gateway URL")
+	nodes := nodeFlag{}
+	fs.Var(nodes, "node", "IPFS backend as backend=api-url, repeatable (default ipfs=$IPFS_API_URL)")
+	clusters := nodeFlag{}
+	fs.Var(clusters, "cluster", "IPFS Cluster backend as backend=rest-api-url, repeatable; user:pass@ in the URL is sent as basic auth")
+	services := nodeFlag{}
+	fs.Var(services, "service", "remote pinning service as backend=endpoint, repeatable; token@ in the URL is sent as the bearer token")
+	strategy := fs.String("strategy", "hybrid", "routing strategy used to pick the backend (add)")
+	backend := fs.String("backend", "", "backend to unpin from (rm), or to pin on instead of asking the router (add)")
+	replMin := fs.Int("replication-min", 0, "minimum cluster peers to pin on; 0 uses the cluster default, -1 every peer (add)")
+	replMax := fs.Int("replication-max", 0, "maximum cluster peers to pin on; 0 uses the cluster default, -1 every peer (add)")
+	pinName := fs.String("name", "", "pin name recorded by the cluster or pinning service (add)")
+	size := fs.Int64("size", 0, "content size in bytes, sent with the routing request")
+	timeout := fs.Duration("timeout", 5*time.Minute, "deadline for the operation; recursive pins can be slow")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli pins %s [flags] <cid>\n\n", name)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 1 || (name == "rm" && *backend == "") {
+		fs.Usage()
+		return exitUsage
+	}
+	cid := fs.Arg(0)
+
+	client, err := newClient(*server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pins: %v\n", err)
+		return exitUnreachable
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	replication := ipfscluster.PinOptions{ReplicationMin: *replMin, ReplicationMax: *replMax, Name: *pinName}
+	pinner := executor.NewPinner(client, nodes.clients(clusters, services)).
+		WithClusters(clusters.clusterClients(), replication).
+		WithServices(services.serviceClients())
+	info := routingclient.ContentInfo{ContentSize: *size, Metadata: map[string]string{"cid": cid}}
+
+	if name == "add" {
+		id := *backend
+		var allocations []string
+		if id != "" {
+			allocations, err = pinner.AddTo(ctx, info, id, cid)
+		} else {
+			id, allocations, err = pinner.Add(ctx, info, *strategy, cid)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "pins: %v\n", err)
+			return exitUnhealthy
+		}
+		fmt.Printf("pinned %s on %s\n", cid, id)
+		if len(allocations) > 0 {
+			fmt.Printf("allocated to %s\n", strings.Join(allocations, ", "))
+		}
+		return exitOK
+	}
+	if err := pinner.Remove(ctx, info, *backend, cid); err != nil {
+		fmt.Fprintf(os.Stderr, "pins: %v\n", err)
+		return exitUnhealthy
+	}
+	fmt.Printf("unpinned %s from %s\n", cid, *backend)
+	return exitOK
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"example.com/ipfs_kit_py/cidutil"
+	"example.com/ipfs_kit_py/erasure"
+	"example.com/ipfs_kit_py/kubo"
+	"example.com/ipfs_kit_py/retrieval"
+)
+
+// runReconstruct implements `routing-cli reconstruct`: it reassembles a
+// striped object from its manifest, given as a file or a CID, reading
+// shards with CIDs from the local node and gateways as get does and other
+// shards from their HTTP locations, and rebuilding any that are lost
+func runReconstruct(args []string) int {
+	fs := flag.NewFlagSet("reconstruct", flag.ContinueOnError)
+	gateways := gatewayFlag{}
+	fs.Var(&gateways, "gateway", "gateway as [backend=]url or [backend=]multiaddr/p2p/<peer ID>, repeatable, tried after the local node (default "+strings.Join(defaultGateways, ", ")+")")
+	local := fs.Bool("local", true, "try the local Kubo node first")
+	apiURL := fs.String("ipfs-api", "", "Kubo RPC API URL (default $IPFS_API_URL or "+kubo.DefaultAPIURL+")")
+	output := fs.String("o", "", "write the object to this file (default stdout)")
+	timeout := fs.Duration("timeout", 30*time.Minute, "deadline for the whole reconstruction")
+	verbose := fs.Bool("v", false, "report shards that could not be used on stderr")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli reconstruct [flags] <manifest.json|manifest CID>\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	r := &retrieval.Retriever{Verify: true}
+	if *local {
+		r.Sources = append(r.Sources, retrieval.Local("", kubo.NewClient(*apiURL)))
+	}
+	sources, err := gateways.sources()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reconstruct: %v\n", err)
+		return exitUsage
+	}
+	r.Sources = append(r.Sources, sources...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	m, err := loadManifest(ctx, r, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reconstruct: manifest: %v\n", err)
+		return exitUnhealthy
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "reconstruct: %v\n", err)
+			return exitUsage
+		}
+		defer f.Close()
+		out = f
+	}
+	report, err := erasure.Reconstruct(ctx, m, shardOpener(r), out, "")
+	if report != nil && *verbose {
+		for _, f := range report.Failed {
+			fmt.Fprintf(os.Stderr, "shard %d on %s: %v\n", f.Index, m.Shards[f.Index].BackendID, f.Err)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reconstruct: %v\n", err)
+		return exitUnhealthy
+	}
+	if *verbose || *output != "" {
+		fmt.Fprintf(os.Stderr, "reassembled %d bytes from %d of %d shards", m.Size, report.Read, len(m.Shards))
+		if n := len(report.Failed); n > 0 {
+			fmt.Fprintf(os.Stderr, ", %d lost", n)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+	return exitOK
+}
+
+// loadManifest reads a manifest from a file, or through r if ref is a CID
+func loadManifest(ctx context.Context, r *retrieval.Retriever, ref string) (*erasure.Manifest, error) {
+	var data []byte
+	if _, err := cidutil.ParseCID(ref); err == nil {
+		res, err := r.Get(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if data, err = io.ReadAll(res.Body); err != nil {
+			return nil, err
+		}
+	} else if data, err = os.ReadFile(ref); err != nil {
+		return nil, err
+	}
+	var m erasure.Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// shardOpener reads shards with a CID through r, and others from their
+// location if it is an HTTP URL
+func shardOpener(r *retrieval.Retriever) erasure.OpenFunc {
+	return func(ctx context.Context, s erasure.Shard) (io.ReadCloser, error) {
+		return openStored(ctx, r, s.CID, s.Location)
+	}
+}
+
+// openStored reads stored content by its CID through r if it has one, and
+// otherwise from its location if that is an HTTP URL
+func openStored(ctx context.Context, r *retrieval.Retriever, cid, location string) (io.ReadCloser, error) {
+	if cid != "" {
+		res, err := r.Get(ctx, cid)
+		if err != nil {
+			return nil, err
+		}
+		return res.Body, nil
+	}
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return nil, fmt.Errorf("no CID, and %q is not an HTTP URL", location)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: HTTP %s", location, resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// replHelp lists the REPL's commands
+const replHelp = `Commands:
+  select [flags] [file]   select a backend for file, or for -type, -size, -hash and -name
+  record [flags]          record the outcome of the last selection
+  insights [flags]        show factor weights, backend scores and success rates
+  backends                list the backends
+  set <name> <value>      set a session variable, used as $name
+  unset <name>            remove a session variable
+  vars                    list the session variables
+  help                    show this list
+  exit                    leave (or Ctrl-D)
+
+Run '<command> -h' for its flags. select sets $backend and $request; a
+variable named like a select or record flag is its default.
+`
+
+// repl is an interactive session: one connection to the routing service
+// and the variables set in it
+type repl struct {
+	client  *routingclient.Client
+	timeout time.Duration
+	vars    map[string]string
+	// last is the content of the last selection, which record reports
+	// the outcome of
+	last *routingclient.ContentInfo
+}
+
+// runRepl implements `routing-cli repl`: a prompt running select, record
+// and insights over one connection, with session variables, for exploring
+// a deployment and debugging with support
+func runRepl(args []string) int {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	server := fs.String("server", "localhost:50051", "gRPC routing server address")
+	strategy := fs.String("strategy", "hybrid", "initial value of $strategy, the routing strategy select uses")
+	timeout := fs.Duration("timeout", 10*time.Second, "deadline for each command's calls")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli repl [flags]\n\nReads commands from stdin; run 'help' at the prompt for the list.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	if _, err := routingclient.ParseStrategy(*strategy); err != nil {
+		fmt.Fprintf(os.Stderr, "repl: %v\n", err)
+		return exitUsage
+	}
+
+	client, err := newClient(*server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "repl: %v\n", err)
+		return exitUnreachable
+	}
+	defer client.Close()
+	r := &repl{
+		client:  client,
+		timeout: *timeout,
+		vars:    map[string]string{"server": *server, "strategy": *strategy},
+	}
+	return r.run(os.Stdin, os.Stdout)
+}
+
+// run reads and runs commands from in until it ends or exit is run,
+// printing the prompt to out
+func (r *repl) run(in io.Reader, out io.Writer) int {
+	// Ctrl-C cancels the running command rather than the session
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	sc := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "routing> ")
+		if !sc.Scan() {
+			fmt.Fprintln(out)
+			return exitOK
+		}
+		words, err := r.expand(sc.Text())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			continue
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if words[0] == "exit" || words[0] == "quit" {
+			return exitOK
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-interrupts:
+				cancel()
+			case <-done:
+			}
+		}()
+		if err := r.exec(ctx, words); err != nil && !errors.Is(err, errReported) {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", words[0], err)
+		}
+		close(done)
+		cancel()
+	}
+}
+
+// exec runs one command
+func (r *repl) exec(ctx context.Context, words []string) error {
+	name, args := words[0], words[1:]
+	switch name {
+	case "select":
+		return r.selectBackend(ctx, args)
+	case "record":
+		return r.record(ctx, args)
+	case "insights":
+		return r.insights(ctx, args)
+	case "backends":
+		return r.backends(ctx, args)
+	case "set":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: set <name> <value>")
+		}
+		r.vars[args[0]] = strings.Join(args[1:], " ")
+		return nil
+	case "unset":
+		for _, v := range args {
+			delete(r.vars, v)
+		}
+		return nil
+	case "vars":
+		names := make([]string, 0, len(r.vars))
+		for v := range r.vars {
+			names = append(names, v)
+		}
+		sort.Strings(names)
+		for _, v := range names {
+			fmt.Printf("%s=%s\n", v, r.vars[v])
+		}
+		return nil
+	case "help", "?":
+		fmt.Print(replHelp)
+		return nil
+	}
+	return fmt.Errorf("unknown command; run 'help' for the list")
+}
+
+// expand splits line into words, as a shell would: quotes group words, and
+// $name or ${name} outside single quotes is replaced by the session
+// variable name
+func (r *repl) expand(line string) ([]string, error) {
+	var (
+		words  []string
+		word   strings.Builder
+		inWord bool
+		quote  rune
+	)
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote, inWord = c, true
+		case quote == 0 && (c == ' ' || c == '\t'):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '$' && quote != '\'':
+			var name string
+			if i+1 < len(runes) && runes[i+1] == '{' {
+				end := i + 2
+				for end < len(runes) && runes[end] != '}' {
+					end++
+				}
+				if end == len(runes) {
+					return nil, fmt.Errorf("unterminated ${")
+				}
+				name, i = string(runes[i+2:end]), end
+			} else {
+				end := i + 1
+				for end < len(runes) && isVarRune(runes[end]) {
+					end++
+				}
+				if end == i+1 {
+					word.WriteRune(c)
+					inWord = true
+					continue
+				}
+				name, i = string(runes[i+1:end]), end-1
+			}
+			v, ok := r.vars[name]
+			if !ok {
+				return nil, fmt.Errorf("$%s is not set", name)
+			}
+			word.WriteString(v)
+			inWord = true
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// isVarRune reports whether c can be part of a variable name
+func isVarRune(c rune) bool {
+	return c == '_' || c == '-' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// errReported is returned by REPL commands whose error, or help, has been
+// printed already
+var errReported = errors.New("reported")
+
+// flagSet returns a FlagSet for a REPL command
+func (r *repl) flagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s\n\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses a REPL command's arguments, which take at most maxArgs
+// positional arguments
+func parse(fs *flag.FlagSet, args []string, maxArgs int) error {
+	if err := fs.Parse(args); err != nil {
+		return errReported
+	}
+	if fs.NArg() > maxArgs {
+		fs.Usage()
+		return errReported
+	}
+	return nil
+}
+
+// selectBackend implements select, remembering the content and decision
+// for record
+func (r *repl) selectBackend(ctx context.Context, args []string) error {
+	fs := r.flagSet("select", "select [flags] [file]")
+	contentType := fs.String("type", r.vars["type"], "content MIME type")
+	size := fs.Int64("size", 0, "content size in bytes")
+	hash := fs.String("hash", r.vars["hash"], "content hash")
+	filename := fs.String("name", r.vars["name"], "file name")
+	strategy := fs.String("strategy", r.vars["strategy"], "routing strategy")
+	dryRun := fs.Bool("dry-run", false, "ask which backend would be chosen without the selection counting")
+	jsonOut := fs.Bool("json", false, "print the response as JSON")
+	if v, ok := r.vars["size"]; ok {
+		if err := fs.Set("size", v); err != nil {
+			return fmt.Errorf("$size: %v", err)
+		}
+	}
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	s, err := routingclient.ParseStrategy(*strategy)
+	if err != nil {
+		return err
+	}
+	info := routingclient.ContentInfo{ContentType: *contentType, ContentSize: *size, ContentHash: *hash, Filename: *filename}
+	if fs.NArg() == 1 {
+		if info, err = routingclient.ContentInfoFromFile(fs.Arg(0)); err != nil {
+			return err
+		}
+	}
+
+	var resp *pb.SelectBackendResponse
+	if *dryRun {
+		resp, err = r.client.DryRun(ctx, info, s)
+	} else {
+		resp, err = r.client.Select(ctx, info, s)
+	}
+	if err != nil {
+		return err
+	}
+	if !*dryRun {
+		r.last = &info
+		r.vars["backend"] = resp.BackendId
+		r.vars["request"] = resp.RequestId
+	}
+	if *jsonOut {
+		data, _ := protojson.MarshalOptions{Multiline: true}.Marshal(resp)
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Printf("%s (score %.3f)\n", resp.BackendId, resp.Score)
+	if resp.Reasoning != "" {
+		fmt.Printf("  %s\n", resp.Reasoning)
+	}
+	factors := resp.GetFactorScores().GetFields()
+	names := make([]string, 0, len(factors))
+	for f := range factors {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	for _, f := range names {
+		fmt.Printf("  %-22s %.3f\n", f, factors[f].GetNumberValue())
+	}
+	for _, alt := range resp.Alternatives {
+		fmt.Printf("  alternative %s (score %.3f)\n", alt.BackendId, alt.Score)
+	}
+	return nil
+}
+
+// record implements record, reporting the outcome of the last selection
+func (r *repl) record(ctx context.Context, args []string) error {
+	fs := r.flagSet("record", "record [flags]")
+	backend := fs.String("backend", r.vars["backend"], "backend the operation used")
+	failed := fs.String("error", "", "record a failure with this error")
+	duration := fs.Duration("duration", 0, "how long the operation took")
+	bytes := fs.Int64("bytes", 0, "how much data the operation moved")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if r.last == nil {
+		return fmt.Errorf("nothing selected yet")
+	}
+	if *backend == "" {
+		return fmt.Errorf("-backend is required")
+	}
+	outcome := routingclient.Outcome{BackendID: *backend, Success: *failed == "", Duration: *duration, Bytes: *bytes}
+	if *failed != "" {
+		outcome.Err = errors.New(*failed)
+	}
+	resp, err := r.client.RecordOutcome(ctx, *r.last, outcome)
+	if err != nil {
+		return err
+	}
+	fmt.Println(resp.Message)
+	return nil
+}
+
+// insights implements insights
+func (r *repl) insights(ctx context.Context, args []string) error {
+	fs := r.flagSet("insights", "insights [flags]")
+	window := fs.Duration("window", 0, "period to report on, rounded up to whole hours (default the server's, 24h)")
+	jsonOut := fs.Bool("json", false, "print the response as JSON")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	resp, err := r.client.GetInsights(ctx, *window)
+	if err != nil {
+		return err
+	}
+	if *jsonOut {
+		data, _ := protojson.MarshalOptions{Multiline: true}.Marshal(resp)
+		fmt.Println(string(data))
+		return nil
+	}
+	printInsights(snapshotInsights(resp))
+	return nil
+}
+
+// backends implements backends
+func (r *repl) backends(ctx context.Context, args []string) error {
+	fs := r.flagSet("backends", "backends")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	backends, err := r.client.ListBackends(ctx, routingclient.ContentInfo{})
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BACKEND\tSTATE\tREGION")
+	for _, b := range backends {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", b.BackendId, enumLabel(b.State.String(), "BACKEND_STATE_"), b.Region)
+	}
+	return tw.Flush()
+}
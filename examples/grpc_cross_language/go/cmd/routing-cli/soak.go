@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"example.com/ipfs_kit_py/mcpclient"
+	"example.com/ipfs_kit_py/routingclient"
+	"example.com/ipfs_kit_py/soak"
+)
+
+// runSoak implements `routing-cli soak`. It exits exitOK when every
+// invariant held and exitUnhealthy when any was violated.
+func runSoak(args []string) int {
+	fs := flag.NewFlagSet("soak", flag.ContinueOnError)
+	mcpURL := fs.String("mcp", mcpclient.DefaultBaseURL, "MCP dashboard base URL")
+	token := fs.String("token", os.Getenv("MCP_API_TOKEN"), "MCP API token")
+	server := fs.String("server", "", "gRPC routing server address: host:port, unix:///path, or a gRPC-Web gateway URL (empty to skip routing operations)")
+	restFallback := fs.String("rest-fallback", "", "HTTP routing API base URL to use when the gRPC server is unreachable")
+	bucket := fs.String("bucket", "soak", "bucket used for soak objects")
+	duration := fs.Duration("duration", time.Hour, "how long to run")
+	workers := fs.Int("workers", 4, "concurrent workers")
+	seed := fs.Int64("seed", 0, "random seed (0 picks one from the clock)")
+	maxSize := fs.Int("max-size", 256<<10, "maximum object size in bytes")
+	cancelRate := fs.Float64("fault-cancel", 0, "fraction of operations cancelled mid-flight")
+	failRate := fs.Float64("fault-outcome", 0, "fraction of routing outcomes reported as failures")
+	sample := fs.Duration("sample", 30*time.Second, "memory sampling and progress interval")
+	maxGrowth := fs.Float64("max-memory-growth", 0.25, "allowed fractional server memory growth")
+	report := fs.String("report", "", "write the JSON report to this file")
+	var ka routingclient.Keepalive
+	ka.RegisterFlags(fs)
+	var comp routingclient.Compression
+	comp.RegisterFlags(fs)
+	var px routingclient.Proxy
+	px.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	cfg := soak.Config{
+		MCP:             mcpclient.NewClient(*mcpURL, *token),
+		Bucket:          *bucket,
+		Duration:        *duration,
+		Concurrency:     *workers,
+		Seed:            *seed,
+		MaxObjectSize:   *maxSize,
+		Faults:          soak.Faults{CancelRate: *cancelRate, FailOutcomeRate: *failRate},
+		HealthURL:       *mcpURL,
+		SampleInterval:  *sample,
+		MaxMemoryGrowth: *maxGrowth,
+		OnProgress: func(r soak.Report) {
+			var ok, failed int64
+			for _, s := range r.Ops {
+				ok += s.OK
+				failed += s.Failed
+			}
+			fmt.Fprintf(os.Stderr, "%s ops=%d failed=%d objects=%d violations=%d\n",
+				r.Elapsed.Round(time.Second), ok+failed, failed, r.Objects, len(r.Violations))
+		},
+	}
+	if *server != "" {
+		proxyOpt, err := px.DialOption()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "soak: %v\n", err)
+			return exitUsage
+		}
+		conn, err := routingclient.Dial(*server, grpc.WithTransportCredentials(routingclient.TransportCredentials(*server)), ka.DialOption(), proxyOpt)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "soak: %v\n", err)
+			return exitUnreachable
+		}
+		if *restFallback != "" {
+			conn = routingclient.NewFallbackConn(conn, routingclient.NewRESTConn(*restFallback, nil))
+		}
+		conn = trafficConn(conn)
+		defer conn.Close()
+		cfg.Routing = routingclient.NewClient(conn)
+		if err := cfg.Routing.SetCompression(comp); err != nil {
+			fmt.Fprintf(os.Stderr, "soak: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	// Ctrl-C ends the run early but still produces a report.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	rep, err := soak.Run(ctx, cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUnreachable
+	}
+	fmt.Print(rep.Summary())
+	if *report != "" {
+		data, _ := json.MarshalIndent(rep, "", "  ")
+		if err := os.WriteFile(*report, data, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "soak: write report: %v\n", err)
+		}
+	}
+	if !rep.Passed {
+		return exitUnhealthy
+	}
+	return exitOK
+}
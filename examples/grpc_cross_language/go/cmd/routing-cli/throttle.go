@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"example.com/ipfs_kit_py/throttle"
+)
+
+// rateUnits are the multipliers of the units a rate may be given in
+var rateUnits = map[string]float64{
+	"": 1, "b": 1,
+	"k": 1e3, "kb": 1e3, "kib": 1 << 10,
+	"m": 1e6, "mb": 1e6, "mib": 1 << 20,
+	"g": 1e9, "gb": 1e9, "gib": 1 << 30,
+}
+
+// rateFlag is a bandwidth limit in bytes per second, given as e.g.
+// "50MiB", "20M" or "1.5GB/s"; 0 is unlimited
+type rateFlag int64
+
+func (f *rateFlag) String() string { return sizeLabel(int64(*f)) }
+
+func (f *rateFlag) Set(s string) error {
+	s = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "/s")
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	v, err := strconv.ParseFloat(s[:i], 64)
+	unit, ok := rateUnits[strings.TrimSpace(s[i:])]
+	if err != nil || !ok || v < 0 {
+		return fmt.Errorf("want a rate such as 50MiB or 20M, got %q", s)
+	}
+	*f = rateFlag(v * unit)
+	return nil
+}
+
+// throttleFlags are the bandwidth limits of a command moving content
+type throttleFlags struct {
+	upload, download, transfer rateFlag
+}
+
+// register adds the -max-*-rate flags to fs
+func (t *throttleFlags) register(fs *flag.FlagSet) {
+	fs.Var(&t.upload, "max-upload-rate", "limit on the bandwidth of all uploads together, e.g. 50MiB (default unlimited)")
+	fs.Var(&t.download, "max-download-rate", "limit on the bandwidth of all reads together, e.g. 50MiB (default unlimited)")
+	fs.Var(&t.transfer, "max-transfer-rate", "limit on the bandwidth of each upload or read on its own (default unlimited)")
+}
+
+// uploads returns the limits for uploads, or nil for none
+func (t *throttleFlags) uploads() *throttle.Limits {
+	return throttleLimits(t.upload, t.transfer)
+}
+
+// downloads returns the limits for reads, or nil for none
+func (t *throttleFlags) downloads() *throttle.Limits {
+	return throttleLimits(t.download, t.transfer)
+}
+
+func throttleLimits(total, each rateFlag) *throttle.Limits {
+	if total <= 0 && each <= 0 {
+		return nil
+	}
+	return &throttle.Limits{Total: throttle.NewLimiter(int64(total)), PerTransfer: int64(each)}
+}
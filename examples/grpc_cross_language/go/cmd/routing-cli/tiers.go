@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/ipfs_kit_py/executor"
+	"example.com/ipfs_kit_py/kubo"
+	"example.com/ipfs_kit_py/pubsub"
+	"example.com/ipfs_kit_py/retrieval"
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// runTiers implements `routing-cli tiers`: a daemon that keeps the content
+// listed in its state file in the hot, warm, cold or archive tier its age,
+// size and access score call for, re-routing content whose tier changes
+// and appending every transition to a log
+func runTiers(args []string) int {
+	fs := flag.NewFlagSet("tiers", flag.ContinueOnError)
+	server := fs.String("server", "localhost:50051", "gRPC routing server address")
+	strategy := fs.String("strategy", "hybrid", "routing strategy re-routed content is uploaded with")
+	targets := nodeFlag{}
+	fs.Var(targets, "to", targetUsage)
+	statePath := fs.String("state", "tiers.json", "JSON array of the tracked objects, rewritten after every evaluation")
+	logPath := fs.String("log", "tiers.transitions.jsonl", "file every transition is appended to")
+	policyPath := fs.String("policy", "", "JSON array of tier definitions, hot first (default a week hot, 90 days warm, a year cold, then archive)")
+	gateways := gatewayFlag{}
+	fs.Var(&gateways, "gateway", "gateway to read content by CID from, as for get, repeatable (default "+strings.Join(defaultGateways, ", ")+")")
+	local := fs.Bool("local", true, "try the local Kubo node first when reading content")
+	apiURL := fs.String("ipfs-api", "", "Kubo RPC API URL (default $IPFS_API_URL or "+kubo.DefaultAPIURL+")")
+	namespace := fs.String("pubsub", "", "pubsub namespace whose successful outcomes count as accesses to the content they name (empty to skip)")
+	halfLife := fs.Duration("half-life", 24*time.Hour, "time for an access to count half as much")
+	minAge := fs.Duration("min-age", 0, "keep content in its tier for at least this long after it was stored")
+	interval := fs.Duration("interval", time.Hour, "time between evaluations")
+	checkpoints := fs.String("checkpoints", "tiers.checkpoints", "directory saving the progress of uploads, so interrupted moves resume rather than restart (empty to keep none)")
+	remove := fs.Bool("remove", false, "delete content from its old tier's backend once it has moved, where -to can")
+	once := fs.Bool("once", false, "evaluate once and exit")
+	plan := fs.Bool("plan", false, "print the transitions an evaluation would make now and exit, moving nothing")
+	output := outputFlag(fs)
+	dealWait := fs.Duration("deal-wait", 24*time.Hour, "how long to wait on exit for Filecoin deals to activate; deals still pending are listed")
+	verbose := fs.Bool("v", false, "print each transition on stderr")
+	metricsAddr := metricsAddrFlag(fs)
+	var throttles throttleFlags
+	throttles.register(fs)
+	var admission admissionFlags
+	admission.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli tiers [flags]\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 0 || (len(targets) == 0 && !*plan) || *interval <= 0 || !checkOutput(output, false) {
+		fs.Usage()
+		return exitUsage
+	}
+	tiers := executor.DefaultTiers()
+	if *policyPath != "" {
+		data, err := os.ReadFile(*policyPath)
+		if err == nil {
+			err = json.Unmarshal(data, &tiers)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tiers: policy: %v\n", err)
+			return exitUsage
+		}
+	}
+	var objects []executor.StoredObject
+	if data, err := os.ReadFile(*statePath); err == nil {
+		if err := json.Unmarshal(data, &objects); err != nil {
+			fmt.Fprintf(os.Stderr, "tiers: state: %v\n", err)
+			return exitUsage
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "tiers: state: %v\n", err)
+		return exitUsage
+	}
+	if *plan {
+		lifecycle := &executor.Lifecycle{Popularity: executor.NewPopularity(*halfLife), MinAge: *minAge, Tiers: tiers}
+		t := &executor.Tiering{Lifecycle: lifecycle}
+		for _, obj := range objects {
+			t.Track(obj)
+		}
+		now := time.Now()
+		return printTierPlan(*output, lifecycle.Plan(t.Objects(now), now))
+	}
+	client, err := newClient(*server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tiers: %v\n", err)
+		return exitUnreachable
+	}
+	defer client.Close()
+	registry, err := targetRegistry(targets, checkpointStore(*checkpoints), client)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tiers: %v\n", err)
+		return exitUsage
+	}
+	defer waitForDeals(registry, *dealWait)
+	admission.apply(registry)
+	registry.SetStorageClasses(executor.DefaultStorageClasses())
+
+	r := &retrieval.Retriever{Verify: true, Throttle: throttles.downloads()}
+	if *local {
+		r.Sources = append(r.Sources, retrieval.Local("", kubo.NewClient(*apiURL)))
+	}
+	sources, err := gateways.sources()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tiers: %v\n", err)
+		return exitUsage
+	}
+	r.Sources = append(r.Sources, sources...)
+
+	stopMetrics, err := serveAgentMetrics(*metricsAddr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tiers: %v\n", err)
+		return exitUsage
+	}
+	defer stopMetrics()
+	if agentMetrics != nil {
+		agentMetrics.Client(client)
+		agentMetrics.Sources(r)
+	}
+	log, err := os.OpenFile(*logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tiers: %v\n", err)
+		return exitUsage
+	}
+	defer log.Close()
+
+	var mu sync.Mutex
+	enc := json.NewEncoder(log)
+	failed := false
+	t := &executor.Tiering{
+		Client: client,
+		Lifecycle: &executor.Lifecycle{
+			Popularity: executor.NewPopularity(*halfLife),
+			MinAge:     *minAge,
+			Tiers:      tiers,
+		},
+		Registry: registry,
+		Strategy: *strategy,
+		Open: func(ctx context.Context, rec executor.PlacementRecord) (io.ReadCloser, error) {
+			return openStored(ctx, r, rec.CID, rec.Location)
+		},
+		Remove:   *remove,
+		Interval: *interval,
+	}
+	t.OnTransition = func(tr executor.Transition) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := enc.Encode(tr); err != nil {
+			fmt.Fprintf(os.Stderr, "tiers: log: %v\n", err)
+		}
+		if tr.Error != "" {
+			failed = true
+			fmt.Fprintf(os.Stderr, "tiers: %s %s -> %s: %s\n", tr.CID, tr.From, tr.To, tr.Error)
+		} else if *verbose {
+			fmt.Fprintf(os.Stderr, "%s %s -> %s (score %.2f), now at %s\n", tr.CID, tr.From, tr.To, tr.Score, tr.ToPlacement.Location)
+		}
+	}
+	t.OnStep = func() {
+		if err := saveTierState(*statePath, t.Objects(time.Now())); err != nil {
+			fmt.Fprintf(os.Stderr, "tiers: state: %v\n", err)
+		}
+	}
+	for _, obj := range objects {
+		t.Track(obj)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if limits := throttles.uploads(); limits != nil {
+		ctx = executor.WithThrottle(ctx, limits)
+	}
+	if historyStore != nil {
+		ctx = executor.WithPlacementLog(ctx, historyStore)
+	}
+	if *once {
+		t.Step(ctx, time.Now())
+		t.OnStep()
+		if failed {
+			return exitUnhealthy
+		}
+		return exitOK
+	}
+	if *namespace != "" {
+		sub := pubsub.NewSubscriber(kubo.NewClient(*apiURL), *namespace, pb.RoutingEventKind_ROUTING_EVENT_KIND_OUTCOMES)
+		sub.OnEvent = func(ev pubsub.Event) {
+			if o := ev.GetOutcome(); o.GetSuccess() && o.GetContentHash() != "" {
+				t.Touch(o.GetContentHash(), time.Now())
+			}
+		}
+		go sub.Run(ctx)
+	}
+	if err := t.Run(ctx); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "tiers: %v\n", err)
+		return exitUnreachable
+	}
+	return exitOK
+}
+
+// printTierPlan prints the transitions of a plan in format
+func printTierPlan(format string, plan []executor.Transition) int {
+	if format == formatText {
+		for _, tr := range plan {
+			fmt.Printf("%s %s -> %s (score %.2f)\n", tr.CID, tr.From, tr.To, tr.Score)
+		}
+		return exitOK
+	}
+	if plan == nil {
+		plan = []executor.Transition{}
+	}
+	if err := printStructured(format, plan, plan); err != nil {
+		fmt.Fprintf(os.Stderr, "tiers: %v\n", err)
+		return exitUnhealthy
+	}
+	return exitOK
+}
+
+// saveTierState writes the tracked objects to path, replacing it only
+// once the new state is complete
+func saveTierState(path string, objects []executor.StoredObject) error {
+	data, err := json.MarshalIndent(objects, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tiers-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
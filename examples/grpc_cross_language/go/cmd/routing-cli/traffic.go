@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"example.com/ipfs_kit_py/history"
+	"example.com/ipfs_kit_py/metrics"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// The recording or replay set up by the global --record and --replay
+// flags, applied to every connection to the routing service
+var (
+	recorder *routingclient.Recorder
+	replay   *routingclient.ReplayConn
+)
+
+// httpAPI is the HTTP routing API set by the global --http-api flag
+var httpAPI string
+
+// openTraffic starts recording RPC traffic to record, or loads the
+// recording at replayPath to serve RPCs from; the returned function
+// reports how the recording or replay went
+func openTraffic(record, replayPath string) (func(), error) {
+	switch {
+	case record != "" && replayPath != "":
+		return nil, fmt.Errorf("--record and --replay cannot be combined")
+	case record != "":
+		f, err := os.Create(record)
+		if err != nil {
+			return nil, err
+		}
+		if recorder, err = routingclient.NewRecorder(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return func() {
+			if err := recorder.Err(); err != nil {
+				fmt.Fprintf(os.Stderr, "routing-cli: %v\n", err)
+			}
+			f.Close()
+		}, nil
+	case replayPath != "":
+		var err error
+		if replay, err = routingclient.OpenReplay(replayPath); err != nil {
+			return nil, err
+		}
+		return func() {
+			if n := replay.Remaining(); n > 0 {
+				fmt.Fprintf(os.Stderr, "routing-cli: %d recorded calls were not replayed\n", n)
+			}
+		}, nil
+	}
+	return func() {}, nil
+}
+
+// newClient connects to the routing service at addr as routingclient.New
+// does, recording or replaying its RPCs, working offline, keeping history
+// and counting or sending metrics if asked to
+func newClient(addr string, opts ...routingclient.Option) (*routingclient.Client, error) {
+	if httpAPI != "" {
+		opts = append(opts, routingclient.WithHTTPAPI(httpAPI))
+	}
+	if recorder != nil {
+		opts = append(opts, routingclient.WithRecorder(recorder))
+	}
+	if replay != nil {
+		opts = append(opts, routingclient.WithReplay(replay))
+	}
+	if offline != nil {
+		opts = append(opts, routingclient.WithOffline(offline))
+	}
+	if historyStore != nil {
+		opts = append(opts, routingclient.WithInterceptor(history.Interceptor(historyStore)))
+	}
+	if jobMetrics != nil {
+		opts = append(opts, routingclient.WithInterceptor(jobMetrics.Interceptor()))
+	}
+	if metricsSink != nil {
+		opts = append(opts, routingclient.WithInterceptor(metrics.Interceptor(metricsSink)))
+	}
+	if agentMetrics != nil {
+		opts = append(opts, routingclient.WithStreamInterceptor(agentMetrics.StreamInterceptor()))
+	}
+	c, err := routingclient.New(addr, opts...)
+	if err == nil && debugState != nil {
+		debugState.AddClient(addr, c)
+	}
+	return c, err
+}
+
+// trafficConn applies the recording or replay to a connection a command
+// dialed itself
+func trafficConn(conn routingclient.Conn) routingclient.Conn {
+	switch {
+	case replay != nil:
+		conn.Close()
+		return replay
+	case recorder != nil:
+		return recorder.Conn(conn)
+	}
+	return conn
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"example.com/ipfs_kit_py/kubo"
+	"example.com/ipfs_kit_py/pubsub"
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// tuiEvents is how many recent decisions and outcomes the TUI keeps
+const tuiEvents = 200
+
+var (
+	tuiTitle  = lipgloss.NewStyle().Bold(true)
+	tuiHeader = lipgloss.NewStyle().Bold(true).Underline(true)
+	tuiFaint  = lipgloss.NewStyle().Faint(true)
+	tuiGood   = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	tuiWarn   = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	tuiBad    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+)
+
+// runTUI implements `routing-cli tui`: a terminal dashboard of the live
+// decisions and outcomes peers publish, each backend's state, score and
+// success rate, and the factor weights
+func runTUI(args []string) int {
+	fs := flag.NewFlagSet("tui", flag.ContinueOnError)
+	server := fs.String("server", "localhost:50051", "gRPC routing server address")
+	interval := fs.Duration("interval", 5*time.Second, "time between insights refreshes")
+	window := fs.Duration("window", 0, "period insights cover, rounded up to whole hours (default the server's, 24h)")
+	apiURL := fs.String("ipfs-api", "", "Kubo RPC API URL (default $IPFS_API_URL or "+kubo.DefaultAPIURL+")")
+	namespace := fs.String("pubsub", pubsub.DefaultNamespace, "pubsub namespace whose decisions and outcomes are shown live (empty to skip)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli tui [flags]\n\nKeys: r refreshes, p pauses the event list, q quits.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 0 || *interval <= 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	client, err := newClient(*server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tui: %v\n", err)
+		return exitUnreachable
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := &tuiModel{
+		ctx:      ctx,
+		client:   client,
+		server:   *server,
+		interval: *interval,
+		window:   *window,
+		states:   make(map[string]*pb.BackendEvent),
+		live:     make(map[string]*tuiCounts),
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen())
+
+	w := client.NewBackendWatcher()
+	w.OnEvent = func(ev *pb.BackendEvent) { p.Send(ev) }
+	go w.Run(ctx)
+	if *namespace != "" {
+		sub := pubsub.NewSubscriber(kubo.NewClient(*apiURL), *namespace,
+			pb.RoutingEventKind_ROUTING_EVENT_KIND_DECISIONS, pb.RoutingEventKind_ROUTING_EVENT_KIND_OUTCOMES)
+		sub.OnEvent = func(ev pubsub.Event) { p.Send(ev) }
+		go sub.Run(ctx)
+	}
+
+	if _, err := p.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "tui: %v\n", err)
+		return exitUnhealthy
+	}
+	return exitOK
+}
+
+// tuiCounts counts the outcomes of one backend seen live
+type tuiCounts struct {
+	ok, failed int
+}
+
+// tuiInsights carries the result of a GetInsights call
+type tuiInsights struct {
+	resp *pb.GetInsightsResponse
+	err  error
+}
+
+// tuiTick asks for the next insights refresh
+type tuiTick struct{}
+
+// tuiModel is the dashboard's state. The backend watcher and pubsub
+// subscriber feed it through tea.Program.Send, so only Update changes it.
+type tuiModel struct {
+	ctx      context.Context
+	client   *routingclient.Client
+	server   string
+	interval time.Duration
+	window   time.Duration
+
+	width, height int
+	insights      *pb.GetInsightsResponse
+	updated       time.Time
+	err           error
+	states        map[string]*pb.BackendEvent
+	live          map[string]*tuiCounts
+	// events are the recent decisions and outcomes, the newest last
+	events []string
+	paused bool
+}
+
+// refresh fetches insights
+func (m *tuiModel) refresh() tea.Msg {
+	ctx, cancel := context.WithTimeout(m.ctx, m.interval)
+	defer cancel()
+	resp, err := m.client.GetInsights(ctx, m.window)
+	return tuiInsights{resp, err}
+}
+
+// Init implements tea.Model
+func (m *tuiModel) Init() tea.Cmd {
+	return m.refresh
+}
+
+// Update implements tea.Model
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		case "r":
+			return m, m.refresh
+		case "p":
+			m.paused = !m.paused
+		}
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tuiInsights:
+		if msg.err == nil {
+			m.insights, m.updated = msg.resp, time.Now()
+		}
+		m.err = msg.err
+		return m, tea.Tick(m.interval, func(time.Time) tea.Msg { return tuiTick{} })
+	case tuiTick:
+		return m, m.refresh
+	case *pb.BackendEvent:
+		m.states[msg.BackendId] = msg
+	case pubsub.Event:
+		if o := msg.GetOutcome(); o != nil {
+			c := m.live[o.BackendId]
+			if c == nil {
+				c = &tuiCounts{}
+				m.live[o.BackendId] = c
+			}
+			if o.Success {
+				c.ok++
+			} else {
+				c.failed++
+			}
+		}
+		if !m.paused {
+			m.events = append(m.events, eventLine(msg))
+			if len(m.events) > tuiEvents {
+				m.events = m.events[len(m.events)-tuiEvents:]
+			}
+		}
+	}
+	return m, nil
+}
+
+// View implements tea.Model
+func (m *tuiModel) View() string {
+	var b strings.Builder
+	status := "waiting for insights"
+	switch {
+	case m.err != nil:
+		status = tuiBad.Render(m.err.Error())
+		if !m.updated.IsZero() {
+			status += tuiFaint.Render(", showing insights from " + m.updated.Format(time.TimeOnly))
+		}
+	case !m.updated.IsZero():
+		status = "updated " + m.updated.Format(time.TimeOnly)
+	}
+	fmt.Fprintf(&b, "%s  %s\n\n", tuiTitle.Render("routing "+m.server), status)
+
+	// backends, from insights and the watcher
+	scores := m.insights.GetBackendScores().GetFields()
+	rates := routingclient.SuccessRates(m.insights)
+	seen := make(map[string]bool)
+	var ids []string
+	for _, set := range []map[string]bool{keys(scores), keys(rates), keys(m.states), keys(m.live)} {
+		for id := range set {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	idWidth := len("BACKEND")
+	for _, id := range ids {
+		idWidth = max(idWidth, len(id))
+	}
+	fmt.Fprintln(&b, tuiHeader.Render(fmt.Sprintf("%-*s  %-11s  %6s  %-20s  %7s  %s", idWidth, "BACKEND", "STATE", "SCORE", "", "SUCCESS", "LIVE")))
+	for _, id := range ids {
+		state := "-"
+		if ev, ok := m.states[id]; ok {
+			state = enumLabel(ev.State.String(), "BACKEND_STATE_")
+		}
+		score, bar := "-", ""
+		if v, ok := scores[id]; ok {
+			score = fmt.Sprintf("%.3f", v.GetNumberValue())
+			bar = tuiBar(v.GetNumberValue(), 20)
+		}
+		rate := "-"
+		if v, ok := rates[id]; ok {
+			rate = fmt.Sprintf("%.1f%%", v*100)
+		}
+		live := "-"
+		if c, ok := m.live[id]; ok {
+			live = fmt.Sprintf("%d ok, %d failed", c.ok, c.failed)
+		}
+		fmt.Fprintf(&b, "%-*s  %s  %6s  %-20s  %7s  %s\n", idWidth, id, tuiState(state), score, bar, rate, live)
+	}
+	if len(ids) == 0 {
+		fmt.Fprintln(&b, tuiFaint.Render("no backends yet"))
+	}
+
+	// factor weights
+	weights := m.insights.GetFactorWeights().GetFields()
+	factors := make([]string, 0, len(weights))
+	for f := range weights {
+		factors = append(factors, f)
+	}
+	sort.Strings(factors)
+	fmt.Fprintf(&b, "\n%s\n", tuiHeader.Render("FACTOR WEIGHTS"))
+	for _, f := range factors {
+		w := weights[f].GetNumberValue()
+		fmt.Fprintf(&b, "%-22s %.3f  %s\n", f, w, tuiBar(w, 20))
+	}
+
+	// recent events, as many as fit
+	title := "DECISIONS AND OUTCOMES"
+	if m.paused {
+		title += " (paused)"
+	}
+	fmt.Fprintf(&b, "\n%s\n", tuiHeader.Render(title))
+	used := strings.Count(b.String(), "\n") + 1
+	room := len(m.events)
+	if m.height > 0 {
+		room = max(m.height-used-1, 0)
+	}
+	events := m.events[max(len(m.events)-room, 0):]
+	clip := lipgloss.NewStyle()
+	if m.width > 0 {
+		clip = clip.MaxWidth(m.width)
+	}
+	for _, line := range events {
+		fmt.Fprintln(&b, clip.Render(line))
+	}
+	if len(m.events) == 0 {
+		fmt.Fprintln(&b, tuiFaint.Render("none seen yet"))
+	}
+	return b.String()
+}
+
+// keys returns the set of m's keys
+func keys[V any](m map[string]V) map[string]bool {
+	set := make(map[string]bool, len(m))
+	for k := range m {
+		set[k] = true
+	}
+	return set
+}
+
+// tuiBar draws v, from 0 to 1, as a bar width cells wide
+func tuiBar(v float64, width int) string {
+	n := int(v*float64(width) + 0.5)
+	n = min(max(n, 0), width)
+	return strings.Repeat("█", n) + tuiFaint.Render(strings.Repeat("░", width-n))
+}
+
+// tuiState colours a backend state label
+func tuiState(state string) string {
+	label := fmt.Sprintf("%-11s", state)
+	switch state {
+	case "healthy":
+		return tuiGood.Render(label)
+	case "degraded":
+		return tuiWarn.Render(label)
+	case "unavailable":
+		return tuiBad.Render(label)
+	}
+	return label
+}
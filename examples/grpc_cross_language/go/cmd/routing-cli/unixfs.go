@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"example.com/ipfs_kit_py/unixfs"
+)
+
+// runUnixFS implements `routing-cli unixfs`, computing the CID `ipfs add`
+// would give a file or directory without uploading it
+func runUnixFS(args []string) int {
+	fs := flag.NewFlagSet("unixfs", flag.ContinueOnError)
+	cidVersion := fs.Int("cid-version", 0, "CID version, 0 or 1")
+	rawLeaves := fs.Bool("raw-leaves", false, "store file chunks as raw blocks (default true with -cid-version 1)")
+	hashFunc := fs.String("hash", "", "multihash function (default sha2-256)")
+	chunk := fs.String("chunker", "", "Kubo chunker spec, e.g. size-262144 or rabin")
+	hidden := fs.Bool("hidden", false, "include dot files in directories")
+	carOut := fs.String("car", "", "also write the DAG as a CAR to this file")
+	output := outputFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: routing-cli unixfs [flags] <path>\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 1 || !checkOutput(output, false) {
+		fs.Usage()
+		return exitUsage
+	}
+
+	b := unixfs.NewBuilder(*cidVersion)
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "raw-leaves" {
+			b.RawLeaves = *rawLeaves
+		}
+	})
+	b.HashFunc, b.Chunker, b.Hidden = *hashFunc, *chunk, *hidden
+
+	var root unixfs.Node
+	var err error
+	if *carOut != "" {
+		root, err = writeUnixFSCAR(*carOut, *b, fs.Arg(0))
+	} else {
+		root, err = b.Add(fs.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unixfs: %v\n", err)
+		return exitUnhealthy
+	}
+	if *output == formatText {
+		fmt.Printf("%s\t%d\n", root.CID, root.Size)
+		return exitOK
+	}
+	result := map[string]interface{}{"cid": root.CID.String(), "size": root.Size}
+	if err := printStructured(*output, result, result); err != nil {
+		fmt.Fprintf(os.Stderr, "unixfs: %v\n", err)
+		return exitUnhealthy
+	}
+	return exitOK
+}
+
+func writeUnixFSCAR(path string, b unixfs.Builder, src string) (unixfs.Node, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return unixfs.Node{}, err
+	}
+	w := bufio.NewWriter(f)
+	root, err := unixfs.WriteCAR(w, b, src)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return root, err
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"example.com/ipfs_kit_py/workpool"
+)
+
+// poolFlags configure the worker pool of a bulk command
+type poolFlags struct {
+	workers    *int
+	perBackend *int
+	limits     nodeFlag
+}
+
+// register adds the -workers, -per-backend and -backend-limit flags to fs
+func (p *poolFlags) register(fs *flag.FlagSet, workers int) {
+	p.workers = fs.Int("workers", workers, "how many items are processed at once")
+	p.perBackend = fs.Int("per-backend", 2, "how many items may use one backend at once (0 for no limit)")
+	p.limits = nodeFlag{}
+	fs.Var(p.limits, "backend-limit", "limit for one backend as backend=n, overriding -per-backend, repeatable")
+}
+
+// pool starts a worker pool as configured
+func (p *poolFlags) pool() (*workpool.Pool, error) {
+	limits := make(map[string]int, len(p.limits))
+	for id, s := range p.limits {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("-backend-limit %s: want a count, got %q", id, s)
+		}
+		limits[id] = n
+	}
+	return workpool.New(workpool.Config{
+		Workers:    *p.workers,
+		PerBackend: *p.perBackend,
+		Limits:     limits,
+	}), nil
+}
+
+// printPoolStats summarises how loaded a pool was, and which backends
+// made items wait
+func printPoolStats(w io.Writer, s workpool.Stats) {
+	fmt.Fprintf(w, "%d workers, saturated %.0f%% of the time, queue peaked at %d\n", s.Workers, s.Saturation*100, s.MaxQueued)
+	ids := make([]string, 0, len(s.Backends))
+	for id := range s.Backends {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if b := s.Backends[id]; b.Waited > 0 {
+			fmt.Fprintf(w, "  %s: %d items waited for one of its %d slots\n", id, b.Waited, b.Limit)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Package diag serves the diagnostics of an agent that seems stuck: Go's
+// pprof profiles at /debug/pprof/, expvar's variables at /debug/vars, and
+// at /debug/routing a JSON snapshot of the routing clients it has added —
+// their connections, cached decisions and pending outcomes — with the
+// outcome queues and goroutine count. The handler exposes the process's
+// internals, so serve it only on a loopback or otherwise private address.
+package diag
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+	"time"
+
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// Env names the environment variable holding the address routing-cli
+// serves diagnostics on
+const Env = "ROUTING_DEBUG_ADDR"
+
+// Diagnostics tracks the clients and queues a snapshot reports. Its
+// methods are safe for concurrent use.
+type Diagnostics struct {
+	start time.Time
+
+	mu      sync.Mutex
+	clients []client
+	queues  []queue
+}
+
+type client struct {
+	server string
+	added  time.Time
+	c      *routingclient.Client
+}
+
+type queue struct {
+	name string
+	q    *routingclient.OutcomeQueue
+}
+
+// Snapshot is the state /debug/routing reports
+type Snapshot struct {
+	Time       time.Time        `json:"time"`
+	Uptime     string           `json:"uptime"`
+	Goroutines int              `json:"goroutines"`
+	Clients    []ClientSnapshot `json:"clients"`
+	Queues     []QueueSnapshot  `json:"queues,omitempty"`
+}
+
+// ClientSnapshot is the state of one routing client
+type ClientSnapshot struct {
+	Server string    `json:"server"`
+	Added  time.Time `json:"added"`
+	// State is the gRPC connection's state, "SHUTDOWN" once the client
+	// is closed, or empty without a gRPC connection
+	State string `json:"state,omitempty"`
+	// Degraded is set while calls go to the REST fallback
+	Degraded        bool                           `json:"degraded"`
+	CacheHits       uint64                         `json:"cache_hits,omitempty"`
+	CacheMisses     uint64                         `json:"cache_misses,omitempty"`
+	CachedDecisions []routingclient.CachedDecision `json:"cached_decisions,omitempty"`
+	// Offline is the offline state, with the outcomes waiting in its log
+	Offline *routingclient.OfflineStats `json:"offline,omitempty"`
+}
+
+// QueueSnapshot is the load of one outcome queue
+type QueueSnapshot struct {
+	Name string `json:"name"`
+	routingclient.QueueStats
+}
+
+// New returns an empty Diagnostics
+func New() *Diagnostics {
+	return &Diagnostics{start: time.Now()}
+}
+
+// AddClient reports c, connected to server, in snapshots
+func (d *Diagnostics) AddClient(server string, c *routingclient.Client) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clients = append(d.clients, client{server: server, added: time.Now(), c: c})
+}
+
+// AddQueue reports q, labelled with name, in snapshots
+func (d *Diagnostics) AddQueue(name string, q *routingclient.OutcomeQueue) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queues = append(d.queues, queue{name, q})
+}
+
+// Snapshot returns the current state of the clients and queues
+func (d *Diagnostics) Snapshot() Snapshot {
+	d.mu.Lock()
+	clients, queues := d.clients, d.queues
+	d.mu.Unlock()
+
+	s := Snapshot{
+		Time:       time.Now().UTC(),
+		Uptime:     time.Since(d.start).Round(time.Second).String(),
+		Goroutines: runtime.NumGoroutine(),
+		Clients:    make([]ClientSnapshot, 0, len(clients)),
+	}
+	for _, cl := range clients {
+		cs := ClientSnapshot{
+			Server:   cl.server,
+			Added:    cl.added.UTC(),
+			State:    cl.c.State(),
+			Degraded: cl.c.Degraded(),
+		}
+		if dc := cl.c.Cache(); dc != nil {
+			cs.CacheHits, cs.CacheMisses = dc.Stats()
+			cs.CachedDecisions = dc.Entries()
+		}
+		if o := cl.c.Offline(); o != nil {
+			stats := o.Stats()
+			cs.Offline = &stats
+		}
+		s.Clients = append(s.Clients, cs)
+	}
+	for _, q := range queues {
+		s.Queues = append(s.Queues, QueueSnapshot{Name: q.name, QueueStats: q.q.Stats()})
+	}
+	return s
+}
+
+// Handler returns the handler serving /debug/pprof/, /debug/vars and
+// /debug/routing
+func (d *Diagnostics) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/routing", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(d.Snapshot())
+	})
+	return mux
+}
@@ -0,0 +1,178 @@
+// Package dnslink resolves DNSLink records, TXT records of the form
+// "dnslink=/ipfs/<cid>" published at _dnslink.<domain>, to content paths,
+// caching each result for its record's TTL.
+package dnslink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxTTL caps how long a resolved link is cached
+const DefaultMaxTTL = time.Hour
+
+// maxDepth bounds chains of DNSLinks pointing at other DNSLink domains
+const maxDepth = 32
+
+// ErrNoLink means a domain publishes no DNSLink record
+var ErrNoLink = errors.New("dnslink: no dnslink record")
+
+// TXT is a TXT record and how long it may be cached
+type TXT struct {
+	Text string
+	TTL  time.Duration
+}
+
+// LookupFunc returns the TXT records at a DNS name; a name that does not
+// exist has no records rather than an error
+type LookupFunc func(ctx context.Context, name string) ([]TXT, error)
+
+// Resolver resolves DNSLink domains with a cache honouring record TTLs
+type Resolver struct {
+	// Lookup queries TXT records (SystemLookup if nil)
+	Lookup LookupFunc
+	// MaxTTL caps how long a result is cached (DefaultMaxTTL if zero)
+	MaxTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+type cached struct {
+	path    string
+	err     error
+	expires time.Time
+}
+
+// NewResolver returns a Resolver using lookup, or the system resolver if
+// lookup is nil
+func NewResolver(lookup LookupFunc) *Resolver {
+	return &Resolver{Lookup: lookup}
+}
+
+// ParseRef returns the domain of a "dnslink:example.com" reference
+func ParseRef(ref string) (string, bool) {
+	domain, ok := strings.CutPrefix(ref, "dnslink:")
+	domain = strings.TrimPrefix(domain, "//")
+	return domain, ok && domain != ""
+}
+
+// Resolve returns the path domain's DNSLink points to: an /ipfs/ path, or
+// an /ipns/ path to a key for IPNS to resolve. Links to other DNSLink
+// domains are followed. A domain without a link fails with ErrNoLink,
+// which is cached too.
+func (r *Resolver) Resolve(ctx context.Context, domain string) (string, error) {
+	domain = normalize(domain)
+	r.mu.Lock()
+	if c, ok := r.cache[domain]; ok && time.Now().Before(c.expires) {
+		r.mu.Unlock()
+		return c.path, c.err
+	}
+	r.mu.Unlock()
+
+	path, ttl, err := r.resolve(ctx, domain, 0)
+	if err != nil && !errors.Is(err, ErrNoLink) {
+		// Lookup failures are not cached
+		return "", err
+	}
+	maxTTL := r.MaxTTL
+	if maxTTL <= 0 {
+		maxTTL = DefaultMaxTTL
+	}
+	if ttl > maxTTL {
+		ttl = maxTTL
+	}
+	if ttl > 0 {
+		r.mu.Lock()
+		if r.cache == nil {
+			r.cache = make(map[string]cached)
+		}
+		r.cache[domain] = cached{path: path, err: err, expires: time.Now().Add(ttl)}
+		r.mu.Unlock()
+	}
+	return path, err
+}
+
+// resolve follows domain's link, returning the path and the shortest TTL
+// along the chain
+func (r *Resolver) resolve(ctx context.Context, domain string, depth int) (string, time.Duration, error) {
+	if depth >= maxDepth {
+		return "", 0, fmt.Errorf("dnslink: %s: more than %d links deep", domain, maxDepth)
+	}
+	link, ttl, err := r.link(ctx, domain)
+	if err != nil {
+		return "", ttl, err
+	}
+	name, ok := strings.CutPrefix(link, "/ipns/")
+	if !ok {
+		return link, ttl, nil
+	}
+	next, rest, _ := strings.Cut(name, "/")
+	if !strings.Contains(next, ".") {
+		// A key, not a domain: left to IPNS
+		return link, ttl, nil
+	}
+	path, nextTTL, err := r.resolve(ctx, normalize(next), depth+1)
+	if nextTTL < ttl {
+		ttl = nextTTL
+	}
+	if err != nil {
+		return "", ttl, err
+	}
+	if rest != "" {
+		path = strings.TrimRight(path, "/") + "/" + rest
+	}
+	return path, ttl, nil
+}
+
+// link returns the DNSLink published for domain, preferring
+// _dnslink.<domain> over the domain itself. Of several links the first in
+// lexicographic order wins, as the specification requires.
+func (r *Resolver) link(ctx context.Context, domain string) (string, time.Duration, error) {
+	lookup := r.Lookup
+	if lookup == nil {
+		lookup = SystemLookup
+	}
+	for _, name := range []string{"_dnslink." + domain, domain} {
+		records, err := lookup(ctx, name)
+		if err != nil {
+			return "", 0, fmt.Errorf("dnslink: lookup %s: %w", name, err)
+		}
+		var links []string
+		var ttl time.Duration
+		for _, rec := range records {
+			v, ok := strings.CutPrefix(strings.TrimSpace(rec.Text), "dnslink=")
+			if !ok || !validLink(v) {
+				continue
+			}
+			links = append(links, v)
+			if len(links) == 1 || rec.TTL < ttl {
+				ttl = rec.TTL
+			}
+		}
+		if len(links) > 0 {
+			sort.Strings(links)
+			return links[0], ttl, nil
+		}
+	}
+	return "", DefaultTTL, fmt.Errorf("%w for %s", ErrNoLink, domain)
+}
+
+// validLink reports whether v is a path DNSLink may point to
+func validLink(v string) bool {
+	for _, ns := range []string{"/ipfs/", "/ipns/"} {
+		if rest, ok := strings.CutPrefix(v, ns); ok && rest != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(domain, "."))
+}
@@ -0,0 +1,148 @@
+package dnslink_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/ipfs_kit_py/dnslink"
+)
+
+// zone is a LookupFunc over fixed records, counting the lookups it answers
+type zone struct {
+	records map[string][]dnslink.TXT
+	lookups int
+	err     error
+}
+
+func (z *zone) lookup(ctx context.Context, name string) ([]dnslink.TXT, error) {
+	z.lookups++
+	return z.records[name], z.err
+}
+
+func txt(text string, ttl time.Duration) []dnslink.TXT {
+	return []dnslink.TXT{{Text: text, TTL: ttl}}
+}
+
+func TestResolve(t *testing.T) {
+	z := &zone{records: map[string][]dnslink.TXT{
+		"_dnslink.docs.example.com": {
+			{Text: "dnslink=/ipfs/bafyzzz", TTL: time.Hour},
+			{Text: "dnslink=/ipfs/bafyaaa", TTL: 5 * time.Minute},
+			{Text: "v=spf1 -all", TTL: time.Second},
+		},
+		"apex.example.com":          txt("dnslink=/ipfs/bafyapex", time.Hour),
+		"_dnslink.www.example.com":  txt("dnslink=/ipns/docs.example.com/guide", time.Hour),
+		"_dnslink.key.example.com":  txt("dnslink=/ipns/k51qzi5uqu5dkey", time.Hour),
+		"_dnslink.bad.example.com":  txt("dnslink=/ipfs/", time.Hour),
+		"_dnslink.loop.example.com": txt("dnslink=/ipns/loop.example.com", time.Hour),
+	}}
+	r := dnslink.NewResolver(z.lookup)
+	tests := []struct {
+		domain, want string
+		err          error
+	}{
+		// the first link in lexicographic order wins
+		{"docs.example.com", "/ipfs/bafyaaa", nil},
+		{"Docs.Example.com.", "/ipfs/bafyaaa", nil},
+		{"apex.example.com", "/ipfs/bafyapex", nil},
+		{"www.example.com", "/ipfs/bafyaaa/guide", nil},
+		{"key.example.com", "/ipns/k51qzi5uqu5dkey", nil},
+		{"bad.example.com", "", dnslink.ErrNoLink},
+		{"missing.example.com", "", dnslink.ErrNoLink},
+	}
+	for _, tt := range tests {
+		got, err := r.Resolve(context.Background(), tt.domain)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("Resolve(%q) = %q, %v, want %q, %v", tt.domain, got, err, tt.want, tt.err)
+		}
+	}
+	if _, err := r.Resolve(context.Background(), "loop.example.com"); err == nil {
+		t.Error("resolved a DNSLink pointing at itself")
+	}
+}
+
+func TestResolveCaches(t *testing.T) {
+	z := &zone{records: map[string][]dnslink.TXT{
+		"_dnslink.a.example.com": txt("dnslink=/ipns/b.example.com", time.Hour),
+		"_dnslink.b.example.com": txt("dnslink=/ipfs/bafyb", 0),
+		"_dnslink.c.example.com": txt("dnslink=/ipfs/bafyc", time.Hour),
+	}}
+	r := dnslink.NewResolver(z.lookup)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := r.Resolve(ctx, "c.example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if z.lookups != 1 {
+		t.Errorf("%d lookups resolving a cached domain twice, want 1", z.lookups)
+	}
+	// a chain is cached for its shortest TTL, here none
+	z.lookups = 0
+	r.Resolve(ctx, "a.example.com")
+	r.Resolve(ctx, "a.example.com")
+	if z.lookups != 4 {
+		t.Errorf("%d lookups resolving an uncacheable chain twice, want 4", z.lookups)
+	}
+	// missing links are cached, lookup failures are not
+	z.lookups = 0
+	r.Resolve(ctx, "none.example.com")
+	r.Resolve(ctx, "none.example.com")
+	if z.lookups != 2 {
+		t.Errorf("%d lookups resolving a domain without a link twice, want 2", z.lookups)
+	}
+	z.lookups, z.err = 0, errors.New("SERVFAIL")
+	for i := 0; i < 2; i++ {
+		if _, err := r.Resolve(ctx, "down.example.com"); err == nil || errors.Is(err, dnslink.ErrNoLink) {
+			t.Fatalf("lookup failure resolved as %v", err)
+		}
+	}
+	if z.lookups != 2 {
+		t.Errorf("%d lookups after two failures, want 2", z.lookups)
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	for ref, want := range map[string]string{"dnslink:example.com": "example.com", "dnslink://example.com": "example.com", "dnslink:": "", "example.com": ""} {
+		if got, ok := dnslink.ParseRef(ref); ok != (want != "") || ok && got != want {
+			t.Errorf("ParseRef(%q) = %q, %v", ref, got, ok)
+		}
+	}
+}
+
+func TestDoHLookup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("type") != "TXT" || r.Header.Get("Accept") != "application/dns-json" {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Query().Get("name") {
+		case "_dnslink.example.com":
+			w.Write([]byte(`{"Status":0,"Answer":[{"type":5,"TTL":60,"data":"alias.example.com."},{"type":16,"TTL":300,"data":"\"dnslink=/ipfs/ba\" \"fy\\\"long\""}]}`))
+		case "missing.example.com":
+			w.Write([]byte(`{"Status":3}`))
+		default:
+			w.Write([]byte(`{"Status":2}`))
+		}
+	}))
+	defer srv.Close()
+	lookup := dnslink.DoHLookup(srv.URL)
+
+	records, err := lookup(context.Background(), "_dnslink.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Text != `dnslink=/ipfs/bafy"long` || records[0].TTL != 5*time.Minute {
+		t.Errorf("records %+v", records)
+	}
+	if records, err := lookup(context.Background(), "missing.example.com"); err != nil || len(records) != 0 {
+		t.Errorf("NXDOMAIN: %v, %v, want no records", records, err)
+	}
+	if _, err := lookup(context.Background(), "servfail.example.com"); err == nil {
+		t.Error("SERVFAIL was not an error")
+	}
+}
@@ -0,0 +1,104 @@
+package dnslink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTTL is the TTL assumed for records whose real TTL is unknown,
+// such as those from SystemLookup, and for domains without a link
+const DefaultTTL = time.Minute
+
+// SystemLookup queries TXT records with the system resolver. It cannot see
+// record TTLs, so every record gets DefaultTTL.
+func SystemLookup(ctx context.Context, name string) ([]TXT, error) {
+	texts, err := net.DefaultResolver.LookupTXT(ctx, name)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	records := make([]TXT, len(texts))
+	for i, t := range texts {
+		records[i] = TXT{Text: t, TTL: DefaultTTL}
+	}
+	return records, nil
+}
+
+// DoHLookup returns a LookupFunc querying a DNS-over-HTTPS JSON endpoint,
+// e.g. https://cloudflare-dns.com/dns-query, which reports each record's
+// TTL
+func DoHLookup(endpoint string) LookupFunc {
+	return func(ctx context.Context, name string) ([]TXT, error) {
+		u := endpoint + "?" + url.Values{"name": {name}, "type": {"TXT"}}.Encode()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/dns-json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("DoH %s: HTTP %s", endpoint, resp.Status)
+		}
+		var out struct {
+			Status int `json:"Status"`
+			Answer []struct {
+				Type int    `json:"type"`
+				TTL  int    `json:"TTL"`
+				Data string `json:"data"`
+			} `json:"Answer"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			return nil, fmt.Errorf("DoH %s: decode response: %w", endpoint, err)
+		}
+		switch out.Status {
+		case 0, 3: // NOERROR, NXDOMAIN
+		default:
+			return nil, fmt.Errorf("DoH %s: DNS status %d", endpoint, out.Status)
+		}
+		var records []TXT
+		for _, a := range out.Answer {
+			if a.Type == 16 { // TXT
+				records = append(records, TXT{Text: unquoteTXT(a.Data), TTL: time.Duration(a.TTL) * time.Second})
+			}
+		}
+		return records, nil
+	}
+}
+
+// unquoteTXT joins the quoted character-strings of TXT record data, e.g.
+// `"dnslink=/ipfs/ba" "fy..."`, as DNS does for long records
+func unquoteTXT(data string) string {
+	if !strings.HasPrefix(data, `"`) {
+		return data
+	}
+	var b strings.Builder
+	quoted, escaped := false, false
+	for _, c := range data {
+		switch {
+		case escaped:
+			b.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
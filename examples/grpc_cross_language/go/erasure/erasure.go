@@ -0,0 +1,189 @@
+// Package erasure splits objects into Reed-Solomon shards, so each shard
+// can be stored on a different backend and the object survives losing up
+// to the parity count of them. A Manifest records where every shard went
+// and how to put the object back together.
+package erasure
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"time"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+// Scheme names the coding in manifests
+const Scheme = "reed-solomon"
+
+// ManifestVersion is the manifest format version this package writes
+const ManifestVersion = 1
+
+// ManifestContentType is the MIME type manifests are stored under
+const ManifestContentType = "application/vnd.ipfs-kit.stripe+json"
+
+// Manifest describes a striped object and where its shards are stored
+type Manifest struct {
+	Version      int    `json:"version"`
+	Scheme       string `json:"scheme"`
+	DataShards   int    `json:"data_shards"`
+	ParityShards int    `json:"parity_shards"`
+	// ShardSize is the size of every shard; the last data shard is padded
+	// with zeros to it
+	ShardSize   int64  `json:"shard_size"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"content_type,omitempty"`
+	Filename    string `json:"filename,omitempty"`
+	// Shards are ordered by index: data shards, then parity shards
+	Shards []Shard `json:"shards"`
+	// ExpiresAt is when the shards may be removed; nil keeps them
+	// indefinitely
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Shard is where one shard is stored
+type Shard struct {
+	Index     int    `json:"index"`
+	BackendID string `json:"backend_id"`
+	Location  string `json:"location"`
+	CID       string `json:"cid,omitempty"`
+	SHA256    string `json:"sha256"`
+}
+
+// Validate checks that m is complete enough to reconstruct from
+func (m *Manifest) Validate() error {
+	switch {
+	case m.Version != ManifestVersion:
+		return fmt.Errorf("erasure: unsupported manifest version %d", m.Version)
+	case m.Scheme != Scheme:
+		return fmt.Errorf("erasure: unsupported scheme %q", m.Scheme)
+	case m.DataShards <= 0 || m.ParityShards < 0:
+		return fmt.Errorf("erasure: invalid shard counts %d+%d", m.DataShards, m.ParityShards)
+	case len(m.Shards) != m.DataShards+m.ParityShards:
+		return fmt.Errorf("erasure: manifest lists %d shards, want %d", len(m.Shards), m.DataShards+m.ParityShards)
+	case m.Size < 0 || m.ShardSize < 0 || m.Size > 0 && (m.Size-1)/int64(m.DataShards) >= m.ShardSize:
+		return fmt.Errorf("erasure: %d shards of %d bytes cannot hold %d bytes", m.DataShards, m.ShardSize, m.Size)
+	}
+	for i, s := range m.Shards {
+		if s.Index != i {
+			return fmt.Errorf("erasure: shard %d listed at position %d", s.Index, i)
+		}
+	}
+	return nil
+}
+
+// Shards are an object's encoded shards, spooled to temporary files
+type Shards struct {
+	Files     []*os.File
+	SHA256    []string
+	ShardSize int64
+	Size      int64
+	// ObjectSHA256 is the hex SHA-256 of the object
+	ObjectSHA256 string
+}
+
+// Encode reads size bytes from r and splits them into data shards plus
+// parity shards, spooled to temporary files in dir (the default temporary
+// directory if empty). Close removes them.
+func Encode(r io.Reader, size int64, data, parity int, dir string) (*Shards, error) {
+	if size <= 0 {
+		return nil, errors.New("erasure: cannot stripe an empty object")
+	}
+	enc, err := reedsolomon.NewStream(data, parity)
+	if err != nil {
+		return nil, fmt.Errorf("erasure: %w", err)
+	}
+	s := &Shards{Size: size, ShardSize: (size + int64(data) - 1) / int64(data)}
+	for range data + parity {
+		f, err := os.CreateTemp(dir, "shard-*")
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.Files = append(s.Files, f)
+	}
+
+	sum := sha256.New()
+	dst := make([]io.Writer, data)
+	for i := range dst {
+		dst[i] = s.Files[i]
+	}
+	if err := enc.Split(io.TeeReader(io.LimitReader(r, size), sum), dst, size); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("erasure: split: %w", err)
+	}
+	s.ObjectSHA256 = hex.EncodeToString(sum.Sum(nil))
+	if err := s.rewind(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	src := make([]io.Reader, data)
+	for i := range src {
+		src[i] = s.Files[i]
+	}
+	par := make([]io.Writer, parity)
+	for i := range par {
+		par[i] = s.Files[data+i]
+	}
+	if err := enc.Encode(src, par); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("erasure: encode: %w", err)
+	}
+
+	for _, f := range s.Files {
+		sum, err := fileSHA256(f)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.SHA256 = append(s.SHA256, sum)
+	}
+	return s, s.rewind()
+}
+
+// Close removes the shard files
+func (s *Shards) Close() error {
+	for _, f := range s.Files {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	return nil
+}
+
+func (s *Shards) rewind() error {
+	for _, f := range s.Files {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileSHA256 hashes f from the start
+func fileSHA256(f *os.File) (string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashingWriter hashes what is written through it
+type hashingWriter struct {
+	w io.Writer
+	h hash.Hash
+}
+
+func (hw *hashingWriter) Write(p []byte) (int, error) {
+	n, err := hw.w.Write(p)
+	hw.h.Write(p[:n])
+	return n, err
+}
@@ -0,0 +1,52 @@
+package erasure_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"example.com/ipfs_kit_py/erasure"
+)
+
+// FuzzManifest decodes its input as a stripe manifest, as reconstruct
+// reads them back from storage. A manifest that validates must only name
+// shards within its data and parity counts.
+func FuzzManifest(f *testing.F) {
+	m := erasure.Manifest{
+		Version:      erasure.ManifestVersion,
+		Scheme:       erasure.Scheme,
+		DataShards:   4,
+		ParityShards: 2,
+		ShardSize:    1 << 20,
+		Size:         4<<20 - 7,
+		SHA256:       fmt.Sprintf("%064x", 1),
+	}
+	for i := 0; i < 6; i++ {
+		m.Shards = append(m.Shards, erasure.Shard{
+			Index:     i,
+			BackendID: fmt.Sprintf("s3-region-%d", i),
+			Location:  fmt.Sprintf("s3://bucket/stripes/%064x/%d", 1, i),
+			SHA256:    fmt.Sprintf("%064x", i),
+		})
+	}
+	valid, err := json.Marshal(&m)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(valid)
+	f.Add([]byte(`{"version":1,"scheme":"reed-solomon","data_shards":1,"parity_shards":0,"shards":[{"index":7}]}`))
+	f.Add([]byte(`{"version":1,"data_shards":-1,"parity_shards":300}`))
+	f.Add([]byte(`null`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var m erasure.Manifest
+		if json.Unmarshal(data, &m) != nil || m.Validate() != nil {
+			return
+		}
+		for _, s := range m.Shards {
+			if s.Index < 0 || s.Index >= m.DataShards+m.ParityShards {
+				t.Fatalf("valid manifest has shard %d of %d+%d", s.Index, m.DataShards, m.ParityShards)
+			}
+		}
+	})
+}
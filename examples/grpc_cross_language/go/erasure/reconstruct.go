@@ -0,0 +1,183 @@
+package erasure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+// ErrCorrupt is returned when the reassembled object does not match the
+// manifest's SHA-256
+var ErrCorrupt = errors.New("erasure: reassembled object does not match its SHA-256")
+
+// OpenFunc opens a stored shard for reading
+type OpenFunc func(ctx context.Context, s Shard) (io.ReadCloser, error)
+
+// ShardFailure is a shard that could not be used
+type ShardFailure struct {
+	Index int
+	Err   error
+}
+
+// Report describes a reconstruction
+type Report struct {
+	// Failed are the shards that could not be read or failed their
+	// checksum, and were rebuilt from the others where needed
+	Failed []ShardFailure
+	// Read is how many shards were read successfully
+	Read int
+}
+
+// Reconstruct reassembles the object m describes and writes it to w. Data
+// shards are read first; parity shards are read only if some of those
+// fail, and up to ParityShards failures are recovered from. Shards are
+// spooled to temporary files in dir (the default temporary directory if
+// empty) and checked against their SHA-256 before use.
+func Reconstruct(ctx context.Context, m *Manifest, open OpenFunc, w io.Writer, dir string) (*Report, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	total := m.DataShards + m.ParityShards
+	files := make([]*os.File, total)
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				f.Close()
+				os.Remove(f.Name())
+			}
+		}
+	}()
+
+	report := &Report{}
+	fetch := func(indexes []int) {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, i := range indexes {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				f, err := fetchShard(ctx, m, m.Shards[i], open, dir)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					report.Failed = append(report.Failed, ShardFailure{Index: i, Err: err})
+					return
+				}
+				files[i] = f
+				report.Read++
+			}(i)
+		}
+		wg.Wait()
+	}
+	data := make([]int, m.DataShards)
+	for i := range data {
+		data[i] = i
+	}
+	fetch(data)
+	if len(report.Failed) > 0 {
+		parity := make([]int, m.ParityShards)
+		for i := range parity {
+			parity[i] = m.DataShards + i
+		}
+		fetch(parity)
+	}
+	if report.Read < m.DataShards {
+		errs := make([]error, len(report.Failed))
+		for i, f := range report.Failed {
+			errs[i] = fmt.Errorf("shard %d: %w", f.Index, f.Err)
+		}
+		return report, fmt.Errorf("erasure: only %d of the %d shards needed could be read: %w", report.Read, m.DataShards, errors.Join(errs...))
+	}
+
+	enc, err := reedsolomon.NewStream(m.DataShards, m.ParityShards)
+	if err != nil {
+		return report, fmt.Errorf("erasure: %w", err)
+	}
+	for _, f := range files[:m.DataShards] {
+		if f == nil {
+			if err := rebuild(enc, m, files, dir); err != nil {
+				return report, err
+			}
+			break
+		}
+	}
+
+	shards := make([]io.Reader, m.DataShards)
+	for i := range shards {
+		if _, err := files[i].Seek(0, io.SeekStart); err != nil {
+			return report, err
+		}
+		shards[i] = files[i]
+	}
+	hw := &hashingWriter{w: w, h: sha256.New()}
+	if err := enc.Join(hw, shards, m.Size); err != nil {
+		return report, fmt.Errorf("erasure: join: %w", err)
+	}
+	if hex.EncodeToString(hw.h.Sum(nil)) != m.SHA256 {
+		return report, ErrCorrupt
+	}
+	return report, nil
+}
+
+// rebuild recreates the missing data shards from the ones read
+func rebuild(enc reedsolomon.StreamEncoder, m *Manifest, files []*os.File, dir string) error {
+	valid := make([]io.Reader, len(files))
+	fill := make([]io.Writer, len(files))
+	for i, f := range files {
+		if f != nil {
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			valid[i] = f
+			continue
+		}
+		if i >= m.DataShards {
+			continue
+		}
+		f, err := os.CreateTemp(dir, "shard-*")
+		if err != nil {
+			return err
+		}
+		files[i], fill[i] = f, f
+	}
+	if err := enc.Reconstruct(valid, fill); err != nil {
+		return fmt.Errorf("erasure: reconstruct: %w", err)
+	}
+	return nil
+}
+
+// fetchShard reads a shard into a temporary file, checking its size and
+// SHA-256
+func fetchShard(ctx context.Context, m *Manifest, s Shard, open OpenFunc, dir string) (*os.File, error) {
+	body, err := open(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	f, err := os.CreateTemp(dir, "shard-*")
+	if err != nil {
+		return nil, err
+	}
+	hw := &hashingWriter{w: f, h: sha256.New()}
+	n, err := io.Copy(hw, io.LimitReader(body, m.ShardSize+1))
+	switch {
+	case err != nil:
+	case n != m.ShardSize:
+		err = fmt.Errorf("read %d bytes, want %d", n, m.ShardSize)
+	case hex.EncodeToString(hw.h.Sum(nil)) != s.SHA256:
+		err = errors.New("SHA-256 mismatch")
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,190 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"example.com/ipfs_kit_py/mcpclient"
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// Admission rejection reasons reported in AdmissionError.Reason
+const (
+	ReasonBackendQuota = "backend_quota"
+	ReasonDiskHeadroom = "disk_headroom"
+	ReasonBucketQuota  = "bucket_quota"
+)
+
+// Quota is the storage usage and limit of a backend or bucket in bytes.
+// A Limit of zero means unlimited.
+type Quota struct {
+	Used  int64 `json:"used"`
+	Limit int64 `json:"limit"`
+}
+
+// Free returns the remaining capacity, or -1 if the quota is unlimited
+func (q Quota) Free() int64 {
+	if q.Limit <= 0 {
+		return -1
+	}
+	if q.Used >= q.Limit {
+		return 0
+	}
+	return q.Limit - q.Used
+}
+
+// fits reports whether size more bytes fit within the quota
+func (q Quota) fits(size int64) bool {
+	free := q.Free()
+	return free < 0 || size <= free
+}
+
+// QuotaReporter is implemented by executors that can report the quota of
+// their backend
+type QuotaReporter interface {
+	Quota(ctx context.Context) (*Quota, error)
+}
+
+// Spooler is implemented by executors that stage whole uploads on local
+// disk before sending them, returning the directory they stage in
+type Spooler interface {
+	SpoolDir() string
+}
+
+// MCPBucketQuota returns an Admission.BucketQuota reading a bucket's size
+// and storage_quota setting from the MCP dashboard behind c
+func MCPBucketQuota(c *mcpclient.Client) func(ctx context.Context, bucket string) (*Quota, error) {
+	return func(ctx context.Context, bucket string) (*Quota, error) {
+		u, err := c.GetBucketUsage(ctx, bucket)
+		if err != nil {
+			return nil, err
+		}
+		return &Quota{Used: u.Size, Limit: u.Quota}, nil
+	}
+}
+
+// AdmissionError is returned when content is rejected before upload. It
+// carries enough detail for callers to retry elsewhere or free space.
+type AdmissionError struct {
+	Reason    string `json:"reason"`
+	BackendID string `json:"backend_id,omitempty"`
+	Bucket    string `json:"bucket,omitempty"`
+	Needed    int64  `json:"needed"`
+	Available int64  `json:"available"`
+
+	// Alternatives are other candidate backends from the routing decision
+	// that passed the backend quota check
+	Alternatives []string `json:"alternatives,omitempty"`
+
+	// CleanupCandidates are content identifiers suggested for removal
+	CleanupCandidates []string `json:"cleanup_candidates,omitempty"`
+}
+
+func (e *AdmissionError) Error() string {
+	target := e.BackendID
+	if e.Reason == ReasonBucketQuota {
+		target = "bucket " + e.Bucket
+	}
+	msg := fmt.Sprintf("admission rejected (%s): %s needs %d bytes but only %d are available", e.Reason, target, e.Needed, e.Available)
+	if len(e.Alternatives) > 0 {
+		msg += fmt.Sprintf("; alternatives: %v", e.Alternatives)
+	}
+	return msg
+}
+
+// Admission checks quotas and local disk headroom before an upload starts
+// so that oversized content is rejected early instead of failing midway
+type Admission struct {
+	// MinHeadroom bytes must remain free in the spool directory of an
+	// executor that stages content locally once the content is staged
+	MinHeadroom int64
+
+	// BucketQuota, if set, returns the quota of the bucket named in the
+	// content's "bucket" metadata
+	BucketQuota func(ctx context.Context, bucket string) (*Quota, error)
+
+	// Cleanup, if set, suggests content that could be removed from a
+	// backend to make room for needed bytes
+	Cleanup func(ctx context.Context, backendID string, needed int64) []string
+}
+
+// Check admits content for the backend selected in decision, returning an
+// *AdmissionError if it should be rejected
+func (a *Admission) Check(ctx context.Context, registry *Registry, info routingclient.ContentInfo, decision *pb.SelectBackendResponse) error {
+	size := info.ContentSize
+	if size <= 0 {
+		return nil
+	}
+
+	if bucket := info.Metadata["bucket"]; bucket != "" && a.BucketQuota != nil {
+		q, err := a.BucketQuota(ctx, bucket)
+		if err != nil {
+			return fmt.Errorf("admission: bucket quota: %w", err)
+		}
+		if !q.fits(size) {
+			return &AdmissionError{
+				Reason:    ReasonBucketQuota,
+				Bucket:    bucket,
+				Needed:    size,
+				Available: q.Free(),
+			}
+		}
+	}
+
+	if exec, ok := registry.Lookup(decision.BackendId); ok {
+		if spooler, ok := exec.(Spooler); ok {
+			if free, err := diskFree(spooler.SpoolDir()); err == nil && free-size < a.MinHeadroom {
+				return &AdmissionError{
+					Reason:    ReasonDiskHeadroom,
+					BackendID: decision.BackendId,
+					Needed:    size + a.MinHeadroom,
+					Available: free,
+				}
+			}
+		}
+	}
+
+	ok, free, err := a.backendFits(ctx, registry, decision.BackendId, size)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+
+	rejection := &AdmissionError{
+		Reason:    ReasonBackendQuota,
+		BackendID: decision.BackendId,
+		Needed:    size,
+		Available: free,
+	}
+	for _, alt := range decision.Alternatives {
+		if fits, _, err := a.backendFits(ctx, registry, alt.BackendId, size); err == nil && fits {
+			rejection.Alternatives = append(rejection.Alternatives, alt.BackendId)
+		}
+	}
+	if a.Cleanup != nil {
+		rejection.CleanupCandidates = a.Cleanup(ctx, decision.BackendId, size-free)
+	}
+	return rejection
+}
+
+// backendFits checks the backend quota if its executor reports one. A
+// backend without an executor is an error rather than a full backend, so
+// it is neither reported as out of space nor offered as an alternative.
+func (a *Admission) backendFits(ctx context.Context, registry *Registry, backendID string, size int64) (bool, int64, error) {
+	exec, ok := registry.Lookup(backendID)
+	if !ok {
+		return false, 0, fmt.Errorf("admission: no executor registered for backend %q", backendID)
+	}
+	reporter, ok := exec.(QuotaReporter)
+	if !ok {
+		return true, -1, nil
+	}
+	q, err := reporter.Quota(ctx)
+	if err != nil {
+		return false, 0, fmt.Errorf("admission: %s quota: %w", backendID, err)
+	}
+	return q.fits(size), q.Free(), nil
+}
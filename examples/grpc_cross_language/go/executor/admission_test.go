@@ -0,0 +1,55 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// quotaExecutor is an executor reporting a fixed quota
+type quotaExecutor struct {
+	class string
+	quota Quota
+}
+
+func (e *quotaExecutor) Class() string { return e.class }
+
+func (e *quotaExecutor) Put(ctx context.Context, info routingclient.ContentInfo, r io.Reader) (*Result, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (e *quotaExecutor) Quota(ctx context.Context) (*Quota, error) { return &e.quota, nil }
+
+func TestAdmissionUnregisteredBackend(t *testing.T) {
+	registry := NewRegistry(
+		&quotaExecutor{class: "full", quota: Quota{Used: 100, Limit: 100}},
+		&quotaExecutor{class: "roomy", quota: Quota{Limit: 1000}},
+	)
+	info := routingclient.ContentInfo{ContentSize: 10}
+	a := &Admission{}
+
+	err := a.Check(context.Background(), registry, info, &pb.SelectBackendResponse{BackendId: "missing"})
+	var rejection *AdmissionError
+	if err == nil || errors.As(err, &rejection) {
+		t.Fatalf("backend without an executor: got %v, want a non-admission error", err)
+	}
+
+	err = a.Check(context.Background(), registry, info, &pb.SelectBackendResponse{
+		BackendId: "full",
+		Alternatives: []*pb.SelectBackendResponse_Alternative{
+			{BackendId: "missing"},
+			{BackendId: "roomy"},
+		},
+	})
+	if !errors.As(err, &rejection) {
+		t.Fatalf("full backend: got %v, want an admission error", err)
+	}
+	if want := []string{"roomy"}; !reflect.DeepEqual(rejection.Alternatives, want) {
+		t.Errorf("alternatives %v, want %v", rejection.Alternatives, want)
+	}
+}
@@ -0,0 +1,149 @@
+package executor
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"example.com/ipfs_kit_py/car"
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// Multicodec and multihash codes used when packing content into CARs
+const (
+	codecRaw    = 0x55
+	codecCAR    = 0x0202
+	hashSHA2256 = 0x12
+)
+
+// cidBase32 is the lowercase, unpadded base32 alphabet used by CIDv1 strings
+var cidBase32 = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// cidV1 builds a binary CIDv1 with a sha2-256 multihash of the given digest
+func cidV1(codec uint64, digest []byte) []byte {
+	buf := binary.AppendUvarint(nil, 1)
+	buf = binary.AppendUvarint(buf, codec)
+	buf = binary.AppendUvarint(buf, hashSHA2256)
+	buf = binary.AppendUvarint(buf, uint64(len(digest)))
+	return append(buf, digest...)
+}
+
+// cidString renders a binary CIDv1 in its canonical base32 string form
+func cidString(cid []byte) string {
+	return "b" + cidBase32.EncodeToString(cid)
+}
+
+// rawCID returns the CIDv1 of data stored as a single raw block
+func rawCID(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return cidV1(codecRaw, sum[:])
+}
+
+// carShard is a CAR spooled to disk for upload as one shard
+type carShard struct {
+	path   string
+	size   int64
+	digest []byte // sha2-256 of the whole file
+}
+
+// cid is the shard's CID, as Storacha identifies stored CARs
+func (s carShard) cid() string {
+	return cidString(cidV1(codecCAR, s.digest))
+}
+
+// carSharder packs blocks into CAR shards of at most max bytes of blocks
+// each, under dir. Only the last shard's header names the root, which is
+// not known until every block has been added, so each shard's blocks are
+// spooled and the shard written once it is full or closed.
+type carSharder struct {
+	dir    string
+	max    int64
+	shards []carShard
+
+	spool   *os.File
+	bw      *bufio.Writer
+	n       int64
+	written map[string]bool
+}
+
+// add appends a block, starting a new shard if it would overflow the
+// current one. Repeated blocks are stored once.
+func (s *carSharder) add(c cidutil.CID, data []byte) error {
+	key := c.Bytes()
+	if s.written == nil {
+		s.written = make(map[string]bool)
+	}
+	if s.written[string(key)] {
+		return nil
+	}
+	s.written[string(key)] = true
+
+	length := uint64(len(key) + len(data))
+	section := int64(len(binary.AppendUvarint(nil, length))) + int64(length)
+	if s.spool != nil && s.n > 0 && s.n+section > s.max {
+		if err := s.finish(); err != nil {
+			return err
+		}
+	}
+	if s.spool == nil {
+		f, err := os.CreateTemp(s.dir, "blocks-*")
+		if err != nil {
+			return err
+		}
+		s.spool, s.bw, s.n = f, bufio.NewWriter(f), 0
+	}
+	s.n += section
+	return car.WriteBlock(s.bw, c, data)
+}
+
+// close writes the last shard, naming root in its header, and returns
+// every shard in order
+func (s *carSharder) close(root cidutil.CID) ([]carShard, error) {
+	if err := s.finish(root); err != nil {
+		return nil, err
+	}
+	return s.shards, nil
+}
+
+// finish writes the current shard: a header with roots, then its blocks
+func (s *carSharder) finish(roots ...cidutil.CID) error {
+	if s.spool == nil {
+		return nil
+	}
+	defer func() {
+		s.spool.Close()
+		os.Remove(s.spool.Name())
+		s.spool = nil
+	}()
+	if err := s.bw.Flush(); err != nil {
+		return err
+	}
+	if _, err := s.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.dir, fmt.Sprintf("shard-%d.car", len(s.shards)))
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	cw := &countingWriter{w: io.MultiWriter(f, h)}
+	if err := car.WriteHeader(cw, roots...); err != nil {
+		return err
+	}
+	if _, err := io.Copy(cw, s.spool); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	s.shards = append(s.shards, carShard{path: path, size: cw.n, digest: h.Sum(nil)})
+	return nil
+}
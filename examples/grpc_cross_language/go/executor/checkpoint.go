@@ -0,0 +1,188 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// CheckpointVersion is the checkpoint file format version
+const CheckpointVersion = 1
+
+// Checkpoints keeps the progress of uploads in files under Dir, so an
+// upload that dies part way resumes where it left off when the same
+// content is put again, rather than starting over. Executors remove an
+// upload's checkpoint once it completes. A nil *Checkpoints keeps none.
+type Checkpoints struct {
+	Dir string
+}
+
+// Checkpoint is the saved progress of one upload
+type Checkpoint struct {
+	Version int `json:"version"`
+	// Backend and Key identify the upload: the executor class, and the
+	// content hash or, without one, the object name
+	Backend string `json:"backend"`
+	Key     string `json:"key"`
+	// Path is where the content is going: the S3 object URL or the MFS
+	// file an IPFS upload is written to
+	Path string `json:"path,omitempty"`
+	// Offset is how much of the content, from its start, is uploaded
+	Offset   int64            `json:"offset"`
+	PartSize int64            `json:"part_size,omitempty"`
+	Parts    []CheckpointPart `json:"parts,omitempty"`
+	// UploadID is the S3 multipart upload the parts belong to
+	UploadID string `json:"upload_id,omitempty"`
+	// CID is the CID of what is uploaded so far: the MFS file on IPFS,
+	// or the CAR shard on Storacha
+	CID string `json:"cid,omitempty"`
+	// Root is the root CID of the content in a Storacha shard
+	Root      string    `json:"root,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CheckpointPart is one uploaded part. Parts are reused on resume only if
+// the content read again has the same SHA-256.
+type CheckpointPart struct {
+	Number int    `json:"number"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	ETag   string `json:"etag,omitempty"`
+}
+
+// checkpointKey identifies content for checkpoints: its hash if known,
+// else name
+func checkpointKey(info routingclient.ContentInfo, name string) string {
+	if info.ContentHash != "" {
+		return info.ContentHash
+	}
+	return name
+}
+
+// Load returns the saved checkpoint of an upload, or a fresh one if there
+// is none or it is unreadable
+func (c *Checkpoints) Load(backend, key string) *Checkpoint {
+	fresh := &Checkpoint{Version: CheckpointVersion, Backend: backend, Key: key}
+	if c == nil || key == "" {
+		return fresh
+	}
+	data, err := os.ReadFile(c.path(backend, key))
+	if err != nil {
+		return fresh
+	}
+	var cp Checkpoint
+	if json.Unmarshal(data, &cp) != nil || cp.Version != CheckpointVersion || cp.Backend != backend || cp.Key != key {
+		return fresh
+	}
+	return &cp
+}
+
+// Save writes cp, replacing the saved checkpoint only once the new one is
+// complete
+func (c *Checkpoints) Save(cp *Checkpoint) error {
+	if c == nil || cp.Key == "" {
+		return nil
+	}
+	cp.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	tmp, err := os.CreateTemp(c.Dir, ".checkpoint-*")
+	if err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(cp.Backend, cp.Key)); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the saved checkpoint of cp's upload
+func (c *Checkpoints) Remove(cp *Checkpoint) error {
+	if c == nil || cp.Key == "" {
+		return nil
+	}
+	err := os.Remove(c.path(cp.Backend, cp.Key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// List returns the saved checkpoints, i.e. the unfinished uploads
+func (c *Checkpoints) List() ([]Checkpoint, error) {
+	if c == nil {
+		return nil, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var checkpoints []Checkpoint
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		var cp Checkpoint
+		if json.Unmarshal(data, &cp) == nil && cp.Version == CheckpointVersion {
+			checkpoints = append(checkpoints, cp)
+		}
+	}
+	return checkpoints, nil
+}
+
+func (c *Checkpoints) path(backend, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, backend+"-"+hex.EncodeToString(sum[:12])+".json")
+}
+
+// part returns the saved part number num if it has the given SHA-256
+func (cp *Checkpoint) part(num int, sha string) (CheckpointPart, bool) {
+	for _, p := range cp.Parts {
+		if p.Number == num && p.SHA256 == sha {
+			return p, true
+		}
+	}
+	return CheckpointPart{}, false
+}
+
+// addPart records an uploaded part, replacing any earlier attempt, and
+// advances Offset over the parts now uploaded without a gap
+func (cp *Checkpoint) addPart(p CheckpointPart) {
+	parts := cp.Parts[:0]
+	for _, q := range cp.Parts {
+		if q.Number != p.Number {
+			parts = append(parts, q)
+		}
+	}
+	cp.Parts = append(parts, p)
+	sort.Slice(cp.Parts, func(i, j int) bool { return cp.Parts[i].Number < cp.Parts[j].Number })
+	cp.Offset = 0
+	for i, q := range cp.Parts {
+		if q.Number != i+1 {
+			break
+		}
+		cp.Offset += q.Size
+	}
+}
@@ -0,0 +1,81 @@
+package executor
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"example.com/ipfs_kit_py/routingclient"
+	"example.com/ipfs_kit_py/workpool"
+)
+
+// DirResult is the outcome of routing one file of a directory
+type DirResult struct {
+	// Path is the file's path relative to the directory, with slashes
+	Path   string  `json:"path"`
+	Result *Result `json:"result,omitempty"`
+	Err    error   `json:"-"`
+}
+
+// RunDir routes and uploads every regular file under dir with Run, as
+// many at once as pool has workers, each upload waiting for a slot on the
+// backend it was routed to (see WithPool). Files that fail do not stop
+// the rest; their DirResult carries the error. The results are in walk
+// order. An error is returned only if dir cannot be walked or ctx ends
+// before every file was queued.
+func RunDir(ctx context.Context, client *routingclient.Client, registry *Registry, pool *workpool.Pool, dir, strategy string) ([]DirResult, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = WithPool(ctx, pool)
+	results := make([]DirResult, len(paths))
+	var wg sync.WaitGroup
+	for i, p := range paths {
+		i, p := i, p
+		rel, _ := filepath.Rel(dir, p)
+		results[i].Path = filepath.ToSlash(rel)
+		wg.Add(1)
+		err := pool.Go(ctx, func(ctx context.Context) {
+			defer wg.Done()
+			if err := ctx.Err(); err != nil {
+				results[i].Err = err
+				return
+			}
+			results[i].Result, results[i].Err = runFile(ctx, client, registry, p, strategy)
+		})
+		if err != nil {
+			wg.Done()
+			wg.Wait()
+			return results[:i], err
+		}
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// runFile routes and uploads the file at path
+func runFile(ctx context.Context, client *routingclient.Client, registry *Registry, path, strategy string) (*Result, error) {
+	info, err := routingclient.ContentInfoFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Run(ctx, client, registry, info, strategy, f)
+}
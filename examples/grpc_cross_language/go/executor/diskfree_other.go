@@ -0,0 +1,10 @@
+//go:build !unix
+
+package executor
+
+import "errors"
+
+// diskFree is not supported on this platform; headroom checks are skipped
+func diskFree(path string) (int64, error) {
+	return 0, errors.New("disk free space is not supported on this platform")
+}
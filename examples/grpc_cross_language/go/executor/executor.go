@@ -0,0 +1,280 @@
+// Package executor performs the actual storage operation on the backend chosen
+// by the routing service and reports the real outcome back to it.
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/ipfs_kit_py/erasure"
+	"example.com/ipfs_kit_py/progress"
+	"example.com/ipfs_kit_py/routingclient"
+	"example.com/ipfs_kit_py/throttle"
+	"example.com/ipfs_kit_py/workpool"
+)
+
+// Result describes where content ended up after a successful upload
+type Result struct {
+	BackendID string `json:"backend_id"`
+	Location  string `json:"location"`
+	CID       string `json:"cid,omitempty"`
+	Bytes     int64  `json:"bytes"`
+	ETag      string `json:"etag,omitempty"`
+	// Provider names where within the backend the content landed, e.g.
+	// "s3:eu-west-1" or "filecoin:f01234"
+	Provider string `json:"provider,omitempty"`
+	// Manifest, for a striped upload, says where each shard went; the
+	// rest of the Result describes where the manifest itself was stored
+	Manifest *erasure.Manifest `json:"manifest,omitempty"`
+	// Replication, for a replicated upload, lists its replicas; the rest
+	// of the Result describes where this manifest was stored
+	Replication *ReplicationManifest `json:"replication,omitempty"`
+	// ExpiresAt is when the content may be removed, for content uploaded
+	// with a TTL
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Executor uploads content to one class of storage backend
+type Executor interface {
+	// Class returns the backend class handled by this executor, e.g. "s3"
+	Class() string
+
+	// Put uploads the content read from r and describes where it was stored
+	Put(ctx context.Context, info routingclient.ContentInfo, r io.Reader) (*Result, error)
+}
+
+// Deleter is implemented by executors that can remove content they stored
+type Deleter interface {
+	Delete(ctx context.Context, rec PlacementRecord) error
+}
+
+// Registry maps backend IDs returned by the router to executors
+type Registry struct {
+	mu        sync.RWMutex
+	executors map[string]Executor
+	admission *Admission
+	placement *Placement
+	classes   *StorageClasses
+	striping  *Striping
+
+	replication *Replication
+}
+
+// NewRegistry creates a registry populated with the given executors
+func NewRegistry(executors ...Executor) *Registry {
+	r := &Registry{executors: make(map[string]Executor)}
+	for _, e := range executors {
+		r.Register(e)
+	}
+	return r
+}
+
+// Register adds an executor, replacing any previous one for the same class
+func (r *Registry) Register(e Executor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.executors[e.Class()] = e
+}
+
+// SetAdmission enables admission control for uploads started with Run
+func (r *Registry) SetAdmission(a *Admission) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.admission = a
+}
+
+// SetPlacement enables placement rules for uploads started with Run
+func (r *Registry) SetPlacement(p *Placement) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.placement = p
+}
+
+// SetStorageClasses enables storage classes for uploads started with Run
+func (r *Registry) SetStorageClasses(s *StorageClasses) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.classes = s
+}
+
+// Lookup finds the executor for a backend ID. Backend IDs such as
+// "s3-us-east-1" or "s3_archive" resolve to the "s3" class executor.
+func (r *Registry) Lookup(backendID string) (Executor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if e, ok := r.executors[backendID]; ok {
+		return e, true
+	}
+	e, ok := r.executors[BackendClass(backendID)]
+	return e, ok
+}
+
+// BackendClass returns the class of a backend ID: the part before the
+// first "-", "_" or ":", so "s3-us-east-1" is class "s3"
+func BackendClass(backendID string) string {
+	if i := strings.IndexAny(backendID, "-_:"); i > 0 {
+		return backendID[:i]
+	}
+	return backendID
+}
+
+// Run selects a backend for the content, uploads it with the matching
+// executor and records the measured outcome with the routing service.
+// If storage classes are set and the content requests one, only backends
+// serving that class are considered, or a *StorageClassError is returned.
+// If placement rules are set, the first permitted backend of the decision is
+// used, or a *PlacementError is returned. If admission control is enabled
+// the content is checked before upload and rejected with an *AdmissionError.
+// If striping is set, content of at least its MinSize is split into shards
+// routed to distinct permitted backends (see Striping). If replication is
+// set, other content is stored on several backends (see Replication).
+func Run(ctx context.Context, client *routingclient.Client, registry *Registry, info routingclient.ContentInfo, strategy string, r io.Reader) (*Result, error) {
+	res, err := run(ctx, client, registry, info, strategy, r, true)
+	if err == nil {
+		logPlacement(ctx, info, res)
+	}
+	return res, err
+}
+
+func run(ctx context.Context, client *routingclient.Client, registry *Registry, info routingclient.ContentInfo, strategy string, r io.Reader, fanOut bool) (*Result, error) {
+	registry.mu.RLock()
+	admission, placement, classes, striping := registry.admission, registry.placement, registry.classes, registry.striping
+	replication := registry.replication
+	registry.mu.RUnlock()
+	if fanOut && striping != nil && info.ContentSize >= striping.MinSize {
+		return stripe(ctx, client, registry, striping, info, strategy, r)
+	}
+	if fanOut && replication != nil {
+		return replicate(ctx, client, registry, replication, info, strategy, r)
+	}
+
+	resp, err := client.SelectBackend(ctx, info, strategy)
+	if err != nil {
+		return nil, fmt.Errorf("select backend: %w", err)
+	}
+
+	if class := ClassOf(info); classes != nil && class != "" {
+		if err := classes.Narrow(class, resp); err != nil {
+			return nil, err
+		}
+	}
+
+	if placement != nil {
+		bucket := info.Metadata["bucket"]
+		backendID, err := placement.Choose(registry, bucket, resp)
+		if err != nil {
+			return nil, err
+		}
+		resp.BackendId = backendID
+		ctx = withProviderFilter(ctx, func(provider string) bool {
+			return placement.Permits(bucket, provider)
+		})
+	}
+
+	exec, ok := registry.Lookup(resp.BackendId)
+	if !ok {
+		return nil, fmt.Errorf("no executor registered for backend %q", resp.BackendId)
+	}
+
+	if admission != nil {
+		if err := admission.Check(ctx, registry, info, resp); err != nil {
+			return nil, err
+		}
+	}
+
+	return Execute(ctx, client, exec, resp.BackendId, info, r)
+}
+
+type progressKey struct{}
+
+// WithProgress reports the progress of uploads run with ctx to fn, as the
+// bytes each executor has read, under the ID of the backend it uploads to.
+// A striped or replicated upload reports every shard or replica, and its
+// manifest, separately.
+func WithProgress(ctx context.Context, fn progress.Func) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+type throttleKey struct{}
+
+// WithThrottle limits the bandwidth of uploads run with ctx, by pacing
+// how fast executors read their content. Every shard, replica and
+// manifest of a striped or replicated upload is a transfer of its own.
+func WithThrottle(ctx context.Context, limits *throttle.Limits) context.Context {
+	return context.WithValue(ctx, throttleKey{}, limits)
+}
+
+type poolKey struct{}
+
+// WithPool makes uploads run with ctx wait for a slot on their backend in
+// pool, so bulk operations keep to its per-backend limits. The wait is not
+// part of the outcome's duration.
+func WithPool(ctx context.Context, pool *workpool.Pool) context.Context {
+	return context.WithValue(ctx, poolKey{}, pool)
+}
+
+// PlacementLog is told where uploads were stored, e.g. history.Store,
+// which keeps them so an agent can later say where content went
+type PlacementLog interface {
+	LogPlacement(info routingclient.ContentInfo, result *Result)
+}
+
+type placementLogKey struct{}
+
+// WithPlacementLog reports each upload Run or Migrate completes with ctx
+// to log. A striped or replicated upload is reported once, with its
+// manifest, rather than per shard or replica.
+func WithPlacementLog(ctx context.Context, log PlacementLog) context.Context {
+	return context.WithValue(ctx, placementLogKey{}, log)
+}
+
+func logPlacement(ctx context.Context, info routingclient.ContentInfo, res *Result) {
+	if log, ok := ctx.Value(placementLogKey{}).(PlacementLog); ok && log != nil {
+		log.LogPlacement(info, res)
+	}
+}
+
+// Execute uploads content with a specific executor and records the outcome
+// against backendID. The outcome is recorded even if the upload fails.
+func Execute(ctx context.Context, client *routingclient.Client, exec Executor, backendID string, info routingclient.ContentInfo, r io.Reader) (*Result, error) {
+	if limits, ok := ctx.Value(throttleKey{}).(*throttle.Limits); ok {
+		r = limits.Reader(ctx, r)
+	}
+	pool, _ := ctx.Value(poolKey{}).(*workpool.Pool)
+	release, err := pool.Acquire(ctx, backendID)
+	if err != nil {
+		return nil, fmt.Errorf("%s upload: wait for a slot on %s: %w", exec.Class(), backendID, err)
+	}
+	defer release()
+	if fn, ok := ctx.Value(progressKey{}).(progress.Func); ok && fn != nil {
+		r = progress.NewReader(r, backendID, info.ContentSize, fn)
+	}
+	start := time.Now()
+	result, putErr := exec.Put(ctx, info, r)
+	outcome := routingclient.Outcome{
+		BackendID: backendID,
+		Success:   putErr == nil,
+		Duration:  time.Since(start),
+		Err:       putErr,
+	}
+
+	if _, err := client.RecordOutcome(ctx, info, outcome); err != nil {
+		if putErr != nil {
+			return nil, fmt.Errorf("%s upload failed: %w (recording outcome also failed: %v)", exec.Class(), putErr, err)
+		}
+		result.ExpiresAt = expiry(start, info.TTL)
+		return result, fmt.Errorf("record outcome: %w", err)
+	}
+	if putErr != nil {
+		return nil, fmt.Errorf("%s upload failed: %w", exec.Class(), putErr)
+	}
+
+	result.BackendID = backendID
+	result.ExpiresAt = expiry(start, info.TTL)
+	return result, nil
+}
@@ -0,0 +1,275 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/ipfs_kit_py/lotus"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// FilecoinConfig configures the Filecoin executor
+type FilecoinConfig struct {
+	Lotus *lotus.Client
+
+	// Miners are tried in order until one accepts the deal proposal
+	Miners []string
+
+	// EpochPrice is the price in attoFIL per epoch per GiB (default "0")
+	EpochPrice string
+
+	// Duration is the deal duration in epochs (default 518400, ~180 days)
+	Duration uint64
+
+	// ImportDir must be readable by the Lotus node, since ClientImport
+	// takes a path on the node's filesystem (default os.TempDir())
+	ImportDir string
+
+	VerifiedDeal  bool
+	FastRetrieval bool
+
+	// WaitUntil is the deal state that counts as success (default
+	// StorageDealStaged, once the deal is published on chain; sealing and
+	// activation take hours more). Put blocks until it is reached or the
+	// deal fails, then tracks the deal to activation in the background.
+	WaitUntil lotus.DealState
+
+	// PollInterval is the deal state polling interval (default 30s)
+	PollInterval time.Duration
+
+	// OnDealUpdate, if set, is called on every observed deal state change,
+	// including from the goroutines tracking deals to activation
+	OnDealUpdate func(*lotus.DealInfo)
+
+	// OnDealActive, if set, is called once a deal Put returned for becomes
+	// active or cannot be tracked further. RecordDealOutcomes reports it to
+	// the routing service.
+	OnDealActive func(DealActivation)
+}
+
+// DealActivation is the end of tracking a deal made by Put
+type DealActivation struct {
+	Proposal string
+	Content  routingclient.ContentInfo
+	// Deal is the last state seen, nil if none was
+	Deal *lotus.DealInfo
+	// Started is when Put began uploading the content
+	Started time.Time
+	// Err is nil once the deal is active, a *lotus.DealFailedError if it
+	// failed, or the error that stopped tracking it
+	Err error
+}
+
+// RecordDealOutcomes returns an OnDealActive reporting each deal's
+// activation or failure to the routing service as an outcome of
+// backendID, timed from the start of its upload
+func RecordDealOutcomes(client *routingclient.Client, backendID string) func(DealActivation) {
+	return func(a DealActivation) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		outcome := routingclient.Outcome{
+			BackendID:      backendID,
+			Success:        a.Err == nil,
+			Duration:       time.Since(a.Started),
+			Err:            a.Err,
+			IdempotencyKey: "filecoin-deal-" + a.Proposal,
+		}
+		if a.Err == nil {
+			outcome.Bytes = a.Content.ContentSize
+		}
+		client.RecordOutcome(ctx, a.Content, outcome)
+	}
+}
+
+// FilecoinExecutor imports content into a Lotus node and makes a storage
+// deal for it, succeeding once the deal reaches the configured state and
+// tracking it to activation after that
+type FilecoinExecutor struct {
+	cfg      FilecoinConfig
+	tracking sync.WaitGroup
+
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+// NewFilecoinExecutor creates a Filecoin executor
+func NewFilecoinExecutor(cfg FilecoinConfig) (*FilecoinExecutor, error) {
+	if cfg.Lotus == nil {
+		return nil, errors.New("filecoin: lotus client is required")
+	}
+	if len(cfg.Miners) == 0 {
+		return nil, errors.New("filecoin: at least one miner is required")
+	}
+	if cfg.EpochPrice == "" {
+		cfg.EpochPrice = "0"
+	}
+	if cfg.Duration == 0 {
+		cfg.Duration = 518400
+	}
+	if cfg.ImportDir == "" {
+		cfg.ImportDir = os.TempDir()
+	}
+	if cfg.WaitUntil == lotus.StorageDealUnknown {
+		cfg.WaitUntil = lotus.StorageDealStaged
+	}
+	return &FilecoinExecutor{cfg: cfg, pending: make(map[string]bool)}, nil
+}
+
+// Class implements Executor
+func (e *FilecoinExecutor) Class() string {
+	return "filecoin"
+}
+
+// SpoolDir implements Spooler: content is written to ImportDir for the
+// Lotus node to import
+func (e *FilecoinExecutor) SpoolDir() string {
+	return e.cfg.ImportDir
+}
+
+// Put implements Executor
+func (e *FilecoinExecutor) Put(ctx context.Context, info routingclient.ContentInfo, r io.Reader) (*Result, error) {
+	started := time.Now()
+	pattern := "routing-import-*"
+	if isCAR(info) {
+		pattern += ".car"
+	}
+	f, err := os.CreateTemp(e.cfg.ImportDir, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("filecoin: stage import: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	size, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("filecoin: stage import: %w", err)
+	}
+
+	path, err := filepath.Abs(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	imported, err := e.cfg.Lotus.ClientImport(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	root := imported.Root.String()
+
+	miner, proposal, err := e.startDeal(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	deal, err := e.cfg.Lotus.WaitDeal(ctx, proposal, e.cfg.WaitUntil, e.cfg.PollInterval, e.cfg.OnDealUpdate)
+	if err != nil {
+		return nil, err
+	}
+	activation := DealActivation{Proposal: proposal, Content: info, Deal: deal, Started: started}
+	if deal.State == lotus.StorageDealActive {
+		if e.cfg.OnDealActive != nil {
+			e.cfg.OnDealActive(activation)
+		}
+	} else {
+		e.track(context.WithoutCancel(ctx), activation)
+	}
+
+	return &Result{
+		Location: fmt.Sprintf("filecoin://%s/%s", miner, deal.ProposalCid),
+		CID:      root,
+		Bytes:    size,
+		Provider: "filecoin:" + miner,
+	}, nil
+}
+
+// Wait blocks until every deal Put returned for before activation has
+// become active or failed, so a command can report them before exiting
+func (e *FilecoinExecutor) Wait() {
+	e.tracking.Wait()
+}
+
+// Pending returns the proposal CIDs of the deals still being tracked to
+// activation, sorted
+func (e *FilecoinExecutor) Pending() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	proposals := make([]string, 0, len(e.pending))
+	for p := range e.pending {
+		proposals = append(proposals, p)
+	}
+	sort.Strings(proposals)
+	return proposals
+}
+
+// track polls a deal to activation in the background, reporting the state
+// changes after a.Deal's to OnDealUpdate and the result to OnDealActive
+func (e *FilecoinExecutor) track(ctx context.Context, a DealActivation) {
+	e.mu.Lock()
+	e.pending[a.Proposal] = true
+	e.mu.Unlock()
+	e.tracking.Add(1)
+	go func() {
+		defer e.tracking.Done()
+		state := a.Deal.State
+		info, err := e.cfg.Lotus.WaitDeal(ctx, a.Proposal, lotus.StorageDealActive, e.cfg.PollInterval, func(info *lotus.DealInfo) {
+			if info.State != state && e.cfg.OnDealUpdate != nil {
+				e.cfg.OnDealUpdate(info)
+			}
+			state = info.State
+		})
+		if info != nil {
+			a.Deal = info
+		}
+		a.Err = err
+		e.mu.Lock()
+		delete(e.pending, a.Proposal)
+		e.mu.Unlock()
+		if e.cfg.OnDealActive != nil {
+			e.cfg.OnDealActive(a)
+		}
+	}()
+}
+
+// Providers implements Provided
+func (e *FilecoinExecutor) Providers() []string {
+	providers := make([]string, len(e.cfg.Miners))
+	for i, m := range e.cfg.Miners {
+		providers[i] = "filecoin:" + m
+	}
+	return providers
+}
+
+// startDeal proposes a deal to each configured miner permitted by the
+// placement rules until one succeeds
+func (e *FilecoinExecutor) startDeal(ctx context.Context, root string) (string, string, error) {
+	var errs []error
+	for _, miner := range e.cfg.Miners {
+		if !providerPermitted(ctx, "filecoin:"+miner) {
+			continue
+		}
+		proposal, err := e.cfg.Lotus.ClientStartDeal(ctx, lotus.DealParams{
+			Root:              root,
+			Miner:             miner,
+			EpochPrice:        e.cfg.EpochPrice,
+			MinBlocksDuration: e.cfg.Duration,
+			VerifiedDeal:      e.cfg.VerifiedDeal,
+			FastRetrieval:     e.cfg.FastRetrieval,
+		})
+		if err == nil {
+			return miner, proposal, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", miner, err))
+	}
+	if len(errs) == 0 {
+		return "", "", errors.New("filecoin: no configured miner is permitted by placement rules")
+	}
+	return "", "", fmt.Errorf("filecoin: no miner accepted the deal: %w", errors.Join(errs...))
+}
@@ -0,0 +1,129 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/ipfs_kit_py/lotus"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// fakeLotus answers the JSON-RPC calls the Filecoin executor makes, moving
+// its one deal a state further along states on each ClientGetDealInfo
+type fakeLotus struct {
+	mu     sync.Mutex
+	states []lotus.DealState
+	polls  int
+}
+
+func (f *fakeLotus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Method string `json:"method"`
+		ID     int64  `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var result interface{}
+	switch strings.TrimPrefix(req.Method, "Filecoin.") {
+	case "ClientImport":
+		result = lotus.ImportResult{Root: lotus.Cid{Root: "bafyroot"}, ImportID: 1}
+	case "WalletDefaultAddress":
+		result = "f1wallet"
+	case "ClientStartDeal":
+		result = lotus.Cid{Root: "bafyproposal"}
+	case "ClientGetDealInfo":
+		f.mu.Lock()
+		state := f.states[min(f.polls, len(f.states)-1)]
+		f.polls++
+		f.mu.Unlock()
+		result = lotus.DealInfo{ProposalCid: lotus.Cid{Root: "bafyproposal"}, State: state, Message: state.String()}
+	default:
+		http.Error(w, "unknown method "+req.Method, http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+func TestFilecoinTracksDealToActivation(t *testing.T) {
+	tests := []struct {
+		name   string
+		states []lotus.DealState
+		failed bool
+	}{
+		{"active", []lotus.DealState{lotus.StorageDealPublishing, lotus.StorageDealStaged, lotus.StorageDealSealing, lotus.StorageDealFinalizing, lotus.StorageDealActive}, false},
+		{"failed", []lotus.DealState{lotus.StorageDealStaged, lotus.StorageDealSealing, lotus.StorageDealError}, true},
+		{"active at once", []lotus.DealState{lotus.StorageDealActive}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(&fakeLotus{states: tt.states})
+			defer srv.Close()
+
+			var (
+				mu          sync.Mutex
+				seen        []lotus.DealState
+				activations []DealActivation
+			)
+			e, err := NewFilecoinExecutor(FilecoinConfig{
+				Lotus:        lotus.NewClient(srv.URL, "token"),
+				Miners:       []string{"f01000"},
+				ImportDir:    t.TempDir(),
+				PollInterval: time.Millisecond,
+				OnDealUpdate: func(info *lotus.DealInfo) {
+					mu.Lock()
+					seen = append(seen, info.State)
+					mu.Unlock()
+				},
+				OnDealActive: func(a DealActivation) {
+					mu.Lock()
+					activations = append(activations, a)
+					mu.Unlock()
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			info := routingclient.ContentInfo{ContentSize: 5}
+			res, err := e.Put(context.Background(), info, strings.NewReader("hello"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.CID != "bafyroot" || res.Location != "filecoin://f01000/bafyproposal" || res.Provider != "filecoin:f01000" {
+				t.Errorf("result %+v", res)
+			}
+			e.Wait()
+			if pending := e.Pending(); len(pending) != 0 {
+				t.Errorf("deals %v still pending after Wait", pending)
+			}
+
+			if !reflect.DeepEqual(seen, tt.states) {
+				t.Errorf("deal updates %v, want %v", seen, tt.states)
+			}
+			if len(activations) != 1 {
+				t.Fatalf("%d activations reported, want 1", len(activations))
+			}
+			a := activations[0]
+			if a.Proposal != "bafyproposal" || a.Content.ContentSize != 5 || a.Started.IsZero() {
+				t.Errorf("activation %+v", a)
+			}
+			if last := tt.states[len(tt.states)-1]; a.Deal == nil || a.Deal.State != last {
+				t.Errorf("activation deal %+v, want state %s", a.Deal, last)
+			}
+			var failed *lotus.DealFailedError
+			if errors.As(a.Err, &failed) != tt.failed {
+				t.Errorf("activation error %v, want deal failure %v", a.Err, tt.failed)
+			}
+		})
+	}
+}
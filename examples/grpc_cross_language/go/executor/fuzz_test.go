@@ -0,0 +1,62 @@
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"example.com/ipfs_kit_py/executor"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// FuzzReplicationManifest decodes its input as a replication manifest, as
+// gc and migrate read them back from storage. Every placement record must
+// come from a replica that was stored.
+func FuzzReplicationManifest(f *testing.F) {
+	f.Add([]byte(`{"version":1,"size":1048576,"sha256":"` + strings.Repeat("1", 64) + `","bucket":"datasets","copies":2,` +
+		`"replicas":[{"backend_id":"ipfs","cid":"bafkqaaa","duration_ms":12},{"backend_id":"s3","error":"timeout"}],` +
+		`"expires_at":"2026-01-02T03:04:05Z"}`))
+	f.Add([]byte(`{"replicas":[{"backend_id":""}],"copies":-1}`))
+	f.Add([]byte(`{"expires_at":"never"}`))
+	f.Add([]byte(`null`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var m executor.ReplicationManifest
+		if json.Unmarshal(data, &m) != nil {
+			return
+		}
+		if records, stored := m.Records(), m.Stored(); len(records) != len(stored) {
+			t.Fatalf("%d placement records for %d stored replicas", len(records), len(stored))
+		}
+	})
+}
+
+// FuzzPlanMigration plans the migration of one object from GetInsights and
+// EstimateCost responses decoded from its input
+func FuzzPlanMigration(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0x0a, 0x00})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		client := routingclient.NewClient(&replyConn{data: data})
+		records := []executor.PlacementRecord{{BackendID: "ipfs", Location: "bafy", Bytes: 1 << 20}}
+		executor.PlanMigration(context.Background(), client, executor.NewRegistry(), records, executor.MigrationPolicy{})
+	})
+}
+
+// replyConn is a grpc.ClientConnInterface answering every unary call by
+// decoding data into the reply
+type replyConn struct {
+	data []byte
+}
+
+func (c *replyConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return proto.Unmarshal(c.data, reply.(proto.Message))
+}
+
+func (c *replyConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, errors.New("streams are not supported")
+}
@@ -0,0 +1,214 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// Reasons a placement is garbage
+const (
+	GCUnpinned   = "unpinned"
+	GCExpired    = "expired"
+	GCRedundant  = "redundant"
+	GCSuperseded = "superseded"
+)
+
+// Cleanup actions
+const (
+	// ActionUnpin unpins the content through the Pinner
+	ActionUnpin = "unpin"
+	// ActionDelete removes the content through its executor's Deleter
+	ActionDelete = "delete"
+	// ActionCollect only runs garbage collection on the IPFS node, for
+	// content that is no longer pinned
+	ActionCollect = "collect"
+)
+
+// GCPolicy decides which placements are garbage
+type GCPolicy struct {
+	// Copies is the most placements a CID should have; those listed after
+	// the first Copies are redundant (0 for no limit)
+	Copies int
+	// Superseded are placements replaced by others, e.g. the sources of
+	// completed migrations
+	Superseded []PlacementRecord
+	// Now is when expiry is judged (default the current time)
+	Now time.Time
+}
+
+// Cleanup proposes removing one placement
+type Cleanup struct {
+	PlacementRecord
+	Reason string `json:"reason"`
+	// Action is how ApplyGC removes it; empty if no configured executor or
+	// pinner can, and it has to be removed by hand
+	Action string `json:"action,omitempty"`
+}
+
+// GCPlan is the outcome of PlanGC
+type GCPlan struct {
+	Cleanups []Cleanup `json:"cleanups"`
+	Checked  int       `json:"checked"`
+	// Bytes is, per backend, what the cleanups would free according to
+	// the placement records' sizes
+	Bytes map[string]int64 `json:"bytes"`
+}
+
+// CleanupFailure is a cleanup ApplyGC could not carry out
+type CleanupFailure struct {
+	Cleanup
+	Error string `json:"error"`
+}
+
+// GCReport is the outcome of ApplyGC
+type GCReport struct {
+	Applied []Cleanup        `json:"applied"`
+	Failed  []CleanupFailure `json:"failed,omitempty"`
+	Skipped []Cleanup        `json:"skipped,omitempty"`
+	// Reclaimed is, per backend, the bytes freed: measured on IPFS nodes
+	// as the drop in repo size over garbage collection, and taken from the
+	// placement records elsewhere
+	Reclaimed map[string]int64 `json:"reclaimed"`
+}
+
+// PlanGC finds the placements that are expired, superseded, beyond the
+// policy's copy count, or, on the Pinner's IPFS nodes, no longer pinned.
+// pinner may be nil; registry's executors that implement Deleter remove
+// content on other backends.
+func PlanGC(ctx context.Context, registry *Registry, pinner *Pinner, records []PlacementRecord, policy GCPolicy) (*GCPlan, error) {
+	if policy.Now.IsZero() {
+		policy.Now = time.Now()
+	}
+	superseded := make(map[string]bool)
+	for _, rec := range policy.Superseded {
+		superseded[gcKey(rec)] = true
+	}
+
+	// Pins are checked once per node, for all of its CIDs
+	var unpinned map[string]map[string]bool
+	if pinner != nil {
+		cids := make(map[string][]string)
+		for _, rec := range records {
+			if _, ok := pinner.nodeFor(rec.BackendID); ok && rec.CID != "" {
+				cids[rec.BackendID] = append(cids[rec.BackendID], rec.CID)
+			}
+		}
+		unpinned = make(map[string]map[string]bool)
+		for id, list := range cids {
+			pins, err := pinner.List(ctx, id, list...)
+			if err != nil {
+				return nil, fmt.Errorf("gc: list pins on %s: %w", id, err)
+			}
+			unpinned[id] = make(map[string]bool)
+			for _, cid := range list {
+				if _, ok := pins[cid]; !ok {
+					unpinned[id][cid] = true
+				}
+			}
+		}
+	}
+
+	plan := &GCPlan{Checked: len(records), Bytes: make(map[string]int64)}
+	propose := func(rec PlacementRecord, reason string) {
+		c := Cleanup{PlacementRecord: rec, Reason: reason}
+		switch {
+		case reason == GCUnpinned:
+			c.Action = ActionCollect
+		case pinner.manages(rec.BackendID) && rec.CID != "":
+			c.Action = ActionUnpin
+		default:
+			if exec, ok := registry.Lookup(rec.BackendID); ok {
+				if _, ok := exec.(Deleter); ok {
+					c.Action = ActionDelete
+				}
+			}
+		}
+		plan.Cleanups = append(plan.Cleanups, c)
+		plan.Bytes[rec.BackendID] += rec.Bytes
+	}
+	copies := make(map[string]int)
+	for _, rec := range records {
+		switch {
+		case rec.ExpiresAt != nil && rec.ExpiresAt.Before(policy.Now):
+			propose(rec, GCExpired)
+		case superseded[gcKey(rec)]:
+			propose(rec, GCSuperseded)
+		case unpinned[rec.BackendID][rec.CID]:
+			propose(rec, GCUnpinned)
+		case rec.CID != "" && policy.Copies > 0 && copies[rec.CID] >= policy.Copies:
+			propose(rec, GCRedundant)
+		case rec.CID != "":
+			copies[rec.CID]++
+		}
+	}
+	return plan, nil
+}
+
+// ApplyGC carries out a plan's cleanups, then runs garbage collection on
+// every IPFS node content was unpinned from. Outcomes of unpins are
+// recorded if the Pinner has a routing client.
+func ApplyGC(ctx context.Context, registry *Registry, pinner *Pinner, plan *GCPlan) *GCReport {
+	report := &GCReport{Reclaimed: make(map[string]int64)}
+	collect := make(map[string]bool)
+	for _, c := range plan.Cleanups {
+		var err error
+		switch c.Action {
+		case ActionCollect:
+		case ActionUnpin:
+			info := routingclient.ContentInfo{ContentSize: c.Bytes}
+			if c.Bucket != "" {
+				info.Metadata = map[string]string{"bucket": c.Bucket}
+			}
+			err = pinner.Remove(ctx, info, c.BackendID, c.CID)
+		case ActionDelete:
+			exec, _ := registry.Lookup(c.BackendID)
+			err = exec.(Deleter).Delete(ctx, c.PlacementRecord)
+		default:
+			report.Skipped = append(report.Skipped, c)
+			continue
+		}
+		if err != nil {
+			report.Failed = append(report.Failed, CleanupFailure{Cleanup: c, Error: err.Error()})
+			continue
+		}
+		report.Applied = append(report.Applied, c)
+		if _, ok := pinner.nodeFor(c.BackendID); ok && c.Action != ActionDelete {
+			collect[c.BackendID] = true
+		} else {
+			report.Reclaimed[c.BackendID] += c.Bytes
+		}
+	}
+
+	ids := make([]string, 0, len(collect))
+	for id := range collect {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		node, _ := pinner.nodeFor(id)
+		before, err := node.RepoStats(ctx)
+		if err == nil {
+			_, err = node.RepoGC(ctx)
+		}
+		if err != nil {
+			report.Failed = append(report.Failed, CleanupFailure{
+				Cleanup: Cleanup{PlacementRecord: PlacementRecord{BackendID: id}, Action: ActionCollect},
+				Error:   err.Error(),
+			})
+			continue
+		}
+		if after, err := node.RepoStats(ctx); err == nil && after.RepoSize < before.RepoSize {
+			report.Reclaimed[id] += before.RepoSize - after.RepoSize
+		}
+	}
+	return report
+}
+
+// gcKey identifies a placement
+func gcKey(rec PlacementRecord) string {
+	return rec.BackendID + " " + rec.Location
+}
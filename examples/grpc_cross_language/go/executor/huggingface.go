@@ -0,0 +1,470 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// DefaultHuggingFaceEndpoint is the public HuggingFace Hub
+const DefaultHuggingFaceEndpoint = "https://huggingface.co"
+
+// HuggingFaceConfig configures the HuggingFace Hub executor
+type HuggingFaceConfig struct {
+	// RepoID is the repository, e.g. "org/dataset-name"
+	RepoID string
+
+	// RepoType is "model", "dataset" or "space" (default "model")
+	RepoType string
+
+	// Revision is the branch to commit to (default "main")
+	Revision string
+
+	// PathPrefix is the directory inside the repository files are
+	// committed under. Each file is stored at <PathPrefix>/<sha256>/<name>,
+	// so different content never overwrites a file of the same name.
+	PathPrefix string
+
+	// Token is the access token; defaults to HUGGINGFACE_TOKEN or HF_TOKEN
+	Token string
+
+	// Endpoint overrides the Hub URL (default DefaultHuggingFaceEndpoint)
+	Endpoint string
+
+	// Concurrency is the number of LFS parts uploaded in parallel (default
+	// 4). The part size is set by the Hub.
+	Concurrency int
+
+	HTTPClient *http.Client
+}
+
+// HuggingFaceExecutor commits files to a HuggingFace Hub repository. Files
+// the Hub routes to LFS are uploaded through the LFS batch API, using
+// multipart transfers for large files.
+type HuggingFaceExecutor struct {
+	cfg  HuggingFaceConfig
+	http *http.Client
+}
+
+// NewHuggingFaceExecutor creates a HuggingFace Hub executor
+func NewHuggingFaceExecutor(cfg HuggingFaceConfig) (*HuggingFaceExecutor, error) {
+	if cfg.RepoID == "" {
+		return nil, errors.New("huggingface: repo ID is required")
+	}
+	if cfg.RepoType == "" {
+		cfg.RepoType = "model"
+	}
+	switch cfg.RepoType {
+	case "model", "dataset", "space":
+	default:
+		return nil, fmt.Errorf("huggingface: invalid repo type %q", cfg.RepoType)
+	}
+	if cfg.Revision == "" {
+		cfg.Revision = "main"
+	}
+	if cfg.Token == "" {
+		cfg.Token = firstNonEmpty(os.Getenv("HUGGINGFACE_TOKEN"), os.Getenv("HF_TOKEN"))
+	}
+	if cfg.Token == "" {
+		return nil, errors.New("huggingface: no token configured (set HF_TOKEN)")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = DefaultHuggingFaceEndpoint
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	if u, err := url.Parse(cfg.Endpoint); err != nil || u.Host == "" {
+		return nil, fmt.Errorf("huggingface: invalid endpoint %q", cfg.Endpoint)
+	}
+	for _, elem := range strings.Split(cfg.PathPrefix, "/") {
+		if elem == ".." {
+			return nil, fmt.Errorf("huggingface: path prefix %q leaves the repository", cfg.PathPrefix)
+		}
+	}
+	cfg.PathPrefix = strings.Trim(path.Clean("/"+cfg.PathPrefix), "/")
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	return &HuggingFaceExecutor{cfg: cfg, http: cfg.HTTPClient}, nil
+}
+
+// Class implements Executor
+func (e *HuggingFaceExecutor) Class() string {
+	return "huggingface"
+}
+
+func (e *HuggingFaceExecutor) provider() string {
+	u, _ := url.Parse(e.cfg.Endpoint)
+	return "huggingface:" + u.Host
+}
+
+// Providers implements Provided
+func (e *HuggingFaceExecutor) Providers() []string {
+	return []string{e.provider()}
+}
+
+// SpoolDir implements Spooler: content is copied to a temporary file to
+// hash it before the upload
+func (e *HuggingFaceExecutor) SpoolDir() string {
+	return os.TempDir()
+}
+
+// Put implements Executor
+func (e *HuggingFaceExecutor) Put(ctx context.Context, info routingclient.ContentInfo, r io.Reader) (*Result, error) {
+	if !providerPermitted(ctx, e.provider()) {
+		return nil, fmt.Errorf("huggingface: %s is not permitted by placement rules", e.provider())
+	}
+	f, err := os.CreateTemp("", "hf-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return nil, fmt.Errorf("huggingface: stage upload: %w", err)
+	}
+	oid := hex.EncodeToString(h.Sum(nil))
+
+	repoPath := e.repoPath(oid, info.Filename)
+
+	sample := make([]byte, 512)
+	n, _ := f.ReadAt(sample, 0)
+	mode, err := e.preupload(ctx, repoPath, size, sample[:n])
+	if err != nil {
+		return nil, err
+	}
+
+	var op map[string]interface{}
+	if mode == "lfs" {
+		if err := e.uploadLFS(ctx, f, oid, size); err != nil {
+			return nil, err
+		}
+		op = map[string]interface{}{
+			"key":   "lfsFile",
+			"value": map[string]interface{}{"path": repoPath, "algo": "sha256", "oid": oid},
+		}
+	} else {
+		content := make([]byte, size)
+		if _, err := f.ReadAt(content, 0); err != nil && err != io.EOF {
+			return nil, err
+		}
+		op = map[string]interface{}{
+			"key": "file",
+			"value": map[string]interface{}{
+				"path":     repoPath,
+				"encoding": "base64",
+				"content":  base64.StdEncoding.EncodeToString(content),
+			},
+		}
+	}
+
+	commitURL, err := e.commit(ctx, fmt.Sprintf("Upload %s", repoPath), op)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Location: commitURL, Bytes: size, ETag: oid, Provider: e.provider()}, nil
+}
+
+// repoPath is where content with SHA-256 oid is committed: a directory
+// named by oid under PathPrefix, holding it under the last element of
+// filename. Only the base name is kept, so filename cannot leave the
+// directory, and one path always holds the same content.
+func (e *HuggingFaceExecutor) repoPath(oid, filename string) string {
+	name := downloadName(filename)
+	if name == "" {
+		name = "content"
+	}
+	return strings.TrimPrefix(path.Join(e.cfg.PathPrefix, oid, name), "/")
+}
+
+// apiPrefix returns the REST API path of the repository
+func (e *HuggingFaceExecutor) apiPrefix() string {
+	return fmt.Sprintf("%s/api/%ss/%s", e.cfg.Endpoint, e.cfg.RepoType, e.cfg.RepoID)
+}
+
+// gitPrefix returns the git URL of the repository used by the LFS API
+func (e *HuggingFaceExecutor) gitPrefix() string {
+	if e.cfg.RepoType == "model" {
+		return fmt.Sprintf("%s/%s.git", e.cfg.Endpoint, e.cfg.RepoID)
+	}
+	return fmt.Sprintf("%s/%ss/%s.git", e.cfg.Endpoint, e.cfg.RepoType, e.cfg.RepoID)
+}
+
+// preupload asks the Hub whether a file should be stored as a regular git
+// blob or in LFS
+func (e *HuggingFaceExecutor) preupload(ctx context.Context, repoPath string, size int64, sample []byte) (string, error) {
+	body := map[string]interface{}{
+		"files": []map[string]interface{}{{
+			"path":   repoPath,
+			"size":   size,
+			"sample": base64.StdEncoding.EncodeToString(sample),
+		}},
+	}
+	var out struct {
+		Files []struct {
+			Path       string `json:"path"`
+			UploadMode string `json:"uploadMode"`
+		} `json:"files"`
+	}
+	target := fmt.Sprintf("%s/preupload/%s", e.apiPrefix(), url.PathEscape(e.cfg.Revision))
+	if err := e.doJSON(ctx, http.MethodPost, target, "application/json", body, &out); err != nil {
+		return "", fmt.Errorf("huggingface: preupload: %w", err)
+	}
+	if len(out.Files) == 0 {
+		return "", errors.New("huggingface: preupload: empty response")
+	}
+	return out.Files[0].UploadMode, nil
+}
+
+// lfsAction is an upload, verify or completion action from the LFS batch API
+type lfsAction struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header"`
+}
+
+// uploadLFS uploads the staged file through the LFS batch API. The Hub
+// responds with either a single upload URL or, for large files, a chunk
+// size plus one presigned URL per part.
+func (e *HuggingFaceExecutor) uploadLFS(ctx context.Context, f *os.File, oid string, size int64) error {
+	batch := map[string]interface{}{
+		"operation": "upload",
+		"transfers": []string{"basic", "multipart"},
+		"hash_algo": "sha256",
+		"objects":   []map[string]interface{}{{"oid": oid, "size": size}},
+		"ref":       map[string]string{"name": e.cfg.Revision},
+	}
+	var out struct {
+		Objects []struct {
+			Actions map[string]lfsAction `json:"actions"`
+			Error   *struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"objects"`
+	}
+	if err := e.doJSON(ctx, http.MethodPost, e.gitPrefix()+"/info/lfs/objects/batch", "application/vnd.git-lfs+json", batch, &out); err != nil {
+		return fmt.Errorf("huggingface: lfs batch: %w", err)
+	}
+	if len(out.Objects) == 0 {
+		return errors.New("huggingface: lfs batch: empty response")
+	}
+	obj := out.Objects[0]
+	if obj.Error != nil {
+		return fmt.Errorf("huggingface: lfs batch: %s (code %d)", obj.Error.Message, obj.Error.Code)
+	}
+
+	upload, ok := obj.Actions["upload"]
+	if !ok {
+		return nil // the Hub already has this object
+	}
+	if chunk, ok := upload.Header["chunk_size"]; ok {
+		if err := e.uploadLFSMultipart(ctx, f, oid, size, upload, chunk); err != nil {
+			return err
+		}
+	} else if _, err := e.putPart(ctx, upload.Href, upload.Header, io.NewSectionReader(f, 0, size), size); err != nil {
+		return fmt.Errorf("huggingface: lfs upload: %w", err)
+	}
+
+	if verify, ok := obj.Actions["verify"]; ok {
+		err := e.doJSON(ctx, http.MethodPost, verify.Href, "application/vnd.git-lfs+json", map[string]interface{}{"oid": oid, "size": size}, nil)
+		if err != nil {
+			return fmt.Errorf("huggingface: lfs verify: %w", err)
+		}
+	}
+	return nil
+}
+
+// uploadLFSMultipart uploads each chunk to its presigned URL, Concurrency
+// at a time and each with its MD5 for the store to check, and then posts
+// the collected ETags to the completion URL
+func (e *HuggingFaceExecutor) uploadLFSMultipart(ctx context.Context, f *os.File, oid string, size int64, upload lfsAction, chunkSize string) error {
+	chunk, err := strconv.ParseInt(chunkSize, 10, 64)
+	if err != nil || chunk <= 0 {
+		return fmt.Errorf("huggingface: invalid chunk size %q", chunkSize)
+	}
+
+	var partNumbers []int
+	for k := range upload.Header {
+		if n, err := strconv.Atoi(k); err == nil {
+			partNumbers = append(partNumbers, n)
+		}
+	}
+	sort.Ints(partNumbers)
+
+	type part struct {
+		PartNumber int    `json:"partNumber"`
+		ETag       string `json:"etag"`
+	}
+	parts := make([]part, len(partNumbers))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	sem := make(chan struct{}, e.cfg.Concurrency)
+	for i, n := range partNumbers {
+		partURL := upload.Header[fmt.Sprintf("%05d", n)]
+		if partURL == "" {
+			partURL = upload.Header[strconv.Itoa(n)]
+		}
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// the last part is whatever remains after the full chunks
+			off := int64(n-1) * chunk
+			section := io.NewSectionReader(f, off, min(chunk, size-off))
+			sum := md5.New()
+			if _, err := io.Copy(sum, section); err != nil {
+				fail(fmt.Errorf("huggingface: lfs part %d: %w", n, err))
+				return
+			}
+			section.Seek(0, io.SeekStart)
+			headers := map[string]string{"Content-MD5": base64.StdEncoding.EncodeToString(sum.Sum(nil))}
+			etag, err := e.putPart(ctx, partURL, headers, section, section.Size())
+			if err != nil {
+				fail(fmt.Errorf("huggingface: lfs part %d: %w", n, err))
+				return
+			}
+			parts[i] = part{PartNumber: n, ETag: etag}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	complete := map[string]interface{}{"oid": oid, "parts": parts}
+	if err := e.doJSON(ctx, http.MethodPost, upload.Href, "application/vnd.git-lfs+json", complete, nil); err != nil {
+		return fmt.Errorf("huggingface: lfs complete: %w", err)
+	}
+	return nil
+}
+
+// commit creates a commit with a single file operation and returns its URL
+func (e *HuggingFaceExecutor) commit(ctx context.Context, summary string, op map[string]interface{}) (string, error) {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	header := map[string]interface{}{
+		"key":   "header",
+		"value": map[string]string{"summary": summary, "description": ""},
+	}
+	if err := enc.Encode(header); err != nil {
+		return "", err
+	}
+	if err := enc.Encode(op); err != nil {
+		return "", err
+	}
+
+	var out struct {
+		CommitURL string `json:"commitUrl"`
+		CommitOid string `json:"commitOid"`
+	}
+	target := fmt.Sprintf("%s/commit/%s", e.apiPrefix(), url.PathEscape(e.cfg.Revision))
+	if err := e.doRaw(ctx, http.MethodPost, target, "application/x-ndjson", body.Bytes(), &out); err != nil {
+		return "", fmt.Errorf("huggingface: commit: %w", err)
+	}
+	return out.CommitURL, nil
+}
+
+// putPart uploads a body to a presigned URL and returns the ETag
+func (e *HuggingFaceExecutor) putPart(ctx context.Context, target string, headers map[string]string, body io.Reader, size int64) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, body)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("HTTP %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// doJSON sends an authenticated JSON request and decodes the response
+func (e *HuggingFaceExecutor) doJSON(ctx context.Context, method, target, contentType string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return e.doRaw(ctx, method, target, contentType, body, out)
+}
+
+// doRaw sends an authenticated request to the Hub and decodes the JSON
+// response into out (if non-nil)
+func (e *HuggingFaceExecutor) doRaw(ctx context.Context, method, target, contentType string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+e.cfg.Token)
+	req.Header.Set("Content-Type", contentType)
+	if strings.Contains(contentType, "git-lfs") {
+		req.Header.Set("Accept", contentType)
+	}
+
+	resp, err := e.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("HTTP %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,204 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// fakeHub serves the preupload, LFS batch and commit endpoints of the
+// HuggingFace Hub for the repository org/repo
+type fakeHub struct {
+	url   string
+	mode  string // uploadMode preupload returns
+	chunk int    // LFS multipart chunk size, 0 for a basic transfer
+
+	mu       sync.Mutex
+	parts    map[int][]byte
+	lfs      []byte
+	verified bool
+	commits  []map[string]interface{}
+}
+
+func newFakeHub(t *testing.T, mode string, chunk int) (*fakeHub, *HuggingFaceExecutor) {
+	t.Helper()
+	hub := &fakeHub{mode: mode, chunk: chunk, parts: map[int][]byte{}}
+	srv := httptest.NewServer(hub)
+	t.Cleanup(srv.Close)
+	hub.url = srv.URL
+	e, err := NewHuggingFaceExecutor(HuggingFaceConfig{
+		RepoID:     "org/repo",
+		PathPrefix: "data/",
+		Token:      "hf_token",
+		Endpoint:   srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hub, e
+}
+
+func (h *fakeHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	if strings.HasPrefix(r.URL.Path, "/api/") && r.Header.Get("Authorization") != "Bearer hf_token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.URL.Path == "/api/models/org/repo/preupload/main":
+		var in struct {
+			Files []struct {
+				Path string `json:"path"`
+			} `json:"files"`
+		}
+		json.Unmarshal(body, &in)
+		fmt.Fprintf(w, `{"files":[{"path":%q,"uploadMode":%q}]}`, in.Files[0].Path, h.mode)
+	case r.URL.Path == "/org/repo.git/info/lfs/objects/batch":
+		var in struct {
+			Transfers []string `json:"transfers"`
+			Objects   []struct {
+				Size int64 `json:"size"`
+			} `json:"objects"`
+		}
+		json.Unmarshal(body, &in)
+		header := map[string]string{}
+		href := h.url + "/lfs/object"
+		if h.chunk > 0 {
+			header["chunk_size"] = strconv.Itoa(h.chunk)
+			for n := 1; int64(n-1)*int64(h.chunk) < in.Objects[0].Size; n++ {
+				header[fmt.Sprintf("%05d", n)] = fmt.Sprintf("%s/lfs/part/%d", h.url, n)
+			}
+			href = h.url + "/lfs/complete"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"objects": []interface{}{map[string]interface{}{
+			"actions": map[string]interface{}{
+				"upload": map[string]interface{}{"href": href, "header": header},
+				"verify": map[string]interface{}{"href": h.url + "/lfs/verify"},
+			},
+		}}})
+	case r.URL.Path == "/lfs/object":
+		h.lfs = body
+	case strings.HasPrefix(r.URL.Path, "/lfs/part/"):
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/lfs/part/"))
+		h.parts[n] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, n))
+	case r.URL.Path == "/lfs/complete":
+		var in struct {
+			Parts []struct {
+				PartNumber int    `json:"partNumber"`
+				ETag       string `json:"etag"`
+			} `json:"parts"`
+		}
+		json.Unmarshal(body, &in)
+		for i, p := range in.Parts {
+			if p.PartNumber != i+1 || p.ETag != fmt.Sprintf(`"etag-%d"`, i+1) {
+				http.Error(w, "bad part list", http.StatusBadRequest)
+				return
+			}
+			h.lfs = append(h.lfs, h.parts[p.PartNumber]...)
+		}
+	case r.URL.Path == "/lfs/verify":
+		h.verified = true
+	case r.URL.Path == "/api/models/org/repo/commit/main":
+		sc := bufio.NewScanner(strings.NewReader(string(body)))
+		for sc.Scan() {
+			var op map[string]interface{}
+			json.Unmarshal(sc.Bytes(), &op)
+			if op["key"] != "header" {
+				h.commits = append(h.commits, op)
+			}
+		}
+		fmt.Fprintf(w, `{"commitUrl":"%s/org/repo/commit/abc","commitOid":"abc"}`, h.url)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func TestHuggingFacePut(t *testing.T) {
+	content := "0123456789"
+	sum := sha256.Sum256([]byte(content))
+	oid := hex.EncodeToString(sum[:])
+	tests := []struct {
+		name  string
+		mode  string
+		chunk int
+	}{
+		{"regular", "regular", 0},
+		{"lfs", "lfs", 0},
+		{"lfs multipart", "lfs", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, e := newFakeHub(t, tt.mode, tt.chunk)
+			info := routingclient.ContentInfo{Filename: "../../README.md"}
+			res, err := e.Put(context.Background(), info, strings.NewReader(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Provider != "huggingface:"+strings.TrimPrefix(hub.url, "http://") || res.ETag != oid || res.Bytes != int64(len(content)) {
+				t.Errorf("result %+v", res)
+			}
+			if len(hub.commits) != 1 {
+				t.Fatalf("%d files committed, want 1", len(hub.commits))
+			}
+			value := hub.commits[0]["value"].(map[string]interface{})
+			if want := "data/" + oid + "/README.md"; value["path"] != want {
+				t.Errorf("committed to %v, want %s", value["path"], want)
+			}
+
+			if tt.mode == "regular" {
+				data, _ := base64.StdEncoding.DecodeString(value["content"].(string))
+				if hub.commits[0]["key"] != "file" || string(data) != content {
+					t.Errorf("regular commit %v", hub.commits[0])
+				}
+				return
+			}
+			if hub.commits[0]["key"] != "lfsFile" || value["oid"] != oid {
+				t.Errorf("lfs commit %v", hub.commits[0])
+			}
+			if string(hub.lfs) != content || !hub.verified {
+				t.Errorf("lfs received %q, verified %v", hub.lfs, hub.verified)
+			}
+			if tt.chunk > 0 && len(hub.parts) != 3 {
+				t.Errorf("%d parts uploaded, want 3", len(hub.parts))
+			}
+		})
+	}
+}
+
+func TestHuggingFaceRepoPath(t *testing.T) {
+	_, e := newFakeHub(t, "regular", 0)
+	for filename, want := range map[string]string{
+		"model.bin":          "data/oid/model.bin",
+		"../../x":            "data/oid/x",
+		`..\..\windows.ini`:  "data/oid/windows.ini",
+		"":                   "data/oid/content",
+		"..":                 "data/oid/content",
+		"dir/":               "data/oid/dir",
+		"/etc/passwd":        "data/oid/passwd",
+		"weights/part-1.bin": "data/oid/part-1.bin",
+	} {
+		if got := e.repoPath("oid", filename); got != want {
+			t.Errorf("repoPath(%q) = %q, want %q", filename, got, want)
+		}
+	}
+
+	if _, err := NewHuggingFaceExecutor(HuggingFaceConfig{RepoID: "org/repo", Token: "t", PathPrefix: "a/../../b"}); err == nil {
+		t.Error("path prefix leaving the repository accepted")
+	}
+}
@@ -0,0 +1,251 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"example.com/ipfs_kit_py/kubo"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// IPFSConfig configures the IPFS executor
+type IPFSConfig struct {
+	// Node is the Kubo node content is added to
+	Node *kubo.Client
+
+	// Add controls the import (default CIDv1 with raw leaves); content is
+	// always pinned
+	Add *kubo.AddOptions
+
+	// Provide announces each upload's CID on the DHT, recursively, before
+	// Put returns, so other peers can find it at once rather than at the
+	// node's next reprovide
+	Provide bool
+
+	// ProvideTimeout bounds the announcement (default 1 minute)
+	ProvideTimeout time.Duration
+
+	// OnProvide, if set, is called with the outcome of every announcement.
+	// A failed announcement does not fail the upload: the content is
+	// stored, and the node's reprovider announces it later.
+	OnProvide func(cid string, err error)
+
+	// PartSize, if set, writes content larger than it to an MFS file in
+	// parts of this size, checking the file's size after each, and pins
+	// the file once complete, so no single request carries the whole
+	// upload. MFS writes to one file are serialized, so parts are written
+	// one at a time. The CID is the MFS file's, whose DAG may differ from
+	// the one Add would build.
+	PartSize int64
+
+	// Checkpoints, if set, makes uploads resumable: content is written in
+	// parts as for PartSize (default 64 MiB), saving progress after each.
+	// Putting the same content again after a failure skips the parts
+	// already written.
+	Checkpoints *Checkpoints
+}
+
+// defaultIPFSPartSize is the part size of resumable uploads when PartSize
+// is not set
+const defaultIPFSPartSize = 64 * 1024 * 1024
+
+// ipfsUploadDir is the MFS directory resumable uploads are written under
+const ipfsUploadDir = "/.ipfs-kit/uploads"
+
+// IPFSExecutor adds content to a Kubo node and pins it
+type IPFSExecutor struct {
+	cfg IPFSConfig
+}
+
+// NewIPFSExecutor creates an IPFS executor
+func NewIPFSExecutor(cfg IPFSConfig) (*IPFSExecutor, error) {
+	if cfg.Node == nil {
+		return nil, errors.New("ipfs: kubo client is required")
+	}
+	if cfg.Add == nil {
+		cfg.Add = &kubo.AddOptions{CIDVersion: 1, RawLeaves: true}
+	}
+	if cfg.ProvideTimeout == 0 {
+		cfg.ProvideTimeout = time.Minute
+	}
+	if cfg.PartSize < 0 {
+		return nil, fmt.Errorf("ipfs: invalid part size %d", cfg.PartSize)
+	}
+	return &IPFSExecutor{cfg: cfg}, nil
+}
+
+// Class implements Executor
+func (e *IPFSExecutor) Class() string {
+	return IPFSClass
+}
+
+// Quota implements QuotaReporter with the size of the node's block store
+// against its Datastore.StorageMax
+func (e *IPFSExecutor) Quota(ctx context.Context) (*Quota, error) {
+	stat, err := e.cfg.Node.RepoStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ipfs: repo stat: %w", err)
+	}
+	return &Quota{Used: stat.RepoSize, Limit: stat.StorageMax}, nil
+}
+
+// Put implements Executor
+func (e *IPFSExecutor) Put(ctx context.Context, info routingclient.ContentInfo, r io.Reader) (*Result, error) {
+	key := checkpointKey(info, info.Filename)
+	if e.cfg.Checkpoints != nil && key != "" {
+		return e.putParts(ctx, e.cfg.Checkpoints.Load(e.Class(), key), r)
+	}
+	if e.cfg.PartSize > 0 && info.ContentSize > e.cfg.PartSize {
+		// Without a checkpoint the file is named for this upload alone
+		cp := &Checkpoint{Key: fmt.Sprintf("%s@%d", key, time.Now().UnixNano())}
+		return e.putParts(ctx, cp, r)
+	}
+	opts := *e.cfg.Add
+	opts.Pin = true
+	opts.OnlyHash = false
+	cr := &countingReader{r: r}
+	added, err := e.cfg.Node.Add(ctx, cr, &opts)
+	if err != nil {
+		return nil, err
+	}
+	return e.stored(ctx, added.Hash, cr.n), nil
+}
+
+// stored announces a stored CID if configured to and describes where it is
+func (e *IPFSExecutor) stored(ctx context.Context, cid string, n int64) *Result {
+	if e.cfg.Provide {
+		err := e.provide(ctx, cid)
+		if e.cfg.OnProvide != nil {
+			e.cfg.OnProvide(cid, err)
+		}
+	}
+	return &Result{Location: "ipfs://" + cid, CID: cid, Bytes: n}
+}
+
+// putParts writes content to the MFS file of cp part by part, skipping
+// those the checkpoint holds, then pins the file's CID and removes the
+// file. Without Checkpoints, a failed upload's file is removed too.
+func (e *IPFSExecutor) putParts(ctx context.Context, cp *Checkpoint, r io.Reader) (res *Result, err error) {
+	node := e.cfg.Node
+	partSize := e.cfg.PartSize
+	if partSize == 0 {
+		partSize = defaultIPFSPartSize
+	}
+	if cp.Path == "" || cp.PartSize != partSize {
+		cp.Path = path.Join(ipfsUploadDir, hashHex([]byte(cp.Key))[:24])
+		cp.PartSize, cp.Parts, cp.Offset = partSize, nil, 0
+	}
+	if e.cfg.Checkpoints == nil {
+		defer func() {
+			if err != nil {
+				node.FilesRm(context.WithoutCancel(ctx), cp.Path, false)
+			}
+		}()
+	}
+	// The node may have lost the file, e.g. to a repo reset
+	if len(cp.Parts) > 0 {
+		if st, err := node.FilesStat(ctx, cp.Path); err != nil || st.Size != cp.Offset {
+			cp.Parts, cp.Offset = nil, 0
+		}
+	}
+
+	var total int64
+	buf := make([]byte, partSize)
+	for num := 1; ; num++ {
+		n, err := io.ReadFull(r, buf)
+		if n == 0 && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("read content: %w", err)
+		}
+		part, offset := buf[:n], total
+		total += int64(n)
+		sum := hashHex(part)
+		if num <= len(cp.Parts) {
+			if _, ok := cp.part(num, sum); ok {
+				continue
+			}
+			// Earlier parts are already consumed, so content that
+			// changed since the checkpoint has to be uploaded afresh
+			node.FilesRm(context.WithoutCancel(ctx), cp.Path, false)
+			e.cfg.Checkpoints.Remove(cp)
+			return nil, fmt.Errorf("ipfs: content differs from part %d of the checkpointed upload; put it again to start over", num)
+		}
+
+		err = node.FilesWrite(ctx, cp.Path, bytes.NewReader(part), &kubo.FilesWriteOptions{
+			Create:     true,
+			Parents:    true,
+			Truncate:   num == 1,
+			Offset:     offset,
+			CIDVersion: e.cfg.Add.CIDVersion,
+			RawLeaves:  e.cfg.Add.RawLeaves,
+			HashFunc:   e.cfg.Add.HashFunc,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ipfs: write part %d: %w", num, err)
+		}
+		st, err := node.FilesStat(ctx, cp.Path)
+		if err != nil {
+			return nil, fmt.Errorf("ipfs: write part %d: %w", num, err)
+		}
+		if st.Size != total {
+			return nil, fmt.Errorf("ipfs: write part %d: %s holds %d bytes, want %d", num, cp.Path, st.Size, total)
+		}
+		cp.addPart(CheckpointPart{Number: num, Size: int64(n), SHA256: sum})
+		cp.CID = st.Hash
+		if err := e.cfg.Checkpoints.Save(cp); err != nil {
+			return nil, fmt.Errorf("ipfs: %w", err)
+		}
+	}
+	if total == 0 {
+		return e.Put(ctx, routingclient.ContentInfo{}, bytes.NewReader(nil))
+	}
+
+	st, err := node.FilesStat(ctx, cp.Path)
+	if err != nil {
+		return nil, fmt.Errorf("ipfs: %w", err)
+	}
+	if st.Size != total {
+		return nil, fmt.Errorf("ipfs: %s holds %d bytes, want %d", cp.Path, st.Size, total)
+	}
+	if err := node.PinAdd(ctx, st.Hash, true); err != nil {
+		return nil, err
+	}
+	// The pin keeps the content once the file is gone
+	node.FilesRm(ctx, cp.Path, false)
+	e.cfg.Checkpoints.Remove(cp)
+	return e.stored(ctx, st.Hash, total), nil
+}
+
+// Delete implements Deleter, unpinning rec's CID; its blocks are removed
+// at the node's next garbage collection
+func (e *IPFSExecutor) Delete(ctx context.Context, rec PlacementRecord) error {
+	if rec.CID == "" {
+		return errors.New("ipfs: placement has no CID")
+	}
+	return e.cfg.Node.PinRm(ctx, rec.CID)
+}
+
+func (e *IPFSExecutor) provide(ctx context.Context, cid string) error {
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.ProvideTimeout)
+	defer cancel()
+	return e.cfg.Node.Provide(ctx, cid, true)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,189 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// MigrationPolicy decides which stored placements are worth moving
+type MigrationPolicy struct {
+	// MinSuccessRate is the success rate, from 0 to 1, below which a
+	// backend counts as degraded and its content is moved off it (default
+	// 0.9)
+	MinSuccessRate float64
+	// MinSavings is the fraction of a placement's projected cost another
+	// backend must save for the content to be moved there (default 0.2)
+	MinSavings float64
+	// Window is how far back the service's success rates are taken from
+	// (default 24 hours)
+	Window time.Duration
+	// Retention and Retrievals describe how the content will be kept from
+	// now on, for the cost estimates (see routingclient.CostQuery)
+	Retention  time.Duration
+	Retrievals float64
+}
+
+// Migration reasons
+const (
+	ReasonDegraded  = "degraded"
+	ReasonExpensive = "expensive"
+)
+
+// MigratedFromKey is the ContentInfo metadata key naming the backend a
+// migrated copy was moved from
+const MigratedFromKey = "migrated_from"
+
+// CIDMetadataKey is the ContentInfo metadata key carrying the CID of
+// content already stored, for executors that name objects by content
+const CIDMetadataKey = "cid"
+
+// MigrationStep moves one placement to a better backend
+type MigrationStep struct {
+	PlacementRecord
+	Reason string `json:"reason"`
+	Target string `json:"target"`
+	// Cost and TargetCost are the projected costs of keeping the content
+	// where it is and on Target
+	Cost       float64 `json:"cost"`
+	TargetCost float64 `json:"target_cost"`
+}
+
+// MigrationPlan is the outcome of PlanMigration
+type MigrationPlan struct {
+	Steps []MigrationStep `json:"steps"`
+	// Stranded are placements on degraded backends with nowhere permitted
+	// to move them
+	Stranded []PlacementRecord `json:"stranded,omitempty"`
+	Checked  int               `json:"checked"`
+	Currency string            `json:"currency,omitempty"`
+	// Savings is the total projected saving of the steps
+	Savings float64 `json:"savings"`
+}
+
+// PlanMigration checks each placement against the service's success rates
+// and cost estimates, planning to move content off degraded backends and
+// onto ones that are cheaper by at least MinSavings. Targets are limited to
+// the backends registry has executors for and, if placement rules are set,
+// permits for the content's bucket.
+func PlanMigration(ctx context.Context, client *routingclient.Client, registry *Registry, records []PlacementRecord, policy MigrationPolicy) (*MigrationPlan, error) {
+	if policy.MinSuccessRate == 0 {
+		policy.MinSuccessRate = 0.9
+	}
+	if policy.MinSavings == 0 {
+		policy.MinSavings = 0.2
+	}
+	if policy.Window == 0 {
+		policy.Window = 24 * time.Hour
+	}
+	insights, err := client.GetInsights(ctx, policy.Window)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: insights: %w", err)
+	}
+	rates := routingclient.SuccessRates(insights)
+	degraded := func(id string) bool {
+		rate, ok := rates[id]
+		return ok && rate < policy.MinSuccessRate
+	}
+	registry.mu.RLock()
+	placement := registry.placement
+	registry.mu.RUnlock()
+
+	plan := &MigrationPlan{Checked: len(records)}
+	for _, rec := range records {
+		var candidates []string
+		for _, id := range registry.backendIDs() {
+			exec, _ := registry.Lookup(id)
+			if id != rec.BackendID && !degraded(id) && (placement == nil || placement.permitsExecutor(rec.Bucket, id, exec)) {
+				candidates = append(candidates, id)
+			}
+		}
+		resp, err := client.EstimateCost(ctx, routingclient.ContentInfo{ContentSize: rec.Bytes}, routingclient.CostQuery{
+			Retention:  policy.Retention,
+			Retrievals: policy.Retrievals,
+			Backends:   append([]string{rec.BackendID}, candidates...),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("migrate: estimate cost of %s on %s: %w", rec.Location, rec.BackendID, err)
+		}
+		plan.Currency = resp.Currency
+
+		step := MigrationStep{PlacementRecord: rec}
+		for _, e := range resp.Estimates {
+			if e.BackendId == rec.BackendID {
+				step.Cost = e.TotalCost
+			} else if step.Target == "" {
+				// Estimates are cheapest first
+				step.Target, step.TargetCost = e.BackendId, e.TotalCost
+			}
+		}
+		switch {
+		case degraded(rec.BackendID) && step.Target == "":
+			plan.Stranded = append(plan.Stranded, rec)
+			continue
+		case degraded(rec.BackendID):
+			step.Reason = ReasonDegraded
+		case step.Target != "" && step.Cost-step.TargetCost >= policy.MinSavings*step.Cost && step.Cost > 0:
+			step.Reason = ReasonExpensive
+		default:
+			continue
+		}
+		plan.Steps = append(plan.Steps, step)
+		plan.Savings += step.Cost - step.TargetCost
+	}
+	return plan, nil
+}
+
+// Migrate copies the content of a planned step, read from r, to its target
+// and records the outcome. The original placement is left in place, to be
+// removed once the new one has been checked. If admission control is
+// enabled the target is checked first, as for Run.
+func Migrate(ctx context.Context, client *routingclient.Client, registry *Registry, step MigrationStep, r io.Reader) (*Result, error) {
+	exec, ok := registry.Lookup(step.Target)
+	if !ok {
+		return nil, fmt.Errorf("no executor registered for backend %q", step.Target)
+	}
+	info := routingclient.ContentInfo{
+		ContentSize: step.Bytes,
+		Filename:    path.Base(step.Location),
+		Metadata:    map[string]string{MigratedFromKey: step.BackendID},
+		TTL:         remaining(step.ExpiresAt, time.Now()),
+	}
+	if step.Bucket != "" {
+		info.Metadata["bucket"] = step.Bucket
+	}
+	if step.CID != "" {
+		info.Metadata[CIDMetadataKey] = step.CID
+	}
+	registry.mu.RLock()
+	admission, placement := registry.admission, registry.placement
+	registry.mu.RUnlock()
+	if admission != nil {
+		// The content already counts against its bucket's quota, so
+		// only the target and its spool are checked
+		checked := info
+		checked.Metadata = map[string]string{MigratedFromKey: step.BackendID}
+		if err := admission.Check(ctx, registry, checked, &pb.SelectBackendResponse{BackendId: step.Target}); err != nil {
+			return nil, err
+		}
+	}
+	if placement != nil {
+		ctx = withProviderFilter(ctx, func(provider string) bool {
+			return placement.Permits(step.Bucket, provider)
+		})
+	}
+	res, err := Execute(ctx, client, exec, step.Target, info, r)
+	if res != nil {
+		// The content expires when it would have where it was
+		res.ExpiresAt = step.ExpiresAt
+	}
+	if err == nil {
+		logPlacement(ctx, info, res)
+	}
+	return res, err
+}
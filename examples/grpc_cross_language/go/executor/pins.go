@@ -0,0 +1,297 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"example.com/ipfs_kit_py/ipfscluster"
+	"example.com/ipfs_kit_py/kubo"
+	"example.com/ipfs_kit_py/pinning"
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// IPFSClass is the backend class of IPFS nodes, e.g. "ipfs" or "ipfs-eu"
+const IPFSClass = "ipfs"
+
+// ClusterClass is the backend class of IPFS Cluster deployments, e.g.
+// "cluster" or "cluster-eu"
+const ClusterClass = "cluster"
+
+// pollInterval is how often a remote pinning service is polled for the
+// state of a pin
+const pollInterval = 2 * time.Second
+
+// Pinner manages pins on IPFS- and cluster-class backends and remote
+// pinning services, reporting each pin and unpin to the routing service as
+// an outcome so pin reliability and latency feed into backend selection.
+// Cluster pins also report the peers they were allocated to as the
+// outcome's placement.
+type Pinner struct {
+	client      *routingclient.Client
+	nodes       map[string]*kubo.Client
+	clusters    map[string]*ipfscluster.Client
+	services    map[string]*pinning.Client
+	replication ipfscluster.PinOptions
+}
+
+// NewPinner returns a Pinner for the given IPFS-class backends, keyed by
+// backend ID. client may be nil if Add is not used; outcomes are then not
+// recorded.
+func NewPinner(client *routingclient.Client, nodes map[string]*kubo.Client) *Pinner {
+	return &Pinner{client: client, nodes: nodes}
+}
+
+// WithClusters adds cluster-class backends, keyed by backend ID, pinned
+// with the given replication factor and name
+func (p *Pinner) WithClusters(clusters map[string]*ipfscluster.Client, replication ipfscluster.PinOptions) *Pinner {
+	p.clusters = clusters
+	p.replication = replication
+	return p
+}
+
+// WithServices adds remote pinning services as backends, keyed by backend
+// ID. Services are named after their provider, e.g. "pinata", so any
+// backend ID is accepted whatever its class.
+func (p *Pinner) WithServices(services map[string]*pinning.Client) *Pinner {
+	p.services = services
+	return p
+}
+
+// Backends returns the IDs of the backends the Pinner manages, sorted
+func (p *Pinner) Backends() []string {
+	ids := make([]string, 0, len(p.nodes)+len(p.clusters)+len(p.services))
+	for id := range p.nodes {
+		ids = append(ids, id)
+	}
+	for id := range p.clusters {
+		ids = append(ids, id)
+	}
+	for id := range p.services {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// manages reports whether backendID is one of the Pinner's backends
+func (p *Pinner) manages(backendID string) bool {
+	if p == nil {
+		return false
+	}
+	_, node := p.nodes[backendID]
+	_, cluster := p.clusters[backendID]
+	_, service := p.services[backendID]
+	return node || cluster || service
+}
+
+// nodeFor returns the IPFS node of backendID; p may be nil
+func (p *Pinner) nodeFor(backendID string) (*kubo.Client, bool) {
+	if p == nil {
+		return nil, false
+	}
+	node, ok := p.nodes[backendID]
+	return node, ok
+}
+
+// Add asks the router for a backend for the content and pins cid on the
+// first backend of the decision that the Pinner manages, as AddTo. It
+// returns the backend used and the pin's placement.
+func (p *Pinner) Add(ctx context.Context, info routingclient.ContentInfo, strategy, cid string) (string, []string, error) {
+	decision, err := p.client.SelectBackend(ctx, info, strategy)
+	if err != nil {
+		return "", nil, fmt.Errorf("select backend: %w", err)
+	}
+	backendID, err := p.choose(decision)
+	if err != nil {
+		return "", nil, err
+	}
+	placement, err := p.AddTo(ctx, info, backendID, cid)
+	return backendID, placement, err
+}
+
+// AddTo pins cid on backendID, bypassing backend selection. For a cluster
+// it returns the peers the pin was allocated to. A remote pinning service
+// is polled until the pin completes, so the recorded outcome covers the
+// whole pin.
+func (p *Pinner) AddTo(ctx context.Context, info routingclient.ContentInfo, backendID, cid string) ([]string, error) {
+	if cluster, ok := p.clusters[backendID]; ok {
+		var allocations []string
+		err := p.record(ctx, info, backendID, func() ([]string, error) {
+			pin, err := cluster.Pin(ctx, cid, p.replication)
+			if err != nil {
+				return nil, err
+			}
+			allocations = pin.Allocations
+			return allocations, nil
+		})
+		return allocations, err
+	}
+	if service, ok := p.services[backendID]; ok {
+		return nil, p.record(ctx, info, backendID, func() ([]string, error) {
+			status, err := service.Add(ctx, pinning.Pin{CID: cid, Name: p.replication.Name})
+			if err == nil && !status.Done() {
+				status, err = service.Wait(ctx, status.RequestID, pollInterval)
+			}
+			if err == nil && status.Status == pinning.StatusFailed {
+				err = fmt.Errorf("pin of %s failed", cid)
+			}
+			return nil, err
+		})
+	}
+	return nil, p.do(ctx, info, backendID, func(node *kubo.Client) error {
+		return node.PinAdd(ctx, cid, true)
+	})
+}
+
+// Remove unpins cid from backendID
+func (p *Pinner) Remove(ctx context.Context, info routingclient.ContentInfo, backendID, cid string) error {
+	if cluster, ok := p.clusters[backendID]; ok {
+		return p.record(ctx, info, backendID, func() ([]string, error) {
+			return nil, cluster.Unpin(ctx, cid)
+		})
+	}
+	if service, ok := p.services[backendID]; ok {
+		return p.record(ctx, info, backendID, func() ([]string, error) {
+			return nil, unpinService(ctx, service, cid)
+		})
+	}
+	return p.do(ctx, info, backendID, func(node *kubo.Client) error {
+		return node.PinRm(ctx, cid)
+	})
+}
+
+// List returns the pins on backendID and their types; with cids, only
+// those that are pinned
+func (p *Pinner) List(ctx context.Context, backendID string, cids ...string) (map[string]string, error) {
+	if cluster, ok := p.clusters[backendID]; ok {
+		return listCluster(ctx, cluster, cids)
+	}
+	if service, ok := p.services[backendID]; ok {
+		statuses, err := service.List(ctx, pinning.ListOptions{CIDs: cids})
+		if err != nil {
+			return nil, err
+		}
+		pins := make(map[string]string, len(statuses))
+		for _, s := range statuses {
+			pins[s.Pin.CID] = "recursive"
+		}
+		return pins, nil
+	}
+	node, ok := p.nodes[backendID]
+	if !ok {
+		return nil, fmt.Errorf("no IPFS node configured for backend %q", backendID)
+	}
+	return node.PinLs(ctx, "", cids...)
+}
+
+// listCluster lists the cluster's pinset, or the given cids that are in it
+func listCluster(ctx context.Context, cluster *ipfscluster.Client, cids []string) (map[string]string, error) {
+	pins := make(map[string]string)
+	if len(cids) == 0 {
+		all, err := cluster.Allocations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, pin := range all {
+			pins[string(pin.CID)] = pin.PinMode()
+		}
+		return pins, nil
+	}
+	for _, cid := range cids {
+		pin, err := cluster.Allocation(ctx, cid)
+		if ipfscluster.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		pins[cid] = pin.PinMode()
+	}
+	return pins, nil
+}
+
+// unpinService removes every pin request for cid, whatever its state
+func unpinService(ctx context.Context, service *pinning.Client, cid string) error {
+	statuses, err := service.List(ctx, pinning.ListOptions{
+		CIDs:     []string{cid},
+		Statuses: []string{pinning.StatusQueued, pinning.StatusPinning, pinning.StatusPinned, pinning.StatusFailed},
+	})
+	if err != nil {
+		return err
+	}
+	if len(statuses) == 0 {
+		return fmt.Errorf("%s is not pinned", cid)
+	}
+	for _, s := range statuses {
+		if err := service.Remove(ctx, s.RequestID); err != nil && !pinning.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// choose picks the first managed backend of a decision: an IPFS- or
+// cluster-class backend, or a pinning service
+func (p *Pinner) choose(decision *pb.SelectBackendResponse) (string, error) {
+	candidates := []string{decision.BackendId}
+	for _, alt := range decision.Alternatives {
+		candidates = append(candidates, alt.BackendId)
+	}
+	for _, id := range candidates {
+		if _, ok := p.nodes[id]; ok && BackendClass(id) == IPFSClass {
+			return id, nil
+		}
+		if _, ok := p.clusters[id]; ok && BackendClass(id) == ClusterClass {
+			return id, nil
+		}
+		if _, ok := p.services[id]; ok {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("no configured pinning backend among %v", candidates)
+}
+
+// do runs a pin operation on the IPFS node backendID and records its
+// outcome
+func (p *Pinner) do(ctx context.Context, info routingclient.ContentInfo, backendID string, op func(*kubo.Client) error) error {
+	node, ok := p.nodes[backendID]
+	if !ok {
+		return fmt.Errorf("no IPFS node configured for backend %q", backendID)
+	}
+	return p.record(ctx, info, backendID, func() ([]string, error) {
+		return nil, op(node)
+	})
+}
+
+// record runs a pin operation and records its outcome, with the placement
+// it returns, even if it fails
+func (p *Pinner) record(ctx context.Context, info routingclient.ContentInfo, backendID string, op func() ([]string, error)) error {
+	start := time.Now()
+	placement, opErr := op()
+	if p.client == nil {
+		if opErr != nil {
+			return fmt.Errorf("%s: %w", backendID, opErr)
+		}
+		return nil
+	}
+	outcome := routingclient.Outcome{
+		BackendID: backendID,
+		Success:   opErr == nil,
+		Duration:  time.Since(start),
+		Err:       opErr,
+		Placement: placement,
+	}
+	if _, err := p.client.RecordOutcome(ctx, info, outcome); err != nil {
+		if opErr != nil {
+			return fmt.Errorf("%s: %w (recording outcome also failed: %v)", backendID, opErr, err)
+		}
+		return fmt.Errorf("record outcome: %w", err)
+	}
+	if opErr != nil {
+		return fmt.Errorf("%s: %w", backendID, opErr)
+	}
+	return nil
+}
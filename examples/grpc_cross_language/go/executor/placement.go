@@ -0,0 +1,236 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// PlacementRule restricts where content of matching buckets may be stored.
+//
+// Patterns are matched with path.Match against backend IDs ("s3-archive")
+// and providers, which name the concrete place content lands as
+// "<class>:<name>", e.g. "s3:eu-west-1" or "filecoin:f01234". Deny patterns
+// win over allow patterns; when Allow is non-empty, only matching targets
+// are permitted.
+type PlacementRule struct {
+	// Bucket is a pattern for the content's "bucket" metadata ("*" for all)
+	Bucket string   `json:"bucket"`
+	Allow  []string `json:"allow,omitempty"`
+	Deny   []string `json:"deny,omitempty"`
+}
+
+// Placement enforces placement rules on routing decisions and executor
+// choices
+type Placement struct {
+	Rules []PlacementRule `json:"rules"`
+}
+
+// PlacementError is returned when no candidate backend satisfies the
+// placement rules for a bucket
+type PlacementError struct {
+	Bucket     string   `json:"bucket"`
+	Candidates []string `json:"candidates"`
+}
+
+func (e *PlacementError) Error() string {
+	return fmt.Sprintf("placement: no permitted backend for bucket %q among %v", e.Bucket, e.Candidates)
+}
+
+// Provided is implemented by executors that store to named providers,
+// such as an S3 region or a set of Filecoin miners
+type Provided interface {
+	Providers() []string
+}
+
+func (p *Placement) rulesFor(bucket string) []PlacementRule {
+	var rules []PlacementRule
+	for _, r := range p.Rules {
+		pattern := r.Bucket
+		if pattern == "" {
+			pattern = "*"
+		}
+		if ok, _ := path.Match(pattern, bucket); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// matchAny reports whether target matches one of patterns. A backend-level
+// target (no ":") also matches provider patterns of its class, so allowing
+// "filecoin:f01234" permits the filecoin backend as a whole.
+func matchAny(patterns []string, target string, backendLevel bool) bool {
+	for _, pat := range patterns {
+		if ok, _ := path.Match(pat, target); ok {
+			return true
+		}
+		if backendLevel {
+			if class, _, found := strings.Cut(pat, ":"); found {
+				if ok, _ := path.Match(class, backendClass(target)); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// backendClass returns the class prefix of a backend ID, as Lookup does
+func backendClass(backendID string) string {
+	if i := strings.IndexAny(backendID, "-_:"); i > 0 {
+		return backendID[:i]
+	}
+	return backendID
+}
+
+// Permits reports whether content in bucket may be stored at target, which
+// is a backend ID or a "<class>:<name>" provider. Patterns naming a whole
+// class ("s3") apply to every backend ID and provider of that class.
+func (p *Placement) Permits(bucket, target string) bool {
+	backendLevel := !strings.Contains(target, ":")
+	class := backendClass(target)
+	for _, r := range p.rulesFor(bucket) {
+		// Deny patterns naming a provider do not rule out the backend as
+		// a whole: denying one miner leaves the others usable.
+		if matchAny(r.Deny, target, false) || matchAny(r.Deny, class, false) {
+			return false
+		}
+		if len(r.Allow) > 0 && !matchAny(r.Allow, target, backendLevel) && !matchAny(r.Allow, class, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// permitsExecutor checks a backend ID and, for executors with named
+// providers, that at least one of them is permitted
+func (p *Placement) permitsExecutor(bucket, backendID string, exec Executor) bool {
+	if !p.Permits(bucket, backendID) {
+		return false
+	}
+	provided, ok := exec.(Provided)
+	if !ok {
+		return true
+	}
+	for _, prov := range provided.Providers() {
+		if p.Permits(bucket, prov) {
+			return true
+		}
+	}
+	return false
+}
+
+// Choose returns the first backend of the routing decision, primary then
+// alternatives, that is permitted for bucket
+func (p *Placement) Choose(registry *Registry, bucket string, decision *pb.SelectBackendResponse) (string, error) {
+	candidates := []string{decision.BackendId}
+	for _, alt := range decision.Alternatives {
+		candidates = append(candidates, alt.BackendId)
+	}
+	for _, id := range candidates {
+		exec, ok := registry.Lookup(id)
+		if ok && p.permitsExecutor(bucket, id, exec) {
+			return id, nil
+		}
+	}
+	return "", &PlacementError{Bucket: bucket, Candidates: candidates}
+}
+
+type providerFilterKey struct{}
+
+// withProviderFilter attaches a provider check that executors consult when
+// they choose between providers (e.g. Filecoin miners)
+func withProviderFilter(ctx context.Context, permit func(provider string) bool) context.Context {
+	return context.WithValue(ctx, providerFilterKey{}, permit)
+}
+
+// providerPermitted reports whether the provider may be used in ctx
+func providerPermitted(ctx context.Context, provider string) bool {
+	permit, ok := ctx.Value(providerFilterKey{}).(func(string) bool)
+	return !ok || permit(provider)
+}
+
+// PlacementRecord describes where a piece of content was stored, as kept by
+// callers for compliance reporting
+type PlacementRecord struct {
+	Bucket    string `json:"bucket"`
+	BackendID string `json:"backend_id"`
+	Provider  string `json:"provider,omitempty"`
+	Location  string `json:"location"`
+	CID       string `json:"cid,omitempty"`
+	Bytes     int64  `json:"bytes,omitempty"`
+	// ExpiresAt is when the content may be removed; nil keeps it
+	// indefinitely
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// expiry is when content stored at start with ttl expires; nil if ttl is
+// not positive
+func expiry(start time.Time, ttl time.Duration) *time.Time {
+	if ttl <= 0 {
+		return nil
+	}
+	t := start.Add(ttl)
+	return &t
+}
+
+// remaining is the TTL that keeps content until expiresAt; 0 if it is nil
+// or already past
+func remaining(expiresAt *time.Time, now time.Time) time.Duration {
+	if expiresAt == nil || !expiresAt.After(now) {
+		return 0
+	}
+	return expiresAt.Sub(now)
+}
+
+// RecordFor builds the placement record of an upload result
+func RecordFor(bucket string, r *Result) PlacementRecord {
+	return PlacementRecord{
+		Bucket:    bucket,
+		BackendID: r.BackendID,
+		Provider:  r.Provider,
+		Location:  r.Location,
+		CID:       r.CID,
+		Bytes:     r.Bytes,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
+
+// PlacementViolation is a stored placement that breaks the current rules
+type PlacementViolation struct {
+	PlacementRecord
+	Target string `json:"target"`
+}
+
+// ComplianceReport is the result of auditing stored placements
+type ComplianceReport struct {
+	Checked    int                  `json:"checked"`
+	Violations []PlacementViolation `json:"violations"`
+}
+
+// Compliant reports whether no violations were found
+func (r *ComplianceReport) Compliant() bool {
+	return len(r.Violations) == 0
+}
+
+// Audit checks existing placements against the rules, e.g. after a policy
+// change, so content stored somewhere now disallowed can be migrated
+func (p *Placement) Audit(records []PlacementRecord) *ComplianceReport {
+	report := &ComplianceReport{Checked: len(records)}
+	for _, rec := range records {
+		target := rec.BackendID
+		if !p.Permits(rec.Bucket, target) {
+			report.Violations = append(report.Violations, PlacementViolation{rec, target})
+			continue
+		}
+		if rec.Provider != "" && !p.Permits(rec.Bucket, rec.Provider) {
+			report.Violations = append(report.Violations, PlacementViolation{rec, rec.Provider})
+		}
+	}
+	return report
+}
@@ -0,0 +1,265 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// Replication stores each upload on several backends, the top-scored ones
+// of its routing decision, rather than only the best. The object's Result
+// then describes where its replication manifest was stored.
+type Replication struct {
+	// Copies is how many backends receive the content (default 2)
+	Copies int
+	// MinCopies is how many replicas must be stored for the upload to
+	// succeed (default Copies)
+	MinCopies int
+	// Dir holds the content while the replicas are uploaded (default the
+	// system temporary directory)
+	Dir string
+}
+
+// ReplicationManifestVersion is the replication manifest format version
+// this package writes
+const ReplicationManifestVersion = 1
+
+// ReplicationManifestContentType is the MIME type replication manifests
+// are stored under
+const ReplicationManifestContentType = "application/vnd.ipfs-kit.replicas+json"
+
+// ReplicaMetadataKey is the ContentInfo metadata key naming the object a
+// replication manifest describes, by its SHA-256
+const ReplicaMetadataKey = "replica_of"
+
+// ReplicationManifest describes a replicated object and every replica
+// attempted, including those that failed
+type ReplicationManifest struct {
+	Version     int       `json:"version"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	ContentType string    `json:"content_type,omitempty"`
+	Filename    string    `json:"filename,omitempty"`
+	Bucket      string    `json:"bucket,omitempty"`
+	Copies      int       `json:"copies"`
+	Replicas    []Replica `json:"replicas"`
+	// ExpiresAt is when the replicas may be removed; nil keeps them
+	// indefinitely
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Replica is one backend's copy of a replicated object
+type Replica struct {
+	BackendID string  `json:"backend_id"`
+	Score     float64 `json:"score"`
+	Location  string  `json:"location,omitempty"`
+	CID       string  `json:"cid,omitempty"`
+	Provider  string  `json:"provider,omitempty"`
+	// Error is why the replica could not be stored; empty if it was
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Stored returns the replicas that were stored successfully
+func (m *ReplicationManifest) Stored() []Replica {
+	var stored []Replica
+	for _, r := range m.Replicas {
+		if r.Error == "" {
+			stored = append(stored, r)
+		}
+	}
+	return stored
+}
+
+// Records returns the placement records of the stored replicas
+func (m *ReplicationManifest) Records() []PlacementRecord {
+	var records []PlacementRecord
+	for _, r := range m.Stored() {
+		records = append(records, PlacementRecord{
+			Bucket:    m.Bucket,
+			BackendID: r.BackendID,
+			Provider:  r.Provider,
+			Location:  r.Location,
+			CID:       r.CID,
+			Bytes:     m.Size,
+			ExpiresAt: m.ExpiresAt,
+		})
+	}
+	return records
+}
+
+// SetReplication enables replication for uploads started with Run. Objects
+// large enough to be striped are striped instead.
+func (r *Registry) SetReplication(replication *Replication) {
+	rp := *replication
+	if rp.Copies == 0 {
+		rp.Copies = 2
+	}
+	if rp.MinCopies == 0 || rp.MinCopies > rp.Copies {
+		rp.MinCopies = rp.Copies
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.replication = &rp
+}
+
+// replicate uploads content to the top Copies permitted backends and then
+// its manifest, which the returned Result describes
+func replicate(ctx context.Context, client *routingclient.Client, registry *Registry, rp *Replication, info routingclient.ContentInfo, strategy string, r io.Reader) (*Result, error) {
+	registry.mu.RLock()
+	admission, placement, classes := registry.admission, registry.placement, registry.classes
+	registry.mu.RUnlock()
+	bucket := info.Metadata["bucket"]
+	class := ClassOf(info)
+
+	// eligible reports whether a backend may hold a replica
+	eligible := func(id string) bool {
+		exec, ok := registry.Lookup(id)
+		if !ok {
+			return false
+		}
+		if classes != nil && class != "" && !classes.Serves(class, id) {
+			return false
+		}
+		return placement == nil || placement.permitsExecutor(bucket, id, exec)
+	}
+
+	resp, err := client.SelectBackend(ctx, info, strategy)
+	if err != nil {
+		return nil, fmt.Errorf("select backend: %w", err)
+	}
+	var targets []Replica
+	chosen := make(map[string]bool)
+	add := func(id string, score float64) {
+		if len(targets) < rp.Copies && !chosen[id] && eligible(id) {
+			chosen[id] = true
+			targets = append(targets, Replica{BackendID: id, Score: score})
+		}
+	}
+	add(resp.BackendId, resp.Score)
+	for _, alt := range resp.Alternatives {
+		add(alt.BackendId, alt.Score)
+	}
+
+	// The router may list fewer alternatives than there are copies to
+	// place, so ask again among the backends not yet chosen
+	for len(targets) < rp.Copies {
+		var open []string
+		for _, id := range registry.backendIDs() {
+			if !chosen[id] && eligible(id) {
+				open = append(open, id)
+			}
+		}
+		if len(open) == 0 {
+			break
+		}
+		more := info
+		more.Backends = open
+		resp, err := client.SelectBackend(ctx, more, strategy)
+		if err != nil {
+			return nil, fmt.Errorf("replicate: select backend for replica %d: %w", len(targets), err)
+		}
+		if chosen[resp.BackendId] || !eligible(resp.BackendId) {
+			break
+		}
+		add(resp.BackendId, resp.Score)
+	}
+	if len(targets) < rp.MinCopies {
+		return nil, fmt.Errorf("replicate: %d replicas needed, only %d backends are registered and permitted", rp.MinCopies, len(targets))
+	}
+
+	if admission != nil {
+		for _, t := range targets {
+			if err := admission.Check(ctx, registry, info, &pb.SelectBackendResponse{BackendId: t.BackendID, Score: t.Score}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if placement != nil {
+		ctx = withProviderFilter(ctx, func(provider string) bool {
+			return placement.Permits(bucket, provider)
+		})
+	}
+
+	f, err := os.CreateTemp(rp.Dir, "replica-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	sum := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, sum), r)
+	if err != nil {
+		return nil, fmt.Errorf("replicate: spool: %w", err)
+	}
+
+	m := &ReplicationManifest{
+		Version:     ReplicationManifestVersion,
+		Size:        size,
+		SHA256:      hex.EncodeToString(sum.Sum(nil)),
+		ContentType: info.ContentType,
+		Filename:    info.Filename,
+		Bucket:      bucket,
+		Copies:      rp.Copies,
+		Replicas:    targets,
+		ExpiresAt:   expiry(time.Now(), info.TTL),
+	}
+
+	// Every replica reads its own section of the spooled file, and each
+	// outcome is recorded against its backend by Execute
+	var wg sync.WaitGroup
+	errs := make([]error, len(targets))
+	for i := range targets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rep := &m.Replicas[i]
+			exec, _ := registry.Lookup(rep.BackendID)
+			start := time.Now()
+			res, err := Execute(ctx, client, exec, rep.BackendID, info, io.NewSectionReader(f, 0, size))
+			rep.DurationMs = time.Since(start).Milliseconds()
+			if err != nil && res == nil {
+				rep.Error = err.Error()
+				errs[i] = fmt.Errorf("%s: %w", rep.BackendID, err)
+				return
+			}
+			rep.Location, rep.CID, rep.Provider = res.Location, res.CID, res.Provider
+		}(i)
+	}
+	wg.Wait()
+	if stored := len(m.Stored()); stored < rp.MinCopies {
+		return nil, fmt.Errorf("replicate: %d of %d replicas stored, %d needed: %w", stored, len(targets), rp.MinCopies, errors.Join(errs...))
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	manifestInfo := routingclient.ContentInfo{
+		ContentType: ReplicationManifestContentType,
+		ContentSize: int64(len(data)),
+		Filename:    info.Filename + ".replicas.json",
+		Metadata:    map[string]string{ReplicaMetadataKey: m.SHA256},
+		TTL:         info.TTL,
+	}
+	if bucket != "" {
+		manifestInfo.Metadata["bucket"] = bucket
+	}
+	res, err := run(ctx, client, registry, manifestInfo, strategy, bytes.NewReader(data), false)
+	if err != nil {
+		return nil, fmt.Errorf("replicate: store manifest: %w", err)
+	}
+	res.Replication = m
+	return res, nil
+}
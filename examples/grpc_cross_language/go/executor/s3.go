@@ -0,0 +1,654 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// S3Config configures the S3 executor. Empty credential and region fields
+// fall back to the standard AWS_* environment variables.
+type S3Config struct {
+	Bucket string
+	Prefix string
+	Region string
+
+	// Endpoint overrides the AWS endpoint for S3-compatible services
+	// such as MinIO, Ceph or Wasabi, e.g. "http://localhost:9000"
+	Endpoint string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// PartSize is the multipart chunk size in bytes (default 8 MiB,
+	// minimum 5 MiB as required by S3). It is raised for content whose
+	// ContentSize would otherwise need more than S3's 10,000 parts, and
+	// for content of unknown size doubled every 1,000 parts.
+	PartSize int64
+
+	// Concurrency is the number of parts uploaded in parallel (default 4)
+	Concurrency int
+
+	// Checkpoints, if set, saves the parts of multipart uploads as they
+	// complete. A failed upload is then left open rather than aborted, and
+	// putting the same content again uploads only the parts missing.
+	Checkpoints *Checkpoints
+
+	// QuotaBytes, if set, caps the bytes stored under Prefix. S3 has no
+	// quota of its own, so Quota lists the objects under Prefix to
+	// measure their size against it.
+	QuotaBytes int64
+
+	// QuotaTTL is how long Quota reuses a listing (default 5 minutes).
+	// Uploads and deletes made through the executor meanwhile are
+	// counted into it.
+	QuotaTTL time.Duration
+
+	HTTPClient *http.Client
+}
+
+// minS3PartSize is the smallest part size S3 accepts for all but the last part
+const minS3PartSize = 5 * 1024 * 1024
+
+// maxS3Parts is the most parts a multipart upload may have
+const maxS3Parts = 10000
+
+// s3PartGrowth is how many parts of content of unknown size are uploaded
+// before the part size doubles, which fits 1,023 times the first part
+// size, 8 TiB at the default, in maxS3Parts
+const s3PartGrowth = 1000
+
+// S3Executor uploads content to an S3 bucket, switching to multipart
+// uploads for content larger than one part
+type S3Executor struct {
+	cfg   S3Config
+	creds awsCredentials
+	base  *url.URL
+	http  *http.Client
+
+	usageMu  sync.Mutex
+	used     int64
+	listedAt time.Time
+}
+
+// NewS3Executor creates an S3 executor for the configured bucket
+func NewS3Executor(cfg S3Config) (*S3Executor, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3: bucket is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	if cfg.PartSize == 0 {
+		cfg.PartSize = 8 * 1024 * 1024
+	}
+	if cfg.PartSize < minS3PartSize {
+		return nil, fmt.Errorf("s3: part size %d is below the 5 MiB minimum", cfg.PartSize)
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.QuotaTTL <= 0 {
+		cfg.QuotaTTL = 5 * time.Minute
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+
+	creds := awsCredentials{
+		AccessKeyID:     firstNonEmpty(cfg.AccessKeyID, os.Getenv("AWS_ACCESS_KEY_ID")),
+		SecretAccessKey: firstNonEmpty(cfg.SecretAccessKey, os.Getenv("AWS_SECRET_ACCESS_KEY")),
+		SessionToken:    firstNonEmpty(cfg.SessionToken, os.Getenv("AWS_SESSION_TOKEN")),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("s3: no credentials configured (set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY)")
+	}
+
+	base, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("s3: invalid endpoint: %w", err)
+	}
+
+	return &S3Executor{cfg: cfg, creds: creds, base: base, http: cfg.HTTPClient}, nil
+}
+
+// Class implements Executor
+func (e *S3Executor) Class() string {
+	return "s3"
+}
+
+func (e *S3Executor) provider() string {
+	return "s3:" + e.cfg.Region
+}
+
+// Providers implements Provided
+func (e *S3Executor) Providers() []string {
+	return []string{e.provider()}
+}
+
+// Quota implements QuotaReporter with the size of the objects under Prefix
+// against QuotaBytes. Without QuotaBytes the bucket is unlimited and
+// nothing is listed. Listing is O(objects), so its result is reused for
+// QuotaTTL and kept current with the executor's own uploads and deletes.
+func (e *S3Executor) Quota(ctx context.Context) (*Quota, error) {
+	if e.cfg.QuotaBytes <= 0 {
+		return &Quota{}, nil
+	}
+	e.usageMu.Lock()
+	used, fresh := e.used, !e.listedAt.IsZero() && time.Since(e.listedAt) < e.cfg.QuotaTTL
+	e.usageMu.Unlock()
+	if !fresh {
+		listedAt := time.Now()
+		var err error
+		if used, err = e.prefixSize(ctx); err != nil {
+			return nil, fmt.Errorf("s3: list objects: %w", err)
+		}
+		e.usageMu.Lock()
+		e.used, e.listedAt = used, listedAt
+		e.usageMu.Unlock()
+	}
+	return &Quota{Used: used, Limit: e.cfg.QuotaBytes}, nil
+}
+
+// addUsage counts n bytes stored, or removed if negative, into the
+// listing Quota reuses
+func (e *S3Executor) addUsage(n int64) {
+	e.usageMu.Lock()
+	defer e.usageMu.Unlock()
+	if !e.listedAt.IsZero() {
+		e.used = max(e.used+n, 0)
+	}
+}
+
+// prefixSize sums the sizes of the objects under Prefix with
+// ListObjectsV2, a page of up to 1,000 objects at a time
+func (e *S3Executor) prefixSize(ctx context.Context) (int64, error) {
+	query := url.Values{"list-type": {"2"}}
+	if e.cfg.Prefix != "" {
+		query.Set("prefix", strings.TrimSuffix(e.cfg.Prefix, "/")+"/")
+	}
+	var total int64
+	for {
+		resp, err := e.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return 0, err
+		}
+		var out struct {
+			Contents []struct {
+				Size int64 `xml:"Size"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&out)
+		resp.Body.Close()
+		if err != nil {
+			return 0, fmt.Errorf("decode response: %w", err)
+		}
+		for _, obj := range out.Contents {
+			total += obj.Size
+		}
+		if !out.IsTruncated || out.NextContinuationToken == "" {
+			return total, nil
+		}
+		query.Set("continuation-token", out.NextContinuationToken)
+	}
+}
+
+// Put implements Executor. Content that fits in a single part is uploaded
+// with PutObject; larger content uses a multipart upload, Concurrency
+// parts at a time, which is aborted if any part fails unless it is
+// checkpointed. Every request carries its body's MD5, which S3 checks.
+func (e *S3Executor) Put(ctx context.Context, info routingclient.ContentInfo, r io.Reader) (*Result, error) {
+	if !providerPermitted(ctx, e.provider()) {
+		return nil, fmt.Errorf("s3: region %s is not permitted by placement rules", e.cfg.Region)
+	}
+
+	partSize := e.partSize(info)
+	first := make([]byte, partSize)
+	n, err := io.ReadFull(r, first)
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		key, err := e.objectKey(info, first[:n])
+		if err != nil {
+			return nil, err
+		}
+		etag, err := e.putObject(ctx, key, info, first[:n])
+		if err != nil {
+			return nil, err
+		}
+		e.addUsage(int64(n))
+		return &Result{Location: e.objectURL(key).String(), Bytes: int64(n), ETag: etag, Provider: e.provider()}, nil
+	case err != nil:
+		return nil, fmt.Errorf("read content: %w", err)
+	}
+
+	key, err := e.objectKey(info, nil)
+	if err != nil {
+		return nil, err
+	}
+	return e.putMultipart(ctx, key, info, partSize, first, r)
+}
+
+// partSize is the configured part size, or for content too large to fit
+// in maxS3Parts of it, the smallest whole number of MiB that fits
+func (e *S3Executor) partSize(info routingclient.ContentInfo) int64 {
+	if info.ContentSize <= e.cfg.PartSize*maxS3Parts {
+		return e.cfg.PartSize
+	}
+	const mib = 1024 * 1024
+	size := (info.ContentSize + maxS3Parts - 1) / maxS3Parts
+	return (size + mib - 1) / mib * mib
+}
+
+// nthPartSize is the size of part num of an upload whose first part is
+// first bytes. Content of known size keeps the size partSize chose;
+// content of unknown size doubles it every s3PartGrowth parts, as S3
+// allows parts of different sizes.
+func nthPartSize(info routingclient.ContentInfo, first int64, num int) int64 {
+	if info.ContentSize > 0 {
+		return first
+	}
+	return first << ((num - 1) / s3PartGrowth)
+}
+
+// Delete implements Deleter, removing the object at rec's location
+func (e *S3Executor) Delete(ctx context.Context, rec PlacementRecord) error {
+	u, err := url.Parse(rec.Location)
+	if err != nil {
+		return fmt.Errorf("s3: %w", err)
+	}
+	bucket := e.objectURL("")
+	key, ok := strings.CutPrefix(u.Path, bucket.Path+"/")
+	if !ok || u.Host != bucket.Host {
+		return fmt.Errorf("s3: %s is not in bucket %s", rec.Location, e.cfg.Bucket)
+	}
+	resp, err := e.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	e.addUsage(-rec.Bytes)
+	return resp.Body.Close()
+}
+
+// putObject uploads content in a single request
+func (e *S3Executor) putObject(ctx context.Context, key string, info routingclient.ContentInfo, body []byte) (string, error) {
+	h := e.objectHeaders(info)
+	h.Set("Content-MD5", contentMD5(body))
+	resp, err := e.do(ctx, http.MethodPut, key, nil, body, h)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+// putMultipart uploads content as a multipart upload starting with the
+// already-read first part, resuming the checkpointed upload of the same
+// content if there is one
+func (e *S3Executor) putMultipart(ctx context.Context, key string, info routingclient.ContentInfo, partSize int64, first []byte, r io.Reader) (*Result, error) {
+	location := e.objectURL(key).String()
+	cp := e.cfg.Checkpoints.Load(e.Class(), checkpointKey(info, key))
+	if cp.UploadID == "" || cp.Path != location || cp.PartSize != partSize {
+		uploadID, err := e.createMultipartUpload(ctx, key, info)
+		if err != nil {
+			return nil, err
+		}
+		cp.Path, cp.PartSize, cp.UploadID, cp.Parts, cp.Offset = location, partSize, uploadID, nil, 0
+		if err := e.cfg.Checkpoints.Save(cp); err != nil {
+			e.abortMultipartUpload(ctx, key, uploadID)
+			return nil, fmt.Errorf("s3: %w", err)
+		}
+	}
+	uploadID := cp.UploadID
+	keep := e.cfg.Checkpoints != nil && cp.Key != ""
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		parts    []completedPart
+		firstErr error
+		total    int64
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	sem := make(chan struct{}, e.cfg.Concurrency)
+	launch := func(num int, data []byte) {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// A part the checkpoint holds with the same content is not
+			// uploaded again
+			sum := hashHex(data)
+			mu.Lock()
+			done, ok := cp.part(num, sum)
+			if ok {
+				parts = append(parts, completedPart{PartNumber: num, ETag: done.ETag})
+			}
+			mu.Unlock()
+			if ok {
+				return
+			}
+
+			etag, err := e.uploadPart(ctx, key, uploadID, num, data)
+			if err != nil {
+				fail(fmt.Errorf("part %d: %w", num, err))
+				return
+			}
+			mu.Lock()
+			parts = append(parts, completedPart{PartNumber: num, ETag: etag})
+			cp.addPart(CheckpointPart{Number: num, Size: int64(len(data)), SHA256: sum, ETag: etag})
+			err = e.cfg.Checkpoints.Save(cp)
+			mu.Unlock()
+			if err != nil {
+				fail(fmt.Errorf("s3: %w", err))
+			}
+		}()
+	}
+
+	launch(1, first)
+	total = int64(len(first))
+	for num := 2; ctx.Err() == nil; num++ {
+		next := make([]byte, nthPartSize(info, partSize, num))
+		n, err := io.ReadFull(r, next)
+		if n > 0 && num > maxS3Parts {
+			fail(fmt.Errorf("s3: content is larger than its size of %d bytes and needs more than %d parts", info.ContentSize, maxS3Parts))
+			break
+		}
+		if n > 0 {
+			total += int64(n)
+			launch(num, next[:n])
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			fail(fmt.Errorf("read content: %w", err))
+			break
+		}
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		if keep && !staleUpload(firstErr) {
+			return nil, firstErr
+		}
+		e.cfg.Checkpoints.Remove(cp)
+		e.abortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+		return nil, firstErr
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	etag, err := e.completeMultipartUpload(ctx, key, uploadID, parts)
+	if err != nil {
+		if keep && !staleUpload(err) {
+			return nil, err
+		}
+		e.cfg.Checkpoints.Remove(cp)
+		e.abortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+		return nil, err
+	}
+	e.cfg.Checkpoints.Remove(cp)
+	e.addUsage(total)
+
+	return &Result{Location: location, Bytes: total, ETag: etag, Provider: e.provider()}, nil
+}
+
+// staleUpload reports whether err means a checkpointed multipart upload
+// can no longer be completed, e.g. because it expired or was aborted, so
+// the content has to be uploaded afresh
+func staleUpload(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "NoSuchUpload") || strings.Contains(msg, "InvalidPart")
+}
+
+// completedPart identifies an uploaded part in CompleteMultipartUpload
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (e *S3Executor) createMultipartUpload(ctx context.Context, key string, info routingclient.ContentInfo) (string, error) {
+	resp, err := e.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, e.objectHeaders(info))
+	if err != nil {
+		return "", fmt.Errorf("create multipart upload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("create multipart upload: decode response: %w", err)
+	}
+	return out.UploadID, nil
+}
+
+func (e *S3Executor) uploadPart(ctx context.Context, key, uploadID string, partNumber int, data []byte) (string, error) {
+	query := url.Values{
+		"partNumber": {strconv.Itoa(partNumber)},
+		"uploadId":   {uploadID},
+	}
+	h := http.Header{"Content-Md5": {contentMD5(data)}}
+	resp, err := e.do(ctx, http.MethodPut, key, query, data, h)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Header.Get("ETag"), nil
+}
+
+func (e *S3Executor) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []completedPart) (string, error) {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := e.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body, nil)
+	if err != nil {
+		return "", fmt.Errorf("complete multipart upload: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// S3 may report a failure inside a 200 response body
+	var out struct {
+		XMLName xml.Name
+		ETag    string `xml:"ETag"`
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("complete multipart upload: decode response: %w", err)
+	}
+	if out.XMLName.Local == "Error" {
+		return "", fmt.Errorf("complete multipart upload: %s: %s", out.Code, out.Message)
+	}
+	return out.ETag, nil
+}
+
+func (e *S3Executor) abortMultipartUpload(ctx context.Context, key, uploadID string) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	resp, err := e.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+// do sends a signed request for an object key and converts S3 error
+// responses into Go errors
+func (e *S3Executor) do(ctx context.Context, method, key string, query url.Values, body []byte, headers http.Header) (*http.Response, error) {
+	u := e.objectURL(key)
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	signV4(req, e.creds, e.cfg.Region, "s3", hashHex(body), time.Now())
+
+	resp, err := e.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, s3ResponseError(resp)
+	}
+	return resp, nil
+}
+
+// objectHeaders builds the content type, download filename, user metadata
+// and storage class headers
+func (e *S3Executor) objectHeaders(info routingclient.ContentInfo) http.Header {
+	h := http.Header{}
+	if info.ContentType != "" {
+		h.Set("Content-Type", info.ContentType)
+	}
+	if name := downloadName(info.Filename); name != "" {
+		// FormatMediaType returns "" for names it cannot encode
+		if v := mime.FormatMediaType("attachment", map[string]string{"filename": name}); v != "" {
+			h.Set("Content-Disposition", v)
+		}
+	}
+	for k, v := range info.Metadata {
+		h.Set("X-Amz-Meta-"+k, v)
+	}
+	if class, ok := s3StorageClasses[ClassOf(info)]; ok {
+		h.Set("X-Amz-Storage-Class", class)
+	}
+	return h
+}
+
+// objectKey names the object holding content by its identity under
+// Prefix: its ContentHash, else its CID, else for a single-part upload the
+// SHA-256 of data, its whole content. Filename never names objects, so it
+// cannot escape Prefix and different content uploaded under one name does
+// not overwrite; it only sets the download filename.
+func (e *S3Executor) objectKey(info routingclient.ContentInfo, data []byte) (string, error) {
+	id, err := info.Hash()
+	if err != nil {
+		return "", fmt.Errorf("s3: %w", err)
+	}
+	if id == "" {
+		id = info.Metadata[CIDMetadataKey]
+	}
+	if id == "" && data != nil {
+		id = hashHex(data)
+	}
+	if id == "" {
+		return "", errors.New("s3: content larger than one part needs a ContentHash or CID to name its object")
+	}
+	if !validObjectID(id) {
+		return "", fmt.Errorf("s3: %q cannot name an object", id)
+	}
+	if prefix := strings.Trim(e.cfg.Prefix, "/"); prefix != "" {
+		return prefix + "/" + id, nil
+	}
+	return id, nil
+}
+
+// downloadName is the last element of filename, or "" if it has none
+func downloadName(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if filename == "" || name == "." || name == ".." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// validObjectID reports whether id, a content hash or CID, is a single
+// path segment of letters, digits, '-', '_' and '.' other than "." and ".."
+func validObjectID(id string) bool {
+	if id == "." || id == ".." {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return id != ""
+}
+
+// objectURL returns the path-style URL of an object
+func (e *S3Executor) objectURL(key string) *url.URL {
+	u := *e.base
+	u.Path = path.Join("/", u.Path, e.cfg.Bucket, key)
+	return &u
+}
+
+// s3ResponseError extracts the error code and message from an S3 error body
+func s3ResponseError(resp *http.Response) error {
+	var s3err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(data, &s3err) == nil && s3err.Code != "" {
+		return fmt.Errorf("s3: %s (HTTP %d): %s", s3err.Code, resp.StatusCode, s3err.Message)
+	}
+	return fmt.Errorf("s3: unexpected HTTP status %s", resp.Status)
+}
+
+// contentMD5 is the Content-MD5 header value for body
+func contentMD5(body []byte) string {
+	sum := md5.Sum(body)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,184 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// fakeS3 serves the subset of the S3 API the executor uses, keeping
+// objects and multipart uploads in memory
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+	puts    int
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *S3Executor) {
+	t.Helper()
+	f := &fakeS3{objects: map[string][]byte{}, uploads: map[string]map[int][]byte{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	e, err := NewS3Executor(S3Config{
+		Bucket:          "bucket",
+		Prefix:          "data/",
+		Region:          "us-east-1",
+		Endpoint:        srv.URL,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		PartSize:        minS3PartSize,
+		QuotaBytes:      1 << 40,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, e
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		http.Error(w, "unsigned", http.StatusForbidden)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	q := r.URL.Query()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && q.Get("list-type") == "2":
+		var sb strings.Builder
+		sb.WriteString("<ListBucketResult>")
+		for key, data := range f.objects {
+			if strings.HasPrefix(key, "/bucket/"+q.Get("prefix")) {
+				fmt.Fprintf(&sb, "<Contents><Key>%s</Key><Size>%d</Size></Contents>", key, len(data))
+			}
+		}
+		sb.WriteString("</ListBucketResult>")
+		io.WriteString(w, sb.String())
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		id := fmt.Sprintf("upload-%d", len(f.uploads)+1)
+		f.uploads[id] = map[int][]byte{}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && q.Has("partNumber"):
+		var num int
+		fmt.Sscan(q.Get("partNumber"), &num)
+		f.uploads[q.Get("uploadId")][num] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"part-%d"`, num))
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		parts := f.uploads[q.Get("uploadId")]
+		var nums []int
+		for num := range parts {
+			nums = append(nums, num)
+		}
+		sort.Ints(nums)
+		var data []byte
+		for _, num := range nums {
+			data = append(data, parts[num]...)
+		}
+		f.objects[r.URL.Path] = data
+		delete(f.uploads, q.Get("uploadId"))
+		io.WriteString(w, `<CompleteMultipartUploadResult><ETag>"multi"</ETag></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodPut:
+		f.puts++
+		f.objects[r.URL.Path] = body
+		w.Header().Set("ETag", `"single"`)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unsupported", http.StatusNotImplemented)
+	}
+}
+
+func TestS3PutKeysObjectsByContent(t *testing.T) {
+	f, e := newFakeS3(t)
+	ctx := context.Background()
+
+	a, err := e.Put(ctx, routingclient.ContentInfo{Filename: "../../etc/passwd"}, strings.NewReader("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := e.Put(ctx, routingclient.ContentInfo{Filename: "../../etc/passwd"}, strings.NewReader("second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Location == b.Location {
+		t.Fatalf("different content under one filename stored at %s", a.Location)
+	}
+	for key := range f.objects {
+		if !strings.HasPrefix(key, "/bucket/data/") || strings.Contains(key, "passwd") {
+			t.Errorf("object stored at %s", key)
+		}
+	}
+	if want := "/bucket/data/" + hashHex([]byte("first")); !strings.HasSuffix(a.Location, want) {
+		t.Errorf("location %s, want suffix %s", a.Location, want)
+	}
+
+	if _, err := e.Put(ctx, routingclient.ContentInfo{ContentHash: "../x"}, strings.NewReader("x")); err == nil {
+		t.Error("content hash with a path separator named an object")
+	}
+}
+
+func TestS3PutMultipart(t *testing.T) {
+	f, e := newFakeS3(t)
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("0123456789abcdef"), (2*minS3PartSize+1024)/16)
+
+	if _, err := e.Put(ctx, routingclient.ContentInfo{}, bytes.NewReader(data)); err == nil {
+		t.Fatal("multipart upload without a content hash or CID succeeded")
+	}
+
+	info := routingclient.ContentInfo{ContentHash: hashHex(data), ContentSize: int64(len(data))}
+	res, err := e.Put(ctx, info, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Bytes != int64(len(data)) || res.ETag != `"multi"` {
+		t.Errorf("result %+v", res)
+	}
+	if got := f.objects["/bucket/data/"+info.ContentHash]; !bytes.Equal(got, data) {
+		t.Errorf("stored %d bytes, want %d", len(got), len(data))
+	}
+	if f.puts != 0 {
+		t.Errorf("%d single-part puts for multipart content", f.puts)
+	}
+
+	q, err := e.Quota(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Used != int64(len(data)) {
+		t.Errorf("quota used %d, want %d", q.Used, len(data))
+	}
+	if err := e.Delete(ctx, PlacementRecord{Location: res.Location, Bytes: res.Bytes}); err != nil {
+		t.Fatal(err)
+	}
+	if q, _ := e.Quota(ctx); q.Used != 0 {
+		t.Errorf("quota used %d after delete, want 0", q.Used)
+	}
+}
+
+func TestNthPartSize(t *testing.T) {
+	const first = minS3PartSize
+	var total int64
+	for num := 1; num <= maxS3Parts; num++ {
+		total += nthPartSize(routingclient.ContentInfo{}, first, num)
+	}
+	// Content of unknown size fits 1,023 first parts in maxS3Parts
+	if want := int64(1023 * s3PartGrowth * first); total != want {
+		t.Errorf("unknown size: %d parts hold %d bytes, want %d", maxS3Parts, total, want)
+	}
+	if got := nthPartSize(routingclient.ContentInfo{ContentSize: 1}, first, maxS3Parts); got != first {
+		t.Errorf("known size: part %d is %d bytes, want %d", maxS3Parts, got, first)
+	}
+}
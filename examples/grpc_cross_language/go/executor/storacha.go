@@ -0,0 +1,527 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/ipfs_kit_py/car"
+	"example.com/ipfs_kit_py/cidutil"
+	"example.com/ipfs_kit_py/routingclient"
+	"example.com/ipfs_kit_py/unixfs"
+)
+
+// storachaEndpoints are the bridge endpoints tried in order of preference,
+// matching the list used by the Python storacha_kit
+var storachaEndpoints = []string{
+	"https://up.storacha.network/bridge", // Primary endpoint
+	"https://api.web3.storage",           // Legacy endpoint
+	"https://up.web3.storage/bridge",     // Alternative endpoint
+}
+
+// maxRawBlockSize is the largest non-CAR payload packed as a single raw
+// block; larger content is imported as a UnixFS file
+const maxRawBlockSize = 1 << 20
+
+// defaultStorachaShardSize is the w3up client's default CAR shard size
+const defaultStorachaShardSize = 133169152
+
+// StorachaConfig configures the Storacha executor
+type StorachaConfig struct {
+	// SpaceDID is the did:key of the space that receives uploads
+	SpaceDID string
+
+	// AuthSecret and Authorization are the X-Auth-Secret and Authorization
+	// header values generated with `storacha bridge generate-tokens`
+	AuthSecret    string
+	Authorization string
+
+	// Endpoints overrides the bridge endpoints to probe. STORACHA_API_URL
+	// or STORACHA_API_ENDPOINT, if set, is always tried first.
+	Endpoints []string
+
+	// ProbeInterval controls how often working endpoints are re-checked
+	// (default 5 minutes)
+	ProbeInterval time.Duration
+
+	// ShardSize is the most bytes of blocks per CAR shard; larger content
+	// is split across shards (default 127 MiB, as the w3up client)
+	ShardSize int64
+
+	// Concurrency is the number of shards uploaded in parallel (default 4)
+	Concurrency int
+
+	// Checkpoints, if set, records each CAR shard once it is uploaded, so
+	// putting the same content again after a failure registers the upload
+	// without sending those shards again
+	Checkpoints *Checkpoints
+
+	HTTPClient *http.Client
+
+	// Logger receives endpoint probing and fallback messages; nil disables logging
+	Logger *log.Logger
+}
+
+// StorachaExecutor uploads content to Storacha (web3.storage) through the
+// HTTP bridge, probing endpoints and falling back between them
+type StorachaExecutor struct {
+	cfg  StorachaConfig
+	http *http.Client
+
+	mu        sync.Mutex
+	working   []string
+	lastProbe time.Time
+}
+
+// NewStorachaExecutor creates a Storacha executor for the configured space
+func NewStorachaExecutor(cfg StorachaConfig) (*StorachaExecutor, error) {
+	if cfg.SpaceDID == "" {
+		return nil, errors.New("storacha: space DID is required")
+	}
+	if cfg.AuthSecret == "" || cfg.Authorization == "" {
+		return nil, errors.New("storacha: bridge auth secret and authorization are required")
+	}
+	if len(cfg.Endpoints) == 0 {
+		cfg.Endpoints = storachaEndpoints
+	}
+	if user := firstNonEmpty(os.Getenv("STORACHA_API_URL"), os.Getenv("STORACHA_API_ENDPOINT")); user != "" {
+		cfg.Endpoints = append([]string{user}, cfg.Endpoints...)
+	}
+	if cfg.ProbeInterval == 0 {
+		cfg.ProbeInterval = 5 * time.Minute
+	}
+	if cfg.ShardSize <= 0 {
+		cfg.ShardSize = defaultStorachaShardSize
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+
+	return &StorachaExecutor{cfg: cfg, http: cfg.HTTPClient}, nil
+}
+
+// Class implements Executor
+func (e *StorachaExecutor) Class() string {
+	return "storacha"
+}
+
+// SpoolDir implements Spooler: content is packed into CAR shards in the
+// temporary directory
+func (e *StorachaExecutor) SpoolDir() string {
+	return os.TempDir()
+}
+
+// Put implements Executor. Content is packed into CAR shards of at most
+// ShardSize, which are uploaded in parallel and registered as one upload:
+// CAR content under its own root, small content as one raw block and
+// larger content as a UnixFS file built locally. Each shard is identified
+// by the hash of its bytes, which Storacha checks on upload.
+func (e *StorachaExecutor) Put(ctx context.Context, info routingclient.ContentInfo, r io.Reader) (*Result, error) {
+	dir, err := os.MkdirTemp("", "storacha-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	shards, root, err := packCAR(dir, info, r, e.cfg.ShardSize)
+	if err != nil {
+		return nil, fmt.Errorf("storacha: %w", err)
+	}
+
+	cp := e.cfg.Checkpoints.Load(e.Class(), checkpointKey(info, root))
+	if cp.Root != root {
+		cp.Root, cp.Parts, cp.Offset = root, nil, 0
+	}
+	if err := e.storeShards(ctx, cp, shards); err != nil {
+		return nil, err
+	}
+
+	links := make([]map[string]string, len(shards))
+	var size int64
+	for i, shard := range shards {
+		links[i] = map[string]string{"/": shard.cid()}
+		size += shard.size
+	}
+	err = e.invoke(ctx, "upload/add", map[string]interface{}{
+		"root":   map[string]string{"/": root},
+		"shards": links,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	e.cfg.Checkpoints.Remove(cp)
+
+	return &Result{Location: "ipfs://" + root, CID: root, Bytes: size}, nil
+}
+
+// storeShards stores the shards cp does not hold yet, Concurrency at a
+// time, recording each in cp as it completes
+func (e *StorachaExecutor) storeShards(ctx context.Context, cp *Checkpoint, shards []carShard) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	sem := make(chan struct{}, e.cfg.Concurrency)
+	for i, shard := range shards {
+		num, sum := i+1, hex.EncodeToString(shard.digest)
+		if _, ok := cp.part(num, sum); ok {
+			continue
+		}
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := e.storeShard(ctx, shard)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				cp.addPart(CheckpointPart{Number: num, Size: shard.size, SHA256: sum, ETag: shard.cid()})
+				err = e.cfg.Checkpoints.Save(cp)
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+// storeShard registers a CAR shard with store/add and uploads it, unless
+// the space already holds it
+func (e *StorachaExecutor) storeShard(ctx context.Context, shard carShard) error {
+	var stored struct {
+		Status  string            `json:"status"`
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+	}
+	err := e.invoke(ctx, "store/add", map[string]interface{}{
+		"link": map[string]string{"/": shard.cid()},
+		"size": shard.size,
+	}, &stored)
+	if err != nil {
+		return err
+	}
+	if stored.Status != "upload" {
+		return nil
+	}
+
+	f, err := os.Open(shard.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return e.putShard(ctx, stored.URL, stored.Headers, f, shard.size)
+}
+
+// packCAR packs content into CAR shards of at most max bytes of blocks
+// under dir, and returns them with the content's root CID
+func packCAR(dir string, info routingclient.ContentInfo, r io.Reader, max int64) ([]carShard, string, error) {
+	s := &carSharder{dir: dir, max: max}
+	if isCAR(info) {
+		return splitCAR(s, r)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxRawBlockSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) <= maxRawBlockSize {
+		root, err := cidutil.CastCID(rawCID(data))
+		if err != nil {
+			return nil, "", err
+		}
+		if err := s.add(root, data); err != nil {
+			return nil, "", err
+		}
+		shards, err := s.close(root)
+		return shards, root.String(), err
+	}
+
+	b := unixfs.NewBuilder(1)
+	b.OnBlock = s.add
+	node, err := b.File(io.MultiReader(bytes.NewReader(data), r))
+	if err != nil {
+		return nil, "", err
+	}
+	shards, err := s.close(node.CID)
+	return shards, node.CID.String(), err
+}
+
+// splitCAR spools a CAR with a single root and shards it by block. A CARv1
+// that fits in one shard is uploaded as it is.
+func splitCAR(s *carSharder, r io.Reader) ([]carShard, string, error) {
+	f, err := os.CreateTemp(s.dir, "input-*.car")
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return nil, "", err
+	}
+	rd, err := car.NewReader(f, size)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(rd.Roots) != 1 {
+		return nil, "", fmt.Errorf("CAR must have exactly one root, found %d", len(rd.Roots))
+	}
+	root := rd.Roots[0]
+	if rd.Version == 1 && size <= s.max {
+		return []carShard{{path: f.Name(), size: size, digest: h.Sum(nil)}}, root.String(), nil
+	}
+
+	err = rd.Blocks(func(b car.BlockInfo) error {
+		data, err := rd.Block(b.CID)
+		if err != nil {
+			return err
+		}
+		return s.add(b.CID, data)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	shards, err := s.close(root)
+	return shards, root.String(), err
+}
+
+// isCAR reports whether the content is already a CAR archive
+func isCAR(info routingclient.ContentInfo) bool {
+	return info.ContentType == "application/vnd.ipld.car" ||
+		strings.EqualFold(path.Ext(info.Filename), ".car")
+}
+
+// putShard uploads a CAR shard to the presigned URL returned by store/add
+func (e *StorachaExecutor) putShard(ctx context.Context, target string, headers map[string]string, body io.Reader, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("storacha: upload shard: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("storacha: upload shard: HTTP %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// invoke runs a single capability through the bridge and decodes the ok
+// result into out (if non-nil)
+func (e *StorachaExecutor) invoke(ctx context.Context, capability string, caveats interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"tasks": [][]interface{}{{capability, e.cfg.SpaceDID, caveats}},
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := e.bridgeRequest(ctx, body, 1)
+	if err != nil {
+		return fmt.Errorf("storacha: %s: %w", capability, err)
+	}
+
+	var receipts []struct {
+		P struct {
+			Out struct {
+				OK    json.RawMessage `json:"ok"`
+				Error json.RawMessage `json:"error"`
+			} `json:"out"`
+		} `json:"p"`
+	}
+	if err := json.Unmarshal(data, &receipts); err != nil {
+		return fmt.Errorf("storacha: %s: decode receipt: %w", capability, err)
+	}
+	if len(receipts) == 0 {
+		return fmt.Errorf("storacha: %s: empty response", capability)
+	}
+	result := receipts[0].P.Out
+	if len(result.Error) > 0 && string(result.Error) != "null" {
+		return fmt.Errorf("storacha: %s failed: %s", capability, result.Error)
+	}
+	if out != nil {
+		if err := json.Unmarshal(result.OK, out); err != nil {
+			return fmt.Errorf("storacha: %s: decode result: %w", capability, err)
+		}
+	}
+	return nil
+}
+
+// bridgeRequest posts a task batch to the current endpoint, moving on to
+// the next working endpoint after a connection error, a 5xx or a 429
+func (e *StorachaExecutor) bridgeRequest(ctx context.Context, body []byte, retries int) ([]byte, error) {
+	endpoint := e.endpoint(ctx)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Auth-Secret", e.cfg.AuthSecret)
+	req.Header.Set("Authorization", e.cfg.Authorization)
+
+	resp, err := e.http.Do(req)
+	if err != nil {
+		var netErr net.Error
+		if retries > 0 && ctx.Err() == nil && errors.As(err, &netErr) && e.demote(endpoint) {
+			e.logf("Connection error with %s, trying next endpoint", endpoint)
+			return e.bridgeRequest(ctx, body, retries-1)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	unavailable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	if unavailable && retries > 0 && ctx.Err() == nil && e.demote(endpoint) {
+		e.logf("%s returned status %d, trying next endpoint", endpoint, resp.StatusCode)
+		return e.bridgeRequest(ctx, body, retries-1)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+	return data, nil
+}
+
+// endpoint returns the preferred working endpoint, re-probing the
+// candidates when the last check is older than ProbeInterval. Probing
+// happens without e.mu held, so a slow endpoint does not stall demote or
+// other uploads; only the result is stored under it.
+func (e *StorachaExecutor) endpoint(ctx context.Context) string {
+	e.mu.Lock()
+	working := e.working
+	stale := len(working) == 0 || time.Since(e.lastProbe) > e.cfg.ProbeInterval
+	e.mu.Unlock()
+
+	if stale {
+		working = e.probe(ctx)
+		e.mu.Lock()
+		e.working = working
+		e.lastProbe = time.Now()
+		e.mu.Unlock()
+	}
+	if len(working) > 0 {
+		return working[0]
+	}
+	e.logf("No working Storacha endpoints found. Using default: %s", e.cfg.Endpoints[0])
+	return e.cfg.Endpoints[0]
+}
+
+// demote moves a failed endpoint to the back of the working list and
+// reports whether another endpoint is available
+func (e *StorachaExecutor) demote(endpoint string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.working) < 2 {
+		return false
+	}
+	for i, ep := range e.working {
+		if ep == endpoint {
+			e.working = append(append(e.working[:i:i], e.working[i+1:]...), ep)
+			break
+		}
+	}
+	return true
+}
+
+// probe checks DNS resolution and HTTP reachability of each candidate
+// endpoint; any response below 500 counts as working
+func (e *StorachaExecutor) probe(ctx context.Context) []string {
+	var working []string
+	for _, endpoint := range e.cfg.Endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			e.logf("Skipping invalid Storacha endpoint %q", endpoint)
+			continue
+		}
+		if _, err := net.DefaultResolver.LookupHost(ctx, u.Hostname()); err != nil {
+			e.logf("DNS resolution failed for %s, skipping endpoint", u.Hostname())
+			continue
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		req, err := http.NewRequestWithContext(probeCtx, http.MethodHead, endpoint, nil)
+		if err != nil {
+			cancel()
+			continue
+		}
+		req.Header.Set("User-Agent", "ipfs-kit-storacha/1.0")
+		resp, err := e.http.Do(req)
+		cancel()
+		if err != nil {
+			e.logf("Error checking endpoint %s: %v", endpoint, err)
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 500 {
+			e.logf("Found working Storacha endpoint: %s (Status code: %d)", endpoint, resp.StatusCode)
+			working = append(working, endpoint)
+		} else {
+			e.logf("Endpoint %s returned error status: %d", endpoint, resp.StatusCode)
+		}
+	}
+	return working
+}
+
+func (e *StorachaExecutor) logf(format string, args ...interface{}) {
+	if e.cfg.Logger != nil {
+		e.cfg.Logger.Printf(format, args...)
+	}
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
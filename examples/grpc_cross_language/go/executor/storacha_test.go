@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// TestStorachaFallsBackOnStatus checks that a bridge endpoint answering
+// 5xx or 429 is demoted in favour of the next working one, while other
+// errors are returned as they are
+func TestStorachaFallsBackOnStatus(t *testing.T) {
+	for _, tt := range []struct {
+		status   int
+		fallback bool
+	}{
+		{http.StatusServiceUnavailable, true},
+		{http.StatusBadGateway, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusUnauthorized, false},
+	} {
+		var failing, healthy atomic.Int32
+		bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				failing.Add(1)
+				w.WriteHeader(tt.status)
+			}
+		}))
+		good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				healthy.Add(1)
+				w.Write([]byte(`[{"p":{"out":{"ok":{}}}}]`))
+			}
+		}))
+
+		t.Setenv("STORACHA_API_URL", "")
+		t.Setenv("STORACHA_API_ENDPOINT", "")
+		e, err := NewStorachaExecutor(StorachaConfig{
+			SpaceDID:      "did:key:z6Mk",
+			AuthSecret:    "secret",
+			Authorization: "auth",
+			Endpoints:     []string{bad.URL, good.URL},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = e.invoke(context.Background(), "space/info", map[string]string{}, nil)
+		if tt.fallback && (err != nil || healthy.Load() != 1) {
+			t.Errorf("HTTP %d: err %v after %d requests to the next endpoint, want a fallback", tt.status, err, healthy.Load())
+		}
+		if !tt.fallback && (err == nil || healthy.Load() != 0) {
+			t.Errorf("HTTP %d: err %v after %d requests to the next endpoint, want the error", tt.status, err, healthy.Load())
+		}
+		if failing.Load() != 1 {
+			t.Errorf("HTTP %d: %d requests to the failing endpoint, want 1", tt.status, failing.Load())
+		}
+		bad.Close()
+		good.Close()
+	}
+}
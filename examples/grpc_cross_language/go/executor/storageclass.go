@@ -0,0 +1,325 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// StorageClass describes how quickly content must be retrievable, so users
+// pick a class instead of an individual backend
+type StorageClass string
+
+// Storage classes, from fastest and most expensive to slowest and cheapest
+const (
+	Hot     StorageClass = "hot"
+	Warm    StorageClass = "warm"
+	Cold    StorageClass = "cold"
+	Archive StorageClass = "archive"
+)
+
+// StorageClassKey is the ContentInfo metadata key naming the content's class
+const StorageClassKey = "storage_class"
+
+// ParseStorageClass validates a storage class name
+func ParseStorageClass(s string) (StorageClass, error) {
+	switch c := StorageClass(s); c {
+	case Hot, Warm, Cold, Archive:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown storage class %q (want hot, warm, cold or archive)", s)
+}
+
+// ClassOf returns the storage class requested in the content's metadata,
+// or "" if none was requested
+func ClassOf(info routingclient.ContentInfo) StorageClass {
+	c, err := ParseStorageClass(info.Metadata[StorageClassKey])
+	if err != nil {
+		return ""
+	}
+	return c
+}
+
+// s3StorageClasses maps storage classes to S3 x-amz-storage-class values
+var s3StorageClasses = map[StorageClass]string{
+	Hot:     "STANDARD",
+	Warm:    "STANDARD_IA",
+	Cold:    "GLACIER",
+	Archive: "DEEP_ARCHIVE",
+}
+
+// StorageClasses maps each storage class to the backend classes that can
+// serve it, in order of preference
+type StorageClasses struct {
+	Backends map[StorageClass][]string `json:"backends"`
+}
+
+// DefaultStorageClasses keeps hot content pinned on IPFS nodes, warm and
+// cold content in S3 (standard-IA and Glacier) and archives in Filecoin
+// deals
+func DefaultStorageClasses() *StorageClasses {
+	return &StorageClasses{Backends: map[StorageClass][]string{
+		Hot:     {"ipfs", "local"},
+		Warm:    {"s3", "storacha"},
+		Cold:    {"s3"},
+		Archive: {"filecoin"},
+	}}
+}
+
+// StorageClassError is returned when no candidate backend of a routing
+// decision serves the requested storage class
+type StorageClassError struct {
+	Class      StorageClass `json:"class"`
+	Candidates []string     `json:"candidates"`
+}
+
+func (e *StorageClassError) Error() string {
+	return fmt.Sprintf("no backend for storage class %s among %v", e.Class, e.Candidates)
+}
+
+// Serves reports whether backendID belongs to a backend class mapped to c
+func (s *StorageClasses) Serves(c StorageClass, backendID string) bool {
+	class := backendClass(backendID)
+	for _, b := range s.Backends[c] {
+		if b == class || b == backendID {
+			return true
+		}
+	}
+	return false
+}
+
+// Narrow rewrites decision so that it only offers backends serving c,
+// keeping the router's order. The class's preferred backends are not
+// forced; the router still ranks those that qualify.
+func (s *StorageClasses) Narrow(c StorageClass, decision *pb.SelectBackendResponse) error {
+	candidates := []string{decision.BackendId}
+	var kept []*pb.SelectBackendResponse_Alternative
+	if s.Serves(c, decision.BackendId) {
+		kept = append(kept, &pb.SelectBackendResponse_Alternative{BackendId: decision.BackendId, Score: decision.Score})
+	}
+	for _, alt := range decision.Alternatives {
+		candidates = append(candidates, alt.BackendId)
+		if s.Serves(c, alt.BackendId) {
+			kept = append(kept, alt)
+		}
+	}
+	if len(kept) == 0 {
+		return &StorageClassError{Class: c, Candidates: candidates}
+	}
+	decision.BackendId, decision.Score = kept[0].BackendId, kept[0].Score
+	decision.Alternatives = kept[1:]
+	return nil
+}
+
+// Popularity tracks how often content is accessed. Each access adds one to
+// a score that halves every HalfLife, so recent accesses count the most.
+type Popularity struct {
+	HalfLife time.Duration
+
+	mu     sync.Mutex
+	scores map[string]heat
+}
+
+type heat struct {
+	score float64
+	at    time.Time
+}
+
+// NewPopularity creates a tracker whose scores halve every halfLife
+func NewPopularity(halfLife time.Duration) *Popularity {
+	return &Popularity{HalfLife: halfLife, scores: make(map[string]heat)}
+}
+
+// Touch records an access to cid at now
+func (p *Popularity) Touch(cid string, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h := p.scores[cid]
+	p.scores[cid] = heat{score: p.decay(h, now) + 1, at: now}
+}
+
+// Seed sets the score of cid as it was at, e.g. when restoring scores
+// saved by an earlier process
+func (p *Popularity) Seed(cid string, score float64, at time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.scores[cid] = heat{score: score, at: at}
+}
+
+// Score returns the decayed access score of cid at now
+func (p *Popularity) Score(cid string, now time.Time) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.decay(p.scores[cid], now)
+}
+
+func (p *Popularity) decay(h heat, now time.Time) float64 {
+	if h.score == 0 || p.HalfLife <= 0 {
+		return h.score
+	}
+	age := now.Sub(h.at)
+	if age <= 0 {
+		return h.score
+	}
+	return h.score * math.Exp2(-float64(age)/float64(p.HalfLife))
+}
+
+// StoredObject is a piece of content and the storage class it is kept in
+type StoredObject struct {
+	CID      string       `json:"cid"`
+	Class    StorageClass `json:"class"`
+	StoredAt time.Time    `json:"stored_at"`
+	Size     int64        `json:"size,omitempty"`
+	// ContentHash is the hash outcomes report the content under, if it is
+	// not the CID
+	ContentHash string `json:"content_hash,omitempty"`
+	// Score is the access score as of ScoredAt, saved so it survives
+	// restarts
+	Score    float64   `json:"score,omitempty"`
+	ScoredAt time.Time `json:"scored_at,omitzero"`
+	// Placement is where the content is stored now
+	Placement *PlacementRecord `json:"placement,omitempty"`
+}
+
+// Transition moves content between storage classes
+type Transition struct {
+	CID   string       `json:"cid"`
+	From  StorageClass `json:"from"`
+	To    StorageClass `json:"to"`
+	Score float64      `json:"score"`
+	// At, FromPlacement, ToPlacement and Error are filled in when a
+	// Tiering daemon carries the transition out
+	At            time.Time        `json:"at,omitzero"`
+	FromPlacement *PlacementRecord `json:"from_placement,omitempty"`
+	ToPlacement   *PlacementRecord `json:"to_placement,omitempty"`
+	Error         string           `json:"error,omitempty"`
+}
+
+// Tier is a storage class and the content it keeps. Content belongs in the
+// first of a lifecycle's tiers that admits it: content no larger than
+// MaxSize that is younger than MaxAge or scores at least MinScore. A tier
+// with neither MaxAge nor MinScore admits content of any age and score.
+type Tier struct {
+	Class StorageClass `json:"class"`
+	// MaxAge is the age, since the content was stored, past which it
+	// leaves the tier unless it scores MinScore (0 for no age limit)
+	MaxAge Duration `json:"max_age,omitempty"`
+	// MinScore is the access score that keeps content in the tier
+	// whatever its age (0 for none)
+	MinScore float64 `json:"min_score,omitempty"`
+	// MaxSize is the largest content the tier holds (0 for no limit)
+	MaxSize int64 `json:"max_size,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "720h" in JSON
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// admits reports whether content of the given age, score and size
+// belongs in t
+func (t Tier) admits(age time.Duration, score float64, size int64) bool {
+	if t.MaxSize > 0 && size > t.MaxSize {
+		return false
+	}
+	if t.MaxAge == 0 && t.MinScore == 0 {
+		return true
+	}
+	return (t.MaxAge > 0 && age < time.Duration(t.MaxAge)) || (t.MinScore > 0 && score >= t.MinScore)
+}
+
+// DefaultTiers keeps content hot for a week, warm for three months, cold
+// for a year and archives it after that. Content scoring at least 5 stays
+// hot, and at least 1 warm, whatever its age; objects over 1 GiB are never
+// hot.
+func DefaultTiers() []Tier {
+	return []Tier{
+		{Class: Hot, MaxAge: Duration(7 * 24 * time.Hour), MinScore: 5, MaxSize: 1 << 30},
+		{Class: Warm, MaxAge: Duration(90 * 24 * time.Hour), MinScore: 1},
+		{Class: Cold, MaxAge: Duration(365 * 24 * time.Hour)},
+		{Class: Archive},
+	}
+}
+
+// Lifecycle decides storage class transitions from popularity. Content
+// scoring at least HotAbove is hot, at least WarmAbove warm, at least
+// ColdAbove cold, and anything less is archived. If Tiers are set they
+// decide instead, from age and size as well as popularity.
+type Lifecycle struct {
+	Popularity *Popularity
+	HotAbove   float64
+	WarmAbove  float64
+	ColdAbove  float64
+
+	// MinAge keeps recently stored content in its class, so new uploads
+	// are not archived before they have had a chance to be read
+	MinAge time.Duration
+
+	Tiers []Tier
+}
+
+// ClassFor returns the storage class content with the given score belongs in
+func (l *Lifecycle) ClassFor(score float64) StorageClass {
+	switch {
+	case score >= l.HotAbove:
+		return Hot
+	case score >= l.WarmAbove:
+		return Warm
+	case score >= l.ColdAbove:
+		return Cold
+	}
+	return Archive
+}
+
+// Plan returns the transitions needed to move objects into the class their
+// current popularity calls for
+func (l *Lifecycle) Plan(objects []StoredObject, now time.Time) []Transition {
+	var plan []Transition
+	for _, obj := range objects {
+		if now.Sub(obj.StoredAt) < l.MinAge {
+			continue
+		}
+		score := l.Popularity.Score(obj.CID, now)
+		to := l.ClassFor(score)
+		if len(l.Tiers) > 0 {
+			to = l.tierFor(obj, score, now)
+		}
+		if to != "" && to != obj.Class {
+			plan = append(plan, Transition{CID: obj.CID, From: obj.Class, To: to, Score: score})
+		}
+	}
+	return plan
+}
+
+// tierFor returns the class of the first tier that admits obj, or "" if
+// none does
+func (l *Lifecycle) tierFor(obj StoredObject, score float64, now time.Time) StorageClass {
+	for _, t := range l.Tiers {
+		if t.admits(now.Sub(obj.StoredAt), score, obj.Size) {
+			return t.Class
+		}
+	}
+	return ""
+}
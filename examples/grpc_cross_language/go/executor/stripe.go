@@ -0,0 +1,202 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"example.com/ipfs_kit_py/erasure"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// Striping splits large uploads into Reed-Solomon shards, each routed to
+// its own backend, so an object survives losing up to ParityShards of
+// them. The object's Result then describes where its manifest, which
+// erasure.Reconstruct reassembles it from, was stored.
+type Striping struct {
+	// DataShards and ParityShards are the shard counts (default 4 and 2)
+	DataShards   int
+	ParityShards int
+	// MinSize is the smallest object striped (default 64 MiB); smaller
+	// ones are uploaded whole
+	MinSize int64
+	// MaxPerBackend is how many of an object's shards one backend may hold
+	// (default 1). Keep it at most ParityShards so losing a backend loses
+	// no data.
+	MaxPerBackend int
+	// Dir holds the shards while they are uploaded (default the system
+	// temporary directory)
+	Dir string
+}
+
+// StripeMetadataKey is the ContentInfo metadata key naming the object a
+// shard belongs to, by its SHA-256; StripeIndexKey holds the shard's index
+const (
+	StripeMetadataKey = "stripe_of"
+	StripeIndexKey    = "stripe_index"
+)
+
+// SetStriping enables erasure-coded striping for uploads started with Run
+func (r *Registry) SetStriping(striping *Striping) {
+	s := *striping
+	if s.DataShards == 0 {
+		s.DataShards = 4
+	}
+	if s.ParityShards == 0 {
+		s.ParityShards = 2
+	}
+	if s.MinSize == 0 {
+		s.MinSize = 64 << 20
+	}
+	if s.MaxPerBackend == 0 {
+		s.MaxPerBackend = 1
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.striping = &s
+}
+
+// backendIDs returns the IDs executors are registered under, sorted
+func (r *Registry) backendIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.executors))
+	for id := range r.executors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// stripe uploads content as shards and then its manifest, which the
+// returned Result describes
+func stripe(ctx context.Context, client *routingclient.Client, registry *Registry, s *Striping, info routingclient.ContentInfo, strategy string, r io.Reader) (*Result, error) {
+	total := s.DataShards + s.ParityShards
+	registry.mu.RLock()
+	placement := registry.placement
+	registry.mu.RUnlock()
+	var ids []string
+	for _, id := range registry.backendIDs() {
+		exec, _ := registry.Lookup(id)
+		if placement == nil || placement.permitsExecutor(info.Metadata["bucket"], id, exec) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids)*s.MaxPerBackend < total {
+		return nil, fmt.Errorf("stripe: %d shards need %d backends at %d per backend, only %d are registered and permitted",
+			total, (total+s.MaxPerBackend-1)/s.MaxPerBackend, s.MaxPerBackend, len(ids))
+	}
+	shards, err := erasure.Encode(r, info.ContentSize, s.DataShards, s.ParityShards, s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	defer shards.Close()
+
+	m := &erasure.Manifest{
+		Version:      erasure.ManifestVersion,
+		Scheme:       erasure.Scheme,
+		DataShards:   s.DataShards,
+		ParityShards: s.ParityShards,
+		ShardSize:    shards.ShardSize,
+		Size:         shards.Size,
+		SHA256:       shards.ObjectSHA256,
+		ContentType:  info.ContentType,
+		Filename:     info.Filename,
+		Shards:       make([]erasure.Shard, total),
+		ExpiresAt:    expiry(time.Now(), info.TTL),
+	}
+
+	// Backends are chosen one shard at a time, each shard's selection
+	// limited to the backends that still have room, then the shards are
+	// uploaded together
+	used := make(map[string]int)
+	infos := make([]routingclient.ContentInfo, total)
+	execs := make([]Executor, total)
+	for i := range total {
+		infos[i] = shardInfo(info, m, shards.SHA256[i], i)
+		var open []string
+		for _, id := range ids {
+			if used[id] < s.MaxPerBackend {
+				open = append(open, id)
+			}
+		}
+		infos[i].Backends = open
+		resp, err := client.SelectBackend(ctx, infos[i], strategy)
+		if err != nil {
+			return nil, fmt.Errorf("stripe: select backend for shard %d: %w", i, err)
+		}
+		exec, ok := registry.Lookup(resp.BackendId)
+		if !ok || used[resp.BackendId] >= s.MaxPerBackend {
+			return nil, fmt.Errorf("stripe: router chose %q for shard %d, which has no room", resp.BackendId, i)
+		}
+		used[resp.BackendId]++
+		m.Shards[i] = erasure.Shard{Index: i, BackendID: resp.BackendId, SHA256: shards.SHA256[i]}
+		execs[i] = exec
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, total)
+	for i := range total {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res, err := Execute(ctx, client, execs[i], m.Shards[i].BackendID, infos[i], shards.Files[i])
+			if err != nil {
+				errs[i] = fmt.Errorf("shard %d: %w", i, err)
+				return
+			}
+			m.Shards[i].Location, m.Shards[i].CID = res.Location, res.CID
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("stripe: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	manifestInfo := routingclient.ContentInfo{
+		ContentType: erasure.ManifestContentType,
+		ContentSize: int64(len(data)),
+		Filename:    info.Filename + ".stripe.json",
+		Metadata:    map[string]string{StripeMetadataKey: m.SHA256},
+		TTL:         info.TTL,
+	}
+	if bucket := info.Metadata["bucket"]; bucket != "" {
+		manifestInfo.Metadata["bucket"] = bucket
+	}
+	res, err := run(ctx, client, registry, manifestInfo, strategy, bytes.NewReader(data), false)
+	if err != nil {
+		return nil, fmt.Errorf("stripe: store manifest: %w", err)
+	}
+	res.Manifest = m
+	return res, nil
+}
+
+// shardInfo describes shard i of the object m to the router
+func shardInfo(info routingclient.ContentInfo, m *erasure.Manifest, sha256 string, i int) routingclient.ContentInfo {
+	metadata := make(map[string]string, len(info.Metadata)+2)
+	for k, v := range info.Metadata {
+		metadata[k] = v
+	}
+	metadata[StripeMetadataKey] = m.SHA256
+	metadata[StripeIndexKey] = strconv.Itoa(i)
+	return routingclient.ContentInfo{
+		ContentType: "application/octet-stream",
+		ContentSize: m.ShardSize,
+		ContentHash: sha256,
+		Filename:    fmt.Sprintf("%s.shard%d", info.Filename, i),
+		Metadata:    metadata,
+		TTL:         info.TTL,
+	}
+}
@@ -0,0 +1,190 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// Tiering keeps tracked content in the storage class its Lifecycle calls
+// for. Every Interval it plans the transitions due and re-routes each
+// object with Run, asking for the new class, so the Registry should have
+// storage classes set. Accesses reported with Touch keep content in the
+// faster tiers.
+type Tiering struct {
+	Client    *routingclient.Client
+	Registry  *Registry
+	Lifecycle *Lifecycle
+	// Strategy is the routing strategy re-routed content is uploaded with
+	Strategy string
+	// Open reads content from where it is stored now
+	Open func(ctx context.Context, rec PlacementRecord) (io.ReadCloser, error)
+	// Remove deletes content from its old placement once it has moved,
+	// where the old backend's executor is a Deleter
+	Remove bool
+	// Interval is the time between evaluations (default 1 hour)
+	Interval time.Duration
+	// OnTransition, if set, is called with every transition attempted
+	OnTransition func(Transition)
+	// OnStep, if set, is called after every evaluation, e.g. to save
+	// Objects
+	OnStep func()
+
+	mu      sync.Mutex
+	objects map[string]*StoredObject
+	hashes  map[string]string
+}
+
+// Track adds obj to the tracked content, or replaces the object with the
+// same CID, restoring its saved access score
+func (t *Tiering) Track(obj StoredObject) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.objects == nil {
+		t.objects = make(map[string]*StoredObject)
+		t.hashes = make(map[string]string)
+	}
+	t.objects[obj.CID] = &obj
+	if obj.ContentHash != "" {
+		t.hashes[obj.ContentHash] = obj.CID
+	}
+	if obj.Score > 0 {
+		t.Lifecycle.Popularity.Seed(obj.CID, obj.Score, obj.ScoredAt)
+	}
+}
+
+// Touch records an access to tracked content by its CID or content hash
+func (t *Tiering) Touch(key string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cid, ok := t.hashes[key]; ok {
+		key = cid
+	}
+	if _, ok := t.objects[key]; ok {
+		t.Lifecycle.Popularity.Touch(key, now)
+	}
+}
+
+// Objects returns the tracked content, sorted by CID, with access scores
+// as of now
+func (t *Tiering) Objects(now time.Time) []StoredObject {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	objects := make([]StoredObject, 0, len(t.objects))
+	for _, obj := range t.objects {
+		o := *obj
+		o.Score, o.ScoredAt = t.Lifecycle.Popularity.Score(o.CID, now), now
+		objects = append(objects, o)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].CID < objects[j].CID })
+	return objects
+}
+
+// Run evaluates the tracked content every Interval until ctx ends,
+// returning ctx's error
+func (t *Tiering) Run(ctx context.Context) error {
+	interval := t.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		t.Step(ctx, time.Now())
+		if t.OnStep != nil {
+			t.OnStep()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Step carries out the transitions due at now and returns them; failed
+// ones have Error set and are retried at the next step
+func (t *Tiering) Step(ctx context.Context, now time.Time) []Transition {
+	plan := t.Lifecycle.Plan(t.Objects(now), now)
+	for i := range plan {
+		if ctx.Err() != nil {
+			return plan[:i]
+		}
+		tr := &plan[i]
+		t.mu.Lock()
+		obj := *t.objects[tr.CID]
+		t.mu.Unlock()
+
+		tr.At = now
+		tr.FromPlacement = obj.Placement
+		placement, err := t.move(ctx, obj, tr.To)
+		if placement != nil {
+			tr.ToPlacement = placement
+			obj.Class, obj.Placement = tr.To, placement
+			t.mu.Lock()
+			t.objects[obj.CID] = &obj
+			t.mu.Unlock()
+		}
+		if err != nil {
+			tr.Error = err.Error()
+		}
+		if t.OnTransition != nil {
+			t.OnTransition(*tr)
+		}
+	}
+	return plan
+}
+
+// move re-routes obj into class to. If the content moved but its old copy
+// could not be removed, both the new placement and an error are returned.
+func (t *Tiering) move(ctx context.Context, obj StoredObject, to StorageClass) (*PlacementRecord, error) {
+	if obj.Placement == nil {
+		return nil, errors.New("tiering: no placement to move the content from")
+	}
+	old := *obj.Placement
+	body, err := t.Open(ctx, old)
+	if err != nil {
+		return nil, fmt.Errorf("tiering: read %s: %w", old.Location, err)
+	}
+	defer body.Close()
+	info := routingclient.ContentInfo{
+		ContentSize: obj.Size,
+		ContentHash: obj.ContentHash,
+		Filename:    path.Base(old.Location),
+		Metadata:    map[string]string{StorageClassKey: string(to)},
+		TTL:         remaining(old.ExpiresAt, time.Now()),
+	}
+	if old.Bucket != "" {
+		info.Metadata["bucket"] = old.Bucket
+	}
+	res, err := Run(ctx, t.Client, t.Registry, info, t.Strategy, body)
+	if err != nil {
+		return nil, fmt.Errorf("tiering: %w", err)
+	}
+	rec := RecordFor(old.Bucket, res)
+	rec.ExpiresAt = old.ExpiresAt
+	if rec.Bytes == 0 {
+		rec.Bytes = obj.Size
+	}
+
+	if !t.Remove || (old.BackendID == rec.BackendID && old.Location == rec.Location) {
+		return &rec, nil
+	}
+	exec, ok := t.Registry.Lookup(old.BackendID)
+	if !ok {
+		return &rec, nil
+	}
+	if deleter, ok := exec.(Deleter); ok {
+		if err := deleter.Delete(ctx, old); err != nil {
+			return &rec, fmt.Errorf("tiering: moved, but removing the old copy failed: %w", err)
+		}
+	}
+	return &rec, nil
+}
@@ -0,0 +1,253 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+
+	"example.com/ipfs_kit_py/history"
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// arrowBatch is how many history records go in each record batch of an
+// Arrow stream
+const arrowBatch = 4096
+
+// arrowTime is the type of every time column: microseconds since the
+// epoch, UTC, as in the Parquet files
+var arrowTime = &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+
+// historySchema is the schema of exported history, the Columns in order;
+// score and success are null where they do not apply
+var historySchema = arrow.NewSchema([]arrow.Field{
+	{Name: "source", Type: arrow.BinaryTypes.String},
+	{Name: "kind", Type: arrow.BinaryTypes.String},
+	{Name: "time", Type: arrowTime},
+	{Name: "content_hash", Type: arrow.BinaryTypes.String},
+	{Name: "content_type", Type: arrow.BinaryTypes.String},
+	{Name: "filename", Type: arrow.BinaryTypes.String},
+	{Name: "size", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "backend_id", Type: arrow.BinaryTypes.String},
+	{Name: "strategy", Type: arrow.BinaryTypes.String},
+	{Name: "score", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	{Name: "reasoning", Type: arrow.BinaryTypes.String},
+	{Name: "request_id", Type: arrow.BinaryTypes.String},
+	{Name: "success", Type: arrow.FixedWidthTypes.Boolean, Nullable: true},
+	{Name: "duration_ms", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "bytes", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "error", Type: arrow.BinaryTypes.String},
+	{Name: "error_class", Type: arrow.BinaryTypes.String},
+	{Name: "location", Type: arrow.BinaryTypes.String},
+	{Name: "cid", Type: arrow.BinaryTypes.String},
+	{Name: "from", Type: arrow.BinaryTypes.String},
+}, nil)
+
+// InsightsSchema is the schema of insights as an Arrow stream: one row per
+// factor weight, backend score or backend success rate, kind being
+// "weight", "score" or "success_rate" and name the factor or backend
+var InsightsSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "time", Type: arrowTime},
+	{Name: "kind", Type: arrow.BinaryTypes.String},
+	{Name: "name", Type: arrow.BinaryTypes.String},
+	{Name: "value", Type: arrow.PrimitiveTypes.Float64},
+}, nil)
+
+// BackendStatsSchema is the schema of backend statistics as an Arrow
+// stream, one row per backend. errors is the failures by category as a
+// JSON object, as the Python ArrowRoutingInterface encodes maps.
+var BackendStatsSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "time", Type: arrowTime},
+	{Name: "backend_id", Type: arrow.BinaryTypes.String},
+	{Name: "state", Type: arrow.BinaryTypes.String},
+	{Name: "window_minutes", Type: arrow.PrimitiveTypes.Int32},
+	{Name: "requests", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "successes", Type: arrow.PrimitiveTypes.Int64},
+	{Name: "success_rate", Type: arrow.PrimitiveTypes.Float64},
+	{Name: "requests_per_minute", Type: arrow.PrimitiveTypes.Float64},
+	{Name: "p50_ms", Type: arrow.PrimitiveTypes.Float64},
+	{Name: "p90_ms", Type: arrow.PrimitiveTypes.Float64},
+	{Name: "p99_ms", Type: arrow.PrimitiveTypes.Float64},
+	{Name: "max_ms", Type: arrow.PrimitiveTypes.Float64},
+	{Name: "mean_ms", Type: arrow.PrimitiveTypes.Float64},
+	{Name: "throughput_bytes_per_second", Type: arrow.PrimitiveTypes.Float64},
+	{Name: "errors", Type: arrow.BinaryTypes.String},
+}, nil)
+
+// arrowWriter writes history records as an Arrow IPC stream, a record
+// batch every arrowBatch records
+type arrowWriter struct {
+	b *array.RecordBuilder
+	w *ipc.Writer
+	n int
+}
+
+func newArrowWriter(w io.Writer) (*arrowWriter, error) {
+	return &arrowWriter{
+		b: array.NewRecordBuilder(memory.DefaultAllocator, historySchema),
+		w: ipc.NewWriter(w, ipc.WithSchema(historySchema)),
+	}, nil
+}
+
+func (a *arrowWriter) Write(r *Record) error {
+	str := func(i int, s string) { a.b.Field(i).(*array.StringBuilder).Append(s) }
+	i64 := func(i int, v int64) { a.b.Field(i).(*array.Int64Builder).Append(v) }
+	str(0, r.Source)
+	str(1, r.Kind)
+	a.b.Field(2).(*array.TimestampBuilder).Append(arrow.Timestamp(r.Time.UnixMicro()))
+	str(3, r.ContentHash)
+	str(4, r.ContentType)
+	str(5, r.Filename)
+	i64(6, r.Size)
+	str(7, r.BackendID)
+	str(8, r.Strategy)
+	if score := a.b.Field(9).(*array.Float64Builder); r.Kind == history.KindDecision {
+		score.Append(r.Score)
+	} else {
+		score.AppendNull()
+	}
+	str(10, r.Reasoning)
+	str(11, r.RequestID)
+	if success := a.b.Field(12).(*array.BooleanBuilder); r.Kind == history.KindOutcome {
+		success.Append(r.Success)
+	} else {
+		success.AppendNull()
+	}
+	i64(13, r.DurationMS)
+	i64(14, r.Bytes)
+	str(15, r.Error)
+	str(16, r.ErrorClass)
+	str(17, r.Location)
+	str(18, r.CID)
+	str(19, r.From)
+	if a.n++; a.n == arrowBatch {
+		return a.flush()
+	}
+	return nil
+}
+
+// flush writes the records built so far as a record batch
+func (a *arrowWriter) flush() error {
+	a.n = 0
+	return writeBatch(a.w, a.b)
+}
+
+func (a *arrowWriter) Close() error {
+	defer a.b.Release()
+	if a.n > 0 {
+		if err := a.flush(); err != nil {
+			return err
+		}
+	}
+	if err := a.w.Close(); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
+
+// InsightsWriter writes insights as an Arrow IPC stream of InsightsSchema,
+// a record batch per snapshot, so a stream written in watch mode holds
+// every refresh
+type InsightsWriter struct {
+	b *array.RecordBuilder
+	w *ipc.Writer
+}
+
+// NewInsightsWriter returns an InsightsWriter to w
+func NewInsightsWriter(w io.Writer) *InsightsWriter {
+	return &InsightsWriter{
+		b: array.NewRecordBuilder(memory.DefaultAllocator, InsightsSchema),
+		w: ipc.NewWriter(w, ipc.WithSchema(InsightsSchema)),
+	}
+}
+
+// Write writes one snapshot: the factor weights, backend scores and
+// success rates taken at t, each sorted by name
+func (iw *InsightsWriter) Write(t time.Time, weights, scores, successRates map[string]float64) error {
+	at := arrow.Timestamp(t.UnixMicro())
+	add := func(kind string, values map[string]float64) {
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			iw.b.Field(0).(*array.TimestampBuilder).Append(at)
+			iw.b.Field(1).(*array.StringBuilder).Append(kind)
+			iw.b.Field(2).(*array.StringBuilder).Append(name)
+			iw.b.Field(3).(*array.Float64Builder).Append(values[name])
+		}
+	}
+	add("weight", weights)
+	add("score", scores)
+	add("success_rate", successRates)
+	return writeBatch(iw.w, iw.b)
+}
+
+// Close ends the stream, without closing the io.Writer it writes to
+func (iw *InsightsWriter) Close() error {
+	iw.b.Release()
+	if err := iw.w.Close(); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
+
+// WriteBackendStats writes stats as an Arrow IPC stream of
+// BackendStatsSchema holding a single record batch
+func WriteBackendStats(w io.Writer, stats ...*pb.BackendStats) error {
+	b := array.NewRecordBuilder(memory.DefaultAllocator, BackendStatsSchema)
+	defer b.Release()
+	f64 := func(i int, v float64) { b.Field(i).(*array.Float64Builder).Append(v) }
+	for _, s := range stats {
+		errs, err := json.Marshal(s.Errors)
+		if err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+		at := time.Now()
+		if ts := s.GetTimestamp(); ts != nil {
+			at = ts.AsTime()
+		}
+		lat := s.GetLatency()
+		b.Field(0).(*array.TimestampBuilder).Append(arrow.Timestamp(at.UnixMicro()))
+		b.Field(1).(*array.StringBuilder).Append(s.BackendId)
+		b.Field(2).(*array.StringBuilder).Append(s.State.String())
+		b.Field(3).(*array.Int32Builder).Append(s.WindowMinutes)
+		b.Field(4).(*array.Int64Builder).Append(s.Requests)
+		b.Field(5).(*array.Int64Builder).Append(s.Successes)
+		f64(6, s.SuccessRate)
+		f64(7, s.RequestsPerMinute)
+		f64(8, lat.GetP50Ms())
+		f64(9, lat.GetP90Ms())
+		f64(10, lat.GetP99Ms())
+		f64(11, lat.GetMaxMs())
+		f64(12, lat.GetMeanMs())
+		f64(13, s.ThroughputBytesPerSecond)
+		b.Field(14).(*array.StringBuilder).Append(string(errs))
+	}
+	iw := ipc.NewWriter(w, ipc.WithSchema(BackendStatsSchema))
+	if err := writeBatch(iw, b); err != nil {
+		return err
+	}
+	if err := iw.Close(); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
+
+// writeBatch writes the rows b has built as a record batch, leaving b
+// empty for the next
+func writeBatch(w *ipc.Writer, b *array.RecordBuilder) error {
+	rec := b.NewRecord()
+	defer rec.Release()
+	if err := w.Write(rec); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
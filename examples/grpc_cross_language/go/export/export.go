@@ -0,0 +1,272 @@
+// Package export writes routing decision and outcome history as CSV,
+// Parquet or Apache Arrow IPC streams for analysis in pandas or DuckDB.
+// Records come from the local history store (package history) and from the
+// service's own history, paged through with routingclient.Client.History;
+// both become rows with the same columns. Insights and backend statistics
+// can be written as Arrow streams too, for pipelines that read them
+// without copying.
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xitongsys/parquet-go/writer"
+
+	"example.com/ipfs_kit_py/history"
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// Formats export writes
+const (
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+	FormatArrow   = "arrow"
+)
+
+// Sources of records
+const (
+	SourceLocal  = "local"
+	SourceServer = "server"
+)
+
+// Record is one exported row. Fields that do not apply to its Kind are
+// left empty.
+type Record struct {
+	// Source is SourceLocal or SourceServer
+	Source string
+	// Kind is "decision", "outcome" or "placement", as in package history
+	Kind        string
+	Time        time.Time
+	ContentHash string
+	ContentType string
+	Filename    string
+	Size        int64
+	BackendID   string
+
+	// Decisions
+	Strategy  string
+	Score     float64
+	Reasoning string
+	RequestID string
+
+	// Outcomes
+	Success    bool
+	DurationMS int64
+	Bytes      int64
+	Error      string
+	ErrorClass string
+
+	// Placements
+	Location string
+	CID      string
+	From     string
+}
+
+// Columns are the names of a Record's columns, in order, as the CSV
+// header and the Parquet and Arrow schemas name them
+var Columns = []string{
+	"source", "kind", "time", "content_hash", "content_type", "filename", "size", "backend_id",
+	"strategy", "score", "reasoning", "request_id",
+	"success", "duration_ms", "bytes", "error", "error_class",
+	"location", "cid", "from",
+}
+
+// FromEntry converts an entry of the local history store
+func FromEntry(e *history.Entry) *Record {
+	return &Record{
+		Source:      SourceLocal,
+		Kind:        e.Kind,
+		Time:        e.Time,
+		ContentHash: e.ContentHash,
+		ContentType: e.ContentType,
+		Filename:    e.Filename,
+		Size:        e.Size,
+		BackendID:   e.BackendID,
+		Strategy:    e.Strategy,
+		Score:       e.Score,
+		Reasoning:   e.Reasoning,
+		RequestID:   e.RequestID,
+		Success:     e.Success,
+		DurationMS:  e.DurationMS,
+		Bytes:       e.Bytes,
+		Error:       e.Error,
+		Location:    e.Location,
+		CID:         e.CID,
+		From:        e.From,
+	}
+}
+
+// FromHistoryRecord converts a record of the service's history
+func FromHistoryRecord(r *pb.HistoryRecord) *Record {
+	kind := history.KindDecision
+	if r.Kind == pb.HistoryRecordKind_HISTORY_RECORD_KIND_OUTCOME {
+		kind = history.KindOutcome
+	}
+	return &Record{
+		Source:      SourceServer,
+		Kind:        kind,
+		Time:        r.GetTimestamp().AsTime(),
+		ContentHash: r.ContentHash,
+		ContentType: r.ContentType,
+		Size:        r.ContentSize,
+		BackendID:   r.BackendId,
+		Strategy:    r.Strategy,
+		Score:       r.Score,
+		Reasoning:   r.Reasoning,
+		RequestID:   r.RequestId,
+		Success:     r.Success,
+		DurationMS:  int64(r.DurationMs),
+		Bytes:       r.BytesTransferred,
+		Error:       r.Error,
+		ErrorClass:  r.ErrorClass,
+	}
+}
+
+// Writer writes records in one format. Close finishes the file, without
+// closing the io.Writer it writes to; a Parquet file is unreadable until
+// then.
+type Writer interface {
+	Write(r *Record) error
+	Close() error
+}
+
+// NewWriter returns a Writer of format, FormatCSV, FormatParquet or
+// FormatArrow, to w
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatParquet:
+		return newParquetWriter(w)
+	case FormatArrow:
+		return newArrowWriter(w)
+	}
+	return nil, fmt.Errorf("export: unknown format %q (want %s, %s or %s)", format, FormatCSV, FormatParquet, FormatArrow)
+}
+
+// csvWriter writes records as CSV with a header row. Times are RFC 3339
+// in UTC.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	if err := cw.w.Write(Columns); err != nil {
+		return nil, fmt.Errorf("export: %w", err)
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) Write(r *Record) error {
+	score := ""
+	if r.Kind == history.KindDecision {
+		score = strconv.FormatFloat(r.Score, 'f', -1, 64)
+	}
+	success := ""
+	if r.Kind == history.KindOutcome {
+		success = strconv.FormatBool(r.Success)
+	}
+	err := cw.w.Write([]string{
+		r.Source, r.Kind, r.Time.UTC().Format(time.RFC3339Nano),
+		r.ContentHash, r.ContentType, r.Filename, strconv.FormatInt(r.Size, 10), r.BackendID,
+		r.Strategy, score, r.Reasoning, r.RequestID,
+		success, strconv.FormatInt(r.DurationMS, 10), strconv.FormatInt(r.Bytes, 10), r.Error, r.ErrorClass,
+		r.Location, r.CID, r.From,
+	})
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	if err := cw.w.Error(); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
+
+// parquetRow is a Record as a Parquet row. Times are microseconds since
+// the epoch, UTC; score and success are null where they do not apply.
+type parquetRow struct {
+	Source      string   `parquet:"name=source, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Kind        string   `parquet:"name=kind, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Time        int64    `parquet:"name=time, type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS"`
+	ContentHash string   `parquet:"name=content_hash, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ContentType string   `parquet:"name=content_type, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Filename    string   `parquet:"name=filename, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Size        int64    `parquet:"name=size, type=INT64"`
+	BackendID   string   `parquet:"name=backend_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Strategy    string   `parquet:"name=strategy, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Score       *float64 `parquet:"name=score, type=DOUBLE, repetitiontype=OPTIONAL"`
+	Reasoning   string   `parquet:"name=reasoning, type=BYTE_ARRAY, convertedtype=UTF8"`
+	RequestID   string   `parquet:"name=request_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Success     *bool    `parquet:"name=success, type=BOOLEAN, repetitiontype=OPTIONAL"`
+	DurationMS  int64    `parquet:"name=duration_ms, type=INT64"`
+	Bytes       int64    `parquet:"name=bytes, type=INT64"`
+	Error       string   `parquet:"name=error, type=BYTE_ARRAY, convertedtype=UTF8"`
+	ErrorClass  string   `parquet:"name=error_class, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Location    string   `parquet:"name=location, type=BYTE_ARRAY, convertedtype=UTF8"`
+	CID         string   `parquet:"name=cid, type=BYTE_ARRAY, convertedtype=UTF8"`
+	From        string   `parquet:"name=from, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// parquetWriter writes records as a Snappy-compressed Parquet file
+type parquetWriter struct {
+	pw *writer.ParquetWriter
+}
+
+func newParquetWriter(w io.Writer) (*parquetWriter, error) {
+	pw, err := writer.NewParquetWriterFromWriter(w, new(parquetRow), 1)
+	if err != nil {
+		return nil, fmt.Errorf("export: %w", err)
+	}
+	return &parquetWriter{pw: pw}, nil
+}
+
+func (p *parquetWriter) Write(r *Record) error {
+	row := parquetRow{
+		Source:      r.Source,
+		Kind:        r.Kind,
+		Time:        r.Time.UnixMicro(),
+		ContentHash: r.ContentHash,
+		ContentType: r.ContentType,
+		Filename:    r.Filename,
+		Size:        r.Size,
+		BackendID:   r.BackendID,
+		Strategy:    r.Strategy,
+		Reasoning:   r.Reasoning,
+		RequestID:   r.RequestID,
+		DurationMS:  r.DurationMS,
+		Bytes:       r.Bytes,
+		Error:       r.Error,
+		ErrorClass:  r.ErrorClass,
+		Location:    r.Location,
+		CID:         r.CID,
+		From:        r.From,
+	}
+	if r.Kind == history.KindDecision {
+		row.Score = &r.Score
+	}
+	if r.Kind == history.KindOutcome {
+		row.Success = &r.Success
+	}
+	if err := p.pw.Write(row); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
+
+func (p *parquetWriter) Close() error {
+	if err := p.pw.WriteStop(); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
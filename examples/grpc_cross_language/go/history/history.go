@@ -0,0 +1,343 @@
+// Package history keeps a local, embedded record of the routing decisions,
+// outcomes and placements a client has made, so an agent keeps that state
+// across restarts and can answer where a piece of content went without
+// asking the routing service. Entries are kept in a bbolt database and
+// indexed by content hash, CID, file name and location.
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"example.com/ipfs_kit_py/executor"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// Env names the history database routing-cli uses by default
+const Env = "ROUTING_HISTORY"
+
+// DefaultPath returns $ROUTING_HISTORY, or routing-history.db in the
+// user's ipfs_kit_py configuration directory
+func DefaultPath() (string, error) {
+	if p := os.Getenv(Env); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("history: path: %w", err)
+	}
+	return filepath.Join(dir, "ipfs_kit_py", "routing-history.db"), nil
+}
+
+// Kinds of entry
+const (
+	KindDecision  = "decision"
+	KindOutcome   = "outcome"
+	KindPlacement = "placement"
+)
+
+// Entry is one recorded event. Fields that do not apply to its Kind are
+// left empty.
+type Entry struct {
+	// Seq orders entries; it is set by Add
+	Seq  uint64    `json:"seq"`
+	Kind string    `json:"kind"`
+	Time time.Time `json:"time"`
+
+	ContentHash string `json:"content_hash,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Filename    string `json:"filename,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	BackendID   string `json:"backend_id"`
+
+	// Decisions
+	Strategy  string  `json:"strategy,omitempty"`
+	Score     float64 `json:"score,omitempty"`
+	Reasoning string  `json:"reasoning,omitempty"`
+	RequestID string  `json:"request_id,omitempty"`
+
+	// Outcomes
+	Success    bool   `json:"success,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+	Bytes      int64  `json:"bytes,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	// Placements
+	Location  string     `json:"location,omitempty"`
+	CID       string     `json:"cid,omitempty"`
+	Provider  string     `json:"provider,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// From is the backend a migrated placement was moved from
+	From string `json:"from,omitempty"`
+	// Manifest is the stripe or replication manifest of a striped or
+	// replicated upload; the rest of the entry says where it was stored
+	Manifest json.RawMessage `json:"manifest,omitempty"`
+}
+
+// terms returns the values the entry is found by in a query for content
+func (e *Entry) terms() []string {
+	terms := []string{e.ContentHash, e.CID, e.Location, e.Filename}
+	if base := filepath.Base(e.Filename); base != e.Filename {
+		terms = append(terms, base)
+	}
+	seen := make(map[string]bool)
+	out := terms[:0]
+	for _, t := range terms {
+		if t != "" && t != "." && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+var (
+	entriesBucket = []byte("entries")
+	// the index maps term, NUL, sequence number to nothing
+	indexBucket = []byte("index")
+)
+
+// Store is a history database. Only one process may have it open; others
+// wait up to a second for it and then fail.
+type Store struct {
+	db *bolt.DB
+	// OnError, if set, is told of entries the interceptor and
+	// LogPlacement could not write, which are otherwise dropped
+	OnError func(error)
+}
+
+// Open opens the database at path, creating it if needed
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("history: %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{entriesBucket, indexBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("history: %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// seqKey encodes a sequence number so keys sort in order
+func seqKey(seq uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, seq)
+}
+
+// indexKey is the index key of term for the entry numbered seq
+func indexKey(term string, seq uint64) []byte {
+	return append(append([]byte(term), 0), seqKey(seq)...)
+}
+
+// Add records entries, setting their Seq and, if unset, Time
+func (s *Store) Add(entries ...*Entry) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, idx := tx.Bucket(entriesBucket), tx.Bucket(indexBucket)
+		for _, e := range entries {
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			e.Seq = seq
+			if e.Time.IsZero() {
+				e.Time = time.Now().UTC()
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(seqKey(seq), data); err != nil {
+				return err
+			}
+			for _, term := range e.terms() {
+				if err := idx.Put(indexKey(term, seq), nil); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	return nil
+}
+
+// add records entries, reporting a failure to OnError
+func (s *Store) add(entries ...*Entry) {
+	if err := s.Add(entries...); err != nil && s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// Query selects entries; zero fields match everything
+type Query struct {
+	// Content is a content hash, CID, file name or location
+	Content   string
+	Kind      string
+	BackendID string
+	Since     time.Time
+	Until     time.Time
+	// Limit caps how many entries are returned, the most recent first
+	Limit int
+}
+
+func (q *Query) match(e *Entry) bool {
+	switch {
+	case q.Kind != "" && e.Kind != q.Kind,
+		q.BackendID != "" && e.BackendID != q.BackendID && e.From != q.BackendID,
+		!q.Since.IsZero() && e.Time.Before(q.Since),
+		!q.Until.IsZero() && !e.Time.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// Find returns the entries matching q, the most recent first
+func (s *Store) Find(q Query) ([]Entry, error) {
+	var out []Entry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(entriesBucket)
+		visit := func(data []byte) (bool, error) {
+			var e Entry
+			if err := json.Unmarshal(data, &e); err != nil {
+				return false, err
+			}
+			if q.match(&e) {
+				out = append(out, e)
+			}
+			return q.Limit > 0 && len(out) >= q.Limit, nil
+		}
+		if q.Content == "" {
+			c := b.Cursor()
+			for k, v := c.Last(); k != nil; k, v = c.Prev() {
+				if done, err := visit(v); done || err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		// the index keys of a term end with its entries' sequence
+		// numbers, so walking back from the term's end visits them
+		// newest first
+		prefix := append([]byte(q.Content), 0)
+		c := tx.Bucket(indexBucket).Cursor()
+		k, _ := c.Seek(indexKey(q.Content, 1<<64-1))
+		if k == nil {
+			k, _ = c.Last()
+		} else {
+			k, _ = c.Prev()
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix) && len(k) == len(prefix)+8; k, _ = c.Prev() {
+			if done, err := visit(b.Get(k[len(prefix):])); done || err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	return out, nil
+}
+
+// Where returns the placements of content, the most recent first, as
+// routing-cli history where prints them: content is a content hash, CID,
+// file name or location
+func (s *Store) Where(content string) ([]Entry, error) {
+	return s.Find(Query{Content: content, Kind: KindPlacement})
+}
+
+// Prune removes the entries recorded before t and returns how many it
+// removed
+func (s *Store) Prune(t time.Time) (int, error) {
+	n := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, idx := tx.Bucket(entriesBucket), tx.Bucket(indexBucket)
+		var old []Entry
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var e Entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if !e.Time.Before(t) {
+				// entries are added in time order
+				break
+			}
+			old = append(old, e)
+		}
+		// deleting under a cursor skips keys, so entries are deleted
+		// once found
+		for _, e := range old {
+			for _, term := range e.terms() {
+				if err := idx.Delete(indexKey(term, e.Seq)); err != nil {
+					return err
+				}
+			}
+			if err := b.Delete(seqKey(e.Seq)); err != nil {
+				return err
+			}
+		}
+		n = len(old)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("history: %w", err)
+	}
+	return n, nil
+}
+
+// LogPlacement records where an upload was stored; see
+// executor.WithPlacementLog
+func (s *Store) LogPlacement(info routingclient.ContentInfo, result *executor.Result) {
+	hash, _ := info.Hash()
+	e := &Entry{
+		Kind:        KindPlacement,
+		ContentHash: hash,
+		ContentType: info.ContentType,
+		Filename:    info.Filename,
+		Size:        info.ContentSize,
+		BackendID:   result.BackendID,
+		Bytes:       result.Bytes,
+		Location:    result.Location,
+		CID:         result.CID,
+		Provider:    result.Provider,
+		ExpiresAt:   result.ExpiresAt,
+		From:        info.Metadata[executor.MigratedFromKey],
+	}
+	var manifest any
+	switch {
+	case result.Manifest != nil:
+		manifest = result.Manifest
+	case result.Replication != nil:
+		manifest = result.Replication
+	}
+	if manifest != nil {
+		e.Manifest, _ = json.Marshal(manifest)
+	}
+	s.add(e)
+}
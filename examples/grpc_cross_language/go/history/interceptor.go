@@ -0,0 +1,71 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// Interceptor returns a client interceptor that records every successful
+// backend selection and every outcome sent in s, e.g. for
+// routingclient.WithInterceptor. Dry runs are not recorded. Entries that
+// cannot be written go to s.OnError and do not fail the call.
+func Interceptor(s *Store) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+			return err
+		}
+		switch method {
+		case pb.RoutingService_SelectBackend_FullMethodName:
+			if resp := reply.(*pb.SelectBackendResponse); !resp.DryRun {
+				s.add(decisionEntry(req.(*pb.SelectBackendRequest), resp))
+			}
+		case pb.RoutingService_RecordOutcome_FullMethodName:
+			s.add(outcomeEntry(req.(*pb.RecordOutcomeRequest)))
+		case pb.RoutingService_RecordOutcomes_FullMethodName:
+			var entries []*Entry
+			for _, o := range req.(*pb.RecordOutcomesRequest).Outcomes {
+				entries = append(entries, outcomeEntry(o))
+			}
+			s.add(entries...)
+		}
+		return nil
+	}
+}
+
+func decisionEntry(req *pb.SelectBackendRequest, resp *pb.SelectBackendResponse) *Entry {
+	e := &Entry{
+		Kind:        KindDecision,
+		Time:        time.Now().UTC(),
+		ContentHash: req.ContentHash,
+		ContentType: req.ContentType,
+		Size:        req.ContentSize,
+		BackendID:   resp.BackendId,
+		Strategy:    req.Strategy,
+		Score:       resp.Score,
+		Reasoning:   resp.Reasoning,
+		RequestID:   resp.RequestId,
+	}
+	if name := req.GetMetadata().GetFields()["filename"]; name != nil {
+		e.Filename = name.GetStringValue()
+	}
+	return e
+}
+
+func outcomeEntry(o *pb.RecordOutcomeRequest) *Entry {
+	return &Entry{
+		Kind:        KindOutcome,
+		Time:        time.Now().UTC(),
+		ContentHash: o.ContentHash,
+		ContentType: o.ContentType,
+		Size:        o.ContentSize,
+		BackendID:   o.BackendId,
+		Success:     o.Success,
+		DurationMS:  int64(o.DurationMs),
+		Bytes:       o.BytesTransferred,
+		Error:       o.Error,
+	}
+}
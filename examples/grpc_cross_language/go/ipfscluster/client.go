@@ -0,0 +1,164 @@
+// Package ipfscluster is a minimal client for the IPFS Cluster REST API
+// covering pinning with a replication factor and reading back where the
+// cluster allocated each pin.
+package ipfscluster
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultAPIURL is the default address of the cluster REST API
+const DefaultAPIURL = "http://127.0.0.1:9094"
+
+// Error is an error returned by the cluster REST API
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("ipfscluster: %s (code %d)", e.Message, e.Code)
+}
+
+// Client calls the IPFS Cluster REST API over HTTP
+type Client struct {
+	apiURL   string
+	username string
+	password string
+	http     *http.Client
+}
+
+// NewClient creates a client for the REST API at apiURL, falling back to
+// $IPFS_CLUSTER_API_URL and then DefaultAPIURL. Basic auth credentials are
+// taken from the URL's user info if present.
+func NewClient(apiURL string) *Client {
+	if apiURL == "" {
+		apiURL = os.Getenv("IPFS_CLUSTER_API_URL")
+	}
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	c := &Client{apiURL: strings.TrimRight(apiURL, "/"), http: http.DefaultClient}
+	if u, err := url.Parse(c.apiURL); err == nil && u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+		u.User = nil
+		c.apiURL = u.String()
+	}
+	return c
+}
+
+// do sends a request to path and decodes the JSON response into out (if
+// non-nil)
+func (c *Client) do(ctx context.Context, method, path string, args url.Values, out interface{}) error {
+	body, err := c.send(ctx, method, path, args)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if out == nil {
+		_, err = io.Copy(io.Discard, body)
+		return err
+	}
+	if err := json.NewDecoder(body).Decode(out); err != nil {
+		return fmt.Errorf("ipfscluster: %s %s: decode response: %w", method, path, err)
+	}
+	return nil
+}
+
+// stream sends a GET request to path and calls fn with each item of the
+// response. Cluster 1.0 streams items as newline-delimited JSON; older
+// versions return a JSON array.
+func (c *Client) stream(ctx context.Context, path string, args url.Values, fn func(json.RawMessage) error) error {
+	body, err := c.send(ctx, http.MethodGet, path, args)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	br := bufio.NewReader(body)
+	dec := json.NewDecoder(br)
+	if first, err := peekNonSpace(br); err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	} else if first == '[' {
+		var items []json.RawMessage
+		if err := dec.Decode(&items); err != nil {
+			return fmt.Errorf("ipfscluster: GET %s: decode response: %w", path, err)
+		}
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for {
+		var item json.RawMessage
+		if err := dec.Decode(&item); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("ipfscluster: GET %s: decode response: %w", path, err)
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+}
+
+// send sends a request and returns the body of a successful response. The
+// caller must close it.
+func (c *Client) send(ctx context.Context, method, path string, args url.Values) (io.ReadCloser, error) {
+	u := c.apiURL + path
+	if len(args) > 0 {
+		u += "?" + args.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ipfscluster: %s %s: %w", method, path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		e := Error{Code: resp.StatusCode}
+		if json.Unmarshal(data, &e) == nil && e.Message != "" {
+			return nil, &e
+		}
+		e.Message = fmt.Sprintf("%s %s: HTTP %s: %s", method, path, resp.Status, bytes.TrimSpace(data))
+		return nil, &e
+	}
+	return resp.Body, nil
+}
+
+// peekNonSpace returns the first non-whitespace byte of br without
+// consuming it
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			br.ReadByte()
+			continue
+		}
+		return b[0], nil
+	}
+}
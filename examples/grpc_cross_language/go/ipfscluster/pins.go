@@ -0,0 +1,167 @@
+package ipfscluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// CID is a CID as the cluster API returns it: a string since cluster 1.0,
+// {"/": "..."} before
+type CID string
+
+// UnmarshalJSON accepts both forms
+func (c *CID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*c = CID(s)
+		return nil
+	}
+	var link struct {
+		Root string `json:"/"`
+	}
+	if err := json.Unmarshal(data, &link); err != nil {
+		return fmt.Errorf("ipfscluster: invalid CID %s", data)
+	}
+	*c = CID(link.Root)
+	return nil
+}
+
+// PinOptions controls Pin. Zero replication factors use the cluster's
+// defaults and -1 pins on every peer.
+type PinOptions struct {
+	ReplicationMin int
+	ReplicationMax int
+	Name           string
+}
+
+// Pin is a pin in the cluster's shared state
+type Pin struct {
+	CID  CID    `json:"cid"`
+	Name string `json:"name"`
+	// Allocations are the IDs of the peers the cluster chose to pin on;
+	// empty when the pin is replicated everywhere
+	Allocations    []string `json:"allocations"`
+	ReplicationMin int      `json:"replication_factor_min"`
+	ReplicationMax int      `json:"replication_factor_max"`
+	MaxDepth       int      `json:"max_depth"`
+	// Mode is "recursive" or "direct"; older clusters only set MaxDepth
+	Mode string `json:"mode"`
+}
+
+// PinMode returns Mode, deriving it from MaxDepth when unset
+func (p *Pin) PinMode() string {
+	if p.Mode != "" {
+		return p.Mode
+	}
+	if p.MaxDepth == 0 {
+		return "direct"
+	}
+	return "recursive"
+}
+
+// PeerStatus is the state of a pin on one cluster peer
+type PeerStatus struct {
+	PeerName string `json:"peername"`
+	// Status is e.g. "pinned", "pinning", "queued" or "pin_error"
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// PinStatus is the state of a pin across the cluster
+type PinStatus struct {
+	CID         CID                   `json:"cid"`
+	Name        string                `json:"name"`
+	Allocations []string              `json:"allocations"`
+	PeerMap     map[string]PeerStatus `json:"peer_map"`
+}
+
+// Peer is a cluster peer
+type Peer struct {
+	ID       string `json:"id"`
+	PeerName string `json:"peername"`
+	Error    string `json:"error"`
+}
+
+// Pin pins cid in the cluster with the requested replication factor and
+// returns the pin, including the peers it was allocated to
+func (c *Client) Pin(ctx context.Context, cid string, opts PinOptions) (*Pin, error) {
+	args := url.Values{}
+	if opts.ReplicationMin != 0 {
+		args.Set("replication-min", fmt.Sprint(opts.ReplicationMin))
+	}
+	if opts.ReplicationMax != 0 {
+		args.Set("replication-max", fmt.Sprint(opts.ReplicationMax))
+	}
+	if opts.Name != "" {
+		args.Set("name", opts.Name)
+	}
+	var out Pin
+	if err := c.do(ctx, http.MethodPost, "/pins/"+url.PathEscape(cid), args, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Unpin removes cid from the cluster's pinset
+func (c *Client) Unpin(ctx context.Context, cid string) error {
+	return c.do(ctx, http.MethodDelete, "/pins/"+url.PathEscape(cid), nil, nil)
+}
+
+// Allocation returns the pin for cid from the cluster's shared state
+func (c *Client) Allocation(ctx context.Context, cid string) (*Pin, error) {
+	var out Pin
+	if err := c.do(ctx, http.MethodGet, "/allocations/"+url.PathEscape(cid), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Allocations returns every pin in the cluster's shared state
+func (c *Client) Allocations(ctx context.Context) ([]Pin, error) {
+	var pins []Pin
+	err := c.stream(ctx, "/allocations", url.Values{"filter": {"pin"}}, func(item json.RawMessage) error {
+		var p Pin
+		if err := json.Unmarshal(item, &p); err != nil {
+			return fmt.Errorf("ipfscluster: decode pin: %w", err)
+		}
+		pins = append(pins, p)
+		return nil
+	})
+	return pins, err
+}
+
+// Status returns the state of cid on each peer it is allocated to
+func (c *Client) Status(ctx context.Context, cid string) (*PinStatus, error) {
+	var out PinStatus
+	if err := c.do(ctx, http.MethodGet, "/pins/"+url.PathEscape(cid), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Peers returns the cluster's peers, sorted by ID
+func (c *Client) Peers(ctx context.Context) ([]Peer, error) {
+	var peers []Peer
+	err := c.stream(ctx, "/peers", nil, func(item json.RawMessage) error {
+		var p Peer
+		if err := json.Unmarshal(item, &p); err != nil {
+			return fmt.Errorf("ipfscluster: decode peer: %w", err)
+		}
+		peers = append(peers, p)
+		return nil
+	})
+	sort.Slice(peers, func(i, j int) bool { return peers[i].ID < peers[j].ID })
+	return peers, err
+}
+
+// IsNotFound reports whether err is the cluster saying the CID is not
+// pinned
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == http.StatusNotFound
+}
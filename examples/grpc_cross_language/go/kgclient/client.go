@@ -0,0 +1,200 @@
+// Package kgclient is a Go client for the IPLD knowledge graph HTTP API
+// (ipfs_kit_py/knowledge_graph_http.py).
+//
+// Entities and relationships are stored as IPLD nodes; every change produces
+// a new CID, which is returned alongside the data so callers can pin or
+// fetch exact versions with GetNode.
+package kgclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultBaseURL is the default address of the knowledge graph API
+const DefaultBaseURL = "http://localhost:8090"
+
+// TokenEnv names the environment variable holding the API's token, which
+// writes need when the server has one
+const TokenEnv = "KG_API_TOKEN"
+
+// ErrNotFound is returned when an entity, relationship or node does not exist
+var ErrNotFound = errors.New("kg: not found")
+
+// ErrExists is returned when adding an entity or relationship that exists
+var ErrExists = errors.New("kg: already exists")
+
+// Entity is a knowledge graph entity
+type Entity struct {
+	ID            string                 `json:"id"`
+	Type          string                 `json:"type"`
+	Properties    map[string]interface{} `json:"properties"`
+	Relationships []string               `json:"relationships,omitempty"`
+	Vector        []float32              `json:"vector,omitempty"`
+	CreatedAt     float64                `json:"created_at,omitempty"`
+	UpdatedAt     float64                `json:"updated_at,omitempty"`
+}
+
+// Relationship is a directed, typed edge between two entities
+type Relationship struct {
+	ID         string                 `json:"id,omitempty"`
+	From       string                 `json:"from"`
+	To         string                 `json:"to"`
+	Type       string                 `json:"type"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	CreatedAt  float64                `json:"created_at,omitempty"`
+}
+
+// Client calls the knowledge graph HTTP API
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient creates a client for the API at baseURL (DefaultBaseURL if
+// empty)
+func NewClient(baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), http: http.DefaultClient}
+}
+
+// SetToken sends token as a bearer token on every request. The server
+// requires it for writes once it has a token, and refuses writes on a
+// non-loopback address without one.
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("kg: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &e) != nil || e.Error == "" {
+			e.Error = string(bytes.TrimSpace(data))
+		}
+		switch resp.StatusCode {
+		case http.StatusNotFound:
+			return fmt.Errorf("%w: %s", ErrNotFound, e.Error)
+		case http.StatusConflict:
+			return fmt.Errorf("%w: %s", ErrExists, e.Error)
+		}
+		return fmt.Errorf("kg: %s %s: HTTP %s: %s", method, path, resp.Status, e.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("kg: %s %s: decode response: %w", method, path, err)
+	}
+	return nil
+}
+
+// AddEntity adds e to the graph and returns the CID of the stored node
+func (c *Client) AddEntity(ctx context.Context, e Entity) (string, error) {
+	var out struct {
+		CID string `json:"cid"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/kg/entities", e, &out); err != nil {
+		return "", err
+	}
+	return out.CID, nil
+}
+
+// GetEntity returns the current version of an entity and its CID
+func (c *Client) GetEntity(ctx context.Context, id string) (*Entity, string, error) {
+	var out struct {
+		Entity Entity `json:"entity"`
+		CID    string `json:"cid"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/kg/entities/"+url.PathEscape(id), nil, &out); err != nil {
+		return nil, "", err
+	}
+	return &out.Entity, out.CID, nil
+}
+
+// AddRelationship links two existing entities. It returns the relationship
+// ID ("from:type:to") and the CID of the stored node.
+func (c *Client) AddRelationship(ctx context.Context, r Relationship) (id, cid string, err error) {
+	var out struct {
+		RelationshipID string `json:"relationship_id"`
+		CID            string `json:"cid"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/kg/relationships", r, &out); err != nil {
+		return "", "", err
+	}
+	return out.RelationshipID, out.CID, nil
+}
+
+// GetRelationship returns a relationship by ID and its CID
+func (c *Client) GetRelationship(ctx context.Context, id string) (*Relationship, string, error) {
+	var out struct {
+		Relationship Relationship `json:"relationship"`
+		CID          string       `json:"cid"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/kg/relationships/"+url.PathEscape(id), nil, &out); err != nil {
+		return nil, "", err
+	}
+	return &out.Relationship, out.CID, nil
+}
+
+// GetNode fetches the graph node stored at cid and decodes it into out,
+// which is typically an *Entity or *Relationship
+func (c *Client) GetNode(ctx context.Context, cid string, out interface{}) error {
+	var resp struct {
+		Node json.RawMessage `json:"node"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/kg/nodes/"+url.PathEscape(cid), nil, &resp); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(resp.Node, out); err != nil {
+		return fmt.Errorf("kg: node %s: %w", cid, err)
+	}
+	return nil
+}
+
+// Stats returns the graph statistics reported by the server
+func (c *Client) Stats(ctx context.Context) (map[string]interface{}, error) {
+	var out struct {
+		Stats map[string]interface{} `json:"stats"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/kg/stats", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Stats, nil
+}
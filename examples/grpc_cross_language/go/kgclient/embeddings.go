@@ -0,0 +1,63 @@
+package kgclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Embedding attaches a vector to stored content. The server keeps it on a
+// "content" entity with ID "content:<CID>", so VectorSearch results report
+// the content in VectorMatch.ContentCID.
+type Embedding struct {
+	CID        string                 `json:"cid"`
+	Vector     []float32              `json:"vector"`
+	Model      string                 `json:"model"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// EmbeddingResult is the outcome of ingesting one Embedding
+type EmbeddingResult struct {
+	CID      string `json:"cid"`
+	EntityID string `json:"entity_id"`
+	// NodeCID is the graph node written for the entity
+	NodeCID string `json:"node_cid"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// AddEmbedding stores e, replacing any earlier vector for the same content.
+// It returns the entity ID and the CID of the stored node.
+func (c *Client) AddEmbedding(ctx context.Context, e Embedding) (entityID, nodeCID string, err error) {
+	results, err := c.AddEmbeddings(ctx, []Embedding{e})
+	if err != nil {
+		return "", "", err
+	}
+	r := results[0]
+	if !r.Success {
+		return "", "", fmt.Errorf("kg: embedding %s: %s", r.CID, r.Error)
+	}
+	return r.EntityID, r.NodeCID, nil
+}
+
+// AddEmbeddings stores a batch of embeddings in one request. All vectors
+// must match the index dimension. Per-item failures are reported in the
+// results rather than as an error.
+func (c *Client) AddEmbeddings(ctx context.Context, embeddings []Embedding) ([]EmbeddingResult, error) {
+	if len(embeddings) == 0 {
+		return nil, nil
+	}
+	req := struct {
+		Embeddings []Embedding `json:"embeddings"`
+	}{embeddings}
+	var out struct {
+		Results []EmbeddingResult `json:"results"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/kg/embeddings", req, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Results) != len(embeddings) {
+		return nil, fmt.Errorf("kg: embeddings: got %d results for %d items", len(out.Results), len(embeddings))
+	}
+	return out.Results, nil
+}
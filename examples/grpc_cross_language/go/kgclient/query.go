@@ -0,0 +1,133 @@
+package kgclient
+
+import (
+	"context"
+	"net/http"
+)
+
+// Direction selects which relationships a query follows
+type Direction string
+
+const (
+	Outgoing Direction = "outgoing"
+	Incoming Direction = "incoming"
+	Both     Direction = "both"
+)
+
+// Neighbor is an entity directly related to the queried entity
+type Neighbor struct {
+	EntityID         string                 `json:"entity_id"`
+	RelationshipID   string                 `json:"relationship_id"`
+	RelationshipType string                 `json:"relationship_type"`
+	Direction        Direction              `json:"direction"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+// TraversalNode is an entity reached by Traverse. Parent and
+// RelationshipID describe the edge it was first reached through; both are
+// empty for the start entity.
+type TraversalNode struct {
+	EntityID         string `json:"entity_id"`
+	Depth            int    `json:"depth"`
+	Parent           string `json:"parent,omitempty"`
+	RelationshipID   string `json:"relationship_id,omitempty"`
+	RelationshipType string `json:"relationship_type,omitempty"`
+}
+
+// PathStep is one hop of a path: an entity and the relationship leaving it
+// (empty on the final step)
+type PathStep struct {
+	EntityID       string `json:"entity_id"`
+	RelationshipID string `json:"relationship_id,omitempty"`
+}
+
+// TraverseOptions bounds a traversal
+type TraverseOptions struct {
+	// MaxDepth is the number of hops to expand (default 2, server max 6)
+	MaxDepth int
+	// RelationshipTypes restricts which edges are followed (all if empty)
+	RelationshipTypes []string
+	Direction         Direction
+	// Limit caps the number of nodes returned (server default 1000)
+	Limit int
+}
+
+type queryRequest struct {
+	Op                string    `json:"op"`
+	EntityID          string    `json:"entity_id,omitempty"`
+	Source            string    `json:"source,omitempty"`
+	Target            string    `json:"target,omitempty"`
+	RelationshipType  string    `json:"relationship_type,omitempty"`
+	RelationshipTypes []string  `json:"relationship_types,omitempty"`
+	Direction         Direction `json:"direction,omitempty"`
+	MaxDepth          int       `json:"max_depth,omitempty"`
+	Limit             int       `json:"limit,omitempty"`
+	Cursor            string    `json:"cursor,omitempty"`
+}
+
+// Neighbors returns the entities directly related to id. relType filters
+// by relationship type when non-empty; dir defaults to Outgoing.
+func (c *Client) Neighbors(ctx context.Context, id, relType string, dir Direction) ([]Neighbor, error) {
+	req := queryRequest{Op: "neighbors", EntityID: id, RelationshipType: relType, Direction: dir}
+	var out struct {
+		Neighbors []Neighbor `json:"neighbors"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/kg/query", req, &out); err != nil {
+		return nil, err
+	}
+	return out.Neighbors, nil
+}
+
+// NeighborsPage returns up to limit neighbors of id in relationship ID
+// order, starting after cursor (from the start if empty). The returned
+// cursor is empty on the last page.
+func (c *Client) NeighborsPage(ctx context.Context, id, relType string, dir Direction, limit int, cursor string) ([]Neighbor, string, error) {
+	req := queryRequest{Op: "neighbors", EntityID: id, RelationshipType: relType, Direction: dir, Limit: limit, Cursor: cursor}
+	var out struct {
+		Neighbors  []Neighbor `json:"neighbors"`
+		NextCursor string     `json:"next_cursor"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/kg/query", req, &out); err != nil {
+		return nil, "", err
+	}
+	return out.Neighbors, out.NextCursor, nil
+}
+
+// Traverse expands breadth-first from id and returns every entity reached,
+// ordered by depth
+func (c *Client) Traverse(ctx context.Context, id string, opts TraverseOptions) ([]TraversalNode, error) {
+	req := queryRequest{
+		Op:                "traverse",
+		EntityID:          id,
+		RelationshipTypes: opts.RelationshipTypes,
+		Direction:         opts.Direction,
+		MaxDepth:          opts.MaxDepth,
+		Limit:             opts.Limit,
+	}
+	var out struct {
+		Nodes []TraversalNode `json:"nodes"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/kg/query", req, &out); err != nil {
+		return nil, err
+	}
+	return out.Nodes, nil
+}
+
+// Paths returns the simple paths from source to target of at most maxDepth
+// hops (server default if 0), following only relTypes when given
+func (c *Client) Paths(ctx context.Context, source, target string, maxDepth int, relTypes ...string) ([][]PathStep, error) {
+	req := queryRequest{
+		Op:                "paths",
+		Source:            source,
+		Target:            target,
+		MaxDepth:          maxDepth,
+		RelationshipTypes: relTypes,
+	}
+	var out struct {
+		Paths [][]PathStep `json:"paths"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/kg/query", req, &out); err != nil {
+		return nil, err
+	}
+	return out.Paths, nil
+}
@@ -0,0 +1,48 @@
+package kgclient
+
+import (
+	"context"
+	"net/http"
+)
+
+// VectorMatch is a nearest-neighbor search result
+type VectorMatch struct {
+	EntityID   string                 `json:"entity_id"`
+	EntityType string                 `json:"entity_type,omitempty"`
+	Score      float64                `json:"score"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	// CID is the entity's current graph node
+	CID string `json:"cid"`
+	// ContentCID is the stored content the entity describes, if any
+	ContentCID string `json:"content_cid,omitempty"`
+	// Hops and Path are set for graph-expanded results: how far the entity
+	// is from the vector match it was reached from, and the route taken
+	Hops int      `json:"hops,omitempty"`
+	Path []string `json:"path,omitempty"`
+}
+
+// SearchOptions tunes a vector search
+type SearchOptions struct {
+	// TopK is the number of results (default 10)
+	TopK int
+	// HopCount expands matches through the graph by this many hops, with
+	// scores halving per hop; 0 returns pure vector matches
+	HopCount int
+}
+
+// VectorSearch returns the entities whose vectors are nearest to query,
+// best first
+func (c *Client) VectorSearch(ctx context.Context, query []float32, opts SearchOptions) ([]VectorMatch, error) {
+	req := struct {
+		Vector   []float32 `json:"vector"`
+		TopK     int       `json:"top_k,omitempty"`
+		HopCount int       `json:"hop_count,omitempty"`
+	}{query, opts.TopK, opts.HopCount}
+	var out struct {
+		Results []VectorMatch `json:"results"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/kg/vector-search", req, &out); err != nil {
+		return nil, err
+	}
+	return out.Results, nil
+}
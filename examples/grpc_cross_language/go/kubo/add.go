@@ -0,0 +1,63 @@
+package kubo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// AddOptions controls how content is imported by Add
+type AddOptions struct {
+	// HashFunc selects the multihash, e.g. "sha2-256", "sha3-256" or
+	// "blake3" (Kubo's default sha2-256 if empty). Non-default hashes
+	// require CIDv1, which is selected automatically.
+	HashFunc   string
+	CIDVersion int
+	RawLeaves  bool
+	Pin        bool
+	// OnlyHash computes the CID without storing the content
+	OnlyHash bool
+	// InlineLimit, if positive, inlines content of at most this many bytes
+	// into an identity CID instead of storing a block
+	// (cidutil.DefaultInlineLimit matches Kubo's default)
+	InlineLimit int
+}
+
+// AddResult is the result of Add
+type AddResult struct {
+	Name string `json:"Name"`
+	Hash string `json:"Hash"`
+	Size string `json:"Size"`
+}
+
+// Add imports r as a UnixFS file and returns its CID
+func (c *Client) Add(ctx context.Context, r io.Reader, opts *AddOptions) (*AddResult, error) {
+	if opts == nil {
+		opts = &AddOptions{}
+	}
+	args := url.Values{
+		"pin":       {fmt.Sprint(opts.Pin)},
+		"only-hash": {fmt.Sprint(opts.OnlyHash)},
+	}
+	version := opts.CIDVersion
+	if opts.HashFunc != "" && opts.HashFunc != "sha2-256" {
+		args.Set("hash", opts.HashFunc)
+		version = 1
+	}
+	if version > 0 {
+		args.Set("cid-version", fmt.Sprint(version))
+	}
+	if opts.RawLeaves {
+		args.Set("raw-leaves", "true")
+	}
+	if opts.InlineLimit > 0 {
+		args.Set("inline", "true")
+		args.Set("inline-limit", fmt.Sprint(opts.InlineLimit))
+	}
+	var out AddResult
+	if err := c.Call(ctx, "add", args, r, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
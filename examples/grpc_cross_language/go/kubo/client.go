@@ -0,0 +1,124 @@
+// Package kubo is a minimal client for the Kubo (go-ipfs) RPC API covering
+// the calls the Go tools need.
+package kubo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultAPIURL is the default address of the Kubo RPC API
+const DefaultAPIURL = "http://127.0.0.1:5001"
+
+// Error is an error returned by the Kubo RPC API
+type Error struct {
+	Message string `json:"Message"`
+	Code    int    `json:"Code"`
+	Type    string `json:"Type"`
+}
+
+func (e *Error) Error() string {
+	return "kubo: " + e.Message
+}
+
+// Client calls the Kubo RPC API over HTTP
+type Client struct {
+	apiURL string
+	http   *http.Client
+}
+
+// NewClient creates a client for the RPC API at apiURL, falling back to
+// $IPFS_API_URL and then DefaultAPIURL
+func NewClient(apiURL string) *Client {
+	if apiURL == "" {
+		apiURL = os.Getenv("IPFS_API_URL")
+	}
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	return &Client{apiURL: strings.TrimRight(apiURL, "/"), http: http.DefaultClient}
+}
+
+// APIURL returns the address of the RPC API the client calls
+func (c *Client) APIURL() string {
+	return c.apiURL
+}
+
+// Post calls an RPC command (e.g. "dag/put") with query arguments and an
+// optional file body, returning the raw response body. The caller must
+// close it.
+func (c *Client) Post(ctx context.Context, cmd string, args url.Values, file io.Reader) (io.ReadCloser, error) {
+	u := c.apiURL + "/api/v0/" + cmd
+	if len(args) > 0 {
+		u += "?" + args.Encode()
+	}
+
+	var body io.Reader
+	contentType := ""
+	if file != nil {
+		// Kubo expects file arguments as a multipart form; stream it
+		// through a pipe so large payloads are not buffered.
+		pr, pw := io.Pipe()
+		mw := multipart.NewWriter(pw)
+		go func() {
+			part, err := mw.CreateFormFile("file", "data")
+			if err == nil {
+				_, err = io.Copy(part, file)
+			}
+			if err == nil {
+				err = mw.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+		body = pr
+		contentType = mw.FormDataContentType()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("kubo: %s: %w", cmd, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var e Error
+		if json.Unmarshal(data, &e) == nil && e.Message != "" {
+			return nil, &e
+		}
+		return nil, fmt.Errorf("kubo: %s: HTTP %s: %s", cmd, resp.Status, bytes.TrimSpace(data))
+	}
+	return resp.Body, nil
+}
+
+// Call runs an RPC command and decodes its JSON response into out (if
+// non-nil)
+func (c *Client) Call(ctx context.Context, cmd string, args url.Values, file io.Reader, out interface{}) error {
+	body, err := c.Post(ctx, cmd, args, file)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if out == nil {
+		_, err = io.Copy(io.Discard, body)
+		return err
+	}
+	if err := json.NewDecoder(body).Decode(out); err != nil {
+		return fmt.Errorf("kubo: %s: decode response: %w", cmd, err)
+	}
+	return nil
+}
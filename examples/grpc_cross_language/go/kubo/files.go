@@ -0,0 +1,126 @@
+package kubo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// File types reported by FilesLs
+const (
+	TypeFile      = 0
+	TypeDirectory = 1
+)
+
+// FileEntry is an entry of an MFS directory listing
+type FileEntry struct {
+	Name string `json:"Name"`
+	Type int    `json:"Type"`
+	Size int64  `json:"Size"`
+	Hash string `json:"Hash"`
+}
+
+// FileStat describes an MFS path
+type FileStat struct {
+	Hash           string `json:"Hash"`
+	Size           int64  `json:"Size"`
+	CumulativeSize int64  `json:"CumulativeSize"`
+	Blocks         int    `json:"Blocks"`
+	// Type is "file", "directory" or "symlink"
+	Type string `json:"Type"`
+}
+
+// FilesCp copies src, an MFS path or /ipfs/<cid>, to the MFS path dst.
+// With parents, missing directories of dst are created. Copying an
+// /ipfs/ path adds no data, so it is how uploaded content is filed into
+// MFS. Both paths go through NormalizeMFSPath, and a dst colliding with an
+// existing entry fails with a *PathConflictError.
+func (c *Client) FilesCp(ctx context.Context, src, dst string, parents bool) error {
+	src, dst = NormalizeMFSPath(src), NormalizeMFSPath(dst)
+	if err := c.checkConflicts(ctx, dst); err != nil {
+		return err
+	}
+	args := url.Values{"arg": {src, dst}, "parents": {fmt.Sprint(parents)}}
+	return c.Call(ctx, "files/cp", args, nil, nil)
+}
+
+// FilesLs lists the MFS directory at path, with sizes and CIDs
+func (c *Client) FilesLs(ctx context.Context, path string) ([]FileEntry, error) {
+	var out struct {
+		Entries []FileEntry `json:"Entries"`
+	}
+	args := url.Values{"arg": {path}, "long": {"true"}}
+	if err := c.Call(ctx, "files/ls", args, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Entries, nil
+}
+
+// FilesStat describes the MFS path
+func (c *Client) FilesStat(ctx context.Context, path string) (*FileStat, error) {
+	var out FileStat
+	if err := c.Call(ctx, "files/stat", url.Values{"arg": {path}}, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FilesWriteOptions controls FilesWrite
+type FilesWriteOptions struct {
+	// Create the file if it does not exist
+	Create bool
+	// Parents creates missing parent directories
+	Parents bool
+	// Truncate the file before writing
+	Truncate bool
+	// Offset is the byte offset to write at
+	Offset int64
+	// CIDVersion, RawLeaves and HashFunc are as for AddOptions and apply
+	// to newly created files
+	CIDVersion int
+	RawLeaves  bool
+	HashFunc   string
+}
+
+// FilesWrite writes r to the MFS file at path, after NormalizeMFSPath.
+// A write that may create the file (Create at offset 0) fails with a
+// *PathConflictError if path collides with an existing entry.
+func (c *Client) FilesWrite(ctx context.Context, path string, r io.Reader, opts *FilesWriteOptions) error {
+	if opts == nil {
+		opts = &FilesWriteOptions{}
+	}
+	path = NormalizeMFSPath(path)
+	if opts.Create && opts.Offset == 0 {
+		if err := c.checkConflicts(ctx, path); err != nil {
+			return err
+		}
+	}
+	args := url.Values{
+		"arg":      {path},
+		"create":   {fmt.Sprint(opts.Create)},
+		"parents":  {fmt.Sprint(opts.Parents)},
+		"truncate": {fmt.Sprint(opts.Truncate)},
+	}
+	if opts.Offset > 0 {
+		args.Set("offset", fmt.Sprint(opts.Offset))
+	}
+	version := opts.CIDVersion
+	if opts.HashFunc != "" && opts.HashFunc != "sha2-256" {
+		args.Set("hash", opts.HashFunc)
+		version = 1
+	}
+	if version > 0 {
+		args.Set("cid-version", fmt.Sprint(version))
+	}
+	if opts.RawLeaves {
+		args.Set("raw-leaves", "true")
+	}
+	return c.Call(ctx, "files/write", args, r, nil)
+}
+
+// FilesRm removes the MFS path; recursive is needed for directories
+func (c *Client) FilesRm(ctx context.Context, path string, recursive bool) error {
+	args := url.Values{"arg": {path}, "recursive": {fmt.Sprint(recursive)}}
+	return c.Call(ctx, "files/rm", args, nil, nil)
+}
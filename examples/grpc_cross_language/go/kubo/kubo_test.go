@@ -0,0 +1,234 @@
+package kubo_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"example.com/ipfs_kit_py/cidutil"
+	"example.com/ipfs_kit_py/kubo"
+)
+
+// fakeNode serves the RPC commands in handlers, recording the query of
+// every call
+type fakeNode struct {
+	mu       sync.Mutex
+	queries  []url.Values
+	handlers map[string]http.HandlerFunc
+}
+
+func (f *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "RPC calls are POSTs", http.StatusMethodNotAllowed)
+		return
+	}
+	cmd := strings.TrimPrefix(r.URL.Path, "/api/v0/")
+	f.mu.Lock()
+	f.queries = append(f.queries, r.URL.Query())
+	f.mu.Unlock()
+	h, ok := f.handlers[cmd]
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(kubo.Error{Message: "unknown command: " + cmd, Type: "error"})
+		return
+	}
+	h(w, r)
+}
+
+func newFakeNode(t *testing.T, handlers map[string]http.HandlerFunc) (*fakeNode, *kubo.Client) {
+	t.Helper()
+	f := &fakeNode{handlers: handlers}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, kubo.NewClient(srv.URL + "/")
+}
+
+func reply(v interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) { json.NewEncoder(w).Encode(v) }
+}
+
+func TestAdd(t *testing.T) {
+	var uploaded string
+	f, c := newFakeNode(t, map[string]http.HandlerFunc{
+		"add": func(w http.ResponseWriter, r *http.Request) {
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			uploaded = string(data)
+			json.NewEncoder(w).Encode(kubo.AddResult{Name: "data", Hash: "bafyadded", Size: "13"})
+		},
+	})
+	res, err := c.Add(context.Background(), strings.NewReader("hello, world!"), &kubo.AddOptions{HashFunc: "blake3", RawLeaves: true, Pin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Hash != "bafyadded" || uploaded != "hello, world!" {
+		t.Errorf("added %+v from %q", res, uploaded)
+	}
+	q := f.queries[0]
+	// a non-default hash needs CIDv1
+	for k, want := range map[string]string{"hash": "blake3", "cid-version": "1", "raw-leaves": "true", "pin": "true", "only-hash": "false"} {
+		if q.Get(k) != want {
+			t.Errorf("add %s=%q, want %q", k, q.Get(k), want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, c := newFakeNode(t, map[string]http.HandlerFunc{
+		"repo/stat": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "gateway timeout", http.StatusGatewayTimeout)
+		},
+	})
+	err := c.Call(context.Background(), "dht/query", nil, nil, nil)
+	var kerr *kubo.Error
+	if !errors.As(err, &kerr) || !strings.Contains(kerr.Message, "unknown command") {
+		t.Errorf("RPC error: %v", err)
+	}
+	_, err = c.RepoStats(context.Background())
+	if err == nil || errors.As(err, &kerr) || !strings.Contains(err.Error(), "gateway timeout") {
+		t.Errorf("HTTP error: %v", err)
+	}
+}
+
+func TestPinLs(t *testing.T) {
+	_, c := newFakeNode(t, map[string]http.HandlerFunc{
+		"pin/ls": func(w http.ResponseWriter, r *http.Request) {
+			keys := map[string]map[string]string{"bafypinned": {"Type": "recursive"}, "bafyindirect": {"Type": "indirect"}}
+			if arg := r.URL.Query().Get("arg"); arg != "" {
+				typ, ok := keys[arg]
+				if !ok {
+					w.WriteHeader(http.StatusInternalServerError)
+					json.NewEncoder(w).Encode(kubo.Error{Message: "path '" + arg + "' is not pinned"})
+					return
+				}
+				keys = map[string]map[string]string{arg: typ}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"Keys": keys})
+		},
+	})
+	all, err := c.PinLs(context.Background(), "")
+	if err != nil || len(all) != 2 {
+		t.Errorf("all pins: %v, %v", all, err)
+	}
+	// unpinned CIDs are left out rather than failing the call
+	some, err := c.PinLs(context.Background(), "", "bafypinned", "bafymissing")
+	if err != nil || len(some) != 1 || some["bafypinned"] != "recursive" {
+		t.Errorf("pins of two CIDs: %v, %v", some, err)
+	}
+}
+
+func TestFindProviders(t *testing.T) {
+	f, c := newFakeNode(t, map[string]http.HandlerFunc{
+		"routing/findprovs": func(w http.ResponseWriter, r *http.Request) {
+			enc := json.NewEncoder(w)
+			enc.Encode(map[string]interface{}{"Type": 0, "ID": "QmQueried"})
+			enc.Encode(map[string]interface{}{"Type": 4, "Responses": []kubo.Provider{{ID: "QmA", Addrs: []string{"/ip4/192.0.2.1/tcp/4001"}}}})
+			enc.Encode(map[string]interface{}{"Type": 4, "Responses": []kubo.Provider{{ID: "QmB"}}})
+		},
+	})
+	providers, err := c.FindProviders(context.Background(), "bafyfind", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 2 || providers[0].ID != "QmA" || providers[1].ID != "QmB" {
+		t.Errorf("providers %+v", providers)
+	}
+	if n := f.queries[0].Get("num-providers"); n != "20" {
+		t.Errorf("num-providers=%s, want the default 20", n)
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	field := func(s string) string {
+		enc, _ := cidutil.MultibaseEncode(cidutil.Base64URL, []byte(s))
+		return enc
+	}
+	f, c := newFakeNode(t, map[string]http.HandlerFunc{
+		"pubsub/sub": func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"from": "QmSender", "data": field("payload"), "seqno": field("\x01"), "topicIDs": []string{field("routing/events")}})
+		},
+	})
+	sub, err := c.PubSubSubscribe(context.Background(), "routing/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	msg, err := sub.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.From != "QmSender" || string(msg.Data) != "payload" || string(msg.Seqno) != "\x01" || len(msg.Topics) != 1 || msg.Topics[0] != "routing/events" {
+		t.Errorf("message %+v", msg)
+	}
+	if _, err := sub.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("after the last message: %v, want io.EOF", err)
+	}
+	if arg := f.queries[0].Get("arg"); arg != field("routing/events") {
+		t.Errorf("subscribed to %q, want the topic multibase-encoded", arg)
+	}
+}
+
+func TestFilesCpConflicts(t *testing.T) {
+	f, c := newFakeNode(t, map[string]http.HandlerFunc{
+		"files/ls": func(w http.ResponseWriter, r *http.Request) {
+			entries := map[string][]kubo.FileEntry{
+				"/":     {{Name: "Docs", Type: kubo.TypeDirectory}},
+				"/Docs": {{Name: "caf\u00e9.txt", Type: kubo.TypeFile}},
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"Entries": entries[r.URL.Query().Get("arg")]})
+		},
+		"files/cp": reply(nil),
+	})
+	ctx := context.Background()
+	var conflict *kubo.PathConflictError
+	if err := c.FilesCp(ctx, "/ipfs/bafysrc", "/docs/new.txt", true); !errors.As(err, &conflict) || conflict.Conflict != "/Docs" {
+		t.Errorf("copy under a directory differing in case: %v", err)
+	}
+	// the new name is the existing one in capitals and decomposed form
+	if err := c.FilesCp(ctx, "/ipfs/bafysrc", "/Docs/CAFE\u0301.txt", true); !errors.As(err, &conflict) || conflict.Conflict != "/Docs/caf\u00e9.txt" {
+		t.Errorf("copy over a name differing in case and form: %v", err)
+	}
+	if err := c.FilesCp(ctx, "/ipfs/bafysrc", "Docs//new.txt", true); err != nil {
+		t.Fatal(err)
+	}
+	q := f.queries[len(f.queries)-1]
+	if args := q["arg"]; len(args) != 2 || args[0] != "/ipfs/bafysrc" || args[1] != "/Docs/new.txt" || q.Get("parents") != "true" {
+		t.Errorf("files/cp %v", q)
+	}
+}
+
+func TestNormalizeMFSPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"docs/a.txt":          "/docs/a.txt",
+		"/docs/../b//c/":      "/b/c",
+		"/cafe\u0301":         "/caf\u00e9",
+		"/ipfs/bafyx/../y":    "/ipfs/y",
+		"/ipns/cafe\u0301.io": "/ipns/cafe\u0301.io",
+	} {
+		if got := kubo.NormalizeMFSPath(in); got != want {
+			t.Errorf("NormalizeMFSPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	t.Setenv("IPFS_API_URL", "http://node:5001/")
+	if got := kubo.NewClient("").APIURL(); got != "http://node:5001" {
+		t.Errorf("APIURL() = %q from $IPFS_API_URL", got)
+	}
+	t.Setenv("IPFS_API_URL", "")
+	if got := kubo.NewClient("").APIURL(); got != kubo.DefaultAPIURL {
+		t.Errorf("APIURL() = %q, want the default", got)
+	}
+}
@@ -0,0 +1,111 @@
+package kubo
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Key is a keypair in the node's keystore; its ID is the IPNS name records
+// published with it live under
+type Key struct {
+	Name string `json:"Name"`
+	ID   string `json:"Id"`
+}
+
+// NameEntry is a published IPNS record: Name points to Value, an /ipfs/
+// path
+type NameEntry struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// NamePublishOptions controls NamePublish
+type NamePublishOptions struct {
+	// Key is the keystore key to publish under (default "self")
+	Key string
+	// Lifetime is how long the record stays valid (Kubo's default if zero)
+	Lifetime time.Duration
+	// TTL is how long resolvers may cache the record (Kubo's default if
+	// zero)
+	TTL time.Duration
+	// AllowOffline stores the record locally when the node is offline
+	// instead of failing
+	AllowOffline bool
+}
+
+// NamePublish publishes an IPNS record pointing at path, an /ipfs/ path or
+// a bare CID
+func (c *Client) NamePublish(ctx context.Context, path string, opts *NamePublishOptions) (*NameEntry, error) {
+	if opts == nil {
+		opts = &NamePublishOptions{}
+	}
+	args := url.Values{"arg": {path}}
+	if opts.Key != "" {
+		args.Set("key", opts.Key)
+	}
+	if opts.Lifetime > 0 {
+		args.Set("lifetime", opts.Lifetime.String())
+	}
+	if opts.TTL > 0 {
+		args.Set("ttl", opts.TTL.String())
+	}
+	if opts.AllowOffline {
+		args.Set("allow-offline", "true")
+	}
+	var out NameEntry
+	if err := c.Call(ctx, "name/publish", args, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// NameResolve resolves an IPNS name, with or without its /ipns/ prefix, to
+// the /ipfs/ path it points to, following names that point to names. With
+// nocache the record is looked up afresh rather than from the node's cache.
+func (c *Client) NameResolve(ctx context.Context, name string, nocache bool) (string, error) {
+	var out struct {
+		Path string `json:"Path"`
+	}
+	args := url.Values{"arg": {name}, "recursive": {"true"}, "nocache": {fmt.Sprint(nocache)}}
+	if err := c.Call(ctx, "name/resolve", args, nil, &out); err != nil {
+		return "", err
+	}
+	return out.Path, nil
+}
+
+// KeyList lists the keys in the node's keystore
+func (c *Client) KeyList(ctx context.Context) ([]Key, error) {
+	var out struct {
+		Keys []Key `json:"Keys"`
+	}
+	if err := c.Call(ctx, "key/list", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Keys, nil
+}
+
+// KeyGen creates an ed25519 key called name
+func (c *Client) KeyGen(ctx context.Context, name string) (*Key, error) {
+	var out Key
+	if err := c.Call(ctx, "key/gen", url.Values{"arg": {name}, "type": {"ed25519"}}, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EnsureKey returns the key called name, creating it if it does not exist,
+// so a mutable pointer can be published under a stable name
+func (c *Client) EnsureKey(ctx context.Context, name string) (*Key, error) {
+	keys, err := c.KeyList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if k.Name == name {
+			return &k, nil
+		}
+	}
+	return c.KeyGen(ctx, name)
+}
@@ -0,0 +1,70 @@
+package kubo
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// PathConflictError is returned when an MFS path would be created next to
+// an existing entry whose name differs only in case or unicode
+// normalization, which a case-insensitive or normalizing mount of MFS
+// (FUSE, WebDAV, macOS) cannot tell apart
+type PathConflictError struct {
+	Path     string
+	Conflict string
+}
+
+func (e *PathConflictError) Error() string {
+	return fmt.Sprintf("kubo: path %q conflicts with %q (differs only in case or unicode form)", e.Path, e.Conflict)
+}
+
+// NormalizeMFSPath returns the canonical form of an MFS path: NFC
+// normalized, cleaned and absolute. /ipfs/ and /ipns/ paths are returned
+// unchanged apart from cleaning.
+func NormalizeMFSPath(p string) string {
+	if strings.HasPrefix(p, "/ipfs/") || strings.HasPrefix(p, "/ipns/") {
+		return path.Clean(p)
+	}
+	return path.Clean("/" + norm.NFC.String(p))
+}
+
+// checkConflicts walks the existing directories along p, an MFS path from
+// NormalizeMFSPath, and reports the first entry that differs from the
+// matching element of p only in case or unicode form
+func (c *Client) checkConflicts(ctx context.Context, p string) error {
+	dir := "/"
+	for _, name := range strings.Split(strings.TrimPrefix(p, "/"), "/") {
+		if name == "" {
+			return nil
+		}
+		entries, err := c.FilesLs(ctx, dir)
+		if err != nil {
+			return err
+		}
+		key := cases.Fold().String(name)
+		var found *FileEntry
+		for i, e := range entries {
+			if cases.Fold().String(norm.NFC.String(e.Name)) == key {
+				found = &entries[i]
+				break
+			}
+		}
+		if found == nil {
+			// the rest of p does not exist yet
+			return nil
+		}
+		if found.Name != name {
+			return &PathConflictError{Path: p, Conflict: path.Join(dir, found.Name)}
+		}
+		if found.Type != TypeDirectory {
+			return nil
+		}
+		dir = path.Join(dir, name)
+	}
+	return nil
+}
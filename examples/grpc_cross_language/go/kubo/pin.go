@@ -0,0 +1,59 @@
+package kubo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PinAdd pins cid, and with recursive everything it links to
+func (c *Client) PinAdd(ctx context.Context, cid string, recursive bool) error {
+	args := url.Values{"arg": {cid}, "recursive": {fmt.Sprint(recursive)}}
+	return c.Call(ctx, "pin/add", args, nil, nil)
+}
+
+// PinRm removes the pin on cid
+func (c *Client) PinRm(ctx context.Context, cid string) error {
+	return c.Call(ctx, "pin/rm", url.Values{"arg": {cid}}, nil, nil)
+}
+
+// PinLs returns the pinned CIDs and their pin types ("recursive",
+// "direct" or "indirect"). typ filters by type ("all" if empty). With cids,
+// only those are checked, and ones not pinned are left out.
+func (c *Client) PinLs(ctx context.Context, typ string, cids ...string) (map[string]string, error) {
+	if typ == "" {
+		typ = "all"
+	}
+	pins := make(map[string]string)
+	if len(cids) == 0 {
+		return pins, c.pinLs(ctx, url.Values{"type": {typ}}, pins)
+	}
+	// Kubo fails the whole call if any argument is not pinned, so ask one
+	// at a time
+	for _, cid := range cids {
+		err := c.pinLs(ctx, url.Values{"type": {typ}, "arg": {cid}}, pins)
+		var kerr *Error
+		if errors.As(err, &kerr) && strings.Contains(kerr.Message, "not pinned") {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pins, nil
+}
+
+func (c *Client) pinLs(ctx context.Context, args url.Values, pins map[string]string) error {
+	var out struct {
+		Keys map[string]struct{ Type string } `json:"Keys"`
+	}
+	if err := c.Call(ctx, "pin/ls", args, nil, &out); err != nil {
+		return err
+	}
+	for cid, k := range out.Keys {
+		pins[cid] = k.Type
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package kubo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// PubSubMessage is a message received on a topic
+type PubSubMessage struct {
+	// From is the peer ID of the node that published the message
+	From   string
+	Data   []byte
+	Seqno  []byte
+	Topics []string
+}
+
+// PubSubPublish publishes data on topic. Pubsub must be enabled on the
+// node, with Pubsub.Enabled or --enable-pubsub-experiment.
+func (c *Client) PubSubPublish(ctx context.Context, topic string, data []byte) error {
+	return c.Call(ctx, "pubsub/pub", url.Values{"arg": {encodeTopic(topic)}}, bytes.NewReader(data), nil)
+}
+
+// PubSubPeers lists the peers the node knows subscribe to topic
+func (c *Client) PubSubPeers(ctx context.Context, topic string) ([]string, error) {
+	var out struct {
+		Strings []string `json:"Strings"`
+	}
+	if err := c.Call(ctx, "pubsub/peers", url.Values{"arg": {encodeTopic(topic)}}, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Strings, nil
+}
+
+// Subscription receives the messages published on a topic
+type Subscription struct {
+	body io.ReadCloser
+	dec  *json.Decoder
+}
+
+// PubSubSubscribe subscribes to topic until ctx ends or the subscription
+// is closed
+func (c *Client) PubSubSubscribe(ctx context.Context, topic string) (*Subscription, error) {
+	body, err := c.Post(ctx, "pubsub/sub", url.Values{"arg": {encodeTopic(topic)}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Subscription{body: body, dec: json.NewDecoder(bufio.NewReader(body))}, nil
+}
+
+// Next waits for the next message. It returns io.EOF when the node ends
+// the subscription.
+func (s *Subscription) Next() (*PubSubMessage, error) {
+	var raw struct {
+		From     string   `json:"from"`
+		Data     string   `json:"data"`
+		Seqno    string   `json:"seqno"`
+		TopicIDs []string `json:"topicIDs"`
+	}
+	if err := s.dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	msg := &PubSubMessage{From: raw.From}
+	var err error
+	if msg.Data, err = decodeField(raw.Data); err != nil {
+		return nil, fmt.Errorf("kubo: pubsub data: %w", err)
+	}
+	if msg.Seqno, err = decodeField(raw.Seqno); err != nil {
+		return nil, fmt.Errorf("kubo: pubsub seqno: %w", err)
+	}
+	for _, t := range raw.TopicIDs {
+		topic, err := decodeField(t)
+		if err != nil {
+			return nil, fmt.Errorf("kubo: pubsub topic: %w", err)
+		}
+		msg.Topics = append(msg.Topics, string(topic))
+	}
+	return msg, nil
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() error {
+	return s.body.Close()
+}
+
+// encodeTopic multibase-encodes a topic, as the RPC API expects
+func encodeTopic(topic string) string {
+	s, _ := cidutil.MultibaseEncode(cidutil.Base64URL, []byte(topic))
+	return s
+}
+
+// decodeField decodes a multibase field of a received message
+func decodeField(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	_, data, err := cidutil.MultibaseDecode(s)
+	return data, err
+}
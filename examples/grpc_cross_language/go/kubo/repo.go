@@ -0,0 +1,58 @@
+package kubo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// RepoStat is the size of the node's block store
+type RepoStat struct {
+	RepoSize   int64 `json:"RepoSize"`
+	StorageMax int64 `json:"StorageMax"`
+	NumObjects int64 `json:"NumObjects"`
+}
+
+// RepoStats returns the size of the node's block store
+func (c *Client) RepoStats(ctx context.Context) (*RepoStat, error) {
+	var out RepoStat
+	if err := c.Call(ctx, "repo/stat", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RepoGC removes the blocks no pin or MFS entry references, returning how
+// many were removed
+func (c *Client) RepoGC(ctx context.Context) (int, error) {
+	body, err := c.Post(ctx, "repo/gc", nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	dec := json.NewDecoder(body)
+	removed := 0
+	var errs []error
+	for {
+		var ev struct {
+			Key   json.RawMessage `json:"Key"`
+			Error string          `json:"Error"`
+		}
+		if err := dec.Decode(&ev); err == io.EOF {
+			break
+		} else if err != nil {
+			return removed, fmt.Errorf("kubo: repo/gc: decode response: %w", err)
+		}
+		if ev.Error != "" {
+			errs = append(errs, errors.New(ev.Error))
+			continue
+		}
+		removed++
+	}
+	if len(errs) > 0 {
+		return removed, fmt.Errorf("kubo: repo/gc: %w", errors.Join(errs...))
+	}
+	return removed, nil
+}
@@ -0,0 +1,77 @@
+package kubo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// DefaultProviderLimit is how many providers FindProviders looks for when
+// not told, as in Kubo
+const DefaultProviderLimit = 20
+
+// queryProvider is the type of routing query events that name providers
+const queryProvider = 4
+
+// Provider is a peer providing content
+type Provider struct {
+	ID    string   `json:"ID"`
+	Addrs []string `json:"Addrs"`
+}
+
+// routingEvent is one event of a streamed routing query
+type routingEvent struct {
+	Type      int        `json:"Type"`
+	ID        string     `json:"ID"`
+	Extra     string     `json:"Extra"`
+	Responses []Provider `json:"Responses"`
+}
+
+// Provide announces on the DHT that the node provides cid, and with
+// recursive everything it links to. The node must have the blocks.
+func (c *Client) Provide(ctx context.Context, cid string, recursive bool) error {
+	args := url.Values{"arg": {cid}, "recursive": {fmt.Sprint(recursive)}}
+	return c.routingQuery(ctx, "routing/provide", args, nil)
+}
+
+// FindProviders looks up peers providing cid on the DHT, stopping after
+// limit of them (DefaultProviderLimit if zero)
+func (c *Client) FindProviders(ctx context.Context, cid string, limit int) ([]Provider, error) {
+	if limit <= 0 {
+		limit = DefaultProviderLimit
+	}
+	args := url.Values{"arg": {cid}, "num-providers": {fmt.Sprint(limit)}}
+	var providers []Provider
+	err := c.routingQuery(ctx, "routing/findprovs", args, func(ev *routingEvent) {
+		if ev.Type == queryProvider {
+			providers = append(providers, ev.Responses...)
+		}
+	})
+	return providers, err
+}
+
+// routingQuery runs a routing command, passing each event it streams to
+// onEvent (if non-nil) until the query ends
+func (c *Client) routingQuery(ctx context.Context, cmd string, args url.Values, onEvent func(*routingEvent)) error {
+	body, err := c.Post(ctx, cmd, args, nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	dec := json.NewDecoder(body)
+	for {
+		var ev routingEvent
+		if err := dec.Decode(&ev); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("kubo: %s: decode response: %w", cmd, err)
+		}
+		if onEvent != nil {
+			onEvent(&ev)
+		}
+	}
+}
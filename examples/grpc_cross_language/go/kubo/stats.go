@@ -0,0 +1,48 @@
+package kubo
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// BitswapStat is the node's bitswap counters since it started
+type BitswapStat struct {
+	BlocksReceived   int64 `json:"BlocksReceived"`
+	BlocksSent       int64 `json:"BlocksSent"`
+	DataReceived     int64 `json:"DataReceived"`
+	DataSent         int64 `json:"DataSent"`
+	DupBlksReceived  int64 `json:"DupBlksReceived"`
+	DupDataReceived  int64 `json:"DupDataReceived"`
+	MessagesReceived int64 `json:"MessagesReceived"`
+	ProvideBufLen    int   `json:"ProvideBufLen"`
+	// Peers are the node's bitswap partners
+	Peers []string `json:"Peers"`
+	// Wantlist holds the CIDs the node is waiting for, as {"/": cid}
+	Wantlist []json.RawMessage `json:"Wantlist"`
+}
+
+// BandwidthStat is the node's traffic across all protocols
+type BandwidthStat struct {
+	TotalIn  int64   `json:"TotalIn"`
+	TotalOut int64   `json:"TotalOut"`
+	RateIn   float64 `json:"RateIn"`
+	RateOut  float64 `json:"RateOut"`
+}
+
+// BitswapStats returns the node's bitswap counters
+func (c *Client) BitswapStats(ctx context.Context) (*BitswapStat, error) {
+	var out BitswapStat
+	if err := c.Call(ctx, "stats/bitswap", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// BandwidthStats returns the node's total traffic and current rates
+func (c *Client) BandwidthStats(ctx context.Context) (*BandwidthStat, error) {
+	var out BandwidthStat
+	if err := c.Call(ctx, "stats/bw", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,221 @@
+// Package lotus is a minimal JSON-RPC client for the Lotus Filecoin node API,
+// covering the calls needed to import data and make storage deals.
+package lotus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultAPIURL is the default Lotus JSON-RPC endpoint
+const DefaultAPIURL = "http://localhost:1234/rpc/v0"
+
+// Cid is a CID in the {"/": "..."} form used by the Lotus API
+type Cid struct {
+	Root string `json:"/"`
+}
+
+// String returns the CID string
+func (c Cid) String() string {
+	return c.Root
+}
+
+// Error is a JSON-RPC error returned by Lotus
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("lotus: %s (code %d)", e.Message, e.Code)
+}
+
+// Client calls the Lotus JSON-RPC API over HTTP
+type Client struct {
+	url   string
+	token string
+	http  *http.Client
+	id    atomic.Int64
+}
+
+// NewClient creates a Lotus client. An empty apiURL uses DefaultAPIURL and
+// an empty token is read from LOTUS_TOKEN or $LOTUS_PATH/token.
+func NewClient(apiURL, token string) *Client {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	if token == "" {
+		token = defaultToken()
+	}
+	return &Client{url: apiURL, token: token, http: http.DefaultClient}
+}
+
+// defaultToken looks up the API token the same way as the Python lotus_kit
+func defaultToken() string {
+	if token := os.Getenv("LOTUS_TOKEN"); token != "" {
+		return token
+	}
+	lotusPath := os.Getenv("LOTUS_PATH")
+	if lotusPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		lotusPath = filepath.Join(home, ".lotus")
+	}
+	data, err := os.ReadFile(filepath.Join(lotusPath, "token"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Call invokes Filecoin.<method> with positional params and decodes the
+// result into out (if non-nil)
+func (c *Client) Call(ctx context.Context, method string, out interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "Filecoin." + method,
+		"params":  params,
+		"id":      c.id.Add(1),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("lotus: %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("lotus: %s: HTTP %s: %s", method, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("lotus: %s: decode response: %w", method, err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if out != nil {
+		if err := json.Unmarshal(rpcResp.Result, out); err != nil {
+			return fmt.Errorf("lotus: %s: decode result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// ImportResult is returned by ClientImport
+type ImportResult struct {
+	Root     Cid    `json:"Root"`
+	ImportID uint64 `json:"ImportID"`
+}
+
+// ClientImport imports a file that is readable by the Lotus node. Paths
+// ending in .car are imported as CAR files.
+func (c *Client) ClientImport(ctx context.Context, path string) (*ImportResult, error) {
+	ref := map[string]interface{}{
+		"Path":  path,
+		"IsCAR": strings.HasSuffix(path, ".car"),
+	}
+	var res ImportResult
+	if err := c.Call(ctx, "ClientImport", &res, ref); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// DealParams describes a storage deal proposal
+type DealParams struct {
+	Root              string
+	Miner             string
+	Wallet            string // empty uses the node's default wallet
+	EpochPrice        string // attoFIL per epoch per GiB, as a decimal string
+	MinBlocksDuration uint64
+	VerifiedDeal      bool
+	FastRetrieval     bool
+}
+
+// ClientStartDeal proposes a storage deal and returns the proposal CID
+func (c *Client) ClientStartDeal(ctx context.Context, p DealParams) (string, error) {
+	if p.Wallet == "" {
+		wallet, err := c.WalletDefaultAddress(ctx)
+		if err != nil {
+			return "", err
+		}
+		p.Wallet = wallet
+	}
+	params := map[string]interface{}{
+		"Data": map[string]interface{}{
+			"TransferType": "graphsync",
+			"Root":         Cid{Root: p.Root},
+		},
+		"Wallet":            p.Wallet,
+		"Miner":             p.Miner,
+		"EpochPrice":        p.EpochPrice,
+		"MinBlocksDuration": p.MinBlocksDuration,
+		"VerifiedDeal":      p.VerifiedDeal,
+		"FastRetrieval":     p.FastRetrieval,
+	}
+	var proposal Cid
+	if err := c.Call(ctx, "ClientStartDeal", &proposal, params); err != nil {
+		return "", err
+	}
+	return proposal.Root, nil
+}
+
+// DealInfo is the deal status returned by ClientGetDealInfo
+type DealInfo struct {
+	ProposalCid   Cid       `json:"ProposalCid"`
+	State         DealState `json:"State"`
+	Message       string    `json:"Message"`
+	Provider      string    `json:"Provider"`
+	PieceCID      *Cid      `json:"PieceCID"`
+	Size          uint64    `json:"Size"`
+	PricePerEpoch string    `json:"PricePerEpoch"`
+	Duration      uint64    `json:"Duration"`
+	DealID        uint64    `json:"DealID"`
+}
+
+// ClientGetDealInfo returns the current state of a deal
+func (c *Client) ClientGetDealInfo(ctx context.Context, proposal string) (*DealInfo, error) {
+	var info DealInfo
+	if err := c.Call(ctx, "ClientGetDealInfo", &info, Cid{Root: proposal}); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// WalletDefaultAddress returns the node's default wallet address
+func (c *Client) WalletDefaultAddress(ctx context.Context) (string, error) {
+	var addr string
+	err := c.Call(ctx, "WalletDefaultAddress", &addr)
+	return addr, err
+}
@@ -0,0 +1,172 @@
+package lotus
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DealState is a storage market deal state as reported by Lotus
+type DealState uint64
+
+// Storage deal states from go-fil-markets
+const (
+	StorageDealUnknown DealState = iota
+	StorageDealProposalNotFound
+	StorageDealProposalRejected
+	StorageDealProposalAccepted
+	StorageDealStaged
+	StorageDealSealing
+	StorageDealFinalizing
+	StorageDealActive
+	StorageDealExpired
+	StorageDealSlashed
+	StorageDealRejecting
+	StorageDealFailing
+	StorageDealFundsReserved
+	StorageDealCheckForAcceptance
+	StorageDealValidating
+	StorageDealAcceptWait
+	StorageDealStartDataTransfer
+	StorageDealTransferring
+	StorageDealWaitingForData
+	StorageDealVerifyData
+	StorageDealReserveProviderFunds
+	StorageDealReserveClientFunds
+	StorageDealProviderFunding
+	StorageDealClientFunding
+	StorageDealPublish
+	StorageDealPublishing
+	StorageDealError
+	StorageDealProviderTransferAwaitRestart
+	StorageDealClientTransferRestart
+	StorageDealAwaitingPreCommit
+	StorageDealTransferQueued
+)
+
+var dealStateNames = [...]string{
+	"StorageDealUnknown",
+	"StorageDealProposalNotFound",
+	"StorageDealProposalRejected",
+	"StorageDealProposalAccepted",
+	"StorageDealStaged",
+	"StorageDealSealing",
+	"StorageDealFinalizing",
+	"StorageDealActive",
+	"StorageDealExpired",
+	"StorageDealSlashed",
+	"StorageDealRejecting",
+	"StorageDealFailing",
+	"StorageDealFundsReserved",
+	"StorageDealCheckForAcceptance",
+	"StorageDealValidating",
+	"StorageDealAcceptWait",
+	"StorageDealStartDataTransfer",
+	"StorageDealTransferring",
+	"StorageDealWaitingForData",
+	"StorageDealVerifyData",
+	"StorageDealReserveProviderFunds",
+	"StorageDealReserveClientFunds",
+	"StorageDealProviderFunding",
+	"StorageDealClientFunding",
+	"StorageDealPublish",
+	"StorageDealPublishing",
+	"StorageDealError",
+	"StorageDealProviderTransferAwaitRestart",
+	"StorageDealClientTransferRestart",
+	"StorageDealAwaitingPreCommit",
+	"StorageDealTransferQueued",
+}
+
+func (s DealState) String() string {
+	if int(s) < len(dealStateNames) {
+		return dealStateNames[s]
+	}
+	return fmt.Sprintf("DealState(%d)", uint64(s))
+}
+
+// Failed reports whether the deal has reached a terminal failure state
+func (s DealState) Failed() bool {
+	switch s {
+	case StorageDealProposalNotFound, StorageDealProposalRejected, StorageDealExpired,
+		StorageDealSlashed, StorageDealRejecting, StorageDealFailing, StorageDealError:
+		return true
+	}
+	return false
+}
+
+// lifecycleOrder ranks the non-failure states of the normal deal lifecycle
+var lifecycleOrder = map[DealState]int{
+	StorageDealProposalAccepted:  1,
+	StorageDealPublish:           2,
+	StorageDealPublishing:        3,
+	StorageDealStaged:            4,
+	StorageDealAwaitingPreCommit: 4,
+	StorageDealSealing:           5,
+	StorageDealFinalizing:        6,
+	StorageDealActive:            7,
+}
+
+// reached reports whether s is at or past target in the normal deal
+// lifecycle (accepted → publishing → staged → sealing → finalizing →
+// active). No state reaches a target outside it.
+func (s DealState) reached(target DealState) bool {
+	want, ok := lifecycleOrder[target]
+	if !ok {
+		return false
+	}
+	have, ok := lifecycleOrder[s]
+	return ok && have >= want
+}
+
+// DealFailedError is returned by WaitDeal when a deal fails
+type DealFailedError struct {
+	Proposal string
+	State    DealState
+	Message  string
+}
+
+func (e *DealFailedError) Error() string {
+	return fmt.Sprintf("lotus: deal %s failed in state %s: %s", e.Proposal, e.State, e.Message)
+}
+
+// WaitDeal polls a deal until it reaches the target state (for example
+// StorageDealStaged, once the deal is published, or StorageDealActive),
+// fails, or ctx is done. onUpdate, if non-nil, is called whenever the deal
+// state changes. A target outside the normal deal lifecycle is an error.
+func (c *Client) WaitDeal(ctx context.Context, proposal string, target DealState, interval time.Duration, onUpdate func(*DealInfo)) (*DealInfo, error) {
+	if _, ok := lifecycleOrder[target]; !ok {
+		return nil, fmt.Errorf("lotus: cannot wait for deal state %s: not a state of the deal lifecycle", target)
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := DealState(^uint64(0))
+	for {
+		info, err := c.ClientGetDealInfo(ctx, proposal)
+		if err != nil {
+			return nil, err
+		}
+		if info.State != last {
+			last = info.State
+			if onUpdate != nil {
+				onUpdate(info)
+			}
+		}
+		if info.State.Failed() {
+			return info, &DealFailedError{Proposal: proposal, State: info.State, Message: info.Message}
+		}
+		if info.State.reached(target) {
+			return info, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return info, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package lotus_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/ipfs_kit_py/lotus"
+)
+
+// rpcRequest is a JSON-RPC request as Lotus receives it
+type rpcRequest struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	ID     int64             `json:"id"`
+	Token  string            `json:"-"`
+}
+
+// fakeNode answers JSON-RPC calls with handle, recording the requests
+func fakeNode(t *testing.T, handle func(req rpcRequest) (interface{}, *lotus.Error)) (*httptest.Server, *[]rpcRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var reqs []rpcRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		mu.Lock()
+		reqs = append(reqs, req)
+		mu.Unlock()
+		result, rpcErr := handle(req)
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if rpcErr != nil {
+			resp["error"] = rpcErr
+		} else {
+			resp["result"] = result
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs
+}
+
+func TestStartDeal(t *testing.T) {
+	srv, reqs := fakeNode(t, func(req rpcRequest) (interface{}, *lotus.Error) {
+		switch req.Method {
+		case "Filecoin.WalletDefaultAddress":
+			return "f1default", nil
+		case "Filecoin.ClientStartDeal":
+			return lotus.Cid{Root: "bafyproposal"}, nil
+		}
+		return nil, &lotus.Error{Code: -32601, Message: "method not found"}
+	})
+	c := lotus.NewClient(srv.URL, "secret")
+	proposal, err := c.ClientStartDeal(context.Background(), lotus.DealParams{Root: "bafyroot", Miner: "f01234", EpochPrice: "500", MinBlocksDuration: 518400})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proposal != "bafyproposal" {
+		t.Errorf("proposal %q", proposal)
+	}
+	if len(*reqs) != 2 || (*reqs)[0].Method != "Filecoin.WalletDefaultAddress" {
+		t.Fatalf("requests %+v, want the default wallet looked up first", *reqs)
+	}
+	var params struct {
+		Data struct {
+			Root lotus.Cid
+		}
+		Wallet, Miner string
+	}
+	deal := (*reqs)[1]
+	if len(deal.Params) != 1 || json.Unmarshal(deal.Params[0], &params) != nil {
+		t.Fatalf("ClientStartDeal params %s", deal.Params)
+	}
+	if params.Data.Root.Root != "bafyroot" || params.Wallet != "f1default" || params.Miner != "f01234" || deal.Token != "secret" {
+		t.Errorf("ClientStartDeal sent %+v with token %q", params, deal.Token)
+	}
+
+	_, err = c.ClientImport(context.Background(), "/data/x.car")
+	var rpcErr *lotus.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32601 {
+		t.Errorf("unknown method: %v", err)
+	}
+	var ref struct {
+		Path  string
+		IsCAR bool
+	}
+	json.Unmarshal((*reqs)[2].Params[0], &ref)
+	if ref.Path != "/data/x.car" || !ref.IsCAR {
+		t.Errorf("ClientImport sent %+v", ref)
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "token invalid", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	_, err := lotus.NewClient(srv.URL, "x").WalletDefaultAddress(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "token invalid") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestDefaultToken(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv, reqs := fakeNode(t, func(rpcRequest) (interface{}, *lotus.Error) { return "f1", nil })
+	t.Setenv("LOTUS_PATH", dir)
+	for _, env := range []string{"", "from-env"} {
+		t.Setenv("LOTUS_TOKEN", env)
+		if _, err := lotus.NewClient(srv.URL, "").WalletDefaultAddress(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := []string{(*reqs)[0].Token, (*reqs)[1].Token}; got[0] != "from-file" || got[1] != "from-env" {
+		t.Errorf("tokens %q, want from-file then from-env", got)
+	}
+}
+
+func TestWaitDeal(t *testing.T) {
+	tests := []struct {
+		name   string
+		states []lotus.DealState
+		target lotus.DealState
+		want   lotus.DealState
+		failed bool
+	}{
+		{"published", []lotus.DealState{lotus.StorageDealProposalAccepted, lotus.StorageDealPublishing, lotus.StorageDealAwaitingPreCommit, lotus.StorageDealSealing}, lotus.StorageDealStaged, lotus.StorageDealAwaitingPreCommit, false},
+		{"active", []lotus.DealState{lotus.StorageDealStaged, lotus.StorageDealSealing, lotus.StorageDealActive}, lotus.StorageDealActive, lotus.StorageDealActive, false},
+		{"failed", []lotus.DealState{lotus.StorageDealProposalAccepted, lotus.StorageDealError}, lotus.StorageDealActive, lotus.StorageDealError, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			polls := 0
+			srv, _ := fakeNode(t, func(rpcRequest) (interface{}, *lotus.Error) {
+				mu.Lock()
+				defer mu.Unlock()
+				state := tt.states[min(polls, len(tt.states)-1)]
+				polls++
+				return lotus.DealInfo{State: state, Message: state.String()}, nil
+			})
+			var updates []lotus.DealState
+			info, err := lotus.NewClient(srv.URL, "x").WaitDeal(context.Background(), "bafyproposal", tt.target, time.Millisecond, func(info *lotus.DealInfo) {
+				updates = append(updates, info.State)
+			})
+			var failed *lotus.DealFailedError
+			if errors.As(err, &failed) != tt.failed || (!tt.failed && err != nil) {
+				t.Fatalf("err = %v", err)
+			}
+			if info.State != tt.want {
+				t.Errorf("stopped at %s, want %s", info.State, tt.want)
+			}
+			if len(updates) != polls {
+				t.Errorf("%d updates for %d distinct states", len(updates), polls)
+			}
+		})
+	}
+
+	if _, err := lotus.NewClient("http://127.0.0.1:1", "x").WaitDeal(context.Background(), "p", lotus.StorageDealError, time.Millisecond, nil); err == nil {
+		t.Error("waited for a state outside the deal lifecycle")
+	}
+}
+
+func TestDealStateString(t *testing.T) {
+	if s := lotus.StorageDealActive.String(); s != "StorageDealActive" {
+		t.Errorf("StorageDealActive.String() = %q", s)
+	}
+	if s := lotus.DealState(99).String(); s != "DealState(99)" {
+		t.Errorf("DealState(99).String() = %q", s)
+	}
+}
@@ -4,17 +4,21 @@
 // from Go, select a backend, and record an outcome.
 //
 // Prerequisites:
-// 1. Go installed (version 1.13+)
+// 1. Go installed (version 1.24+; cidutil uses crypto/sha3)
 // 2. Protobuf compiler (protoc) installed
 // 3. Go gRPC packages installed:
 //    go install google.golang.org/protobuf/cmd/protoc-gen-go@latest
 //    go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@latest
 //
-// To generate code from the proto file:
-//    protoc --go_out=. --go-grpc_out=. routing.proto
+// To generate the routing package from the proto file:
+//    mkdir -p routing
+//    protoc -I ../../../ipfs_kit_py/routing/protos \
+//      --go_out=routing --go_opt=paths=source_relative,Mrouting.proto=example.com/ipfs_kit_py/routing \
+//      --go-grpc_out=routing --go-grpc_opt=paths=source_relative,Mrouting.proto=example.com/ipfs_kit_py/routing \
+//      routing.proto
 //
 // To build this example:
-//    go build -o routing_client main.go
+//    go build -o routing_client .
 //
 // To run this example:
 //    ./routing_client
@@ -24,27 +28,73 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
 	"os"
 	"time"
 
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	// Update this import path to match your generated code location
+	"example.com/ipfs_kit_py/p2p"
 	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
 )
 
 var (
-	serverAddr = flag.String("server", "localhost:50051", "The server address in the format host:port")
+	serverAddr = flag.String("server", "localhost:50051", "The server address in the format host:port, unix:///path/to/socket, an http(s) gRPC-Web gateway URL, or a libp2p multiaddr ending in /p2p/<peer ID>")
 	jsonOutput = flag.Bool("json", false, "Output in JSON format")
+
+	// With -dry-run the server explains its decisions without counting or
+	// learning from them, and no outcomes are recorded
+	dryRun = flag.Bool("dry-run", false, "Ask for decisions without affecting learning state, and record no outcomes")
+
+	// With -rest-fallback set, calls degrade to the HTTP API if the gRPC
+	// port is blocked
+	restFallback = flag.String("rest-fallback", "", "HTTP routing API base URL to use when the gRPC server is unreachable")
+
+	// -record captures the run's RPCs so -replay can reproduce it without
+	// the server
+	record = flag.String("record", "", "Record every RPC to this file")
+	replay = flag.String("replay", "", "Serve RPCs from a file written by -record instead of the server")
+
+	timeout         = flag.Duration("timeout", 30*time.Second, "Overall deadline for the whole run (0 for none)")
+	selectTimeout   = flag.Duration("select-timeout", 2*time.Second, "Deadline for each SelectBackend call")
+	outcomeTimeout  = flag.Duration("outcome-timeout", 2*time.Second, "Deadline for each RecordOutcome call")
+	insightsTimeout = flag.Duration("insights-timeout", 5*time.Second, "Deadline for the GetInsights call")
+
+	// Keepalive is off by default; long-lived agents behind NAT should set
+	// -keepalive-time below the NAT idle timeout
+	keepaliveParams routingclient.Keepalive
+
+	// Compression is off by default; -compression gzip shrinks metadata
+	// Structs and insights responses at some CPU cost
+	compressionParams routingclient.Compression
+
+	// Proxy defaults to $HTTPS_PROXY / $NO_PROXY; -proxy overrides it
+	proxyParams routingclient.Proxy
 )
 
+func init() {
+	keepaliveParams.RegisterFlags(flag.CommandLine)
+	compressionParams.RegisterFlags(flag.CommandLine)
+	proxyParams.RegisterFlags(flag.CommandLine)
+}
+
+// callContext derives a context for a single RPC, bounded by both the
+// per-call timeout and the deadline of parent
+func callContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
 // ContentInfo represents the content metadata
 type ContentInfo struct {
 	ContentType string            `json:"content_type"`
@@ -84,19 +134,52 @@ func printResult(data interface{}) {
 
 func main() {
 	flag.Parse()
+	// Multiaddr servers are dialed over libp2p with the key at
+	// $ROUTING_P2P_KEY
+	p2p.Register()
 
-	// Set up a connection to the server
-	conn, err := grpc.Dial(*serverAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// Set up a connection to the server. Every RPC carries an
+	// x-correlation-id header and is logged with it.
+	opts := []routingclient.Option{
+		routingclient.WithKeepalive(keepaliveParams),
+		routingclient.WithCompression(compressionParams),
+		routingclient.WithProxy(proxyParams),
+		routingclient.WithLogger(log.Printf),
+	}
+	if *restFallback != "" {
+		opts = append(opts, routingclient.WithRESTFallback(*restFallback))
+	}
+	if *record != "" {
+		f, err := os.Create(*record)
+		if err != nil {
+			log.Fatalf("Failed to record: %v", err)
+		}
+		defer f.Close()
+		rec, err := routingclient.NewRecorder(f)
+		if err != nil {
+			log.Fatalf("Failed to record: %v", err)
+		}
+		opts = append(opts, routingclient.WithRecorder(rec))
+	}
+	if *replay != "" {
+		conn, err := routingclient.OpenReplay(*replay)
+		if err != nil {
+			log.Fatalf("Failed to replay: %v", err)
+		}
+		opts = append(opts, routingclient.WithReplay(conn))
+	}
+	rc, err := routingclient.New(*serverAddr, opts...)
 	if err != nil {
 		log.Fatalf("Failed to connect: %v", err)
 	}
-	defer conn.Close()
-	client := pb.NewRoutingServiceClient(conn)
+	defer rc.Close()
+	client := rc.RPC()
 
 	log.Printf("Connected to server at %s", *serverAddr)
 
-	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// The overall deadline bounds the whole run; each RPC below gets its
+	// own, shorter deadline derived from it
+	ctx, cancel := callContext(context.Background(), *timeout)
 	defer cancel()
 
 	// Sample content types
@@ -127,6 +210,10 @@ func main() {
 	// Try different routing strategies
 	strategies := []string{"content_type", "cost", "performance", "hybrid"}
 	for _, strategy := range strategies {
+		// One correlation ID ties the selection and its outcome together
+		correlationID := routingclient.NewCorrelationID()
+		opCtx := routingclient.WithCorrelationID(ctx, correlationID)
+
 		// Create request
 		req := &pb.SelectBackendRequest{
 			ContentType: contentInfo.ContentType,
@@ -134,12 +221,15 @@ func main() {
 			ContentHash: contentInfo.ContentHash,
 			Metadata:    metadataStruct,
 			Strategy:    strategy,
-			RequestId:   fmt.Sprintf("go-client-%d", rand.Int()),
+			RequestId:   correlationID,
 			Timestamp:   timestamppb.Now(),
+			DryRun:      *dryRun,
 		}
 
 		// Call SelectBackend
-		resp, err := client.SelectBackend(ctx, req)
+		selectCtx, selectCancel := callContext(opCtx, *selectTimeout)
+		resp, err := client.SelectBackend(selectCtx, req, compressionParams.CallOption(true))
+		selectCancel()
 		if err != nil {
 			log.Fatalf("Failed to select backend: %v", err)
 		}
@@ -150,7 +240,10 @@ func main() {
 			"backend_id":  resp.BackendId,
 			"score":       resp.Score,
 			"request_id":  resp.RequestId,
+			"correlation_id": correlationID,
 			"timestamp":   resp.Timestamp.AsTime().Format(time.RFC3339),
+			"reasoning":   resp.Reasoning,
+			"dry_run":     resp.DryRun,
 			"alternatives": make([]map[string]interface{}, 0),
 		}
 
@@ -164,22 +257,28 @@ func main() {
 
 		log.Printf("Strategy '%s' selected backend: %s with score %.2f", 
 			strategy, resp.BackendId, resp.Score)
+		if *dryRun {
+			log.Printf("Dry run: %s", resp.Reasoning)
+			printResult(result)
+			continue
+		}
 
-		// Simulate operation success (80% success rate)
-		success := rand.Float32() < 0.8
-
-		// Record outcome
-		outcomeReq := &pb.RecordOutcomeRequest{
-			BackendId:   resp.BackendId,
-			Success:     success,
+		// Simulate the transfer. The operation times it and counts the
+		// bytes moved; 20% of transfers fail.
+		op := rc.StartOperation(opCtx, routingclient.ContentInfo{
 			ContentType: contentInfo.ContentType,
 			ContentSize: contentInfo.ContentSize,
 			ContentHash: contentInfo.ContentHash,
-			DurationMs:  int32(rand.Intn(490) + 10), // 10-500ms
-			Timestamp:   timestamppb.Now(),
+			Filename:    contentInfo.Filename,
+		}, resp)
+		_, err = io.Copy(op.Writer(io.Discard), io.LimitReader(rand.New(rand.NewSource(time.Now().UnixNano())), contentInfo.ContentSize))
+		if err == nil && rand.Float32() >= 0.8 {
+			err = errors.New("simulated transfer failure")
 		}
-
-		outcomeResp, err := client.RecordOutcome(ctx, outcomeReq)
+		// The outcome timeout covers only the RPC, not the transfer
+		outcomeCtx, outcomeCancel := callContext(opCtx, *outcomeTimeout)
+		outcomeResp, err := op.FinishContext(outcomeCtx, err)
+		outcomeCancel()
 		if err != nil {
 			log.Fatalf("Failed to record outcome: %v", err)
 		}
@@ -192,7 +291,9 @@ func main() {
 		TimeWindowHours: 24,
 	}
 
-	insightsResp, err := client.GetInsights(ctx, insightsReq)
+	insightsCtx, insightsCancel := callContext(ctx, *insightsTimeout)
+	defer insightsCancel()
+	insightsResp, err := client.GetInsights(insightsCtx, insightsReq)
 	if err != nil {
 		log.Fatalf("Failed to get insights: %v", err)
 	}
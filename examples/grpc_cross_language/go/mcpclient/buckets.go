@@ -0,0 +1,313 @@
+package mcpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// BucketPolicy is the replication and retention policy attached to a bucket
+type BucketPolicy struct {
+	ReplicationFactor int    `json:"replication_factor"`
+	CachePolicy       string `json:"cache_policy"`
+	RetentionDays     int    `json:"retention_days"`
+}
+
+// Bucket describes a bucket as reported by list_buckets and get_bucket
+type Bucket struct {
+	Name        string       `json:"name"`
+	Backend     string       `json:"backend"`
+	Backends    []string     `json:"backends,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Status      string       `json:"status,omitempty"`
+	Tier        string       `json:"tier,omitempty"`
+	CreatedAt   string       `json:"created_at"`
+	Size        int64        `json:"size"`
+	FileCount   int          `json:"file_count"`
+	FolderCount int          `json:"folder_count"`
+	Policy      BucketPolicy `json:"policy"`
+}
+
+// BucketFile is an entry returned by ListFiles
+type BucketFile struct {
+	Name        string            `json:"name"`
+	Path        string            `json:"path"`
+	IsDir       bool              `json:"is_dir"`
+	Size        int64             `json:"size"`
+	MimeType    string            `json:"mime_type,omitempty"`
+	Modified    string            `json:"modified"`
+	StoragePath string            `json:"storage_path,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// BucketUsage is the storage a bucket uses, as reported by
+// get_bucket_usage. Quota is the bucket's storage_quota setting in bytes,
+// zero if it has none.
+type BucketUsage struct {
+	Bucket    string `json:"bucket"`
+	Size      int64  `json:"total_size_bytes"`
+	FileCount int    `json:"file_count"`
+	Quota     int64  `json:"storage_quota"`
+	Error     string `json:"error,omitempty"`
+}
+
+// UploadResult is returned by UploadFile
+type UploadResult struct {
+	Path   string `json:"file_path"`
+	Size   int64  `json:"file_size"`
+	Bucket string `json:"bucket_name"`
+	Method string `json:"upload_method"`
+}
+
+// decodeBucketResult decodes a bucket tool result. Most of the file tools
+// still wrap their payload in a legacy {"jsonrpc", "result"} envelope, which
+// is unwrapped here so callers see the same shape either way.
+func decodeBucketResult(tool string, res *ToolResult, out interface{}) error {
+	var envelope struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Result  json.RawMessage `json:"result"`
+	}
+	raw := res.StructuredContent
+	if len(raw) == 0 || string(raw) == "null" {
+		raw = json.RawMessage(res.Text())
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		if err := json.Unmarshal(raw, &envelope); err == nil && envelope.JSONRPC != "" {
+			raw = envelope.Result
+		}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("mcp: %s: %w", tool, err)
+	}
+	return nil
+}
+
+func (c *Client) bucketTool(ctx context.Context, tool string, args map[string]interface{}, out interface{}) error {
+	res, err := c.CallTool(ctx, tool, args)
+	if err != nil {
+		return err
+	}
+	return decodeBucketResult(tool, res, out)
+}
+
+// ListBuckets returns all buckets known to the dashboard
+func (c *Client) ListBuckets(ctx context.Context) ([]Bucket, error) {
+	var buckets []Bucket
+	if err := c.bucketTool(ctx, "list_buckets", nil, &buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// GetBucket returns a single bucket by name
+func (c *Client) GetBucket(ctx context.Context, name string) (*Bucket, error) {
+	var b Bucket
+	if err := c.bucketTool(ctx, "get_bucket", map[string]interface{}{"name": name}, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// CreateBucket creates a bucket. backend may be empty to use the server's
+// default.
+func (c *Client) CreateBucket(ctx context.Context, name, backend string) (*Bucket, error) {
+	args := map[string]interface{}{"name": name}
+	if backend != "" {
+		args["backend"] = backend
+	}
+	var b Bucket
+	if err := c.bucketTool(ctx, "create_bucket", args, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// DeleteBucket deletes a bucket and its contents. It reports whether the
+// bucket existed.
+func (c *Client) DeleteBucket(ctx context.Context, name string) (bool, error) {
+	var out struct {
+		Status string `json:"status"`
+	}
+	if err := c.bucketTool(ctx, "delete_bucket", map[string]interface{}{"name": name}, &out); err != nil {
+		return false, err
+	}
+	return out.Status == "deleted", nil
+}
+
+// GetBucketUsage returns the size of bucket's files and its storage quota
+func (c *Client) GetBucketUsage(ctx context.Context, bucket string) (*BucketUsage, error) {
+	var u BucketUsage
+	if err := c.bucketTool(ctx, "get_bucket_usage", map[string]interface{}{"name": bucket}, &u); err != nil {
+		return nil, err
+	}
+	if u.Error != "" {
+		return nil, fmt.Errorf("mcp: get_bucket_usage: %s", u.Error)
+	}
+	return &u, nil
+}
+
+// ListFiles lists the entries under dir in bucket ("" lists the root)
+func (c *Client) ListFiles(ctx context.Context, bucket, dir string) ([]BucketFile, error) {
+	args := map[string]interface{}{"bucket": bucket, "path": NormalizePath(dir), "show_metadata": false}
+	var out struct {
+		Items []BucketFile `json:"items"`
+	}
+	if err := c.bucketTool(ctx, "bucket_list_files", args, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// Mkdir creates dir in bucket, including any missing parents
+func (c *Client) Mkdir(ctx context.Context, bucket, dir string) error {
+	args := map[string]interface{}{"bucket": bucket, "path": NormalizePath(dir), "create_parents": true}
+	return c.bucketTool(ctx, "bucket_mkdir", args, nil)
+}
+
+// UploadFile stores data at dst in bucket. Content is sent base64 encoded so
+// binary files survive the JSON transport. Like the other file methods, dst
+// is passed through NormalizePath first, and like UploadFolder it fails with
+// a *PathConflictError if dst or one of its directories collides with an
+// existing entry.
+func (c *Client) UploadFile(ctx context.Context, bucket, dst string, data []byte) (*UploadResult, error) {
+	dst = NormalizePath(dst)
+	local := pathSet{}
+	for p := dst; p != "."; p = path.Dir(p) {
+		local.add(p)
+	}
+	if err := c.checkRemoteConflicts(ctx, bucket, local); err != nil {
+		return nil, err
+	}
+	return c.upload(ctx, bucket, dst, data)
+}
+
+// upload stores data at the normalized path dst in bucket
+func (c *Client) upload(ctx context.Context, bucket, dst string, data []byte) (*UploadResult, error) {
+	args := map[string]interface{}{
+		"bucket":       bucket,
+		"path":         dst,
+		"content":      base64.StdEncoding.EncodeToString(data),
+		"mode":         "base64",
+		"apply_policy": true,
+	}
+	var out UploadResult
+	if err := c.bucketTool(ctx, "bucket_upload_file", args, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UploadFolder uploads every regular file under dir to bucket, preserving
+// the relative layout beneath prefix. Before anything is uploaded, paths
+// are checked for collisions that differ only in case or unicode form,
+// both within dir and against entries already in the bucket; any such
+// collision aborts the upload with a *PathConflictError.
+func (c *Client) UploadFolder(ctx context.Context, bucket, dir, prefix string) ([]UploadResult, error) {
+	type entry struct {
+		src, dst string
+		dir      bool
+	}
+	var entries []entry
+	local := pathSet{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		dst := NormalizePath(path.Join(prefix, filepath.ToSlash(rel)))
+		if dst == "." || (!d.IsDir() && !d.Type().IsRegular()) {
+			return nil
+		}
+		if err := local.add(dst); err != nil {
+			return err
+		}
+		entries = append(entries, entry{src: p, dst: dst, dir: d.IsDir()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkRemoteConflicts(ctx, bucket, local); err != nil {
+		return nil, err
+	}
+
+	var results []UploadResult
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		if e.dir {
+			if err := c.Mkdir(ctx, bucket, e.dst); err != nil {
+				return results, err
+			}
+			continue
+		}
+		data, err := os.ReadFile(e.src)
+		if err != nil {
+			return results, err
+		}
+		res, err := c.upload(ctx, bucket, e.dst, data)
+		if err != nil {
+			return results, fmt.Errorf("upload %s: %w", e.dst, err)
+		}
+		results = append(results, *res)
+	}
+	return results, nil
+}
+
+// checkRemoteConflicts lists each parent directory of the paths in local
+// and reports existing entries that collide with them
+func (c *Client) checkRemoteConflicts(ctx context.Context, bucket string, local pathSet) error {
+	dirs := map[string]bool{}
+	for _, p := range local {
+		dirs[path.Dir(p)] = true
+	}
+	for d := range dirs {
+		files, err := c.ListFiles(ctx, bucket, d)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			remote := NormalizePath(f.Path)
+			if p, ok := local[foldPath(remote)]; ok && p != remote {
+				return &PathConflictError{Path: p, Conflict: remote}
+			}
+		}
+	}
+	return nil
+}
+
+// DownloadFile returns the content stored at src in bucket
+func (c *Client) DownloadFile(ctx context.Context, bucket, src string) ([]byte, error) {
+	args := map[string]interface{}{"bucket": bucket, "path": NormalizePath(src), "format": "base64"}
+	var out struct {
+		Content string `json:"content"`
+	}
+	if err := c.bucketTool(ctx, "bucket_download_file", args, &out); err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(out.Content)
+	if err != nil {
+		return nil, fmt.Errorf("mcp: bucket_download_file: %w", err)
+	}
+	return data, nil
+}
+
+// DeleteFile removes a file or directory from bucket along with its
+// replicas
+func (c *Client) DeleteFile(ctx context.Context, bucket, p string) error {
+	args := map[string]interface{}{"bucket": bucket, "path": NormalizePath(p), "remove_replicas": true}
+	return c.bucketTool(ctx, "bucket_delete_file", args, nil)
+}
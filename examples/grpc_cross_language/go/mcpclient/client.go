@@ -0,0 +1,232 @@
+// Package mcpclient is a Go client for the MCP dashboard's JSON-RPC
+// endpoint, allowing Go services to list and invoke the same tools the web
+// dashboard uses.
+package mcpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultBaseURL is the default address of the MCP dashboard
+const DefaultBaseURL = "http://localhost:8081"
+
+// ProtocolVersion is the MCP protocol version sent in initialize
+const ProtocolVersion = "2024-11-05"
+
+// RPCError is a JSON-RPC error returned by the server
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp: %s (code %d)", e.Message, e.Code)
+}
+
+// Client calls the dashboard's JSON-RPC endpoint over HTTP
+type Client struct {
+	endpoint string
+	token    string
+	http     *http.Client
+	id       atomic.Int64
+}
+
+// NewClient creates a client for the dashboard at baseURL (DefaultBaseURL
+// if empty). token is sent as x-api-token when the dashboard requires it.
+func NewClient(baseURL, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		endpoint: strings.TrimRight(baseURL, "/") + "/mcp",
+		token:    token,
+		http:     http.DefaultClient,
+	}
+}
+
+// Call sends a JSON-RPC request and decodes the result into out (if non-nil)
+func (c *Client) Call(ctx context.Context, method string, params, out interface{}) error {
+	msg := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"id":      c.id.Add(1),
+	}
+	if params != nil {
+		msg["params"] = params
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("x-api-token", c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("mcp: %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("mcp: %s: HTTP %s: %s", method, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *RPCError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("mcp: %s: decode response: %w", method, err)
+	}
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+	if out != nil {
+		if err := json.Unmarshal(rpcResp.Result, out); err != nil {
+			return fmt.Errorf("mcp: %s: decode result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// ServerInfo is returned by Initialize
+type ServerInfo struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ServerInfo      struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"serverInfo"`
+}
+
+// Initialize performs the MCP handshake
+func (c *Client) Initialize(ctx context.Context) (*ServerInfo, error) {
+	params := map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]string{"name": "ipfs-kit-go", "version": "1.0.0"},
+	}
+	var info ServerInfo
+	if err := c.Call(ctx, "initialize", params, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Ping checks that the JSON-RPC endpoint is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	return c.Call(ctx, "ping", nil, nil)
+}
+
+// Tool describes a tool advertised by the server
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// ListTools returns the tools advertised by tools/list
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var out struct {
+		Tools []Tool `json:"tools"`
+	}
+	if err := c.Call(ctx, "tools/list", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Tools, nil
+}
+
+// Content is a content block in a tool result
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// ToolResult is the result of a tools/call request
+type ToolResult struct {
+	Content           []Content       `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError"`
+}
+
+// Text returns the concatenated text content of the result
+func (r *ToolResult) Text() string {
+	var parts []string
+	for _, c := range r.Content {
+		if c.Type == "text" {
+			parts = append(parts, c.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Decode unmarshals the structured result into out, falling back to the
+// JSON text content for servers that only return text
+func (r *ToolResult) Decode(out interface{}) error {
+	if len(r.StructuredContent) > 0 && string(r.StructuredContent) != "null" {
+		return json.Unmarshal(r.StructuredContent, out)
+	}
+	return json.Unmarshal([]byte(r.Text()), out)
+}
+
+// ToolError is returned by CallTool when the tool reports a failure
+type ToolError struct {
+	Tool    string
+	Message string
+}
+
+func (e *ToolError) Error() string {
+	return fmt.Sprintf("mcp: tool %s failed: %s", e.Tool, e.Message)
+}
+
+// CallTool invokes a tool with the given arguments
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (*ToolResult, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	params := map[string]interface{}{"name": name, "arguments": args}
+
+	var res ToolResult
+	if err := c.Call(ctx, "tools/call", params, &res); err != nil {
+		return nil, err
+	}
+	if res.IsError {
+		return &res, &ToolError{Tool: name, Message: res.Text()}
+	}
+	return &res, nil
+}
+
+// Health is the result of the health_check tool
+type Health struct {
+	Status    string `json:"status"`
+	Timestamp string `json:"timestamp"`
+}
+
+// HealthCheck calls the health_check tool
+func (c *Client) HealthCheck(ctx context.Context) (*Health, error) {
+	res, err := c.CallTool(ctx, "health_check", nil)
+	if err != nil {
+		return nil, err
+	}
+	var h Health
+	if err := res.Decode(&h); err != nil {
+		return nil, fmt.Errorf("mcp: health_check: %w", err)
+	}
+	return &h, nil
+}
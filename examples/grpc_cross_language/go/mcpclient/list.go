@@ -0,0 +1,115 @@
+package mcpclient
+
+import (
+	"context"
+	"time"
+)
+
+// ListOptions filters, sorts and pages a list call. Zero values leave the
+// corresponding argument unset.
+type ListOptions struct {
+	// Prefix matches the start of an entry's name or path
+	Prefix string
+	// Type is "file", "directory" or a MIME type prefix such as "image/"
+	Type           string
+	MinSize        int64
+	MaxSize        int64
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+	// SortBy names the sort field; Desc reverses the order
+	SortBy string
+	Desc   bool
+	// Limit is the page size (server maximum 1000); Cursor continues from
+	// the NextCursor of a previous page
+	Limit  int
+	Cursor string
+}
+
+// args adds the options to tool arguments
+func (o ListOptions) args(args map[string]interface{}) map[string]interface{} {
+	set := func(k string, v interface{}, ok bool) {
+		if ok {
+			args[k] = v
+		}
+	}
+	set("prefix", o.Prefix, o.Prefix != "")
+	set("type", o.Type, o.Type != "")
+	set("min_size", o.MinSize, o.MinSize > 0)
+	set("max_size", o.MaxSize, o.MaxSize > 0)
+	set("modified_after", o.ModifiedAfter.Format(time.RFC3339), !o.ModifiedAfter.IsZero())
+	set("modified_before", o.ModifiedBefore.Format(time.RFC3339), !o.ModifiedBefore.IsZero())
+	set("sort_by", o.SortBy, o.SortBy != "")
+	set("order", "desc", o.Desc)
+	set("limit", o.Limit, o.Limit > 0)
+	set("cursor", o.Cursor, o.Cursor != "")
+	return args
+}
+
+// FilePage is one page of a bucket listing
+type FilePage struct {
+	Items []BucketFile `json:"items"`
+	// Total is the number of entries matching the filters
+	Total int `json:"total_count"`
+	// NextCursor continues the listing; empty on the last page
+	NextCursor string `json:"next_cursor"`
+}
+
+// ListFilesPage lists one page of the entries under dir in bucket
+func (c *Client) ListFilesPage(ctx context.Context, bucket, dir string, opts ListOptions) (*FilePage, error) {
+	args := opts.args(map[string]interface{}{"bucket": bucket, "path": NormalizePath(dir), "show_metadata": false})
+	var page FilePage
+	if err := c.bucketTool(ctx, "bucket_list_files", args, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// WalkFiles calls fn for every entry under dir matching opts, fetching
+// pages of opts.Limit entries (100 if unset) until the listing ends or fn
+// returns an error
+func (c *Client) WalkFiles(ctx context.Context, bucket, dir string, opts ListOptions, fn func(BucketFile) error) error {
+	if opts.Limit <= 0 {
+		opts.Limit = 100
+	}
+	for {
+		page, err := c.ListFilesPage(ctx, bucket, dir, opts)
+		if err != nil {
+			return err
+		}
+		for _, f := range page.Items {
+			if err := fn(f); err != nil {
+				return err
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// Pin is a pinned CID as reported by list_pins
+type Pin struct {
+	CID  string `json:"cid"`
+	Name string `json:"name,omitempty"`
+}
+
+// PinPage is one page of the pin list
+type PinPage struct {
+	Items      []Pin  `json:"items"`
+	Total      int    `json:"total_count"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// ListPins lists one page of pins. Pins support Prefix (on CID or name),
+// SortBy "cid" or "name", Desc, Limit and Cursor.
+func (c *Client) ListPins(ctx context.Context, opts ListOptions) (*PinPage, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 100
+	}
+	var page PinPage
+	if err := c.bucketTool(ctx, "list_pins_page", opts.args(map[string]interface{}{}), &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
@@ -0,0 +1,235 @@
+package mcpclient_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"example.com/ipfs_kit_py/mcpclient"
+)
+
+// toolFunc answers a tools/call with a result, which is sent as
+// structured content, or a tool error message
+type toolFunc func(args map[string]interface{}) (result interface{}, toolErr string)
+
+// fakeDashboard serves the dashboard's JSON-RPC endpoint, answering
+// tools/call with tools and recording the arguments of every call
+type fakeDashboard struct {
+	mu    sync.Mutex
+	tools map[string]toolFunc
+	calls []string
+	args  []map[string]interface{}
+	// text sends results as JSON text content rather than structured
+	text bool
+}
+
+func (f *fakeDashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/mcp" || r.Header.Get("x-api-token") != "secret" {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	var req struct {
+		Method string `json:"method"`
+		ID     int64  `json:"id"`
+		Params struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+		} `json:"params"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	tool, ok := f.tools[req.Params.Name]
+	switch {
+	case req.Method != "tools/call":
+		resp["error"] = mcpclient.RPCError{Code: -32601, Message: "method not found"}
+	case !ok:
+		resp["error"] = mcpclient.RPCError{Code: -32602, Message: "unknown tool " + req.Params.Name}
+	default:
+		f.mu.Lock()
+		f.calls = append(f.calls, req.Params.Name)
+		f.args = append(f.args, req.Params.Arguments)
+		f.mu.Unlock()
+		result, toolErr := tool(req.Params.Arguments)
+		if toolErr != "" {
+			resp["result"] = mcpclient.ToolResult{IsError: true, Content: []mcpclient.Content{{Type: "text", Text: toolErr}}}
+			break
+		}
+		data, _ := json.Marshal(result)
+		if f.text {
+			resp["result"] = mcpclient.ToolResult{Content: []mcpclient.Content{{Type: "text", Text: string(data)}}}
+		} else {
+			resp["result"] = mcpclient.ToolResult{StructuredContent: data}
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func newDashboard(t *testing.T, tools map[string]toolFunc) (*fakeDashboard, *mcpclient.Client) {
+	t.Helper()
+	f, url := serveDashboard(t, tools)
+	return f, mcpclient.NewClient(url+"/", "secret")
+}
+
+func serveDashboard(t *testing.T, tools map[string]toolFunc) (*fakeDashboard, string) {
+	t.Helper()
+	f := &fakeDashboard{tools: tools}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, srv.URL
+}
+
+// envelope wraps v in the legacy JSON-RPC envelope the file tools return
+func envelope(v interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "result": v}
+}
+
+func TestCallTool(t *testing.T) {
+	_, url := serveDashboard(t, map[string]toolFunc{
+		"health_check": func(map[string]interface{}) (interface{}, string) {
+			return mcpclient.Health{Status: "healthy", Timestamp: "2026-01-02T03:04:05Z"}, ""
+		},
+		"ipfs_add": func(map[string]interface{}) (interface{}, string) { return nil, "daemon not running" },
+	})
+	c := mcpclient.NewClient(url, "secret")
+	ctx := context.Background()
+	h, err := c.HealthCheck(ctx)
+	if err != nil || h.Status != "healthy" {
+		t.Errorf("health %+v, %v", h, err)
+	}
+	var toolErr *mcpclient.ToolError
+	if _, err := c.CallTool(ctx, "ipfs_add", nil); !errors.As(err, &toolErr) || toolErr.Message != "daemon not running" {
+		t.Errorf("failing tool: %v", err)
+	}
+	var rpcErr *mcpclient.RPCError
+	if _, err := c.CallTool(ctx, "missing", nil); !errors.As(err, &rpcErr) || rpcErr.Code != -32602 {
+		t.Errorf("unknown tool: %v", err)
+	}
+	if err := mcpclient.NewClient(url, "wrong").Ping(ctx); err == nil {
+		t.Error("ping with a wrong token succeeded")
+	}
+}
+
+// TestBucketResults checks bucket tools decode the same whether the
+// result is structured or text, wrapped in the legacy envelope or not
+func TestBucketResults(t *testing.T) {
+	bucket := mcpclient.Bucket{Name: "datasets", Backend: "ipfs", Size: 42}
+	for _, text := range []bool{false, true} {
+		f, c := newDashboard(t, map[string]toolFunc{
+			"list_buckets": func(map[string]interface{}) (interface{}, string) { return []mcpclient.Bucket{bucket}, "" },
+			"get_bucket":   func(map[string]interface{}) (interface{}, string) { return envelope(bucket), "" },
+			"bucket_download_file": func(map[string]interface{}) (interface{}, string) {
+				return envelope(map[string]string{"content": base64.StdEncoding.EncodeToString([]byte("\x00binary"))}), ""
+			},
+		})
+		f.text = text
+		ctx := context.Background()
+		buckets, err := c.ListBuckets(ctx)
+		if err != nil || len(buckets) != 1 || buckets[0].Name != "datasets" {
+			t.Errorf("text %v: buckets %+v, %v", text, buckets, err)
+		}
+		b, err := c.GetBucket(ctx, "datasets")
+		if err != nil || b.Size != 42 {
+			t.Errorf("text %v: bucket %+v, %v", text, b, err)
+		}
+		data, err := c.DownloadFile(ctx, "datasets", "/dir/../file.bin")
+		if err != nil || string(data) != "\x00binary" {
+			t.Errorf("text %v: downloaded %q, %v", text, data, err)
+		}
+		if p := f.args[2]["path"]; p != "file.bin" {
+			t.Errorf("downloaded path %v, want it normalized", p)
+		}
+	}
+}
+
+func TestUploadConflicts(t *testing.T) {
+	remote := map[string][]mcpclient.BucketFile{
+		".":    {{Name: "Docs", Path: "Docs", IsDir: true}},
+		"Docs": {{Name: "readme.md", Path: "Docs/readme.md"}},
+	}
+	f, c := newDashboard(t, map[string]toolFunc{
+		"bucket_list_files": func(args map[string]interface{}) (interface{}, string) {
+			return envelope(map[string]interface{}{"items": remote[args["path"].(string)]}), ""
+		},
+		"bucket_upload_file": func(args map[string]interface{}) (interface{}, string) {
+			data, _ := base64.StdEncoding.DecodeString(args["content"].(string))
+			return envelope(mcpclient.UploadResult{Path: args["path"].(string), Size: int64(len(data)), Bucket: args["bucket"].(string)}), ""
+		},
+		"bucket_mkdir": func(map[string]interface{}) (interface{}, string) {
+			return envelope(map[string]bool{"success": true}), ""
+		},
+	})
+	ctx := context.Background()
+	var conflict *mcpclient.PathConflictError
+	if _, err := c.UploadFile(ctx, "site", "docs/new.md", []byte("x")); !errors.As(err, &conflict) || conflict.Conflict != "Docs" {
+		t.Errorf("upload under a directory differing in case: %v", err)
+	}
+	res, err := c.UploadFile(ctx, "site", "Docs/new.md", []byte("hello"))
+	if err != nil || res.Path != "Docs/new.md" || res.Size != 5 {
+		t.Errorf("uploaded %+v, %v", res, err)
+	}
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "sub"), 0o755)
+	os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("a"), 0o644)
+	os.WriteFile(filepath.Join(dir, "sub", "A.txt"), []byte("A"), 0o644)
+	before := len(f.calls)
+	if _, err := c.UploadFolder(ctx, "site", dir, "Docs"); !errors.As(err, &conflict) {
+		t.Errorf("folder with names differing in case: %v", err)
+	}
+	if len(f.calls) != before {
+		t.Errorf("%v called for a conflicting folder, want nothing", f.calls[before:])
+	}
+	os.Remove(filepath.Join(dir, "sub", "A.txt"))
+	results, err := c.UploadFolder(ctx, "site", dir, "Docs")
+	if err != nil || len(results) != 1 || results[0].Path != "Docs/sub/a.txt" {
+		t.Errorf("uploaded folder %+v, %v", results, err)
+	}
+}
+
+func TestWalkFiles(t *testing.T) {
+	files := []mcpclient.BucketFile{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	f, c := newDashboard(t, map[string]toolFunc{
+		"bucket_list_files": func(args map[string]interface{}) (interface{}, string) {
+			start := 0
+			if cur, ok := args["cursor"].(string); ok {
+				start = int(cur[0] - '0')
+			}
+			end := min(start+int(args["limit"].(float64)), len(files))
+			page := mcpclient.FilePage{Items: files[start:end], Total: len(files)}
+			if end < len(files) {
+				page.NextCursor = string(rune('0' + end))
+			}
+			return envelope(page), ""
+		},
+	})
+	var names []string
+	err := c.WalkFiles(context.Background(), "site", "", mcpclient.ListOptions{Limit: 2, Prefix: "x", Desc: true}, func(f mcpclient.BucketFile) error {
+		names = append(names, f.Name)
+		return nil
+	})
+	if err != nil || len(names) != 3 || len(f.calls) != 2 {
+		t.Errorf("walked %v in %d calls, %v", names, len(f.calls), err)
+	}
+	if f.args[0]["prefix"] != "x" || f.args[0]["order"] != "desc" || f.args[0]["path"] != "." {
+		t.Errorf("list arguments %v", f.args[0])
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	for in, want := range map[string]string{
+		"/":                 ".",
+		"a\\b/../c":         "a/c",
+		"/docs//cafe\u0301": "docs/caf\u00e9",
+	} {
+		if got := mcpclient.NormalizePath(in); got != want {
+			t.Errorf("NormalizePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
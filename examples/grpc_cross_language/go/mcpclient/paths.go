@@ -0,0 +1,56 @@
+package mcpclient
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// PathConflictError is returned when two bucket paths differ only in case
+// or unicode normalization. They would silently overwrite each other on
+// case-insensitive or normalizing filesystems (macOS, Windows, FUSE and
+// WebDAV mounts), so uploads refuse them instead.
+type PathConflictError struct {
+	Path     string
+	Conflict string
+}
+
+func (e *PathConflictError) Error() string {
+	return fmt.Sprintf("mcp: path %q conflicts with %q (differs only in case or unicode form)", e.Path, e.Conflict)
+}
+
+// NormalizePath returns the canonical form of a bucket path: NFC
+// normalized, slash separated and cleaned, without a leading slash. macOS
+// produces NFD names, so without this the same file uploaded from two
+// machines would land under two different byte strings.
+func NormalizePath(p string) string {
+	p = norm.NFC.String(strings.ReplaceAll(p, "\\", "/"))
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
+
+// foldPath is the key two paths collide on: normalized and case folded
+func foldPath(p string) string {
+	return cases.Fold().String(NormalizePath(p))
+}
+
+// pathSet detects paths that collide after case folding
+type pathSet map[string]string
+
+// add records p, returning a PathConflictError if a different path with
+// the same folded form was already added
+func (s pathSet) add(p string) error {
+	p = NormalizePath(p)
+	key := foldPath(p)
+	if prev, ok := s[key]; ok && prev != p {
+		return &PathConflictError{Path: p, Conflict: prev}
+	}
+	s[key] = p
+	return nil
+}
@@ -0,0 +1,235 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+
+	"example.com/ipfs_kit_py/retrieval"
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// Agent reports a long-lived agent's own state for Prometheus to scrape:
+// its routing client's decision cache, offline log and REST fallback, the
+// backend states a watcher follows, outcome queues, gateway sources, the
+// streams open through StreamInterceptor, and the Go runtime's goroutines,
+// memory and GC. Handler serves it in the OpenMetrics format to scrapers
+// asking for it, and the Prometheus text format otherwise.
+type Agent struct {
+	reg     *prometheus.Registry
+	streams *prometheus.GaugeVec
+}
+
+// NewAgent returns an Agent reporting the Go runtime and the process
+func NewAgent() *Agent {
+	a := &Agent{
+		reg: prometheus.NewRegistry(),
+		streams: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "routing_agent_streams_open",
+			Help: "Streams to the routing service open, by method.",
+		}, []string{"method"}),
+	}
+	a.reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		a.streams,
+	)
+	return a
+}
+
+// Handler returns the HTTP handler serving the metrics
+func (a *Agent) Handler() http.Handler {
+	return promhttp.HandlerFor(a.reg, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
+
+// StreamInterceptor returns a stream interceptor counting the streams
+// open, e.g. for routingclient.WithStreamInterceptor. A stream stops
+// counting when a receive fails, io.EOF included, or its context ends.
+func (a *Agent) StreamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, err
+		}
+		open := a.streams.WithLabelValues(method)
+		open.Inc()
+		s := &countedStream{ClientStream: cs, end: open.Dec}
+		go func() {
+			<-cs.Context().Done()
+			s.close()
+		}()
+		return s, nil
+	}
+}
+
+// countedStream is a stream StreamInterceptor counts
+type countedStream struct {
+	grpc.ClientStream
+	once sync.Once
+	end  func()
+}
+
+func (s *countedStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.close()
+	}
+	return err
+}
+
+// close stops counting the stream, once
+func (s *countedStream) close() {
+	s.once.Do(s.end)
+}
+
+// Client reports c's decision cache, offline state and REST fallback,
+// those it has
+func (a *Agent) Client(c *routingclient.Client) {
+	if dc := c.Cache(); dc != nil {
+		a.reg.MustRegister(
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name: "routing_agent_decision_cache_hits_total",
+				Help: "Selections answered from the decision cache.",
+			}, func() float64 {
+				hits, _ := dc.Stats()
+				return float64(hits)
+			}),
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Name: "routing_agent_decision_cache_misses_total",
+				Help: "Selections the decision cache could not answer.",
+			}, func() float64 {
+				_, misses := dc.Stats()
+				return float64(misses)
+			}),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name: "routing_agent_decision_cache_hit_ratio",
+				Help: "Share of selections answered from the decision cache so far.",
+			}, func() float64 {
+				hits, misses := dc.Stats()
+				if hits+misses == 0 {
+					return 0
+				}
+				return float64(hits) / float64(hits+misses)
+			}),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name: "routing_agent_decision_cache_entries",
+				Help: "Decisions cached, including expired ones not yet evicted.",
+			}, func() float64 { return float64(dc.Len()) }),
+		)
+	}
+	if o := c.Offline(); o != nil {
+		a.reg.MustRegister(
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name: "routing_agent_offline_decisions",
+				Help: "Last known decisions kept for when the service is unreachable.",
+			}, func() float64 { return float64(o.Stats().Decisions) }),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name: "routing_agent_offline_pending_outcomes",
+				Help: "Outcomes in the offline log waiting to be replayed.",
+			}, func() float64 { return float64(o.Stats().Pending) }),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Name: "routing_agent_offline_log_bytes",
+				Help: "Size of the offline outcome log.",
+			}, func() float64 { return float64(o.Stats().LogBytes) }),
+		)
+	}
+	a.reg.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "routing_agent_rest_fallback_active",
+		Help: "1 while calls go to the REST fallback because the gRPC server is unreachable.",
+	}, func() float64 {
+		if c.Degraded() {
+			return 1
+		}
+		return 0
+	}))
+}
+
+// Queue reports q's depth, batches in flight and totals, labelled with
+// name
+func (a *Agent) Queue(name string, q *routingclient.OutcomeQueue) {
+	labels := prometheus.Labels{"queue": name}
+	gauge := func(metric, help string, v func(routingclient.QueueStats) int) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: metric, Help: help, ConstLabels: labels},
+			func() float64 { return float64(v(q.Stats())) })
+	}
+	counter := func(metric, help string, v func(routingclient.QueueStats) uint64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{Name: metric, Help: help, ConstLabels: labels},
+			func() float64 { return float64(v(q.Stats())) })
+	}
+	a.reg.MustRegister(
+		gauge("routing_agent_outcome_queue_depth", "Outcomes waiting to be batched.",
+			func(s routingclient.QueueStats) int { return s.Queued }),
+		gauge("routing_agent_outcome_queue_capacity", "Outcomes the queue holds before blocking.",
+			func(s routingclient.QueueStats) int { return s.Capacity }),
+		gauge("routing_agent_outcome_queue_batches_in_flight", "Outcome batches being sent.",
+			func(s routingclient.QueueStats) int { return s.InFlight }),
+		counter("routing_agent_outcome_queue_sent_total", "Outcomes in batches that were recorded.",
+			func(s routingclient.QueueStats) uint64 { return s.Sent }),
+		counter("routing_agent_outcome_queue_failed_total", "Outcomes in batches that were not recorded.",
+			func(s routingclient.QueueStats) uint64 { return s.Failed }),
+		counter("routing_agent_outcome_queue_deferred_total", "Outcomes written to the offline log instead.",
+			func(s routingclient.QueueStats) uint64 { return s.Deferred }),
+	)
+}
+
+// Watcher reports the state of each backend w follows, the router's
+// breaker on it, as a set of 0/1 gauges, one per state
+func (a *Agent) Watcher(w *routingclient.BackendWatcher) {
+	desc := prometheus.NewDesc("routing_agent_backend_state",
+		"1 for the state each backend is in, 0 for the others.", []string{"backend", "state"}, nil)
+	a.reg.MustRegister(&collector{desc: []*prometheus.Desc{desc}, collect: func(ch chan<- prometheus.Metric) {
+		for id, ev := range w.States() {
+			for v, name := range pb.BackendState_name {
+				if v == 0 {
+					continue
+				}
+				value := 0.0
+				if int32(ev.State) == v {
+					value = 1
+				}
+				state := strings.ToLower(strings.TrimPrefix(name, "BACKEND_STATE_"))
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, id, state)
+			}
+		}
+	}})
+}
+
+// Sources reports the reads and latency of each of r's sources
+func (a *Agent) Sources(r *retrieval.Retriever) {
+	reads := prometheus.NewDesc("routing_agent_source_reads_total",
+		"Reads from each content source, by result.", []string{"source", "result"}, nil)
+	latency := prometheus.NewDesc("routing_agent_source_latency_seconds",
+		"Moving average of each source's time to first byte.", []string{"source"}, nil)
+	a.reg.MustRegister(&collector{desc: []*prometheus.Desc{reads, latency}, collect: func(ch chan<- prometheus.Metric) {
+		for _, s := range r.Stats() {
+			ch <- prometheus.MustNewConstMetric(reads, prometheus.CounterValue, float64(s.Successes), s.ID, "success")
+			ch <- prometheus.MustNewConstMetric(reads, prometheus.CounterValue, float64(s.Failures), s.ID, "failure")
+			ch <- prometheus.MustNewConstMetric(reads, prometheus.CounterValue, float64(s.Abandoned), s.ID, "abandoned")
+			ch <- prometheus.MustNewConstMetric(latency, prometheus.GaugeValue, s.Latency.Seconds(), s.ID)
+		}
+	}})
+}
+
+// collector is a prometheus.Collector producing its metrics with collect
+// on each scrape
+type collector struct {
+	desc    []*prometheus.Desc
+	collect func(ch chan<- prometheus.Metric)
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.desc {
+		ch <- d
+	}
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(ch)
+}
@@ -0,0 +1,134 @@
+// Package metrics exports what routing clients do — objects routed,
+// outcomes, bytes moved and operation durations per backend — to a
+// metrics system. A Sink receives the selections and outcomes Interceptor
+// sees. Job is the sink for batch runs that exit before any Prometheus
+// server could scrape them, pushing its totals to a Prometheus
+// Pushgateway when the job ends; StatsD sends each one to a StatsD or
+// DogStatsD agent as it happens.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"google.golang.org/grpc"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// PushgatewayEnv names the environment variable holding the default
+// Pushgateway URL
+const PushgatewayEnv = "ROUTING_PUSHGATEWAY"
+
+// Job holds the metrics of one job run. Its methods are safe for
+// concurrent use.
+type Job struct {
+	reg   *prometheus.Registry
+	start time.Time
+
+	routed   *prometheus.CounterVec
+	outcomes *prometheus.CounterVec
+	bytes    *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+
+	elapsed     prometheus.Gauge
+	exitCode    prometheus.Gauge
+	lastSuccess prometheus.Gauge
+}
+
+// NewJob starts timing a job
+func NewJob() *Job {
+	j := &Job{
+		reg:   prometheus.NewRegistry(),
+		start: time.Now(),
+		routed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "routing_job_objects_routed_total",
+			Help: "Objects the job had the router place, by backend chosen.",
+		}, []string{"backend", "strategy"}),
+		outcomes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "routing_job_outcomes_total",
+			Help: "Operation outcomes the job recorded, by backend and result.",
+		}, []string{"backend", "result"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "routing_job_bytes_total",
+			Help: "Bytes the job's recorded operations transferred, by backend.",
+		}, []string{"backend"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "routing_job_operation_duration_seconds",
+			Help:    "Duration of the job's recorded operations, by backend.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 4, 9),
+		}, []string{"backend"}),
+		elapsed: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "routing_job_duration_seconds",
+			Help: "How long the job ran.",
+		}),
+		exitCode: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "routing_job_exit_code",
+			Help: "The job's exit code, 0 on success.",
+		}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "routing_job_last_success_timestamp_seconds",
+			Help: "When the job last finished successfully, in seconds since the epoch.",
+		}),
+	}
+	j.reg.MustRegister(j.routed, j.outcomes, j.bytes, j.duration, j.elapsed, j.exitCode)
+	return j
+}
+
+// Interceptor returns a client interceptor counting every successful
+// backend selection and every outcome sent, e.g. for
+// routingclient.WithInterceptor. Dry runs are not counted.
+func (j *Job) Interceptor() grpc.UnaryClientInterceptor {
+	return Interceptor(j)
+}
+
+// Routed implements Sink
+func (j *Job) Routed(req *pb.SelectBackendRequest, resp *pb.SelectBackendResponse) {
+	j.routed.WithLabelValues(resp.BackendId, req.Strategy).Inc()
+}
+
+// Outcome implements Sink
+func (j *Job) Outcome(o *pb.RecordOutcomeRequest) {
+	result := "success"
+	if !o.Success {
+		result = "failure"
+	}
+	j.outcomes.WithLabelValues(o.BackendId, result).Inc()
+	j.bytes.WithLabelValues(o.BackendId).Add(float64(o.BytesTransferred))
+	j.duration.WithLabelValues(o.BackendId).Observe(float64(o.DurationMs) / 1000)
+}
+
+// Close implements Sink. A Job's metrics are only sent by Push.
+func (j *Job) Close() error {
+	return nil
+}
+
+// Push ends the job with exit code code and pushes its metrics to the
+// Pushgateway at url under job, grouped by grouping's labels and
+// replacing the metrics an earlier run of the group pushed. The last success
+// timestamp is only pushed when code is 0, so a failed run leaves the
+// previous one's in place for alerts on stale jobs.
+func (j *Job) Push(ctx context.Context, url, job string, grouping map[string]string, code int) error {
+	j.elapsed.Set(time.Since(j.start).Seconds())
+	j.exitCode.Set(float64(code))
+	p := push.New(url, job).Gatherer(j.reg)
+	for name, value := range grouping {
+		p = p.Grouping(name, value)
+	}
+	if code == 0 {
+		j.lastSuccess.SetToCurrentTime()
+		p = p.Collector(j.lastSuccess)
+		if err := p.PushContext(ctx); err != nil {
+			return fmt.Errorf("metrics: push to %s: %w", url, err)
+		}
+		return nil
+	}
+	// Add keeps the last success timestamp a previous run pushed
+	if err := p.AddContext(ctx); err != nil {
+		return fmt.Errorf("metrics: push to %s: %w", url, err)
+	}
+	return nil
+}
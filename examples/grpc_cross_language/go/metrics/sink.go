@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// Sink types Config.Sink selects
+const (
+	SinkStatsD    = "statsd"
+	SinkDogStatsD = "dogstatsd"
+)
+
+// ConfigEnv names the environment variable holding a sink URL for
+// ParseConfig
+const ConfigEnv = "ROUTING_METRICS"
+
+// Sink receives the backend selections and outcomes a client sees, for
+// export to a metrics system. Job is the Prometheus Pushgateway sink and
+// StatsD the StatsD and DogStatsD one. Implementations must be safe for
+// concurrent use.
+type Sink interface {
+	// Routed is called for each backend selection that is not a dry run
+	Routed(req *pb.SelectBackendRequest, resp *pb.SelectBackendResponse)
+	// Outcome is called for each outcome recorded
+	Outcome(o *pb.RecordOutcomeRequest)
+	// Close sends anything buffered and releases the sink
+	Close() error
+}
+
+// Config selects and configures a Sink for Open
+type Config struct {
+	// Sink is SinkStatsD or SinkDogStatsD
+	Sink string `json:"sink"`
+	// Address is the agent's host:port (default localhost:8125)
+	Address string `json:"address"`
+	// Prefix starts every metric name (default "routing.")
+	Prefix string `json:"prefix"`
+	// Tags are added to every metric, as "key:value"; plain StatsD has
+	// no tags and ignores them
+	Tags []string `json:"tags"`
+	// FlushInterval is the longest a metric waits in the buffer
+	// (default 1s)
+	FlushInterval time.Duration `json:"flush_interval"`
+}
+
+// ParseConfig parses a sink URL, scheme://host:port?prefix=p&tag=k:v&flush=1s,
+// with scheme statsd or dogstatsd and tag repeatable, as in $ROUTING_METRICS
+func ParseConfig(s string) (Config, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return Config{}, fmt.Errorf("metrics: %w", err)
+	}
+	cfg := Config{Sink: strings.ToLower(u.Scheme), Address: u.Host, Tags: u.Query()["tag"]}
+	q := u.Query()
+	if q.Has("prefix") {
+		cfg.Prefix = q.Get("prefix")
+	}
+	if f := q.Get("flush"); f != "" {
+		if cfg.FlushInterval, err = time.ParseDuration(f); err != nil {
+			return Config{}, fmt.Errorf("metrics: flush: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// Open returns the sink cfg selects
+func Open(cfg Config) (Sink, error) {
+	switch cfg.Sink {
+	case SinkStatsD, SinkDogStatsD:
+		return NewStatsD(cfg)
+	}
+	return nil, fmt.Errorf("metrics: unknown sink %q (want %s or %s)", cfg.Sink, SinkStatsD, SinkDogStatsD)
+}
+
+// Interceptor returns a client interceptor telling s of every successful
+// backend selection and every outcome sent, e.g. for
+// routingclient.WithInterceptor. Dry runs are left out.
+func Interceptor(s Sink) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+			return err
+		}
+		switch method {
+		case pb.RoutingService_SelectBackend_FullMethodName:
+			if resp := reply.(*pb.SelectBackendResponse); !resp.DryRun {
+				s.Routed(req.(*pb.SelectBackendRequest), resp)
+			}
+		case pb.RoutingService_RecordOutcome_FullMethodName:
+			s.Outcome(req.(*pb.RecordOutcomeRequest))
+		case pb.RoutingService_RecordOutcomes_FullMethodName:
+			for _, o := range req.(*pb.RecordOutcomesRequest).Outcomes {
+				s.Outcome(o)
+			}
+		}
+		return nil
+	}
+}
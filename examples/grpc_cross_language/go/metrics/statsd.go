@@ -0,0 +1,191 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// statsdPacket is the most a datagram carries, small enough not to be
+// fragmented on an Ethernet MTU
+const statsdPacket = 1432
+
+// StatsD is a Sink sending each selection and outcome to a StatsD or
+// DogStatsD agent over UDP, as these metrics:
+//
+//	<prefix>objects_routed          counter, per backend selected
+//	<prefix>outcomes                counter, per outcome recorded
+//	<prefix>bytes                   counter, bytes an outcome transferred
+//	<prefix>operation_duration      timer, an outcome's duration in ms
+//	<prefix>decision_score          gauge, the selected backend's score
+//
+// DogStatsD tags them with backend, strategy, content_type and, on
+// outcomes, result ("success" or "failure"). Plain StatsD has no tags, so
+// their values are appended to the name instead, e.g.
+// routing.outcomes.ipfs.image_png.success. Lines are batched into
+// datagrams and sent at least every FlushInterval.
+type StatsD struct {
+	// OnError, if set, is told of datagrams that could not be sent, which
+	// are otherwise dropped
+	OnError func(error)
+
+	conn   net.Conn
+	dog    bool
+	prefix string
+	tags   string
+
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewStatsD returns a StatsD sink for cfg, whose Sink is SinkStatsD or
+// SinkDogStatsD
+func NewStatsD(cfg Config) (*StatsD, error) {
+	if cfg.Address == "" {
+		cfg.Address = "localhost:8125"
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "routing."
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	conn, err := net.Dial("udp", cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("metrics: %w", err)
+	}
+	s := &StatsD{
+		conn:   conn,
+		dog:    cfg.Sink == SinkDogStatsD,
+		prefix: cfg.Prefix,
+		tags:   strings.Join(cfg.Tags, ","),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go s.run(cfg.FlushInterval)
+	return s, nil
+}
+
+// Routed implements Sink
+func (s *StatsD) Routed(req *pb.SelectBackendRequest, resp *pb.SelectBackendResponse) {
+	tags := [][2]string{{"backend", resp.BackendId}, {"strategy", req.Strategy}, {"content_type", req.ContentType}}
+	s.send("objects_routed", "1", "c", tags)
+	s.send("decision_score", strconv.FormatFloat(resp.Score, 'f', -1, 64), "g", tags)
+}
+
+// Outcome implements Sink
+func (s *StatsD) Outcome(o *pb.RecordOutcomeRequest) {
+	result := "success"
+	if !o.Success {
+		result = "failure"
+	}
+	tags := [][2]string{{"backend", o.BackendId}, {"content_type", o.ContentType}, {"result", result}}
+	s.send("outcomes", "1", "c", tags)
+	s.send("bytes", strconv.FormatInt(o.BytesTransferred, 10), "c", tags[:2])
+	s.send("operation_duration", strconv.Itoa(int(o.DurationMs)), "ms", tags)
+}
+
+// Close sends what is buffered and closes the connection
+func (s *StatsD) Close() error {
+	close(s.stop)
+	<-s.done
+	s.flush()
+	return s.conn.Close()
+}
+
+// run flushes the buffer every interval until Close
+func (s *StatsD) run(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+// send buffers one metric line, sending the buffer first if the line
+// would not fit in its datagram
+func (s *StatsD) send(name, value, kind string, tags [][2]string) {
+	var line strings.Builder
+	line.WriteString(s.prefix)
+	line.WriteString(name)
+	if !s.dog {
+		for _, t := range tags {
+			line.WriteByte('.')
+			line.WriteString(statsdName(t[1]))
+		}
+	}
+	fmt.Fprintf(&line, ":%s|%s", value, kind)
+	if s.dog {
+		line.WriteString("|#")
+		line.WriteString(s.tags)
+		for i, t := range tags {
+			if i > 0 || s.tags != "" {
+				line.WriteByte(',')
+			}
+			fmt.Fprintf(&line, "%s:%s", t[0], dogTag(t[1]))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.buf.Len() > 0 && s.buf.Len()+1+line.Len() > statsdPacket {
+		s.write()
+	}
+	if s.buf.Len() > 0 {
+		s.buf.WriteByte('\n')
+	}
+	s.buf.WriteString(line.String())
+}
+
+// flush sends the buffered lines
+func (s *StatsD) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.buf.Len() > 0 {
+		s.write()
+	}
+}
+
+// write sends the buffer as a datagram and empties it; s.mu is held
+func (s *StatsD) write() {
+	_, err := s.conn.Write(s.buf.Bytes())
+	s.buf.Reset()
+	if err != nil && s.OnError != nil {
+		s.OnError(fmt.Errorf("metrics: statsd: %w", err))
+	}
+}
+
+// statsdName makes a tag value safe as a StatsD name segment
+func statsdName(v string) string {
+	if v == "" {
+		return "none"
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, v)
+}
+
+// dogTag makes a tag value safe in a DogStatsD tag list
+func dogTag(v string) string {
+	if v == "" {
+		return "none"
+	}
+	return strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_").Replace(v)
+}
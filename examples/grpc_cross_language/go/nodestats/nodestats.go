@@ -0,0 +1,104 @@
+// Package nodestats scrapes a Kubo node's bitswap and bandwidth counters
+// into metric samples for the routing service, so the health of the node a
+// client retrieves through is weighed like its probes of remote endpoints.
+// A Scraper's Sample method is a routingclient.Sampler, for a Prober to
+// report every round.
+package nodestats
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"example.com/ipfs_kit_py/kubo"
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// DefaultBackendID is the backend samples are reported under unless told
+// otherwise, matching the router's local IPFS backend
+const DefaultBackendID = "ipfs"
+
+// Scraper turns successive scrapes of a node into samples covering the
+// interval between them
+type Scraper struct {
+	node      *kubo.Client
+	backendID string
+	// MinPeers is the fewest bitswap partners a working node has; fewer
+	// fail the sample (default 1)
+	MinPeers int
+
+	mu   sync.Mutex
+	prev *scrape
+}
+
+type scrape struct {
+	at      time.Time
+	bitswap kubo.BitswapStat
+}
+
+// New returns a Scraper for node reporting under backendID, or
+// DefaultBackendID if empty
+func New(node *kubo.Client, backendID string) *Scraper {
+	if backendID == "" {
+		backendID = DefaultBackendID
+	}
+	return &Scraper{node: node, backendID: backendID, MinPeers: 1}
+}
+
+// Sample scrapes the node once. Latency is how long the node took to
+// answer; bytes and throughput are the non-duplicate data bitswap received
+// since the previous sample, so the first sample and the first after a
+// node restart carry none. Throughput averages over idle time too, making
+// it a lower bound on transfer speed. A node that cannot be reached or has
+// too few peers fails the sample.
+func (s *Scraper) Sample(ctx context.Context) *pb.MetricSample {
+	sample := &pb.MetricSample{
+		BackendId: s.backendID,
+		Endpoint:  s.node.APIURL(),
+		Timestamp: timestamppb.Now(),
+	}
+	start := time.Now()
+	bs, err := s.node.BitswapStats(ctx)
+	if err != nil {
+		sample.Error = err.Error()
+		return sample
+	}
+	sample.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	bw, err := s.node.BandwidthStats(ctx)
+	if err != nil {
+		sample.Error = err.Error()
+		return sample
+	}
+	node := &pb.NodeStats{
+		Peers:                 int32(len(bs.Peers)),
+		WantlistLength:        int32(len(bs.Wantlist)),
+		RateInBytesPerSecond:  bw.RateIn,
+		RateOutBytesPerSecond: bw.RateOut,
+	}
+	sample.Node = node
+
+	s.mu.Lock()
+	prev := s.prev
+	s.prev = &scrape{at: start, bitswap: *bs}
+	s.mu.Unlock()
+	// Counters that went backwards mean the node restarted
+	if prev != nil && bs.BlocksReceived >= prev.bitswap.BlocksReceived && bs.DataReceived >= prev.bitswap.DataReceived {
+		node.BlocksReceived = bs.BlocksReceived - prev.bitswap.BlocksReceived
+		node.DuplicateBlocksReceived = bs.DupBlksReceived - prev.bitswap.DupBlksReceived
+		node.BlocksSent = bs.BlocksSent - prev.bitswap.BlocksSent
+		sample.Bytes = (bs.DataReceived - bs.DupDataReceived) - (prev.bitswap.DataReceived - prev.bitswap.DupDataReceived)
+		if elapsed := start.Sub(prev.at); sample.Bytes > 0 && elapsed > 0 {
+			sample.ThroughputBytesPerSecond = float64(sample.Bytes) / elapsed.Seconds()
+		}
+	}
+
+	if min := s.MinPeers; len(bs.Peers) < min {
+		sample.Error = fmt.Sprintf("nodestats: %d bitswap peers, want at least %d", len(bs.Peers), min)
+		return sample
+	}
+	sample.Success = true
+	return sample
+}
@@ -0,0 +1,49 @@
+package p2p
+
+import (
+	"io"
+	"net"
+	"strings"
+
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// Forward serves proto by connecting each incoming stream to target, a
+// host:port or unix:/path, like `ipfs p2p listen`. onStream, if set, is
+// told of every stream with the remote peer ID and any dial error.
+func (h *Host) Forward(proto protocol.ID, target string, onStream func(peer string, err error)) {
+	h.SetStreamHandler(proto, func(s network.Stream) {
+		kind, address := "tcp", target
+		if path, ok := strings.CutPrefix(target, "unix:"); ok {
+			kind, address = "unix", strings.TrimPrefix(path, "//")
+		}
+		c, err := net.Dial(kind, address)
+		if onStream != nil {
+			onStream(s.Conn().RemotePeer().String(), err)
+		}
+		if err != nil {
+			s.Reset()
+			return
+		}
+		pipe(s, c)
+	})
+}
+
+// pipe copies between s and c until both directions end, passing on each
+// side's end of input as a half-close
+func pipe(s network.Stream, c net.Conn) {
+	done := make(chan struct{})
+	go func() {
+		io.Copy(c, s)
+		if tc, ok := c.(interface{ CloseWrite() error }); ok {
+			tc.CloseWrite()
+		}
+		close(done)
+	}()
+	io.Copy(s, c)
+	s.CloseWrite()
+	<-done
+	s.Close()
+	c.Close()
+}
@@ -0,0 +1,170 @@
+// Package p2p lets the Go tools reach routing and data nodes over libp2p
+// where they expose no gRPC or HTTP port. A Host identifies this client by
+// a persistent peer ID and dials a node's multiaddr, e.g.
+// /ip4/203.0.113.7/udp/4001/quic-v1/p2p/12D3KooW..., opening a stream that
+// the node forwards to its local service. The stream protocols follow
+// Kubo's `ipfs p2p` conventions, so a Kubo node can do the forwarding:
+//
+//	ipfs p2p listen /x/ipfs-kit/routing /ip4/127.0.0.1/tcp/50051
+//
+// or `routing-cli p2p serve` where there is no Kubo node.
+package p2p
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+)
+
+// Stream protocols of the services a node exposes
+const (
+	// RoutingProtocol carries the routing service's gRPC connection
+	RoutingProtocol protocol.ID = "/x/ipfs-kit/routing"
+	// GatewayProtocol carries HTTP requests to an IPFS gateway
+	GatewayProtocol protocol.ID = "/x/ipfs-kit/gateway"
+)
+
+// KeyEnv names the file holding the client's private key
+const KeyEnv = "ROUTING_P2P_KEY"
+
+// Host is a libp2p host whose peer ID identifies this client or node
+type Host struct {
+	host.Host
+}
+
+// NewHost starts a host with key listening on listen, multiaddrs such as
+// /ip4/0.0.0.0/tcp/4011. A host without listen addresses only dials out.
+func NewHost(key crypto.PrivKey, listen ...string) (*Host, error) {
+	opts := []libp2p.Option{libp2p.Identity(key)}
+	if len(listen) > 0 {
+		opts = append(opts, libp2p.ListenAddrStrings(listen...))
+	} else {
+		opts = append(opts, libp2p.NoListenAddrs)
+	}
+	h, err := libp2p.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("p2p: %w", err)
+	}
+	return &Host{Host: h}, nil
+}
+
+// Multiaddrs returns the addresses others dial the host at, each ending in
+// its peer ID
+func (h *Host) Multiaddrs() []string {
+	addrs := make([]string, 0, len(h.Addrs()))
+	for _, a := range h.Addrs() {
+		addrs = append(addrs, a.String()+"/p2p/"+h.ID().String())
+	}
+	return addrs
+}
+
+// DefaultKeyPath returns $ROUTING_P2P_KEY, or routing-p2p.key in the
+// user's ipfs_kit_py configuration directory
+func DefaultKeyPath() (string, error) {
+	if p := os.Getenv(KeyEnv); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("p2p: key path: %w", err)
+	}
+	return filepath.Join(dir, "ipfs_kit_py", "routing-p2p.key"), nil
+}
+
+// LoadKey reads the private key at path, generating an Ed25519 key there
+// if the file does not exist, so the peer ID stays the same across runs
+func LoadKey(path string) (crypto.PrivKey, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := crypto.UnmarshalPrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("p2p: %s: %w", path, err)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("p2p: %w", err)
+	}
+	key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("p2p: generate key: %w", err)
+	}
+	if data, err = crypto.MarshalPrivateKey(key); err != nil {
+		return nil, fmt.Errorf("p2p: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("p2p: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("p2p: %w", err)
+	}
+	return key, nil
+}
+
+// IsAddr reports whether target is a multiaddr naming a peer, which only
+// a Host can dial
+func IsAddr(target string) bool {
+	return strings.HasPrefix(target, "/") && strings.Contains(target, "/p2p/")
+}
+
+// ParseAddr parses a multiaddr ending in /p2p/<peer ID>
+func ParseAddr(addr string) (*peer.AddrInfo, error) {
+	ai, err := peer.AddrInfoFromString(addr)
+	if err != nil {
+		return nil, fmt.Errorf("p2p: %s: %w", addr, err)
+	}
+	return ai, nil
+}
+
+// Dial connects to the peer at addr and opens a stream speaking proto,
+// returned as a net.Conn
+func (h *Host) Dial(ctx context.Context, addr string, proto protocol.ID) (net.Conn, error) {
+	ai, err := ParseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	return h.dial(ctx, *ai, proto)
+}
+
+func (h *Host) dial(ctx context.Context, ai peer.AddrInfo, proto protocol.ID) (net.Conn, error) {
+	if err := h.Connect(ctx, ai); err != nil {
+		return nil, fmt.Errorf("p2p: connect %s: %w", ai.ID, err)
+	}
+	s, err := h.NewStream(ctx, ai.ID, proto)
+	if err != nil {
+		return nil, fmt.Errorf("p2p: %s: open %s: %w", ai.ID, proto, err)
+	}
+	return &streamConn{Stream: s}, nil
+}
+
+// streamConn is a stream used as a net.Conn
+type streamConn struct {
+	network.Stream
+}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	return addr{c.Conn().LocalMultiaddr().String() + "/p2p/" + c.Conn().LocalPeer().String()}
+}
+
+func (c *streamConn) RemoteAddr() net.Addr {
+	return addr{c.Conn().RemoteMultiaddr().String() + "/p2p/" + c.Conn().RemotePeer().String()}
+}
+
+// addr is a multiaddr as a net.Addr
+type addr struct{ s string }
+
+func (a addr) Network() string { return "libp2p" }
+func (a addr) String() string  { return a.s }
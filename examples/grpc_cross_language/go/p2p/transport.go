@@ -0,0 +1,98 @@
+package p2p
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/protocol"
+	"google.golang.org/grpc"
+
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// Transport returns a routingclient.Transport dialing multiaddr targets
+// over h with RoutingProtocol
+func (h *Host) Transport() routingclient.Transport {
+	return &transport{host: func() (*Host, error) { return h, nil }}
+}
+
+// transport dials the routing service's gRPC connection over a stream
+type transport struct {
+	host func() (*Host, error)
+}
+
+func (t *transport) Match(target string) bool {
+	return IsAddr(target)
+}
+
+func (t *transport) Dial(target string) (string, []grpc.DialOption, error) {
+	ai, err := ParseAddr(target)
+	if err != nil {
+		return "", nil, err
+	}
+	h, err := t.host()
+	if err != nil {
+		return "", nil, err
+	}
+	// passthrough hands the multiaddr to the dialer as is; the peer ID
+	// stands in for a host name as the authority
+	return "passthrough:///" + target, []grpc.DialOption{
+		grpc.WithAuthority(ai.ID.String()),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			if !strings.HasPrefix(addr, "/") {
+				addr = "/" + addr
+			}
+			return h.Dial(ctx, addr, RoutingProtocol)
+		}),
+	}, nil
+}
+
+var defaultHost struct {
+	once sync.Once
+	h    *Host
+	err  error
+}
+
+// Default returns a dial-only host with the key at DefaultKeyPath, started
+// on first use
+func Default() (*Host, error) {
+	d := &defaultHost
+	d.once.Do(func() {
+		path, err := DefaultKeyPath()
+		if err != nil {
+			d.err = err
+			return
+		}
+		key, err := LoadKey(path)
+		if err != nil {
+			d.err = err
+			return
+		}
+		d.h, d.err = NewHost(key)
+	})
+	return d.h, d.err
+}
+
+// Register makes routingclient dial multiaddr targets through the Default
+// host, which is only started once such a target is dialed
+func Register() {
+	routingclient.RegisterTransport(&transport{host: Default})
+}
+
+// HTTPClient returns a client sending every request to the peer at addr
+// over proto, e.g. GatewayProtocol, and the base URL to request through
+// it
+func (h *Host) HTTPClient(addr string, proto protocol.ID) (*http.Client, string, error) {
+	ai, err := ParseAddr(addr)
+	if err != nil {
+		return nil, "", err
+	}
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return h.dial(ctx, *ai, proto)
+		},
+	}}, "http://" + ai.ID.String(), nil
+}
@@ -0,0 +1,107 @@
+// Package pinning is a client for the IPFS Pinning Service API, the
+// standard remote pinning interface implemented by third-party providers
+// such as Pinata and Filebase.
+package pinning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Error is an error returned by a pinning service
+type Error struct {
+	// Code is the HTTP status code
+	Code int
+	// Reason is a mandatory machine-readable string, e.g. "NOT_FOUND" or
+	// "INSUFFICIENT_FUNDS"
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+func (e *Error) Error() string {
+	if e.Details != "" {
+		return fmt.Sprintf("pinning: %s: %s (HTTP %d)", e.Reason, e.Details, e.Code)
+	}
+	return fmt.Sprintf("pinning: %s (HTTP %d)", e.Reason, e.Code)
+}
+
+// Client calls a Pinning Service API endpoint over HTTP
+type Client struct {
+	endpoint string
+	token    string
+	http     *http.Client
+}
+
+// NewClient creates a client for the service at endpoint, e.g.
+// https://api.pinata.cloud/psa, authenticating with the bearer token. An
+// empty token is taken from the endpoint's user info, so a service can be
+// given as https://TOKEN@host/path.
+func NewClient(endpoint, token string) *Client {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if u, err := url.Parse(endpoint); err == nil && u.User != nil {
+		if token == "" {
+			token = u.User.Username()
+		}
+		u.User = nil
+		endpoint = u.String()
+	}
+	return &Client{endpoint: endpoint, token: token, http: http.DefaultClient}
+}
+
+// do sends a request with an optional JSON body to path and decodes the
+// JSON response into out (if non-nil)
+func (c *Client) do(ctx context.Context, method, path string, args url.Values, in, out interface{}) error {
+	u := c.endpoint + path
+	if len(args) > 0 {
+		u += "?" + args.Encode()
+	}
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("pinning: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var failure struct {
+			Error *Error `json:"error"`
+		}
+		if json.Unmarshal(data, &failure) == nil && failure.Error != nil && failure.Error.Reason != "" {
+			failure.Error.Code = resp.StatusCode
+			return failure.Error
+		}
+		return &Error{Code: resp.StatusCode, Reason: http.StatusText(resp.StatusCode), Details: string(bytes.TrimSpace(data))}
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("pinning: %s %s: decode response: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package pinning_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/ipfs_kit_py/pinning"
+)
+
+// fakeService is a Pinning Service API over an in-memory set of pins,
+// answering list requests with pages of at most pageSize
+type fakeService struct {
+	mu       sync.Mutex
+	pins     []pinning.PinStatus // newest first
+	pageSize int
+	polls    map[string]int
+	token    string
+}
+
+func (f *fakeService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer "+f.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"reason": "UNAUTHORIZED", "details": "bad token"}})
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/psa/pins/")
+	switch {
+	case r.URL.Path == "/psa/pins" && r.Method == http.MethodPost:
+		var pin pinning.Pin
+		json.NewDecoder(r.Body).Decode(&pin)
+		status := pinning.PinStatus{RequestID: fmt.Sprint("req-", len(f.pins)), Status: pinning.StatusQueued, Created: time.Now(), Pin: pin}
+		f.pins = append([]pinning.PinStatus{status}, f.pins...)
+		json.NewEncoder(w).Encode(status)
+	case r.URL.Path == "/psa/pins":
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		before, _ := time.Parse(time.RFC3339Nano, r.URL.Query().Get("before"))
+		var matched []pinning.PinStatus
+		for _, p := range f.pins {
+			if before.IsZero() || p.Created.Before(before) {
+				matched = append(matched, p)
+			}
+		}
+		page := matched[:min(len(matched), limit, f.pageSize)]
+		json.NewEncoder(w).Encode(map[string]interface{}{"count": len(matched), "results": page})
+	case r.Method == http.MethodGet:
+		for i, p := range f.pins {
+			if p.RequestID == id {
+				// each poll moves the pin a step towards pinned
+				f.polls[id]++
+				if f.polls[id] > 1 {
+					f.pins[i].Status = pinning.StatusPinned
+				}
+				json.NewEncoder(w).Encode(f.pins[i])
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"reason": "NOT_FOUND"}})
+	case r.Method == http.MethodDelete:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "unexpected request", http.StatusTeapot)
+	}
+}
+
+func TestAddAndWait(t *testing.T) {
+	fake := &fakeService{pageSize: 10, polls: map[string]int{}, token: "secret"}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	// the token can ride in the endpoint's user info
+	c := pinning.NewClient(strings.Replace(srv.URL, "://", "://secret@", 1)+"/psa/", "")
+	ctx := context.Background()
+
+	status, err := c.Add(ctx, pinning.Pin{CID: "bafyadd", Name: "report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != pinning.StatusQueued || status.Done() || status.Pin.Name != "report" {
+		t.Fatalf("added %+v", status)
+	}
+	status, err = c.Wait(ctx, status.RequestID, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != pinning.StatusPinned || !status.Done() {
+		t.Errorf("waited for %+v", status)
+	}
+	if err := c.Remove(ctx, status.RequestID); err != nil {
+		t.Errorf("remove: %v", err)
+	}
+
+	_, err = c.Get(ctx, "missing")
+	if !pinning.IsNotFound(err) {
+		t.Errorf("get missing: %v, want not found", err)
+	}
+	_, err = pinning.NewClient(srv.URL+"/psa", "wrong").Get(ctx, "req-0")
+	if e, ok := err.(*pinning.Error); !ok || e.Code != http.StatusUnauthorized || e.Reason != "UNAUTHORIZED" || pinning.IsNotFound(err) {
+		t.Errorf("bad token: %v", err)
+	}
+}
+
+// TestListPages lists through a service returning smaller pages than
+// asked for
+func TestListPages(t *testing.T) {
+	fake := &fakeService{pageSize: 3, token: "secret"}
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 9; i >= 0; i-- {
+		fake.pins = append(fake.pins, pinning.PinStatus{RequestID: fmt.Sprint("req-", i), Status: pinning.StatusPinned, Created: start.Add(time.Duration(i) * time.Second)})
+	}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	c := pinning.NewClient(srv.URL+"/psa", "secret")
+
+	for _, limit := range []int{0, 4, 20} {
+		pins, err := c.List(context.Background(), pinning.ListOptions{Limit: limit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := len(fake.pins)
+		if limit > 0 {
+			want = min(limit, want)
+		}
+		if len(pins) != want {
+			t.Errorf("limit %d: listed %d pins, want %d", limit, len(pins), want)
+			continue
+		}
+		for i, p := range pins {
+			if p.RequestID != fake.pins[i].RequestID {
+				t.Errorf("limit %d: pin %d is %s, want %s", limit, i, p.RequestID, fake.pins[i].RequestID)
+			}
+		}
+	}
+}
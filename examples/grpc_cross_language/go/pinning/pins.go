@@ -0,0 +1,170 @@
+package pinning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Pin statuses
+const (
+	StatusQueued  = "queued"
+	StatusPinning = "pinning"
+	StatusPinned  = "pinned"
+	StatusFailed  = "failed"
+)
+
+// maxLimit is the largest page the API allows
+const maxLimit = 1000
+
+// Pin is a pin request: the CID to pin and optional hints
+type Pin struct {
+	CID  string `json:"cid"`
+	Name string `json:"name,omitempty"`
+	// Origins are multiaddrs of nodes known to have the content, which the
+	// service may connect to instead of finding providers itself
+	Origins []string          `json:"origins,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+}
+
+// PinStatus is the state of a pin request
+type PinStatus struct {
+	RequestID string    `json:"requestid"`
+	Status    string    `json:"status"`
+	Created   time.Time `json:"created"`
+	Pin       Pin       `json:"pin"`
+	// Delegates are multiaddrs of the service's nodes that will fetch the
+	// content; connecting to them speeds up pinning
+	Delegates []string          `json:"delegates"`
+	Info      map[string]string `json:"info,omitempty"`
+}
+
+// Done reports whether the request has finished, successfully or not
+func (s *PinStatus) Done() bool {
+	return s.Status == StatusPinned || s.Status == StatusFailed
+}
+
+// ListOptions filters List. Statuses defaults to pinned only, as in the
+// API.
+type ListOptions struct {
+	CIDs     []string
+	Name     string
+	Statuses []string
+	Before   time.Time
+	After    time.Time
+	// Limit caps the number of results; zero lists all of them
+	Limit int
+}
+
+// Add asks the service to pin
+func (c *Client) Add(ctx context.Context, pin Pin) (*PinStatus, error) {
+	var out PinStatus
+	if err := c.do(ctx, http.MethodPost, "/pins", nil, pin, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Get returns the state of a pin request
+func (c *Client) Get(ctx context.Context, requestID string) (*PinStatus, error) {
+	var out PinStatus
+	if err := c.do(ctx, http.MethodGet, "/pins/"+url.PathEscape(requestID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Replace replaces a pin request with a new pin, e.g. of an updated CID.
+// The service removes the old pin once the new one is pinned.
+func (c *Client) Replace(ctx context.Context, requestID string, pin Pin) (*PinStatus, error) {
+	var out PinStatus
+	if err := c.do(ctx, http.MethodPost, "/pins/"+url.PathEscape(requestID), nil, pin, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Remove removes a pin request
+func (c *Client) Remove(ctx context.Context, requestID string) error {
+	return c.do(ctx, http.MethodDelete, "/pins/"+url.PathEscape(requestID), nil, nil, nil)
+}
+
+// List returns the pin requests matching opts, newest first, following
+// pages until all of them (or opts.Limit) have been read
+func (c *Client) List(ctx context.Context, opts ListOptions) ([]PinStatus, error) {
+	args := url.Values{}
+	if len(opts.CIDs) > 0 {
+		args.Set("cid", strings.Join(opts.CIDs, ","))
+	}
+	if opts.Name != "" {
+		args.Set("name", opts.Name)
+	}
+	if len(opts.Statuses) > 0 {
+		args.Set("status", strings.Join(opts.Statuses, ","))
+	}
+	if !opts.After.IsZero() {
+		args.Set("after", opts.After.UTC().Format(time.RFC3339))
+	}
+	before := opts.Before
+	var all []PinStatus
+	for {
+		page := maxLimit
+		if opts.Limit > 0 && opts.Limit-len(all) < page {
+			page = opts.Limit - len(all)
+		}
+		args.Set("limit", fmt.Sprint(page))
+		if !before.IsZero() {
+			args.Set("before", before.UTC().Format(time.RFC3339Nano))
+		}
+		var out struct {
+			Count   int         `json:"count"`
+			Results []PinStatus `json:"results"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/pins", args, nil, &out); err != nil {
+			return nil, err
+		}
+		all = append(all, out.Results...)
+		// Services may return smaller pages than asked for, so count, the
+		// number matching the filters including before, decides when to
+		// stop
+		if len(out.Results) == 0 || len(out.Results) >= out.Count || (opts.Limit > 0 && len(all) >= opts.Limit) {
+			return all, nil
+		}
+		before = out.Results[len(out.Results)-1].Created
+	}
+}
+
+// Wait polls a pin request every interval until it is pinned or failed,
+// returning its final state. A failed pin is returned with an error.
+func (c *Client) Wait(ctx context.Context, requestID string, interval time.Duration) (*PinStatus, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		status, err := c.Get(ctx, requestID)
+		if err != nil {
+			return nil, err
+		}
+		if status.Status == StatusFailed {
+			return status, fmt.Errorf("pinning: pin of %s failed", status.Pin.CID)
+		}
+		if status.Done() {
+			return status, nil
+		}
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// IsNotFound reports whether err is the service saying the pin request
+// does not exist
+func IsNotFound(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == http.StatusNotFound
+}
@@ -0,0 +1,116 @@
+// Package progress reports how far long transfers have got, as bytes
+// done, rate and estimated time remaining, to a callback or a channel, so
+// multi-GB uploads and downloads are not silent until they end.
+package progress
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// DefaultInterval is how often a Reader reports while data flows
+const DefaultInterval = 500 * time.Millisecond
+
+// Update is a transfer's progress
+type Update struct {
+	// Name identifies the transfer, e.g. the backend an upload goes to or
+	// the CID being read
+	Name string `json:"name"`
+	Done int64  `json:"done"`
+	// Total is the size of the transfer, or 0 if it is not known
+	Total int64 `json:"total,omitempty"`
+	// Rate is the average rate so far, in bytes per second
+	Rate    float64       `json:"rate"`
+	Elapsed time.Duration `json:"elapsed"`
+	// ETA is the estimated time remaining; 0 if Total is not known
+	ETA time.Duration `json:"eta,omitempty"`
+	// Finished is set on a transfer's last update
+	Finished bool `json:"finished,omitempty"`
+	// Err is why the transfer ended, if it failed
+	Err error `json:"-"`
+}
+
+// Fraction is how much of the transfer is done, from 0 to 1, or -1 if
+// Total is not known
+func (u Update) Fraction() float64 {
+	if u.Total <= 0 {
+		return -1
+	}
+	return min(float64(u.Done)/float64(u.Total), 1)
+}
+
+// Func receives progress updates. It is called from the goroutine doing
+// the transfer, so it should return quickly.
+type Func func(Update)
+
+// Chan returns a Func sending updates to ch. Updates ch has no room for
+// are dropped so a slow consumer never stalls the transfer, except the
+// last, which is always delivered.
+func Chan(ch chan<- Update) Func {
+	return func(u Update) {
+		if u.Finished {
+			ch <- u
+			return
+		}
+		select {
+		case ch <- u:
+		default:
+		}
+	}
+}
+
+// Reader reports the progress of reads through it: every DefaultInterval
+// while data flows, and once more when the underlying reader returns EOF
+// or an error. Like any io.Reader it is not safe for concurrent reads.
+type Reader struct {
+	r        io.Reader
+	fn       Func
+	u        Update
+	start    time.Time
+	reported time.Time
+}
+
+// NewReader wraps r, reporting to fn under name; total is the expected
+// size, or 0 if it is not known
+func NewReader(r io.Reader, name string, total int64, fn Func) *Reader {
+	return &Reader{r: r, fn: fn, u: Update{Name: name, Total: max(total, 0)}}
+}
+
+func (p *Reader) Read(b []byte) (int, error) {
+	if p.start.IsZero() {
+		p.start = time.Now()
+		p.reported = p.start
+	}
+	n, err := p.r.Read(b)
+	p.u.Done += int64(n)
+	if p.u.Finished {
+		return n, err
+	}
+	now := time.Now()
+	switch {
+	case err != nil:
+		p.u.Finished = true
+		if !errors.Is(err, io.EOF) {
+			p.u.Err = err
+		}
+	case now.Sub(p.reported) < DefaultInterval:
+		return n, err
+	}
+	p.reported = now
+	p.report(now)
+	return n, err
+}
+
+// report sends the current progress, deriving the rate and ETA
+func (p *Reader) report(now time.Time) {
+	u := p.u
+	u.Elapsed = now.Sub(p.start)
+	if secs := u.Elapsed.Seconds(); secs > 0 {
+		u.Rate = float64(u.Done) / secs
+	}
+	if u.Total > u.Done && u.Rate > 0 {
+		u.ETA = time.Duration(float64(u.Total-u.Done) / u.Rate * float64(time.Second))
+	}
+	p.fn(u)
+}
@@ -0,0 +1,63 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// publishTimeout bounds publishing one intercepted event
+const publishTimeout = 5 * time.Second
+
+// Interceptor returns a client interceptor that publishes every
+// successful backend selection and recorded outcome through p, e.g. for
+// routingclient.WithInterceptor. Dry runs are not published. Publishing
+// happens in the background so RPCs are not slowed; failures go to
+// onError if set.
+func Interceptor(p *Publisher, onError func(error)) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
+			return err
+		}
+		var events []*pb.RoutingEvent
+		switch method {
+		case pb.RoutingService_SelectBackend_FullMethodName:
+			resp := reply.(*pb.SelectBackendResponse)
+			if resp.DryRun {
+				return nil
+			}
+			events = append(events, &pb.RoutingEvent{Event: &pb.RoutingEvent_Decision{Decision: &pb.RoutingDecision{
+				Request:  proto.Clone(req.(*pb.SelectBackendRequest)).(*pb.SelectBackendRequest),
+				Response: proto.Clone(resp).(*pb.SelectBackendResponse),
+			}}})
+		case pb.RoutingService_RecordOutcome_FullMethodName:
+			events = append(events, outcomeEvent(req.(*pb.RecordOutcomeRequest)))
+		case pb.RoutingService_RecordOutcomes_FullMethodName:
+			for _, o := range req.(*pb.RecordOutcomesRequest).Outcomes {
+				events = append(events, outcomeEvent(o))
+			}
+		default:
+			return nil
+		}
+		go func() {
+			pctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), publishTimeout)
+			defer cancel()
+			for _, ev := range events {
+				if err := p.Publish(pctx, ev); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}()
+		return nil
+	}
+}
+
+func outcomeEvent(o *pb.RecordOutcomeRequest) *pb.RoutingEvent {
+	return &pb.RoutingEvent{Event: &pb.RoutingEvent_Outcome{
+		Outcome: proto.Clone(o).(*pb.RecordOutcomeRequest),
+	}}
+}
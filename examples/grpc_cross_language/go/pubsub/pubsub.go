@@ -0,0 +1,129 @@
+// Package pubsub broadcasts routing decisions, outcomes and backend health
+// to interested peers over IPFS pubsub, through a Kubo node's RPC API. The
+// topic names and the RoutingEvent schema are defined in routing.proto:
+// each kind of event has its own topic,
+// /ipfs-kit/routing/v1/<namespace>/<kind>, carrying binary-encoded
+// RoutingEvents.
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"example.com/ipfs_kit_py/kubo"
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// TopicPrefix starts every routing event topic
+const TopicPrefix = "/ipfs-kit/routing/v1/"
+
+// DefaultNamespace is the namespace of deployments that configure none
+const DefaultNamespace = "default"
+
+const kindPrefix = "ROUTING_EVENT_KIND_"
+
+// Kinds of event, each published on its own topic
+const (
+	Decisions = pb.RoutingEventKind_ROUTING_EVENT_KIND_DECISIONS
+	Outcomes  = pb.RoutingEventKind_ROUTING_EVENT_KIND_OUTCOMES
+	Backends  = pb.RoutingEventKind_ROUTING_EVENT_KIND_BACKENDS
+)
+
+// AllKinds lists every kind of event
+var AllKinds = []pb.RoutingEventKind{Decisions, Outcomes, Backends}
+
+// Topic returns the topic events of kind are published on in namespace,
+// or DefaultNamespace if empty
+func Topic(namespace string, kind pb.RoutingEventKind) string {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	return TopicPrefix + namespace + "/" + KindName(kind)
+}
+
+// KindName returns the topic name of kind, e.g. "outcomes"
+func KindName(kind pb.RoutingEventKind) string {
+	return strings.ToLower(strings.TrimPrefix(kind.String(), kindPrefix))
+}
+
+// ParseKind parses a kind given by its topic name ("outcomes") or enum
+// name
+func ParseKind(name string) (pb.RoutingEventKind, error) {
+	v, ok := pb.RoutingEventKind_value[kindPrefix+strings.ToUpper(strings.TrimPrefix(name, kindPrefix))]
+	if !ok || v == 0 {
+		return 0, fmt.Errorf("pubsub: unknown event kind %q", name)
+	}
+	return pb.RoutingEventKind(v), nil
+}
+
+// KindOf returns the kind of ev, from the event it carries
+func KindOf(ev *pb.RoutingEvent) pb.RoutingEventKind {
+	switch ev.GetEvent().(type) {
+	case *pb.RoutingEvent_Decision:
+		return Decisions
+	case *pb.RoutingEvent_Outcome:
+		return Outcomes
+	case *pb.RoutingEvent_Backend:
+		return Backends
+	}
+	return pb.RoutingEventKind_ROUTING_EVENT_KIND_UNSPECIFIED
+}
+
+// Publisher publishes routing events through a node
+type Publisher struct {
+	node      *kubo.Client
+	namespace string
+	// Sender identifies this publisher in events that do not name one,
+	// e.g. its reporter ID
+	Sender string
+}
+
+// NewPublisher creates a publisher for namespace, or DefaultNamespace if
+// empty
+func NewPublisher(node *kubo.Client, namespace string) *Publisher {
+	return &Publisher{node: node, namespace: namespace}
+}
+
+// Publish publishes ev on the topic of its kind, filling in the sender
+// and timestamp if unset
+func (p *Publisher) Publish(ctx context.Context, ev *pb.RoutingEvent) error {
+	kind := KindOf(ev)
+	if kind == pb.RoutingEventKind_ROUTING_EVENT_KIND_UNSPECIFIED {
+		return fmt.Errorf("pubsub: event carries nothing to publish")
+	}
+	if ev.Sender == "" {
+		ev.Sender = p.Sender
+	}
+	if ev.Timestamp == nil {
+		ev.Timestamp = timestamppb.Now()
+	}
+	data, err := proto.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("pubsub: %w", err)
+	}
+	if err := p.node.PubSubPublish(ctx, Topic(p.namespace, kind), data); err != nil {
+		return fmt.Errorf("pubsub: publish %s: %w", KindName(kind), err)
+	}
+	return nil
+}
+
+// Decision publishes a backend selection
+func (p *Publisher) Decision(ctx context.Context, req *pb.SelectBackendRequest, resp *pb.SelectBackendResponse) error {
+	return p.Publish(ctx, &pb.RoutingEvent{Event: &pb.RoutingEvent_Decision{
+		Decision: &pb.RoutingDecision{Request: req, Response: resp},
+	}})
+}
+
+// Outcome publishes the outcome of an operation
+func (p *Publisher) Outcome(ctx context.Context, outcome *pb.RecordOutcomeRequest) error {
+	return p.Publish(ctx, &pb.RoutingEvent{Event: &pb.RoutingEvent_Outcome{Outcome: outcome}})
+}
+
+// Backend publishes a change in a backend's health
+func (p *Publisher) Backend(ctx context.Context, ev *pb.BackendEvent) error {
+	return p.Publish(ctx, &pb.RoutingEvent{Event: &pb.RoutingEvent_Backend{Backend: ev}})
+}
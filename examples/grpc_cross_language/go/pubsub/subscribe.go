@@ -0,0 +1,122 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"example.com/ipfs_kit_py/kubo"
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// Event is a routing event received from a peer
+type Event struct {
+	*pb.RoutingEvent
+	// From is the peer ID of the node it was published through
+	From string
+	// Kind is the kind of the topic it arrived on
+	Kind pb.RoutingEventKind
+}
+
+// Subscriber receives routing events published in a namespace
+type Subscriber struct {
+	node      *kubo.Client
+	namespace string
+	kinds     []pb.RoutingEventKind
+
+	// OnEvent is called with every event. Events of different kinds may
+	// arrive concurrently.
+	OnEvent func(Event)
+	// OnInvalid, if set, is told of messages that are not events of their
+	// topic's kind, which are otherwise dropped
+	OnInvalid func(from string, kind pb.RoutingEventKind, err error)
+	// Retry is the initial delay before resubscribing after the node ends
+	// a subscription; it doubles up to a minute (default 1s)
+	Retry time.Duration
+}
+
+// NewSubscriber creates a subscriber for the given kinds of event in
+// namespace, or all kinds if none are given. Run starts it.
+func NewSubscriber(node *kubo.Client, namespace string, kinds ...pb.RoutingEventKind) *Subscriber {
+	if len(kinds) == 0 {
+		kinds = AllKinds
+	}
+	return &Subscriber{node: node, namespace: namespace, kinds: kinds, Retry: time.Second}
+}
+
+// Run subscribes to each kind's topic until ctx ends, resubscribing when
+// the node ends a subscription. It returns ctx's error.
+func (s *Subscriber) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, kind := range s.kinds {
+		wg.Add(1)
+		go func(kind pb.RoutingEventKind) {
+			defer wg.Done()
+			s.follow(ctx, kind)
+		}(kind)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// follow keeps a subscription to kind's topic until ctx ends
+func (s *Subscriber) follow(ctx context.Context, kind pb.RoutingEventKind) {
+	delay := s.Retry
+	for {
+		if received, _ := s.receive(ctx, kind); received {
+			delay = s.Retry
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return
+		}
+		delay = min(2*delay, time.Minute)
+	}
+}
+
+// receive follows one subscription until it ends, reporting whether any
+// message arrived
+func (s *Subscriber) receive(ctx context.Context, kind pb.RoutingEventKind) (bool, error) {
+	sub, err := s.node.PubSubSubscribe(ctx, Topic(s.namespace, kind))
+	if err != nil {
+		return false, err
+	}
+	defer sub.Close()
+	received := false
+	for {
+		msg, err := sub.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return received, nil
+			}
+			return received, err
+		}
+		received = true
+		ev := &pb.RoutingEvent{}
+		if err := proto.Unmarshal(msg.Data, ev); err != nil {
+			s.invalid(msg.From, kind, err)
+			continue
+		}
+		if got := KindOf(ev); got != kind {
+			s.invalid(msg.From, kind, fmt.Errorf("pubsub: %s event on %s topic", KindName(got), KindName(kind)))
+			continue
+		}
+		if s.OnEvent != nil {
+			s.OnEvent(Event{RoutingEvent: ev, From: msg.From, Kind: kind})
+		}
+	}
+}
+
+func (s *Subscriber) invalid(from string, kind pb.RoutingEventKind, err error) {
+	if s.OnInvalid != nil {
+		s.OnInvalid(from, kind, err)
+	}
+}
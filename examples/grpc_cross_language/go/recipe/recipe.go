@@ -0,0 +1,170 @@
+// Package recipe runs declarative multi-step workflows whose steps are MCP
+// tool calls, with per-step retries and persisted job state.
+//
+// A recipe is a JSON document such as:
+//
+//	{
+//	  "name": "ingest-and-pin",
+//	  "params": {"bucket": "datasets"},
+//	  "steps": [
+//	    {"name": "add", "tool": "ipfs_add", "args": {"path": "{{.Params.path}}"}, "retries": 2},
+//	    {"name": "pin", "tool": "create_pin", "args": {"cid": "{{.Steps.add.cid}}"}, "retry_delay": "10s"}
+//	  ]
+//	}
+//
+// String arguments are Go templates evaluated against the recipe
+// parameters and the structured outputs of earlier steps.
+package recipe
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+)
+
+// Duration is a time.Duration encoded in JSON as a string such as "5s"
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Step is a single MCP tool invocation within a recipe
+type Step struct {
+	Name string                 `json:"name"`
+	Tool string                 `json:"tool"`
+	Args map[string]interface{} `json:"args,omitempty"`
+
+	// Retries is the number of additional attempts after a failure
+	Retries int `json:"retries,omitempty"`
+
+	// RetryDelay is the wait before the first retry; it doubles on each
+	// subsequent retry (default 2s)
+	RetryDelay Duration `json:"retry_delay,omitempty"`
+
+	// Timeout bounds a single attempt (default: no timeout)
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// Recipe is a named, ordered list of steps
+type Recipe struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Params      map[string]string `json:"params,omitempty"`
+	Steps       []Step            `json:"steps"`
+}
+
+// Load reads and validates a recipe from a JSON file
+func Load(path string) (*Recipe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Recipe
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("recipe %s: %w", path, err)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("recipe %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// Validate checks that the recipe is well formed
+func (r *Recipe) Validate() error {
+	if r.Name == "" {
+		return errors.New("recipe name is required")
+	}
+	if len(r.Steps) == 0 {
+		return errors.New("recipe has no steps")
+	}
+	seen := make(map[string]bool, len(r.Steps))
+	for i, s := range r.Steps {
+		if s.Name == "" {
+			return fmt.Errorf("step %d has no name", i+1)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("duplicate step name %q", s.Name)
+		}
+		seen[s.Name] = true
+		if s.Tool == "" {
+			return fmt.Errorf("step %q has no tool", s.Name)
+		}
+		if s.Retries < 0 {
+			return fmt.Errorf("step %q has negative retries", s.Name)
+		}
+	}
+	return nil
+}
+
+// templateData is the data available to argument templates
+type templateData struct {
+	Params map[string]string
+	Steps  map[string]interface{}
+}
+
+// renderArgs evaluates templates in all string values of args
+func renderArgs(args map[string]interface{}, data templateData) (map[string]interface{}, error) {
+	out, err := renderValue(args, data)
+	if err != nil {
+		return nil, err
+	}
+	rendered, _ := out.(map[string]interface{})
+	return rendered, nil
+}
+
+func renderValue(v interface{}, data templateData) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		tmpl, err := template.New("arg").Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			r, err := renderValue(item, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			r, err := renderValue(item, data)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			out[i] = r
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,177 @@
+package recipe_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/ipfs_kit_py/mcpclient"
+	"example.com/ipfs_kit_py/recipe"
+)
+
+// fakeServer answers tools/call for ipfs_add with a CID and for create_pin
+// with the CID it was given, failing its first failures calls
+type fakeServer struct {
+	mu       sync.Mutex
+	failures int
+	calls    []map[string]interface{}
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     int64 `json:"id"`
+		Params struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+		} `json:"params"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	f.mu.Lock()
+	f.calls = append(f.calls, map[string]interface{}{"tool": req.Params.Name, "args": req.Params.Arguments})
+	fail := f.failures > 0
+	if fail {
+		f.failures--
+	}
+	f.mu.Unlock()
+
+	var result mcpclient.ToolResult
+	switch {
+	case fail:
+		result = mcpclient.ToolResult{IsError: true, Content: []mcpclient.Content{{Type: "text", Text: "temporarily unavailable"}}}
+	case req.Params.Name == "ipfs_add":
+		result.StructuredContent, _ = json.Marshal(map[string]string{"cid": "bafy-" + req.Params.Arguments["path"].(string)})
+	default:
+		// a text-only result
+		result.Content = []mcpclient.Content{{Type: "text", Text: "pinned " + req.Params.Arguments["cid"].(string)}}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+func newRunner(t *testing.T, f *fakeServer) *recipe.Runner {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return &recipe.Runner{Client: mcpclient.NewClient(srv.URL, ""), StatePath: filepath.Join(t.TempDir(), "job.json")}
+}
+
+func ingest() *recipe.Recipe {
+	return &recipe.Recipe{
+		Name:   "ingest",
+		Params: map[string]string{"path": "default.bin"},
+		Steps: []recipe.Step{
+			{Name: "add", Tool: "ipfs_add", Args: map[string]interface{}{"path": "{{.Params.path}}"}, Retries: 2, RetryDelay: recipe.Duration(time.Millisecond)},
+			{Name: "pin", Tool: "create_pin", Args: map[string]interface{}{"cid": "{{.Steps.add.cid}}", "tags": []interface{}{"{{.Params.path}}"}}},
+		},
+	}
+}
+
+func TestRunPassesOutputs(t *testing.T) {
+	f := &fakeServer{failures: 2}
+	r := newRunner(t, f)
+	var updates int
+	r.OnUpdate = func(recipe.Job) { updates++ }
+	job, err := r.Start(context.Background(), ingest(), map[string]string{"path": "data.csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != recipe.StatusSucceeded || job.Steps[0].Attempts != 3 {
+		t.Errorf("job %s, add step %+v", job.Status, job.Steps[0])
+	}
+	if done, total := job.Progress(); done != 2 || total != 2 {
+		t.Errorf("progress %d/%d", done, total)
+	}
+	pin := f.calls[len(f.calls)-1]["args"].(map[string]interface{})
+	if pin["cid"] != "bafy-data.csv" || pin["tags"].([]interface{})[0] != "data.csv" {
+		t.Errorf("pin called with %v", pin)
+	}
+	if string(job.Steps[1].Output) != `"pinned bafy-data.csv"` {
+		t.Errorf("text output recorded as %s", job.Steps[1].Output)
+	}
+	if updates == 0 {
+		t.Error("OnUpdate was never called")
+	}
+}
+
+// TestResume fails a job at its first step, then resumes it from the
+// state file once the server recovers
+func TestResume(t *testing.T) {
+	f := &fakeServer{failures: 3}
+	r := newRunner(t, f)
+	job, err := r.Start(context.Background(), ingest(), nil)
+	if err == nil || !strings.Contains(err.Error(), "step add") {
+		t.Fatalf("err = %v, want step add to fail", err)
+	}
+	if job.Status != recipe.StatusFailed || job.Steps[0].Status != recipe.StatusFailed || job.Steps[1].Status != recipe.StatusPending {
+		t.Fatalf("job %s, steps %+v", job.Status, job.Steps)
+	}
+	data, err := os.ReadFile(r.StatePath)
+	if err != nil || !strings.Contains(string(data), `"status": "failed"`) {
+		t.Fatalf("state file %s, %v", data, err)
+	}
+
+	job, err = r.Resume(context.Background(), r.StatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != recipe.StatusSucceeded || job.Steps[0].Attempts != 4 {
+		t.Errorf("resumed job %s, add step %+v", job.Status, job.Steps[0])
+	}
+	if pin := f.calls[len(f.calls)-1]["args"].(map[string]interface{}); pin["cid"] != "bafy-default.bin" {
+		t.Errorf("pin called with %v", pin)
+	}
+
+	// a resumed job skips the steps that succeeded
+	calls := len(f.calls)
+	job.Steps[1].Status = recipe.StatusPending
+	if err := r.Run(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.calls) != calls+1 || f.calls[calls]["tool"] != "create_pin" {
+		t.Errorf("rerun made calls %v, want create_pin only", f.calls[calls:])
+	}
+}
+
+func TestMissingTemplateKey(t *testing.T) {
+	f := &fakeServer{}
+	rec := &recipe.Recipe{Name: "broken", Steps: []recipe.Step{{Name: "add", Tool: "ipfs_add", Args: map[string]interface{}{"path": "{{.Params.missing}}"}}}}
+	job, err := newRunner(t, f).Start(context.Background(), rec, nil)
+	if err == nil || len(f.calls) != 0 || !strings.HasPrefix(job.Steps[0].Error, "render args") {
+		t.Errorf("err = %v, %d calls, step %+v", err, len(f.calls), job.Steps[0])
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		json, err string
+	}{
+		{`{"name": "ok", "steps": [{"name": "a", "tool": "t", "retry_delay": "10s", "timeout": "1m"}]}`, ""},
+		{`{"steps": [{"name": "a", "tool": "t"}]}`, "name is required"},
+		{`{"name": "x", "steps": []}`, "no steps"},
+		{`{"name": "x", "steps": [{"name": "a", "tool": "t"}, {"name": "a", "tool": "t"}]}`, "duplicate step"},
+		{`{"name": "x", "steps": [{"name": "a"}]}`, "no tool"},
+		{`{"name": "x", "steps": [{"name": "a", "tool": "t", "retries": -1}]}`, "negative retries"},
+		{`{"name": "x", "steps": [{"name": "a", "tool": "t", "timeout": "soon"}]}`, "invalid duration"},
+	}
+	for i, tt := range tests {
+		path := filepath.Join(dir, "recipe.json")
+		os.WriteFile(path, []byte(tt.json), 0o644)
+		rec, err := recipe.Load(path)
+		if tt.err == "" {
+			if err != nil || time.Duration(rec.Steps[0].RetryDelay) != 10*time.Second || time.Duration(rec.Steps[0].Timeout) != time.Minute {
+				t.Errorf("%d: %+v, %v", i, rec, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%d: err = %v, want %q", i, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package recipe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"example.com/ipfs_kit_py/mcpclient"
+)
+
+// Status is the state of a job or step
+type Status string
+
+// Job and step statuses
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// StepState records the progress of one step
+type StepState struct {
+	Name     string          `json:"name"`
+	Tool     string          `json:"tool"`
+	Status   Status          `json:"status"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error,omitempty"`
+	Output   json.RawMessage `json:"output,omitempty"`
+	Started  time.Time       `json:"started,omitempty"`
+	Finished time.Time       `json:"finished,omitempty"`
+}
+
+// Job is the state of a recipe execution, monitored as a single unit
+type Job struct {
+	ID       string            `json:"id"`
+	Recipe   Recipe            `json:"recipe"`
+	Params   map[string]string `json:"params"`
+	Status   Status            `json:"status"`
+	Steps    []StepState       `json:"steps"`
+	Started  time.Time         `json:"started"`
+	Finished time.Time         `json:"finished,omitempty"`
+}
+
+// Progress returns the number of succeeded steps and the total
+func (j *Job) Progress() (done, total int) {
+	for _, s := range j.Steps {
+		if s.Status == StatusSucceeded {
+			done++
+		}
+	}
+	return done, len(j.Steps)
+}
+
+// Runner executes recipes against an MCP server
+type Runner struct {
+	Client *mcpclient.Client
+
+	// StatePath, if set, is where job state is written after every
+	// transition so an interrupted job can be resumed
+	StatePath string
+
+	// OnUpdate, if set, receives a snapshot of the job after every transition
+	OnUpdate func(Job)
+
+	mu sync.Mutex
+}
+
+// Start creates a new job for a recipe. Params override the recipe's
+// default parameters.
+func (r *Runner) Start(ctx context.Context, rec *Recipe, params map[string]string) (*Job, error) {
+	if err := rec.Validate(); err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]string, len(rec.Params)+len(params))
+	for k, v := range rec.Params {
+		merged[k] = v
+	}
+	for k, v := range params {
+		merged[k] = v
+	}
+
+	job := &Job{
+		ID:      fmt.Sprintf("%s-%d", rec.Name, time.Now().UnixNano()),
+		Recipe:  *rec,
+		Params:  merged,
+		Status:  StatusPending,
+		Started: time.Now().UTC(),
+	}
+	for _, s := range rec.Steps {
+		job.Steps = append(job.Steps, StepState{Name: s.Name, Tool: s.Tool, Status: StatusPending})
+	}
+	return job, r.Run(ctx, job)
+}
+
+// Resume loads a job from a state file and continues it from the first
+// step that has not succeeded
+func (r *Runner) Resume(ctx context.Context, statePath string) (*Job, error) {
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("decode job state: %w", err)
+	}
+	return &job, r.Run(ctx, &job)
+}
+
+// Run executes the job's remaining steps in order. Steps that already
+// succeeded are skipped and their outputs are available to templates.
+func (r *Runner) Run(ctx context.Context, job *Job) error {
+	data := templateData{Params: job.Params, Steps: make(map[string]interface{})}
+
+	job.Status = StatusRunning
+	job.Finished = time.Time{}
+	r.update(job)
+
+	for i := range job.Steps {
+		state := &job.Steps[i]
+		if state.Status == StatusSucceeded {
+			data.Steps[state.Name] = decodeOutput(state.Output)
+			continue
+		}
+
+		if err := r.runStep(ctx, job, job.Recipe.Steps[i], state, data); err != nil {
+			job.Status = StatusFailed
+			job.Finished = time.Now().UTC()
+			r.update(job)
+			return fmt.Errorf("recipe %s: step %s: %w", job.Recipe.Name, state.Name, err)
+		}
+		data.Steps[state.Name] = decodeOutput(state.Output)
+	}
+
+	job.Status = StatusSucceeded
+	job.Finished = time.Now().UTC()
+	r.update(job)
+	return nil
+}
+
+// runStep executes one step with retries and exponential backoff
+func (r *Runner) runStep(ctx context.Context, job *Job, step Step, state *StepState, data templateData) error {
+	args, err := renderArgs(step.Args, data)
+	if err != nil {
+		state.Status = StatusFailed
+		state.Error = fmt.Sprintf("render args: %v", err)
+		return err
+	}
+
+	delay := time.Duration(step.RetryDelay)
+	if delay <= 0 {
+		delay = 2 * time.Second
+	}
+
+	state.Status = StatusRunning
+	state.Started = time.Now().UTC()
+	state.Error = ""
+	for attempt := 0; ; attempt++ {
+		state.Attempts++
+		r.update(job)
+
+		res, err := r.callTool(ctx, step, args)
+		if err == nil {
+			state.Status = StatusSucceeded
+			state.Output = res.StructuredContent
+			if len(state.Output) == 0 || string(state.Output) == "null" {
+				state.Output, _ = json.Marshal(res.Text())
+			}
+			state.Finished = time.Now().UTC()
+			r.update(job)
+			return nil
+		}
+
+		state.Error = err.Error()
+		if attempt >= step.Retries || ctx.Err() != nil {
+			state.Status = StatusFailed
+			state.Finished = time.Now().UTC()
+			return err
+		}
+
+		r.update(job)
+		select {
+		case <-ctx.Done():
+			state.Status = StatusFailed
+			state.Finished = time.Now().UTC()
+			return ctx.Err()
+		case <-time.After(delay << attempt):
+		}
+	}
+}
+
+// callTool makes a single attempt, bounded by the step timeout
+func (r *Runner) callTool(ctx context.Context, step Step, args map[string]interface{}) (*mcpclient.ToolResult, error) {
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(step.Timeout))
+		defer cancel()
+	}
+	return r.Client.CallTool(ctx, step.Tool, args)
+}
+
+// update persists the job state and notifies the observer
+func (r *Runner) update(job *Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.StatePath != "" {
+		if data, err := json.MarshalIndent(job, "", "  "); err == nil {
+			tmp := r.StatePath + ".tmp"
+			if os.WriteFile(tmp, data, 0o644) == nil {
+				os.Rename(tmp, r.StatePath)
+			}
+		}
+	}
+	if r.OnUpdate != nil {
+		r.OnUpdate(*job)
+	}
+}
+
+// decodeOutput converts a step's raw output into a template value
+func decodeOutput(raw json.RawMessage) interface{} {
+	var v interface{}
+	if len(raw) > 0 {
+		json.Unmarshal(raw, &v)
+	}
+	return v
+}
@@ -0,0 +1,391 @@
+// Package retrieval reads content from the best of several IPFS gateways
+// and the local node. Sources are tried in order of their measured
+// performance; a slow source gets a hedged request to the next one racing
+// it, and a failed one fails over to the next at once. Fetch instead races
+// several sources from the start. Every attempt is reported to the routing
+// service as an outcome, so gateway performance is learned like any other
+// backend's.
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/ipfs_kit_py/cidutil"
+	"example.com/ipfs_kit_py/progress"
+	"example.com/ipfs_kit_py/routingclient"
+	"example.com/ipfs_kit_py/throttle"
+)
+
+// DefaultHedgeDelay is how long a source may take to start responding
+// before the next source is tried alongside it
+const DefaultHedgeDelay = 500 * time.Millisecond
+
+// ewmaWeight is the weight of the newest sample in a source's latency
+const ewmaWeight = 0.3
+
+// Recorder receives the outcome of each attempt, e.g. a
+// *routingclient.OutcomeQueue, which records without blocking reads
+type Recorder interface {
+	Record(info routingclient.ContentInfo, outcome routingclient.Outcome) error
+}
+
+// Retriever reads content from its Sources
+type Retriever struct {
+	Sources []Source
+	// HedgeDelay is how long to wait on a source before also trying the
+	// next (DefaultHedgeDelay if zero); negative waits for each source to
+	// fail first
+	HedgeDelay time.Duration
+	// Verify checks raw-codec reads against their CID; a corrupt body
+	// fails its final Read with cidutil.ErrDigestMismatch and is recorded
+	// as an integrity failure of its source
+	Verify bool
+	// Width is how many of the best sources Fetch queries at once (all
+	// if zero)
+	Width int
+	// Recorder, if set, is given an outcome for every attempt. Recording
+	// errors are ignored so they never fail a read.
+	Recorder Recorder
+	// OnProgress, if set, is given the progress of reading each Result's
+	// Body, named by the ref read
+	OnProgress progress.Func
+	// Throttle, if set, limits the bandwidth of reading each Result's Body
+	Throttle *throttle.Limits
+
+	mu    sync.Mutex
+	stats map[string]*SourceStats
+}
+
+// SourceStats is a source's measured performance
+type SourceStats struct {
+	ID        string `json:"id"`
+	Successes int    `json:"successes"`
+	Failures  int    `json:"failures"`
+	// Abandoned counts reads the source lost to a faster one
+	Abandoned int `json:"abandoned"`
+	// Latency is a moving average of the time to first byte of
+	// successful reads, with the time an abandoned read had taken as a
+	// lower bound for it
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// score ranks a source, lowest first. Untried sources score zero so each
+// gets a chance to be measured.
+func (s *SourceStats) score() float64 {
+	if s == nil || s.Successes+s.Failures+s.Abandoned == 0 {
+		return 0
+	}
+	latency := s.Latency
+	if s.Successes+s.Abandoned == 0 {
+		latency = time.Minute
+	}
+	failRate := float64(s.Failures) / float64(s.Successes+s.Failures+s.Abandoned)
+	return float64(latency) * (1 + 4*failRate)
+}
+
+// Attempt is a source's finished try at a read
+type Attempt struct {
+	Source   string        `json:"source"`
+	Duration time.Duration `json:"duration_ns"`
+	Err      error         `json:"-"`
+}
+
+// Result is a successful read. Body must be closed by the caller; the
+// winning source's outcome is recorded once it is read to the end, fails
+// or is closed.
+type Result struct {
+	Body io.ReadCloser
+	// Size is the content length, or -1 if the source did not say
+	Size int64
+	// Source is the ID of the source that served the read
+	Source string
+	// Attempts are the sources that failed before Source won, then Source
+	Attempts []Attempt
+	// Outrun are the sources still trying when Source won, which were
+	// cancelled, with how long each had taken
+	Outrun []Attempt
+}
+
+// opened is a source's response to Open
+type opened struct {
+	i    int
+	body io.ReadCloser
+	size int64
+	err  error
+	ttfb time.Duration
+}
+
+// Get reads ref, a CID optionally followed by a path, from the best
+// source that can serve it
+func (r *Retriever) Get(ctx context.Context, ref string) (*Result, error) {
+	hedge := r.HedgeDelay
+	if hedge == 0 {
+		hedge = DefaultHedgeDelay
+	}
+	return r.race(ctx, ref, 1, hedge, false)
+}
+
+// Fetch reads ref from whichever of the best Width sources (all if zero)
+// responds first, querying them at once rather than hedging, and cancels
+// the rest. A source that fails is replaced by the next. Every source's
+// outcome is recorded, the cancelled ones as OutcomeClassOutrun with the
+// time they had taken, so their latencies can be compared with the
+// winner's.
+func (r *Retriever) Fetch(ctx context.Context, ref string) (*Result, error) {
+	width := r.Width
+	if width <= 0 {
+		width = len(r.Sources)
+	}
+	return r.race(ctx, ref, width, -1, true)
+}
+
+// race reads ref starting with the best width sources, launching the next
+// after each hedge delay (never if negative) and on each failure. The
+// first source to respond wins and the rest are cancelled; with
+// recordOutrun their outcomes are recorded too.
+func (r *Retriever) race(ctx context.Context, ref string, width int, hedge time.Duration, recordOutrun bool) (*Result, error) {
+	sources := r.order()
+	if len(sources) == 0 {
+		return nil, errors.New("retrieval: no sources configured")
+	}
+
+	results := make(chan opened, len(sources))
+	cancels := make([]context.CancelFunc, len(sources))
+	started := make([]time.Time, len(sources))
+	next, running := 0, 0
+	launch := func() {
+		i, src := next, sources[next]
+		actx, cancel := context.WithCancel(ctx)
+		cancels[i], started[i] = cancel, time.Now()
+		next++
+		running++
+		go func() {
+			start := time.Now()
+			body, size, err := src.Open(actx, ref)
+			results <- opened{i: i, body: body, size: size, err: err, ttfb: time.Since(start)}
+		}()
+	}
+	for next < min(width, len(sources)) {
+		launch()
+	}
+	timer := time.NewTimer(max(hedge, 0))
+	defer timer.Stop()
+
+	var attempts []Attempt
+	var errs []error
+	finished := make([]bool, len(sources))
+	for running > 0 {
+		var hedgeC <-chan time.Time
+		if hedge > 0 && next < len(sources) {
+			hedgeC = timer.C
+		}
+		select {
+		case <-hedgeC:
+			launch()
+			timer.Reset(hedge)
+
+		case o := <-results:
+			running--
+			finished[o.i] = true
+			id := sources[o.i].ID()
+			if o.err != nil {
+				cancels[o.i]()
+				attempts = append(attempts, Attempt{Source: id, Duration: o.ttfb, Err: o.err})
+				errs = append(errs, o.err)
+				if ctx.Err() == nil {
+					r.observe(id, o.ttfb, o.err)
+					r.record(id, o.ttfb, 0, o.err, "")
+				}
+				if next < len(sources) {
+					launch()
+					timer.Reset(max(hedge, 0))
+				}
+				continue
+			}
+			// The first to respond wins; the rest are abandoned
+			var outrun []Attempt
+			for j, cancel := range cancels {
+				if j != o.i && cancel != nil && !finished[j] {
+					cancel()
+					loser, elapsed := sources[j].ID(), time.Since(started[j])
+					r.abandon(loser, elapsed)
+					err := fmt.Errorf("retrieval: %s: outrun by %s", loser, id)
+					outrun = append(outrun, Attempt{Source: loser, Duration: elapsed, Err: err})
+					if recordOutrun {
+						r.record(loser, elapsed, 0, err, routingclient.OutcomeClassOutrun)
+					}
+				}
+			}
+			go func(n int) {
+				for ; n > 0; n-- {
+					if late := <-results; late.err == nil {
+						late.body.Close()
+					}
+				}
+			}(running)
+			attempts = append(attempts, Attempt{Source: id, Duration: o.ttfb})
+			return &Result{
+				Body:     r.track(ctx, ref, id, o, cancels[o.i]),
+				Size:     o.size,
+				Source:   id,
+				Attempts: attempts,
+				Outrun:   outrun,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("retrieval: %s: every source failed: %w", ref, errors.Join(errs...))
+}
+
+// track wraps the winning body so its outcome is recorded when the read
+// ends, verifying and throttling it on the way if enabled
+func (r *Retriever) track(ctx context.Context, ref, id string, o opened, cancel context.CancelFunc) io.ReadCloser {
+	b := &trackedBody{body: o.body, r: o.body, cancel: cancel, start: time.Now().Add(-o.ttfb)}
+	if r.Verify {
+		if c, err := cidutil.ParseCID(ref); err == nil && c.Codec == cidutil.Raw {
+			if vr, err := cidutil.NewVerifyingReader(o.body, c.Hash); err == nil {
+				b.r = vr
+			}
+		}
+	}
+	b.r = r.Throttle.Reader(ctx, b.r)
+	if r.OnProgress != nil {
+		b.r = progress.NewReader(b.r, ref, o.size, r.OnProgress)
+	}
+	b.done = func(n int64, err error) {
+		r.observe(id, o.ttfb, err)
+		r.record(id, time.Since(b.start), n, err, "")
+	}
+	return b
+}
+
+// observe updates a source's statistics with a finished read
+func (r *Retriever) observe(id string, ttfb time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.source(id)
+	if err != nil {
+		s.Failures++
+		return
+	}
+	s.sample(ttfb)
+	s.Successes++
+}
+
+// abandon updates a source's statistics with a read it lost after elapsed
+func (r *Retriever) abandon(id string, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.source(id)
+	if elapsed > s.Latency {
+		s.sample(elapsed)
+	}
+	s.Abandoned++
+}
+
+// source returns id's statistics, creating them; r.mu must be held
+func (r *Retriever) source(id string) *SourceStats {
+	if r.stats == nil {
+		r.stats = make(map[string]*SourceStats)
+	}
+	s := r.stats[id]
+	if s == nil {
+		s = &SourceStats{ID: id}
+		r.stats[id] = s
+	}
+	return s
+}
+
+// sample adds a latency sample to the moving average
+func (s *SourceStats) sample(d time.Duration) {
+	if s.Successes+s.Abandoned == 0 {
+		s.Latency = d
+		return
+	}
+	s.Latency = time.Duration(ewmaWeight*float64(d) + (1-ewmaWeight)*float64(s.Latency))
+}
+
+// record reports an attempt's outcome to the Recorder, if any
+func (r *Retriever) record(id string, duration time.Duration, n int64, err error, class string) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Record(routingclient.ContentInfo{ContentSize: n}, routingclient.Outcome{
+		BackendID: id,
+		Success:   err == nil,
+		Duration:  duration,
+		Err:       err,
+		Bytes:     n,
+		Class:     class,
+	})
+}
+
+// order returns the sources best first, keeping the configured order
+// among equals
+func (r *Retriever) order() []Source {
+	sources := append([]Source(nil), r.Sources...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sort.SliceStable(sources, func(i, j int) bool {
+		return r.stats[sources[i].ID()].score() < r.stats[sources[j].ID()].score()
+	})
+	return sources
+}
+
+// Stats returns the measured performance of each source, best first
+func (r *Retriever) Stats() []SourceStats {
+	sources := r.order()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]SourceStats, len(sources))
+	for i, src := range sources {
+		if s := r.stats[src.ID()]; s != nil {
+			out[i] = *s
+		} else {
+			out[i] = SourceStats{ID: src.ID()}
+		}
+	}
+	return out
+}
+
+// trackedBody counts what is read from a body and reports it once the
+// read ends
+type trackedBody struct {
+	body   io.ReadCloser
+	r      io.Reader
+	cancel context.CancelFunc
+	start  time.Time
+	done   func(n int64, err error)
+	n      int64
+	once   sync.Once
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	if err != nil {
+		b.end(err)
+	}
+	return n, err
+}
+
+// Close ends the read; closing early still counts as a success, since the
+// source delivered everything that was asked of it
+func (b *trackedBody) Close() error {
+	b.end(nil)
+	err := b.body.Close()
+	b.cancel()
+	return err
+}
+
+func (b *trackedBody) end(err error) {
+	if err == io.EOF {
+		err = nil
+	}
+	b.once.Do(func() { b.done(b.n, err) })
+}
@@ -0,0 +1,97 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"example.com/ipfs_kit_py/kubo"
+)
+
+// GatewayClass is the backend class of HTTP gateways, e.g.
+// "gateway-ipfs.io"
+const GatewayClass = "gateway"
+
+// Source is somewhere content can be read from
+type Source interface {
+	// ID is the backend ID attempts are recorded under
+	ID() string
+	// Open starts reading ref, a CID optionally followed by a path,
+	// returning its size or -1 if unknown
+	Open(ctx context.Context, ref string) (io.ReadCloser, int64, error)
+}
+
+// Gateway returns a Source reading from the HTTP gateway at gatewayURL,
+// e.g. https://ipfs.io. An empty id becomes "gateway-<host>".
+func Gateway(id, gatewayURL string) Source {
+	return HTTPGateway(id, gatewayURL, nil)
+}
+
+// HTTPGateway is Gateway making its requests with hc (http.DefaultClient
+// if nil), e.g. a client that reaches the gateway over libp2p
+func HTTPGateway(id, gatewayURL string, hc *http.Client) Source {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	gatewayURL = strings.TrimRight(gatewayURL, "/")
+	if id == "" {
+		host := gatewayURL
+		if u, err := url.Parse(gatewayURL); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		id = GatewayClass + "-" + host
+	}
+	return &gatewaySource{id: id, url: gatewayURL, http: hc}
+}
+
+type gatewaySource struct {
+	id   string
+	url  string
+	http *http.Client
+}
+
+func (g *gatewaySource) ID() string { return g.id }
+
+func (g *gatewaySource) Open(ctx context.Context, ref string) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.url+"/ipfs/"+ref, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := g.http.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("retrieval: %s: %w", g.id, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("retrieval: %s: HTTP %s", g.id, resp.Status)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// Local returns a Source reading through a Kubo node's RPC API, which
+// serves from its blockstore when it has the content and fetches over
+// bitswap otherwise. An empty id becomes "ipfs".
+func Local(id string, node *kubo.Client) Source {
+	if id == "" {
+		id = "ipfs"
+	}
+	return &localSource{id: id, node: node}
+}
+
+type localSource struct {
+	id   string
+	node *kubo.Client
+}
+
+func (l *localSource) ID() string { return l.id }
+
+func (l *localSource) Open(ctx context.Context, ref string) (io.ReadCloser, int64, error) {
+	body, err := l.node.Post(ctx, "cat", url.Values{"arg": {"/ipfs/" + ref}}, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("retrieval: %s: %w", l.id, err)
+	}
+	return body, -1, nil
+}
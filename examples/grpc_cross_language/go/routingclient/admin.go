@@ -0,0 +1,61 @@
+package routingclient
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// AuthorizationHeader is the metadata key (and HTTP header) carrying the
+// admin token, as "Bearer <token>"
+const AuthorizationHeader = "authorization"
+
+// AdminTokenEnv names the environment variable holding the server's admin
+// token; routing-cli reads the same variable
+const AdminTokenEnv = "ROUTING_ADMIN_TOKEN"
+
+// SetFactorWeights updates the scoring factor weights the service ranks
+// backends with. Factors not named keep their weight and the server
+// normalises the result. It requires a Client created with WithToken and
+// the server's admin token; otherwise it fails with ErrUnauthorized.
+func (c *Client) SetFactorWeights(ctx context.Context, weights map[string]float64) (*pb.SetFactorWeightsResponse, error) {
+	ctx, cancel := withTimeout(ctx, c.timeouts.Select)
+	defer cancel()
+	resp, err := c.rpc.SetFactorWeights(ctx, &pb.SetFactorWeightsRequest{Weights: weights})
+	return resp, toError(err)
+}
+
+// FactorWeights returns the scoring factor weights currently in effect
+func (c *Client) FactorWeights(ctx context.Context) (map[string]float64, error) {
+	ctx, cancel := withTimeout(ctx, c.timeouts.Select)
+	defer cancel()
+	resp, err := c.rpc.GetInsights(ctx, &pb.GetInsightsRequest{})
+	if err != nil {
+		return nil, toError(err)
+	}
+	weights := make(map[string]float64)
+	for factor, v := range resp.GetFactorWeights().GetFields() {
+		weights[factor] = v.GetNumberValue()
+	}
+	return weights, nil
+}
+
+// tokenInterceptor sends token as a bearer token on every call. Unlike
+// grpc.WithPerRPCCredentials it also works over RESTConn and WebConn and
+// does not insist on TLS, so local plaintext deployments can use it.
+func tokenInterceptor(token string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, AuthorizationHeader, "Bearer "+token)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func tokenStreamInterceptor(token string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx = metadata.AppendToOutgoingContext(ctx, AuthorizationHeader, "Bearer "+token)
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
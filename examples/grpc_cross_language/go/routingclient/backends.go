@@ -0,0 +1,60 @@
+package routingclient
+
+import (
+	"context"
+	"mime"
+	"strings"
+	"time"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// ListBackends returns the backends registered with the service, sorted by
+// ID. If filter has a ContentType or ContentSize, only backends accepting
+// such content are returned.
+func (c *Client) ListBackends(ctx context.Context, filter ContentInfo) ([]*pb.BackendInfo, error) {
+	ctx, cancel := withTimeout(ctx, c.timeouts.Select)
+	defer cancel()
+	resp, err := c.rpc.ListBackends(ctx, &pb.ListBackendsRequest{
+		ContentType: filter.ContentType,
+		ContentSize: filter.ContentSize,
+	})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return resp.Backends, nil
+}
+
+// GetBackendStats returns statistics for one backend over the last
+// window, rounded down to whole minutes; zero uses the server's default of
+// an hour. Unknown backends fail with ErrUnknownBackend.
+func (c *Client) GetBackendStats(ctx context.Context, backendID string, window time.Duration) (*pb.BackendStats, error) {
+	ctx, cancel := withTimeout(ctx, c.timeouts.Select)
+	defer cancel()
+	stats, err := c.rpc.GetBackendStats(ctx, &pb.GetBackendStatsRequest{
+		BackendId:     backendID,
+		WindowMinutes: int32(window / time.Minute),
+	})
+	return stats, toError(err)
+}
+
+// Accepts reports whether b takes content described by info, checking its
+// maximum object size and content type patterns such as "image/*"
+func Accepts(b *pb.BackendInfo, info ContentInfo) bool {
+	if max := b.GetMaxObjectSize(); max > 0 && info.ContentSize > max {
+		return false
+	}
+	if len(b.GetContentTypes()) == 0 || info.ContentType == "" {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(info.ContentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range b.GetContentTypes() {
+		if pattern == mt || strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
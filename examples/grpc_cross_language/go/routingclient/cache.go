@@ -0,0 +1,182 @@
+package routingclient
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// DecisionCache is an LRU cache of routing decisions keyed by the request
+// they answer, so repeated requests for hot content skip the RPC. Requests
+// for the same content and strategy that differ in any other input, such
+// as the content type or client location, are cached apart.
+type DecisionCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[requestKey]*list.Element
+	hits    uint64
+	misses  uint64
+}
+
+type decisionKey struct {
+	hash, strategy string
+}
+
+// requestKey identifies a cached decision by its whole request
+type requestKey struct {
+	decisionKey
+	// request is the encoded request without its ID and timestamp
+	request string
+}
+
+// keyOf returns the cache key of req: every field but the per-call
+// request ID and timestamp
+func keyOf(req *pb.SelectBackendRequest) requestKey {
+	in := proto.Clone(req).(*pb.SelectBackendRequest)
+	in.RequestId, in.Timestamp = "", nil
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(in)
+	return requestKey{decisionKey{req.ContentHash, req.Strategy}, string(data)}
+}
+
+type cachedDecision struct {
+	key     requestKey
+	resp    *pb.SelectBackendResponse
+	expires time.Time
+}
+
+// NewDecisionCache creates a cache holding at most maxEntries decisions
+// (unbounded if zero) for ttl each
+func NewDecisionCache(maxEntries int, ttl time.Duration) *DecisionCache {
+	return &DecisionCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[requestKey]*list.Element),
+	}
+}
+
+// SetCache enables decision caching in SelectBackend; nil disables it.
+// Content without a hash is never cached. It is safe to call while
+// selections are in flight.
+func (c *Client) SetCache(cache *DecisionCache) {
+	c.cache.Store(cache)
+}
+
+// Get returns a copy of the decision cached for req, if present and not
+// expired
+func (dc *DecisionCache) Get(req *pb.SelectBackendRequest) (*pb.SelectBackendResponse, bool) {
+	key := keyOf(req)
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	el, ok := dc.entries[key]
+	if ok && time.Now().After(el.Value.(*cachedDecision).expires) {
+		dc.remove(el)
+		ok = false
+	}
+	if !ok {
+		dc.misses++
+		return nil, false
+	}
+	dc.hits++
+	dc.order.MoveToFront(el)
+	return proto.Clone(el.Value.(*cachedDecision).resp).(*pb.SelectBackendResponse), true
+}
+
+// Put stores a copy of resp as the decision for req, evicting the least
+// recently used decision if the cache is full
+func (dc *DecisionCache) Put(req *pb.SelectBackendRequest, resp *pb.SelectBackendResponse) {
+	key := keyOf(req)
+	entry := &cachedDecision{
+		key:     key,
+		resp:    proto.Clone(resp).(*pb.SelectBackendResponse),
+		expires: time.Now().Add(dc.ttl),
+	}
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if el, ok := dc.entries[key]; ok {
+		el.Value = entry
+		dc.order.MoveToFront(el)
+		return
+	}
+	dc.entries[key] = dc.order.PushFront(entry)
+	if dc.maxEntries > 0 && dc.order.Len() > dc.maxEntries {
+		dc.remove(dc.order.Back())
+	}
+}
+
+// Invalidate drops every cached decision for hash, e.g. after the outcome
+// of an upload showed the chosen backend failing
+func (dc *DecisionCache) Invalidate(hash string) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	for key, el := range dc.entries {
+		if key.hash == hash {
+			dc.remove(el)
+		}
+	}
+}
+
+// Purge empties the cache
+func (dc *DecisionCache) Purge() {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.order.Init()
+	dc.entries = make(map[requestKey]*list.Element)
+}
+
+// Len returns the number of cached decisions, including expired ones not
+// yet evicted
+func (dc *DecisionCache) Len() int {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return dc.order.Len()
+}
+
+// CachedDecision is a decision held in a DecisionCache
+type CachedDecision struct {
+	ContentHash string    `json:"content_hash"`
+	Strategy    string    `json:"strategy"`
+	BackendID   string    `json:"backend_id"`
+	Score       float64   `json:"score"`
+	Expires     time.Time `json:"expires"`
+}
+
+// Entries returns the cached decisions, most recently used first,
+// including expired ones not yet evicted
+func (dc *DecisionCache) Entries() []CachedDecision {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	out := make([]CachedDecision, 0, dc.order.Len())
+	for el := dc.order.Front(); el != nil; el = el.Next() {
+		d := el.Value.(*cachedDecision)
+		out = append(out, CachedDecision{
+			ContentHash: d.key.hash,
+			Strategy:    d.key.strategy,
+			BackendID:   d.resp.BackendId,
+			Score:       d.resp.Score,
+			Expires:     d.expires,
+		})
+	}
+	return out
+}
+
+// Stats returns the number of cache hits and misses so far
+func (dc *DecisionCache) Stats() (hits, misses uint64) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	return dc.hits, dc.misses
+}
+
+func (dc *DecisionCache) remove(el *list.Element) {
+	dc.order.Remove(el)
+	delete(dc.entries, el.Value.(*cachedDecision).key)
+}
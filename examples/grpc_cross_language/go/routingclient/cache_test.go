@@ -0,0 +1,87 @@
+package routingclient_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// TestDecisionCacheKey checks that a cached decision only answers requests
+// identical to the one it was made for, apart from the request ID
+func TestDecisionCacheKey(t *testing.T) {
+	var calls atomic.Int32
+	conn := &replyConn{invoke: func(args, reply any) error {
+		calls.Add(1)
+		proto.Merge(reply.(proto.Message), &pb.SelectBackendResponse{BackendId: "ipfs"})
+		return nil
+	}}
+	client := routingclient.NewClient(conn)
+	client.SetCache(routingclient.NewDecisionCache(0, time.Minute))
+	ctx := context.Background()
+
+	base := routingclient.ContentInfo{ContentType: "text/plain", ContentSize: 10, ContentHash: "h", Metadata: map[string]string{"bucket": "a"}}
+	variants := []routingclient.ContentInfo{
+		base,
+		{ContentType: "image/png", ContentSize: 10, ContentHash: "h", Metadata: map[string]string{"bucket": "a"}},
+		{ContentType: "text/plain", ContentSize: 11, ContentHash: "h", Metadata: map[string]string{"bucket": "a"}},
+		{ContentType: "text/plain", ContentSize: 10, ContentHash: "h", Metadata: map[string]string{"bucket": "b"}},
+		{ContentType: "text/plain", ContentSize: 10, ContentHash: "h", Metadata: map[string]string{"bucket": "a"}, Backends: []string{"s3"}},
+		{ContentType: "text/plain", ContentSize: 10, ContentHash: "h", Metadata: map[string]string{"bucket": "a"}, TTL: 48 * time.Hour},
+	}
+	for i, info := range variants {
+		if _, err := client.SelectBackend(ctx, info, "hybrid"); err != nil {
+			t.Fatal(err)
+		}
+		if got := int(calls.Load()); got != i+1 {
+			t.Fatalf("variant %d: %d calls, want %d", i, got, i+1)
+		}
+	}
+	for _, info := range variants {
+		client.SelectBackend(ctx, info, "hybrid")
+	}
+	if got := int(calls.Load()); got != len(variants) {
+		t.Errorf("%d calls after repeating every request, want %d", got, len(variants))
+	}
+
+	client.SelectBackend(ctx, base, "cost")
+	client.SetLocality(routingclient.Locality{Region: "eu-west-1"})
+	client.SelectBackend(ctx, base, "hybrid")
+	if got := int(calls.Load()); got != len(variants)+2 {
+		t.Errorf("%d calls after changing strategy and location, want %d", got, len(variants)+2)
+	}
+}
+
+// TestSetCacheConcurrent swaps the cache while selections run; go test
+// -race reports the data race SetCache had
+func TestSetCacheConcurrent(t *testing.T) {
+	conn := &replyConn{invoke: func(args, reply any) error {
+		proto.Merge(reply.(proto.Message), &pb.SelectBackendResponse{BackendId: "ipfs"})
+		return nil
+	}}
+	client := routingclient.NewClient(conn)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				client.SelectBackend(context.Background(), routingclient.ContentInfo{ContentHash: "h"}, "hybrid")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				client.SetCache(routingclient.NewDecisionCache(4, time.Minute))
+				client.SetCache(nil)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,319 @@
+// Package routingclient wraps the generated routing gRPC stubs with a small,
+// idiomatic Go API for selecting backends and recording outcomes.
+package routingclient
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// ContentInfo represents the content metadata sent with routing requests
+type ContentInfo struct {
+	ContentType string            `json:"content_type"`
+	ContentSize int64             `json:"content_size"`
+	ContentHash string            `json:"content_hash"`
+	Filename    string            `json:"filename"`
+	Metadata    map[string]string `json:"metadata"`
+	// Providers is the DHT provider lookup for a retrieval of content
+	// already on IPFS, e.g. from FindProviders; nil if none was made
+	Providers *Providers `json:"providers,omitempty"`
+	// Backends, if set, restricts the selection to these backend IDs
+	Backends []string `json:"backends,omitempty"`
+	// TTL is how long the content is to be stored, e.g. 30 days; 0 keeps
+	// it indefinitely. The router prefers backends priced for that
+	// retention, and executors record when the content expires.
+	TTL time.Duration `json:"ttl,omitempty"`
+
+	hash *lazyHash // set by ContentInfoFromFile
+}
+
+// Outcome describes the result of an operation performed against a backend
+type Outcome struct {
+	BackendID string
+	Success   bool
+	Duration  time.Duration
+	Err       error
+	// IdempotencyKey lets the server drop retried copies of this outcome.
+	// It is generated when empty; callers that retry RecordOutcome
+	// themselves should set it so every attempt carries the same key.
+	// Only the Python HTTP routing API checks it, so against the Python
+	// server retries are deduplicated when the client has one (see
+	// WithHTTPAPI), and counted each time over plain gRPC.
+	IdempotencyKey string
+	// Bytes is how much data the operation moved, if known
+	Bytes int64
+	// Class categorises a failure for the server's statistics, e.g.
+	// OutcomeClassIntegrity. It is derived from Err when empty.
+	Class string
+	// Placement says where within the backend the content landed, e.g.
+	// the cluster peers a pin was allocated to
+	Placement []string
+}
+
+// OutcomeClassOutrun marks outcomes of reads cancelled because another
+// backend raced to answer first. They are not failures: the duration is
+// how long the backend had taken without answering, a lower bound on its
+// latency.
+const OutcomeClassOutrun = "outrun"
+
+// Timeouts bounds individual RPCs. Each is applied on top of the caller's
+// context, so the earlier deadline wins; zero applies no extra deadline.
+type Timeouts struct {
+	Select  time.Duration
+	Outcome time.Duration
+}
+
+// Client is a thin wrapper around the generated RoutingServiceClient
+type Client struct {
+	conn        Conn // set by New, which owns it
+	rpc         pb.RoutingServiceClient
+	scorer      Scorer
+	cache       atomic.Pointer[DecisionCache]
+	offline     *Offline
+	fallback    *FallbackConn
+	grpcConn    *grpc.ClientConn
+	timeouts    Timeouts
+	compression Compression
+	locality    *localityHint
+}
+
+// NewClient creates a Client using an established gRPC connection, which
+// the caller closes. New dials and configures the connection itself.
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{rpc: pb.NewRoutingServiceClient(conn)}
+}
+
+// SetTimeouts sets per-call deadlines for SelectBackend and RecordOutcome
+func (c *Client) SetTimeouts(t Timeouts) {
+	c.timeouts = t
+}
+
+// SetCompression compresses the client's RPCs with c, overriding any
+// default set on the connection. It returns an error if c's algorithm is not
+// registered.
+func (c *Client) SetCompression(comp Compression) error {
+	if err := comp.Validate(); err != nil {
+		return err
+	}
+	c.compression = comp
+	return nil
+}
+
+// Cache returns the client's decision cache, or nil without one
+func (c *Client) Cache() *DecisionCache {
+	return c.cache.Load()
+}
+
+// Offline returns the client's offline state, or nil without one
+func (c *Client) Offline() *Offline {
+	return c.offline
+}
+
+// Degraded reports whether the client's calls are going to its REST
+// fallback because the gRPC server is unreachable; it is always false for
+// a client without one
+func (c *Client) Degraded() bool {
+	return c.fallback != nil && c.fallback.Degraded()
+}
+
+// State returns the state of the client's gRPC connection, such as
+// "READY" or "TRANSIENT_FAILURE", and "SHUTDOWN" once closed. It is empty
+// for a client without one of its own, over gRPC-Web or a replay.
+func (c *Client) State() string {
+	if c.grpcConn == nil {
+		return ""
+	}
+	return c.grpcConn.GetState().String()
+}
+
+// withTimeout bounds ctx by d if d is positive
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// RPC returns the underlying generated client for calls not covered here
+func (c *Client) RPC() pb.RoutingServiceClient {
+	return c.rpc
+}
+
+// SelectBackend asks the routing service for the best backend for the
+// content. If a DecisionCache is set, a cached decision for the same
+// request is returned without calling the service. If a Scorer is
+// set, the response is re-ranked with it. RPC failures are returned as
+// *Error; a response naming no backend is ErrNoBackendAvailable.
+//
+// strategy is a strategy name such as "cost"; names ParseStrategy rejects
+// fail with ErrInvalidStrategy without calling the service. Select takes
+// the typed form.
+func (c *Client) SelectBackend(ctx context.Context, info ContentInfo, strategy string) (*pb.SelectBackendResponse, error) {
+	s, err := ParseStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+	return c.Select(ctx, info, s)
+}
+
+// Select is SelectBackend with a typed strategy, e.g. StrategyCost. The
+// request carries both the enum and its string form, so servers that
+// predate the enum still see the strategy.
+func (c *Client) Select(ctx context.Context, info ContentInfo, s pb.RoutingStrategy) (*pb.SelectBackendResponse, error) {
+	return c.selectBackend(ctx, info, s, false)
+}
+
+// DryRun is Select as a what-if: the service decides and explains as
+// usual (see the response's Reasoning) but does not count the request or
+// learn from it. The decision cache is bypassed.
+func (c *Client) DryRun(ctx context.Context, info ContentInfo, s pb.RoutingStrategy) (*pb.SelectBackendResponse, error) {
+	return c.selectBackend(ctx, info, s, true)
+}
+
+func (c *Client) selectBackend(ctx context.Context, info ContentInfo, s pb.RoutingStrategy, dryRun bool) (*pb.SelectBackendResponse, error) {
+	strategy := StrategyName(s)
+	info, err := info.withHash()
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := metadataStruct(info.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("build metadata: %w", err)
+	}
+
+	// The request ID doubles as the correlation ID so the decision can be
+	// traced in server logs
+	rpcCtx, requestID := ensureCorrelation(ctx)
+	rpcCtx, cancel := withTimeout(rpcCtx, c.timeouts.Select)
+	defer cancel()
+	req := &pb.SelectBackendRequest{
+		ContentType:       info.ContentType,
+		ContentSize:       info.ContentSize,
+		ContentHash:       info.ContentHash,
+		Metadata:          metadata,
+		Strategy:          strategy,
+		RequestId:         requestID,
+		Timestamp:         timestamppb.Now(),
+		StrategyType:      s,
+		ContentCategory:   CategoryOf(info.ContentType),
+		DryRun:            dryRun,
+		ClientLocation:    c.locality.get(rpcCtx).proto(),
+		Providers:         info.Providers.proto(),
+		AvailableBackends: info.Backends,
+		RetentionDays:     retentionDays(info.TTL),
+	}
+
+	// Decisions are cached by the whole request, but provider counts
+	// change between calls, so decisions made with them are not cached
+	cache := c.cache.Load()
+	cacheable := cache != nil && info.ContentHash != "" && !dryRun && info.Providers == nil
+	if cacheable {
+		if resp, ok := cache.Get(req); ok {
+			return c.rerank(ctx, info, resp), nil
+		}
+	}
+
+	resp, err := c.rpc.SelectBackend(rpcCtx, req, c.compression.CallOption(true))
+	if err != nil {
+		if o := c.offline; o != nil && !dryRun && unreachable(ctx, err) {
+			if resp, ok := o.decision(info, strategy, requestID); ok {
+				if o.OnOffline != nil {
+					o.OnOffline(err)
+				}
+				return c.rerank(ctx, info, resp), nil
+			}
+		}
+		return nil, toError(err)
+	}
+	if resp.BackendId == "" {
+		return nil, newError(errorStatus(codes.FailedPrecondition, ReasonNoBackendAvailable, "server selected no backend"))
+	}
+	if cacheable {
+		cache.Put(req, resp)
+	}
+	if c.offline != nil && !dryRun {
+		c.offline.remember(info, strategy, resp)
+		c.offline.online(c)
+	}
+	return c.rerank(ctx, info, resp), nil
+}
+
+// rerank applies the client's scorer, if any, to resp
+func (c *Client) rerank(ctx context.Context, info ContentInfo, resp *pb.SelectBackendResponse) *pb.SelectBackendResponse {
+	if c.scorer != nil {
+		Rerank(ctx, c.scorer, info, resp)
+	}
+	return resp
+}
+
+// RecordOutcome reports the outcome of an operation for the given content.
+// With an Offline set, an outcome the service cannot be reached for is
+// queued in its log instead, and the response has Success unset and says
+// so in Message.
+func (c *Client) RecordOutcome(ctx context.Context, info ContentInfo, outcome Outcome) (*pb.RecordOutcomeResponse, error) {
+	rpcCtx, cancel := withTimeout(ctx, c.timeouts.Outcome)
+	defer cancel()
+	req := outcomeRequest(info, outcome)
+	resp, err := c.rpc.RecordOutcome(rpcCtx, req, c.compression.CallOption(false))
+	if o := c.offline; o != nil {
+		if err == nil {
+			o.online(c)
+		} else if unreachable(ctx, err) && o.queue(req) == nil {
+			if o.OnOffline != nil {
+				o.OnOffline(err)
+			}
+			return &pb.RecordOutcomeResponse{Message: "queued offline", Timestamp: timestamppb.Now()}, nil
+		}
+	}
+	return resp, toError(err)
+}
+
+// outcomeRequest builds the RecordOutcome request for an outcome. If the
+// content hash cannot be computed the outcome is sent without one.
+func outcomeRequest(info ContentInfo, outcome Outcome) *pb.RecordOutcomeRequest {
+	info, _ = info.withHash()
+	key := outcome.IdempotencyKey
+	if key == "" {
+		key = NewCorrelationID()
+	}
+	req := &pb.RecordOutcomeRequest{
+		BackendId:        outcome.BackendID,
+		Success:          outcome.Success,
+		ContentType:      info.ContentType,
+		ContentSize:      info.ContentSize,
+		ContentHash:      info.ContentHash,
+		DurationMs:       int32(outcome.Duration.Milliseconds()),
+		Timestamp:        timestamppb.Now(),
+		IdempotencyKey:   key,
+		BytesTransferred: outcome.Bytes,
+		Placement:        outcome.Placement,
+	}
+	if outcome.Err != nil {
+		req.Error = outcome.Err.Error()
+	}
+	req.ErrorClass = outcome.ErrorClass()
+	return req
+}
+
+// metadataStruct converts string metadata into a protobuf Struct
+func metadataStruct(metadata map[string]string) (*structpb.Struct, error) {
+	fields := make(map[string]interface{}, len(metadata))
+	for k, v := range metadata {
+		fields[k] = v
+	}
+	return structpb.NewStruct(fields)
+}
+
+// newRequestID generates a client-side request identifier
+func newRequestID() string {
+	return fmt.Sprintf("go-client-%d", time.Now().UnixNano())
+}
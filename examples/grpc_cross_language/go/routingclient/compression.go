@@ -0,0 +1,72 @@
+package routingclient
+
+import (
+	"flag"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// Compression configures gRPC message compression. It pays off for
+// requests carrying large metadata Structs, outcome batches and insights
+// responses; selection requests are small and latency-sensitive, so they
+// can be left uncompressed with SkipSelect.
+//
+// The client advertises every registered compressor in grpc-accept-encoding,
+// so the server may compress responses whenever it supports one of them.
+// Sending with an algorithm the server lacks fails with Unimplemented.
+type Compression struct {
+	// Algorithm names a registered compressor. "gzip" is always available;
+	// "zstd" requires the program to register a zstd encoding.Compressor.
+	// Empty or "none" sends messages uncompressed.
+	Algorithm string
+	// SkipSelect leaves SelectBackend requests uncompressed
+	SkipSelect bool
+}
+
+// RegisterFlags adds -compression and -compression-skip-select to fs,
+// defaulting to the current values of c
+func (c *Compression) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Algorithm, "compression", c.Algorithm, "compress RPC messages with gzip or zstd (none disables)")
+	fs.BoolVar(&c.SkipSelect, "compression-skip-select", c.SkipSelect, "send SelectBackend requests uncompressed for lower latency")
+}
+
+// enabled reports whether c compresses anything
+func (c Compression) enabled() bool {
+	return c.Algorithm != "" && c.Algorithm != "none"
+}
+
+// Validate reports an error if the algorithm is not registered
+func (c Compression) Validate() error {
+	if !c.enabled() || c.Algorithm == gzip.Name {
+		return nil
+	}
+	if encoding.GetCompressor(c.Algorithm) == nil {
+		return fmt.Errorf("routing: compressor %q is not registered", c.Algorithm)
+	}
+	return nil
+}
+
+// DialOption returns the grpc dial option compressing every call on the
+// connection with c's algorithm. It is a no-op when compression is disabled.
+func (c Compression) DialOption() grpc.DialOption {
+	if !c.enabled() {
+		return grpc.EmptyDialOption{}
+	}
+	return grpc.WithDefaultCallOptions(grpc.UseCompressor(c.Algorithm))
+}
+
+// CallOption returns the call option for a single RPC. lowLatency marks
+// calls that SkipSelect applies to; they are sent uncompressed even when
+// the connection compresses by default.
+func (c Compression) CallOption(lowLatency bool) grpc.CallOption {
+	switch {
+	case !c.enabled():
+		return grpc.EmptyCallOption{}
+	case lowLatency && c.SkipSelect:
+		return grpc.UseCompressor(encoding.Identity)
+	}
+	return grpc.UseCompressor(c.Algorithm)
+}
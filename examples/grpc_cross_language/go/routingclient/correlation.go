@@ -0,0 +1,106 @@
+package routingclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// CorrelationHeader is the metadata key (and HTTP header) carrying the
+// correlation ID of a call
+const CorrelationHeader = "x-correlation-id"
+
+type correlationKey struct{}
+
+// NewCorrelationID returns a random 128-bit correlation ID in hex
+func NewCorrelationID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithCorrelationID returns a context whose calls carry id, so one ID can
+// tie a selection, the transfer it led to and its outcome together
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation ID of ctx: the one set with
+// WithCorrelationID, else one already in the outgoing metadata, else ""
+func CorrelationID(ctx context.Context) string {
+	if id, ok := ctx.Value(correlationKey{}).(string); ok && id != "" {
+		return id
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if v := md.Get(CorrelationHeader); len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// ensureCorrelation returns ctx carrying a correlation ID, generating one
+// if needed, with the ID in the outgoing metadata
+func ensureCorrelation(ctx context.Context) (context.Context, string) {
+	id := CorrelationID(ctx)
+	if id == "" {
+		id = NewCorrelationID()
+	}
+	ctx = WithCorrelationID(ctx, id)
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(CorrelationHeader)) > 0 {
+		return ctx, id
+	}
+	return metadata.AppendToOutgoingContext(ctx, CorrelationHeader, id), id
+}
+
+// Logf is a printf-style logger such as log.Printf
+type Logf func(format string, args ...any)
+
+// CorrelationInterceptor returns a unary client interceptor that forwards
+// the context's correlation ID, or generates one, on every call. If logf is
+// non-nil it logs each call with the ID and the request_id the server
+// echoed, when the response has one.
+func CorrelationInterceptor(logf Logf) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, id := ensureCorrelation(ctx)
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if logf != nil {
+			var requestID string
+			if r, ok := reply.(interface{ GetRequestId() string }); ok && err == nil {
+				requestID = r.GetRequestId()
+			}
+			logf("routing: %s correlation_id=%s request_id=%s code=%s duration=%s",
+				path.Base(method), id, requestID, status.Code(err), time.Since(start).Round(time.Microsecond))
+		}
+		return err
+	}
+}
+
+// CorrelationStreamInterceptor is the streaming counterpart of
+// CorrelationInterceptor; it logs when the stream opens
+func CorrelationStreamInterceptor(logf Logf) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, id := ensureCorrelation(ctx)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if logf != nil {
+			logf("routing: %s stream correlation_id=%s code=%s", path.Base(method), id, status.Code(err))
+		}
+		return cs, err
+	}
+}
+
+// WithCorrelation wraps conn so every call goes through the correlation
+// interceptors. Unlike the dial options it works for any Conn, including
+// WebConn, RESTConn and FallbackConn.
+func WithCorrelation(conn Conn, logf Logf) Conn {
+	return intercept(conn,
+		[]grpc.UnaryClientInterceptor{CorrelationInterceptor(logf)},
+		[]grpc.StreamClientInterceptor{CorrelationStreamInterceptor(logf)})
+}
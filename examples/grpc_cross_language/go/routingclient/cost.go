@@ -0,0 +1,59 @@
+package routingclient
+
+import (
+	"context"
+	"time"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// CostQuery describes how content will be kept, for EstimateCost
+type CostQuery struct {
+	// Retention is how long the content is stored, rounded up to whole
+	// days; zero uses the content's TTL, or without one the server's
+	// default of 30 days
+	Retention time.Duration
+	// Retrievals is how many times the content is expected to be read in
+	// full over the retention period
+	Retrievals float64
+	// Backends limits the estimate to these candidates; empty means every
+	// backend that accepts the content
+	Backends []string
+}
+
+// EstimateCost projects the storage and egress cost of info on each
+// candidate backend, cheapest first, so pipelines can budget before
+// committing a large upload. Amounts are in the response's currency.
+// Naming an unknown backend fails with ErrUnknownBackend.
+func (c *Client) EstimateCost(ctx context.Context, info ContentInfo, q CostQuery) (*pb.EstimateCostResponse, error) {
+	ctx, cancel := withTimeout(ctx, c.timeouts.Select)
+	defer cancel()
+	retention := q.Retention
+	if retention == 0 {
+		retention = info.TTL
+	}
+	resp, err := c.rpc.EstimateCost(ctx, &pb.EstimateCostRequest{
+		ContentType:        info.ContentType,
+		ContentSize:        info.ContentSize,
+		RetentionDays:      retentionDays(retention),
+		ExpectedRetrievals: q.Retrievals,
+		Backends:           q.Backends,
+	})
+	return resp, toError(err)
+}
+
+// retentionDays is d in whole days, rounded up
+func retentionDays(d time.Duration) int32 {
+	return int32((d + 24*time.Hour - 1) / (24 * time.Hour))
+}
+
+// Cheapest returns the lowest-cost estimate, or nil if there is none
+func Cheapest(resp *pb.EstimateCostResponse) *pb.CostEstimate {
+	var best *pb.CostEstimate
+	for _, e := range resp.GetEstimates() {
+		if best == nil || e.TotalCost < best.TotalCost {
+			best = e
+		}
+	}
+	return best
+}
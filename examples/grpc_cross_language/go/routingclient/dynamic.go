@@ -0,0 +1,286 @@
+package routingclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Server reflection methods, newest first. The v1alpha messages are
+// wire-compatible with v1, so both are spoken with the v1 types.
+var reflectionMethods = []string{
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+}
+
+// DynamicClient calls RPCs described by the server's reflection service
+// instead of generated code, so new or experimental methods can be used
+// without regenerating stubs. Requests and responses are protobuf JSON.
+type DynamicClient struct {
+	cc grpc.ClientConnInterface
+
+	mu      sync.Mutex
+	version int // index into reflectionMethods
+	files   map[string]*descriptorpb.FileDescriptorProto
+}
+
+// NewDynamicClient creates a DynamicClient on cc. The server must have
+// reflection enabled.
+func NewDynamicClient(cc grpc.ClientConnInterface) *DynamicClient {
+	return &DynamicClient{cc: cc, files: make(map[string]*descriptorpb.FileDescriptorProto)}
+}
+
+// Services lists the services the server exposes, sorted by name
+func (d *DynamicClient) Services(ctx context.Context) ([]string, error) {
+	resp, err := d.reflect(ctx, &rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		names = append(names, s.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Service resolves a service descriptor by its full name
+func (d *DynamicClient) Service(ctx context.Context, name string) (protoreflect.ServiceDescriptor, error) {
+	files, err := d.resolve(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("routing: %s: %w", name, err)
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("routing: %s is not a service", name)
+	}
+	return sd, nil
+}
+
+// Method resolves a method given as "pkg.Service/Method" or
+// "pkg.Service.Method"
+func (d *DynamicClient) Method(ctx context.Context, name string) (protoreflect.MethodDescriptor, error) {
+	name = strings.TrimPrefix(name, "/")
+	i := strings.LastIndexAny(name, "/.")
+	if i <= 0 {
+		return nil, fmt.Errorf("routing: method %q is not of the form pkg.Service/Method", name)
+	}
+	sd, err := d.Service(ctx, name[:i])
+	if err != nil {
+		return nil, err
+	}
+	md := sd.Methods().ByName(protoreflect.Name(name[i+1:]))
+	if md == nil {
+		return nil, fmt.Errorf("routing: service %s has no method %s", sd.FullName(), name[i+1:])
+	}
+	return md, nil
+}
+
+// Call invokes method with the JSON request in and passes each response,
+// as JSON, to fn: once for a unary method, once per message for a
+// server-streaming one. Client-streaming methods are not supported.
+func (d *DynamicClient) Call(ctx context.Context, method string, in []byte, fn func(out []byte) error, opts ...grpc.CallOption) error {
+	md, err := d.Method(ctx, method)
+	if err != nil {
+		return err
+	}
+	if md.IsStreamingClient() {
+		return fmt.Errorf("routing: %s is client-streaming, which dynamic calls do not support", md.FullName())
+	}
+	req := dynamicpb.NewMessage(md.Input())
+	if len(strings.TrimSpace(string(in))) > 0 {
+		if err := protojson.Unmarshal(in, req); err != nil {
+			return fmt.Errorf("routing: %s request: %w", md.Input().FullName(), err)
+		}
+	}
+	path := fmt.Sprintf("/%s/%s", md.Parent().FullName(), md.Name())
+	emit := func(m proto.Message) error {
+		out, err := protojson.Marshal(m)
+		if err != nil {
+			return err
+		}
+		return fn(out)
+	}
+
+	if !md.IsStreamingServer() {
+		resp := dynamicpb.NewMessage(md.Output())
+		if err := d.cc.Invoke(ctx, path, req, resp, opts...); err != nil {
+			return err
+		}
+		return emit(resp)
+	}
+	cs, err := d.cc.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, path, opts...)
+	if err != nil {
+		return err
+	}
+	if err := cs.SendMsg(req); err != nil {
+		return err
+	}
+	if err := cs.CloseSend(); err != nil {
+		return err
+	}
+	for {
+		resp := dynamicpb.NewMessage(md.Output())
+		if err := cs.RecvMsg(resp); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := emit(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// reflect sends one reflection request, falling back to v1alpha on servers
+// without v1
+func (d *DynamicClient) reflect(ctx context.Context, req *rpb.ServerReflectionRequest) (*rpb.ServerReflectionResponse, error) {
+	d.mu.Lock()
+	version := d.version
+	d.mu.Unlock()
+	for ; version < len(reflectionMethods); version++ {
+		resp, err := d.reflectOnce(ctx, reflectionMethods[version], req)
+		if status.Code(err) == codes.Unimplemented {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		d.mu.Lock()
+		d.version = version
+		d.mu.Unlock()
+		if e := resp.GetErrorResponse(); e != nil {
+			return nil, status.Error(codes.Code(e.ErrorCode), e.ErrorMessage)
+		}
+		return resp, nil
+	}
+	return nil, status.Error(codes.Unimplemented, "routing: server reflection is not enabled on the server")
+}
+
+func (d *DynamicClient) reflectOnce(ctx context.Context, method string, req *rpb.ServerReflectionRequest) (*rpb.ServerReflectionResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cs, err := d.cc.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}, method)
+	if err != nil {
+		return nil, err
+	}
+	if err := cs.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := cs.CloseSend(); err != nil {
+		return nil, err
+	}
+	resp := new(rpb.ServerReflectionResponse)
+	if err := cs.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// resolve fetches the file defining symbol and its dependencies and
+// returns a registry of every file fetched so far
+func (d *DynamicClient) resolve(ctx context.Context, symbol string) (*protoregistry.Files, error) {
+	resp, err := d.reflect(ctx, &rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol},
+	})
+	if err != nil {
+		return nil, err
+	}
+	pending, err := d.addFiles(resp)
+	if err != nil {
+		return nil, err
+	}
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		if d.known(name) {
+			continue
+		}
+		resp, err := d.reflect(ctx, &rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: name},
+		})
+		if err != nil {
+			// Servers often omit well-known types; use the compiled-in copy
+			fd, gerr := protoregistry.GlobalFiles.FindFileByPath(name)
+			if gerr != nil {
+				return nil, fmt.Errorf("routing: resolve %s: %w", name, err)
+			}
+			d.mu.Lock()
+			d.files[name] = protodesc.ToFileDescriptorProto(fd)
+			d.mu.Unlock()
+			for i := 0; i < fd.Imports().Len(); i++ {
+				pending = append(pending, fd.Imports().Get(i).Path())
+			}
+			continue
+		}
+		more, err := d.addFiles(resp)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, more...)
+	}
+
+	d.mu.Lock()
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, fd := range d.files {
+		set.File = append(set.File, fd)
+	}
+	d.mu.Unlock()
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("routing: build descriptors: %w", err)
+	}
+	return files, nil
+}
+
+// addFiles stores the files in a reflection response and returns the
+// dependencies not yet fetched
+func (d *DynamicClient) addFiles(resp *rpb.ServerReflectionResponse) ([]string, error) {
+	var missing []string
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		fd := new(descriptorpb.FileDescriptorProto)
+		if err := proto.Unmarshal(raw, fd); err != nil {
+			return nil, fmt.Errorf("routing: decode file descriptor: %w", err)
+		}
+		d.files[fd.GetName()] = fd
+	}
+	for _, fd := range d.files {
+		for _, dep := range fd.Dependency {
+			if _, ok := d.files[dep]; !ok {
+				missing = append(missing, dep)
+			}
+		}
+	}
+	return missing, nil
+}
+
+func (d *DynamicClient) known(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.files[name]
+	return ok
+}
@@ -0,0 +1,126 @@
+package routingclient
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// Routing strategies. Passing these rather than strings to Select catches
+// typos at compile time.
+const (
+	StrategyContentType = pb.RoutingStrategy_ROUTING_STRATEGY_CONTENT_TYPE
+	StrategyCost        = pb.RoutingStrategy_ROUTING_STRATEGY_COST
+	StrategyPerformance = pb.RoutingStrategy_ROUTING_STRATEGY_PERFORMANCE
+	StrategyHybrid      = pb.RoutingStrategy_ROUTING_STRATEGY_HYBRID
+	StrategyLatency     = pb.RoutingStrategy_ROUTING_STRATEGY_LATENCY
+	StrategyGeographic  = pb.RoutingStrategy_ROUTING_STRATEGY_GEOGRAPHIC
+	StrategyReliability = pb.RoutingStrategy_ROUTING_STRATEGY_RELIABILITY
+	StrategyBalanced    = pb.RoutingStrategy_ROUTING_STRATEGY_BALANCED
+)
+
+const (
+	strategyPrefix = "ROUTING_STRATEGY_"
+	categoryPrefix = "CONTENT_CATEGORY_"
+)
+
+// StrategyName returns the string form of s sent in the legacy strategy
+// field, e.g. "content_type", or "" for the unspecified strategy
+func StrategyName(s pb.RoutingStrategy) string {
+	return enumName(s.String(), strategyPrefix)
+}
+
+// ParseStrategy parses a strategy given in string form ("cost") or as the
+// enum name ("ROUTING_STRATEGY_COST"). "" is the unspecified strategy.
+// Unknown names return an error matching ErrInvalidStrategy.
+func ParseStrategy(name string) (pb.RoutingStrategy, error) {
+	v, ok := parseEnum(name, strategyPrefix, pb.RoutingStrategy_value)
+	if !ok {
+		return 0, fmt.Errorf("%w %q", ErrInvalidStrategy, name)
+	}
+	return pb.RoutingStrategy(v), nil
+}
+
+// CategoryName returns the string form of c, e.g. "image"
+func CategoryName(c pb.ContentCategory) string {
+	return enumName(c.String(), categoryPrefix)
+}
+
+// ParseCategory parses a content category given in string form or as the
+// enum name
+func ParseCategory(name string) (pb.ContentCategory, error) {
+	v, ok := parseEnum(name, categoryPrefix, pb.ContentCategory_value)
+	if !ok {
+		return 0, fmt.Errorf("routing: invalid content category %q", name)
+	}
+	return pb.ContentCategory(v), nil
+}
+
+// CategoryOf classifies a MIME type. Unrecognised types are
+// CONTENT_CATEGORY_UNSPECIFIED, leaving the server to decide.
+func CategoryOf(contentType string) pb.ContentCategory {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	major, minor, _ := strings.Cut(mt, "/")
+	switch major {
+	case "image":
+		return pb.ContentCategory_CONTENT_CATEGORY_IMAGE
+	case "video":
+		return pb.ContentCategory_CONTENT_CATEGORY_VIDEO
+	case "audio":
+		return pb.ContentCategory_CONTENT_CATEGORY_AUDIO
+	case "text":
+		if minor == "csv" || minor == "tab-separated-values" {
+			return pb.ContentCategory_CONTENT_CATEGORY_DATASET
+		}
+		return pb.ContentCategory_CONTENT_CATEGORY_DOCUMENT
+	case "application":
+		return applicationCategory(minor)
+	}
+	return pb.ContentCategory_CONTENT_CATEGORY_UNSPECIFIED
+}
+
+// applicationCategory classifies an application/* subtype
+func applicationCategory(minor string) pb.ContentCategory {
+	switch {
+	case minor == "pdf" || minor == "msword" || minor == "rtf" ||
+		strings.HasPrefix(minor, "vnd.openxmlformats") || strings.HasPrefix(minor, "vnd.oasis.opendocument"):
+		return pb.ContentCategory_CONTENT_CATEGORY_DOCUMENT
+	case minor == "json" || minor == "xml" || minor == "x-ndjson" || minor == "vnd.apache.parquet" ||
+		minor == "x-hdf5" || minor == "vnd.apache.arrow.file":
+		return pb.ContentCategory_CONTENT_CATEGORY_DATASET
+	case minor == "onnx" || minor == "x-safetensors" || minor == "x-pytorch" || minor == "x-tensorflow":
+		return pb.ContentCategory_CONTENT_CATEGORY_MODEL
+	case minor == "zip" || minor == "gzip" || minor == "x-tar" || minor == "x-7z-compressed" ||
+		minor == "zstd" || minor == "x-xz" || minor == "vnd.ipld.car":
+		return pb.ContentCategory_CONTENT_CATEGORY_ARCHIVE
+	case minor == "octet-stream":
+		return pb.ContentCategory_CONTENT_CATEGORY_BINARY
+	}
+	return pb.ContentCategory_CONTENT_CATEGORY_UNSPECIFIED
+}
+
+// enumName lower-cases an enum value name and strips its prefix; the zero
+// value maps to ""
+func enumName(name, prefix string) string {
+	if name == prefix+"UNSPECIFIED" {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(name, prefix))
+}
+
+func parseEnum(name, prefix string, values map[string]int32) (int32, bool) {
+	if name == "" {
+		return 0, true
+	}
+	upper := strings.ToUpper(name)
+	if !strings.HasPrefix(upper, prefix) {
+		upper = prefix + upper
+	}
+	v, ok := values[upper]
+	return v, ok
+}
@@ -0,0 +1,135 @@
+package routingclient
+
+import (
+	"errors"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Routing failures callers commonly act on. Errors returned by Client
+// methods match them with errors.Is:
+//
+//	if errors.Is(err, routingclient.ErrNoBackendAvailable) { ... }
+var (
+	ErrNoBackendAvailable = errors.New("routing: no backend available")
+	ErrInvalidStrategy    = errors.New("routing: invalid strategy")
+	ErrUnauthorized       = errors.New("routing: unauthorized")
+	ErrUnknownBackend     = errors.New("routing: unknown backend")
+)
+
+// ErrorDomain is the google.rpc.ErrorInfo domain of routing server errors
+const ErrorDomain = "routing.ipfs_kit_py"
+
+// ErrorInfo reasons a server attaches to say which failure occurred.
+// They take precedence over guessing from the status code.
+const (
+	ReasonNoBackendAvailable = "NO_BACKEND_AVAILABLE"
+	ReasonInvalidStrategy    = "INVALID_STRATEGY"
+	ReasonUnauthorized       = "UNAUTHORIZED"
+	ReasonUnknownBackend     = "UNKNOWN_BACKEND"
+)
+
+var reasonErrors = map[string]error{
+	ReasonNoBackendAvailable: ErrNoBackendAvailable,
+	ReasonInvalidStrategy:    ErrInvalidStrategy,
+	ReasonUnauthorized:       ErrUnauthorized,
+	ReasonUnknownBackend:     ErrUnknownBackend,
+}
+
+// Error is a failed routing RPC. It unwraps to one of the Err* values when
+// the failure is recognised, and still carries the gRPC status, so
+// status.Code and status.FromError keep working on it.
+type Error struct {
+	Code    codes.Code
+	Message string
+	// Reason is the server's ErrorInfo reason, if it sent one
+	Reason string
+	// Metadata is the server's ErrorInfo metadata, if it sent any
+	Metadata map[string]string
+
+	kind error
+	st   *status.Status
+}
+
+func (e *Error) Error() string {
+	return "routing: " + e.Code.String() + ": " + e.Message
+}
+
+// Unwrap returns the Err* value the failure was classified as, or nil
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+// GRPCStatus returns the original status
+func (e *Error) GRPCStatus() *status.Status {
+	return e.st
+}
+
+// toError converts a gRPC status error into an *Error. Other errors,
+// including nil, are returned unchanged.
+func toError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return newError(st)
+}
+
+func newError(st *status.Status) *Error {
+	e := &Error{Code: st.Code(), Message: st.Message(), st: st}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			e.Reason, e.Metadata = info.Reason, info.Metadata
+			break
+		}
+	}
+	e.kind = classify(e)
+	return e
+}
+
+// classify maps a failure to an Err* value, preferring the server's reason
+func classify(e *Error) error {
+	if kind, ok := reasonErrors[e.Reason]; ok {
+		return kind
+	}
+	msg := strings.ToLower(e.Message)
+	switch e.Code {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return ErrUnauthorized
+	case codes.InvalidArgument:
+		if strings.Contains(msg, "strategy") {
+			return ErrInvalidStrategy
+		}
+	case codes.NotFound, codes.FailedPrecondition, codes.ResourceExhausted:
+		if e.Code == codes.NotFound && strings.Contains(msg, "unknown backend") {
+			return ErrUnknownBackend
+		}
+		if strings.Contains(msg, "backend") {
+			return ErrNoBackendAvailable
+		}
+	}
+	return nil
+}
+
+// errorStatus builds a status carrying an ErrorInfo with reason, as a
+// server would send it
+func errorStatus(code codes.Code, reason, msg string) *status.Status {
+	st := status.New(code, msg)
+	if reason == "" {
+		return st
+	}
+	if withInfo, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain}); err == nil {
+		return withInfo
+	}
+	return st
+}
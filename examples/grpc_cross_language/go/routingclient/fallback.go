@@ -0,0 +1,137 @@
+package routingclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// FallbackConn sends calls over a primary connection, usually gRPC, and
+// degrades to a secondary one, usually a RESTConn, when the primary is
+// unreachable. Once it has fallen back it stays on the secondary for
+// Retry before trying the primary again.
+//
+// The primary counts as unreachable when it fails with Unavailable, or,
+// for a *grpc.ClientConn, when it cannot reach the ready state within
+// ConnectTimeout; a blocked port otherwise stalls until the caller's
+// deadline. Calls that fail for other reasons are not retried.
+type FallbackConn struct {
+	Primary   Conn
+	Secondary Conn
+	// ConnectTimeout bounds how long a call waits for the primary to
+	// connect (default 3s)
+	ConnectTimeout time.Duration
+	// Retry is how long to use the secondary before probing the primary
+	// again (default 30s)
+	Retry time.Duration
+	// OnFallback, if set, is called with the primary's error each time
+	// calls switch to the secondary
+	OnFallback func(err error)
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+// NewFallbackConn creates a FallbackConn with the default timeouts
+func NewFallbackConn(primary, secondary Conn) *FallbackConn {
+	return &FallbackConn{
+		Primary:        primary,
+		Secondary:      secondary,
+		ConnectTimeout: 3 * time.Second,
+		Retry:          30 * time.Second,
+	}
+}
+
+// Close closes both connections
+func (f *FallbackConn) Close() error {
+	return errors.Join(f.Primary.Close(), f.Secondary.Close())
+}
+
+// Degraded reports whether calls are currently going to the secondary
+func (f *FallbackConn) Degraded() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return time.Now().Before(f.until)
+}
+
+// Invoke implements grpc.ClientConnInterface
+func (f *FallbackConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	if f.Degraded() {
+		return f.Secondary.Invoke(ctx, method, args, reply, opts...)
+	}
+	err := f.ready(ctx)
+	if err == nil {
+		err = f.Primary.Invoke(ctx, method, args, reply, opts...)
+	}
+	if !f.unreachable(ctx, err) {
+		return err
+	}
+	f.fallBack(err)
+	return f.Secondary.Invoke(ctx, method, args, reply, opts...)
+}
+
+// NewStream implements grpc.ClientConnInterface. Only opening the stream
+// falls back; a stream that breaks later returns its error.
+func (f *FallbackConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if f.Degraded() {
+		return f.Secondary.NewStream(ctx, desc, method, opts...)
+	}
+	err := f.ready(ctx)
+	if err == nil {
+		var cs grpc.ClientStream
+		if cs, err = f.Primary.NewStream(ctx, desc, method, opts...); err == nil {
+			return cs, nil
+		}
+	}
+	if !f.unreachable(ctx, err) {
+		return nil, err
+	}
+	f.fallBack(err)
+	return f.Secondary.NewStream(ctx, desc, method, opts...)
+}
+
+// ready waits up to ConnectTimeout for a gRPC primary to connect
+func (f *FallbackConn) ready(ctx context.Context) error {
+	cc, ok := f.Primary.(*grpc.ClientConn)
+	if !ok {
+		return nil
+	}
+	state := cc.GetState()
+	if state == connectivity.Ready {
+		return nil
+	}
+	cc.Connect()
+	wctx, cancel := context.WithTimeout(ctx, f.ConnectTimeout)
+	defer cancel()
+	for state != connectivity.Ready {
+		if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+			return status.Errorf(codes.Unavailable, "routing: primary connection is %s", state)
+		}
+		if !cc.WaitForStateChange(wctx, state) {
+			return status.Errorf(codes.Unavailable, "routing: primary did not connect within %s", f.ConnectTimeout)
+		}
+		state = cc.GetState()
+	}
+	return nil
+}
+
+// unreachable reports whether err means the primary could not be reached
+// and the caller still has time to try the secondary
+func (f *FallbackConn) unreachable(ctx context.Context, err error) bool {
+	return status.Code(err) == codes.Unavailable && ctx.Err() == nil
+}
+
+func (f *FallbackConn) fallBack(err error) {
+	f.mu.Lock()
+	f.until = time.Now().Add(f.Retry)
+	f.mu.Unlock()
+	if f.OnFallback != nil {
+		f.OnFallback(err)
+	}
+}
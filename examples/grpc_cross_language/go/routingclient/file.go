@@ -0,0 +1,157 @@
+package routingclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sniffLen is how much of a file is read to detect its type, which is all
+// http.DetectContentType looks at and covers tar's magic at offset 257
+const sniffLen = 512
+
+// magic is a file signature http.DetectContentType does not know
+type magic struct {
+	offset int
+	sig    []byte
+	mime   string
+}
+
+var magics = []magic{
+	{0, []byte("PAR1"), "application/vnd.apache.parquet"},
+	{0, []byte{0x28, 0xb5, 0x2f, 0xfd}, "application/zstd"},
+	{0, []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}, "application/x-7z-compressed"},
+	{0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, "application/x-xz"},
+	{0, []byte{0x89, 'H', 'D', 'F', '\r', '\n', 0x1a, '\n'}, "application/x-hdf5"},
+	{0, []byte("ARROW1"), "application/vnd.apache.arrow.file"},
+	{257, []byte("ustar"), "application/x-tar"},
+}
+
+// ContentInfoFromFile describes the file at path for routing. The size and
+// modification time come from stat, and the MIME type is sniffed from the
+// file's first bytes, falling back to the extension when the content is
+// plain text or unrecognised. Metadata gets "mtime", "extension" and
+// "size_bucket" (small, medium, large or very_large).
+//
+// The content hash, the hex SHA-256 of the file, is not computed here:
+// Hash computes it on first use, and SelectBackend and RecordOutcome call
+// Hash when ContentHash is empty, so describing a large file stays cheap.
+func ContentInfoFromFile(path string) (ContentInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ContentInfo{}, fmt.Errorf("routing: %w", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return ContentInfo{}, fmt.Errorf("routing: %w", err)
+	}
+	if !fi.Mode().IsRegular() {
+		return ContentInfo{}, fmt.Errorf("routing: %s is not a regular file", path)
+	}
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return ContentInfo{}, fmt.Errorf("routing: read %s: %w", path, err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	return ContentInfo{
+		ContentType: sniffType(head[:n], ext),
+		ContentSize: fi.Size(),
+		Filename:    filepath.Base(path),
+		Metadata: map[string]string{
+			"mtime":       fi.ModTime().UTC().Format(time.RFC3339),
+			"extension":   ext,
+			"size_bucket": sizeBucket(fi.Size()),
+		},
+		hash: &lazyHash{path: path},
+	}, nil
+}
+
+// sniffType detects the MIME type of content starting with head. The
+// extension decides only when sniffing finds nothing more specific than
+// text or binary, e.g. for JSON or CSV.
+func sniffType(head []byte, ext string) string {
+	for _, m := range magics {
+		if len(head) >= m.offset+len(m.sig) && bytes.Equal(head[m.offset:m.offset+len(m.sig)], m.sig) {
+			return m.mime
+		}
+	}
+	sniffed := http.DetectContentType(head)
+	generic := sniffed == "application/octet-stream" || strings.HasPrefix(sniffed, "text/plain")
+	if generic {
+		if byExt := mime.TypeByExtension(ext); byExt != "" {
+			return byExt
+		}
+	}
+	return sniffed
+}
+
+// sizeBucket names the size classes the routers use
+func sizeBucket(size int64) string {
+	switch {
+	case size < 1<<20:
+		return "small"
+	case size < 100<<20:
+		return "medium"
+	case size < 1<<30:
+		return "large"
+	}
+	return "very_large"
+}
+
+// lazyHash hashes a file once, on first use
+type lazyHash struct {
+	path string
+	once sync.Once
+	sum  string
+	err  error
+}
+
+func (l *lazyHash) get() (string, error) {
+	l.once.Do(func() {
+		f, err := os.Open(l.path)
+		if err != nil {
+			l.err = fmt.Errorf("routing: hash: %w", err)
+			return
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			l.err = fmt.Errorf("routing: hash %s: %w", l.path, err)
+			return
+		}
+		l.sum = hex.EncodeToString(h.Sum(nil))
+	})
+	return l.sum, l.err
+}
+
+// Hash returns ContentHash if set. For a ContentInfo from
+// ContentInfoFromFile it otherwise hashes the file, once; copies of info
+// share the result. Other ContentInfos without a hash return "".
+func (info ContentInfo) Hash() (string, error) {
+	if info.ContentHash != "" || info.hash == nil {
+		return info.ContentHash, nil
+	}
+	return info.hash.get()
+}
+
+// withHash returns info with ContentHash filled in by Hash
+func (info ContentInfo) withHash() (ContentInfo, error) {
+	sum, err := info.Hash()
+	if err != nil {
+		return info, err
+	}
+	info.ContentHash = sum
+	return info, nil
+}
@@ -0,0 +1,185 @@
+package routingclient_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// FuzzMetadata builds a SelectBackend request from metadata read from its
+// input as key=value lines. When the client accepts the metadata, the
+// request must carry every pair unchanged.
+func FuzzMetadata(f *testing.F) {
+	f.Add("bucket=datasets\nsize_bucket=large")
+	f.Add("k=v=w\n=empty key\nno pair")
+	f.Add("tier=hot\ntier=cold")
+	f.Add("名前=値")
+	f.Add("\xff=\xfe")
+	f.Fuzz(func(t *testing.T, data string) {
+		metadata := make(map[string]string)
+		for _, line := range strings.Split(data, "\n") {
+			if k, v, ok := strings.Cut(line, "="); ok {
+				metadata[k] = v
+			}
+		}
+		var sent *pb.SelectBackendRequest
+		conn := &replyConn{invoke: func(args, reply any) error {
+			sent = args.(*pb.SelectBackendRequest)
+			reply.(*pb.SelectBackendResponse).BackendId = "ipfs"
+			return nil
+		}}
+		client := routingclient.NewClient(conn)
+		info := routingclient.ContentInfo{ContentSize: int64(len(data)), ContentHash: "fuzz", Metadata: metadata}
+		if _, err := client.SelectBackend(context.Background(), info, "hybrid"); err != nil {
+			return
+		}
+		fields := sent.GetMetadata().GetFields()
+		if len(fields) != len(metadata) {
+			t.Fatalf("request carries %d metadata keys, want %d", len(fields), len(metadata))
+		}
+		for k, v := range metadata {
+			if got := fields[k].GetStringValue(); got != v {
+				t.Fatalf("metadata %q = %q, want %q", k, got, v)
+			}
+		}
+	})
+}
+
+// FuzzSelect decodes its input as the service's SelectBackend response,
+// with a decision cache and scorer set so their paths see it too
+func FuzzSelect(f *testing.F) {
+	scores, err := structpb.NewStruct(map[string]interface{}{"cost": 0.4, "latency": 0.9})
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(marshal(f, &pb.SelectBackendResponse{
+		BackendId:    "ipfs",
+		Score:        0.9,
+		FactorScores: scores,
+		Alternatives: []*pb.SelectBackendResponse_Alternative{{BackendId: "s3", Score: 0.7}, {BackendId: "", Score: -1}},
+		Reasoning:    "fuzz",
+	}))
+	f.Add(marshal(f, &pb.SelectBackendResponse{DryRun: true}))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		client := routingclient.NewClient(&replyConn{data: data})
+		client.SetCache(routingclient.NewDecisionCache(8, time.Minute))
+		client.SetScorer(routingclient.ScorerFunc(func(_ context.Context, _ routingclient.ContentInfo, c routingclient.Candidate) float64 {
+			return c.Score
+		}))
+		info := routingclient.ContentInfo{ContentSize: int64(len(data)), ContentHash: "fuzz"}
+		ctx := context.Background()
+		if _, err := client.SelectBackend(ctx, info, "hybrid"); err != nil {
+			return
+		}
+		// the second answer comes from the cache
+		client.SelectBackend(ctx, info, "hybrid")
+	})
+}
+
+// FuzzInsights decodes its input as the service's GetInsights and
+// EstimateCost responses, as the migrate and tiers planners consume them
+func FuzzInsights(f *testing.F) {
+	rates, err := structpb.NewStruct(map[string]interface{}{"ipfs": 0.99, "s3": "high", "filecoin": nil})
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(marshal(f, &pb.GetInsightsResponse{BackendSuccessRates: rates, FactorWeights: rates}))
+	f.Add(marshal(f, &pb.EstimateCostResponse{
+		Estimates: []*pb.CostEstimate{{BackendId: "s3", TotalCost: 0.4}, {BackendId: "ipfs", TotalCost: -1}},
+		Currency:  "USD",
+	}))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		client := routingclient.NewClient(&replyConn{data: data})
+		ctx := context.Background()
+		if resp, err := client.GetInsights(ctx, time.Hour); err == nil {
+			routingclient.SuccessRates(resp)
+		}
+		client.FactorWeights(ctx)
+		if resp, err := client.EstimateCost(ctx, routingclient.ContentInfo{}, routingclient.CostQuery{}); err == nil {
+			routingclient.Cheapest(resp)
+		}
+	})
+}
+
+// FuzzREST serves its input as the body of every answer of the HTTP
+// routing API, which RESTConn decodes as JSON
+func FuzzREST(f *testing.F) {
+	f.Add([]byte(`{"backend":"ipfs","confidence":0.9,"reasoning":"fuzz","timestamp":"2026-01-02T03:04:05Z"}`))
+	f.Add([]byte(`{"success":true,"message":"recorded"}`))
+	f.Add([]byte(`{"backends":[{"id":"ipfs","available":true},{"id":7}],"success_rates":{"ipfs":"x"}}`))
+	f.Add([]byte(`{"estimates":[{"backend_id":"s3","total":1e309}],"currency":null}`))
+	f.Add([]byte(`[]`))
+	f.Add([]byte(`{"timestamp":"yesterday"}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		hc := &http.Client{Transport: bodyTransport(data)}
+		client := routingclient.NewClient(routingclient.NewRESTConn("http://router.invalid", hc))
+		ctx := context.Background()
+		info := routingclient.ContentInfo{ContentSize: int64(len(data)), ContentHash: "fuzz"}
+		client.SelectBackend(ctx, info, "hybrid")
+		if resp, err := client.GetInsights(ctx, time.Hour); err == nil {
+			routingclient.SuccessRates(resp)
+		}
+		if resp, err := client.EstimateCost(ctx, info, routingclient.CostQuery{}); err == nil {
+			routingclient.Cheapest(resp)
+		}
+		client.ListBackends(ctx, info)
+		client.RecordOutcome(ctx, info, routingclient.Outcome{BackendID: "ipfs", Success: true})
+	})
+}
+
+// marshal returns m's wire encoding, as a seed
+func marshal(f *testing.F, m proto.Message) []byte {
+	data, err := proto.Marshal(m)
+	if err != nil {
+		f.Fatal(err)
+	}
+	return data
+}
+
+// replyConn is a grpc.ClientConnInterface answering every unary call by
+// decoding data into the reply, or with invoke if set
+type replyConn struct {
+	data   []byte
+	invoke func(args, reply any) error
+}
+
+func (c *replyConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	if c.invoke != nil {
+		return c.invoke(args, reply)
+	}
+	return proto.Unmarshal(c.data, reply.(proto.Message))
+}
+
+func (c *replyConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, errors.New("streams are not supported")
+}
+
+// bodyTransport answers every HTTP request with a JSON body
+type bodyTransport []byte
+
+func (b bodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(b)),
+		ContentLength: int64(len(b)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,353 @@
+package routingclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Conn is a connection the generated clients can call through: a
+// *grpc.ClientConn or a *WebConn
+type Conn interface {
+	grpc.ClientConnInterface
+	Close() error
+}
+
+// Dial connects to target. http:// and https:// URLs are served by a
+// gRPC-Web gateway and use a WebConn, which ignores opts; targets of a
+// registered Transport are dialed through it; anything else is passed to
+// grpc.NewClient with opts.
+func Dial(target string, opts ...grpc.DialOption) (Conn, error) {
+	if IsWeb(target) {
+		return NewWebConn(target, nil), nil
+	}
+	if t := transportFor(target); t != nil {
+		dialTarget, topts, err := t.Dial(target)
+		if err != nil {
+			return nil, err
+		}
+		return grpc.NewClient(dialTarget, append(opts, topts...)...)
+	}
+	return grpc.NewClient(target, opts...)
+}
+
+// IsWeb reports whether target is the URL of a gRPC-Web gateway
+func IsWeb(target string) bool {
+	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+}
+
+// gRPC-Web frame flags
+const (
+	webDataFrame    = 0x00
+	webTrailerFrame = 0x80
+)
+
+// WebConn calls the routing service through a gRPC-Web gateway such as
+// Envoy's grpc_web filter, for deployments that only expose HTTP. It
+// supports unary and server-streaming RPCs; gRPC-Web has no client
+// streaming. Messages are sent uncompressed.
+type WebConn struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewWebConn creates a WebConn for the gateway at baseURL, using hc
+// (http.DefaultClient if nil), which honours the proxy environment
+func NewWebConn(baseURL string, hc *http.Client) *WebConn {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &WebConn{baseURL: strings.TrimRight(baseURL, "/"), http: hc}
+}
+
+// Close releases idle connections
+func (c *WebConn) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+// Invoke implements grpc.ClientConnInterface
+func (c *WebConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	cs, err := c.NewStream(ctx, &grpc.StreamDesc{}, method, opts...)
+	if err != nil {
+		return err
+	}
+	if err := cs.SendMsg(args); err != nil {
+		return err
+	}
+	if err := cs.RecvMsg(reply); err != nil {
+		if err == io.EOF {
+			return status.Error(codes.Internal, "grpc-web: unary response has no message")
+		}
+		return err
+	}
+	// Read to the trailers for the final status
+	if err := cs.RecvMsg(nil); err != io.EOF {
+		if err == nil {
+			return status.Error(codes.Internal, "grpc-web: unary response has more than one message")
+		}
+		return err
+	}
+	return nil
+}
+
+// NewStream implements grpc.ClientConnInterface. The request is sent on
+// the first SendMsg.
+func (c *WebConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if desc.ClientStreams {
+		return nil, status.Errorf(codes.Unimplemented, "grpc-web: %s: client streaming is not supported", method)
+	}
+	s := &webStream{conn: c, ctx: ctx, method: method}
+	for _, o := range opts {
+		switch o := o.(type) {
+		case grpc.HeaderCallOption:
+			s.headerAddr = o.HeaderAddr
+		case grpc.TrailerCallOption:
+			s.trailerAddr = o.TrailerAddr
+		}
+	}
+	return s, nil
+}
+
+// webStream is a single gRPC-Web call
+type webStream struct {
+	conn        *WebConn
+	ctx         context.Context
+	method      string
+	headerAddr  *metadata.MD
+	trailerAddr *metadata.MD
+
+	sent    bool
+	body    io.ReadCloser
+	r       *bufio.Reader
+	header  metadata.MD
+	trailer metadata.MD
+	err     error // final status once the stream ends
+}
+
+func (s *webStream) Context() context.Context { return s.ctx }
+
+func (s *webStream) CloseSend() error { return nil }
+
+func (s *webStream) Header() (metadata.MD, error) {
+	if !s.sent {
+		return nil, errors.New("grpc-web: header requested before the request was sent")
+	}
+	return s.header, nil
+}
+
+func (s *webStream) Trailer() metadata.MD { return s.trailer }
+
+// SendMsg posts the request; gRPC-Web carries exactly one request message
+func (s *webStream) SendMsg(m any) error {
+	if s.sent {
+		return status.Error(codes.Internal, "grpc-web: only one request message may be sent")
+	}
+	s.sent = true
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return status.Errorf(codes.Internal, "grpc-web: %T is not a proto message", m)
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return status.Errorf(codes.Internal, "grpc-web: marshal request: %v", err)
+	}
+	frame := make([]byte, 5+len(data))
+	frame[0] = webDataFrame
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(data)))
+	copy(frame[5:], data)
+
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.conn.baseURL+s.method, bytes.NewReader(frame))
+	if err != nil {
+		return status.Errorf(codes.Internal, "grpc-web: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("Accept", "application/grpc-web+proto")
+	req.Header.Set("X-Grpc-Web", "1")
+	if md, ok := metadata.FromOutgoingContext(s.ctx); ok {
+		for k, vs := range md {
+			for _, v := range vs {
+				req.Header.Add(k, v)
+			}
+		}
+	}
+	if deadline, ok := s.ctx.Deadline(); ok {
+		req.Header.Set("Grpc-Timeout", encodeTimeout(time.Until(deadline)))
+	}
+
+	resp, err := s.conn.http.Do(req)
+	if err != nil {
+		if ctxErr := s.ctx.Err(); ctxErr != nil {
+			return status.FromContextError(ctxErr).Err()
+		}
+		return status.Errorf(codes.Unavailable, "grpc-web: %v", err)
+	}
+	s.header = headerMD(resp.Header)
+	if s.headerAddr != nil {
+		*s.headerAddr = s.header
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return status.Errorf(httpStatusCode(resp.StatusCode), "grpc-web: HTTP %s", resp.Status)
+	}
+	// A trailers-only response carries the status in the headers
+	if code := resp.Header.Get("Grpc-Status"); code != "" {
+		resp.Body.Close()
+		s.finish(s.header)
+		if s.err != nil {
+			return s.err
+		}
+		return nil
+	}
+	s.body, s.r = resp.Body, bufio.NewReader(resp.Body)
+	return nil
+}
+
+// RecvMsg reads the next response message into m, or returns io.EOF once
+// the call ended with OK. m may be nil to skip a message.
+func (s *webStream) RecvMsg(m any) error {
+	if !s.sent {
+		return status.Error(codes.Internal, "grpc-web: RecvMsg before SendMsg")
+	}
+	if s.r == nil {
+		if s.err != nil {
+			return s.err
+		}
+		return io.EOF
+	}
+	for {
+		var hdr [5]byte
+		if _, err := io.ReadFull(s.r, hdr[:]); err != nil {
+			s.close()
+			if err == io.EOF {
+				s.err = status.Error(codes.Internal, "grpc-web: response ended without trailers")
+			} else {
+				s.err = s.readErr(err)
+			}
+			return s.err
+		}
+		data := make([]byte, binary.BigEndian.Uint32(hdr[1:]))
+		if _, err := io.ReadFull(s.r, data); err != nil {
+			s.close()
+			s.err = s.readErr(err)
+			return s.err
+		}
+		switch {
+		case hdr[0]&webTrailerFrame != 0:
+			s.close()
+			tp := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(data), strings.NewReader("\r\n"))))
+			mh, err := tp.ReadMIMEHeader()
+			if err != nil {
+				s.err = status.Errorf(codes.Internal, "grpc-web: malformed trailers: %v", err)
+				return s.err
+			}
+			s.finish(headerMD(http.Header(mh)))
+			if s.err != nil {
+				return s.err
+			}
+			return io.EOF
+		case hdr[0] != webDataFrame:
+			s.close()
+			s.err = status.Errorf(codes.Internal, "grpc-web: unsupported frame flags %#x", hdr[0])
+			return s.err
+		case m == nil:
+			return nil
+		}
+		msg, ok := m.(proto.Message)
+		if !ok {
+			return status.Errorf(codes.Internal, "grpc-web: %T is not a proto message", m)
+		}
+		if err := proto.Unmarshal(data, msg); err != nil {
+			return status.Errorf(codes.Internal, "grpc-web: unmarshal response: %v", err)
+		}
+		return nil
+	}
+}
+
+// finish records the trailers and the status they carry
+func (s *webStream) finish(trailer metadata.MD) {
+	s.trailer = trailer
+	if s.trailerAddr != nil {
+		*s.trailerAddr = trailer
+	}
+	code := codes.Unknown
+	if v := trailer.Get("grpc-status"); len(v) > 0 {
+		if n, err := strconv.Atoi(v[0]); err == nil {
+			code = codes.Code(n)
+		}
+	}
+	if code == codes.OK {
+		return
+	}
+	var msg string
+	if v := trailer.Get("grpc-message"); len(v) > 0 {
+		msg, _ = url.PathUnescape(v[0])
+	}
+	s.err = status.Error(code, msg)
+}
+
+func (s *webStream) close() {
+	s.body.Close()
+	s.r = nil
+}
+
+func (s *webStream) readErr(err error) error {
+	if ctxErr := s.ctx.Err(); ctxErr != nil {
+		return status.FromContextError(ctxErr).Err()
+	}
+	return status.Errorf(codes.Unavailable, "grpc-web: read response: %v", err)
+}
+
+// headerMD converts HTTP headers to lowercase-keyed metadata
+func headerMD(h http.Header) metadata.MD {
+	md := make(metadata.MD, len(h))
+	for k, vs := range h {
+		md[strings.ToLower(k)] = vs
+	}
+	return md
+}
+
+// encodeTimeout formats d as a grpc-timeout header value
+func encodeTimeout(d time.Duration) string {
+	if d <= 0 {
+		return "1n"
+	}
+	if ms := d.Milliseconds(); ms < 1e8 {
+		return fmt.Sprintf("%dm", max(ms, 1))
+	}
+	return fmt.Sprintf("%dS", int64(d.Seconds()))
+}
+
+// httpStatusCode maps an HTTP error status to a gRPC code, as gRPC does
+// for responses that never reached a gRPC server
+func httpStatusCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.Internal
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.Unimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,62 @@
+package routingclient
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// HistoryQuery selects the decisions and outcomes History returns; zero
+// fields match everything
+type HistoryQuery struct {
+	Since     time.Time
+	Until     time.Time
+	Kind      pb.HistoryRecordKind
+	BackendID string
+	// PageSize is how many records each call fetches (default the
+	// server's, 1000)
+	PageSize int
+}
+
+// History calls fn with each decision and outcome the service has kept
+// that matches q, oldest first, fetching them a page at a time. It stops at
+// the first error, from the service or fn, and returns it.
+func (c *Client) History(ctx context.Context, q HistoryQuery, fn func(*pb.HistoryRecord) error) error {
+	req := &pb.GetHistoryRequest{
+		Kind:      q.Kind,
+		BackendId: q.BackendID,
+		PageSize:  int32(q.PageSize),
+	}
+	if !q.Since.IsZero() {
+		req.Since = timestamppb.New(q.Since)
+	}
+	if !q.Until.IsZero() {
+		req.Until = timestamppb.New(q.Until)
+	}
+	for {
+		resp, err := c.historyPage(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, r := range resp.Records {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+		if resp.NextPageToken == "" {
+			return nil
+		}
+		req.PageToken = resp.NextPageToken
+	}
+}
+
+// historyPage fetches one page of history
+func (c *Client) historyPage(ctx context.Context, req *pb.GetHistoryRequest) (*pb.GetHistoryResponse, error) {
+	ctx, cancel := withTimeout(ctx, c.timeouts.Select)
+	defer cancel()
+	resp, err := c.rpc.GetHistory(ctx, req)
+	return resp, toError(err)
+}
@@ -0,0 +1,107 @@
+package routingclient
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// HTTPAPIEnv names the environment variable holding the HTTP routing API
+// base URL, e.g. http://localhost:8081, New uses when no option names one
+const HTTPAPIEnv = "ROUTING_HTTP_API"
+
+// servicerMethods are the RPCs ipfs_kit_py's gRPC servicer implemented
+// before it was deprecated. The RPCs added since (RecordOutcomes,
+// StreamOutcomes, ListBackends, GetBackendStats, EstimateCost,
+// SetFactorWeights, ReportMetrics, WatchBackends and GetHistory) are served
+// only by the HTTP routing API, ipfs_kit_py/routing/http_server.py.
+var servicerMethods = map[string]bool{
+	methodSelectBackend: true,
+	methodRecordOutcome: true,
+	methodGetInsights:   true,
+	methodStreamMetrics: true,
+}
+
+// httpPreferred are servicer RPCs a SplitConn still sends over HTTP when
+// it can: the servicer records outcomes without checking their
+// idempotency keys, so only the HTTP API drops retried copies of them
+var httpPreferred = map[string]bool{
+	methodRecordOutcome: true,
+}
+
+// servicerIgnores reports whether req uses SelectBackend fields the gRPC
+// servicer ignored, selecting by the request's content alone: only the
+// HTTP API honours dry runs, client locations, strategies other than
+// hybrid, DHT provider counts and retention periods
+func servicerIgnores(req *pb.SelectBackendRequest) bool {
+	strategy := req.GetStrategy()
+	if req.GetStrategyType() != pb.RoutingStrategy_ROUTING_STRATEGY_UNSPECIFIED {
+		strategy = StrategyName(req.GetStrategyType())
+	}
+	return req.GetDryRun() || req.GetClientLocation() != nil || req.GetProviders() != nil ||
+		req.GetRetentionDays() > 0 || (strategy != "" && strategy != "hybrid")
+}
+
+// SplitConn sends the RPCs ipfs_kit_py's gRPC servicer implements over
+// GRPC and the rest to the HTTP routing API over HTTP, so one Client can
+// use the whole service against the Python server. RecordOutcome goes over
+// HTTP as well, for the HTTP API's deduplication of retried outcomes, and
+// so do selections using fields the servicer ignored. With a nil HTTP every
+// RPC goes over GRPC, and the later RPCs failing with Unimplemented say
+// where they are served instead.
+type SplitConn struct {
+	GRPC Conn
+	HTTP *RESTConn
+}
+
+// NewSplitConn creates a SplitConn sending the later RPCs to the HTTP
+// routing API at baseURL
+func NewSplitConn(grpcConn Conn, baseURL string) *SplitConn {
+	return &SplitConn{GRPC: grpcConn, HTTP: NewRESTConn(baseURL, nil)}
+}
+
+// Close closes both connections
+func (s *SplitConn) Close() error {
+	if s.HTTP == nil {
+		return s.GRPC.Close()
+	}
+	return errors.Join(s.GRPC.Close(), s.HTTP.Close())
+}
+
+// Invoke implements grpc.ClientConnInterface
+func (s *SplitConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	if s.HTTP != nil {
+		req, selection := args.(*pb.SelectBackendRequest)
+		if !servicerMethods[method] || httpPreferred[method] || (selection && servicerIgnores(req)) {
+			return s.HTTP.Invoke(ctx, method, args, reply, opts...)
+		}
+	}
+	return s.explain(method, s.GRPC.Invoke(ctx, method, args, reply, opts...))
+}
+
+// NewStream implements grpc.ClientConnInterface. A stream the server does
+// not implement may only fail on its first receive, with the status
+// explain cannot see.
+func (s *SplitConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if !servicerMethods[method] && s.HTTP != nil {
+		return s.HTTP.NewStream(ctx, desc, method, opts...)
+	}
+	cs, err := s.GRPC.NewStream(ctx, desc, method, opts...)
+	return cs, s.explain(method, err)
+}
+
+// explain adds where to find method to the Unimplemented error of a
+// server without it
+func (s *SplitConn) explain(method string, err error) error {
+	if status.Code(err) != codes.Unimplemented || servicerMethods[method] {
+		return err
+	}
+	return status.Errorf(codes.Unimplemented,
+		"%s: the Python routing service serves this RPC over its HTTP API only; set WithHTTPAPI or $%s (%v)",
+		method, HTTPAPIEnv, status.Convert(err).Message())
+}
@@ -0,0 +1,122 @@
+package routingclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "example.com/ipfs_kit_py/routing"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// closingConn is a replyConn usable as a Conn
+type closingConn struct{ replyConn }
+
+func (c *closingConn) Close() error { return nil }
+
+// TestSplitConnSendsOutcomesOverHTTP checks that a SplitConn records
+// outcomes with the HTTP API, which deduplicates them by idempotency key,
+// while selections stay on gRPC
+func TestSplitConnSendsOutcomesOverHTTP(t *testing.T) {
+	var grpcMethods []string
+	grpcConn := &closingConn{replyConn{invoke: func(args, reply any) error {
+		grpcMethods = append(grpcMethods, string(proto.MessageName(args.(proto.Message)).Name()))
+		if r, ok := reply.(*pb.SelectBackendResponse); ok {
+			r.BackendId = "ipfs"
+		}
+		return nil
+	}}}
+	seen := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/record-outcome" {
+			http.NotFound(w, r)
+			return
+		}
+		var in struct {
+			IdempotencyKey string `json:"idempotency_key"`
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		dup := seen[in.IdempotencyKey]
+		seen[in.IdempotencyKey] = true
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "duplicate": dup})
+	}))
+	defer srv.Close()
+
+	client := routingclient.NewClient(routingclient.NewSplitConn(grpcConn, srv.URL))
+	ctx := context.Background()
+	info := routingclient.ContentInfo{ContentHash: "h"}
+	if _, err := client.SelectBackend(ctx, info, "hybrid"); err != nil {
+		t.Fatal(err)
+	}
+	outcome := routingclient.Outcome{BackendID: "ipfs", Success: true, IdempotencyKey: "retry-1"}
+	for i := 0; i < 2; i++ {
+		if _, err := client.RecordOutcome(ctx, info, outcome); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(grpcMethods) != 1 || grpcMethods[0] != "SelectBackendRequest" {
+		t.Errorf("gRPC calls %v, want only SelectBackend", grpcMethods)
+	}
+	if len(seen) != 1 || !seen["retry-1"] {
+		t.Errorf("HTTP API saw idempotency keys %v, want retry-1", seen)
+	}
+}
+
+// TestSplitConnSelectsOverHTTP checks that selections using fields the
+// gRPC servicer ignored go to the HTTP API, which honours them
+func TestSplitConnSelectsOverHTTP(t *testing.T) {
+	var grpcCalls int
+	grpcConn := &closingConn{replyConn{invoke: func(args, reply any) error {
+		grpcCalls++
+		reply.(*pb.SelectBackendResponse).BackendId = "grpc"
+		return nil
+	}}}
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&in)
+		bodies = append(bodies, in)
+		json.NewEncoder(w).Encode(map[string]interface{}{"backend": "http"})
+	}))
+	defer srv.Close()
+	conn := routingclient.NewSplitConn(grpcConn, srv.URL)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		req   *pb.SelectBackendRequest
+		field string
+	}{
+		{"plain", &pb.SelectBackendRequest{Strategy: "hybrid"}, ""},
+		{"dry run", &pb.SelectBackendRequest{DryRun: true}, "dry_run"},
+		{"client location", &pb.SelectBackendRequest{ClientLocation: &pb.SelectBackendRequest_GeoLocation{Region: "eu-west-1"}}, "client_location"},
+		{"strategy", &pb.SelectBackendRequest{Strategy: "cost"}, "strategy"},
+		{"strategy type", &pb.SelectBackendRequest{StrategyType: pb.RoutingStrategy_ROUTING_STRATEGY_LATENCY}, "strategy"},
+		{"providers", &pb.SelectBackendRequest{Providers: &pb.SelectBackendRequest_Providers{Count: 0}}, "providers"},
+		{"retention", &pb.SelectBackendRequest{RetentionDays: 30}, "retention_days"},
+	}
+	for _, tt := range tests {
+		bodies, grpcCalls = nil, 0
+		resp := &pb.SelectBackendResponse{}
+		if err := conn.Invoke(ctx, "/ipfs_kit_py.routing.RoutingService/SelectBackend", tt.req, resp); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if tt.field == "" {
+			if resp.BackendId != "grpc" || grpcCalls != 1 {
+				t.Errorf("%s: selected %q over HTTP, want gRPC", tt.name, resp.BackendId)
+			}
+			continue
+		}
+		if resp.BackendId != "http" || grpcCalls != 0 || len(bodies) != 1 {
+			t.Errorf("%s: selected %q with %d gRPC calls, want the HTTP API", tt.name, resp.BackendId, grpcCalls)
+			continue
+		}
+		if v, ok := bodies[0][tt.field]; !ok || v == "" {
+			t.Errorf("%s: HTTP request %v lacks %s", tt.name, bodies[0], tt.field)
+		}
+	}
+}
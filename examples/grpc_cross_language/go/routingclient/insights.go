@@ -0,0 +1,29 @@
+package routingclient
+
+import (
+	"context"
+	"time"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// GetInsights returns the service's view of its backends over the last
+// window, rounded up to whole hours; zero uses the server's default of 24
+// hours
+func (c *Client) GetInsights(ctx context.Context, window time.Duration) (*pb.GetInsightsResponse, error) {
+	ctx, cancel := withTimeout(ctx, c.timeouts.Select)
+	defer cancel()
+	hours := (window + time.Hour - 1) / time.Hour
+	resp, err := c.rpc.GetInsights(ctx, &pb.GetInsightsRequest{TimeWindowHours: int32(hours)})
+	return resp, toError(err)
+}
+
+// SuccessRates returns each backend's success rate, from 0 to 1, as
+// reported in resp; backends without outcomes in the window are absent
+func SuccessRates(resp *pb.GetInsightsResponse) map[string]float64 {
+	rates := make(map[string]float64)
+	for id, v := range resp.GetBackendSuccessRates().GetFields() {
+		rates[id] = v.GetNumberValue()
+	}
+	return rates
+}
@@ -0,0 +1,51 @@
+package routingclient
+
+import (
+	"flag"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Keepalive configures client-side HTTP/2 keepalive pings, which keep
+// long-lived connections alive through NAT and firewalls that silently
+// drop idle flows, and detect dead connections without waiting for an RPC
+// to time out.
+//
+// The server must permit pings at least as often as Time, or it closes
+// the connection with "too_many_pings"; gRPC servers default to one ping
+// every 5 minutes.
+type Keepalive struct {
+	// Time is the idle period after which the client pings the server.
+	// Zero disables keepalive; gRPC raises values below 10s to 10s.
+	Time time.Duration
+	// Timeout is how long to wait for a ping ack before closing the
+	// connection (gRPC default 20s if zero)
+	Timeout time.Duration
+	// PermitWithoutStream sends pings even when no RPC is in flight,
+	// which is what idle agents need to keep NAT mappings open
+	PermitWithoutStream bool
+}
+
+// RegisterFlags adds -keepalive-time, -keepalive-timeout and
+// -keepalive-permit-without-stream to fs, defaulting to the current values
+// of k
+func (k *Keepalive) RegisterFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&k.Time, "keepalive-time", k.Time, "ping the server after this much inactivity (0 disables keepalive)")
+	fs.DurationVar(&k.Timeout, "keepalive-timeout", k.Timeout, "close the connection if a keepalive ping is not acknowledged within this time")
+	fs.BoolVar(&k.PermitWithoutStream, "keepalive-permit-without-stream", k.PermitWithoutStream, "send keepalive pings even with no active RPCs")
+}
+
+// DialOption returns the grpc dial option applying k. It is a no-op when
+// keepalive is disabled.
+func (k Keepalive) DialOption() grpc.DialOption {
+	if k.Time <= 0 {
+		return grpc.EmptyDialOption{}
+	}
+	return grpc.WithKeepaliveParams(keepalive.ClientParameters{
+		Time:                k.Time,
+		Timeout:             k.Timeout,
+		PermitWithoutStream: k.PermitWithoutStream,
+	})
+}
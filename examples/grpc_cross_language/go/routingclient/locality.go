@@ -0,0 +1,254 @@
+package routingclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// Environment variables that set the locality explicitly. They take
+// precedence over anything detected from cloud metadata.
+const (
+	RegionEnv  = "ROUTING_REGION"
+	ZoneEnv    = "ROUTING_ZONE"
+	NetworkEnv = "ROUTING_NETWORK"
+)
+
+// Locality describes where the client runs, so the service can prefer
+// backends close to it. Every field is optional.
+type Locality struct {
+	Provider  string // "aws", "gcp", "azure", or empty
+	Region    string
+	Zone      string
+	Network   string
+	Latitude  float64
+	Longitude float64
+}
+
+// IsZero reports whether l carries no hints
+func (l Locality) IsZero() bool {
+	return l == Locality{}
+}
+
+func (l Locality) proto() *pb.SelectBackendRequest_GeoLocation {
+	if l.IsZero() {
+		return nil
+	}
+	return &pb.SelectBackendRequest_GeoLocation{
+		Latitude:  l.Latitude,
+		Longitude: l.Longitude,
+		Region:    l.Region,
+		Zone:      l.Zone,
+		Network:   l.Network,
+		Provider:  l.Provider,
+	}
+}
+
+// merge fills l's empty fields from other
+func (l Locality) merge(other Locality) Locality {
+	if l.Provider == "" {
+		l.Provider = other.Provider
+	}
+	if l.Region == "" {
+		l.Region = other.Region
+	}
+	if l.Zone == "" {
+		l.Zone = other.Zone
+	}
+	if l.Network == "" {
+		l.Network = other.Network
+	}
+	if l.Latitude == 0 && l.Longitude == 0 {
+		l.Latitude, l.Longitude = other.Latitude, other.Longitude
+	}
+	return l
+}
+
+// LocalityFromEnv reads the locality from ROUTING_REGION, ROUTING_ZONE and
+// ROUTING_NETWORK, falling back to AWS_REGION or AWS_DEFAULT_REGION for
+// the region
+func LocalityFromEnv() Locality {
+	l := Locality{
+		Region:  os.Getenv(RegionEnv),
+		Zone:    os.Getenv(ZoneEnv),
+		Network: os.Getenv(NetworkEnv),
+	}
+	for _, env := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if l.Region == "" {
+			l.Region = os.Getenv(env)
+		}
+	}
+	return l
+}
+
+// DetectLocality returns the locality from the environment, completed
+// from the cloud metadata endpoint of AWS, GCP or Azure when the client
+// runs there. The endpoints are queried concurrently and the whole
+// detection gives up when ctx ends, so callers off-cloud should pass a
+// short deadline; a second is plenty on-cloud.
+func DetectLocality(ctx context.Context) Locality {
+	l := LocalityFromEnv()
+	if l.Region != "" && l.Zone != "" && l.Network != "" {
+		return l
+	}
+	probes := []func(context.Context, *http.Client) (Locality, error){probeAWS, probeGCP, probeAzure}
+	results := make(chan Locality, len(probes))
+	hc := &http.Client{Transport: &http.Transport{Proxy: nil}}
+	for _, probe := range probes {
+		go func(probe func(context.Context, *http.Client) (Locality, error)) {
+			found, err := probe(ctx, hc)
+			if err != nil {
+				found = Locality{}
+			}
+			results <- found
+		}(probe)
+	}
+	for range probes {
+		select {
+		case found := <-results:
+			if !found.IsZero() {
+				return l.merge(found)
+			}
+		case <-ctx.Done():
+			return l
+		}
+	}
+	return l
+}
+
+// metadataGet fetches a cloud metadata path, returning its trimmed body
+func metadataGet(ctx context.Context, hc *http.Client, method, url string, header map[string]string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata %s: %s", url, resp.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// probeAWS reads the placement from EC2 instance metadata (IMDSv2)
+func probeAWS(ctx context.Context, hc *http.Client) (Locality, error) {
+	const base = "http://169.254.169.254/latest"
+	token, err := metadataGet(ctx, hc, http.MethodPut, base+"/api/token",
+		map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+	if err != nil {
+		return Locality{}, err
+	}
+	hdr := map[string]string{"X-aws-ec2-metadata-token": token}
+	zone, err := metadataGet(ctx, hc, http.MethodGet, base+"/meta-data/placement/availability-zone", hdr)
+	if err != nil {
+		return Locality{}, err
+	}
+	l := Locality{Provider: "aws", Zone: zone}
+	l.Region, _ = metadataGet(ctx, hc, http.MethodGet, base+"/meta-data/placement/region", hdr)
+	if mac, err := metadataGet(ctx, hc, http.MethodGet, base+"/meta-data/mac", hdr); err == nil {
+		l.Network, _ = metadataGet(ctx, hc, http.MethodGet, base+"/meta-data/network/interfaces/macs/"+mac+"/vpc-id", hdr)
+	}
+	return l, nil
+}
+
+// probeGCP reads the zone and network from GCE instance metadata
+func probeGCP(ctx context.Context, hc *http.Client) (Locality, error) {
+	const base = "http://metadata.google.internal/computeMetadata/v1/instance"
+	hdr := map[string]string{"Metadata-Flavor": "Google"}
+	// projects/<number>/zones/us-central1-a
+	zone, err := metadataGet(ctx, hc, http.MethodGet, base+"/zone", hdr)
+	if err != nil {
+		return Locality{}, err
+	}
+	l := Locality{Provider: "gcp", Zone: path.Base(zone)}
+	if i := strings.LastIndex(l.Zone, "-"); i > 0 {
+		l.Region = l.Zone[:i]
+	}
+	// projects/<number>/networks/default
+	if network, err := metadataGet(ctx, hc, http.MethodGet, base+"/network-interfaces/0/network", hdr); err == nil {
+		l.Network = path.Base(network)
+	}
+	return l, nil
+}
+
+// probeAzure reads the location and zone from Azure instance metadata
+func probeAzure(ctx context.Context, hc *http.Client) (Locality, error) {
+	body, err := metadataGet(ctx, hc, http.MethodGet,
+		"http://169.254.169.254/metadata/instance/compute?api-version=2021-02-01",
+		map[string]string{"Metadata": "true"})
+	if err != nil {
+		return Locality{}, err
+	}
+	var compute struct {
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+	}
+	if err := json.Unmarshal([]byte(body), &compute); err != nil {
+		return Locality{}, err
+	}
+	l := Locality{Provider: "azure", Region: compute.Location}
+	if compute.Zone != "" {
+		l.Zone = compute.Location + "-" + compute.Zone
+	}
+	return l, nil
+}
+
+// localityHint is a locality being detected in the background
+type localityHint struct {
+	done  chan struct{}
+	value Locality
+}
+
+func detectedLocality(timeout time.Duration) *localityHint {
+	h := &localityHint{done: make(chan struct{})}
+	go func() {
+		defer close(h.done)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		h.value = DetectLocality(ctx)
+	}()
+	return h
+}
+
+func fixedLocality(l Locality) *localityHint {
+	h := &localityHint{done: make(chan struct{}), value: l}
+	close(h.done)
+	return h
+}
+
+// get waits for detection to finish, or for ctx to end
+func (h *localityHint) get(ctx context.Context) Locality {
+	if h == nil {
+		return Locality{}
+	}
+	select {
+	case <-h.done:
+		return h.value
+	case <-ctx.Done():
+		return Locality{}
+	}
+}
+
+// SetLocality sends l with every selection so the service can prefer
+// nearby backends. See DetectLocality to find it.
+func (c *Client) SetLocality(l Locality) {
+	c.locality = fixedLocality(l)
+}
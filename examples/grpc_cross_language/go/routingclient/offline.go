@@ -0,0 +1,510 @@
+package routingclient
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// Files an Offline keeps in its directory
+const (
+	offlineDecisions = "decisions.json"
+	offlineOutcomes  = "outcomes.wal"
+)
+
+// offlineSaveInterval is how often remembered decisions are written out;
+// Close writes those not yet saved
+const offlineSaveInterval = 10 * time.Second
+
+// offlineReplayBatch is how many queued outcomes a replay sends per
+// RecordOutcomes call, and offlineReplayTimeout bounds a replay started
+// by a call that reached the service
+const (
+	offlineReplayBatch   = 100
+	offlineReplayTimeout = time.Minute
+)
+
+// Offline keeps a client working while the routing service cannot be
+// reached. It remembers the last decision the service made for each
+// content hash and strategy, and the latest for each strategy and content
+// category; a selection that fails because the service is unreachable is
+// answered with the remembered decision for the content, or else the
+// latest for its kind. Outcomes that cannot be recorded are appended to a
+// write-ahead log on disk instead, and replayed in order once a call
+// reaches the service again; their idempotency keys keep a replay
+// interrupted part way from counting an outcome twice.
+//
+// The service counts as unreachable when a call fails with Unavailable,
+// or exceeds the client's own timeout while the caller's context is still
+// live. Dry runs are never answered offline. Decisions and outcomes
+// survive restarts in the directory, so an Offline may be opened by a
+// process that starts without the service.
+type Offline struct {
+	// MaxAge is how old a remembered decision may be and still be used
+	// (default 24h)
+	MaxAge time.Duration
+	// MaxDecisions bounds how many decisions are remembered, dropping the
+	// oldest (default 10,000)
+	MaxDecisions int
+	// OnOffline, if set, is called with the service's error each time a
+	// call is answered offline
+	OnOffline func(err error)
+	// OnReplay, if set, is called after each replay started by a call
+	// that reached the service, with how many outcomes it sent
+	OnReplay func(sent int, err error)
+
+	dir string
+
+	mu        sync.Mutex
+	decisions map[decisionKey]*knownDecision
+	latest    map[decisionKey]*knownDecision // keyed by category, strategy
+	dirty     bool
+	saved     time.Time
+	wal       *os.File
+	walSize   int64
+	pending   int
+
+	replayMu  sync.Mutex
+	replays   sync.WaitGroup
+	unbatched atomic.Bool
+}
+
+// knownDecision is a remembered decision, in decisions.json
+type knownDecision struct {
+	Hash     string `json:"hash"`
+	Strategy string `json:"strategy"`
+	Category string `json:"category"`
+	// Response is the SelectBackendResponse in wire form
+	Response []byte    `json:"response"`
+	At       time.Time `json:"at"`
+}
+
+// OfflineStats is a snapshot of an Offline's state
+type OfflineStats struct {
+	Decisions int `json:"decisions"`
+	// Pending is how many outcomes wait in the log to be replayed
+	Pending  int   `json:"pending"`
+	LogBytes int64 `json:"log_bytes"`
+}
+
+// OpenOffline opens, creating it if needed, the offline state kept in dir
+func OpenOffline(dir string) (*Offline, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("offline: %w", err)
+	}
+	o := &Offline{
+		MaxAge:       24 * time.Hour,
+		MaxDecisions: 10000,
+		dir:          dir,
+		decisions:    make(map[decisionKey]*knownDecision),
+		latest:       make(map[decisionKey]*knownDecision),
+		saved:        time.Now(),
+	}
+	if err := o.loadDecisions(); err != nil {
+		return nil, err
+	}
+	if err := o.openLog(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// SetOffline keeps the client working offline with o; nil disables it.
+// One Offline may be shared by several clients of the same service.
+func (c *Client) SetOffline(o *Offline) {
+	c.offline = o
+}
+
+// Stats returns the Offline's current state
+func (o *Offline) Stats() OfflineStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return OfflineStats{Decisions: len(o.decisions), Pending: o.pending, LogBytes: o.walSize}
+}
+
+// Close waits for a replay in progress, saves the remembered decisions
+// and closes the log. Outcomes still pending are replayed by the next
+// process to open the directory.
+func (o *Offline) Close() error {
+	o.replays.Wait()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var err error
+	if o.dirty {
+		err = o.saveDecisions()
+	}
+	return errors.Join(err, o.wal.Close())
+}
+
+// unreachable reports whether err means the routing service could not be
+// reached, rather than that it answered with an error
+func unreachable(ctx context.Context, err error) bool {
+	code := status.Code(err)
+	// batch errors wrap the *Error
+	if e := (*Error)(nil); errors.As(err, &e) {
+		code = e.Code
+	}
+	switch code {
+	case codes.Unavailable:
+		return true
+	case codes.DeadlineExceeded:
+		// the client's timeout, not the caller's deadline, ran out
+		return ctx.Err() == nil
+	}
+	return false
+}
+
+// offlineKeys returns the keys a decision is remembered under: by content
+// hash and by content category
+func offlineKeys(info ContentInfo, strategy string) (byHash, byKind decisionKey) {
+	return decisionKey{info.ContentHash, strategy}, decisionKey{CategoryOf(info.ContentType).String(), strategy}
+}
+
+// remember records resp as the service's latest decision for info
+func (o *Offline) remember(info ContentInfo, strategy string, resp *pb.SelectBackendResponse) {
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		return
+	}
+	byHash, byKind := offlineKeys(info, strategy)
+	d := &knownDecision{Hash: byHash.hash, Strategy: strategy, Category: byKind.hash, Response: data, At: time.Now().UTC()}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if byHash.hash != "" {
+		o.decisions[byHash] = d
+		if len(o.decisions) > o.MaxDecisions && o.MaxDecisions > 0 {
+			o.evictOldest()
+		}
+	}
+	o.latest[byKind] = d
+	o.dirty = true
+	if time.Since(o.saved) >= offlineSaveInterval {
+		o.saveDecisions()
+	}
+}
+
+// evictOldest drops the oldest remembered decision
+func (o *Offline) evictOldest() {
+	var oldest decisionKey
+	var at time.Time
+	for key, d := range o.decisions {
+		if at.IsZero() || d.At.Before(at) {
+			oldest, at = key, d.At
+		}
+	}
+	delete(o.decisions, oldest)
+}
+
+// decision returns the remembered decision to answer a selection for info
+// with, stamped with requestID
+func (o *Offline) decision(info ContentInfo, strategy, requestID string) (*pb.SelectBackendResponse, bool) {
+	byHash, byKind := offlineKeys(info, strategy)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, d := range []*knownDecision{o.decisions[byHash], o.latest[byKind]} {
+		if d == nil || (o.MaxAge > 0 && time.Since(d.At) > o.MaxAge) {
+			continue
+		}
+		resp := &pb.SelectBackendResponse{}
+		if proto.Unmarshal(d.Response, resp) != nil || !allowed(info.Backends, resp.BackendId) {
+			continue
+		}
+		resp.RequestId = requestID
+		resp.DryRun = false
+		how := "for this content"
+		if d.Hash != info.ContentHash || info.ContentHash == "" {
+			how = "for " + d.Category + " content"
+		}
+		resp.Reasoning = fmt.Sprintf("offline: last decision %s, made %s. %s", how, d.At.Format(time.RFC3339), resp.Reasoning)
+		return resp, true
+	}
+	return nil, false
+}
+
+// allowed reports whether backends, if set, contains id
+func allowed(backends []string, id string) bool {
+	if len(backends) == 0 {
+		return true
+	}
+	for _, b := range backends {
+		if b == id {
+			return true
+		}
+	}
+	return false
+}
+
+// loadDecisions reads the decisions saved in the directory
+func (o *Offline) loadDecisions() error {
+	data, err := os.ReadFile(filepath.Join(o.dir, offlineDecisions))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("offline: %w", err)
+	}
+	var saved []*knownDecision
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("offline: %s: %w", offlineDecisions, err)
+	}
+	for _, d := range saved {
+		if d.Hash != "" {
+			o.decisions[decisionKey{d.Hash, d.Strategy}] = d
+		}
+		kind := decisionKey{d.Category, d.Strategy}
+		if cur := o.latest[kind]; cur == nil || d.At.After(cur.At) {
+			o.latest[kind] = d
+		}
+	}
+	return nil
+}
+
+// saveDecisions replaces the saved decisions with the remembered ones.
+// The latest decision for each kind of content is kept even when its
+// hash has been evicted.
+func (o *Offline) saveDecisions() error {
+	saved := make([]*knownDecision, 0, len(o.decisions)+len(o.latest))
+	for _, d := range o.decisions {
+		saved = append(saved, d)
+	}
+	for _, d := range o.latest {
+		if d.Hash == "" || o.decisions[decisionKey{d.Hash, d.Strategy}] != d {
+			saved = append(saved, d)
+		}
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("offline: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(o.dir, offlineDecisions), data); err != nil {
+		return fmt.Errorf("offline: %w", err)
+	}
+	o.dirty = false
+	o.saved = time.Now()
+	return nil
+}
+
+// writeFileAtomic replaces path with data, so a crash leaves either the
+// old or the new file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// The outcome log is a sequence of records, each a 4-byte big-endian
+// length and CRC-32 of the RecordOutcomeRequest that follows in wire
+// form. A record cut short by a crash, or whose checksum does not match,
+// ends the log; openLog truncates it there.
+
+func (o *Offline) logPath() string {
+	return filepath.Join(o.dir, offlineOutcomes)
+}
+
+// openLog opens the outcome log for appending, counting the outcomes it
+// holds
+func (o *Offline) openLog() error {
+	reqs, ends, err := readLog(o.logPath(), -1)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	f, err := os.OpenFile(o.logPath(), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("offline: %w", err)
+	}
+	var size int64
+	if len(ends) > 0 {
+		size = ends[len(ends)-1]
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return fmt.Errorf("offline: %w", err)
+	}
+	o.wal, o.walSize, o.pending = f, size, len(reqs)
+	return nil
+}
+
+// readLog reads the outcomes in the log at path, up to limit bytes if it
+// is not negative, and the offset each record ends at
+func readLog(path string, limit int64) ([]*pb.RecordOutcomeRequest, []int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if limit >= 0 {
+		r = io.LimitReader(f, limit)
+	}
+	var (
+		reqs   []*pb.RecordOutcomeRequest
+		ends   []int64
+		offset int64
+		header [8]byte
+	)
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return reqs, ends, nil
+		}
+		data := make([]byte, binary.BigEndian.Uint32(header[:4]))
+		if _, err := io.ReadFull(r, data); err != nil || crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:]) {
+			return reqs, ends, nil
+		}
+		req := &pb.RecordOutcomeRequest{}
+		if proto.Unmarshal(data, req) != nil {
+			return reqs, ends, nil
+		}
+		offset += int64(len(header) + len(data))
+		reqs = append(reqs, req)
+		ends = append(ends, offset)
+	}
+}
+
+// queue appends outcomes to the log and syncs it
+func (o *Offline) queue(reqs ...*pb.RecordOutcomeRequest) error {
+	var buf []byte
+	for _, req := range reqs {
+		data, err := proto.Marshal(req)
+		if err != nil {
+			return fmt.Errorf("offline: %w", err)
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(data)))
+		buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(data))
+		buf = append(buf, data...)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, err := o.wal.Write(buf); err != nil {
+		// drop the partial record so later appends stay readable
+		o.wal.Truncate(o.walSize)
+		return fmt.Errorf("offline: %w", err)
+	}
+	if err := o.wal.Sync(); err != nil {
+		return fmt.Errorf("offline: %w", err)
+	}
+	o.walSize += int64(len(buf))
+	o.pending += len(reqs)
+	return nil
+}
+
+// Replay sends the outcomes queued in the log through c, oldest first, and
+// removes those sent from the log. It stops at the first batch the service
+// cannot be reached for, leaving it and later outcomes queued; outcomes
+// the service rejects are dropped and reported in the error.
+func (o *Offline) Replay(ctx context.Context, c *Client) (int, error) {
+	o.replayMu.Lock()
+	defer o.replayMu.Unlock()
+	return o.replay(ctx, c)
+}
+
+// online starts a replay of queued outcomes in the background, if there
+// are any and none is running, after a call through c reached the service
+func (o *Offline) online(c *Client) {
+	if o.Stats().Pending == 0 || !o.replayMu.TryLock() {
+		return
+	}
+	o.replays.Add(1)
+	go func() {
+		defer o.replays.Done()
+		defer o.replayMu.Unlock()
+		ctx, cancel := context.WithTimeout(context.Background(), offlineReplayTimeout)
+		defer cancel()
+		sent, err := o.replay(ctx, c)
+		if o.OnReplay != nil {
+			o.OnReplay(sent, err)
+		}
+	}()
+}
+
+// replay is Replay with replayMu held
+func (o *Offline) replay(ctx context.Context, c *Client) (int, error) {
+	o.mu.Lock()
+	size := o.walSize
+	o.mu.Unlock()
+	reqs, ends, err := readLog(o.logPath(), size)
+	if err != nil {
+		return 0, fmt.Errorf("offline: %w", err)
+	}
+
+	done, sent := 0, 0
+	var errs []error
+	for done < len(reqs) {
+		n := min(len(reqs)-done, offlineReplayBatch)
+		err := c.sendOutcomes(ctx, reqs[done:done+n], &o.unbatched)
+		if err != nil && unreachable(ctx, err) {
+			errs = append(errs, err)
+			break
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("dropped: %w", err))
+		} else {
+			sent += n
+		}
+		done += n
+	}
+	if done > 0 {
+		if err := o.trim(ends[done-1], done); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// trim removes the first n outcomes, which end at offset, from the log.
+// Outcomes queued since the replay read the log are kept.
+func (o *Offline) trim(offset int64, n int) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	src, err := os.Open(o.logPath())
+	if err != nil {
+		return fmt.Errorf("offline: %w", err)
+	}
+	defer src.Close()
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("offline: %w", err)
+	}
+	rest, err := io.ReadAll(io.LimitReader(src, o.walSize-offset))
+	if err != nil {
+		return fmt.Errorf("offline: %w", err)
+	}
+	if err := writeFileAtomic(o.logPath(), rest); err != nil {
+		return fmt.Errorf("offline: %w", err)
+	}
+	f, err := os.OpenFile(o.logPath(), os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("offline: %w", err)
+	}
+	o.wal.Close()
+	o.wal, o.walSize, o.pending = f, int64(len(rest)), o.pending-n
+	return nil
+}
@@ -0,0 +1,134 @@
+package routingclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// ErrOperationFinished is returned by Finish on an operation that was
+// already finished
+var ErrOperationFinished = errors.New("routing: operation already finished")
+
+// Operation times the work done against a selected backend and records
+// its outcome, so callers need not measure durations or sizes themselves:
+//
+//	op := client.StartOperation(ctx, info, decision)
+//	_, err := io.Copy(op.Writer(dst), src)
+//	op.Finish(err)
+//
+// Bytes moved through Reader and Writer, or added with AddBytes, are
+// reported with the outcome. An Operation is safe for concurrent use.
+type Operation struct {
+	client  *Client
+	ctx     context.Context
+	info    ContentInfo
+	backend string
+	key     string
+	start   time.Time
+	bytes   atomic.Int64
+
+	once sync.Once
+}
+
+// StartOperation starts timing an operation on the backend decision
+// selected. The outcome carries the decision's request ID as its
+// correlation ID, tying the two together in server logs.
+func (c *Client) StartOperation(ctx context.Context, info ContentInfo, decision *pb.SelectBackendResponse) *Operation {
+	if id := decision.GetRequestId(); id != "" && CorrelationID(ctx) == "" {
+		ctx = WithCorrelationID(ctx, id)
+	}
+	ctx, _ = ensureCorrelation(ctx)
+	return &Operation{
+		client:  c,
+		ctx:     ctx,
+		info:    info,
+		backend: decision.GetBackendId(),
+		key:     NewCorrelationID(),
+		start:   time.Now(),
+	}
+}
+
+// Backend returns the backend the operation runs against
+func (op *Operation) Backend() string {
+	return op.backend
+}
+
+// Elapsed returns the time since the operation started
+func (op *Operation) Elapsed() time.Duration {
+	return time.Since(op.start)
+}
+
+// AddBytes counts n bytes as moved
+func (op *Operation) AddBytes(n int64) {
+	op.bytes.Add(n)
+}
+
+// Reader returns r counting the bytes read through it
+func (op *Operation) Reader(r io.Reader) io.Reader {
+	return &countingReader{r: r, op: op}
+}
+
+// Writer returns w counting the bytes written through it
+func (op *Operation) Writer(w io.Writer) io.Writer {
+	return &countingWriter{w: w, op: op}
+}
+
+// Outcome returns the outcome of the operation as if it finished now with
+// err, without recording it, e.g. to hand to an OutcomeQueue. Its
+// idempotency key is fixed, so recording it twice counts once.
+func (op *Operation) Outcome(err error) Outcome {
+	return Outcome{
+		BackendID:      op.backend,
+		Success:        err == nil,
+		Duration:       op.Elapsed(),
+		Err:            err,
+		IdempotencyKey: op.key,
+		Bytes:          op.bytes.Load(),
+	}
+}
+
+// Finish ends the operation with err (nil for success) and records its
+// outcome. Later calls return ErrOperationFinished.
+func (op *Operation) Finish(err error) (*pb.RecordOutcomeResponse, error) {
+	return op.FinishContext(op.ctx, err)
+}
+
+// FinishContext is Finish recording the outcome with ctx rather than the
+// context the operation started with, so a deadline for the RPC can start
+// once the work is done. The outcome keeps the operation's correlation ID.
+func (op *Operation) FinishContext(ctx context.Context, err error) (*pb.RecordOutcomeResponse, error) {
+	ctx = WithCorrelationID(ctx, CorrelationID(op.ctx))
+	resp, rerr := (*pb.RecordOutcomeResponse)(nil), ErrOperationFinished
+	op.once.Do(func() {
+		resp, rerr = op.client.RecordOutcome(ctx, op.info, op.Outcome(err))
+	})
+	return resp, rerr
+}
+
+type countingReader struct {
+	r  io.Reader
+	op *Operation
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.op.AddBytes(int64(n))
+	return n, err
+}
+
+type countingWriter struct {
+	w  io.Writer
+	op *Operation
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.op.AddBytes(int64(n))
+	return n, err
+}
@@ -0,0 +1,433 @@
+package routingclient
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// Option configures a Client created with New
+type Option func(*options)
+
+type options struct {
+	tls          *tls.Config
+	retry        *RetryPolicy
+	metrics      Metrics
+	unary        []grpc.UnaryClientInterceptor
+	stream       []grpc.StreamClientInterceptor
+	keepalive    Keepalive
+	compression  Compression
+	proxy        Proxy
+	timeouts     Timeouts
+	restFallback string
+	httpAPI      string
+	logf         Logf
+	scorer       Scorer
+	cache        *DecisionCache
+	token        string
+	locality     *localityHint
+	dialOpts     []grpc.DialOption
+	recorder     *Recorder
+	replay       *ReplayConn
+	offline      *Offline
+}
+
+// WithTLS dials the server over TLS with cfg. Without it, connections are
+// plaintext, or use local credentials for Unix sockets.
+func WithTLS(cfg *tls.Config) Option {
+	return func(o *options) { o.tls = cfg }
+}
+
+// WithRetry retries failed RPCs according to p using gRPC's built-in
+// retry support. Retried RecordOutcome calls carry the same idempotency
+// key, so the server counts them once; the Python server only does so
+// over its HTTP API, which WithHTTPAPI sends outcomes to.
+func WithRetry(p RetryPolicy) Option {
+	return func(o *options) { o.retry = &p }
+}
+
+// WithMetrics reports every RPC to m
+func WithMetrics(m Metrics) Option {
+	return func(o *options) { o.metrics = m }
+}
+
+// WithInterceptor adds a unary interceptor. Interceptors run in the order
+// given, after the client's own correlation and metrics interceptors.
+func WithInterceptor(i grpc.UnaryClientInterceptor) Option {
+	return func(o *options) { o.unary = append(o.unary, i) }
+}
+
+// WithStreamInterceptor adds a streaming interceptor, like WithInterceptor
+func WithStreamInterceptor(i grpc.StreamClientInterceptor) Option {
+	return func(o *options) { o.stream = append(o.stream, i) }
+}
+
+// WithKeepalive sets the connection's keepalive parameters
+func WithKeepalive(k Keepalive) Option {
+	return func(o *options) { o.keepalive = k }
+}
+
+// WithCompression compresses RPCs as described by c
+func WithCompression(c Compression) Option {
+	return func(o *options) { o.compression = c }
+}
+
+// WithProxy dials through p instead of the proxy named by the environment
+func WithProxy(p Proxy) Option {
+	return func(o *options) { o.proxy = p }
+}
+
+// WithTimeouts sets per-call deadlines, as SetTimeouts does
+func WithTimeouts(t Timeouts) Option {
+	return func(o *options) { o.timeouts = t }
+}
+
+// WithRESTFallback uses the HTTP routing API at baseURL when the gRPC
+// server is unreachable; see FallbackConn
+func WithRESTFallback(baseURL string) Option {
+	return func(o *options) { o.restFallback = baseURL }
+}
+
+// WithHTTPAPI sends the RPCs ipfs_kit_py's gRPC servicer lacks to the
+// HTTP routing API at baseURL; see SplitConn. WithRESTFallback implies it
+// for the fallback's URL, and without either New reads $ROUTING_HTTP_API.
+func WithHTTPAPI(baseURL string) Option {
+	return func(o *options) { o.httpAPI = baseURL }
+}
+
+// WithLogger logs every RPC with its correlation ID, and REST fallbacks,
+// to logf
+func WithLogger(logf Logf) Option {
+	return func(o *options) { o.logf = logf }
+}
+
+// WithScorer re-ranks selections with s, as SetScorer does
+func WithScorer(s Scorer) Option {
+	return func(o *options) { o.scorer = s }
+}
+
+// WithCache caches selections in cache, as SetCache does
+func WithCache(cache *DecisionCache) Option {
+	return func(o *options) { o.cache = cache }
+}
+
+// WithToken sends token as a bearer token on every RPC, for the admin
+// RPCs. Use it with TLS or a Unix socket; over plaintext TCP the token is
+// visible on the network.
+func WithToken(token string) Option {
+	return func(o *options) { o.token = token }
+}
+
+// WithLocality sends l with every selection, instead of the locality
+// read from ROUTING_REGION, ROUTING_ZONE and ROUTING_NETWORK
+func WithLocality(l Locality) Option {
+	return func(o *options) { o.locality = fixedLocality(l) }
+}
+
+// WithDetectedLocality completes the environment's locality from the
+// cloud metadata endpoint, as DetectLocality does, giving up after
+// timeout (1s if zero). Detection runs in the background; the first
+// selection waits for it.
+func WithDetectedLocality(timeout time.Duration) Option {
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	return func(o *options) { o.locality = detectedLocality(timeout) }
+}
+
+// WithDialOptions passes extra options to grpc.NewClient. They are
+// applied last, so they override the ones New derives.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) { o.dialOpts = append(o.dialOpts, opts...) }
+}
+
+// WithRecorder records every RPC the client makes, and its response, with
+// rec
+func WithRecorder(rec *Recorder) Option {
+	return func(o *options) { o.recorder = rec }
+}
+
+// WithReplay serves the client's RPCs from a recording instead of
+// connecting to addr; see ReplayConn. The client's interceptors, scorer
+// and cache still apply.
+func WithReplay(conn *ReplayConn) Option {
+	return func(o *options) { o.replay = conn }
+}
+
+// WithOffline keeps the client working while the service is unreachable;
+// see Offline
+func WithOffline(off *Offline) Option {
+	return func(o *options) { o.offline = off }
+}
+
+// New connects to the routing service at addr and returns a Client that
+// owns the connection; call Close when done. addr is anything Dial
+// accepts. For a gRPC-Web gateway only the interceptor, metrics, logger,
+// timeout, scorer and cache options apply.
+func New(addr string, opts ...Option) (*Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := o.compression.Validate(); err != nil {
+		return nil, err
+	}
+
+	var conn Conn
+	if o.replay != nil {
+		conn = o.replay
+	} else {
+		var err error
+		if conn, err = o.dial(addr); err != nil {
+			return nil, err
+		}
+	}
+	primary := conn
+	if split, ok := conn.(*SplitConn); ok {
+		primary = split.GRPC
+	}
+	fallback, _ := primary.(*FallbackConn)
+	grpcConn, _ := primary.(*grpc.ClientConn)
+	if fallback != nil {
+		grpcConn, _ = fallback.Primary.(*grpc.ClientConn)
+	}
+	if o.recorder != nil {
+		conn = o.recorder.Conn(conn)
+	}
+
+	var unary []grpc.UnaryClientInterceptor
+	var stream []grpc.StreamClientInterceptor
+	if o.logf != nil {
+		unary = append(unary, CorrelationInterceptor(o.logf))
+		stream = append(stream, CorrelationStreamInterceptor(o.logf))
+	}
+	if o.metrics != nil {
+		unary = append(unary, metricsInterceptor(o.metrics))
+		stream = append(stream, metricsStreamInterceptor(o.metrics))
+	}
+	if o.token != "" {
+		unary = append(unary, tokenInterceptor(o.token))
+		stream = append(stream, tokenStreamInterceptor(o.token))
+	}
+	unary = append(unary, o.unary...)
+	stream = append(stream, o.stream...)
+	if len(unary) > 0 || len(stream) > 0 {
+		conn = intercept(conn, unary, stream)
+	}
+
+	c := NewClient(conn)
+	c.conn = conn
+	c.timeouts = o.timeouts
+	c.compression = o.compression
+	c.scorer = o.scorer
+	c.cache.Store(o.cache)
+	c.offline = o.offline
+	c.fallback = fallback
+	c.grpcConn = grpcConn
+	c.locality = o.locality
+	if c.locality == nil {
+		if l := LocalityFromEnv(); !l.IsZero() {
+			c.locality = fixedLocality(l)
+		}
+	}
+	return c, nil
+}
+
+// dial connects to addr as configured, falling back to the REST API if
+// one is set, and sends the RPCs only the HTTP API serves there
+func (o *options) dial(addr string) (Conn, error) {
+	creds := TransportCredentials(addr)
+	if o.tls != nil {
+		creds = credentials.NewTLS(o.tls)
+	}
+	proxyOpt, err := o.proxy.DialOption()
+	if err != nil {
+		return nil, err
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		o.keepalive.DialOption(),
+		o.compression.DialOption(),
+		proxyOpt,
+	}
+	if o.retry != nil {
+		dialOpts = append(dialOpts, o.retry.DialOption())
+	}
+	conn, err := Dial(addr, append(dialOpts, o.dialOpts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("routing: dial %s: %w", addr, err)
+	}
+
+	if o.restFallback != "" {
+		fc := NewFallbackConn(conn, NewRESTConn(o.restFallback, nil))
+		if o.logf != nil {
+			fc.OnFallback = func(err error) {
+				o.logf("routing: gRPC server unreachable (%v); using REST API at %s", err, o.restFallback)
+			}
+		}
+		conn = fc
+	}
+	httpAPI := o.httpAPI
+	if httpAPI == "" {
+		httpAPI = o.restFallback
+	}
+	if httpAPI == "" {
+		httpAPI = os.Getenv(HTTPAPIEnv)
+	}
+	if httpAPI == "" {
+		return &SplitConn{GRPC: conn}, nil
+	}
+	return NewSplitConn(conn, httpAPI), nil
+}
+
+// Close closes the connection of a Client created with New. It is a no-op
+// for clients created with NewClient, whose connection the caller owns.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// RetryPolicy describes how failed RPCs are retried. Only failures with
+// one of Codes are retried, with exponential backoff and jitter.
+type RetryPolicy struct {
+	// MaxAttempts includes the original call; gRPC caps it at 5
+	MaxAttempts       int
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	BackoffMultiplier float64
+	Codes             []codes.Code
+}
+
+// DefaultRetryPolicy retries Unavailable failures up to three times in all
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:       3,
+	InitialBackoff:    100 * time.Millisecond,
+	MaxBackoff:        2 * time.Second,
+	BackoffMultiplier: 2,
+	Codes:             []codes.Code{codes.Unavailable},
+}
+
+// DialOption returns the service config enabling p for the routing
+// service's methods
+func (p RetryPolicy) DialOption() grpc.DialOption {
+	retryable := make([]string, len(p.Codes))
+	for i, c := range p.Codes {
+		retryable[i] = codeName(c)
+	}
+	cfg := map[string]any{
+		"methodConfig": []any{map[string]any{
+			"name": []any{map[string]any{"service": "ipfs_kit_py.routing.RoutingService"}},
+			"retryPolicy": map[string]any{
+				"maxAttempts":          p.MaxAttempts,
+				"initialBackoff":       durationJSON(p.InitialBackoff),
+				"maxBackoff":           durationJSON(p.MaxBackoff),
+				"backoffMultiplier":    p.BackoffMultiplier,
+				"retryableStatusCodes": retryable,
+			},
+		}},
+	}
+	b, _ := json.Marshal(cfg) // maps of strings and numbers always marshal
+	return grpc.WithDefaultServiceConfig(string(b))
+}
+
+// codeName returns c in the service config's UPPER_SNAKE form
+func codeName(c codes.Code) string {
+	var b strings.Builder
+	prev := ' '
+	for _, r := range c.String() {
+		if r >= 'A' && r <= 'Z' && prev >= 'a' && prev <= 'z' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return strings.ToUpper(b.String())
+}
+
+// durationJSON formats d as a protobuf JSON duration
+func durationJSON(d time.Duration) string {
+	return fmt.Sprintf("%.9gs", d.Seconds())
+}
+
+// Metrics receives one observation per RPC, for export to Prometheus,
+// OpenTelemetry or similar
+type Metrics interface {
+	// ObserveRPC is called when a unary call completes, or when a stream
+	// opens, with its full method name, status code and latency
+	ObserveRPC(method string, code codes.Code, elapsed time.Duration)
+}
+
+func metricsInterceptor(m Metrics) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		m.ObserveRPC(method, status.Code(err), time.Since(start))
+		return err
+	}
+}
+
+func metricsStreamInterceptor(m Metrics) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		m.ObserveRPC(method, status.Code(err), time.Since(start))
+		return cs, err
+	}
+}
+
+// intercept wraps conn so calls pass through the interceptors, first
+// outermost. Unlike dial options this works for any Conn, including
+// WebConn, RESTConn and FallbackConn. The interceptors receive a nil
+// *grpc.ClientConn.
+func intercept(conn Conn, unary []grpc.UnaryClientInterceptor, stream []grpc.StreamClientInterceptor) Conn {
+	return &interceptedConn{Conn: conn, unary: unary, stream: stream}
+}
+
+type interceptedConn struct {
+	Conn
+	unary  []grpc.UnaryClientInterceptor
+	stream []grpc.StreamClientInterceptor
+}
+
+func (c *interceptedConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return c.invoke(0)(ctx, method, args, reply, nil, opts...)
+}
+
+// invoke returns the invoker running the interceptors from i on
+func (c *interceptedConn) invoke(i int) grpc.UnaryInvoker {
+	if i == len(c.unary) {
+		return func(ctx context.Context, method string, args, reply any, _ *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return c.Conn.Invoke(ctx, method, args, reply, opts...)
+		}
+	}
+	return func(ctx context.Context, method string, args, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return c.unary[i](ctx, method, args, reply, cc, c.invoke(i+1), opts...)
+	}
+}
+
+func (c *interceptedConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return c.streamer(0)(ctx, desc, nil, method, opts...)
+}
+
+// streamer returns the streamer running the interceptors from i on
+func (c *interceptedConn) streamer(i int) grpc.Streamer {
+	if i == len(c.stream) {
+		return func(ctx context.Context, desc *grpc.StreamDesc, _ *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return c.Conn.NewStream(ctx, desc, method, opts...)
+		}
+	}
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return c.stream[i](ctx, desc, cc, method, c.streamer(i+1), opts...)
+	}
+}
@@ -0,0 +1,330 @@
+package routingclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// ErrQueueFull is returned by OutcomeQueue.Record when the queue is full
+var ErrQueueFull = errors.New("routing: outcome queue full")
+
+// ErrQueueClosed is returned by OutcomeQueue.Record after Close
+var ErrQueueClosed = errors.New("routing: outcome queue closed")
+
+// BatchConfig configures an OutcomeQueue
+type BatchConfig struct {
+	// MaxBatch flushes once this many outcomes are queued (default 100)
+	MaxBatch int
+	// FlushInterval flushes queued outcomes at least this often
+	// (default 1s)
+	FlushInterval time.Duration
+	// QueueSize is how many outcomes may wait before Record fails with
+	// ErrQueueFull (default 10 * MaxBatch)
+	QueueSize int
+	// Timeout bounds each flush RPC (default 10s)
+	Timeout time.Duration
+	// Concurrency is how many batches may be in flight at once (default
+	// 1), so a bulk operation's outcomes keep up with a slow service
+	Concurrency int
+	// OnError, if set, is called when a batch of n outcomes could not be
+	// recorded; with a Concurrency above 1 it may be called concurrently
+	OnError func(err error, n int)
+}
+
+// OutcomeQueue records outcomes in the background, sending them in
+// batches with the RecordOutcomes RPC. Servers without that RPC are sent
+// the batch one RecordOutcome call at a time.
+type OutcomeQueue struct {
+	client *Client
+	cfg    BatchConfig
+
+	mu     sync.RWMutex
+	closed bool
+	in     chan *pb.RecordOutcomeRequest
+	flush  chan chan error
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// unbatched is set once the server reports RecordOutcomes unimplemented
+	unbatched atomic.Bool
+
+	inFlight atomic.Int64
+	sent     atomic.Uint64
+	failed   atomic.Uint64
+	deferred atomic.Uint64
+}
+
+// QueueStats is a snapshot of an OutcomeQueue's load
+type QueueStats struct {
+	// Queued is how many outcomes wait to be batched, of at most Capacity
+	Queued   int `json:"queued"`
+	Capacity int `json:"capacity"`
+	// InFlight is how many batches are being sent, of at most Concurrency
+	InFlight    int `json:"in_flight"`
+	Concurrency int `json:"concurrency"`
+	// Sent and Failed count the outcomes in batches that were and were
+	// not recorded
+	Sent   uint64 `json:"sent"`
+	Failed uint64 `json:"failed"`
+	// Deferred counts the outcomes written to the client's Offline log
+	// because the service was unreachable
+	Deferred uint64 `json:"deferred"`
+}
+
+// NewOutcomeQueue starts a background queue that records outcomes through
+// c. Close must be called to flush the remaining outcomes.
+func (c *Client) NewOutcomeQueue(cfg BatchConfig) *OutcomeQueue {
+	if cfg.MaxBatch <= 0 {
+		cfg.MaxBatch = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10 * cfg.MaxBatch
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &OutcomeQueue{
+		client: c,
+		cfg:    cfg,
+		in:     make(chan *pb.RecordOutcomeRequest, cfg.QueueSize),
+		flush:  make(chan chan error),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// Record queues an outcome without waiting for it to be sent. It fails
+// with ErrQueueFull rather than block when the service falls behind.
+func (q *OutcomeQueue) Record(info ContentInfo, outcome Outcome) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.in <- outcomeRequest(info, outcome):
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Flush sends the outcomes queued so far and waits for the result
+func (q *OutcomeQueue) Flush(ctx context.Context) error {
+	reply := make(chan error, 1)
+	select {
+	case q.flush <- reply:
+	case <-q.done:
+		return ErrQueueClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting outcomes and sends those still queued. If ctx
+// ends first, the pending sends are abandoned and ctx's error returned.
+func (q *OutcomeQueue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.in)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-q.done
+		return ctx.Err()
+	}
+}
+
+// Stats returns the queue's current load
+func (q *OutcomeQueue) Stats() QueueStats {
+	return QueueStats{
+		Queued:      len(q.in),
+		Capacity:    cap(q.in),
+		InFlight:    int(q.inFlight.Load()),
+		Concurrency: q.cfg.Concurrency,
+		Sent:        q.sent.Load(),
+		Failed:      q.failed.Load(),
+		Deferred:    q.deferred.Load(),
+	}
+}
+
+func (q *OutcomeQueue) run() {
+	defer close(q.done)
+	defer q.cancel()
+
+	ticker := time.NewTicker(q.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	// Batches are sent in the background, Concurrency at a time; the
+	// loop blocks while all are in flight
+	slots := make(chan struct{}, q.cfg.Concurrency)
+	var inFlight sync.WaitGroup
+	defer inFlight.Wait()
+	start := func(batch []*pb.RecordOutcomeRequest, errc chan<- error) {
+		slots <- struct{}{}
+		inFlight.Add(1)
+		go func() {
+			defer inFlight.Done()
+			err := q.send(batch)
+			<-slots
+			if errc != nil {
+				errc <- err
+			}
+		}()
+	}
+
+	var batch []*pb.RecordOutcomeRequest
+	send := func() {
+		if len(batch) > 0 {
+			start(batch, nil)
+			batch = nil
+		}
+	}
+
+	for {
+		select {
+		case req, ok := <-q.in:
+			if !ok {
+				send()
+				return
+			}
+			batch = append(batch, req)
+			if len(batch) >= q.cfg.MaxBatch {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case reply := <-q.flush:
+			batch = q.drain(batch)
+			errc := make(chan error, (len(batch)+q.cfg.MaxBatch-1)/q.cfg.MaxBatch)
+			for len(batch) > 0 {
+				n := min(len(batch), q.cfg.MaxBatch)
+				start(batch[:n], errc)
+				batch = batch[n:]
+			}
+			batch = nil
+			// Batches sent earlier are waited for too
+			inFlight.Wait()
+			close(errc)
+			var errs []error
+			for err := range errc {
+				errs = append(errs, err)
+			}
+			reply <- errors.Join(errs...)
+		}
+	}
+}
+
+// drain appends the outcomes already waiting in the queue to batch
+func (q *OutcomeQueue) drain(batch []*pb.RecordOutcomeRequest) []*pb.RecordOutcomeRequest {
+	for {
+		select {
+		case req, ok := <-q.in:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, req)
+		default:
+			return batch
+		}
+	}
+}
+
+// send records one batch, reporting failures to OnError
+func (q *OutcomeQueue) send(batch []*pb.RecordOutcomeRequest) error {
+	ctx, cancel := context.WithTimeout(q.ctx, q.cfg.Timeout)
+	defer cancel()
+
+	q.inFlight.Add(1)
+	err := q.sendBatch(ctx, batch)
+	q.inFlight.Add(-1)
+	// With the service unreachable, an Offline client's outcomes wait in
+	// its log to be replayed
+	if o := q.client.offline; err != nil && o != nil && unreachable(q.ctx, err) && o.queue(batch...) == nil {
+		q.deferred.Add(uint64(len(batch)))
+		if o.OnOffline != nil {
+			o.OnOffline(err)
+		}
+		return nil
+	}
+	if err != nil {
+		q.failed.Add(uint64(len(batch)))
+		if q.cfg.OnError != nil {
+			q.cfg.OnError(err, len(batch))
+		}
+		return err
+	}
+	q.sent.Add(uint64(len(batch)))
+	return nil
+}
+
+func (q *OutcomeQueue) sendBatch(ctx context.Context, batch []*pb.RecordOutcomeRequest) error {
+	return q.client.sendOutcomes(ctx, batch, &q.unbatched)
+}
+
+// sendOutcomes records a batch with the RecordOutcomes RPC, or one
+// RecordOutcome call at a time once unbatched is set, which it sets when
+// the server reports RecordOutcomes unimplemented
+func (c *Client) sendOutcomes(ctx context.Context, batch []*pb.RecordOutcomeRequest, unbatched *atomic.Bool) error {
+	if !unbatched.Load() {
+		resp, err := c.rpc.RecordOutcomes(ctx, &pb.RecordOutcomesRequest{
+			Outcomes: batch,
+			BatchId:  newRequestID(),
+		}, c.compression.CallOption(false))
+		if status.Code(err) != codes.Unimplemented {
+			if err != nil {
+				return fmt.Errorf("record outcomes: %w", toError(err))
+			}
+			if len(resp.Errors) > 0 {
+				return fmt.Errorf("record outcomes: %d of %d rejected: %s", len(resp.Errors), len(batch), strings.Join(resp.Errors, "; "))
+			}
+			return nil
+		}
+		unbatched.Store(true)
+	}
+
+	var errs []error
+	for _, req := range batch {
+		if _, err := c.rpc.RecordOutcome(ctx, req, c.compression.CallOption(false)); err != nil {
+			errs = append(errs, toError(err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("record outcomes: %d of %d failed: %w", len(errs), len(batch), errors.Join(errs...))
+	}
+	return nil
+}
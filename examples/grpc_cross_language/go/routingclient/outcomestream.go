@@ -0,0 +1,128 @@
+package routingclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// OutcomeStream records outcomes over one long-lived StreamOutcomes
+// stream, for agents that produce them continuously. The server
+// acknowledges periodically; Pending reports how many outcomes it has not
+// acknowledged yet.
+//
+// A stream that breaks is not reopened. Outcomes carry idempotency keys, so
+// callers may resend the unacknowledged ones on a new stream or through
+// an OutcomeQueue without double-counting.
+type OutcomeStream struct {
+	stream grpc.BidiStreamingClient[pb.RecordOutcomeRequest, pb.OutcomeAck]
+	onAck  func(*pb.OutcomeAck)
+
+	sendMu sync.Mutex
+
+	mu   sync.Mutex
+	sent int64
+	ack  *pb.OutcomeAck
+	err  error
+	done chan struct{}
+}
+
+// OpenOutcomeStream opens a StreamOutcomes stream. onAck, if non-nil, is
+// called from a background goroutine with each acknowledgement. The
+// stream lives until Close or until ctx ends. Over the HTTP routing API
+// (see SplitConn) each outcome is recorded as it is sent. gRPC servers
+// without the RPC fail the first Send or Close with Unimplemented; use an
+// OutcomeQueue there.
+func (c *Client) OpenOutcomeStream(ctx context.Context, onAck func(*pb.OutcomeAck)) (*OutcomeStream, error) {
+	stream, err := c.rpc.StreamOutcomes(ctx, c.compression.CallOption(false))
+	if err != nil {
+		return nil, toError(err)
+	}
+	s := &OutcomeStream{stream: stream, onAck: onAck, done: make(chan struct{})}
+	go s.recv()
+	return s, nil
+}
+
+// Send sends one outcome. It returns once the outcome is handed to the
+// transport, not when the server acknowledges it. Send is safe for
+// concurrent use.
+func (s *OutcomeStream) Send(info ContentInfo, outcome Outcome) error {
+	req := outcomeRequest(info, outcome)
+	s.sendMu.Lock()
+	err := s.stream.Send(req)
+	s.sendMu.Unlock()
+	if errors.Is(err, io.EOF) {
+		// The stream ended; the reason comes from the receive side
+		<-s.done
+		return s.Err()
+	}
+	if err != nil {
+		return toError(err)
+	}
+	s.mu.Lock()
+	s.sent++
+	s.mu.Unlock()
+	return nil
+}
+
+// Acked returns the latest acknowledgement, or nil before the first
+func (s *OutcomeStream) Acked() *pb.OutcomeAck {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ack
+}
+
+// Pending returns how many sent outcomes the server has not acknowledged
+func (s *OutcomeStream) Pending() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sent - s.ack.GetReceived()
+}
+
+// Err returns the error that ended the stream, or nil while it is open
+// or if it ended cleanly
+func (s *OutcomeStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close ends the stream and waits for the server's final acknowledgement,
+// which it returns along with any error that ended the stream
+func (s *OutcomeStream) Close() (*pb.OutcomeAck, error) {
+	s.sendMu.Lock()
+	err := s.stream.CloseSend()
+	s.sendMu.Unlock()
+	if err != nil {
+		return s.Acked(), toError(err)
+	}
+	<-s.done
+	return s.Acked(), s.Err()
+}
+
+// recv collects acknowledgements until the server ends the stream
+func (s *OutcomeStream) recv() {
+	defer close(s.done)
+	for {
+		ack, err := s.stream.Recv()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.mu.Lock()
+				s.err = toError(err)
+				s.mu.Unlock()
+			}
+			return
+		}
+		s.mu.Lock()
+		s.ack = ack
+		s.mu.Unlock()
+		if s.onAck != nil {
+			s.onAck(ack)
+		}
+	}
+}
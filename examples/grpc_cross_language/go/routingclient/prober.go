@@ -0,0 +1,187 @@
+package routingclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// ProbeTarget is an endpoint measured on behalf of a backend, typically a
+// gateway URL serving a known object
+type ProbeTarget struct {
+	BackendID string
+	URL       string
+}
+
+// Sampler produces a sample for a round from something other than an
+// HTTP probe, e.g. the statistics of a node the client runs. A nil sample
+// is skipped.
+type Sampler func(ctx context.Context) *pb.MetricSample
+
+// Prober periodically measures latency and throughput from this client to
+// each backend endpoint and reports the samples with ReportMetrics, so the
+// performance and latency strategies reflect what clients actually see.
+// It is optional; without it the service relies on recorded outcomes.
+type Prober struct {
+	client  *Client
+	targets []ProbeTarget
+	// Samplers run alongside the probes each round
+	Samplers []Sampler
+
+	// Interval between rounds (default 1m)
+	Interval time.Duration
+	// Timeout bounds each probe (default 10s)
+	Timeout time.Duration
+	// MaxBytes caps the body read to measure throughput (default 1 MiB)
+	MaxBytes int64
+	// ReporterID identifies this client in reports
+	ReporterID string
+	// HTTP is the client probes are made with (http.DefaultClient if nil)
+	HTTP *http.Client
+	// OnSample, if set, is called with every sample before it is reported
+	OnSample func(*pb.MetricSample)
+}
+
+// NewProber creates a prober for targets. Run starts it.
+func (c *Client) NewProber(targets ...ProbeTarget) *Prober {
+	return &Prober{
+		client:   c,
+		targets:  targets,
+		Interval: time.Minute,
+		Timeout:  10 * time.Second,
+		MaxBytes: 1 << 20,
+	}
+}
+
+// Run probes every Interval until ctx ends. Failed reports are dropped and
+// retried with the next round's samples. It returns ctx's error, or the
+// error of a server without ReportMetrics.
+func (p *Prober) Run(ctx context.Context) error {
+	t := time.NewTicker(p.Interval)
+	defer t.Stop()
+	for {
+		_, err := p.Report(ctx, p.ProbeAll(ctx))
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if status.Code(err) == codes.Unimplemented {
+			return err
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ProbeAll probes every target and runs every sampler once, concurrently
+func (p *Prober) ProbeAll(ctx context.Context) []*pb.MetricSample {
+	n := len(p.targets) + len(p.Samplers)
+	samples := make([]*pb.MetricSample, n)
+	done := make(chan struct{})
+	for i, target := range p.targets {
+		go func(i int, target ProbeTarget) {
+			samples[i] = p.Probe(ctx, target)
+			done <- struct{}{}
+		}(i, target)
+	}
+	for i, sampler := range p.Samplers {
+		go func(i int, sampler Sampler) {
+			if samples[i] = sampler(ctx); samples[i] != nil && p.OnSample != nil {
+				p.OnSample(samples[i])
+			}
+			done <- struct{}{}
+		}(len(p.targets)+i, sampler)
+	}
+	for i := 0; i < n; i++ {
+		<-done
+	}
+	out := samples[:0]
+	for _, s := range samples {
+		if s != nil {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Probe measures one target: latency is the time to the response headers,
+// throughput the rate at which up to MaxBytes of the body arrive. Statuses
+// of 400 and above count as failures.
+func (p *Prober) Probe(ctx context.Context, target ProbeTarget) *pb.MetricSample {
+	sample := &pb.MetricSample{
+		BackendId: target.BackendID,
+		Endpoint:  target.URL,
+		Timestamp: timestamppb.Now(),
+	}
+	defer func() {
+		if p.OnSample != nil {
+			p.OnSample(sample)
+		}
+	}()
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, nil)
+	if err != nil {
+		sample.Error = err.Error()
+		return sample
+	}
+	hc := p.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+
+	start := time.Now()
+	resp, err := hc.Do(req)
+	if err != nil {
+		sample.Error = err.Error()
+		return sample
+	}
+	defer resp.Body.Close()
+	sample.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	bodyStart := time.Now()
+	n, err := io.Copy(io.Discard, io.LimitReader(resp.Body, p.MaxBytes))
+	elapsed := time.Since(bodyStart)
+	sample.Bytes = n
+	if n > 0 && elapsed > 0 {
+		sample.ThroughputBytesPerSecond = float64(n) / elapsed.Seconds()
+	}
+	switch {
+	case err != nil:
+		sample.Error = err.Error()
+	case resp.StatusCode >= http.StatusBadRequest:
+		sample.Error = fmt.Sprintf("HTTP %s", resp.Status)
+	default:
+		sample.Success = true
+	}
+	return sample
+}
+
+// Report sends samples to the service. Empty reports are not sent.
+func (p *Prober) Report(ctx context.Context, samples []*pb.MetricSample) (*pb.ReportMetricsResponse, error) {
+	if len(samples) == 0 {
+		return &pb.ReportMetricsResponse{}, nil
+	}
+	c := p.client
+	ctx, cancel := withTimeout(ctx, c.timeouts.Outcome)
+	defer cancel()
+	resp, err := c.rpc.ReportMetrics(ctx, &pb.ReportMetricsRequest{
+		Samples:    samples,
+		ReporterId: p.ReporterID,
+		Location:   c.locality.get(ctx).proto(),
+	}, c.compression.CallOption(false))
+	return resp, toError(err)
+}
@@ -0,0 +1,40 @@
+package routingclient
+
+import (
+	"context"
+
+	"example.com/ipfs_kit_py/kubo"
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// Providers is the result of a DHT provider lookup for content on IPFS.
+// Sent with a selection for a retrieval, it lets the service weigh how
+// well the content is provided when choosing where to read it from.
+type Providers struct {
+	// Count is the number of peers found providing the content
+	Count int `json:"count"`
+	// Limit is the most the lookup asked for, so Count == Limit means at
+	// least that many
+	Limit int `json:"limit"`
+}
+
+func (p *Providers) proto() *pb.SelectBackendRequest_Providers {
+	if p == nil {
+		return nil
+	}
+	return &pb.SelectBackendRequest_Providers{Count: int32(p.Count), Limit: int32(p.Limit)}
+}
+
+// FindProviders looks up the DHT providers of cid through node, stopping
+// after limit of them (kubo.DefaultProviderLimit if zero), for
+// ContentInfo.Providers
+func FindProviders(ctx context.Context, node *kubo.Client, cid string, limit int) (*Providers, error) {
+	if limit <= 0 {
+		limit = kubo.DefaultProviderLimit
+	}
+	found, err := node.FindProviders(ctx, cid, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &Providers{Count: len(found), Limit: limit}, nil
+}
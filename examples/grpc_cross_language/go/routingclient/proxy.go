@@ -0,0 +1,179 @@
+package routingclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+	"google.golang.org/grpc"
+)
+
+// Proxy routes the gRPC connection through an HTTP CONNECT or SOCKS5 proxy.
+//
+// URL selects the proxy:
+//   - http://[user:pass@]host:port or https://... tunnel with CONNECT
+//   - socks5://[user:pass@]host:port resolves the target locally;
+//     socks5h:// lets the proxy resolve it
+//   - "none" or "direct" dials the server directly
+//   - empty reads HTTPS_PROXY, HTTP_PROXY and NO_PROXY (or their lowercase
+//     forms) from the environment, which may also name a socks5 proxy
+//
+// Unix socket targets are always dialed directly.
+type Proxy struct {
+	URL string
+}
+
+// RegisterFlags adds -proxy to fs, defaulting to the current value of p
+func (p *Proxy) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&p.URL, "proxy", p.URL, "proxy URL (http://, https://, socks5://, socks5h://); empty uses $HTTPS_PROXY, none disables")
+}
+
+// DialOption returns the grpc dial option applying p, or an error if the
+// proxy URL is invalid
+func (p Proxy) DialOption() (grpc.DialOption, error) {
+	switch p.URL {
+	case "none", "direct":
+		return grpc.WithNoProxy(), nil
+	case "":
+		env := httpproxy.FromEnvironment().ProxyFunc()
+		return grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			if _, ok := unixAddr(addr); ok {
+				return dialDirect(ctx, addr)
+			}
+			// The scheme only picks HTTPS_PROXY over HTTP_PROXY; gRPC
+			// always needs a tunnel
+			u, err := env(&url.URL{Scheme: "https", Host: addr})
+			if err != nil {
+				return nil, fmt.Errorf("routing: proxy from environment: %w", err)
+			}
+			if u == nil {
+				return dialDirect(ctx, addr)
+			}
+			if err := checkProxyURL(u); err != nil {
+				return nil, err
+			}
+			return dialProxy(ctx, u, addr)
+		}), nil
+	}
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return nil, fmt.Errorf("routing: proxy URL: %w", err)
+	}
+	if err := checkProxyURL(u); err != nil {
+		return nil, err
+	}
+	return grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		if _, ok := unixAddr(addr); ok {
+			return dialDirect(ctx, addr)
+		}
+		return dialProxy(ctx, u, addr)
+	}), nil
+}
+
+func checkProxyURL(u *url.URL) error {
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("routing: unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("routing: proxy URL %q has no host", u.Redacted())
+	}
+	return nil
+}
+
+// dialProxy opens a connection to addr through the proxy at u
+func dialProxy(ctx context.Context, u *url.URL, addr string) (net.Conn, error) {
+	if u.Scheme == "socks5" || u.Scheme == "socks5h" {
+		d, err := proxy.FromURL(u, &net.Dialer{})
+		if err != nil {
+			return nil, fmt.Errorf("routing: socks proxy: %w", err)
+		}
+		conn, err := d.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("routing: socks proxy %s: %w", u.Host, err)
+		}
+		return conn, nil
+	}
+	return dialConnect(ctx, u, addr)
+}
+
+// dialConnect tunnels to addr with an HTTP CONNECT request
+func dialConnect(ctx context.Context, u *url.URL, addr string) (net.Conn, error) {
+	host := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("routing: proxy %s: %w", host, err)
+	}
+	if u.Scheme == "https" {
+		tc := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("routing: proxy %s: %w", host, err)
+		}
+		conn = tc
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Host: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		cred := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + pass))
+		req.Header.Set("Proxy-Authorization", "Basic "+cred)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("routing: proxy %s: %w", host, err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("routing: proxy %s: %w", host, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("routing: proxy %s: CONNECT %s: %s", host, addr, resp.Status)
+	}
+	if br.Buffered() > 0 {
+		// The server spoke first; keep what the reader already consumed
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn whose first reads drain a bufio.Reader
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
@@ -0,0 +1,380 @@
+package routingclient
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// TrafficVersion is the version of the traffic recording format
+const TrafficVersion = 1
+
+// TrafficHeader starts a traffic recording
+type TrafficHeader struct {
+	Version int
+	Start   time.Time
+}
+
+// Exchange is one recorded RPC: the messages sent and received, in
+// protobuf wire form, and how the call ended
+type Exchange struct {
+	Method string
+	// Stream is set for streaming RPCs, whose messages are in the order
+	// they were sent and received
+	Stream    bool
+	Requests  [][]byte
+	Responses [][]byte
+	Code      codes.Code
+	Message   string
+	Start     time.Time
+	Duration  time.Duration
+}
+
+// Err returns the status error the call ended with, nil if it succeeded
+func (e *Exchange) Err() error {
+	if e.Code == codes.OK {
+		return nil
+	}
+	return status.Error(e.Code, e.Message)
+}
+
+// Recorder writes every RPC of the connections it wraps to a traffic
+// recording, for ReplayConn to serve back, so routing behaviour reported
+// in an issue can be reproduced without the server. Unary calls are
+// written when they return and streams when they end; streams still open
+// are written, as cancelled, when their connection is closed. Messages are
+// recorded as sent, so a recording contains whatever metadata the content
+// carried. Only the first write error is kept, by Err; calls are not
+// failed because recording did.
+type Recorder struct {
+	mu   sync.Mutex
+	enc  *gob.Encoder
+	err  error
+	open map[*recordStream]struct{}
+}
+
+// NewRecorder starts a recording on w
+func NewRecorder(w io.Writer) (*Recorder, error) {
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(TrafficHeader{Version: TrafficVersion, Start: time.Now().UTC()}); err != nil {
+		return nil, fmt.Errorf("record: %w", err)
+	}
+	return &Recorder{enc: enc, open: make(map[*recordStream]struct{})}, nil
+}
+
+// Err returns the first error writing the recording
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Conn returns conn with its calls recorded
+func (r *Recorder) Conn(conn Conn) Conn {
+	return &recordConn{Conn: conn, rec: r}
+}
+
+// write appends e to the recording
+func (r *Recorder) write(e *Exchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		if err := r.enc.Encode(e); err != nil {
+			r.err = fmt.Errorf("record: %w", err)
+		}
+	}
+}
+
+// finish writes the exchange of a stream that ended with err
+func (r *Recorder) finish(s *recordStream, err error) {
+	r.mu.Lock()
+	if _, ok := r.open[s]; !ok {
+		r.mu.Unlock()
+		return
+	}
+	delete(r.open, s)
+	r.mu.Unlock()
+	s.end(err)
+	r.write(&s.ex)
+}
+
+// marshal returns m in wire form, nil if it is not a protobuf message
+func marshal(m any) []byte {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return nil
+	}
+	data, _ := proto.Marshal(msg)
+	return data
+}
+
+type recordConn struct {
+	Conn
+	rec *Recorder
+}
+
+func (c *recordConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := c.Conn.Invoke(ctx, method, args, reply, opts...)
+	st := status.Convert(err)
+	e := &Exchange{
+		Method:   method,
+		Requests: [][]byte{marshal(args)},
+		Code:     st.Code(),
+		Message:  st.Message(),
+		Start:    start.UTC(),
+		Duration: time.Since(start),
+	}
+	if err == nil {
+		e.Responses = [][]byte{marshal(reply)}
+	}
+	c.rec.write(e)
+	return err
+}
+
+func (c *recordConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	start := time.Now()
+	cs, err := c.Conn.NewStream(ctx, desc, method, opts...)
+	if err != nil {
+		st := status.Convert(err)
+		c.rec.write(&Exchange{Method: method, Stream: true, Code: st.Code(), Message: st.Message(), Start: start.UTC()})
+		return nil, err
+	}
+	s := &recordStream{ClientStream: cs, rec: c.rec, serverStreams: desc.ServerStreams, start: start}
+	s.ex = Exchange{Method: method, Stream: true, Start: start.UTC()}
+	c.rec.mu.Lock()
+	c.rec.open[s] = struct{}{}
+	c.rec.mu.Unlock()
+	return s, nil
+}
+
+// Close writes the streams still open, as cancelled, and closes the
+// connection
+func (c *recordConn) Close() error {
+	c.rec.mu.Lock()
+	open := make([]*recordStream, 0, len(c.rec.open))
+	for s := range c.rec.open {
+		open = append(open, s)
+	}
+	c.rec.mu.Unlock()
+	for _, s := range open {
+		c.rec.finish(s, status.Error(codes.Canceled, "connection closed"))
+	}
+	return c.Conn.Close()
+}
+
+// recordStream records the messages of a stream
+type recordStream struct {
+	grpc.ClientStream
+	rec           *Recorder
+	serverStreams bool
+	start         time.Time
+
+	mu sync.Mutex
+	ex Exchange
+}
+
+func (s *recordStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.mu.Lock()
+		s.ex.Requests = append(s.ex.Requests, marshal(m))
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (s *recordStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.rec.finish(s, err)
+		return err
+	}
+	s.mu.Lock()
+	s.ex.Responses = append(s.ex.Responses, marshal(m))
+	s.mu.Unlock()
+	// A client-streaming call ends with its one response
+	if !s.serverStreams {
+		s.rec.finish(s, nil)
+	}
+	return nil
+}
+
+// end sets how the stream ended; io.EOF is a clean end
+func (s *recordStream) end(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil && !errors.Is(err, io.EOF) {
+		st := status.Convert(err)
+		s.ex.Code, s.ex.Message = st.Code(), st.Message()
+	}
+	s.ex.Duration = time.Since(s.start)
+}
+
+// ReadTraffic reads a traffic recording
+func ReadTraffic(r io.Reader) (TrafficHeader, []Exchange, error) {
+	dec := gob.NewDecoder(r)
+	var h TrafficHeader
+	if err := dec.Decode(&h); err != nil {
+		return h, nil, fmt.Errorf("replay: not a traffic recording: %w", err)
+	}
+	if h.Version != TrafficVersion {
+		return h, nil, fmt.Errorf("replay: unsupported traffic recording version %d", h.Version)
+	}
+	var exchanges []Exchange
+	for {
+		var e Exchange
+		err := dec.Decode(&e)
+		// A recording cut short by a crash ends with a partial exchange
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			return h, exchanges, nil
+		}
+		if err != nil {
+			return h, exchanges, fmt.Errorf("replay: exchange %d: %w", len(exchanges)+1, err)
+		}
+		exchanges = append(exchanges, e)
+	}
+}
+
+// ReplayConn serves RPCs from a traffic recording instead of a server.
+// Each call of a method gets the response of the next recorded call of
+// that method, whatever its request, so a replayed run sees the decisions,
+// errors and stream messages the recorded one did. A call with no
+// recorded call left fails with Unavailable.
+type ReplayConn struct {
+	mu    sync.Mutex
+	calls map[string][]*Exchange
+}
+
+// NewReplayConn creates a ReplayConn serving exchanges
+func NewReplayConn(exchanges []Exchange) *ReplayConn {
+	c := &ReplayConn{calls: make(map[string][]*Exchange)}
+	for i := range exchanges {
+		e := &exchanges[i]
+		c.calls[e.Method] = append(c.calls[e.Method], e)
+	}
+	return c
+}
+
+// OpenReplay creates a ReplayConn serving the recording at path
+func OpenReplay(path string) (*ReplayConn, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	_, exchanges, err := ReadTraffic(f)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayConn(exchanges), nil
+}
+
+// Remaining returns how many recorded calls have not been replayed
+func (c *ReplayConn) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, calls := range c.calls {
+		n += len(calls)
+	}
+	return n
+}
+
+// Close is a no-op
+func (c *ReplayConn) Close() error {
+	return nil
+}
+
+// next returns the next recorded call of method
+func (c *ReplayConn) next(method string, stream bool) (*Exchange, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	calls := c.calls[method]
+	if len(calls) == 0 {
+		return nil, status.Errorf(codes.Unavailable, "replay: no recorded %s call left", method)
+	}
+	e := calls[0]
+	c.calls[method] = calls[1:]
+	if e.Stream != stream {
+		return nil, status.Errorf(codes.Internal, "replay: %s was recorded as a different kind of call", method)
+	}
+	return e, nil
+}
+
+// unmarshal decodes a recorded message into m
+func unmarshal(data []byte, m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return status.Errorf(codes.Internal, "replay: cannot decode into %T", m)
+	}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return status.Errorf(codes.Internal, "replay: %v", err)
+	}
+	return nil
+}
+
+func (c *ReplayConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	if err := ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	e, err := c.next(method, false)
+	if err != nil {
+		return err
+	}
+	if err := e.Err(); err != nil {
+		return err
+	}
+	if len(e.Responses) == 0 {
+		return status.Errorf(codes.Internal, "replay: recorded %s call has no response", method)
+	}
+	return unmarshal(e.Responses[0], reply)
+}
+
+func (c *ReplayConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	e, err := c.next(method, true)
+	if err != nil {
+		return nil, err
+	}
+	return &replayStream{ctx: ctx, ex: e}, nil
+}
+
+// replayStream serves a recorded stream's responses in order, then how it
+// ended; a stream that failed to open fails on its first RecvMsg. Messages
+// sent are discarded.
+type replayStream struct {
+	ctx context.Context
+	ex  *Exchange
+	n   int
+}
+
+func (s *replayStream) Header() (metadata.MD, error) { return metadata.MD{}, nil }
+func (s *replayStream) Trailer() metadata.MD         { return metadata.MD{} }
+func (s *replayStream) CloseSend() error             { return nil }
+func (s *replayStream) Context() context.Context     { return s.ctx }
+func (s *replayStream) SendMsg(m any) error          { return nil }
+
+func (s *replayStream) RecvMsg(m any) error {
+	if s.n < len(s.ex.Responses) {
+		s.n++
+		return unmarshal(s.ex.Responses[s.n-1], m)
+	}
+	if err := s.ex.Err(); err != nil {
+		return err
+	}
+	if err := s.ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	return io.EOF
+}
@@ -0,0 +1,922 @@
+package routingclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// RPC method names of the routing service
+const (
+	methodSelectBackend  = "/ipfs_kit_py.routing.RoutingService/SelectBackend"
+	methodRecordOutcome  = "/ipfs_kit_py.routing.RoutingService/RecordOutcome"
+	methodRecordOutcomes = "/ipfs_kit_py.routing.RoutingService/RecordOutcomes"
+	methodStreamOutcomes = "/ipfs_kit_py.routing.RoutingService/StreamOutcomes"
+	methodGetInsights    = "/ipfs_kit_py.routing.RoutingService/GetInsights"
+	methodSetWeights     = "/ipfs_kit_py.routing.RoutingService/SetFactorWeights"
+	methodListBackends   = "/ipfs_kit_py.routing.RoutingService/ListBackends"
+	methodBackendStats   = "/ipfs_kit_py.routing.RoutingService/GetBackendStats"
+	methodEstimateCost   = "/ipfs_kit_py.routing.RoutingService/EstimateCost"
+	methodStreamMetrics  = "/ipfs_kit_py.routing.RoutingService/StreamMetrics"
+	methodReportMetrics  = "/ipfs_kit_py.routing.RoutingService/ReportMetrics"
+	methodWatchBackends  = "/ipfs_kit_py.routing.RoutingService/WatchBackends"
+	methodGetHistory     = "/ipfs_kit_py.routing.RoutingService/GetHistory"
+)
+
+// RESTConn serves the routing service's RPCs from its HTTP/JSON API
+// (ipfs_kit_py/routing/http_server.py), so a Client built on it keeps the
+// same methods when only HTTP is reachable. StreamMetrics polls
+// /api/v1/metrics, WatchBackends polls /api/v1/backends and StreamOutcomes
+// posts each outcome to /api/v1/record-outcome. Fields the HTTP API does
+// not return, such as alternatives and factor scores, are left empty.
+type RESTConn struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewRESTConn creates a RESTConn for the HTTP routing API at baseURL, using
+// hc (http.DefaultClient if nil)
+func NewRESTConn(baseURL string, hc *http.Client) *RESTConn {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &RESTConn{baseURL: strings.TrimRight(baseURL, "/"), http: hc}
+}
+
+// Close releases idle connections
+func (c *RESTConn) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+// restOutcome is an outcome in the HTTP API's shape
+type restOutcome struct {
+	Backend          string   `json:"backend"`
+	Success          bool     `json:"success"`
+	DurationMs       int32    `json:"duration_ms"`
+	ContentType      string   `json:"content_type,omitempty"`
+	ContentSize      int64    `json:"content_size,omitempty"`
+	ContentHash      string   `json:"content_hash,omitempty"`
+	ErrorMessage     string   `json:"error_message,omitempty"`
+	IdempotencyKey   string   `json:"idempotency_key,omitempty"`
+	BytesTransferred int64    `json:"bytes_transferred,omitempty"`
+	ErrorClass       string   `json:"error_class,omitempty"`
+	Placement        []string `json:"placement,omitempty"`
+}
+
+func toRESTOutcome(req *pb.RecordOutcomeRequest) restOutcome {
+	return restOutcome{
+		Backend:          req.BackendId,
+		Success:          req.Success,
+		DurationMs:       req.DurationMs,
+		ContentType:      req.ContentType,
+		ContentSize:      req.ContentSize,
+		ContentHash:      req.ContentHash,
+		ErrorMessage:     req.Error,
+		IdempotencyKey:   req.IdempotencyKey,
+		BytesTransferred: req.BytesTransferred,
+		ErrorClass:       req.ErrorClass,
+		Placement:        req.Placement,
+	}
+}
+
+// Invoke implements grpc.ClientConnInterface for the unary RPCs
+func (c *RESTConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	var hdr *metadata.MD
+	for _, o := range opts {
+		if o, ok := o.(grpc.HeaderCallOption); ok {
+			hdr = o.HeaderAddr
+		}
+	}
+	switch method {
+	case methodSelectBackend:
+		req, resp := args.(*pb.SelectBackendRequest), reply.(*pb.SelectBackendResponse)
+		body := map[string]interface{}{
+			"content_type": req.ContentType,
+			"content_size": req.ContentSize,
+			"content_hash": req.ContentHash,
+			"strategy":     req.Strategy,
+			"request_id":   req.RequestId,
+		}
+		if req.StrategyType != pb.RoutingStrategy_ROUTING_STRATEGY_UNSPECIFIED {
+			body["strategy"] = StrategyName(req.StrategyType)
+		}
+		if req.ContentCategory != pb.ContentCategory_CONTENT_CATEGORY_UNSPECIFIED {
+			body["content_category"] = CategoryName(req.ContentCategory)
+		}
+		if req.Priority != "" {
+			body["priority"] = req.Priority
+		}
+		if req.DryRun {
+			body["dry_run"] = true
+		}
+		if loc := req.ClientLocation; loc != nil {
+			body["client_location"] = map[string]interface{}{
+				"latitude":  loc.Latitude,
+				"longitude": loc.Longitude,
+				"region":    loc.Region,
+				"zone":      loc.Zone,
+				"network":   loc.Network,
+				"provider":  loc.Provider,
+			}
+		}
+		if p := req.Providers; p != nil {
+			body["providers"] = map[string]interface{}{"count": p.Count, "limit": p.Limit}
+		}
+		if len(req.AvailableBackends) > 0 {
+			body["available_backends"] = req.AvailableBackends
+		}
+		if req.RetentionDays > 0 {
+			body["retention_days"] = req.RetentionDays
+		}
+		if req.Metadata != nil {
+			body["metadata"] = req.Metadata.AsMap()
+		}
+		var out struct {
+			Backend    string  `json:"backend"`
+			Confidence float64 `json:"confidence"`
+			Reasoning  string  `json:"reasoning"`
+			DryRun     bool    `json:"dry_run"`
+			Timestamp  string  `json:"timestamp"`
+		}
+		if err := c.do(ctx, http.MethodPost, "/api/v1/select-backend", body, &out, hdr); err != nil {
+			return err
+		}
+		proto.Reset(resp)
+		resp.BackendId = out.Backend
+		resp.Score = out.Confidence
+		resp.Reasoning = out.Reasoning
+		resp.DryRun = out.DryRun
+		resp.RequestId = req.RequestId
+		resp.Timestamp = restTimestamp(out.Timestamp)
+		return nil
+
+	case methodRecordOutcome:
+		req, resp := args.(*pb.RecordOutcomeRequest), reply.(*pb.RecordOutcomeResponse)
+		var out struct {
+			Success   bool   `json:"success"`
+			Message   string `json:"message"`
+			Duplicate bool   `json:"duplicate"`
+			Timestamp string `json:"timestamp"`
+		}
+		if err := c.do(ctx, http.MethodPost, "/api/v1/record-outcome", toRESTOutcome(req), &out, hdr); err != nil {
+			return err
+		}
+		proto.Reset(resp)
+		resp.Success = out.Success
+		resp.Message = out.Message
+		resp.Duplicate = out.Duplicate
+		resp.Timestamp = restTimestamp(out.Timestamp)
+		return nil
+
+	case methodRecordOutcomes:
+		req, resp := args.(*pb.RecordOutcomesRequest), reply.(*pb.RecordOutcomesResponse)
+		outcomes := make([]restOutcome, len(req.Outcomes))
+		for i, o := range req.Outcomes {
+			outcomes[i] = toRESTOutcome(o)
+		}
+		body := map[string]interface{}{"outcomes": outcomes, "batch_id": req.BatchId}
+		var out struct {
+			Recorded   int32    `json:"recorded"`
+			Duplicates int32    `json:"duplicates"`
+			Errors     []string `json:"errors"`
+			Timestamp  string   `json:"timestamp"`
+		}
+		if err := c.do(ctx, http.MethodPost, "/api/v1/record-outcomes", body, &out, hdr); err != nil {
+			return err
+		}
+		proto.Reset(resp)
+		resp.Recorded = out.Recorded
+		resp.Duplicates = out.Duplicates
+		resp.Errors = out.Errors
+		resp.Timestamp = restTimestamp(out.Timestamp)
+		return nil
+
+	case methodGetInsights:
+		req, resp := args.(*pb.GetInsightsRequest), reply.(*pb.GetInsightsResponse)
+		q := url.Values{}
+		if req.BackendId != "" {
+			q.Set("backend_id", req.BackendId)
+		}
+		if req.ContentType != "" {
+			q.Set("content_type", req.ContentType)
+		}
+		if req.TimeWindowHours > 0 {
+			q.Set("time_window_hours", strconv.Itoa(int(req.TimeWindowHours)))
+		}
+		path := "/api/v1/insights"
+		if len(q) > 0 {
+			path += "?" + q.Encode()
+		}
+		var out struct {
+			Insights struct {
+				BackendDistribution   map[string]interface{} `json:"backend_distribution"`
+				FactorWeights         map[string]interface{} `json:"factor_weights"`
+				Placement             map[string]interface{} `json:"placement"`
+				AverageResponseTimeMs float64                `json:"average_response_time_ms"`
+				SuccessRate           float64                `json:"success_rate"`
+			} `json:"insights"`
+			Timestamp string `json:"timestamp"`
+		}
+		if err := c.do(ctx, http.MethodGet, path, nil, &out, hdr); err != nil {
+			return err
+		}
+		proto.Reset(resp)
+		var err error
+		if resp.BackendUsageStats, err = structpb.NewStruct(out.Insights.BackendDistribution); err != nil {
+			return status.Errorf(codes.Internal, "rest: insights: %v", err)
+		}
+		if out.Insights.FactorWeights != nil {
+			if resp.FactorWeights, err = structpb.NewStruct(out.Insights.FactorWeights); err != nil {
+				return status.Errorf(codes.Internal, "rest: insights: %v", err)
+			}
+		}
+		if out.Insights.Placement != nil {
+			if resp.Placement, err = structpb.NewStruct(out.Insights.Placement); err != nil {
+				return status.Errorf(codes.Internal, "rest: insights: %v", err)
+			}
+		}
+		resp.LatencyStats, _ = structpb.NewStruct(map[string]interface{}{"average_ms": out.Insights.AverageResponseTimeMs})
+		resp.BackendSuccessRates, _ = structpb.NewStruct(map[string]interface{}{"overall": out.Insights.SuccessRate})
+		resp.Timestamp = restTimestamp(out.Timestamp)
+		return nil
+
+	case methodEstimateCost:
+		req, resp := args.(*pb.EstimateCostRequest), reply.(*pb.EstimateCostResponse)
+		body := map[string]interface{}{
+			"content_type":        req.ContentType,
+			"content_size":        req.ContentSize,
+			"expected_retrievals": req.ExpectedRetrievals,
+		}
+		if req.RetentionDays > 0 {
+			body["retention_days"] = req.RetentionDays
+		}
+		if len(req.Backends) > 0 {
+			body["backends"] = req.Backends
+		}
+		var out struct {
+			Estimates []struct {
+				BackendID             string  `json:"backend_id"`
+				StorageCost           float64 `json:"storage_cost"`
+				EgressCost            float64 `json:"egress_cost"`
+				TotalCost             float64 `json:"total_cost"`
+				StorageCostPerGBMonth float64 `json:"storage_cost_per_gb_month"`
+				EgressCostPerGB       float64 `json:"egress_cost_per_gb"`
+			} `json:"estimates"`
+			Currency  string `json:"currency"`
+			Timestamp string `json:"timestamp"`
+		}
+		if err := c.do(ctx, http.MethodPost, "/api/v1/estimate-cost", body, &out, hdr); err != nil {
+			return err
+		}
+		proto.Reset(resp)
+		for _, e := range out.Estimates {
+			resp.Estimates = append(resp.Estimates, &pb.CostEstimate{
+				BackendId:             e.BackendID,
+				StorageCost:           e.StorageCost,
+				EgressCost:            e.EgressCost,
+				TotalCost:             e.TotalCost,
+				StorageCostPerGbMonth: e.StorageCostPerGBMonth,
+				EgressCostPerGb:       e.EgressCostPerGB,
+			})
+		}
+		resp.Currency = out.Currency
+		resp.Timestamp = restTimestamp(out.Timestamp)
+		return nil
+
+	case methodReportMetrics:
+		req, resp := args.(*pb.ReportMetricsRequest), reply.(*pb.ReportMetricsResponse)
+		samples := make([]map[string]interface{}, len(req.Samples))
+		for i, sm := range req.Samples {
+			samples[i] = map[string]interface{}{
+				"backend_id":                  sm.BackendId,
+				"endpoint":                    sm.Endpoint,
+				"success":                     sm.Success,
+				"latency_ms":                  sm.LatencyMs,
+				"throughput_bytes_per_second": sm.ThroughputBytesPerSecond,
+				"bytes":                       sm.Bytes,
+				"error":                       sm.Error,
+			}
+			if n := sm.Node; n != nil {
+				samples[i]["node"] = restNodeStats{
+					Peers:                   n.Peers,
+					WantlistLength:          n.WantlistLength,
+					BlocksReceived:          n.BlocksReceived,
+					DuplicateBlocksReceived: n.DuplicateBlocksReceived,
+					BlocksSent:              n.BlocksSent,
+					RateIn:                  n.RateInBytesPerSecond,
+					RateOut:                 n.RateOutBytesPerSecond,
+				}
+			}
+		}
+		body := map[string]interface{}{"samples": samples}
+		if req.ReporterId != "" {
+			body["reporter_id"] = req.ReporterId
+		}
+		var out struct {
+			Accepted  int32  `json:"accepted"`
+			Rejected  int32  `json:"rejected"`
+			Timestamp string `json:"timestamp"`
+		}
+		if err := c.do(ctx, http.MethodPost, "/api/v1/metrics/report", body, &out, hdr); err != nil {
+			return err
+		}
+		proto.Reset(resp)
+		resp.Accepted = out.Accepted
+		resp.Rejected = out.Rejected
+		resp.Timestamp = restTimestamp(out.Timestamp)
+		return nil
+
+	case methodSetWeights:
+		req, resp := args.(*pb.SetFactorWeightsRequest), reply.(*pb.SetFactorWeightsResponse)
+		var out struct {
+			Weights         map[string]float64 `json:"weights"`
+			PreviousWeights map[string]float64 `json:"previous_weights"`
+			Timestamp       string             `json:"timestamp"`
+		}
+		body := map[string]interface{}{"weights": req.Weights}
+		if err := c.do(ctx, http.MethodPut, "/api/v1/admin/factor-weights", body, &out, hdr); err != nil {
+			return err
+		}
+		proto.Reset(resp)
+		resp.Weights = out.Weights
+		resp.PreviousWeights = out.PreviousWeights
+		resp.Timestamp = restTimestamp(out.Timestamp)
+		return nil
+
+	case methodListBackends:
+		req, resp := args.(*pb.ListBackendsRequest), reply.(*pb.ListBackendsResponse)
+		q := url.Values{}
+		if req.ContentType != "" {
+			q.Set("content_type", req.ContentType)
+		}
+		if req.ContentSize > 0 {
+			q.Set("content_size", strconv.FormatInt(req.ContentSize, 10))
+		}
+		path := "/api/v1/backends"
+		if len(q) > 0 {
+			path += "?" + q.Encode()
+		}
+		var out struct {
+			Backends  map[string]restBackend `json:"backends"`
+			Timestamp string                 `json:"timestamp"`
+		}
+		if err := c.do(ctx, http.MethodGet, path, nil, &out, hdr); err != nil {
+			return err
+		}
+		proto.Reset(resp)
+		for id, b := range out.Backends {
+			resp.Backends = append(resp.Backends, &pb.BackendInfo{
+				BackendId:     id,
+				MaxObjectSize: b.MaxObjectSize,
+				ContentTypes:  b.ContentTypes,
+				Region:        b.Region,
+				PricingClass:  pb.PricingClass(pb.PricingClass_value["PRICING_CLASS_"+strings.ToUpper(b.PricingClass)]),
+				State:         b.backendState(),
+			})
+		}
+		sort.Slice(resp.Backends, func(i, j int) bool { return resp.Backends[i].BackendId < resp.Backends[j].BackendId })
+		resp.Timestamp = restTimestamp(out.Timestamp)
+		return nil
+
+	case methodBackendStats:
+		req, resp := args.(*pb.GetBackendStatsRequest), reply.(*pb.BackendStats)
+		path := "/api/v1/backends/" + url.PathEscape(req.BackendId) + "/stats"
+		if req.WindowMinutes > 0 {
+			path += "?window_minutes=" + strconv.Itoa(int(req.WindowMinutes))
+		}
+		var out struct {
+			BackendID     string  `json:"backend_id"`
+			WindowMinutes int32   `json:"window_minutes"`
+			Requests      int64   `json:"requests"`
+			Successes     int64   `json:"successes"`
+			SuccessRate   float64 `json:"success_rate"`
+			LatencyMs     struct {
+				P50  float64 `json:"p50"`
+				P90  float64 `json:"p90"`
+				P99  float64 `json:"p99"`
+				Max  float64 `json:"max"`
+				Mean float64 `json:"mean"`
+			} `json:"latency_ms"`
+			Throughput        float64          `json:"throughput_bytes_per_second"`
+			RequestsPerMinute float64          `json:"requests_per_minute"`
+			Errors            map[string]int64 `json:"errors"`
+			State             string           `json:"state"`
+			Node              *restNodeStats   `json:"node"`
+			Timestamp         string           `json:"timestamp"`
+		}
+		if err := c.do(ctx, http.MethodGet, path, nil, &out, hdr); err != nil {
+			return err
+		}
+		proto.Reset(resp)
+		resp.BackendId = out.BackendID
+		resp.WindowMinutes = out.WindowMinutes
+		resp.Requests = out.Requests
+		resp.Successes = out.Successes
+		resp.SuccessRate = out.SuccessRate
+		resp.Latency = &pb.BackendStats_Latency{
+			P50Ms:  out.LatencyMs.P50,
+			P90Ms:  out.LatencyMs.P90,
+			P99Ms:  out.LatencyMs.P99,
+			MaxMs:  out.LatencyMs.Max,
+			MeanMs: out.LatencyMs.Mean,
+		}
+		resp.ThroughputBytesPerSecond = out.Throughput
+		resp.RequestsPerMinute = out.RequestsPerMinute
+		resp.Errors = out.Errors
+		resp.State = restBackend{State: out.State}.backendState()
+		if n := out.Node; n != nil {
+			resp.Node = &pb.NodeStats{
+				Peers:                   n.Peers,
+				WantlistLength:          n.WantlistLength,
+				BlocksReceived:          n.BlocksReceived,
+				DuplicateBlocksReceived: n.DuplicateBlocksReceived,
+				BlocksSent:              n.BlocksSent,
+				RateInBytesPerSecond:    n.RateIn,
+				RateOutBytesPerSecond:   n.RateOut,
+			}
+		}
+		resp.Timestamp = restTimestamp(out.Timestamp)
+		return nil
+
+	case methodGetHistory:
+		req, resp := args.(*pb.GetHistoryRequest), reply.(*pb.GetHistoryResponse)
+		q := url.Values{}
+		if req.Since != nil {
+			q.Set("since", req.Since.AsTime().UTC().Format("2006-01-02T15:04:05.999999"))
+		}
+		if req.Until != nil {
+			q.Set("until", req.Until.AsTime().UTC().Format("2006-01-02T15:04:05.999999"))
+		}
+		if req.Kind != pb.HistoryRecordKind_HISTORY_RECORD_KIND_UNSPECIFIED {
+			q.Set("kind", req.Kind.String())
+		}
+		if req.BackendId != "" {
+			q.Set("backend_id", req.BackendId)
+		}
+		if req.PageSize > 0 {
+			q.Set("page_size", strconv.Itoa(int(req.PageSize)))
+		}
+		if req.PageToken != "" {
+			q.Set("page_token", req.PageToken)
+		}
+		path := "/api/v1/history"
+		if len(q) > 0 {
+			path += "?" + q.Encode()
+		}
+		var out struct {
+			Records []struct {
+				Kind             string  `json:"kind"`
+				Timestamp        string  `json:"timestamp"`
+				RequestID        string  `json:"request_id"`
+				BackendID        string  `json:"backend_id"`
+				ContentHash      string  `json:"content_hash"`
+				ContentType      string  `json:"content_type"`
+				ContentSize      int64   `json:"content_size"`
+				Strategy         string  `json:"strategy"`
+				Score            float64 `json:"score"`
+				Reasoning        string  `json:"reasoning"`
+				Success          bool    `json:"success"`
+				DurationMs       int32   `json:"duration_ms"`
+				BytesTransferred int64   `json:"bytes_transferred"`
+				Error            string  `json:"error"`
+				ErrorClass       string  `json:"error_class"`
+			} `json:"records"`
+			NextPageToken string `json:"next_page_token"`
+		}
+		if err := c.do(ctx, http.MethodGet, path, nil, &out, hdr); err != nil {
+			return err
+		}
+		proto.Reset(resp)
+		for _, r := range out.Records {
+			kind := pb.HistoryRecordKind_HISTORY_RECORD_KIND_DECISION
+			if r.Kind == "outcome" {
+				kind = pb.HistoryRecordKind_HISTORY_RECORD_KIND_OUTCOME
+			}
+			resp.Records = append(resp.Records, &pb.HistoryRecord{
+				Kind:             kind,
+				Timestamp:        restTimestamp(r.Timestamp),
+				RequestId:        r.RequestID,
+				BackendId:        r.BackendID,
+				ContentHash:      r.ContentHash,
+				ContentType:      r.ContentType,
+				ContentSize:      r.ContentSize,
+				Strategy:         r.Strategy,
+				Score:            r.Score,
+				Reasoning:        r.Reasoning,
+				Success:          r.Success,
+				DurationMs:       r.DurationMs,
+				BytesTransferred: r.BytesTransferred,
+				Error:            r.Error,
+				ErrorClass:       r.ErrorClass,
+			})
+		}
+		resp.NextPageToken = out.NextPageToken
+		return nil
+	}
+	return status.Errorf(codes.Unimplemented, "rest: %s has no HTTP equivalent", method)
+}
+
+// restNodeStats is a node's transfer statistics in the HTTP API
+type restNodeStats struct {
+	Peers                   int32   `json:"peers"`
+	WantlistLength          int32   `json:"wantlist_length"`
+	BlocksReceived          int64   `json:"blocks_received"`
+	DuplicateBlocksReceived int64   `json:"duplicate_blocks_received"`
+	BlocksSent              int64   `json:"blocks_sent"`
+	RateIn                  float64 `json:"rate_in_bytes_per_second"`
+	RateOut                 float64 `json:"rate_out_bytes_per_second"`
+}
+
+// restBackend is a backend in the HTTP API's /api/v1/backends listing
+type restBackend struct {
+	MaxObjectSize int64    `json:"max_object_size"`
+	ContentTypes  []string `json:"content_types"`
+	Region        string   `json:"region"`
+	PricingClass  string   `json:"pricing_class"`
+	State         string   `json:"state"`
+	Reason        string   `json:"reason"`
+	SuccessRate   float64  `json:"success_rate"`
+	LatencyMs     float64  `json:"latency_ms"`
+	Since         string   `json:"since"`
+}
+
+func (b restBackend) backendState() pb.BackendState {
+	return pb.BackendState(pb.BackendState_value["BACKEND_STATE_"+strings.ToUpper(b.State)])
+}
+
+// NewStream implements grpc.ClientConnInterface. StreamMetrics polls the
+// metrics endpoint at the requested interval (default 5s); WatchBackends
+// polls the backends endpoint every 5s and reports state changes;
+// StreamOutcomes records each outcome as it is sent.
+func (c *RESTConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	switch method {
+	case methodStreamMetrics:
+		return &metricsPoller{conn: c, ctx: ctx}, nil
+	case methodWatchBackends:
+		return &backendPoller{conn: c, ctx: ctx, interval: 5 * time.Second}, nil
+	case methodStreamOutcomes:
+		return &outcomePoster{conn: c, ctx: ctx, ready: make(chan struct{}, 1)}, nil
+	}
+	return nil, status.Errorf(codes.Unimplemented, "rest: %s has no HTTP equivalent", method)
+}
+
+// metricsPoller emulates StreamMetrics by polling /api/v1/metrics
+type metricsPoller struct {
+	conn     *RESTConn
+	ctx      context.Context
+	interval time.Duration
+	polled   bool
+}
+
+func (p *metricsPoller) Header() (metadata.MD, error) { return nil, nil }
+func (p *metricsPoller) Trailer() metadata.MD         { return nil }
+func (p *metricsPoller) CloseSend() error             { return nil }
+func (p *metricsPoller) Context() context.Context     { return p.ctx }
+
+func (p *metricsPoller) SendMsg(m any) error {
+	req, ok := m.(*pb.StreamMetricsRequest)
+	if !ok {
+		return status.Errorf(codes.Internal, "rest: unexpected request %T", m)
+	}
+	p.interval = time.Duration(req.UpdateIntervalSeconds) * time.Second
+	if p.interval <= 0 {
+		p.interval = 5 * time.Second
+	}
+	return nil
+}
+
+func (p *metricsPoller) RecvMsg(m any) error {
+	if p.polled {
+		t := time.NewTimer(p.interval)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-p.ctx.Done():
+			return status.FromContextError(p.ctx.Err()).Err()
+		}
+	}
+	p.polled = true
+	var out struct {
+		Metrics   map[string]interface{} `json:"metrics"`
+		Timestamp string                 `json:"timestamp"`
+	}
+	if err := p.conn.do(p.ctx, http.MethodGet, "/api/v1/metrics", nil, &out, nil); err != nil {
+		return err
+	}
+	update := m.(*pb.MetricsUpdate)
+	proto.Reset(update)
+	metrics, err := structpb.NewStruct(out.Metrics)
+	if err != nil {
+		return status.Errorf(codes.Internal, "rest: metrics: %v", err)
+	}
+	update.Metrics = metrics
+	if health, _ := out.Metrics["system_health"].(string); health != "" && health != "healthy" {
+		update.Status = pb.MetricsUpdate_WARNING
+	}
+	update.Timestamp = restTimestamp(out.Timestamp)
+	return nil
+}
+
+// backendPoller emulates WatchBackends by polling /api/v1/backends and
+// reporting backends whose state differs from the previous poll
+type backendPoller struct {
+	conn     *RESTConn
+	ctx      context.Context
+	interval time.Duration
+	req      *pb.WatchBackendsRequest
+	polled   bool
+	states   map[string]pb.BackendState
+	pending  []*pb.BackendEvent
+}
+
+func (p *backendPoller) Header() (metadata.MD, error) { return nil, nil }
+func (p *backendPoller) Trailer() metadata.MD         { return nil }
+func (p *backendPoller) CloseSend() error             { return nil }
+func (p *backendPoller) Context() context.Context     { return p.ctx }
+
+func (p *backendPoller) SendMsg(m any) error {
+	req, ok := m.(*pb.WatchBackendsRequest)
+	if !ok {
+		return status.Errorf(codes.Internal, "rest: unexpected request %T", m)
+	}
+	p.req = req
+	return nil
+}
+
+func (p *backendPoller) RecvMsg(m any) error {
+	for len(p.pending) == 0 {
+		if p.polled {
+			t := time.NewTimer(p.interval)
+			select {
+			case <-t.C:
+			case <-p.ctx.Done():
+				t.Stop()
+				return status.FromContextError(p.ctx.Err()).Err()
+			}
+		}
+		if err := p.poll(); err != nil {
+			return err
+		}
+	}
+	proto.Reset(m.(*pb.BackendEvent))
+	proto.Merge(m.(*pb.BackendEvent), p.pending[0])
+	p.pending = p.pending[1:]
+	return nil
+}
+
+// poll queues an event for each backend whose state changed; the first
+// poll queues every backend if the request asked for current states
+func (p *backendPoller) poll() error {
+	path := "/api/v1/backends"
+	if ids := p.req.GetBackendIds(); len(ids) > 0 {
+		path += "?" + url.Values{"backends": {strings.Join(ids, ",")}}.Encode()
+	}
+	var out struct {
+		Backends map[string]restBackend `json:"backends"`
+	}
+	if err := p.conn.do(p.ctx, http.MethodGet, path, nil, &out, nil); err != nil {
+		return err
+	}
+	first := !p.polled
+	p.polled = true
+	if p.states == nil {
+		p.states = make(map[string]pb.BackendState)
+	}
+	ids := make([]string, 0, len(out.Backends))
+	for id := range out.Backends {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		b := out.Backends[id]
+		state := b.backendState()
+		prev, seen := p.states[id]
+		p.states[id] = state
+		// Later polls report changes and newly seen backends
+		var report bool
+		switch {
+		case seen:
+			report = prev != state
+		case first:
+			report = p.req.GetIncludeCurrent()
+		default:
+			report = true
+		}
+		if !report {
+			continue
+		}
+		p.pending = append(p.pending, &pb.BackendEvent{
+			BackendId:     id,
+			State:         state,
+			PreviousState: prev,
+			Reason:        b.Reason,
+			SuccessRate:   b.SuccessRate,
+			LatencyMs:     b.LatencyMs,
+			Timestamp:     restTimestamp(b.Since),
+		})
+	}
+	return nil
+}
+
+// outcomePoster emulates StreamOutcomes by posting each outcome to
+// /api/v1/record-outcome as it is sent. Acknowledgements carry the running
+// totals after each outcome; ones not yet received are merged, as a
+// server acknowledging periodically would.
+type outcomePoster struct {
+	conn  *RESTConn
+	ctx   context.Context
+	ready chan struct{} // signalled when there is an ack or the stream ends
+
+	mu      sync.Mutex
+	ack     pb.OutcomeAck
+	unacked bool
+	errs    []string
+	closed  bool
+	err     error
+}
+
+func (p *outcomePoster) Header() (metadata.MD, error) { return nil, nil }
+func (p *outcomePoster) Trailer() metadata.MD         { return nil }
+func (p *outcomePoster) Context() context.Context     { return p.ctx }
+
+func (p *outcomePoster) CloseSend() error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.signal()
+	return nil
+}
+
+func (p *outcomePoster) signal() {
+	select {
+	case p.ready <- struct{}{}:
+	default:
+	}
+}
+
+// SendMsg records one outcome. Outcomes the server rejects are reported
+// in the next ack's errors; any other failure ends the stream, and
+// SendMsg returns io.EOF with the error left for RecvMsg.
+func (p *outcomePoster) SendMsg(m any) error {
+	req, ok := m.(*pb.RecordOutcomeRequest)
+	if !ok {
+		return status.Errorf(codes.Internal, "rest: unexpected request %T", m)
+	}
+	p.mu.Lock()
+	done := p.closed || p.err != nil
+	p.mu.Unlock()
+	if done {
+		return io.EOF
+	}
+	var out struct {
+		Duplicate bool `json:"duplicate"`
+	}
+	err := p.conn.do(p.ctx, http.MethodPost, "/api/v1/record-outcome", toRESTOutcome(req), &out, nil)
+	p.mu.Lock()
+	defer p.signal()
+	defer p.mu.Unlock()
+	switch {
+	case err == nil && out.Duplicate:
+		p.ack.Duplicates++
+	case err == nil:
+		p.ack.Recorded++
+	case status.Code(err) == codes.InvalidArgument:
+		p.errs = append(p.errs, fmt.Sprintf("outcome %d: %s", p.ack.Received, status.Convert(err).Message()))
+	default:
+		p.err = err
+		return io.EOF
+	}
+	p.ack.Received++
+	p.ack.LastIdempotencyKey = req.IdempotencyKey
+	p.unacked = true
+	return nil
+}
+
+// RecvMsg returns the totals once outcomes were sent since the last ack,
+// then io.EOF after CloseSend or the error that ended the stream
+func (p *outcomePoster) RecvMsg(m any) error {
+	for {
+		p.mu.Lock()
+		if p.unacked {
+			ack := m.(*pb.OutcomeAck)
+			proto.Reset(ack)
+			proto.Merge(ack, &p.ack)
+			ack.Errors = p.errs
+			ack.Timestamp = timestamppb.Now()
+			p.errs, p.unacked = nil, false
+			p.mu.Unlock()
+			return nil
+		}
+		err, closed := p.err, p.closed
+		p.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		if closed {
+			return io.EOF
+		}
+		select {
+		case <-p.ready:
+		case <-p.ctx.Done():
+			return status.FromContextError(p.ctx.Err()).Err()
+		}
+	}
+}
+
+// do sends a JSON request and decodes the JSON response, mapping failures
+// to gRPC status errors. Response headers are stored in hdr if non-nil.
+func (c *RESTConn) do(ctx context.Context, method, path string, body, out interface{}, hdr *metadata.MD) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return status.Errorf(codes.Internal, "rest: %v", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, r)
+	if err != nil {
+		return status.Errorf(codes.Internal, "rest: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		for k, vs := range md {
+			for _, v := range vs {
+				req.Header.Add(k, v)
+			}
+		}
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return status.FromContextError(ctxErr).Err()
+		}
+		return status.Errorf(codes.Unavailable, "rest: %v", err)
+	}
+	defer resp.Body.Close()
+	if hdr != nil {
+		*hdr = headerMD(resp.Header)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error     string `json:"error"`
+			ErrorType string `json:"error_type"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&e)
+		code := httpStatusCode(resp.StatusCode)
+		if resp.StatusCode == http.StatusBadRequest {
+			code = codes.InvalidArgument
+		}
+		// The server's error_type is the ErrorInfo reason a gRPC server
+		// would send, lower-cased
+		reason := strings.ToUpper(e.ErrorType)
+		switch {
+		case reason == ReasonNoBackendAvailable:
+			code = codes.FailedPrecondition
+		case resp.StatusCode == http.StatusNotFound && reason != "":
+			// The route exists; the server did not find what it names
+			code = codes.NotFound
+		}
+		if e.Error == "" {
+			e.Error = resp.Status
+		}
+		return errorStatus(code, reason, fmt.Sprintf("rest: %s %s: %s", method, path, e.Error)).Err()
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return status.Errorf(codes.Internal, "rest: %s %s: %v", method, path, err)
+	}
+	return nil
+}
+
+// restTimestamp parses the server's naive UTC ISO timestamps, falling back
+// to the current time
+func restTimestamp(s string) *timestamppb.Timestamp {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return timestamppb.New(t)
+		}
+	}
+	return timestamppb.Now()
+}
@@ -0,0 +1,71 @@
+package routingclient
+
+import (
+	"context"
+	"sort"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// Candidate is a backend offered by SelectBackend, either the primary choice
+// or one of its alternatives, with the score the service gave it
+type Candidate struct {
+	BackendID string
+	Score     float64
+}
+
+// Scorer re-scores candidate backends with knowledge the routing service
+// does not have, such as data locality or internal cost tables. Score
+// receives the service's score and returns the one to rank by; returning
+// a negative score drops the candidate.
+type Scorer interface {
+	Score(ctx context.Context, info ContentInfo, c Candidate) float64
+}
+
+// ScorerFunc adapts a function to the Scorer interface
+type ScorerFunc func(ctx context.Context, info ContentInfo, c Candidate) float64
+
+// Score calls f
+func (f ScorerFunc) Score(ctx context.Context, info ContentInfo, c Candidate) float64 {
+	return f(ctx, info, c)
+}
+
+// SetScorer installs a scorer that SelectBackend applies to every decision;
+// nil restores the service's ranking
+func (c *Client) SetScorer(s Scorer) {
+	c.scorer = s
+}
+
+// Rerank rescores the primary backend and alternatives of resp with s and
+// rewrites resp so BackendId is the best candidate and Alternatives holds
+// the rest, best first. Ties keep the service's order. FactorScores still
+// describe the service's own choice. If s drops every candidate, resp is
+// left unchanged.
+func Rerank(ctx context.Context, s Scorer, info ContentInfo, resp *pb.SelectBackendResponse) {
+	candidates := make([]Candidate, 0, len(resp.Alternatives)+1)
+	candidates = append(candidates, Candidate{resp.BackendId, resp.Score})
+	for _, alt := range resp.Alternatives {
+		candidates = append(candidates, Candidate{alt.BackendId, alt.Score})
+	}
+
+	scored := candidates[:0]
+	for _, cand := range candidates {
+		if score := s.Score(ctx, info, cand); score >= 0 {
+			scored = append(scored, Candidate{cand.BackendID, score})
+		}
+	}
+	if len(scored) == 0 {
+		return
+	}
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+
+	resp.BackendId = scored[0].BackendID
+	resp.Score = scored[0].Score
+	resp.Alternatives = resp.Alternatives[:0]
+	for _, cand := range scored[1:] {
+		resp.Alternatives = append(resp.Alternatives, &pb.SelectBackendResponse_Alternative{
+			BackendId: cand.BackendID,
+			Score:     cand.Score,
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package routingclient
+
+import (
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Transport dials targets gRPC has no resolver for, such as libp2p
+// multiaddrs. Transports are optional plug-ins registered with
+// RegisterTransport, so programs that do not use one do not link it.
+type Transport interface {
+	// Match reports whether target is the transport's to dial
+	Match(target string) bool
+	// Dial returns the target to hand grpc.NewClient in place of target
+	// and the dial options that reach it. They are applied after the
+	// caller's, overriding any dialer those set.
+	Dial(target string) (string, []grpc.DialOption, error)
+}
+
+var (
+	transportsMu sync.RWMutex
+	transports   []Transport
+)
+
+// RegisterTransport makes Dial, and so New, use t for the targets it
+// matches. Transports registered first take precedence.
+func RegisterTransport(t Transport) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	transports = append(transports, t)
+}
+
+// transportFor returns the registered transport matching target, if any
+func transportFor(target string) Transport {
+	transportsMu.RLock()
+	defer transportsMu.RUnlock()
+	for _, t := range transports {
+		if t.Match(target) {
+			return t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package routingclient
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials/local"
+)
+
+// IsUnix reports whether target names a Unix domain socket:
+// unix:///abs/path, unix:relative/path or unix-abstract:name. gRPC dials
+// these natively, so co-located clients skip TCP entirely.
+func IsUnix(target string) bool {
+	return strings.HasPrefix(target, "unix:") || strings.HasPrefix(target, "unix-abstract:")
+}
+
+// TransportCredentials returns the credentials for dialing target without
+// TLS. Unix sockets get local credentials, which report the connection as
+// private so per-RPC credentials such as bearer tokens may be sent over it;
+// other targets get insecure credentials.
+func TransportCredentials(target string) credentials.TransportCredentials {
+	if IsUnix(target) {
+		return local.NewCredentials()
+	}
+	return insecure.NewCredentials()
+}
+
+// unixAddr returns the socket path from an address handed to a custom
+// dialer: gRPC passes unix targets as "unix://path" or "unix:path", and
+// abstract sockets as a path starting with NUL
+func unixAddr(addr string) (string, bool) {
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		return strings.TrimPrefix(addr, "unix://"), true
+	case strings.HasPrefix(addr, "unix:"):
+		return strings.TrimPrefix(addr, "unix:"), true
+	case strings.HasPrefix(addr, "\x00"):
+		return "@" + addr[1:], true
+	}
+	return "", false
+}
+
+// dialDirect dials addr without a proxy, over a Unix socket if it names one
+func dialDirect(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	if path, ok := unixAddr(addr); ok {
+		return d.DialContext(ctx, "unix", path)
+	}
+	return d.DialContext(ctx, "tcp", addr)
+}
@@ -0,0 +1,50 @@
+package routingclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// OutcomeClassIntegrity marks outcomes for content a backend returned
+// corrupted: it was stored or served, but failed verification on
+// retrieval. Outcomes whose Err wraps cidutil.ErrDigestMismatch get it
+// automatically.
+const OutcomeClassIntegrity = "integrity"
+
+// ErrorClass returns o.Class, or the class derived from o.Err
+func (o Outcome) ErrorClass() string {
+	if o.Class == "" && errors.Is(o.Err, cidutil.ErrDigestMismatch) {
+		return OutcomeClassIntegrity
+	}
+	return o.Class
+}
+
+// VerifyContent checks content retrieved for info against what was stored:
+// against cid when it is a raw-codec CID, and against info.ContentHash
+// when that is a hex SHA-256. Failures wrap cidutil.ErrDigestMismatch, so
+// recording them as an Outcome's Err classes them as integrity failures.
+// Content with neither to check against passes.
+func VerifyContent(info ContentInfo, cid string, content []byte) error {
+	if cid != "" {
+		c, err := cidutil.ParseCID(cid)
+		if err == nil && c.Codec == cidutil.Raw {
+			if err := c.VerifyBlock(content); err != nil {
+				return fmt.Errorf("routing: content for %s: %w", cid, err)
+			}
+		}
+	}
+	if want := strings.ToLower(info.ContentHash); len(want) == sha256.Size*2 {
+		if _, err := hex.DecodeString(want); err == nil {
+			sum := sha256.Sum256(content)
+			if hex.EncodeToString(sum[:]) != want {
+				return fmt.Errorf("routing: content hash: %w (sha2-256)", cidutil.ErrDigestMismatch)
+			}
+		}
+	}
+	return nil
+}
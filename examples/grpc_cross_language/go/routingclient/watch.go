@@ -0,0 +1,136 @@
+package routingclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "example.com/ipfs_kit_py/routing"
+)
+
+// BackendWatcher follows backend health through the WatchBackends stream
+// and keeps the latest state of each backend. It is a Scorer: installed
+// with SetScorer it drops unavailable backends from decisions and ranks
+// degraded ones lower, so traffic shifts as soon as the service notices a
+// failure rather than after the agent's own requests time out.
+type BackendWatcher struct {
+	client   *Client
+	backends []string
+
+	// OnEvent, if set, is called with every event, after the state is
+	// updated
+	OnEvent func(*pb.BackendEvent)
+	// DegradedPenalty multiplies the score of degraded backends
+	// (default 0.5)
+	DegradedPenalty float64
+	// Retry is the initial delay before reopening a broken stream; it
+	// doubles up to a minute (default 1s)
+	Retry time.Duration
+
+	mu     sync.RWMutex
+	states map[string]*pb.BackendEvent
+}
+
+// NewBackendWatcher creates a watcher for the named backends, or all
+// backends if none are named. Run starts it.
+func (c *Client) NewBackendWatcher(backends ...string) *BackendWatcher {
+	return &BackendWatcher{
+		client:          c,
+		backends:        backends,
+		DegradedPenalty: 0.5,
+		Retry:           time.Second,
+		states:          make(map[string]*pb.BackendEvent),
+	}
+}
+
+// Run watches until ctx ends, reopening the stream when it breaks; each
+// new stream starts with the current states. It returns ctx's error, or
+// the error of a server without WatchBackends.
+func (w *BackendWatcher) Run(ctx context.Context) error {
+	delay := w.Retry
+	for {
+		received, err := w.watch(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if status.Code(err) == codes.Unimplemented {
+			return err
+		}
+		if received {
+			delay = w.Retry
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+		delay = min(2*delay, time.Minute)
+	}
+}
+
+// watch follows one stream until it ends, reporting whether any event
+// arrived
+func (w *BackendWatcher) watch(ctx context.Context) (bool, error) {
+	stream, err := w.client.rpc.WatchBackends(ctx, &pb.WatchBackendsRequest{
+		BackendIds:     w.backends,
+		IncludeCurrent: true,
+	})
+	if err != nil {
+		return false, toError(err)
+	}
+	received := false
+	for {
+		ev, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return received, nil
+			}
+			return received, toError(err)
+		}
+		received = true
+		w.mu.Lock()
+		w.states[ev.BackendId] = ev
+		w.mu.Unlock()
+		if w.OnEvent != nil {
+			w.OnEvent(ev)
+		}
+	}
+}
+
+// State returns the latest known state of a backend; unknown backends are
+// BACKEND_STATE_UNSPECIFIED
+func (w *BackendWatcher) State(backendID string) pb.BackendState {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.states[backendID].GetState()
+}
+
+// States returns the latest event for each backend seen so far
+func (w *BackendWatcher) States() map[string]*pb.BackendEvent {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	states := make(map[string]*pb.BackendEvent, len(w.states))
+	for id, ev := range w.states {
+		states[id] = ev
+	}
+	return states
+}
+
+// Score implements Scorer: unavailable backends are dropped and degraded
+// ones penalised; others keep the service's score
+func (w *BackendWatcher) Score(_ context.Context, _ ContentInfo, c Candidate) float64 {
+	switch w.State(c.BackendID) {
+	case pb.BackendState_BACKEND_STATE_UNAVAILABLE:
+		return -1
+	case pb.BackendState_BACKEND_STATE_DEGRADED:
+		return c.Score * w.DegradedPenalty
+	}
+	return c.Score
+}
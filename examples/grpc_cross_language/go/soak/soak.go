@@ -0,0 +1,484 @@
+// Package soak runs long randomized workloads against a deployment and
+// checks that it keeps its invariants while doing so.
+//
+// Workers upload, read back, list, delete and route random objects through
+// the MCP dashboard's bucket tools (and the routing service when configured),
+// keeping a ledger of what should exist. Reads and listings are checked
+// against the ledger, so lost objects and index drift are reported, and the
+// server's memory use is sampled to catch unbounded growth. Optional fault
+// injection cancels requests mid-flight and reports synthetic failures to
+// the router to exercise recovery paths.
+package soak
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/ipfs_kit_py/mcpclient"
+	"example.com/ipfs_kit_py/routingclient"
+)
+
+// Faults controls failure injection. Rates are probabilities in [0, 1].
+type Faults struct {
+	// CancelRate is the chance an operation's context is cancelled mid-flight
+	CancelRate float64 `json:"cancel_rate"`
+	// FailOutcomeRate is the chance a routing outcome is reported as failed
+	FailOutcomeRate float64 `json:"fail_outcome_rate"`
+}
+
+// Config configures a soak run
+type Config struct {
+	MCP *mcpclient.Client
+	// Routing is optional; when set, route operations exercise the router
+	Routing *routingclient.Client
+	// Bucket is created if missing and used for all objects
+	Bucket      string
+	Duration    time.Duration
+	Concurrency int
+	Seed        int64
+	// MaxObjectSize bounds the size of generated objects (default 256 KiB)
+	MaxObjectSize int
+	Faults        Faults
+	// HealthURL is the dashboard base URL used to sample server memory;
+	// empty disables the memory invariant
+	HealthURL string
+	// SampleInterval is how often memory is sampled (default 30s)
+	SampleInterval time.Duration
+	// MaxMemoryGrowth is the allowed fractional growth in server memory
+	// between the first and last quarter of the run (default 0.25)
+	MaxMemoryGrowth float64
+	// OnProgress is called after every sample with the running report
+	OnProgress func(Report)
+}
+
+// Violation is a broken invariant
+type Violation struct {
+	Invariant string    `json:"invariant"`
+	Detail    string    `json:"detail"`
+	At        time.Time `json:"at"`
+}
+
+// OpStats counts the results of one operation type
+type OpStats struct {
+	OK        int64 `json:"ok"`
+	Failed    int64 `json:"failed"`
+	Cancelled int64 `json:"cancelled"`
+}
+
+// Report summarises a soak run
+type Report struct {
+	Started      time.Time          `json:"started"`
+	Elapsed      time.Duration      `json:"elapsed_ns"`
+	Ops          map[string]OpStats `json:"ops"`
+	Objects      int                `json:"objects"`
+	MemorySample []uint64           `json:"memory_samples,omitempty"`
+	Violations   []Violation        `json:"violations"`
+	Passed       bool               `json:"passed"`
+}
+
+type object struct {
+	sum  string
+	size int
+}
+
+type run struct {
+	cfg Config
+
+	mu     sync.Mutex
+	report Report
+	ledger map[string]object
+	seq    int
+}
+
+// Run executes the soak workload until cfg.Duration elapses or ctx is done
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if cfg.MCP == nil {
+		return nil, errors.New("soak: MCP client is required")
+	}
+	if cfg.Bucket == "" {
+		cfg.Bucket = "soak"
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.MaxObjectSize <= 0 {
+		cfg.MaxObjectSize = 256 << 10
+	}
+	if cfg.SampleInterval <= 0 {
+		cfg.SampleInterval = 30 * time.Second
+	}
+	if cfg.MaxMemoryGrowth <= 0 {
+		cfg.MaxMemoryGrowth = 0.25
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+
+	if _, err := cfg.MCP.GetBucket(ctx, cfg.Bucket); err != nil {
+		if _, err := cfg.MCP.CreateBucket(ctx, cfg.Bucket, ""); err != nil {
+			return nil, fmt.Errorf("soak: create bucket %s: %w", cfg.Bucket, err)
+		}
+	}
+
+	r := &run{
+		cfg:    cfg,
+		ledger: make(map[string]object),
+		report: Report{Started: time.Now(), Ops: make(map[string]OpStats)},
+	}
+
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r.worker(ctx, rand.New(rand.NewSource(seed)))
+		}(cfg.Seed + int64(i))
+	}
+
+	ticker := time.NewTicker(cfg.SampleInterval)
+	defer ticker.Stop()
+	r.sample(ctx)
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+			r.sample(ctx)
+			if cfg.OnProgress != nil {
+				cfg.OnProgress(r.snapshot())
+			}
+		}
+	}
+	wg.Wait()
+
+	// Final pass: every object in the ledger must still be readable and
+	// listed, using a fresh context since the run's context has expired.
+	final, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Minute)
+	defer cancel()
+	r.verifyAll(final)
+	r.sample(final)
+	r.checkMemory()
+
+	rep := r.snapshot()
+	rep.Passed = len(rep.Violations) == 0
+	return &rep, nil
+}
+
+func (r *run) worker(ctx context.Context, rng *rand.Rand) {
+	for ctx.Err() == nil {
+		op, fn := r.pick(rng)
+		opCtx, cancel := context.WithCancel(ctx)
+		if rng.Float64() < r.cfg.Faults.CancelRate {
+			time.AfterFunc(time.Duration(rng.Intn(50))*time.Millisecond, cancel)
+		}
+		err := fn(opCtx, rng)
+		injected := opCtx.Err() != nil && ctx.Err() == nil
+		cancel()
+		r.count(op, err, injected || ctx.Err() != nil)
+	}
+}
+
+func (r *run) pick(rng *rand.Rand) (string, func(context.Context, *rand.Rand) error) {
+	switch n := rng.Intn(100); {
+	case n < 35:
+		return "upload", r.upload
+	case n < 70:
+		return "read", r.read
+	case n < 80:
+		return "list", r.list
+	case n < 90:
+		return "delete", r.delete
+	default:
+		if r.cfg.Routing == nil {
+			return "read", r.read
+		}
+		return "route", r.route
+	}
+}
+
+func (r *run) count(op string, err error, cancelled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.report.Ops[op]
+	switch {
+	case err == nil:
+		s.OK++
+	case cancelled:
+		s.Cancelled++
+	default:
+		s.Failed++
+	}
+	r.report.Ops[op] = s
+}
+
+func (r *run) violate(invariant, format string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Violations = append(r.report.Violations, Violation{
+		Invariant: invariant,
+		Detail:    fmt.Sprintf(format, args...),
+		At:        time.Now(),
+	})
+}
+
+func (r *run) upload(ctx context.Context, rng *rand.Rand) error {
+	data := make([]byte, 1+rng.Intn(r.cfg.MaxObjectSize))
+	rng.Read(data)
+	sum := sha256.Sum256(data)
+
+	r.mu.Lock()
+	r.seq++
+	name := fmt.Sprintf("obj-%d-%06d", r.cfg.Seed, r.seq)
+	r.mu.Unlock()
+
+	if _, err := r.cfg.MCP.UploadFile(ctx, r.cfg.Bucket, name, data); err != nil {
+		// The object may or may not have landed; clean it up so it does
+		// not show up as an unexpected listing entry later.
+		_ = r.cfg.MCP.DeleteFile(context.WithoutCancel(ctx), r.cfg.Bucket, name)
+		return err
+	}
+	r.mu.Lock()
+	r.ledger[name] = object{sum: hex.EncodeToString(sum[:]), size: len(data)}
+	r.mu.Unlock()
+	return nil
+}
+
+// claim picks a random ledger entry and removes it so no other worker
+// touches it until release puts it back
+func (r *run) claim(rng *rand.Rand) (string, object, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.ledger) == 0 {
+		return "", object{}, false
+	}
+	i := rng.Intn(len(r.ledger))
+	for name, obj := range r.ledger {
+		if i == 0 {
+			delete(r.ledger, name)
+			return name, obj, true
+		}
+		i--
+	}
+	return "", object{}, false
+}
+
+func (r *run) release(name string, obj object) {
+	r.mu.Lock()
+	r.ledger[name] = obj
+	r.mu.Unlock()
+}
+
+func (r *run) read(ctx context.Context, rng *rand.Rand) error {
+	name, obj, ok := r.claim(rng)
+	if !ok {
+		return nil
+	}
+	defer r.release(name, obj)
+	return r.verify(ctx, name, obj)
+}
+
+func (r *run) verify(ctx context.Context, name string, obj object) error {
+	data, err := r.cfg.MCP.DownloadFile(ctx, r.cfg.Bucket, name)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.violate("no-lost-objects", "%s: %v", name, err)
+		}
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != obj.sum {
+		r.violate("no-lost-objects", "%s: content hash %s, want %s", name, got, obj.sum)
+		return fmt.Errorf("soak: %s: content mismatch", name)
+	}
+	return nil
+}
+
+func (r *run) delete(ctx context.Context, rng *rand.Rand) error {
+	name, obj, ok := r.claim(rng)
+	if !ok {
+		return nil
+	}
+	if err := r.cfg.MCP.DeleteFile(ctx, r.cfg.Bucket, name); err != nil {
+		if ctx.Err() != nil {
+			// A cancelled delete may still have run server-side, so the
+			// object can no longer be tracked; finish the delete instead.
+			_ = r.cfg.MCP.DeleteFile(context.WithoutCancel(ctx), r.cfg.Bucket, name)
+			return err
+		}
+		r.release(name, obj)
+		return err
+	}
+	return nil
+}
+
+func (r *run) list(ctx context.Context, _ *rand.Rand) error {
+	r.mu.Lock()
+	want := make(map[string]bool, len(r.ledger))
+	for name := range r.ledger {
+		want[name] = true
+	}
+	r.mu.Unlock()
+
+	files, err := r.cfg.MCP.ListFiles(ctx, r.cfg.Bucket, "")
+	if err != nil {
+		return err
+	}
+	listed := make(map[string]bool, len(files))
+	for _, f := range files {
+		listed[f.Path] = true
+	}
+	for name := range want {
+		// Objects deleted concurrently are no longer in the ledger.
+		r.mu.Lock()
+		_, still := r.ledger[name]
+		r.mu.Unlock()
+		if still && !listed[name] {
+			r.violate("index-consistency", "%s is in the ledger but missing from the bucket listing", name)
+		}
+	}
+	return nil
+}
+
+func (r *run) route(ctx context.Context, rng *rand.Rand) error {
+	info := routingclient.ContentInfo{
+		ContentType: "application/octet-stream",
+		ContentSize: int64(1 + rng.Intn(r.cfg.MaxObjectSize)),
+		Metadata:    map[string]string{"source": "soak"},
+	}
+	decision, err := r.cfg.Routing.SelectBackend(ctx, info, "hybrid")
+	if err != nil {
+		return err
+	}
+	outcome := routingclient.Outcome{
+		BackendID: decision.GetBackendId(),
+		Success:   rng.Float64() >= r.cfg.Faults.FailOutcomeRate,
+		Duration:  time.Duration(10+rng.Intn(500)) * time.Millisecond,
+	}
+	if !outcome.Success {
+		outcome.Err = errors.New("soak: injected failure")
+	}
+	_, err = r.cfg.Routing.RecordOutcome(ctx, info, outcome)
+	return err
+}
+
+func (r *run) verifyAll(ctx context.Context) {
+	r.mu.Lock()
+	objects := make(map[string]object, len(r.ledger))
+	for name, obj := range r.ledger {
+		objects[name] = obj
+	}
+	r.mu.Unlock()
+
+	for name, obj := range objects {
+		if ctx.Err() != nil {
+			return
+		}
+		_ = r.verify(ctx, name, obj)
+	}
+	_ = r.list(ctx, nil)
+}
+
+// sample records the server's memory use from /api/system/health
+func (r *run) sample(ctx context.Context) {
+	if r.cfg.HealthURL == "" {
+		return
+	}
+	u := strings.TrimRight(r.cfg.HealthURL, "/") + "/api/system/health"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	var health struct {
+		Memory struct {
+			Used uint64 `json:"used"`
+		} `json:"memory"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil || health.Memory.Used == 0 {
+		return
+	}
+	r.mu.Lock()
+	r.report.MemorySample = append(r.report.MemorySample, health.Memory.Used)
+	r.mu.Unlock()
+}
+
+// checkMemory compares mean memory use in the first and last quarter of the
+// run; steady growth beyond MaxMemoryGrowth is reported as a leak
+func (r *run) checkMemory() {
+	r.mu.Lock()
+	samples := append([]uint64(nil), r.report.MemorySample...)
+	r.mu.Unlock()
+	if len(samples) < 8 {
+		return
+	}
+	q := len(samples) / 4
+	mean := func(s []uint64) float64 {
+		var t float64
+		for _, v := range s {
+			t += float64(v)
+		}
+		return t / float64(len(s))
+	}
+	first, last := mean(samples[:q]), mean(samples[len(samples)-q:])
+	if growth := (last - first) / first; growth > r.cfg.MaxMemoryGrowth {
+		r.violate("bounded-memory", "server memory grew %.0f%% (%.0f -> %.0f bytes)", growth*100, first, last)
+	}
+}
+
+func (r *run) snapshot() Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rep := r.report
+	rep.Elapsed = time.Since(rep.Started)
+	rep.Objects = len(r.ledger)
+	rep.Ops = make(map[string]OpStats, len(r.report.Ops))
+	for k, v := range r.report.Ops {
+		rep.Ops[k] = v
+	}
+	rep.MemorySample = append([]uint64(nil), r.report.MemorySample...)
+	rep.Violations = append([]Violation(nil), r.report.Violations...)
+	return rep
+}
+
+// Summary renders the report as human-readable text
+func (rep *Report) Summary() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "soak run: %s, %d objects in ledger\n", rep.Elapsed.Round(time.Second), rep.Objects)
+	for _, op := range []string{"upload", "read", "list", "delete", "route"} {
+		s, ok := rep.Ops[op]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&b, "  %-7s ok=%d failed=%d cancelled=%d\n", op, s.OK, s.Failed, s.Cancelled)
+	}
+	if len(rep.Violations) == 0 {
+		b.WriteString("all invariants held\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%d invariant violations:\n", len(rep.Violations))
+	for _, v := range rep.Violations {
+		fmt.Fprintf(&b, "  [%s] %s\n", v.Invariant, v.Detail)
+	}
+	return b.String()
+}
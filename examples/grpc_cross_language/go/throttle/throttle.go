@@ -0,0 +1,129 @@
+// Package throttle limits the bandwidth transfers use, overall and per
+// transfer, so bulk migrations run from production hosts leave room for
+// the traffic they share the network with.
+package throttle
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxChunk caps a single read through a Reader, so a caller asking for a
+// large buffer at once, such as a multipart upload filling a part, is
+// paced smoothly rather than in bursts
+const maxChunk = 64 << 10
+
+// Limiter is a token bucket shared by the transfers it limits. A nil
+// Limiter does not limit.
+type Limiter struct {
+	rate  float64 // bytes per second
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter limits transfers to bytesPerSecond between them, allowing
+// bursts of up to a second's worth; it returns nil, no limit, if
+// bytesPerSecond is not positive
+func NewLimiter(bytesPerSecond int64) *Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	rate := float64(bytesPerSecond)
+	return &Limiter{rate: rate, burst: rate, tokens: rate}
+}
+
+// Rate returns the limit in bytes per second, or 0 for none
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	return int64(l.rate)
+}
+
+// Wait blocks until n more bytes may pass, or ctx is done. The bytes are
+// counted even if ctx ends the wait, as they have already been read.
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	// Tokens may go negative; later callers then queue behind this one
+	l.tokens -= float64(n)
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Limits are the bandwidth limits of one direction of transfer
+type Limits struct {
+	// Total is shared by every transfer; nil for no limit
+	Total *Limiter
+	// PerTransfer caps each transfer on its own, in bytes per second; 0
+	// for no limit
+	PerTransfer int64
+}
+
+// Reader limits a transfer read through r; a nil Limits does not limit
+func (l *Limits) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return NewReader(ctx, r, l.Total, NewLimiter(l.PerTransfer))
+}
+
+// Reader paces reads through it to its limiters. A read that would exceed
+// a limit returns its data once the limit allows, or the context's error
+// if it is done first.
+type Reader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*Limiter
+}
+
+// NewReader wraps r, waiting on every non-nil limiter for what is read;
+// with none, r is returned as is
+func NewReader(ctx context.Context, r io.Reader, limiters ...*Limiter) io.Reader {
+	var ls []*Limiter
+	for _, l := range limiters {
+		if l != nil {
+			ls = append(ls, l)
+		}
+	}
+	if len(ls) == 0 {
+		return r
+	}
+	return &Reader{ctx: ctx, r: r, limiters: ls}
+}
+
+func (t *Reader) Read(p []byte) (int, error) {
+	if len(p) > maxChunk {
+		p = p[:maxChunk]
+	}
+	n, err := t.r.Read(p)
+	for _, l := range t.limiters {
+		if werr := l.Wait(t.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,95 @@
+package throttle_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/ipfs_kit_py/throttle"
+)
+
+func TestNoLimit(t *testing.T) {
+	for _, rate := range []int64{0, -1} {
+		if l := throttle.NewLimiter(rate); l != nil {
+			t.Errorf("NewLimiter(%d) = %v, want nil", rate, l)
+		}
+	}
+	var l *throttle.Limiter
+	if l.Rate() != 0 {
+		t.Errorf("nil Limiter has rate %d", l.Rate())
+	}
+	if err := l.Wait(context.Background(), 1<<30); err != nil {
+		t.Errorf("nil Limiter: %v", err)
+	}
+	r := strings.NewReader("data")
+	if got := throttle.NewReader(context.Background(), r, nil, nil); got != r {
+		t.Error("NewReader without limiters wrapped the reader")
+	}
+	var limits *throttle.Limits
+	if got := limits.Reader(context.Background(), r); got != r {
+		t.Error("nil Limits wrapped the reader")
+	}
+}
+
+// TestReaderPaces reads a second and a half's worth through a limiter
+// whose burst covers the first second, so the rest has to wait
+func TestReaderPaces(t *testing.T) {
+	const rate = 1 << 20
+	data := bytes.Repeat([]byte("x"), rate*3/2)
+	l := throttle.NewLimiter(rate)
+	if l.Rate() != rate {
+		t.Fatalf("Rate() = %d, want %d", l.Rate(), rate)
+	}
+	start := time.Now()
+	got, err := io.ReadAll(throttle.NewReader(context.Background(), bytes.NewReader(data), l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("read %d bytes, want %d", len(got), len(data))
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("read 1.5s worth in %v", elapsed)
+	}
+}
+
+// TestPerTransfer checks each transfer gets a burst of its own under
+// PerTransfer, while Total is shared. The context is already canceled, so
+// any read that would have to wait fails instead.
+func TestPerTransfer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limits := &throttle.Limits{PerTransfer: 1 << 10}
+	for i := 0; i < 2; i++ {
+		if _, err := io.ReadAll(limits.Reader(ctx, bytes.NewReader(make([]byte, 1<<10)))); err != nil {
+			t.Errorf("transfer %d within its burst: %v", i, err)
+		}
+	}
+	if _, err := io.ReadAll(limits.Reader(ctx, bytes.NewReader(make([]byte, 4<<10)))); !errors.Is(err, context.Canceled) {
+		t.Errorf("transfer past its burst: %v, want context.Canceled", err)
+	}
+
+	limits.Total = throttle.NewLimiter(1 << 10)
+	if _, err := io.ReadAll(limits.Reader(ctx, bytes.NewReader(make([]byte, 1<<10)))); err != nil {
+		t.Errorf("first transfer under Total: %v", err)
+	}
+	if _, err := io.ReadAll(limits.Reader(ctx, bytes.NewReader(make([]byte, 1<<10)))); !errors.Is(err, context.Canceled) {
+		t.Errorf("second transfer under a spent Total: %v, want context.Canceled", err)
+	}
+}
+
+func TestWaitCanceled(t *testing.T) {
+	l := throttle.NewLimiter(100)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx, 50); err != nil {
+		t.Errorf("wait within the burst: %v", err)
+	}
+	if err := l.Wait(ctx, 1000); !errors.Is(err, context.Canceled) {
+		t.Errorf("wait past the burst: %v, want context.Canceled", err)
+	}
+}
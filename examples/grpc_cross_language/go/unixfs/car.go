@@ -0,0 +1,57 @@
+package unixfs
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"example.com/ipfs_kit_py/car"
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// WriteCAR builds the DAG of path with b and writes it to w as a CARv1
+// rooted at it. Blocks are spooled to a temporary file first, since the
+// header names the root, which is built last.
+func WriteCAR(w io.Writer, b Builder, path string) (Node, error) {
+	spool, err := os.CreateTemp("", "unixfs-*.car")
+	if err != nil {
+		return Node{}, err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	bw := bufio.NewWriter(spool)
+	onBlock := b.OnBlock
+	written := make(map[string]bool)
+	b.OnBlock = func(c cidutil.CID, data []byte) error {
+		if onBlock != nil {
+			if err := onBlock(c, data); err != nil {
+				return err
+			}
+		}
+		// Repeated chunks are stored once
+		if key := string(c.Bytes()); !written[key] {
+			written[key] = true
+			return car.WriteBlock(bw, c, data)
+		}
+		return nil
+	}
+	root, err := b.Add(path)
+	if err != nil {
+		return Node{}, err
+	}
+	if err := bw.Flush(); err != nil {
+		return Node{}, err
+	}
+
+	if err := car.WriteHeader(w, root.CID); err != nil {
+		return Node{}, err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return Node{}, err
+	}
+	if _, err := io.Copy(w, spool); err != nil {
+		return Node{}, err
+	}
+	return root, nil
+}
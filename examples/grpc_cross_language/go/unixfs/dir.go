@@ -0,0 +1,88 @@
+package unixfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// shardingThreshold is Kubo's HAMTShardingSize: directories whose links'
+// names and CIDs exceed it are sharded into a HAMT
+const shardingThreshold = 256 << 10
+
+// Entry is a named child of a directory
+type Entry struct {
+	Name string
+	Node Node
+}
+
+// Directory builds a directory node over entries, sorted by name as
+// dag-pb requires, or a HAMT of them where Kubo would shard the directory
+func (b *Builder) Directory(entries []Entry) (Node, error) {
+	entries = append([]Entry(nil), entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	links := make([]link, len(entries))
+	estimate := 0
+	for i, e := range entries {
+		if i > 0 && e.Name == entries[i-1].Name {
+			return Node{}, fmt.Errorf("unixfs: duplicate directory entry %q", e.Name)
+		}
+		links[i] = link{cid: e.Node.CID, name: e.Name, size: e.Node.Size}
+		estimate += len(e.Name) + len(e.Node.CID.Bytes())
+	}
+	if estimate > shardingThreshold {
+		return b.shardedDirectory(links)
+	}
+	return b.pbNode(links, unixfsData(typeDirectory, nil, nil, nil), 0)
+}
+
+// Symlink builds a symlink node pointing at target
+func (b *Builder) Symlink(target string) (Node, error) {
+	return b.pbNode(nil, unixfsData(typeSymlink, []byte(target), nil, nil), 0)
+}
+
+// Add builds the DAG of a file, symlink or directory tree on disk, as
+// `ipfs add -r` does. Dot files are skipped unless Hidden is set.
+func (b *Builder) Add(path string) (Node, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return Node{}, err
+	}
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return Node{}, err
+		}
+		return b.Symlink(target)
+
+	case fi.IsDir():
+		names, err := os.ReadDir(path)
+		if err != nil {
+			return Node{}, err
+		}
+		var entries []Entry
+		for _, d := range names {
+			if !b.Hidden && strings.HasPrefix(d.Name(), ".") {
+				continue
+			}
+			n, err := b.Add(filepath.Join(path, d.Name()))
+			if err != nil {
+				return Node{}, err
+			}
+			entries = append(entries, Entry{Name: d.Name(), Node: n})
+		}
+		return b.Directory(entries)
+
+	case fi.Mode().IsRegular():
+		f, err := os.Open(path)
+		if err != nil {
+			return Node{}, err
+		}
+		defer f.Close()
+		return b.File(f)
+	}
+	return Node{}, fmt.Errorf("unixfs: %s is not a regular file, directory or symlink", path)
+}
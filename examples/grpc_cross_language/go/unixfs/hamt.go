@@ -0,0 +1,141 @@
+package unixfs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// HAMT parameters of Kubo's sharded directories: 256 buckets per shard,
+// keyed by the murmur3-x64-64 hash of the entry name, 8 bits per level
+const (
+	hamtFanout   = 256
+	hamtHashType = 0x22 // the murmur3-x64-64 multicodec
+)
+
+// hamtEntry is a directory link with the hash of its name
+type hamtEntry struct {
+	hash []byte
+	link link
+}
+
+// shard is a HAMT node. A bucket holds one entry, or a shard one level
+// down for entries whose hashes agree so far.
+type shard struct {
+	depth   int
+	entries map[int]hamtEntry
+	shards  map[int]*shard
+}
+
+func newShard(depth int) *shard {
+	return &shard{depth: depth, entries: map[int]hamtEntry{}, shards: map[int]*shard{}}
+}
+
+// add inserts e. Only insertion is supported, so the result is the one
+// go-unixfs reaches whatever the order of insertion.
+func (s *shard) add(e hamtEntry) error {
+	if s.depth >= len(e.hash) {
+		return fmt.Errorf("unixfs: HAMT shards run out of hash bits for %q", e.link.name)
+	}
+	i := int(e.hash[s.depth])
+	if sub, ok := s.shards[i]; ok {
+		return sub.add(e)
+	}
+	prev, ok := s.entries[i]
+	if !ok {
+		s.entries[i] = e
+		return nil
+	}
+	delete(s.entries, i)
+	sub := newShard(s.depth + 1)
+	s.shards[i] = sub
+	if err := sub.add(prev); err != nil {
+		return err
+	}
+	return sub.add(e)
+}
+
+// shardedDirectory builds the HAMT of a directory too large for one node
+func (b *Builder) shardedDirectory(links []link) (Node, error) {
+	root := newShard(0)
+	for _, l := range links {
+		if err := root.add(hamtEntry{hash: murmur3x64_64([]byte(l.name)), link: l}); err != nil {
+			return Node{}, err
+		}
+	}
+	return b.shardNode(root)
+}
+
+// shardNode builds a shard's node, its shards first. Links are named by
+// bucket as two hex digits, followed by the entry name for entries, and
+// the Data field is a bitfield of the buckets in use.
+func (b *Builder) shardNode(s *shard) (Node, error) {
+	var links []link
+	var bitfield [hamtFanout / 8]byte
+	for i := 0; i < hamtFanout; i++ {
+		prefix := fmt.Sprintf("%02X", i)
+		if sub, ok := s.shards[i]; ok {
+			n, err := b.shardNode(sub)
+			if err != nil {
+				return Node{}, err
+			}
+			links = append(links, link{cid: n.CID, name: prefix, size: n.Size})
+		} else if e, ok := s.entries[i]; ok {
+			links = append(links, link{cid: e.link.cid, name: prefix + e.link.name, size: e.link.size})
+		} else {
+			continue
+		}
+		// go-bitfield's layout: bit i in byte len-1-i/8, leading zero
+		// bytes dropped
+		bitfield[len(bitfield)-1-i/8] |= 1 << (i % 8)
+	}
+	used := bitfield[:]
+	for len(used) > 0 && used[0] == 0 {
+		used = used[1:]
+	}
+	data := unixfsData(typeHAMTShard, used, nil, nil)
+	data = appendVarintField(data, 5, hamtHashType)
+	data = appendVarintField(data, 6, hamtFanout)
+	return b.pbNode(links, data, 0)
+}
+
+// murmur3x64_64 returns the first 64 bits of MurmurHash3's x64 128-bit
+// hash with seed 0, big-endian, as go-unixfs's HAMT hashes names
+func murmur3x64_64(data []byte) []byte {
+	const c1, c2 = 0x87c37b91114253d5, 0x4cf5ad432745937f
+	var h1, h2 uint64
+	length := uint64(len(data))
+	for ; len(data) >= 16; data = data[16:] {
+		k1 := binary.LittleEndian.Uint64(data)
+		k2 := binary.LittleEndian.Uint64(data[8:])
+		h1 ^= bits.RotateLeft64(k1*c1, 31) * c2
+		h1 = (bits.RotateLeft64(h1, 27)+h2)*5 + 0x52dce729
+		h2 ^= bits.RotateLeft64(k2*c2, 33) * c1
+		h2 = (bits.RotateLeft64(h2, 31)+h1)*5 + 0x38495ab5
+	}
+	var tail [16]byte
+	copy(tail[:], data)
+	if len(data) > 8 {
+		h2 ^= bits.RotateLeft64(binary.LittleEndian.Uint64(tail[8:])*c2, 33) * c1
+	}
+	if len(data) > 0 {
+		h1 ^= bits.RotateLeft64(binary.LittleEndian.Uint64(tail[:8])*c1, 31) * c2
+	}
+
+	h1 ^= length
+	h2 ^= length
+	h1 += h2
+	h2 += h1
+	h1, h2 = fmix64(h1), fmix64(h2)
+	h1 += h2
+	return binary.BigEndian.AppendUint64(nil, h1)
+}
+
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
@@ -0,0 +1,63 @@
+package unixfs
+
+import (
+	"encoding/binary"
+
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// UnixFS node types, the Data.Type field
+const (
+	typeDirectory = 1
+	typeFile      = 2
+	typeSymlink   = 4
+	typeHAMTShard = 5
+)
+
+// link is a dag-pb link; Kubo always writes Name, even when empty
+type link struct {
+	cid  cidutil.CID
+	name string
+	size uint64
+}
+
+// unixfsData encodes a UnixFS Data message: Type (1), Data (2), filesize
+// (3) and unpacked blocksizes (4). Empty data and a nil filesize are
+// omitted, as go-unixfs does.
+func unixfsData(typ uint64, data []byte, filesize *uint64, blocksizes []uint64) []byte {
+	buf := appendVarintField(nil, 1, typ)
+	if len(data) > 0 {
+		buf = appendBytesField(buf, 2, data)
+	}
+	if filesize != nil {
+		buf = appendVarintField(buf, 3, *filesize)
+	}
+	for _, s := range blocksizes {
+		buf = appendVarintField(buf, 4, s)
+	}
+	return buf
+}
+
+// encodePBNode encodes a dag-pb PBNode: Links (2) before Data (1), each
+// PBLink as Hash (1), Name (2) and Tsize (3)
+func encodePBNode(links []link, data []byte) []byte {
+	var buf []byte
+	for _, l := range links {
+		pl := appendBytesField(nil, 1, l.cid.Bytes())
+		pl = appendBytesField(pl, 2, []byte(l.name))
+		pl = appendVarintField(pl, 3, l.size)
+		buf = appendBytesField(buf, 2, pl)
+	}
+	return appendBytesField(buf, 1, data)
+}
+
+func appendVarintField(buf []byte, field, v uint64) []byte {
+	buf = binary.AppendUvarint(buf, field<<3)
+	return binary.AppendUvarint(buf, v)
+}
+
+func appendBytesField(buf []byte, field uint64, b []byte) []byte {
+	buf = binary.AppendUvarint(buf, field<<3|2)
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
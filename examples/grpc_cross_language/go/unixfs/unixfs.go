@@ -0,0 +1,196 @@
+// Package unixfs builds UnixFS DAGs locally the way `ipfs add` does, so
+// the Go client knows a file's or directory's CID, and can write its CAR,
+// before anything is uploaded.
+//
+// With matching options the CIDs equal Kubo's: the balanced layout with
+// 174 links per node, Kubo's chunkers, dag-pb nodes and optional raw
+// leaves, and HAMT sharding of directories past Kubo's size threshold.
+package unixfs
+
+import (
+	"fmt"
+	"io"
+
+	"example.com/ipfs_kit_py/chunker"
+	"example.com/ipfs_kit_py/cidutil"
+)
+
+// DefaultMaxLinks is the fan-out of Kubo's balanced layout
+const DefaultMaxLinks = 174
+
+// Builder turns content into UnixFS DAGs. The zero value matches
+// `ipfs add` with CIDv0; NewBuilder sets Kubo's defaults for a CID version.
+type Builder struct {
+	CIDVersion int
+	// RawLeaves stores file chunks as raw blocks rather than dag-pb nodes.
+	// Kubo enables it with CIDv1.
+	RawLeaves bool
+	// HashFunc names the multihash, as kubo.AddOptions.HashFunc
+	// (sha2-256 if empty); non-default hashes imply CIDv1
+	HashFunc string
+	// Chunker is a Kubo --chunker spec ("" for size-262144)
+	Chunker string
+	// MaxLinks bounds links per file node (DefaultMaxLinks if <= 0)
+	MaxLinks int
+	// Hidden includes dot files when adding directories
+	Hidden bool
+	// OnBlock, if set, receives every block as it is built, children
+	// before parents
+	OnBlock func(c cidutil.CID, data []byte) error
+}
+
+// NewBuilder returns a Builder with Kubo's defaults for cidVersion
+func NewBuilder(cidVersion int) *Builder {
+	return &Builder{CIDVersion: cidVersion, RawLeaves: cidVersion == 1}
+}
+
+// Node is a built DAG
+type Node struct {
+	CID cidutil.CID
+	// Size is the encoded size of the whole DAG, the Tsize of links to it
+	Size uint64
+	// FileSize is the content size of a file, 0 for directories
+	FileSize uint64
+}
+
+// File builds the DAG of the content read from r
+func (b *Builder) File(r io.Reader) (Node, error) {
+	split, err := chunker.FromString(r, b.Chunker)
+	if err != nil {
+		return Node{}, err
+	}
+	maxLinks := b.MaxLinks
+	if maxLinks <= 0 {
+		maxLinks = DefaultMaxLinks
+	}
+
+	// levels[i] holds nodes of height i waiting for a parent; a full level
+	// is carried into the one above, giving the balanced layout's tree
+	var levels [][]Node
+	add := func(level int, n Node) error {
+		for {
+			if level == len(levels) {
+				levels = append(levels, nil)
+			}
+			levels[level] = append(levels[level], n)
+			if len(levels[level]) < maxLinks {
+				return nil
+			}
+			parent, err := b.fileNode(levels[level])
+			if err != nil {
+				return err
+			}
+			levels[level] = levels[level][:0]
+			n, level = parent, level+1
+		}
+	}
+
+	leaves := 0
+	err = chunker.Split(split, func(_ int64, chunk []byte) error {
+		leaves++
+		leaf, err := b.leaf(chunk)
+		if err != nil {
+			return err
+		}
+		return add(0, leaf)
+	})
+	if err != nil {
+		return Node{}, err
+	}
+	if leaves == 0 {
+		// An empty file is a single empty leaf
+		leaf, err := b.leaf(nil)
+		if err != nil {
+			return Node{}, err
+		}
+		levels = [][]Node{{leaf}}
+	}
+
+	for i := 0; ; i++ {
+		if i == len(levels)-1 && len(levels[i]) == 1 {
+			return levels[i][0], nil
+		}
+		if len(levels[i]) == 0 {
+			continue
+		}
+		parent, err := b.fileNode(levels[i])
+		if err != nil {
+			return Node{}, err
+		}
+		levels[i] = nil
+		if i+1 == len(levels) {
+			levels = append(levels, nil)
+		}
+		levels[i+1] = append(levels[i+1], parent)
+	}
+}
+
+// leaf stores one chunk as a raw block or a UnixFS file node
+func (b *Builder) leaf(chunk []byte) (Node, error) {
+	if b.RawLeaves {
+		c, err := b.cid(cidutil.Raw, chunk)
+		if err != nil {
+			return Node{}, err
+		}
+		return Node{CID: c, Size: uint64(len(chunk)), FileSize: uint64(len(chunk))}, b.emit(c, chunk)
+	}
+	size := uint64(len(chunk))
+	return b.pbNode(nil, unixfsData(typeFile, chunk, &size, nil), size)
+}
+
+// fileNode links children under a UnixFS file node recording their sizes
+func (b *Builder) fileNode(children []Node) (Node, error) {
+	links := make([]link, len(children))
+	blocksizes := make([]uint64, len(children))
+	var size uint64
+	for i, c := range children {
+		links[i] = link{cid: c.CID, size: c.Size}
+		blocksizes[i] = c.FileSize
+		size += c.FileSize
+	}
+	return b.pbNode(links, unixfsData(typeFile, nil, &size, blocksizes), size)
+}
+
+// pbNode encodes and emits a dag-pb node
+func (b *Builder) pbNode(links []link, data []byte, fileSize uint64) (Node, error) {
+	block := encodePBNode(links, data)
+	c, err := b.cid(cidutil.DagPB, block)
+	if err != nil {
+		return Node{}, err
+	}
+	size := uint64(len(block))
+	for _, l := range links {
+		size += l.size
+	}
+	return Node{CID: c, Size: size, FileSize: fileSize}, b.emit(c, block)
+}
+
+// cid hashes a block. Raw blocks and non-default hashes have no CIDv0
+// form, so they are CIDv1 whatever CIDVersion says, as in Kubo.
+func (b *Builder) cid(codec uint64, block []byte) (cidutil.CID, error) {
+	code := uint64(cidutil.SHA2_256)
+	if b.HashFunc != "" {
+		var err error
+		if code, err = cidutil.HashCode(b.HashFunc); err != nil {
+			return cidutil.CID{}, err
+		}
+	}
+	mh, err := cidutil.Sum(code, block)
+	if err != nil {
+		return cidutil.CID{}, err
+	}
+	if b.CIDVersion == 0 && codec == cidutil.DagPB && code == cidutil.SHA2_256 {
+		return cidutil.NewCIDv0(mh)
+	}
+	if b.CIDVersion != 0 && b.CIDVersion != 1 {
+		return cidutil.CID{}, fmt.Errorf("unixfs: unsupported CID version %d", b.CIDVersion)
+	}
+	return cidutil.NewCIDv1(codec, mh), nil
+}
+
+func (b *Builder) emit(c cidutil.CID, block []byte) error {
+	if b.OnBlock == nil {
+		return nil
+	}
+	return b.OnBlock(c, block)
+}
@@ -0,0 +1,223 @@
+package unixfs_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"example.com/ipfs_kit_py/cidutil"
+	"example.com/ipfs_kit_py/unixfs"
+)
+
+// seededData returns the bytes go-ipfs-util's NewSeededRand(seed) reads,
+// the test data of Kubo's importer tests
+func seededData(seed int64, n int) []byte {
+	r := rand.New(rand.NewSource(seed))
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(r.Intn(255))
+	}
+	return b
+}
+
+// added is the root `ipfs add --cid-version=<version> --raw-leaves=<raw>`
+// gives a file, and the size of its DAG
+type added struct {
+	version int
+	raw     bool
+	cid     string
+	size    uint64
+}
+
+type fileVector struct {
+	name    string
+	data    []byte
+	chunker string
+	added   []added
+}
+
+func checkFiles(t *testing.T, tests []fileVector) {
+	t.Helper()
+	for _, tt := range tests {
+		for _, a := range tt.added {
+			b := unixfs.NewBuilder(a.version)
+			b.RawLeaves = a.raw
+			b.Chunker = tt.chunker
+			n, err := b.File(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("%s, CIDv%d raw leaves %v: %v", tt.name, a.version, a.raw, err)
+			}
+			if n.CID.String() != a.cid || n.Size != a.size || n.FileSize != uint64(len(tt.data)) {
+				t.Errorf("%s, CIDv%d raw leaves %v: %s of %d bytes (file %d), want %s of %d",
+					tt.name, a.version, a.raw, n.CID, n.Size, n.FileSize, a.cid, a.size)
+			}
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	data := seededData(0xdeadbeef, 10<<20)
+	checkFiles(t, []fileVector{
+		{"empty", nil, "", []added{
+			{0, false, "QmbFMke1KXqnYyBBWxB74N4c5SBnJMVAiMNRcGu6x1AwQH", 6},
+			{0, true, "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku", 0},
+			{1, false, "bafybeif7ztnhq65lumvvtr4ekcwd2ifwgm3awq4zfr3srh462rwyinlb4y", 6},
+			{1, true, "bafkreihdwdcefgh4dqkjv67uzcmw7ojee6xedzdetojuzjevtenxquvyku", 0},
+		}},
+		{"hello world", []byte("hello world\n"), "", []added{
+			{0, false, "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o", 20},
+			{0, true, "bafkreifjjcie6lypi6ny7amxnfftagclbuxndqonfipmb64f2km2devei4", 12},
+			{1, false, "bafybeicg2rebjoofv4kbyovkw7af3rpiitvnl6i7ckcywaq6xjcxnc2mby", 20},
+			{1, true, "bafkreifjjcie6lypi6ny7amxnfftagclbuxndqonfipmb64f2km2devei4", 12},
+		}},
+		{"one chunk", data[:256<<10], "", []added{
+			{0, false, "QmZsarAkLHgRn8Zux5qbHgDHsTktougoFnC98hw1bza6ks", 262158},
+			{0, true, "bafkreidixxuzul3u4pxgtgplq7vejxfd2usyd7a7lxxxwj23a5fc3xmfhu", 262144},
+			{1, false, "bafybeiflluzbjpypbe65qxvpb54dllh5bflthdsnfqbvc3way3xtuvaptq", 262158},
+			{1, true, "bafkreidixxuzul3u4pxgtgplq7vejxfd2usyd7a7lxxxwj23a5fc3xmfhu", 262144},
+		}},
+		{"one chunk and a byte", data[:256<<10+1], "", []added{
+			{0, false, "QmSwSgWgsbRHBZjkofhN5vgRmmnpBTiwHN7fWEC4PJ3vWV", 262267},
+			{0, true, "QmVF137TzNAxZbLgqiGck7JqiSRyTtR3GhPqWme8Wdey5y", 262249},
+			{1, false, "bafybeic27kg4ddzowxccvp3n4jgai7cmlhpx7yeb5bn646wxa5kydjm4re", 262271},
+			{1, true, "bafybeidgr5hool3wqripz54ezp7nkz6qendowgtfzuoh3xqmiqbt3frqna", 262249},
+		}},
+		// go-unixfs's TestStableCid and go-unixfsnode's TestBuildUnixFSFile
+		{"10 MiB", data, "", []added{
+			{0, false, "QmZN1qquw84zhV4j6vT56tCcmFxaDaySL1ezTXFvMdNmrK", 10488250},
+			{0, true, "QmYcpMhk8XXUXC9qq1NYMRPeES9UotwEh7saj5ogFHHERH", 10487770},
+			{1, false, "bafybeihzylkmud2iixa7wanckwg7x3knxlkw5eshchkc4wpcj5cgxzvium", 10488330},
+			{1, true, "bafybeieyxejezqto5xwcxtvh5tskowwxrn3hmbk3hcgredji3g7abtnfkq", 10487770},
+		}},
+		// 175 and 174*174+1 chunks, one more than the levels below hold;
+		// the balanced layout gives the last chunk a parent of its own
+		{"two levels", data[:174*1024+1], "size-1024", []added{
+			{0, false, "QmQkbLGamYvow98cWcTrZPX7qdcMwrqew6safhpyx9F2Pb", 188262},
+			{0, true, "QmV93aSiqKowNAPexbMy9WjpPW5PwvQrABiuHdi28KQ74Q", 186690},
+			{1, false, "bafybeig57jmaesppv73jdkwukq43kfw6kkuu24dda3yir2rpufzgpwsjte", 188616},
+			{1, true, "bafybeiakocr4ljzbr5gnpgbqypgtvepum3t5txwjfwljc5vb35dyxdjg6q", 186694},
+		}},
+		{"three levels", data[:174*174*16+1], "size-16", []added{
+			{0, false, "QmTwDCpvQZ5hE4MVv3c8FxbwMZVr9bzJTFjGVu3uwNWSKH", 2068382},
+			{0, true, "QmRmHvoNg98kBvGbArJY5J77t4aehUvcVEiYNfaAwyUiG1", 1886720},
+			{1, false, "bafybeigj7kh2wjsnpr5k2mgnuf4hbwmeczsqwjxhkg3zgn4e3f7x2y2srq", 2129291},
+			{1, true, "bafybeieccv6s4gax4jz5voioc6hlk4xtwqepl7ikhg5cc6ow52rigvsywq", 1887074},
+		}},
+	})
+}
+
+func TestFileChunkers(t *testing.T) {
+	data := seededData(0xdeadbeef, 1<<20)
+	checkFiles(t, []fileVector{
+		{"rabin", data, "rabin", []added{
+			{0, false, "QmPWvnuwng5r5XCMfEvvBs8j8t4ANGK9uvYYxyG1HvLBSQ", 1048832},
+			{1, true, "bafybeicbl6jyvqhsf5uueyyqrjir6zopozk524mk7623iv5dh3dmbcdm5i", 1048784},
+		}},
+		{"rabin-16-32-64", data[:1000], "rabin-16-32-64", []added{
+			{0, false, "QmUepco2w5CKyqGYkm8hsheTs43ny1efGkvwVmU3WbXJX1", 2203},
+			{1, true, "bafybeifqldafpmlbvdax7unmtv6oywh6qdeq3hsccjzakyyngmsmejfqm4", 2065},
+		}},
+		{"buzhash", data, "buzhash", []added{
+			{0, false, "QmZcUzJmmKKWEa2mULvftK14S7gQm3GB234RzkbkXQq38i", 1048894},
+			{1, true, "bafybeign4gds2zp2b6es4n2wprdy5xsdvzmsvoqjbozgh2isaw6p443vsa", 1048834},
+		}},
+	})
+}
+
+// files builds a file of each name, with the name as its content
+func files(t *testing.T, b *unixfs.Builder, names ...string) []unixfs.Entry {
+	t.Helper()
+	entries := make([]unixfs.Entry, len(names))
+	for i, name := range names {
+		n, err := b.File(bytes.NewReader([]byte(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[i] = unixfs.Entry{Name: name, Node: n}
+	}
+	return entries
+}
+
+func TestDirectory(t *testing.T) {
+	for version, want := range []string{
+		"QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn",
+		"bafybeiczsscdsbs7ffqz55asqdf3smv6klcw3gofszvwlyarci47bgf354",
+	} {
+		n, err := unixfs.NewBuilder(version).Directory(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.CID.String() != want || n.Size != 4 {
+			t.Errorf("empty directory, CIDv%d: %s of %d bytes, want %s of 4", version, n.CID, n.Size, want)
+		}
+	}
+
+	// go-unixfsnode's TestBuildUnixFSRecursive tree
+	b := unixfs.NewBuilder(1)
+	file := func(content string) unixfs.Node {
+		n, err := b.File(bytes.NewReader([]byte(content)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	sub, err := b.Directory([]unixfs.Entry{{Name: "2", Node: file("222")}, {Name: "1", Node: file("111")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "bafybeibohj54uixf2mso4t53suyarv6cfuxt6b5cj6qjsqaa2ezfxnu5pu"; sub.CID.String() != want {
+		t.Errorf("directory b is %s, want %s", sub.CID, want)
+	}
+	root, err := b.Directory([]unixfs.Entry{{Name: "a", Node: file("aaa")}, {Name: "b", Node: sub}, {Name: "c", Node: file("ccc")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "bafybeihswl3f7pa7fueyayewcvr3clkdz7oetv4jolyejgw26p6l3qzlbm"; root.CID.String() != want || root.Size != 245 {
+		t.Errorf("root is %s of %d bytes, want %s of 245", root.CID, root.Size, want)
+	}
+	if _, err := b.Directory([]unixfs.Entry{{Name: "a", Node: sub}, {Name: "a", Node: sub}}); err == nil {
+		t.Error("duplicate entry accepted")
+	}
+}
+
+// TestShardedDirectory checks directories either side of Kubo's sharding
+// threshold, with go-unixfsnode's TestBuildUnixFSRecursiveLargeSharded
+// entries: the CIDv1 roots are that test's
+func TestShardedDirectory(t *testing.T) {
+	names := make([]string, 1357)
+	for i := range names {
+		names[i] = fmt.Sprintf("long name to fill out bytes to make the sharded directory test flip over the sharded directory limit because link names are included in the directory entry %d", i)
+	}
+	tests := []struct {
+		version int
+		entries int
+		cid     string
+		size    uint64
+	}{
+		{1, 1343, "bafybeihecq4rpl4nw3cgfb2uiwltgsmw5sutouvuldv5fxn4gfbihvnalq", 490665},
+		{1, 1344, "bafybeigyvxs6og5jbmpaa43qbhhd5swklqcfzqdrtjgfh53qjon6hpjaye", 515735},
+		// CIDv0 links are shorter, so more entries fit unsharded
+		{0, 1357, "QmPe1fnNzkbuWuAqGSsrHPvJd8wyCYsKWmUJ9mMsXr6gMc", 532362},
+	}
+	for _, tt := range tests {
+		b := unixfs.NewBuilder(tt.version)
+		blocks := 0
+		b.OnBlock = func(_ cidutil.CID, _ []byte) error {
+			blocks++
+			return nil
+		}
+		entries := files(t, b, names[:tt.entries]...)
+		blocks = 0
+		n, err := b.Directory(entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.CID.String() != tt.cid || n.Size != tt.size {
+			t.Errorf("%d entries, CIDv%d: %s of %d bytes, want %s of %d", tt.entries, tt.version, n.CID, n.Size, tt.cid, tt.size)
+		}
+		if sharded := blocks > 1; sharded != (tt.entries != 1343) {
+			t.Errorf("%d entries, CIDv%d: %d directory blocks", tt.entries, tt.version, blocks)
+		}
+	}
+}
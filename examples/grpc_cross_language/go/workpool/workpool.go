@@ -0,0 +1,258 @@
+// Package workpool runs bulk operations, such as routing a directory or
+// migrating many placements, on a bounded number of workers, and limits
+// how many of them use any one backend at once, so a large batch neither
+// spawns a goroutine per item nor piles onto a single backend. A Pool
+// reports its queue depth and how saturated its workers and backends are.
+package workpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by Go after Close
+var ErrClosed = errors.New("workpool: pool closed")
+
+// Config configures a Pool
+type Config struct {
+	// Workers is how many tasks run at once (default 4)
+	Workers int
+	// QueueSize is how many tasks may wait for a worker before Go blocks
+	// (default 2 * Workers)
+	QueueSize int
+	// PerBackend is how many tasks may use one backend at once, as
+	// claimed with Acquire; 0 leaves backends limited by Workers alone
+	PerBackend int
+	// Limits overrides PerBackend for particular backend IDs
+	Limits map[string]int
+}
+
+// Stats is a snapshot of a pool's load
+type Stats struct {
+	Workers int `json:"workers"`
+	// Queued is how many tasks are waiting for a worker, and MaxQueued
+	// the most that ever were
+	Queued    int `json:"queued"`
+	MaxQueued int `json:"max_queued"`
+	// Running is how many tasks are on a worker, including those waiting
+	// for a backend slot
+	Running   int    `json:"running"`
+	Completed uint64 `json:"completed"`
+	// Saturation is the fraction of the pool's life every worker was busy
+	Saturation float64 `json:"saturation"`
+	// Backends has the load of each backend a slot was claimed on
+	Backends map[string]BackendStats `json:"backends,omitempty"`
+}
+
+// BackendStats is the load of one backend
+type BackendStats struct {
+	// Limit is how many tasks may use the backend at once; 0 is no limit
+	Limit  int `json:"limit"`
+	Active int `json:"active"`
+	// Waiting is how many tasks are waiting for a slot, and Waited how
+	// many ever had to
+	Waiting int    `json:"waiting"`
+	Waited  uint64 `json:"waited"`
+}
+
+// Pool runs tasks on a fixed set of workers
+type Pool struct {
+	cfg   Config
+	tasks chan task
+	wg    sync.WaitGroup
+	start time.Time
+
+	// sending guards tasks against being closed while Go sends on it
+	sending sync.RWMutex
+	closed  bool
+
+	mu        sync.Mutex
+	queued    int
+	maxQueued int
+	running   int
+	completed uint64
+	busy      time.Duration
+	busySince time.Time
+	backends  map[string]*backend
+}
+
+type task struct {
+	ctx context.Context
+	fn  func(context.Context)
+}
+
+// backend holds the slots of one backend
+type backend struct {
+	slots   chan struct{}
+	waiting int
+	waited  uint64
+}
+
+// New starts a pool's workers. Close must be called to stop them.
+func New(cfg Config) *Pool {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 2 * cfg.Workers
+	}
+	p := &Pool{
+		cfg:      cfg,
+		tasks:    make(chan task, cfg.QueueSize),
+		start:    time.Now(),
+		backends: make(map[string]*backend),
+	}
+	p.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Go queues fn to run on a worker with ctx, blocking while the queue is
+// full. It fails with ctx's error if ctx ends first, or ErrClosed after
+// Close; fn is then never run. Once queued, fn runs even if ctx has ended
+// by the time a worker takes it, so it should check ctx itself.
+func (p *Pool) Go(ctx context.Context, fn func(ctx context.Context)) error {
+	p.sending.RLock()
+	defer p.sending.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+	p.mu.Lock()
+	p.queued++
+	p.maxQueued = max(p.maxQueued, p.queued)
+	p.mu.Unlock()
+	select {
+	case p.tasks <- task{ctx: ctx, fn: fn}:
+		return nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		p.queued--
+		p.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Close runs the tasks already queued and stops the workers once they
+// are done
+func (p *Pool) Close() {
+	p.sending.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.sending.Unlock()
+	p.wg.Wait()
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for t := range p.tasks {
+		p.mu.Lock()
+		p.queued--
+		p.running++
+		if p.running == p.cfg.Workers {
+			p.busySince = time.Now()
+		}
+		p.mu.Unlock()
+
+		t.fn(t.ctx)
+
+		p.mu.Lock()
+		if p.running == p.cfg.Workers {
+			p.busy += time.Since(p.busySince)
+		}
+		p.running--
+		p.completed++
+		p.mu.Unlock()
+	}
+}
+
+// Acquire waits for a slot on backendID, returning the func releasing it.
+// It fails with ctx's error if ctx ends first. A nil Pool, or a backend
+// without a limit, has a slot at once. A task holding a slot must not
+// claim another on the same backend, or it may wait on itself.
+func (p *Pool) Acquire(ctx context.Context, backendID string) (release func(), err error) {
+	if p == nil {
+		return func() {}, nil
+	}
+	b := p.backend(backendID)
+	if b.slots == nil {
+		return func() {}, nil
+	}
+	release = func() { <-b.slots }
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	p.mu.Lock()
+	b.waiting++
+	b.waited++
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		b.waiting--
+		p.mu.Unlock()
+	}()
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// backend returns backendID's slots, creating them
+func (p *Pool) backend(backendID string) *backend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b := p.backends[backendID]
+	if b == nil {
+		b = &backend{}
+		limit, ok := p.cfg.Limits[backendID]
+		if !ok {
+			limit = p.cfg.PerBackend
+		}
+		if limit > 0 {
+			b.slots = make(chan struct{}, limit)
+		}
+		p.backends[backendID] = b
+	}
+	return b
+}
+
+// Stats returns the pool's current load
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	busy := p.busy
+	if p.running == p.cfg.Workers {
+		busy += time.Since(p.busySince)
+	}
+	s := Stats{
+		Workers:   p.cfg.Workers,
+		Queued:    p.queued,
+		MaxQueued: p.maxQueued,
+		Running:   p.running,
+		Completed: p.completed,
+	}
+	if life := time.Since(p.start); life > 0 {
+		s.Saturation = min(float64(busy)/float64(life), 1)
+	}
+	if len(p.backends) > 0 {
+		s.Backends = make(map[string]BackendStats, len(p.backends))
+		for id, b := range p.backends {
+			s.Backends[id] = BackendStats{
+				Limit:   cap(b.slots),
+				Active:  len(b.slots),
+				Waiting: b.waiting,
+				Waited:  b.waited,
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,258 @@
+/**
+ * End-to-end pipeline example for the routing service (Node.js)
+ *
+ * Routes a piece of generated content, uploads and pins it in Kubo, links
+ * it into the knowledge graph, reads it back through a gateway and reports
+ * the outcome to the router. Exits non-zero if any stage fails.
+ *
+ * Routing uses gRPC when --server is given, otherwise the HTTP routing API
+ * at --routing-http.
+ *
+ * Prerequisites:
+ * 1. Node.js 18+ (for the built-in fetch)
+ * 2. Required npm packages:
+ *    npm install @grpc/grpc-js @grpc/proto-loader minimist
+ *
+ * To run this example:
+ *    node e2e.js --routing-http http://localhost:8081
+ */
+
+const crypto = require('crypto');
+const path = require('path');
+
+const argv = require('minimist')(process.argv.slice(2), {
+  string: ['server', 'routing-http', 'ipfs-api', 'gateway', 'kg'],
+  boolean: ['json'],
+  default: {
+    'ipfs-api': process.env.IPFS_API_URL || 'http://127.0.0.1:5001',
+    gateway: 'http://127.0.0.1:8080',
+    kg: 'http://localhost:8090',
+    size: 64 * 1024,
+  },
+});
+
+const trim = (url) => (url || '').replace(/\/+$/, '');
+const apiURL = trim(argv['ipfs-api']);
+const gatewayURL = trim(argv.gateway);
+const kgURL = trim(argv.kg);
+const routingHTTP = trim(argv['routing-http']);
+
+class StageError extends Error {}
+
+async function request(method, url, { body, headers } = {}) {
+  let resp;
+  try {
+    resp = await fetch(url, { method, body, headers });
+  } catch (err) {
+    throw new StageError(`${method} ${url}: ${err.cause ? err.cause.message : err.message}`);
+  }
+  if (!resp.ok) {
+    const detail = (await resp.text()).slice(0, 4096).trim();
+    throw new StageError(`${method} ${url}: HTTP ${resp.status}: ${detail}`);
+  }
+  return resp;
+}
+
+async function postJSON(url, payload, headers = {}) {
+  const resp = await request('POST', url, {
+    body: JSON.stringify(payload),
+    headers: { 'Content-Type': 'application/json', ...headers },
+  });
+  return resp.json();
+}
+
+// Calls the Kubo RPC API; body is an optional FormData
+async function kuboCall(command, params, body) {
+  const qs = new URLSearchParams(params).toString();
+  const resp = await request('POST', `${apiURL}/api/v0/${command}?${qs}`, { body });
+  // add streams one JSON object per line; the last one is the root
+  const lines = (await resp.text()).split('\n').filter((l) => l.trim());
+  return lines.length ? JSON.parse(lines[lines.length - 1]) : {};
+}
+
+// Router over gRPC (RoutingService) or the HTTP routing API
+function makeRouter() {
+  if (argv.server) {
+    const grpc = require('@grpc/grpc-js');
+    const protoLoader = require('@grpc/proto-loader');
+    const PROTO_PATH = path.resolve(__dirname, '../../../ipfs_kit_py/routing/protos/routing.proto');
+    const packageDefinition = protoLoader.loadSync(PROTO_PATH, {
+      keepCase: true,
+      longs: String,
+      enums: String,
+      defaults: true,
+      oneofs: true,
+    });
+    const RoutingService = grpc.loadPackageDefinition(packageDefinition).ipfs_kit_py.routing.RoutingService;
+    const client = new RoutingService(argv.server, grpc.credentials.createInsecure());
+    const call = (method, req) =>
+      new Promise((resolve, reject) => {
+        client[method](req, (err, resp) => (err ? reject(new StageError(err.message)) : resolve(resp)));
+      });
+    return {
+      async selectBackend(info) {
+        const resp = await call('selectBackend', { ...info, strategy: 'hybrid' });
+        return resp.backend_id;
+      },
+      async recordOutcome(info, outcome) {
+        await call('recordOutcome', { ...info, ...outcome });
+      },
+      close: () => client.close(),
+    };
+  }
+  if (routingHTTP) {
+    return {
+      async selectBackend(info) {
+        const resp = await postJSON(`${routingHTTP}/api/v1/select-backend`, {
+          content_type: info.content_type,
+          content_size: info.content_size,
+          strategy: 'hybrid',
+        });
+        return resp.backend;
+      },
+      async recordOutcome(info, outcome) {
+        await postJSON(`${routingHTTP}/api/v1/record-outcome`, {
+          backend: outcome.backend_id,
+          success: outcome.success,
+          duration_ms: outcome.duration_ms,
+          content_type: info.content_type,
+          content_size: info.content_size,
+        });
+      },
+      close: () => {},
+    };
+  }
+  return null;
+}
+
+async function main() {
+  const content = crypto.randomBytes(Number(argv.size));
+  const info = {
+    content_type: 'application/octet-stream',
+    content_size: content.length,
+    content_hash: crypto.createHash('sha256').update(content).digest('hex'),
+  };
+  const filename = `e2e-${Math.floor(Date.now() / 1000)}.bin`;
+
+  const router = makeRouter();
+  const steps = [];
+  let failed = false;
+  let backendID = '';
+  let cid = '';
+  let uploadMs = 0;
+
+  const step = async (name, fn) => {
+    if (failed) {
+      steps.push({ name, ok: false, detail: 'skipped' });
+      return;
+    }
+    const start = process.hrtime.bigint();
+    const result = { name, ok: true };
+    try {
+      result.detail = (await fn()) || '';
+    } catch (err) {
+      if (!(err instanceof StageError)) throw err;
+      result.ok = false;
+      result.error = err.message;
+      failed = true;
+    }
+    result.duration_ms = Number(process.hrtime.bigint() - start) / 1e6;
+    steps.push(result);
+  };
+
+  if (router) {
+    await step('route', async () => {
+      backendID = await router.selectBackend(info);
+      if (!backendID) throw new StageError('router returned no backend');
+      return backendID;
+    });
+  }
+  await step('upload', async () => {
+    const form = new FormData();
+    form.append('file', new Blob([content]), filename);
+    const start = Date.now();
+    const out = await kuboCall('add', { 'cid-version': '1', 'raw-leaves': 'true', pin: 'true' }, form);
+    uploadMs = Date.now() - start;
+    cid = out.Hash || '';
+    if (!cid) throw new StageError(`add returned no hash: ${JSON.stringify(out)}`);
+    return cid;
+  });
+  await step('pin', async () => {
+    const out = await kuboCall('pin/ls', { arg: cid, type: 'recursive' });
+    if (!(out.Keys && out.Keys[cid])) throw new StageError(`${cid} is not pinned`);
+    return 'recursive';
+  });
+  if (kgURL) {
+    await step('link', async () => {
+      const runID = `e2e-run:${info.content_hash.slice(0, 16)}`;
+      const contentID = `content:${cid}`;
+      // writes need the API's token once it has one
+      const auth = process.env.KG_API_TOKEN ? { Authorization: `Bearer ${process.env.KG_API_TOKEN}` } : {};
+      await postJSON(`${kgURL}/api/v1/kg/entities`, {
+        id: runID,
+        type: 'e2e_run',
+        properties: { backend: backendID },
+      }, auth);
+      try {
+        await postJSON(`${kgURL}/api/v1/kg/entities`, {
+          id: contentID,
+          type: 'content',
+          properties: { cid, name: filename },
+        }, auth);
+      } catch (err) {
+        if (!err.message.includes('HTTP 409')) throw err;
+      }
+      const rel = await postJSON(`${kgURL}/api/v1/kg/relationships`, {
+        from: runID,
+        to: contentID,
+        type: 'uploaded',
+      }, auth);
+      const out = await postJSON(`${kgURL}/api/v1/kg/query`, {
+        op: 'neighbors',
+        entity_id: runID,
+        relationship_type: 'uploaded',
+        direction: 'outgoing',
+      });
+      if (!(out.neighbors || []).some((n) => n.entity_id === contentID)) {
+        throw new StageError(`relationship ${rel.relationship_id} not returned by neighbor query`);
+      }
+      return rel.relationship_id;
+    });
+  }
+  await step('retrieve', async () => {
+    const resp = await request('GET', `${gatewayURL}/ipfs/${cid}`);
+    const got = Buffer.from(await resp.arrayBuffer());
+    if (!got.equals(content)) {
+      throw new StageError(`retrieved ${got.length} bytes that do not match the ${content.length} uploaded`);
+    }
+    return `${got.length} bytes from ${gatewayURL}`;
+  });
+  if (router && backendID) {
+    // Report the measured upload even if a later stage failed
+    failed = false;
+    await step('record-outcome', async () => {
+      await router.recordOutcome(info, { backend_id: backendID, success: cid !== '', duration_ms: uploadMs });
+      return '';
+    });
+  }
+  if (router) router.close();
+
+  const ok = !steps.some((s) => s.error);
+  if (argv.json) {
+    console.log(JSON.stringify({ ok, cid, steps }, null, 2));
+  } else {
+    for (const s of steps) {
+      const status = s.error ? 'FAIL' : s.ok ? 'OK' : 'SKIP';
+      const ms = (s.duration_ms || 0).toFixed(1).padStart(8);
+      console.log(`${s.name.padEnd(16)} ${status.padEnd(5)} ${ms}ms  ${s.detail || ''}${s.error || ''}`.trimEnd());
+    }
+  }
+  return ok ? 0 : 1;
+}
+
+main()
+  .then((code) => process.exit(code))
+  .catch((err) => {
+    console.error(`e2e: ${err.stack || err}`);
+    process.exit(1);
+  });
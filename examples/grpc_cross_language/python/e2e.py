@@ -0,0 +1,226 @@
+#!/usr/bin/env python3
+"""
+End-to-end pipeline example for the routing service (Python).
+
+Routes a piece of generated content, uploads and pins it in Kubo, links it
+into the knowledge graph, reads it back through a gateway and reports the
+outcome to the router. Exits non-zero if any stage fails.
+
+Only the standard library is used, so this runs without installing
+ipfs_kit_py. Routing goes through the HTTP routing API
+(ipfs_kit_py/routing/http_server.py).
+
+Usage:
+    python3 e2e.py --routing-http http://localhost:8081
+"""
+
+import argparse
+import hashlib
+import json
+import os
+import sys
+import time
+import urllib.error
+import urllib.parse
+import urllib.request
+import uuid
+
+
+class StageError(Exception):
+    """A stage of the pipeline failed"""
+
+
+def request(method, url, body=None, headers=None, timeout=30):
+    """Perform an HTTP request and return (status, body bytes)"""
+    req = urllib.request.Request(url, data=body, method=method, headers=headers or {})
+    try:
+        with urllib.request.urlopen(req, timeout=timeout) as resp:
+            return resp.status, resp.read()
+    except urllib.error.HTTPError as e:
+        detail = e.read()[:4096].decode("utf-8", "replace").strip()
+        raise StageError(f"{method} {url}: HTTP {e.code}: {detail}") from None
+    except urllib.error.URLError as e:
+        raise StageError(f"{method} {url}: {e.reason}") from None
+
+
+def post_json(url, payload, timeout=30, headers=None):
+    _, data = request(
+        "POST", url, json.dumps(payload).encode(), {"Content-Type": "application/json", **(headers or {})}, timeout
+    )
+    return json.loads(data or b"{}")
+
+
+def kubo_call(api_url, command, params, body=None, timeout=60):
+    """Call the Kubo RPC API; body is an optional multipart (content_type, bytes) pair"""
+    url = f"{api_url}/api/v0/{command}?{urllib.parse.urlencode(params)}"
+    headers = {}
+    payload = b""
+    if body is not None:
+        headers["Content-Type"], payload = body
+    _, data = request("POST", url, payload, headers, timeout)
+    # add streams one JSON object per line; the last one is the root
+    lines = [line for line in data.splitlines() if line.strip()]
+    return json.loads(lines[-1]) if lines else {}
+
+
+def multipart(name, filename, content):
+    boundary = uuid.uuid4().hex
+    head = (
+        f"--{boundary}\r\n"
+        f'Content-Disposition: form-data; name="{name}"; filename="{filename}"\r\n'
+        "Content-Type: application/octet-stream\r\n\r\n"
+    ).encode()
+    tail = f"\r\n--{boundary}--\r\n".encode()
+    return f"multipart/form-data; boundary={boundary}", head + content + tail
+
+
+def main():
+    parser = argparse.ArgumentParser(description="Run the end-to-end routing pipeline")
+    parser.add_argument("--routing-http", default="", help="HTTP routing API base URL (empty to skip routing)")
+    parser.add_argument(
+        "--ipfs-api",
+        default=os.environ.get("IPFS_API_URL", "http://127.0.0.1:5001"),
+        help="Kubo RPC API URL",
+    )
+    parser.add_argument("--gateway", default="http://127.0.0.1:8080", help="IPFS gateway used to verify retrieval")
+    parser.add_argument("--kg", default="http://localhost:8090", help="knowledge graph API base URL (empty to skip)")
+    parser.add_argument("--size", type=int, default=64 << 10, help="size of the generated content in bytes")
+    parser.add_argument("--json", action="store_true", help="print results as JSON")
+    args = parser.parse_args()
+
+    routing = args.routing_http.rstrip("/")
+    api = args.ipfs_api.rstrip("/")
+    gateway = args.gateway.rstrip("/")
+    kg = args.kg.rstrip("/")
+
+    content = os.urandom(args.size)
+    content_hash = hashlib.sha256(content).hexdigest()
+    filename = f"e2e-{int(time.time())}.bin"
+    content_type = "application/octet-stream"
+
+    steps = []
+    state = {"failed": False, "backend": "", "cid": "", "upload_ms": 0}
+
+    def step(name, fn):
+        if state["failed"]:
+            steps.append({"name": name, "ok": False, "detail": "skipped"})
+            return
+        start = time.monotonic()
+        result = {"name": name, "ok": True}
+        try:
+            result["detail"] = fn() or ""
+        except StageError as e:
+            result.update(ok=False, error=str(e))
+            state["failed"] = True
+        result["duration_ms"] = round((time.monotonic() - start) * 1000, 1)
+        steps.append(result)
+
+    def route():
+        resp = post_json(
+            f"{routing}/api/v1/select-backend",
+            {"content_type": content_type, "content_size": len(content), "strategy": "hybrid"},
+        )
+        state["backend"] = resp.get("backend", "")
+        if not state["backend"]:
+            raise StageError(f"no backend in response: {resp}")
+        return state["backend"]
+
+    def upload():
+        start = time.monotonic()
+        out = kubo_call(
+            api,
+            "add",
+            {"cid-version": "1", "raw-leaves": "true", "pin": "true"},
+            multipart("file", filename, content),
+        )
+        state["upload_ms"] = int((time.monotonic() - start) * 1000)
+        state["cid"] = out.get("Hash", "")
+        if not state["cid"]:
+            raise StageError(f"add returned no hash: {out}")
+        return state["cid"]
+
+    def pin():
+        cid = state["cid"]
+        out = kubo_call(api, "pin/ls", {"arg": cid, "type": "recursive"})
+        if cid not in out.get("Keys", {}):
+            raise StageError(f"{cid} is not pinned")
+        return "recursive"
+
+    def link():
+        cid = state["cid"]
+        run_id = f"e2e-run:{content_hash[:16]}"
+        content_id = f"content:{cid}"
+        # writes need the API's token once it has one
+        token = os.environ.get("KG_API_TOKEN")
+        auth = {"Authorization": f"Bearer {token}"} if token else None
+        post_json(
+            f"{kg}/api/v1/kg/entities",
+            {"id": run_id, "type": "e2e_run", "properties": {"backend": state["backend"]}},
+            headers=auth,
+        )
+        try:
+            post_json(
+                f"{kg}/api/v1/kg/entities",
+                {"id": content_id, "type": "content", "properties": {"cid": cid, "name": filename}},
+                headers=auth,
+            )
+        except StageError as e:
+            if "HTTP 409" not in str(e):
+                raise
+        rel = post_json(
+            f"{kg}/api/v1/kg/relationships",
+            {"from": run_id, "to": content_id, "type": "uploaded"},
+            headers=auth,
+        )
+        out = post_json(
+            f"{kg}/api/v1/kg/query",
+            {"op": "neighbors", "entity_id": run_id, "relationship_type": "uploaded", "direction": "outgoing"},
+        )
+        if not any(n.get("entity_id") == content_id for n in out.get("neighbors", [])):
+            raise StageError(f"relationship {rel.get('relationship_id')} not returned by neighbor query")
+        return rel.get("relationship_id", "")
+
+    def retrieve():
+        _, data = request("GET", f"{gateway}/ipfs/{state['cid']}", timeout=60)
+        if data != content:
+            raise StageError(f"retrieved {len(data)} bytes that do not match the {len(content)} uploaded")
+        return f"{len(data)} bytes from {gateway}"
+
+    def record_outcome():
+        post_json(
+            f"{routing}/api/v1/record-outcome",
+            {
+                "backend": state["backend"],
+                "success": bool(state["cid"]),
+                "duration_ms": state["upload_ms"],
+                "content_type": content_type,
+                "content_size": len(content),
+            },
+        )
+        return ""
+
+    if routing:
+        step("route", route)
+    step("upload", upload)
+    step("pin", pin)
+    if kg:
+        step("link", link)
+    step("retrieve", retrieve)
+    if routing and state["backend"]:
+        # Report the measured upload even if a later stage failed
+        state["failed"] = False
+        step("record-outcome", record_outcome)
+
+    ok = not any("error" in s for s in steps)
+    if args.json:
+        print(json.dumps({"ok": ok, "cid": state["cid"], "steps": steps}, indent=2))
+    else:
+        for s in steps:
+            status = "FAIL" if "error" in s else ("OK" if s["ok"] else "SKIP")
+            line = f"{s['name']:<16} {status:<5} {s.get('duration_ms', 0):8.1f}ms  {s.get('detail', '')}{s.get('error', '')}"
+            print(line.rstrip())
+    return 0 if ok else 1
+
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -0,0 +1,511 @@
+"""
+HTTP Knowledge Graph API Server
+
+Exposes the IPLD knowledge graph (:class:`IPLDGraphDB`) over a small REST
+API so non-Python clients (such as the Go bindings in
+``examples/grpc_cross_language/go/kgclient``) can add entities and
+relationships, fetch graph nodes by CID, run neighbor, traversal and
+path queries, and ingest and search embedding vectors.
+
+The server listens on 127.0.0.1 by default. Writes (adding entities,
+relationships and embeddings) need ``Authorization: Bearer <token>`` once
+``KG_API_TOKEN`` is set, and are refused on any other address without it.
+"""
+
+import hmac
+import ipaddress
+import json
+import os
+import anyio
+import logging
+from datetime import datetime
+from typing import Any, Dict, List, Optional
+from aiohttp import web
+from aiohttp.web import Request, Response, json_response
+
+logger = logging.getLogger(__name__)
+
+# Writes require "Authorization: Bearer <token>" with the token from this
+# environment variable; without it they are only allowed on loopback
+TOKEN_ENV = "KG_API_TOKEN"
+
+
+def _dumps(value: Any) -> str:
+    """Serialize graph payloads, which may contain CID objects."""
+    return json.dumps(value, default=str)
+
+
+def _is_loopback(host: str) -> bool:
+    """Whether host only accepts connections from this machine."""
+    if host == "localhost":
+        return True
+    try:
+        return ipaddress.ip_address(host).is_loopback
+    except ValueError:
+        return False
+
+
+class _BadRequest(Exception):
+    """A request field has the wrong type or value."""
+
+
+class KnowledgeGraphHTTPServer:
+    """HTTP API server wrapping an IPLDGraphDB instance."""
+
+    # Upper bound on traversal and path depth to keep queries cheap
+    MAX_QUERY_DEPTH = 6
+
+    def __init__(self, graph_db, host: str = "127.0.0.1", port: int = 8090):
+        self.graph = graph_db
+        self.host = host
+        self.port = port
+        self.app = web.Application()
+        self._setup_routes()
+
+    def _setup_routes(self):
+        """Set up HTTP API routes."""
+        self.app.router.add_post("/api/v1/kg/entities", self.add_entity)
+        self.app.router.add_get("/api/v1/kg/entities/{entity_id}", self.get_entity)
+        self.app.router.add_post("/api/v1/kg/relationships", self.add_relationship)
+        self.app.router.add_get("/api/v1/kg/relationships/{relationship_id}", self.get_relationship)
+        self.app.router.add_get("/api/v1/kg/nodes/{cid}", self.get_node)
+        self.app.router.add_post("/api/v1/kg/query", self.query)
+        self.app.router.add_post("/api/v1/kg/vector-search", self.vector_search)
+        self.app.router.add_post("/api/v1/kg/embeddings", self.add_embeddings)
+        self.app.router.add_get("/api/v1/kg/stats", self.get_stats)
+        self.app.router.add_get("/health", self.health_check)
+
+    @staticmethod
+    def _error(message: str, status: int) -> Response:
+        return json_response({
+            "success": False,
+            "error": message,
+            "timestamp": datetime.utcnow().isoformat()
+        }, status=status)
+
+    @staticmethod
+    def _status_for(result: Dict[str, Any]) -> int:
+        """Map an IPLDGraphDB operation result onto an HTTP status."""
+        if result.get("success"):
+            return 200
+        error = str(result.get("error", ""))
+        if "already exists" in error:
+            return 409
+        if "not found" in error:
+            return 404
+        return 400 if "error_type" not in result else 500
+
+    def _authorize_write(self, request: Request) -> Optional[Response]:
+        """Check the bearer token of a write, returning an error response if it fails."""
+        token = os.environ.get(TOKEN_ENV)
+        if not token:
+            if _is_loopback(self.host):
+                return None
+            return self._error(f"Writes disabled on {self.host}; set {TOKEN_ENV} to enable them", 403)
+        scheme, _, given = request.headers.get("Authorization", "").partition(" ")
+        if scheme.lower() != "bearer" or not hmac.compare_digest(given.strip().encode(), token.encode()):
+            response = self._error("Missing or invalid token", 401)
+            response.headers["WWW-Authenticate"] = "Bearer"
+            return response
+        return None
+
+    async def _read_object(self, request: Request) -> Dict[str, Any]:
+        """Read the request body, which must be a JSON object."""
+        try:
+            data = await request.json()
+        except Exception:
+            raise _BadRequest("Request body must be JSON")
+        if not isinstance(data, dict):
+            raise _BadRequest("Request body must be a JSON object")
+        return data
+
+    @staticmethod
+    def _int_field(data: Dict[str, Any], name: str, default: int) -> int:
+        """Read an integer field, default if absent."""
+        value = data.get(name, default)
+        if isinstance(value, bool):
+            raise _BadRequest(f"{name} must be an integer")
+        try:
+            return int(value)
+        except (TypeError, ValueError, OverflowError):
+            raise _BadRequest(f"{name} must be an integer") from None
+
+    @staticmethod
+    def _str_field(data: Dict[str, Any], name: str, required: bool = True) -> Optional[str]:
+        """Read a string field, None if absent and not required."""
+        value = data.get(name)
+        if value is None or value == "":
+            if required:
+                raise _BadRequest(f"Missing required field: {name}")
+            return None
+        if not isinstance(value, str):
+            raise _BadRequest(f"{name} must be a string")
+        return value
+
+    @staticmethod
+    def _str_list_field(data: Dict[str, Any], name: str) -> Optional[List[str]]:
+        """Read an optional list of strings."""
+        value = data.get(name)
+        if value is None:
+            return None
+        if not isinstance(value, list) or not all(isinstance(v, str) for v in value):
+            raise _BadRequest(f"{name} must be a list of strings")
+        return value
+
+    async def add_entity(self, request: Request) -> Response:
+        """Add an entity to the graph."""
+        denied = self._authorize_write(request)
+        if denied is not None:
+            return denied
+        try:
+            data = await self._read_object(request)
+            entity_id = self._str_field(data, "id")
+            entity_type = self._str_field(data, "type")
+            if not isinstance(data.get("properties") or {}, dict):
+                raise _BadRequest("properties must be an object")
+        except _BadRequest as e:
+            return self._error(str(e), 400)
+
+        result = await anyio.to_thread.run_sync(
+            lambda: self.graph.add_entity(
+                entity_id, entity_type, data.get("properties") or {}, vector=data.get("vector")
+            )
+        )
+        return json_response(result, status=self._status_for(result), dumps=_dumps)
+
+    async def get_entity(self, request: Request) -> Response:
+        """Fetch an entity by ID, including the CID of its current version."""
+        entity_id = request.match_info["entity_id"]
+        entity = await anyio.to_thread.run_sync(self.graph.get_entity, entity_id)
+        if entity is None:
+            return self._error(f"Entity '{entity_id}' not found", 404)
+        return json_response({
+            "success": True,
+            "entity": entity,
+            "cid": str(self.graph.entities[entity_id]["cid"]),
+        }, dumps=_dumps)
+
+    async def add_relationship(self, request: Request) -> Response:
+        """Add a relationship between two existing entities."""
+        denied = self._authorize_write(request)
+        if denied is not None:
+            return denied
+        try:
+            data = await self._read_object(request)
+            for field in ("from", "to", "type"):
+                self._str_field(data, field)
+            if not isinstance(data.get("properties") or {}, dict):
+                raise _BadRequest("properties must be an object")
+        except _BadRequest as e:
+            return self._error(str(e), 400)
+
+        result = await anyio.to_thread.run_sync(
+            lambda: self.graph.add_relationship(
+                data["from"], data["to"], data["type"], properties=data.get("properties")
+            )
+        )
+        return json_response(result, status=self._status_for(result), dumps=_dumps)
+
+    async def get_relationship(self, request: Request) -> Response:
+        """Fetch a relationship by ID."""
+        relationship_id = request.match_info["relationship_id"]
+        relationship = await anyio.to_thread.run_sync(self.graph.get_relationship, relationship_id)
+        if relationship is None:
+            return self._error(f"Relationship '{relationship_id}' not found", 404)
+        return json_response({
+            "success": True,
+            "relationship": relationship,
+            "cid": str(self.graph.relationships["relationship_cids"][relationship_id]),
+        }, dumps=_dumps)
+
+    async def get_node(self, request: Request) -> Response:
+        """Fetch any graph node (entity or relationship version) by CID."""
+        cid = request.match_info["cid"]
+        try:
+            node = await anyio.to_thread.run_sync(self.graph.ipfs.dag_get, cid)
+        except Exception as e:
+            return self._error(f"Node '{cid}' not found: {e}", 404)
+        if node is None:
+            return self._error(f"Node '{cid}' not found", 404)
+        return json_response({"success": True, "cid": cid, "node": node}, dumps=_dumps)
+
+    async def query(self, request: Request) -> Response:
+        """Run a graph query.
+
+        The ``op`` field selects the query:
+
+        * ``neighbors``: relationships of ``entity_id`` filtered by
+          ``relationship_type`` and ``direction``, paged with ``limit`` and
+          ``cursor``
+        * ``traverse``: breadth-first expansion from ``entity_id`` up to
+          ``max_depth`` hops, following ``relationship_types``
+        * ``paths``: simple paths from ``source`` to ``target`` of at most
+          ``max_depth`` hops
+        """
+        try:
+            data = await self._read_object(request)
+            op = data.get("op")
+            direction = data.get("direction", "outgoing")
+            if not isinstance(direction, str) or direction not in ("outgoing", "incoming", "both"):
+                raise _BadRequest(f"Invalid direction: {direction}")
+            max_depth = self._int_field(data, "max_depth", 2)
+            if max_depth < 1 or max_depth > self.MAX_QUERY_DEPTH:
+                raise _BadRequest(f"max_depth must be between 1 and {self.MAX_QUERY_DEPTH}")
+            relationship_types = self._str_list_field(data, "relationship_types")
+            if op == "neighbors":
+                entity_id = self._str_field(data, "entity_id")
+                relationship_type = self._str_field(data, "relationship_type", required=False)
+                cursor = self._str_field(data, "cursor", required=False)
+                limit = self._int_field(data, "limit", 0)
+            elif op == "traverse":
+                entity_id = self._str_field(data, "entity_id")
+                limit = self._int_field(data, "limit", 1000)
+            elif op == "paths":
+                source = self._str_field(data, "source")
+                target = self._str_field(data, "target")
+        except _BadRequest as e:
+            return self._error(str(e), 400)
+
+        if op == "neighbors":
+            if entity_id not in self.graph.entities:
+                return self._error(f"Entity '{entity_id}' not found", 404)
+            neighbors = await anyio.to_thread.run_sync(
+                lambda: self.graph.query_related(
+                    entity_id, relationship_type=relationship_type, direction=direction
+                )
+            )
+            # Neighbors are paged in relationship ID order; the cursor is
+            # the last ID returned, so pages stay stable as edges change
+            neighbors.sort(key=lambda n: n["relationship_id"])
+            if cursor:
+                neighbors = [n for n in neighbors if n["relationship_id"] > cursor]
+            next_cursor = None
+            if limit > 0 and len(neighbors) > limit:
+                neighbors = neighbors[:limit]
+                next_cursor = neighbors[-1]["relationship_id"]
+            return json_response(
+                {"success": True, "neighbors": neighbors, "next_cursor": next_cursor}, dumps=_dumps
+            )
+
+        if op == "traverse":
+            if entity_id not in self.graph.entities:
+                return self._error(f"Entity '{entity_id}' not found", 404)
+            nodes = await anyio.to_thread.run_sync(
+                lambda: self._traverse(
+                    entity_id,
+                    max_depth,
+                    relationship_types,
+                    direction,
+                    limit,
+                )
+            )
+            return json_response({"success": True, "nodes": nodes}, dumps=_dumps)
+
+        if op == "paths":
+            for entity_id in (source, target):
+                if entity_id not in self.graph.entities:
+                    return self._error(f"Entity '{entity_id}' not found", 404)
+            paths = await anyio.to_thread.run_sync(
+                lambda: self.graph.path_between(
+                    source, target, max_depth=max_depth,
+                    relationship_types=relationship_types,
+                )
+            )
+            steps = [
+                [{"entity_id": e, "relationship_id": r} for e, r in path]
+                for path in paths
+            ]
+            return json_response({"success": True, "paths": steps}, dumps=_dumps)
+
+        return self._error(f"Unknown query op: {op}", 400)
+
+    def _traverse(self, start, max_depth, relationship_types, direction, limit):
+        """Breadth-first expansion from start, recording how each entity was reached."""
+        seen = {start}
+        nodes = [{"entity_id": start, "depth": 0, "parent": None, "relationship_id": None}]
+        frontier = [start]
+        for depth in range(1, max_depth + 1):
+            next_frontier = []
+            for entity_id in frontier:
+                for rel in self.graph.query_related(entity_id, direction=direction):
+                    if relationship_types and rel["relationship_type"] not in relationship_types:
+                        continue
+                    neighbor = rel["entity_id"]
+                    if neighbor in seen:
+                        continue
+                    seen.add(neighbor)
+                    nodes.append({
+                        "entity_id": neighbor,
+                        "depth": depth,
+                        "parent": entity_id,
+                        "relationship_id": rel["relationship_id"],
+                        "relationship_type": rel["relationship_type"],
+                    })
+                    if len(nodes) >= limit:
+                        return nodes
+                    next_frontier.append(neighbor)
+            frontier = next_frontier
+        return nodes
+
+    async def vector_search(self, request: Request) -> Response:
+        """Nearest-neighbor search over entity vectors.
+
+        With ``hop_count`` > 0 the matches are expanded through the graph
+        (GraphRAG style), with scores decaying per hop. Each result carries
+        the entity's node CID and, when the entity references stored
+        content through a ``cid`` property, that content CID.
+        """
+        try:
+            data = await self._read_object(request)
+            vector = data.get("vector")
+            if not isinstance(vector, list) or not vector:
+                raise _BadRequest("Missing required field: vector")
+            top_k = self._int_field(data, "top_k", 10)
+            hop_count = self._int_field(data, "hop_count", 0)
+            if hop_count < 0 or hop_count > self.MAX_QUERY_DEPTH:
+                raise _BadRequest(f"hop_count must be between 0 and {self.MAX_QUERY_DEPTH}")
+        except _BadRequest as e:
+            return self._error(str(e), 400)
+
+        def search():
+            if hop_count == 0:
+                results = self.graph.vector_search(vector, top_k=top_k)
+            else:
+                results = self.graph.graph_vector_search(vector, hop_count=hop_count, top_k=top_k)
+                for r in results:
+                    r["hops"] = r.pop("distance", 0)
+            for r in results:
+                entry = self.graph.entities.get(r["entity_id"]) or {}
+                r["cid"] = str(entry.get("cid", ""))
+                entity = self.graph.get_entity(r["entity_id"]) or {}
+                r.setdefault("entity_type", entity.get("type"))
+                r.setdefault("properties", entity.get("properties", {}))
+                content_cid = (r["properties"] or {}).get("cid")
+                if content_cid:
+                    r["content_cid"] = str(content_cid)
+            return results
+
+        try:
+            results = await anyio.to_thread.run_sync(search)
+        except (TypeError, ValueError) as e:
+            return self._error(str(e), 400)
+        return json_response({"success": True, "results": results}, dumps=_dumps)
+
+    async def add_embeddings(self, request: Request) -> Response:
+        """Attach embedding vectors to stored content.
+
+        Each item ``{"cid", "vector", "model", "properties"}`` creates or
+        updates a ``content`` entity with ID ``content:<cid>``, so the
+        vectors land in the same index :meth:`IPLDGraphDB.vector_search`
+        reads and search results point back at the content CID.
+        """
+        denied = self._authorize_write(request)
+        if denied is not None:
+            return denied
+        try:
+            data = await self._read_object(request)
+        except _BadRequest as e:
+            return self._error(str(e), 400)
+
+        items = data.get("embeddings")
+        if not isinstance(items, list) or not items:
+            return self._error("Missing required field: embeddings", 400)
+        for item in items:
+            if not isinstance(item, dict):
+                return self._error("Each embedding must be an object", 400)
+            if not isinstance(item.get("cid"), str) or not isinstance(item.get("model"), str) \
+                    or not item["cid"] or not item["model"]:
+                return self._error("Each embedding needs cid and model", 400)
+            vector = item.get("vector")
+            if not isinstance(vector, list) or not vector:
+                return self._error(f"Embedding for {item['cid']} has no vector", 400)
+            if not isinstance(item.get("properties") or {}, dict):
+                return self._error(f"Embedding properties for {item['cid']} must be an object", 400)
+        dims = {len(item["vector"]) for item in items}
+        index_dim = self.graph.vectors.get("dimension", 0)
+        if len(dims) > 1 or (index_dim and dims != {index_dim}):
+            expected = index_dim or min(dims)
+            return self._error(f"Vector dimension mismatch: index uses {expected}", 400)
+
+        def ingest():
+            results = []
+            for item in items:
+                entity_id = f"content:{item['cid']}"
+                properties = dict(item.get("properties") or {})
+                properties.update({"cid": item["cid"], "embedding_model": item["model"]})
+                if entity_id in self.graph.entities:
+                    res = self.graph.update_entity(entity_id, properties=properties, vector=item["vector"])
+                else:
+                    res = self.graph.add_entity(entity_id, "content", properties, vector=item["vector"])
+                results.append({
+                    "cid": item["cid"],
+                    "entity_id": entity_id,
+                    "node_cid": str(res.get("cid", "")),
+                    "success": bool(res.get("success")),
+                    "error": res.get("error"),
+                })
+            return results
+
+        results = await anyio.to_thread.run_sync(ingest)
+        ok = all(r["success"] for r in results)
+        return json_response({"success": ok, "results": results}, dumps=_dumps)
+
+    async def get_stats(self, request: Request) -> Response:
+        """Get graph statistics."""
+        stats = await anyio.to_thread.run_sync(self.graph.get_statistics)
+        return json_response({"success": True, "stats": stats}, dumps=_dumps)
+
+    async def health_check(self, request: Request) -> Response:
+        """Health check endpoint."""
+        return json_response({
+            "status": "healthy",
+            "service": "ipfs-kit-knowledge-graph-api",
+            "entities": len(self.graph.entities),
+            "timestamp": datetime.utcnow().isoformat()
+        })
+
+    async def start(self):
+        """Start the HTTP server."""
+        runner = web.AppRunner(self.app)
+        await runner.setup()
+
+        site = web.TCPSite(runner, self.host, self.port)
+        await site.start()
+
+        logger.info(f"Knowledge graph API server started on {self.host}:{self.port}")
+        return site
+
+
+# Standalone server functionality
+async def main():
+    """Main function to run the knowledge graph server standalone."""
+    import argparse
+
+    from ipfs_kit_py.ipfs_kit import ipfs_kit
+    from ipfs_kit_py.ipld_knowledge_graph import IPLDGraphDB
+
+    parser = argparse.ArgumentParser(description="IPFS Kit Knowledge Graph HTTP API Server")
+    parser.add_argument("--host", default="127.0.0.1",
+                        help=f"Server host; writes on a non-loopback host need {TOKEN_ENV}")
+    parser.add_argument("--port", type=int, default=8090, help="Server port")
+    parser.add_argument("--graph-path", default="~/.ipfs_graph", help="Local path for graph indexes")
+    parser.add_argument("--debug", action="store_true", help="Enable debug logging")
+
+    args = parser.parse_args()
+    logging.basicConfig(level=logging.DEBUG if args.debug else logging.INFO)
+
+    kit = ipfs_kit()
+    graph_db = IPLDGraphDB(kit, base_path=args.graph_path)
+    server = KnowledgeGraphHTTPServer(graph_db, host=args.host, port=args.port)
+    await server.start()
+
+    print(f"IPFS Kit knowledge graph API running on {args.host}:{args.port}")
+    try:
+        while True:
+            await anyio.sleep(1)
+    except KeyboardInterrupt:
+        logger.info("Shutting down knowledge graph server")
+
+
+if __name__ == "__main__":
+    anyio.run(main)
@@ -12,6 +12,8 @@ from fastapi import APIRouter, HTTPException, Body, Path, Query
 # Import the IPNS operations
 import ipfs_ipns_operations
 
+from ipfs_kit_py.mcp.extensions.key_rotation import KeyRotation
+
 # Configure logger
 logger = logging.getLogger(__name__)
 
@@ -23,16 +25,18 @@ class IPNSController:
     the business logic to the IPNS operations model.
     """
 
-    def __init__(self, ipns_operations=None, key_manager=None):
+    def __init__(self, ipns_operations=None, key_manager=None, key_rotation=None):
         """
         Initialize the IPNS controller.
 
         Args:
             ipns_operations: IPNS operations model to use for operations
             key_manager: Key manager to use for key operations
+            key_rotation: Key rotation workflow to use for rotations
         """
         self.ipns_operations = ipns_operations or ipfs_ipns_operations.get_instance()
         self.key_manager = key_manager or self.ipns_operations.key_manager
+        self.key_rotation = key_rotation or KeyRotation(self.ipns_operations)
         logger.info("IPNS Controller initialized")
 
     def register_routes(self, router: APIRouter):
@@ -96,7 +100,23 @@ class IPNSController:
             self.rotate_key,
             methods=["POST"],
             summary="Rotate an IPNS key",
-            description="Rotate an IPNS key by creating a new one and updating records",
+            description="Rotate an IPNS key or the peer identity, re-publishing its name, updating peering partners and re-announcing its content",
+        )
+
+        router.add_api_route(
+            "/ipfs/key/rotate/rollback",
+            self.rollback_key_rotation,
+            methods=["POST"],
+            summary="Roll back a key rotation",
+            description="Restore the key a rotation replaced while its rollback window is open",
+        )
+
+        router.add_api_route(
+            "/ipfs/key/rotations",
+            self.list_key_rotations,
+            methods=["GET"],
+            summary="List key rotations",
+            description="List recorded key rotations and their rollback windows",
         )
 
         router.add_api_route(
@@ -341,26 +361,42 @@ class IPNSController:
         new_key_type: Optional[str] = Body(None),
         size: Optional[int] = Body(None),
         preserve_old: bool = Body(True),
+        republish: bool = Body(True),
+        reannounce: bool = Body(True),
+        rollback_window: Optional[int] = Body(None),
+        peering_partners: Optional[List[str]] = Body(None),
     ) -> Dict[str, Any]:
         """
-        Rotate an IPNS key by creating a new one and updating records.
+        Rotate an IPNS key, or the peer identity for "self", re-publishing
+        its name, moving peering partners to the new peer ID and
+        re-announcing its content.
 
         Args:
             name: Name of the key to rotate
             new_key_type: Type for the new key (default: same as old)
             size: Size for the new key (default: same as old)
-            preserve_old: Whether to preserve the old key with a timestamp suffix
+            preserve_old: Whether to keep the old key after the rollback window
+            republish: Whether to re-publish the key's name with the new key
+            reannounce: Whether to re-announce the published content
+            rollback_window: Seconds the rotation can be rolled back for
+            peering_partners: RPC API URLs of nodes peering with this one
 
         Returns:
-            Dictionary with operation results
+            Dictionary with operation results and the rotation record
         """
         logger.debug(f"Rotating IPNS key: {name}")
+        options = {"republish": republish, "reannounce": reannounce}
+        if rollback_window is not None:
+            options["rollback_window"] = rollback_window
+        if peering_partners is not None:
+            options["peering_partners"] = peering_partners
         try:
-            result = self.key_manager.rotate_key(
+            result = self.key_rotation.rotate(
                 name=name,
                 new_key_type=new_key_type,
                 size=size,
                 preserve_old=preserve_old,
+                options=options,
             )
             return result
         except Exception as e:
@@ -369,6 +405,40 @@ class IPNSController:
                 status_code=500, detail=f"Error rotating IPNS key: {str(e)}"
             )
 
+    async def rollback_key_rotation(self, rotation_id: str = Body(..., embed=True)) -> Dict[str, Any]:
+        """
+        Roll back a key rotation whose rollback window is still open.
+
+        Args:
+            rotation_id: ID of the rotation to undo
+
+        Returns:
+            Dictionary with operation results
+        """
+        logger.debug(f"Rolling back key rotation: {rotation_id}")
+        try:
+            return self.key_rotation.rollback(rotation_id)
+        except Exception as e:
+            logger.error(f"Error rolling back key rotation: {str(e)}")
+            raise HTTPException(
+                status_code=500, detail=f"Error rolling back key rotation: {str(e)}"
+            )
+
+    async def list_key_rotations(self) -> Dict[str, Any]:
+        """
+        List recorded key rotations, newest first.
+
+        Returns:
+            Dictionary with the rotation records
+        """
+        try:
+            return self.key_rotation.list_rotations()
+        except Exception as e:
+            logger.error(f"Error listing key rotations: {str(e)}")
+            raise HTTPException(
+                status_code=500, detail=f"Error listing key rotations: {str(e)}"
+            )
+
     async def get_key_metrics(self) -> Dict[str, Any]:
         """
         Get performance metrics for key operations.
@@ -13,7 +13,7 @@ module docstring causing the original import section to be lost and producing a
 cascade of "name is not defined" errors. This docstring has been reduced and the
 imports + helpers restored below.
 """
-import os, sys, json, time, logging, socket, signal, tarfile, shutil, subprocess, inspect, atexit, threading, mimetypes
+import os, sys, json, time, base64, logging, socket, signal, tarfile, shutil, subprocess, inspect, atexit, threading, mimetypes
 import anyio
 import anyio.abc
 from collections import deque
@@ -22,6 +22,7 @@ from datetime import datetime, timezone, timedelta
 from typing import Any, Dict, List, Optional, Iterable
 from contextlib import suppress, asynccontextmanager
 from types import SimpleNamespace
+from urllib.parse import quote
 
 # Import comprehensive service manager
 try:
@@ -368,6 +369,10 @@ def ensure_paths(data_dir: Optional[str]):
     backends_file = data_dir_path / "backends.json"
     buckets_file = data_dir_path / "buckets.json"
     pins_file = data_dir_path / "pins.json"
+    bucket_history_file = data_dir_path / "bucket_history.json"
+    share_links_file = data_dir_path / "share_links.json"
+    share_key_file = data_dir_path / "share_receipt_key"
+    derivatives_dir = data_dir_path / "derivatives"
 
     # When tests pass an explicit temp data_dir, they generally expect a clean
     # state (no demo/default buckets/backends).
@@ -439,6 +444,10 @@ def ensure_paths(data_dir: Optional[str]):
         backends_file=backends_file,
         buckets_file=buckets_file,
         pins_file=pins_file,
+        bucket_history_file=bucket_history_file,
+        share_links_file=share_links_file,
+        share_key_file=share_key_file,
+        derivatives_dir=derivatives_dir,
     )
 
 
@@ -530,6 +539,9 @@ def _normalize_buckets(items, vfs_base_path: Path = None):
             'cache_policy': pol.get('cache_policy', 'none') or 'none',
             'retention_days': int(pol.get('retention_days', 0) or 0),
         }
+        transforms = [t for t in pol.get('transforms') or [] if t in _SERVE_TRANSFORMS]
+        if transforms:
+            norm_policy['transforms'] = transforms
         
         # Calculate actual bucket statistics if VFS path provided
         bucket_stats = {"size": 0, "file_count": 0, "folder_count": 0, "total_size": 0}
@@ -611,6 +623,242 @@ def _safe_vfs_path(root: Path, user_path: str) -> Path:
         raise ValueError("invalid path")
     return p
 
+# Serve-time transformations a bucket can enable in its policy's
+# "transforms": "image" resizes images to the w/h query parameters,
+# "markdown" renders markdown to HTML and "json" pretty-prints JSON.
+# Downloads with raw=true are served as stored.
+_SERVE_TRANSFORMS = ("image", "markdown", "json")
+_MAX_IMAGE_DIMENSION = 4096
+_DERIVATIVES_MAX_BYTES = 256 << 20
+
+class _DerivativesCache:
+    """Transformed copies of stored files, kept on disk under root. Entries
+    are keyed by the source's SHA-256 and the transformation, so an edited
+    file never serves a stale derivative, and the least recently used are
+    evicted once the cache outgrows max_bytes."""
+
+    def __init__(self, root: Path, max_bytes: int) -> None:
+        self.root = root
+        self.max_bytes = max_bytes
+        self._lock = threading.Lock()
+
+    @staticmethod
+    def key(digest: bytes, transform: str, params: Dict[str, Any]) -> str:
+        import hashlib
+        spec = json.dumps([transform, params], sort_keys=True).encode("utf-8")
+        return hashlib.sha256(digest + spec).hexdigest()
+
+    def get(self, key: str) -> Optional[bytes]:
+        path = self.root / key
+        try:
+            data = path.read_bytes()
+        except OSError:
+            return None
+        with suppress(OSError):
+            os.utime(path)  # mark as recently used
+        return data
+
+    def put(self, key: str, data: bytes) -> None:
+        with self._lock:
+            self.root.mkdir(parents=True, exist_ok=True)
+            tmp = self.root / f".{key}.tmp"
+            tmp.write_bytes(data)
+            tmp.replace(self.root / key)
+            entries = []
+            for p in self.root.iterdir():
+                if p.name.startswith("."):
+                    continue
+                with suppress(OSError):
+                    st = p.stat()
+                    entries.append((st.st_mtime, st.st_size, p))
+            total = sum(size for _, size, _ in entries)
+            for _, size, p in sorted(entries):
+                if total <= self.max_bytes:
+                    break
+                with suppress(OSError):
+                    p.unlink()
+                total -= size
+
+def _apply_transform(path: Path, transform: str, params: Dict[str, Any]) -> bytes:
+    """Derive the served form of a stored file. Image resizing needs Pillow
+    and markdown rendering the markdown package; without them this raises
+    ImportError."""
+    data = path.read_bytes()
+    if transform == "image":
+        import io
+        from PIL import Image
+        with Image.open(io.BytesIO(data)) as im:
+            fmt = im.format
+            # fit within w x h, keeping the aspect ratio and never enlarging
+            im.thumbnail((params.get("w") or im.width, params.get("h") or im.height))
+            out = io.BytesIO()
+            im.save(out, format=fmt)
+        return out.getvalue()
+    if transform == "markdown":
+        import html
+        import markdown
+        body = markdown.markdown(data.decode("utf-8", "replace"), extensions=["fenced_code", "tables"])
+        return (
+            f'<!DOCTYPE html>\n<html><head><meta charset="utf-8"><title>{html.escape(path.name)}</title></head>\n'
+            f"<body>\n{body}\n</body></html>\n"
+        ).encode("utf-8")
+    if transform == "json":
+        return (json.dumps(json.loads(data), indent=2, ensure_ascii=False) + "\n").encode("utf-8")
+    raise ValueError(f"unknown transform {transform}")
+
+def _transforms_arg(value) -> List[str]:
+    """Validate a bucket policy's transforms, a list of _SERVE_TRANSFORMS"""
+    if isinstance(value, str):
+        value = [value]
+    if not isinstance(value, list) or any(t not in _SERVE_TRANSFORMS for t in value):
+        raise HTTPException(400, f"transforms must be a list of {', '.join(_SERVE_TRANSFORMS)}")
+    return sorted(set(value))
+
+# Standard list arguments accepted by paginated list tools: bucket_list_files
+# (and its list_bucket_files alias), list_pins_page and, in
+# knowledge_graph_http, neighbor queries. Job lists are not covered: the
+# dashboard has no job list tool to page, and the training job manager's
+# list_jobs is only reachable from Python, where it takes offset and limit.
+_LIST_ARGS = {
+    "prefix": {"type":"string", "title":"Name Prefix"},
+    "type": {"type":"string", "title":"Type", "description":"file, directory or a MIME type prefix"},
+    "min_size": {"type":"integer", "title":"Min Size"},
+    "max_size": {"type":"integer", "title":"Max Size"},
+    "modified_after": {"type":"string", "title":"Modified After", "format":"date-time"},
+    "modified_before": {"type":"string", "title":"Modified Before", "format":"date-time"},
+    "sort_by": {"type":"string", "title":"Sort By"},
+    "order": {"type":"string", "title":"Order", "enum":["asc","desc"], "default":"asc"},
+    "limit": {"type":"integer", "title":"Page Size"},
+    "cursor": {"type":"string", "title":"Cursor"},
+}
+_MAX_PAGE_SIZE = 1000
+
+# Operations recorded in a bucket path's history, shown as the File Details
+# timeline. Share links cover a whole bucket and are recorded against its
+# root (path ""), which every path's timeline includes.
+_FILE_HISTORY_OPS = ("upload", "rename", "copy", "delete", "repin", "migrate", "share")
+_MAX_FILE_HISTORY = 200
+
+# Lifetimes of the share link expirations generate_bucket_share_link offers
+_SHARE_EXPIRATIONS = {
+    "never": None,
+    "1h": timedelta(hours=1),
+    "24h": timedelta(days=1),
+    "7d": timedelta(days=7),
+    "30d": timedelta(days=30),
+}
+
+def _raw_cid(digest: bytes) -> str:
+    """CIDv1 of content addressed as a single raw block with its SHA-256
+    digest, in base32: what `ipfs add --raw-leaves --cid-version=1` gives
+    content that fits one chunk, and verifiable with sha256 alone"""
+    cid = bytes([0x01, 0x55, 0x12, 0x20]) + digest
+    return "b" + base64.b32encode(cid).decode("ascii").rstrip("=").lower()
+
+def _parse_time(value):
+    try:
+        t = datetime.fromisoformat(str(value).replace("Z", "+00:00"))
+    except ValueError:
+        return None
+    return t if t.tzinfo else t.replace(tzinfo=UTC)
+
+def _int_arg(args: Dict[str, Any], name: str) -> Optional[int]:
+    value = args.get(name)
+    if value is None or value == "":
+        return None
+    if isinstance(value, bool):
+        raise HTTPException(400, f"{name} must be an integer")
+    try:
+        return int(value)
+    except (TypeError, ValueError):
+        raise HTTPException(400, f"{name} must be an integer")
+
+def _time_arg(args: Dict[str, Any], name: str):
+    if not args.get(name):
+        return None
+    t = _parse_time(args[name])
+    if t is None:
+        raise HTTPException(400, f"{name} must be an ISO 8601 date-time")
+    return t
+
+def _encode_cursor(key) -> str:
+    return base64.urlsafe_b64encode(json.dumps(key).encode()).decode().rstrip("=")
+
+def _decode_cursor(cursor: str):
+    try:
+        key = json.loads(base64.urlsafe_b64decode(cursor + "=" * (-len(cursor) % 4)))
+    except Exception:
+        raise HTTPException(400, "Invalid cursor")
+    if not isinstance(key, list) or len(key) != 2:
+        raise HTTPException(400, "Invalid cursor")
+    return key
+
+def _paginate(items, args: Dict[str, Any], id_field: str, sort_fields: Iterable[str]):
+    """Filter, sort and page list items with the standard list arguments.
+
+    The cursor encodes the sort key of the last item returned, so pages stay
+    stable when items are added or removed between calls. Returns the page,
+    the cursor for the next page (None on the last one) and the number of
+    items matching the filters.
+    """
+    prefix = args.get("prefix")
+    kind = args.get("type")
+    min_size, max_size = _int_arg(args, "min_size"), _int_arg(args, "max_size")
+    after, before = _time_arg(args, "modified_after"), _time_arg(args, "modified_before")
+
+    def keep(it):
+        if prefix and not (str(it.get("name") or "").startswith(prefix) or str(it.get(id_field) or "").startswith(prefix)):
+            return False
+        if kind:
+            is_dir = bool(it.get("is_dir"))
+            if kind in ("file", "directory"):
+                if is_dir != (kind == "directory"):
+                    return False
+            elif not str(it.get("mime_type") or "").startswith(kind):
+                return False
+        size = it.get("size") or 0
+        if min_size is not None and size < min_size:
+            return False
+        if max_size is not None and size > max_size:
+            return False
+        if after or before:
+            t = _parse_time(it.get("modified") or it.get("created_at") or "")
+            if t is None or (after and t <= after) or (before and t >= before):
+                return False
+        return True
+
+    sort_fields = list(sort_fields)
+    sort_by = args.get("sort_by") or sort_fields[0]
+    if sort_by not in sort_fields:
+        raise HTTPException(400, f"Cannot sort by {sort_by}; use one of {', '.join(sort_fields)}")
+    order = args.get("order") or "asc"
+    if order not in ("asc", "desc"):
+        raise HTTPException(400, f"Invalid order: {order}; use asc or desc")
+    desc = order == "desc"
+    blank = 0 if sort_by == "size" else ""
+
+    def key(it):
+        value = it.get(sort_by)
+        return [blank if value is None else value, str(it.get(id_field) or "")]
+
+    matched = sorted((it for it in items if keep(it)), key=key, reverse=desc)
+    page = matched
+    if args.get("cursor"):
+        last = _decode_cursor(args["cursor"])
+        try:
+            page = [it for it in matched if (key(it) < last if desc else key(it) > last)]
+        except TypeError:
+            # a cursor from a listing sorted by another field
+            raise HTTPException(400, "Invalid cursor")
+    limit = _int_arg(args, "limit")
+    next_cursor = None
+    if limit:
+        limit = max(1, min(limit, _MAX_PAGE_SIZE))
+        if len(page) > limit:
+            page = page[:limit]
+            next_cursor = _encode_cursor(key(page[-1]))
+    return page, next_cursor, len(matched)
+
 def _run_cmd_bytes(cmd: List[str], timeout: float = 30.0) -> Dict[str, Any]:
     """Run command returning dict with raw bytes; mirrors shape of _run_cmd.
 
@@ -654,6 +902,8 @@ class ConsolidatedMCPDashboard:
 
         # Initialize comprehensive service manager (will be lazily loaded)
         self._service_manager = None  # Will be initialized on first use to avoid circular imports
+        self._file_digests: Dict[str, Any] = {}  # path -> (size, mtime_ns, sha256) of served files
+        self._derivatives = _DerivativesCache(self.paths.derivatives_dir, _DERIVATIVES_MAX_BYTES)
         self._service_status_overrides: Dict[str, str] = {}
 
         # Ensure graceful persistence on process signals (SIGTERM/SIGINT)
@@ -772,6 +1022,164 @@ class ConsolidatedMCPDashboard:
                 self._service_manager = None
         return self._service_manager
 
+    def _record_file_event(self, bucket: str, path: str, op: str, actor: Optional[str] = None, **details) -> None:
+        """Append an operation to a bucket path's history, keeping the most
+        recent _MAX_FILE_HISTORY. Failures are logged, never raised, so
+        history never blocks the operation it records."""
+        try:
+            history = _read_json(self.paths.bucket_history_file, {})
+            events = history.setdefault(f"{bucket}:{path}", [])
+            event = {"op": op, "at": datetime.now(UTC).isoformat(), "actor": actor or None}
+            event.update({k: v for k, v in details.items() if v is not None})
+            events.append(event)
+            del events[:-_MAX_FILE_HISTORY]
+            _atomic_write_json(self.paths.bucket_history_file, history)
+        except Exception as e:
+            self.log.warning(f"Failed to record {op} of {bucket}:{path}: {e}")
+
+    def _move_file_history(self, bucket: str, src: str, dst: str) -> None:
+        """Carry a renamed path's history over to its new path"""
+        history = _read_json(self.paths.bucket_history_file, {})
+        events = history.pop(f"{bucket}:{src}", None)
+        if events:
+            history[f"{bucket}:{dst}"] = events + history.get(f"{bucket}:{dst}", [])
+            _atomic_write_json(self.paths.bucket_history_file, history)
+
+    def _file_history(self, bucket: str, path: Optional[str] = None, prefix: Optional[str] = None) -> List[Dict[str, Any]]:
+        """Return the history of a bucket path, or of every path under
+        prefix, oldest first, with the bucket-wide events (shares) merged in"""
+        history = _read_json(self.paths.bucket_history_file, {})
+        events = []
+        for key, items in history.items():
+            b, _, p = key.partition(":")
+            if b != bucket:
+                continue
+            if p and path is not None and p != path:
+                continue
+            if p and prefix and not p.startswith(prefix):
+                continue
+            events.extend(dict(e, path=p) for e in items if isinstance(e, dict))
+        events.sort(key=lambda e: e.get("at") or "")
+        return events
+
+    def _share_link(self, bucket: str, token: str) -> Dict[str, Any]:
+        """Return the share link token grants on bucket, raising 404 for an
+        unknown token or one for another bucket and 410 once it expired"""
+        links = _read_json(self.paths.share_links_file, {})
+        link = links.get(token) if token else None
+        if not isinstance(link, dict) or link.get("bucket") != bucket:
+            raise HTTPException(404, "Share link not found")
+        expires = _parse_time(link.get("expires_at")) if link.get("expires_at") else None
+        if expires is not None and expires <= datetime.now(UTC):
+            raise HTTPException(410, "Share link expired")
+        return link
+
+    def _file_digest(self, full_path: Path):
+        """Return the size and SHA-256 of a stored file, hashing it only
+        when it changed since it was last served"""
+        st = full_path.stat()
+        key = str(full_path)
+        cached = self._file_digests.get(key)
+        if cached and cached[:2] == (st.st_size, st.st_mtime_ns):
+            return cached[0], cached[2]
+        import hashlib
+        h, size = hashlib.sha256(), 0
+        with full_path.open("rb") as f:
+            for block in iter(lambda: f.read(1 << 20), b""):
+                h.update(block)
+                size += len(block)
+        self._file_digests[key] = (size, st.st_mtime_ns, h.digest())
+        return size, h.digest()
+
+    async def _serve_transformed(self, bucket: str, full_path: Path, request: Request) -> Optional[Response]:
+        """Serve a download in the form the bucket's transforms policy asks
+        for, through the derivatives cache, or return None to serve the
+        stored file as it is"""
+        query = request.query_params
+        if query.get("raw", "").lower() in ("1", "true"):
+            return None
+        transforms = []
+        for b in _normalize_buckets(_read_json(self.paths.buckets_file, default=[])):
+            if b.get("name") == bucket:
+                transforms = b["policy"].get("transforms") or []
+        media_type = mimetypes.guess_type(full_path)[0] or "application/octet-stream"
+        params: Dict[str, Any] = {}
+        if "image" in transforms and media_type.startswith("image/") and media_type != "image/svg+xml" and (query.get("w") or query.get("h")):
+            transform = "image"
+            for dim in ("w", "h"):
+                if not query.get(dim):
+                    continue
+                try:
+                    params[dim] = int(query[dim])
+                except ValueError:
+                    raise HTTPException(400, f"{dim} must be an integer")
+                if not 1 <= params[dim] <= _MAX_IMAGE_DIMENSION:
+                    raise HTTPException(400, f"{dim} must be between 1 and {_MAX_IMAGE_DIMENSION}")
+        elif "markdown" in transforms and media_type == "text/markdown":
+            transform, media_type = "markdown", "text/html; charset=utf-8"
+        elif "json" in transforms and media_type == "application/json":
+            transform = "json"
+        else:
+            return None
+
+        _, digest = await anyio.to_thread.run_sync(self._file_digest, full_path)
+        key = self._derivatives.key(digest, transform, params)
+        headers = {"ETag": f'"{key}"'}
+        if transform == "markdown":
+            # markdown may embed raw HTML; keep any scripts in it from running
+            headers["Content-Security-Policy"] = "default-src 'none'; img-src * data:; style-src 'unsafe-inline'"
+        if key in [t.strip().strip('"') for t in request.headers.get("if-none-match", "").split(",")]:
+            return Response(status_code=304, headers=headers)
+        data = self._derivatives.get(key)
+        if data is None:
+            try:
+                data = await anyio.to_thread.run_sync(_apply_transform, full_path, transform, params)
+            except ImportError as e:
+                raise HTTPException(501, f"The {transform} transformation needs {e.name}, which is not installed")
+            except Exception as e:
+                # not a readable image or not valid JSON: serve it as stored
+                self.log.warning(f"Serving {bucket}/{full_path.name} untransformed: {transform} failed: {e}")
+                return None
+            self._derivatives.put(key, data)
+        return Response(content=data, media_type=media_type, headers=headers)
+
+    def _share_receipt_key(self):
+        """Load the Ed25519 key signing share receipts, creating it on first use"""
+        from cryptography.hazmat.primitives.asymmetric.ed25519 import Ed25519PrivateKey
+        path = self.paths.share_key_file
+        if path.exists():
+            return Ed25519PrivateKey.from_private_bytes(path.read_bytes())
+        from cryptography.hazmat.primitives import serialization
+        key = Ed25519PrivateKey.generate()
+        raw = key.private_bytes(serialization.Encoding.Raw, serialization.PrivateFormat.Raw, serialization.NoEncryption())
+        fd = os.open(str(path), os.O_WRONLY | os.O_CREAT | os.O_EXCL, 0o600)
+        with os.fdopen(fd, "wb") as f:
+            f.write(raw)
+        return key
+
+    def _share_public_key(self) -> str:
+        from cryptography.hazmat.primitives import serialization
+        pub = self._share_receipt_key().public_key()
+        return base64.b64encode(pub.public_bytes(serialization.Encoding.Raw, serialization.PublicFormat.Raw)).decode("ascii")
+
+    def _share_receipt(self, bucket: str, path: str, size: int, digest: bytes) -> Dict[str, Any]:
+        """Build the integrity receipt of a shared file: its CID and byte
+        count, signed with Ed25519 over the receipt's other fields as
+        compact JSON with sorted keys"""
+        receipt = {
+            "bucket": bucket,
+            "path": path,
+            "cid": _raw_cid(digest),
+            "bytes": size,
+            "sha256": digest.hex(),
+            "issued_at": datetime.now(UTC).isoformat(),
+            "algorithm": "ed25519",
+            "public_key": self._share_public_key(),
+        }
+        payload = json.dumps(receipt, sort_keys=True, separators=(",", ":")).encode("utf-8")
+        receipt["signature"] = base64.b64encode(self._share_receipt_key().sign(payload)).decode("ascii")
+        return receipt
+
     def _get_peer_manager(self):
         """Get or initialize the simple file-backed PeerManager.
 
@@ -2382,6 +2790,7 @@ class ConsolidatedMCPDashboard:
             rf = payload.get("replication_factor") if payload.get("replication_factor") is not None else (pol_in or {}).get("replication_factor")
             cp = payload.get("cache_policy") if payload.get("cache_policy") is not None else (pol_in or {}).get("cache_policy")
             rd = payload.get("retention_days") if payload.get("retention_days") is not None else (pol_in or {}).get("retention_days")
+            tf = payload.get("transforms") if payload.get("transforms") is not None else (pol_in or {}).get("transforms")
             if rf is not None:
                 try:
                     rf = int(rf)
@@ -2398,6 +2807,8 @@ class ConsolidatedMCPDashboard:
                     raise HTTPException(400, "retention_days must be int")
                 if rd < 0:
                     raise HTTPException(400, "retention_days must be >=0")
+            if tf is not None:
+                tf = _transforms_arg(tf)
             items = _normalize_buckets(_read_json(self.paths.buckets_file, default=[]))
             updated = False
             pol: Dict[str, Any] = {}
@@ -2407,6 +2818,7 @@ class ConsolidatedMCPDashboard:
                     if rf is not None: pol['replication_factor'] = rf
                     if cp is not None: pol['cache_policy'] = cp
                     if rd is not None: pol['retention_days'] = rd
+                    if tf is not None: pol['transforms'] = tf
                     pol.setdefault('replication_factor', 1)
                     pol.setdefault('cache_policy', 'none')
                     pol.setdefault('retention_days', 0)
@@ -2559,11 +2971,13 @@ class ConsolidatedMCPDashboard:
                     f.write(content)
                 
                 stat_info = file_path.stat()
+                rel_path = file_path.relative_to(self.paths.vfs_root / bucket_name).as_posix()
+                self._record_file_event(bucket_name, rel_path, "upload", size=stat_info.st_size)
                 return {
                     "success": True,
                     "file": {
                         "name": file.filename,
-                        "path": str(file_path.relative_to(self.paths.vfs_root / bucket_name)),
+                        "path": rel_path,
                         "size": stat_info.st_size,
                         "mime_type": (mimetypes.guess_type(file.filename or "")[0] if (file and getattr(file, 'filename', None)) else None),
                         "uploaded": datetime.now(UTC).isoformat()
@@ -2573,8 +2987,9 @@ class ConsolidatedMCPDashboard:
                 raise HTTPException(500, f"Failed to save file: {str(e)}")
 
         @app.get("/api/buckets/{bucket_name}/download/{file_path:path}")
-        async def download_file_from_bucket(bucket_name: str, file_path: str):
-            """Download a file from a bucket."""
+        async def download_file_from_bucket(bucket_name: str, file_path: str, request: Request):
+            """Download a file from a bucket, transformed as the bucket's
+            transforms policy asks for."""
             # Verify bucket exists
             items = _normalize_buckets(_read_json(self.paths.buckets_file, default=[]))
             if not any(b.get("name") == bucket_name for b in items):
@@ -2584,6 +2999,9 @@ class ConsolidatedMCPDashboard:
             if not full_path.exists() or not full_path.is_file():
                 raise HTTPException(404, "File not found")
             
+            transformed = await self._serve_transformed(bucket_name, full_path, request)
+            if transformed is not None:
+                return transformed
             mime_type = mimetypes.guess_type(full_path)[0] or "application/octet-stream"
             return FileResponse(
                 path=str(full_path),
@@ -2591,6 +3009,67 @@ class ConsolidatedMCPDashboard:
                 media_type=mime_type
             )
 
+        @app.get("/shared/{bucket_name}")
+        async def list_shared_bucket(bucket_name: str, token: str = "") -> Dict[str, Any]:
+            """List the files a share link grants, each with its download URL."""
+            link = self._share_link(bucket_name, token)
+            root = self.paths.vfs_root / bucket_name
+            files = []
+            if root.is_dir():
+                for p in sorted(root.rglob("*")):
+                    if p.is_file():
+                        rel = p.relative_to(root).as_posix()
+                        files.append({
+                            "path": rel,
+                            "size": p.stat().st_size,
+                            "url": f"/shared/{bucket_name}/{quote(rel)}?token={token}",
+                        })
+            return {
+                "bucket": bucket_name,
+                "access_type": link.get("access_type"),
+                "expires_at": link.get("expires_at"),
+                "files": files,
+            }
+
+        @app.get("/api/shares/receipt-key")
+        async def share_receipt_key() -> Dict[str, Any]:
+            """Public key share receipts are signed with, for recipients to pin."""
+            return {"algorithm": "ed25519", "public_key": self._share_public_key()}
+
+        @app.get("/shared/{bucket_name}/{file_path:path}")
+        async def download_shared_file(bucket_name: str, file_path: str, request: Request, token: str = "", receipt: bool = False):
+            """Stream a shared file with a strong ETag (its CID), or with
+            receipt=true return its signed integrity receipt instead. The
+            bucket's serve-time transforms apply as to dashboard downloads."""
+            self._share_link(bucket_name, token)
+            root = (self.paths.vfs_root / bucket_name).resolve()
+            full_path = (root / file_path.lstrip('/')).resolve()
+            if root not in full_path.parents or not full_path.is_file():
+                raise HTTPException(404, "File not found")
+
+            size, digest = await anyio.to_thread.run_sync(self._file_digest, full_path)
+            if receipt:
+                return self._share_receipt(bucket_name, full_path.relative_to(root).as_posix(), size, digest)
+            transformed = await self._serve_transformed(bucket_name, full_path, request)
+            if transformed is not None:
+                return transformed
+            etag = f'"{_raw_cid(digest)}"'
+            headers = {
+                "ETag": etag,
+                "Repr-Digest": f"sha-256=:{base64.b64encode(digest).decode('ascii')}:",
+                "Cache-Control": "private, no-cache",
+            }
+            if_none_match = request.headers.get("if-none-match", "")
+            if if_none_match.strip() == "*" or etag in [t.strip() for t in if_none_match.split(",")]:
+                return Response(status_code=304, headers=headers)
+            mime_type = mimetypes.guess_type(full_path)[0] or "application/octet-stream"
+            return FileResponse(
+                path=str(full_path),
+                filename=full_path.name,
+                media_type=mime_type,
+                headers=headers,
+            )
+
         @app.delete("/api/buckets/{bucket_name}/files/{file_path:path}")
         async def delete_file_from_bucket(bucket_name: str, file_path: str, _auth=Depends(_auth_dep)) -> Dict[str, Any]:
             """Delete a file or directory from a bucket."""
@@ -2608,6 +3087,7 @@ class ConsolidatedMCPDashboard:
                     shutil.rmtree(full_path)
                 else:
                     full_path.unlink()
+                self._record_file_event(bucket_name, file_path.lstrip('/'), "delete")
                 return {"success": True, "message": f"Deleted '{file_path}'"}
             except Exception as e:
                 raise HTTPException(500, f"Failed to delete: {str(e)}")
@@ -2635,11 +3115,14 @@ class ConsolidatedMCPDashboard:
             
             try:
                 old_path.rename(new_path)
+                rel_path = new_path.relative_to(self.paths.vfs_root / bucket_name).as_posix()
+                self._move_file_history(bucket_name, file_path.lstrip('/'), rel_path)
+                self._record_file_event(bucket_name, rel_path, "rename", **{"from": file_path.lstrip('/')})
                 return {
                     "success": True, 
                     "old_name": old_path.name,
                     "new_name": new_name,
-                    "path": str(new_path.relative_to(self.paths.vfs_root / bucket_name))
+                    "path": rel_path
                 }
             except Exception as e:
                 raise HTTPException(500, f"Failed to rename: {str(e)}")
@@ -2672,11 +3155,14 @@ class ConsolidatedMCPDashboard:
             
             try:
                 source_path.rename(dest_path)
+                rel_path = dest_path.relative_to(bucket_base).as_posix()
+                self._move_file_history(bucket_name, file_path.lstrip('/'), rel_path)
+                self._record_file_event(bucket_name, rel_path, "rename", **{"from": file_path.lstrip('/')})
                 return {
                     "success": True,
                     "source": file_path,
                     "destination": destination,
-                    "new_path": str(dest_path.relative_to(bucket_base))
+                    "new_path": rel_path
                 }
             except Exception as e:
                 raise HTTPException(500, f"Failed to move: {str(e)}")
@@ -3004,6 +3490,9 @@ class ConsolidatedMCPDashboard:
                 "group_gid": stat_info.st_gid,
             }
             
+            if bucket and p.is_file():
+                stats["history"] = self._file_history(bucket, path)
+            
             if p.is_dir():
                 # Directory stats
                 total_size = 0
@@ -3469,12 +3958,12 @@ class ConsolidatedMCPDashboard:
             {"name": "get_bucket", "description": "Get bucket by name", "inputSchema": {"type":"object", "required":["name"], "properties": {"name": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}}}},
             {"name": "update_bucket", "description": "Update bucket (merge fields)", "inputSchema": {"type":"object", "required":["name","patch"], "properties": {"name": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "patch": {"type":"object", "title":"Patch"}}}},
             {"name": "get_bucket_policy", "description": "Get bucket policy", "inputSchema": {"type":"object", "required":["name"], "properties": {"name": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}}}},
-            {"name": "update_bucket_policy", "description": "Update bucket policy", "inputSchema": {"type":"object", "required":["name"], "properties": {"name": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "replication_factor": {"type":"number", "title":"Replication", "default":1}, "cache_policy": {"type":"string", "title":"Cache", "enum":["none","memory","disk"], "default":"none"}, "retention_days": {"type":"number", "title":"Retention Days", "default":0}}}},
+            {"name": "update_bucket_policy", "description": "Update bucket policy", "inputSchema": {"type":"object", "required":["name"], "properties": {"name": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "replication_factor": {"type":"number", "title":"Replication", "default":1}, "cache_policy": {"type":"string", "title":"Cache", "enum":["none","memory","disk"], "default":"none"}, "retention_days": {"type":"number", "title":"Retention Days", "default":0}, "transforms": {"type":"array", "title":"Serve Transforms", "items": {"type":"string", "enum":list(_SERVE_TRANSFORMS)}}}}},
             # Comprehensive bucket file management tools
-            {"name": "bucket_list_files", "description": "List files in bucket with metadata priority", "inputSchema": {"type":"object", "required":["bucket"], "properties": {"bucket": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "path": {"type":"string", "title":"Path", "default":"."}, "show_metadata": {"type":"boolean", "title":"Show Metadata", "default":True}}}},
+            {"name": "bucket_list_files", "description": "List files in bucket with metadata priority", "inputSchema": {"type":"object", "required":["bucket"], "properties": {"bucket": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "path": {"type":"string", "title":"Path", "default":"."}, "show_metadata": {"type":"boolean", "title":"Show Metadata", "default":True}, **_LIST_ARGS}}},
             {"name": "list_bucket_files", "description": "List files in bucket (alias for bucket_list_files)", "inputSchema": {"type":"object", "required":["bucket"], "properties": {"bucket": {"type":"string", "title":"Bucket"}, "path": {"type":"string", "title":"Path", "default":""}, "metadata_first": {"type":"boolean", "title":"Metadata First", "default":True}}}},
             {"name": "create_folder", "description": "Create a new folder in bucket", "inputSchema": {"type":"object", "required":["bucket","name"], "properties": {"bucket": {"type":"string", "title":"Bucket"}, "name": {"type":"string", "title":"Folder Name"}}}},
-            {"name": "bucket_upload_file", "description": "Upload file to bucket with replication policy", "inputSchema": {"type":"object", "required":["bucket","path","content"], "properties": {"bucket": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "path": {"type":"string", "title":"File Path"}, "content": {"type":"string", "title":"Content", "ui": {"widget":"textarea", "rows":6}}, "mode": {"type":"string", "title":"Mode", "enum":["text","hex","base64"], "default":"text"}, "apply_policy": {"type":"boolean", "title":"Apply Bucket Policy", "default":True}}}},
+            {"name": "bucket_upload_file", "description": "Upload file to bucket with replication policy", "inputSchema": {"type":"object", "required":["bucket","path","content"], "properties": {"bucket": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "path": {"type":"string", "title":"File Path"}, "content": {"type":"string", "title":"Content", "ui": {"widget":"textarea", "rows":6}}, "mode": {"type":"string", "title":"Mode", "enum":["text","hex","base64"], "default":"text"}, "apply_policy": {"type":"boolean", "title":"Apply Bucket Policy", "default":True}, "actor": {"type":"string", "title":"Uploaded By"}}}},
             {"name": "bucket_download_file", "description": "Download file from bucket", "inputSchema": {"type":"object", "required":["bucket","path"], "properties": {"bucket": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "path": {"type":"string", "title":"File Path"}, "format": {"type":"string", "title":"Format", "enum":["text","hex","base64"], "default":"text"}}}},
             {"name": "bucket_delete_file", "description": "Delete file from bucket", "inputSchema": {"type":"object", "required":["bucket","path"], "confirm": {"message":"This will delete the file from the bucket. Continue?"}, "properties": {"bucket": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "path": {"type":"string", "title":"File Path"}, "remove_replicas": {"type":"boolean", "title":"Remove Replicas", "default":True}}}},
             {"name": "bucket_rename_file", "description": "Rename/move file in bucket", "inputSchema": {"type":"object", "required":["bucket","src","dst"], "properties": {"bucket": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "src": {"type":"string", "title":"Source Path"}, "dst": {"type":"string", "title":"Destination Path"}, "update_replicas": {"type":"boolean", "title":"Update Replicas", "default":True}}}},
@@ -3485,9 +3974,12 @@ class ConsolidatedMCPDashboard:
             {"name": "bucket_get_full_metadata", "description": "Get complete metadata for entire bucket including all file CID hashes for IPFS reconstruction", "inputSchema": {"type":"object", "required":["bucket"], "properties": {"bucket": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}}}},
             # Enhanced bucket management tools
             {"name": "get_bucket_usage", "description": "Get bucket usage statistics", "inputSchema": {"type":"object", "required":["name"], "properties": {"name": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}}}},
-            {"name": "generate_bucket_share_link", "description": "Generate shareable link for bucket", "inputSchema": {"type":"object", "required":["bucket"], "properties": {"bucket": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "access_type": {"type":"string", "title":"Access Type", "enum":["read_only","read_write","admin"], "default":"read_only"}, "expiration": {"type":"string", "title":"Expiration", "enum":["never","1h","24h","7d","30d"], "default":"never"}}}},
+            {"name": "generate_bucket_share_link", "description": "Generate shareable link for bucket", "inputSchema": {"type":"object", "required":["bucket"], "properties": {"bucket": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "access_type": {"type":"string", "title":"Access Type", "enum":["read_only","read_write","admin"], "default":"read_only"}, "expiration": {"type":"string", "title":"Expiration", "enum":["never","1h","24h","7d","30d"], "default":"never"}, "shared_with": {"type":"string", "title":"Shared With"}}}},
+            {"name": "bucket_file_history", "description": "Search the operation history of bucket paths (uploads, renames, re-pins, migrations, shares)", "inputSchema": {"type":"object", "required":["bucket"], "properties": {"bucket": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "path": {"type":"string", "title":"File Path"}, "prefix": {"type":"string", "title":"Path Prefix"}, "op": {"type":"string", "title":"Operation", "enum":list(_FILE_HISTORY_OPS)}, "actor": {"type":"string", "title":"Actor"}, "since": {"type":"string", "title":"Since", "format":"date-time"}, "until": {"type":"string", "title":"Until", "format":"date-time"}, "limit": {"type":"integer", "title":"Most Recent"}}}},
+            {"name": "bucket_record_file_event", "description": "Record an operation done outside the dashboard, such as a migration, in a bucket path's history", "inputSchema": {"type":"object", "required":["bucket","path","op"], "properties": {"bucket": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "path": {"type":"string", "title":"File Path"}, "op": {"type":"string", "title":"Operation", "enum":list(_FILE_HISTORY_OPS)}, "actor": {"type":"string", "title":"Actor"}, "details": {"type":"object", "title":"Details"}}}},
             {"name": "bucket_selective_sync", "description": "Sync selected files in bucket", "inputSchema": {"type":"object", "required":["bucket","files"], "properties": {"bucket": {"type":"string", "title":"Bucket", "ui": {"enumFrom":"buckets", "valueKey":"name", "labelKey":"name"}}, "files": {"type":"array", "title":"Files to Sync", "items": {"type":"string"}}, "options": {"type":"object", "title":"Sync Options", "properties": {"force_update": {"type":"boolean", "default":False}, "verify_checksums": {"type":"boolean", "default":True}, "create_backup": {"type":"boolean", "default":False}}}}}},
             {"name": "list_pins", "description": "List pins", "inputSchema": {}},
+            {"name": "list_pins_page", "description": "List one page of pins, filtered and sorted", "inputSchema": {"type":"object", "properties": {k: _LIST_ARGS[k] for k in ("prefix", "sort_by", "order", "limit", "cursor")}}},
             {"name": "create_pin", "description": "Create pin", "inputSchema": {"type":"object", "required":["cid"], "properties": {"cid": {"type":"string", "title":"CID"}, "name": {"type":"string", "title":"Name"}}}},
             {"name": "delete_pin", "description": "Delete pin", "inputSchema": {"type":"object", "required":["cid"], "confirm": {"message":"This will unpin the CID. Continue?"}, "properties": {"cid": {"type":"string", "title":"CID", "ui": {"enumFrom":"pins", "valueKey":"cid", "labelFormat":"{name} ({cid})"}}}}},
             {"name": "pins_export", "description": "Export pins (raw list)", "inputSchema": {}},
@@ -4908,7 +5400,8 @@ class ConsolidatedMCPDashboard:
                     } if show_metadata else {}
                 })
             
-            return {"jsonrpc": "2.0", "result": {"bucket": bucket, "path": path, "items": files, "total_count": len(files)}, "id": None}
+            page, next_cursor, total = _paginate(files, args, "path", ("name", "size", "modified", "created_at"))
+            return {"jsonrpc": "2.0", "result": {"bucket": bucket, "path": path, "items": page, "total_count": total, "next_cursor": next_cursor}, "id": None}
 
         if name == "list_bucket_files":
             # Alias for bucket_list_files with parameter mapping
@@ -4920,7 +5413,8 @@ class ConsolidatedMCPDashboard:
             
             # Delegate to existing bucket_list_files implementation synchronously
             # to avoid awaiting inside a non-async context
-            mapped_args = {"bucket": bucket, "path": path, "show_metadata": metadata_first}
+            mapped_args = {k: args[k] for k in _LIST_ARGS if k in args}
+            mapped_args.update({"bucket": bucket, "path": path, "show_metadata": metadata_first})
             return self._handle_buckets("bucket_list_files", mapped_args)
         
         if name == "create_folder":
@@ -5005,6 +5499,7 @@ class ConsolidatedMCPDashboard:
             except Exception as e:
                 # Don't fail upload if metadata update fails
                 self.log.warning(f"Failed to update file metadata: {e}")
+            self._record_file_event(bucket, path, "upload", args.get("actor"), size=len(file_content))
             
             return {
                 "jsonrpc": "2.0", 
@@ -5090,6 +5585,7 @@ class ConsolidatedMCPDashboard:
                 del metadata[file_key]
             
             _atomic_write_json(metadata_file, metadata)
+            self._record_file_event(bucket, path, "delete", args.get("actor"), remove_replicas=remove_replicas)
             
             return {"jsonrpc": "2.0", "result": {"ok": True, "path": path, "bucket": bucket, "removed_metadata": deleted_meta}, "id": None}
 
@@ -5134,6 +5630,8 @@ class ConsolidatedMCPDashboard:
                 del metadata[src_key]
             
             _atomic_write_json(metadata_file, metadata)
+            self._move_file_history(bucket, src, dst)
+            self._record_file_event(bucket, dst, "rename", args.get("actor"), **{"from": src})
             
             return {"jsonrpc": "2.0", "result": {"ok": True, "src": src, "dst": dst, "bucket": bucket}, "id": None}
 
@@ -5176,6 +5674,7 @@ class ConsolidatedMCPDashboard:
                         replica["update_needed"] = True
                 metadata[dst_key] = file_meta
                 _atomic_write_json(metadata_file, metadata)
+            self._record_file_event(dst_bucket, dst_path_arg, "copy", args.get("actor"), **{"from": src_key})
 
             return {"jsonrpc": "2.0", "result": {"ok": True, "src_bucket": src_bucket, "src_path": src_path_arg, "dst_bucket": dst_bucket, "dst_path": dst_path_arg}, "id": None}
 
@@ -5218,17 +5717,24 @@ class ConsolidatedMCPDashboard:
             metadata = _read_json(metadata_file, {})
             
             synced_files = 0
+            repinned = []
             for file_key, file_meta in metadata.items():
                 if file_meta.get("bucket") == bucket:
                     # Simulate sync process
                     if "replicas" in file_meta:
+                        backends = []
                         for replica in file_meta["replicas"]:
                             if replica.get("status") == "syncing" or force_sync:
                                 replica["status"] = "synced"
                                 replica["last_sync"] = datetime.now(UTC).isoformat()
+                                backends.append(replica.get("backend"))
                         synced_files += 1
+                        if backends:
+                            repinned.append((file_meta.get("path") or file_key.partition(":")[2], backends))
             
             _atomic_write_json(metadata_file, metadata)
+            for path, backends in repinned:
+                self._record_file_event(bucket, path, "repin", args.get("actor"), backends=backends)
             
             return {"jsonrpc": "2.0", "result": {"ok": True, "bucket": bucket, "synced_files": synced_files, "force_sync": force_sync}, "id": None}
 
@@ -5310,6 +5816,7 @@ class ConsolidatedMCPDashboard:
             except Exception as e:
                 # Log but don't fail if we can't read additional metadata
                 pass
+            result["history"] = self._file_history(bucket, path)
             
             return {"jsonrpc": "2.0", "result": result, "id": None}
 
@@ -5433,10 +5940,22 @@ class ConsolidatedMCPDashboard:
                 
                 total_size_gb = total_size_bytes / (1024 * 1024 * 1024)
                 
+                # The quota set in the bucket's advanced settings, in bytes
+                storage_quota = None
+                bucket_config_path = self.paths.data_dir / "bucket_configs" / f"{bucket_name}.yaml"
+                if bucket_config_path.exists() and yaml:
+                    try:
+                        with bucket_config_path.open('r') as f:
+                            config = yaml.safe_load(f) or {}
+                            storage_quota = (config.get("settings") or {}).get("storage_quota")
+                    except Exception:
+                        pass
+                
                 return {"jsonrpc": "2.0", "result": {
                     "total_size_bytes": total_size_bytes,
                     "total_size_gb": round(total_size_gb, 3),
                     "file_count": file_count,
+                    "storage_quota": storage_quota,
                     "bucket": bucket_name
                 }, "id": None}
                 
@@ -5449,6 +5968,44 @@ class ConsolidatedMCPDashboard:
                     "error": str(e)
                 }, "id": None}
 
+        if name == "bucket_file_history":
+            bucket = args.get("bucket")
+            if not bucket:
+                raise HTTPException(400, "Missing bucket")
+            op, actor = args.get("op"), args.get("actor")
+            since, until = _time_arg(args, "since"), _time_arg(args, "until")
+            events = []
+            for e in self._file_history(bucket, args.get("path"), args.get("prefix")):
+                if op and e.get("op") != op:
+                    continue
+                if actor and e.get("actor") != actor:
+                    continue
+                if since or until:
+                    t = _parse_time(e.get("at") or "")
+                    if t is None or (since and t < since) or (until and t >= until):
+                        continue
+                events.append(e)
+            limit = _int_arg(args, "limit")
+            if limit:
+                # the most recent events
+                events = events[-max(1, min(limit, _MAX_PAGE_SIZE)):]
+            return {"jsonrpc": "2.0", "result": {"bucket": bucket, "events": events, "count": len(events)}, "id": None}
+
+        if name == "bucket_record_file_event":
+            # For operations done outside the dashboard, such as migrations
+            # by routing-cli or re-pins by a replication agent
+            bucket, path, op = args.get("bucket"), args.get("path"), args.get("op")
+            if not bucket or path is None or not op:
+                raise HTTPException(400, "Missing bucket, path, or op")
+            if op not in _FILE_HISTORY_OPS:
+                raise HTTPException(400, f"Unknown op {op}; use one of {', '.join(_FILE_HISTORY_OPS)}")
+            details = args.get("details") or {}
+            if not isinstance(details, dict):
+                raise HTTPException(400, "details must be an object")
+            details = {k: v for k, v in details.items() if k not in ("op", "at", "actor", "path")}
+            self._record_file_event(bucket, path, op, args.get("actor"), **details)
+            return {"jsonrpc": "2.0", "result": {"ok": True, "bucket": bucket, "path": path, "op": op}, "id": None}
+
         if name == "generate_bucket_share_link":
             bucket = args.get("bucket")
             access_type = args.get("access_type", "read_only")
@@ -5456,45 +6013,42 @@ class ConsolidatedMCPDashboard:
             
             if not bucket:
                 return {"jsonrpc": "2.0", "error": {"code": -32602, "message": "Missing bucket name"}, "id": None}
+            if expiration not in _SHARE_EXPIRATIONS:
+                return {"jsonrpc": "2.0", "error": {"code": -32602, "message": f"Unknown expiration {expiration}; use one of {', '.join(_SHARE_EXPIRATIONS)}"}, "id": None}
             
-            # Generate a simple share link (in production, this would include proper token generation)
-            import hashlib
-            import time
-            
-            token_data = f"{bucket}:{access_type}:{expiration}:{int(time.time())}"
-            token = hashlib.md5(token_data.encode()).hexdigest()[:16]
+            # Links are downloaded through /shared/{bucket}, which checks the
+            # token and its expiry against share_links.json
+            import secrets
             
-            # Store share link info (in production, this would go to a proper database)
-            share_links_path = self.paths.data_dir / "share_links.json"
-            share_links = {}
-            if share_links_path.exists():
-                try:
-                    with open(share_links_path, 'r', encoding='utf-8') as f:
-                        share_links = json.load(f)
-                except Exception:
-                    pass
+            token = secrets.token_urlsafe(24)
+            created = datetime.now(UTC)
+            lifetime = _SHARE_EXPIRATIONS[expiration]
+            expires_at = (created + lifetime).isoformat() if lifetime else None
             
+            share_links = _read_json(self.paths.share_links_file, {})
             share_links[token] = {
                 "bucket": bucket,
                 "access_type": access_type,
                 "expiration": expiration,
-                "created_at": datetime.now(UTC).isoformat()
+                "expires_at": expires_at,
+                "shared_with": args.get("shared_with"),
+                "created_at": created.isoformat()
             }
-            
-            try:
-                with open(share_links_path, 'w', encoding='utf-8') as f:
-                    json.dump(share_links, f, indent=2)
-            except Exception:
-                pass
+            _atomic_write_json(self.paths.share_links_file, share_links)
             
             share_link = f"/shared/{bucket}?token={token}"
+            self._record_file_event(
+                bucket, "", "share", args.get("actor"),
+                access_type=access_type, expiration=expiration, shared_with=args.get("shared_with"),
+            )
             
             return {"jsonrpc": "2.0", "result": {
                 "share_link": share_link,
                 "token": token,
                 "bucket": bucket,
                 "access_type": access_type,
-                "expiration": expiration
+                "expiration": expiration,
+                "expires_at": expires_at
             }, "id": None}
 
         if name == "bucket_selective_sync":
@@ -5553,6 +6107,7 @@ class ConsolidatedMCPDashboard:
             rf = args.get("replication_factor") if args.get("replication_factor") is not None else (pol_in or {}).get("replication_factor")
             cp = args.get("cache_policy") if args.get("cache_policy") is not None else (pol_in or {}).get("cache_policy")
             rd = args.get("retention_days") if args.get("retention_days") is not None else (pol_in or {}).get("retention_days")
+            tf = args.get("transforms") if args.get("transforms") is not None else (pol_in or {}).get("transforms")
             # validation / partial updates allowed
             if rf is not None:
                 try:
@@ -5570,6 +6125,8 @@ class ConsolidatedMCPDashboard:
                     raise HTTPException(400, "retention_days must be int")
                 if rd < 0:
                     raise HTTPException(400, "retention_days must be >=0")
+            if tf is not None:
+                tf = _transforms_arg(tf)
             items = _normalize_buckets(_read_json(self.paths.buckets_file, default=[]))
             updated = False
             pol: Dict[str, Any] = {}
@@ -5579,6 +6136,7 @@ class ConsolidatedMCPDashboard:
                     if rf is not None: pol['replication_factor'] = rf
                     if cp is not None: pol['cache_policy'] = cp
                     if rd is not None: pol['retention_days'] = rd
+                    if tf is not None: pol['transforms'] = tf
                     # ensure defaults
                     pol.setdefault('replication_factor', 1)
                     pol.setdefault('cache_policy', 'none')
@@ -5598,6 +6156,10 @@ class ConsolidatedMCPDashboard:
         if name == "list_pins":
             items = _normalize_pins(_read_json(self.paths.pins_file, default=[]))
             return items
+        if name == "list_pins_page":
+            items = _normalize_pins(_read_json(self.paths.pins_file, default=[]))
+            page, next_cursor, total = _paginate(items, args, "cid", ("cid", "name"))
+            return {"items": page, "total_count": total, "next_cursor": next_cursor}
         if name == "create_pin":
             cid = args.get("cid")
             label = args.get("name")
@@ -9833,6 +10395,20 @@ class ConsolidatedMCPDashboard:
         }
     }
     
+    // One line of a bucket path's operation history for the File Details timeline
+    function describeFileEvent(e) {
+        const when = e.at ? new Date(e.at).toLocaleString() : '-';
+        let what = e.op;
+        if (e.op === 'upload') what = 'uploaded' + (e.size != null ? ` (${formatBytes(e.size)})` : '');
+        else if (e.op === 'rename') what = `renamed from ${e.from}`;
+        else if (e.op === 'copy') what = `copied from ${e.from}`;
+        else if (e.op === 'delete') what = 'deleted';
+        else if (e.op === 'repin') what = 're-pinned' + (e.backends ? ` on ${e.backends.join(', ')}` : '');
+        else if (e.op === 'migrate') what = 'migrated' + (e.from ? ` from ${e.from}` : '') + (e.to ? ` to ${e.to}` : '');
+        else if (e.op === 'share') what = `bucket shared (${e.access_type || 'read_only'})` + (e.shared_with ? ` with ${e.shared_with}` : '');
+        return `${when}  ${what}` + (e.actor ? ` by ${e.actor}` : '');
+    }
+    
     async function showFileDetails(item, path, bucket) {
         const detailsPanel = document.getElementById('file-details');
         const statsEl = document.getElementById('file-stats');
@@ -9862,6 +10438,11 @@ class ConsolidatedMCPDashboard:
                 statsText += `  Total Size: ${formatBytes(stats.total_size || 0)}\n`;
             }
             
+            if (stats.history && stats.history.length) {
+                statsText += `\nHistory:\n`;
+                stats.history.forEach(e => { statsText += `  ${describeFileEvent(e)}\n`; });
+            }
+            
             statsEl.textContent = statsText;
             detailsPanel.style.display = 'block';
             
@@ -10691,6 +11272,16 @@ class ConsolidatedMCPDashboard:
             
             metadataHTML += '</div>';
             
+            const history = metadata.history || result.history || [];
+            if (history.length > 0) {
+                metadataHTML += '<div style="margin-top:8px;"><strong>History:</strong></div>';
+                metadataHTML += '<div style="font-size:10px;">';
+                history.forEach(e => {
+                    metadataHTML += `<div style="margin:2px 0;">${describeFileEvent(e)}</div>`;
+                });
+                metadataHTML += '</div>';
+            }
+            
             if (result.replicas && result.replicas.length > 0) {
                 metadataHTML += '<div style="margin-top:8px;"><strong>Replicas:</strong></div>';
                 metadataHTML += '<div style="font-size:10px;">';
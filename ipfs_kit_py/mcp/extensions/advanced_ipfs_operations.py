@@ -16,6 +16,8 @@ from ipfs_dht_operations import get_instance as get_dht_instance
 from ipfs_ipns_operations import get_instance as get_ipns_instance, KeyType, KeyProtectionLevel
 from ipfs_dag_operations import get_instance as get_dag_instance, IPLDFormat
 
+from ipfs_kit_py.mcp.extensions.key_rotation import KeyRotation
+
 # Set up logging
 logger = logging.getLogger("ipfs_advanced_operations")
 
@@ -52,6 +54,7 @@ class AdvancedIPFSOperations:
         self.dht = get_dht_instance(self.connection_pool, self.config)
         self.ipns = get_ipns_instance(self.connection_pool, self.config)
         self.dag = get_dag_instance(self.connection_pool, self.config)
+        self.key_rotation = KeyRotation(self.ipns, self.config)
         
         logger.info("Advanced IPFS Operations initialized")
     
@@ -321,20 +324,48 @@ class AdvancedIPFSOperations:
         options: Optional[Dict[str, Any]] = None,
     ) -> Dict[str, Any]:
         """
-        Rotate an IPNS key by creating a new one and updating records.
+        Rotate an IPNS key, or the peer identity for "self", re-publishing
+        its name, moving peering partners to the new peer ID and
+        re-announcing its content. The rotation can be rolled back until
+        its rollback window closes.
         
         Args:
             name: Name of the key to rotate
             new_key_type: Type for the new key
             size: Size for the new key
-            preserve_old: Whether to preserve the old key with a timestamp suffix
-            options: Additional options
+            preserve_old: Whether to keep the old key after the rollback window
+            options: Additional options (republish, reannounce,
+                rollback_window, peering_partners, lifetime, ttl)
             
         Returns:
-            Operation result
+            Operation result with the rotation record
         """
         logger.debug(f"IPNS rotate key: {name}")
-        return self.ipns.key_manager.rotate_key(name, new_key_type, size, preserve_old, options)
+        if isinstance(new_key_type, KeyType):
+            new_key_type = new_key_type.value
+        return self.key_rotation.rotate(name, new_key_type, size, preserve_old, options)
+    
+    def rollback_key_rotation(self, rotation_id: str) -> Dict[str, Any]:
+        """
+        Undo a key rotation whose rollback window is still open.
+        
+        Args:
+            rotation_id: ID of the rotation to undo
+            
+        Returns:
+            Operation result with the updated rotation record
+        """
+        logger.debug(f"IPNS rollback key rotation: {rotation_id}")
+        return self.key_rotation.rollback(rotation_id)
+    
+    def list_key_rotations(self) -> Dict[str, Any]:
+        """
+        List recorded key rotations, newest first.
+        
+        Returns:
+            Operation result with the rotation records
+        """
+        return self.key_rotation.list_rotations()
     
     # --- IPNS Publishing Operations ---
     
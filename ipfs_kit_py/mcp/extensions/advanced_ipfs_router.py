@@ -99,7 +99,16 @@ class RotateKeyRequest(BaseModel):
     name: str = Field(..., description="Name of the key to rotate")
     new_key_type: Optional[str] = Field(None, description="Type for the new key")
     size: Optional[int] = Field(None, description="Size for the new key")
-    preserve_old: bool = Field(True, description="Whether to preserve the old key with a timestamp suffix")
+    preserve_old: bool = Field(True, description="Whether to keep the old key after the rollback window closes")
+    republish: bool = Field(True, description="Whether to re-publish the key's name with the new key")
+    reannounce: bool = Field(True, description="Whether to re-announce the published content")
+    rollback_window: Optional[int] = Field(None, description="Seconds the rotation can be rolled back for (0 disables rollback)")
+    peering_partners: Optional[List[str]] = Field(None, description="RPC API URLs of nodes peering with this one, moved to the new peer ID when rotating \"self\"")
+
+
+class RollbackKeyRotationRequest(BaseModel):
+    """Request model for rolling back a key rotation."""
+    rotation_id: str = Field(..., description="ID of the rotation to undo")
 
 
 # IPNS Publishing Models
@@ -329,7 +338,23 @@ class AdvancedIPFSRouter:
             self.rotate_key,
             methods=["POST"],
             summary="Rotate IPNS key",
-            description="Rotate an IPNS key by creating a new one and updating records",
+            description="Rotate an IPNS key or the peer identity, re-publishing its name, updating peering partners and re-announcing its content",
+        )
+        
+        router.add_api_route(
+            "/ipfs/advanced/key/rotate/rollback",
+            self.rollback_key_rotation,
+            methods=["POST"],
+            summary="Roll back key rotation",
+            description="Restore the key a rotation replaced while its rollback window is open",
+        )
+        
+        router.add_api_route(
+            "/ipfs/advanced/key/rotations",
+            self.list_key_rotations,
+            methods=["GET"],
+            summary="List key rotations",
+            description="List recorded key rotations and their rollback windows",
         )
         
         # ----- IPNS Publishing Routes -----
@@ -951,11 +976,17 @@ class AdvancedIPFSRouter:
         
         try:
             # Call advanced IPFS operations
+            options = {"republish": request.republish, "reannounce": request.reannounce}
+            if request.rollback_window is not None:
+                options["rollback_window"] = request.rollback_window
+            if request.peering_partners is not None:
+                options["peering_partners"] = request.peering_partners
             result = self.advanced_ipfs.rotate_key(
                 request.name,
                 request.new_key_type,
                 request.size,
                 request.preserve_old,
+                options,
             )
             
             # Add operation ID
@@ -973,6 +1004,63 @@ class AdvancedIPFSRouter:
                 "error_type": type(e).__name__,
             }
     
+    async def rollback_key_rotation(self, request: RollbackKeyRotationRequest) -> Dict[str, Any]:
+        """
+        Roll back a key rotation.
+        
+        Args:
+            request: Request with the rotation ID
+            
+        Returns:
+            Operation result
+        """
+        operation_id = f"rollback_key_rotation_{int(time.time() * 1000)}"
+        
+        try:
+            result = self.advanced_ipfs.rollback_key_rotation(request.rotation_id)
+            
+            # Add operation ID
+            if "operation_id" not in result:
+                result["operation_id"] = operation_id
+            
+            return result
+        except Exception as e:
+            logger.error(f"Error in rollback_key_rotation: {e}")
+            return {
+                "success": False,
+                "operation_id": operation_id,
+                "duration_ms": 0,
+                "error": str(e),
+                "error_type": type(e).__name__,
+            }
+    
+    async def list_key_rotations(self) -> Dict[str, Any]:
+        """
+        List recorded key rotations.
+        
+        Returns:
+            Operation result with the rotation records
+        """
+        operation_id = f"list_key_rotations_{int(time.time() * 1000)}"
+        
+        try:
+            result = self.advanced_ipfs.list_key_rotations()
+            
+            # Add operation ID
+            if "operation_id" not in result:
+                result["operation_id"] = operation_id
+            
+            return result
+        except Exception as e:
+            logger.error(f"Error in list_key_rotations: {e}")
+            return {
+                "success": False,
+                "operation_id": operation_id,
+                "duration_ms": 0,
+                "error": str(e),
+                "error_type": type(e).__name__,
+            }
+    
     # ----- IPNS Publishing Handlers -----
     
     async def publish(self, request: PublishRequest) -> Dict[str, Any]:
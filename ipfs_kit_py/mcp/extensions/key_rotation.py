@@ -0,0 +1,450 @@
+"""
+IPNS key and peer identity rotation for the MCP server.
+
+Rotating a key on its own strands everything that refers to it: names
+published with it stop updating, peering partners keep dialing the old peer
+ID, and provider records name a peer that no longer exists. KeyRotation
+rotates a key and repairs those references, keeping the old key for a
+rollback window so that a rotation which breaks something can be undone.
+
+IPNS keys are rotated through the node's RPC API while it runs. The peer
+identity ("self") can only be rotated with the node stopped, so it is
+rotated through the ipfs CLI on the repository; the node's reprovider
+announces its content under the new identity when it starts again.
+"""
+
+import base64
+import json
+import logging
+import os
+import subprocess
+import tempfile
+import threading
+import time
+import urllib.parse
+import urllib.request
+import uuid
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+logger = logging.getLogger(__name__)
+
+# Rotation states
+ACTIVE = "active"
+ROLLED_BACK = "rolled_back"
+FINALIZED = "finalized"
+
+# Default rollback window in seconds
+DEFAULT_ROLLBACK_WINDOW = 24 * 60 * 60
+
+
+class KeyRotationError(Exception):
+    """Raised when a step of a rotation or rollback fails."""
+
+
+class KeyRotation:
+    """
+    Rotates IPNS keys and the node's peer identity.
+
+    A rotation:
+    1. resolves the name published with the key, so it can be re-published;
+    2. rotates the key, keeping the old one as `<name>-<timestamp>`;
+    3. re-publishes the name's value with the new key;
+    4. moves each peering partner's entry for the node to the new peer ID
+       (peer identity only);
+    5. re-announces the published content on the DHT.
+
+    Rotations are recorded in a state file, and until their rollback window
+    closes `rollback` restores the old key, its name and the partners'
+    entries. Once the window closes the old key is removed if the caller
+    asked not to preserve it.
+    """
+
+    def __init__(self, ipns_operations, config: Optional[Dict[str, Any]] = None):
+        """
+        Initialize key rotation.
+
+        Args:
+            ipns_operations: IPNS operations model with a key_manager and
+                connection_pool
+            config: Configuration options:
+                - `key_rotation_state` (str): file rotations are recorded in
+                  (default ~/.ipfs_kit/key_rotations.json)
+                - `rollback_window` (int): default rollback window in seconds
+                - `ipfs_binary` (str): ipfs CLI used for identity rotation
+                - `ipfs_path` (str): repository of the node (default $IPFS_PATH)
+                - `peering_partners` (List[str]): RPC API URLs of the nodes
+                  peering with this one
+        """
+        self.config = config or {}
+        self.ipns = ipns_operations
+        self.key_manager = ipns_operations.key_manager
+        self.connection_pool = ipns_operations.connection_pool
+        self.state_file = Path(
+            self.config.get("key_rotation_state") or Path.home() / ".ipfs_kit" / "key_rotations.json"
+        )
+        self.rollback_window = int(self.config.get("rollback_window", DEFAULT_ROLLBACK_WINDOW))
+        self.ipfs_binary = self.config.get("ipfs_binary", "ipfs")
+        self.ipfs_path = self.config.get("ipfs_path") or os.environ.get("IPFS_PATH")
+        self.peering_partners = list(self.config.get("peering_partners", []))
+        self._lock = threading.Lock()
+
+    # --- Rotation ---
+
+    def rotate(
+        self,
+        name: str,
+        new_key_type: Optional[str] = None,
+        size: Optional[int] = None,
+        preserve_old: bool = True,
+        options: Optional[Dict[str, Any]] = None,
+    ) -> Dict[str, Any]:
+        """
+        Rotate a key and repair what refers to it.
+
+        Args:
+            name: Name of the key to rotate; "self" rotates the peer identity
+            new_key_type: Type for the new key (default: same as old)
+            size: Size for the new key (default: same as old)
+            preserve_old: Whether to keep the old key once the rollback
+                window closes
+            options: Additional options:
+                - `republish` (bool): re-publish the key's name (default True)
+                - `reannounce` (bool): re-announce its content (default True)
+                - `rollback_window` (int): seconds the rotation can be rolled
+                  back for; 0 disables rollback
+                - `peering_partners` (List[str]): partner RPC API URLs,
+                  replacing the configured ones
+                - `lifetime`, `ttl` (str): for the re-published record
+
+        Returns:
+            Operation result with the rotation record. `success` is False if
+            any step failed; `rotated` says whether the key itself was.
+        """
+        options = options or {}
+        start_time = time.time()
+        window = int(options.get("rollback_window", self.rollback_window))
+        with self._lock:
+            self._expire()
+            try:
+                if name == "self":
+                    record, errors = self._rotate_identity(new_key_type, size, options)
+                else:
+                    # Rollback needs the old key until the window closes
+                    keep_old = preserve_old or window > 0
+                    record, errors = self._rotate_ipns_key(name, new_key_type, size, keep_old, options)
+            except KeyRotationError as e:
+                logger.error(f"Key rotation of '{name}' failed: {e}")
+                return {
+                    "success": False,
+                    "rotated": False,
+                    "error": str(e),
+                    "duration": time.time() - start_time,
+                }
+            record["preserve_old"] = preserve_old
+            record["rollback_until"] = record["rotated_at"] + window if window > 0 else None
+            record["errors"] = errors
+            self._save_record(record)
+
+        if errors:
+            logger.warning(f"Rotated key '{name}' with {len(errors)} failed steps: {errors}")
+        else:
+            logger.info(f"Rotated key '{name}': {record['old_key_id']} -> {record['new_key_id']}")
+        result = {
+            "success": not errors,
+            "rotated": True,
+            "rotation": record,
+            "duration": time.time() - start_time,
+        }
+        if errors:
+            result["error"] = f"{len(errors)} rotation steps failed"
+            if record["rollback_until"]:
+                result["error"] += f"; roll back with rotation {record['id']}"
+        return result
+
+    def _rotate_ipns_key(self, name, new_key_type, size, keep_old, options):
+        """Rotate an IPNS key through the RPC API"""
+        errors: List[str] = []
+        key_result = self.key_manager.get_key(name)
+        old_id = key_result["key"].get("Id") if key_result.get("success") else None
+        value = self._resolve(old_id) if old_id else None
+
+        rotated = self.key_manager.rotate_key(name, new_key_type, size, keep_old, options)
+        if not rotated.get("success"):
+            raise KeyRotationError(rotated.get("error", "key rotation failed"))
+        record = self._new_record(
+            "ipns", name, old_id, rotated.get("old_key_name"), rotated.get("new_key_id"), value
+        )
+
+        if value and options.get("republish", True):
+            published = self.ipns.publish(
+                value, key_name=name, lifetime=options.get("lifetime"), ttl=options.get("ttl"), resolve=False
+            )
+            record["republished"] = bool(published.get("success"))
+            if not published.get("success"):
+                errors.append(f"republish {value}: {published.get('error')}")
+        if value and options.get("reannounce", True):
+            errors += self._reannounce(record, value)
+        return record, errors
+
+    def _rotate_identity(self, new_key_type, size, options):
+        """Rotate the peer identity with the ipfs CLI; the node must be stopped"""
+        errors: List[str] = []
+        old_id = self._ipfs("id", "-f=<id>").strip()
+        value = self._ipfs_resolve(old_id)
+
+        backup = f"self-{int(time.time())}"
+        args = ["key", "rotate", "--oldkey", backup]
+        if new_key_type:
+            args += ["--type", new_key_type]
+        if size:
+            args += ["--size", str(size)]
+        self._ipfs(*args)
+        new_id = self._ipfs("id", "-f=<id>").strip()
+        record = self._new_record("identity", "self", old_id, backup, new_id, value)
+
+        if value and options.get("republish", True):
+            try:
+                self._ipfs("name", "publish", "--allow-offline", "--key=self", value)
+                record["republished"] = True
+            except KeyRotationError as e:
+                errors.append(f"republish {value}: {e}")
+        partners = options.get("peering_partners", self.peering_partners)
+        record["peering_partners"] = list(partners)
+        for partner in partners:
+            try:
+                self._repeer(partner, old_id, new_id)
+            except Exception as e:
+                errors.append(f"peering partner {partner}: {e}")
+        # The node's reprovider announces everything it stores at startup
+        record["reannounced"] = "on daemon start"
+        return record, errors
+
+    def _new_record(self, kind, name, old_id, old_name, new_id, value) -> Dict[str, Any]:
+        return {
+            "id": uuid.uuid4().hex,
+            "kind": kind,
+            "key_name": name,
+            "old_key_id": old_id,
+            "old_key_name": old_name,
+            "new_key_id": new_id,
+            "value": value,
+            "rotated_at": time.time(),
+            "status": ACTIVE,
+            "republished": False,
+            "reannounced": False,
+        }
+
+    # --- Rollback ---
+
+    def rollback(self, rotation_id: str) -> Dict[str, Any]:
+        """
+        Undo a rotation whose rollback window is still open: restore the old
+        key under its name, re-publish its name and move peering partners
+        back to the old peer ID. The new key is removed.
+
+        Args:
+            rotation_id: ID of the rotation to undo
+
+        Returns:
+            Operation result with the updated rotation record
+        """
+        start_time = time.time()
+        with self._lock:
+            self._expire()
+            record = self._load().get(rotation_id)
+            if record is None:
+                return {"success": False, "error": f"Unknown rotation {rotation_id}"}
+            if record["status"] != ACTIVE or not record.get("rollback_until") or not record.get("old_key_name"):
+                return {"success": False, "error": f"Rotation {rotation_id} cannot be rolled back ({record['status']})"}
+            try:
+                if record["kind"] == "identity":
+                    errors = self._rollback_identity(record)
+                else:
+                    errors = self._rollback_ipns_key(record)
+            except KeyRotationError as e:
+                logger.error(f"Rollback of rotation {rotation_id} failed: {e}")
+                return {"success": False, "error": str(e), "rotation": record, "duration": time.time() - start_time}
+            record["status"] = ROLLED_BACK
+            record["rolled_back_at"] = time.time()
+            record["rollback_errors"] = errors
+            self._save_record(record)
+
+        result = {"success": not errors, "rotation": record, "duration": time.time() - start_time}
+        if errors:
+            result["error"] = f"{len(errors)} rollback steps failed"
+        return result
+
+    def _rollback_ipns_key(self, record) -> List[str]:
+        name = record["key_name"]
+        removed = self.key_manager.remove_key(name)
+        if not removed.get("success"):
+            raise KeyRotationError(f"remove new key: {removed.get('error')}")
+        renamed = self.key_manager.rename_key(record["old_key_name"], name, force=True)
+        if not renamed.get("success"):
+            raise KeyRotationError(f"restore old key {record['old_key_name']}: {renamed.get('error')}")
+
+        errors: List[str] = []
+        value = record.get("value")
+        if value:
+            published = self.ipns.publish(value, key_name=name, resolve=False)
+            if not published.get("success"):
+                errors.append(f"republish {value}: {published.get('error')}")
+            errors += self._reannounce(record, value)
+        return errors
+
+    def _rollback_identity(self, record) -> List[str]:
+        """Make the preserved key the identity again; the node must be stopped"""
+        if not self.ipfs_path:
+            raise KeyRotationError("identity rollback needs the repository path (ipfs_path or $IPFS_PATH)")
+        with tempfile.TemporaryDirectory() as tmp:
+            exported = os.path.join(tmp, "identity.key")
+            self._ipfs("key", "export", "--format=libp2p-protobuf-cleartext", "-o", exported, record["old_key_name"])
+            priv_key = base64.b64encode(Path(exported).read_bytes()).decode()
+
+        config_path = Path(self.ipfs_path) / "config"
+        try:
+            config = json.loads(config_path.read_text())
+            config["Identity"] = {"PeerID": record["old_key_id"], "PrivKey": priv_key}
+            tmp_path = config_path.with_suffix(".rollback")
+            tmp_path.write_text(json.dumps(config, indent=2))
+            os.replace(tmp_path, config_path)
+        except (OSError, ValueError) as e:
+            raise KeyRotationError(f"restore identity in {config_path}: {e}")
+        self._ipfs("key", "rm", record["old_key_name"])
+
+        errors: List[str] = []
+        if record.get("value"):
+            try:
+                self._ipfs("name", "publish", "--allow-offline", "--key=self", record["value"])
+            except KeyRotationError as e:
+                errors.append(f"republish {record['value']}: {e}")
+        for partner in record.get("peering_partners", []):
+            try:
+                self._repeer(partner, record["new_key_id"], record["old_key_id"])
+            except Exception as e:
+                errors.append(f"peering partner {partner}: {e}")
+        return errors
+
+    def list_rotations(self) -> Dict[str, Any]:
+        """
+        List recorded rotations, newest first.
+
+        Returns:
+            Operation result with the rotation records
+        """
+        with self._lock:
+            self._expire()
+            rotations = sorted(self._load().values(), key=lambda r: r["rotated_at"], reverse=True)
+        return {"success": True, "rotations": rotations, "count": len(rotations)}
+
+    def _expire(self) -> None:
+        """Finalize rotations whose rollback window has closed, removing old
+        keys the caller did not ask to preserve"""
+        now = time.time()
+        for record in self._load().values():
+            until = record.get("rollback_until")
+            if record["status"] != ACTIVE or (until and until > now):
+                continue
+            record["status"] = FINALIZED
+            if not record.get("preserve_old") and record.get("old_key_name"):
+                if record["kind"] == "identity":
+                    try:
+                        self._ipfs("key", "rm", record["old_key_name"])
+                    except KeyRotationError as e:
+                        # The node is running; the key stays in the keystore
+                        logger.warning(f"Could not remove old identity {record['old_key_name']}: {e}")
+                else:
+                    removed = self.key_manager.remove_key(record["old_key_name"])
+                    if not removed.get("success"):
+                        logger.warning(f"Could not remove old key {record['old_key_name']}: {removed.get('error')}")
+            self._save_record(record)
+
+    # --- Helpers ---
+
+    def _resolve(self, key_id: str) -> Optional[str]:
+        """Return the path the name of key_id points to, or None"""
+        result = self.ipns.resolve(key_id, recursive=False, nocache=True)
+        return result.get("value") if result.get("success") else None
+
+    def _ipfs_resolve(self, peer_id: str) -> Optional[str]:
+        try:
+            return self._ipfs("name", "resolve", "--offline", f"/ipns/{peer_id}").strip() or None
+        except KeyRotationError:
+            return None
+
+    def _reannounce(self, record, value: str) -> List[str]:
+        """Announce the content value points to on the DHT"""
+        parts = value.strip("/").split("/")
+        if len(parts) < 2 or parts[0] != "ipfs":
+            return []
+        try:
+            response = self.connection_pool.post("routing/provide", params={"arg": parts[1], "recursive": "true"})
+            if response.status_code != 200:
+                return [f"reannounce {parts[1]}: HTTP {response.status_code}"]
+        except Exception as e:
+            return [f"reannounce {parts[1]}: {e}"]
+        record["reannounced"] = True
+        return []
+
+    def _repeer(self, partner: str, old_id: str, new_id: str) -> None:
+        """Move a peering partner's entry for this node from old_id to new_id,
+        in its config and live"""
+        peers = self._partner_call(partner, "config", [("arg", "Peering.Peers")]).get("Value") or []
+        addrs: List[str] = []
+        for peer in peers:
+            if peer.get("ID") == old_id:
+                peer["ID"] = new_id
+                addrs = peer.get("Addrs") or []
+        if not addrs and not any(p.get("ID") == new_id for p in peers):
+            # The partner does not peer with this node
+            return
+        self._partner_call(
+            partner, "config", [("arg", "Peering.Peers"), ("arg", json.dumps(peers)), ("json", "true")]
+        )
+        self._partner_call(partner, "swarm/peering/rm", [("arg", old_id)])
+        if addrs:
+            self._partner_call(partner, "swarm/peering/add", [("arg", f"{a}/p2p/{new_id}") for a in addrs])
+
+    def _partner_call(self, partner: str, command: str, params) -> Dict[str, Any]:
+        url = f"{partner.rstrip('/')}/api/v0/{command}?{urllib.parse.urlencode(params)}"
+        req = urllib.request.Request(url, method="POST")
+        with urllib.request.urlopen(req, timeout=30) as resp:
+            body = resp.read()
+        return json.loads(body) if body.strip() else {}
+
+    def _ipfs(self, *args: str) -> str:
+        """Run the ipfs CLI on the repository"""
+        env = dict(os.environ)
+        if self.ipfs_path:
+            env["IPFS_PATH"] = self.ipfs_path
+        try:
+            proc = subprocess.run(
+                [self.ipfs_binary, *args], env=env, capture_output=True, text=True, timeout=120
+            )
+        except (OSError, subprocess.TimeoutExpired) as e:
+            raise KeyRotationError(f"ipfs {args[0]}: {e}")
+        if proc.returncode != 0:
+            stderr = proc.stderr.strip()
+            if "lock" in stderr:
+                stderr += " (stop the IPFS daemon to rotate or restore the peer identity)"
+            raise KeyRotationError(f"ipfs {' '.join(args[:2])}: {stderr}")
+        return proc.stdout
+
+    def _load(self) -> Dict[str, Dict[str, Any]]:
+        try:
+            data = json.loads(self.state_file.read_text())
+        except FileNotFoundError:
+            return {}
+        except (OSError, ValueError) as e:
+            logger.warning(f"Could not read key rotations from {self.state_file}: {e}")
+            return {}
+        return {r["id"]: r for r in data.get("rotations", [])}
+
+    def _save_record(self, record: Dict[str, Any]) -> None:
+        rotations = self._load()
+        rotations[record["id"]] = record
+        self.state_file.parent.mkdir(parents=True, exist_ok=True)
+        tmp_path = self.state_file.with_suffix(".tmp")
+        tmp_path.write_text(json.dumps({"rotations": list(rotations.values())}, indent=2))
+        os.replace(tmp_path, self.state_file)
@@ -5,34 +5,292 @@ This HTTP REST API provides all routing functionality previously available
 through gRPC, without protobuf dependencies or version conflicts.
 """
 
+import hmac
 import json
+import os
+import uuid
 import anyio
 import logging
-from datetime import datetime
+from collections import OrderedDict, deque
+from datetime import datetime, timezone
 from typing import Dict, List, Any, Optional
 from aiohttp import web
 from aiohttp.web import Request, Response, json_response
 
 logger = logging.getLogger(__name__)
 
+# Header carrying the client's correlation ID, echoed on every response
+CORRELATION_HEADER = "X-Correlation-ID"
+
+# Idempotency keys are remembered for this long, up to this many at once
+IDEMPOTENCY_TTL_SECONDS = 24 * 3600
+IDEMPOTENCY_MAX_KEYS = 10000
+
+# Backend health is judged on this many recent outcomes per backend
+BACKEND_WINDOW = 50
+# Outcomes kept per backend for statistics
+BACKEND_HISTORY = 10000
+# Decisions and outcomes kept for GET /api/v1/history, across all backends
+ROUTING_HISTORY = 100000
+# Records per history page by default, and at most
+HISTORY_PAGE_SIZE = 1000
+HISTORY_MAX_PAGE_SIZE = 10000
+# Success rates below these mark a backend degraded or unavailable
+DEGRADED_SUCCESS_RATE = 0.9
+UNAVAILABLE_SUCCESS_RATE = 0.5
+# This many consecutive failures mark a backend unavailable at once
+UNAVAILABLE_CONSECUTIVE_FAILURES = 5
+
+# Client probe samples kept per backend, and how long they stay relevant
+PROBE_HISTORY = 100
+PROBE_MAX_AGE_SECONDS = 600
+# Content at least this large is routed by measured throughput rather than
+# latency under the performance strategy
+THROUGHPUT_ROUTING_SIZE = 10 * 1024 * 1024
+
+# Retrievals of content at least this many DHT peers provide are steered
+# to IPFS; content no peer provides is steered away from it
+WELL_PROVIDED_COUNT = 3
+
+# Backends the router selects between and what each accepts.
+# max_object_size 0 means no limit; empty content_types accepts all.
+# min_retention_days is the shortest storage a backend bills for, e.g.
+# the minimum Filecoin deal duration; content kept for less is billed as
+# if kept that long.
+BACKEND_REGISTRY = {
+    "ipfs": {
+        "max_object_size": 0,
+        "content_types": [],
+        "region": "global",
+        "pricing_class": "low",
+    },
+    "s3": {
+        "max_object_size": 5 * 1024 ** 4,
+        "content_types": [],
+        "region": "us-east-1",
+        "pricing_class": "medium",
+    },
+    "filecoin": {
+        "max_object_size": 32 * 1024 ** 3,
+        "content_types": [],
+        "region": "global",
+        "pricing_class": "low",
+        "min_retention_days": 180,
+    },
+}
+
+# Remote pinning services (IPFS Pinning Service API providers such as
+# Pinata or Filebase) to register as backends, as a comma-separated list of
+# backend names; clients hold the endpoints and tokens
+PINNING_SERVICES_ENV = "ROUTING_PINNING_SERVICES"
+
+for _name in os.environ.get(PINNING_SERVICES_ENV, "").split(","):
+    if _name.strip():
+        BACKEND_REGISTRY.setdefault(_name.strip(), {
+            "max_object_size": 0,
+            "content_types": [],
+            "region": "global",
+            "pricing_class": "medium",
+            "kind": "pinning_service",
+        })
+
+# Storage (per GB-month) and egress (per GB) rates in USD for cost
+# estimates, as in config_manager's backend_costs
+BACKEND_COSTS = {
+    "ipfs": {"storage_cost_per_gb": 0.0, "retrieval_cost_per_gb": 0.0},
+    "filecoin": {"storage_cost_per_gb": 0.00002, "retrieval_cost_per_gb": 0.0001},
+    "s3": {"storage_cost_per_gb": 0.023, "retrieval_cost_per_gb": 0.0},
+}
+COST_CURRENCY = "USD"
+
+# Error categories for backend statistics, checked in order against the
+# lower-cased error message; anything else is "other". Clients may also
+# send one of these as error_class, which takes precedence.
+ERROR_CATEGORIES = [
+    ("integrity", ("digest mismatch", "verification failed", "integrity", "checksum")),
+    ("timeout", ("timeout", "timed out", "deadline")),
+    ("unavailable", ("unavailable", "connection refused", "connection reset", "unreachable", "503", "502")),
+    ("not_found", ("not found", "404", "no such")),
+    ("permission", ("permission", "forbidden", "unauthorized", "401", "403", "access denied")),
+    ("quota", ("quota", "rate limit", "too many requests", "429", "insufficient", "no space")),
+    ("invalid", ("invalid", "bad request", "400", "malformed", "too large")),
+]
+
+# error_class of outcomes for reads a client cancelled because another
+# backend answered first. They are not failures, so they are left out of
+# health and success rates; their duration is a lower bound on latency.
+OUTRUN_CLASS = "outrun"
+
+# Scoring factors and their starting weights, as in config_manager's
+# optimization_weights. Weights are kept normalised to sum to 1.
+DEFAULT_FACTOR_WEIGHTS = {
+    "network_quality": 0.25,
+    "content_match": 0.2,
+    "cost_efficiency": 0.2,
+    "geographic_proximity": 0.15,
+    "load_balancing": 0.05,
+    "reliability": 0.1,
+    "historical_success": 0.05,
+}
+
+# Admin endpoints require "Authorization: Bearer <token>" with the token
+# from this environment variable; they are disabled when it is unset
+ADMIN_TOKEN_ENV = "ROUTING_ADMIN_TOKEN"
+
+# Strategies select-backend accepts
+VALID_STRATEGIES = {
+    "hybrid", "performance", "cost", "content_type", "latency",
+    "geographic", "reliability", "balanced",
+}
+
 class HTTPRoutingServer:
     """HTTP API server providing routing functionality without gRPC/protobuf."""
     
     def __init__(self, host: str = "0.0.0.0", port: int = 8080):
         self.host = host
         self.port = port
-        self.app = web.Application()
+        self.app = web.Application(middlewares=[self._correlation_middleware])
         self._setup_routes()
         self._request_count = 0
         self._start_time = datetime.utcnow()
+        self._seen_keys: "OrderedDict[str, float]" = OrderedDict()
+        self._backend_outcomes: Dict[str, deque] = {}
+        self._backend_states: Dict[str, Dict[str, Any]] = {}
+        self._factor_weights: Dict[str, float] = dict(DEFAULT_FACTOR_WEIGHTS)
+        self._probe_samples: Dict[str, deque] = {}
+        self._placement: Dict[str, Dict[str, int]] = {}
+        self._history: deque = deque(maxlen=ROUTING_HISTORY)
+        self._history_seq = 0
+    
+    def _seen(self, key: Optional[str]) -> bool:
+        """Record an idempotency key, returning True if it was already seen.
+
+        Outcomes without a key are always counted. Keys expire after
+        IDEMPOTENCY_TTL_SECONDS and the oldest are evicted past
+        IDEMPOTENCY_MAX_KEYS, which bounds memory while covering any
+        realistic client retry window.
+        """
+        if not key:
+            return False
+        now = datetime.utcnow().timestamp()
+        while self._seen_keys:
+            oldest, seen_at = next(iter(self._seen_keys.items()))
+            if now - seen_at < IDEMPOTENCY_TTL_SECONDS and len(self._seen_keys) < IDEMPOTENCY_MAX_KEYS:
+                break
+            del self._seen_keys[oldest]
+        if key in self._seen_keys:
+            return True
+        self._seen_keys[key] = now
+        return False
+    
+    def _keep(self, record: Dict[str, Any]) -> None:
+        """Add a decision or outcome to the history GET /api/v1/history pages through."""
+        self._history_seq += 1
+        record["seq"] = self._history_seq
+        record["time"] = datetime.now(timezone.utc).timestamp()
+        self._history.append(record)
+    
+    def _note_outcome(self, outcome: Dict[str, Any]) -> None:
+        """Add an outcome to its backend's history and update its state."""
+        backend = outcome["backend"]
+        self._keep({
+            "kind": "outcome",
+            "backend_id": backend,
+            "content_hash": outcome.get("content_hash") or "",
+            "content_type": outcome.get("content_type") or "",
+            "content_size": int(outcome.get("content_size") or 0),
+            "success": bool(outcome["success"]),
+            "duration_ms": int(outcome.get("duration_ms") or 0),
+            "bytes_transferred": int(outcome.get("bytes_transferred") or 0),
+            "error": outcome.get("error_message") or "",
+            "error_class": outcome.get("error_class") or "",
+        })
+        history = self._backend_outcomes.setdefault(backend, deque(maxlen=BACKEND_HISTORY))
+        history.append({
+            "time": datetime.utcnow().timestamp(),
+            "success": bool(outcome["success"]),
+            "duration_ms": float(outcome.get("duration_ms") or 0),
+            "content_size": int(outcome.get("bytes_transferred") or outcome.get("content_size") or 0),
+            "error": outcome.get("error_message") or "",
+            "error_class": outcome.get("error_class") or "",
+        })
+        if outcome["success"]:
+            # e.g. the cluster peers a pin was allocated to
+            counts = self._placement.setdefault(backend, {})
+            for location in outcome.get("placement") or []:
+                counts[location] = counts.get(location, 0) + 1
+        if outcome.get("error_class") == OUTRUN_CLASS:
+            return
+        
+        window = []
+        for o in reversed(history):
+            if len(window) == BACKEND_WINDOW:
+                break
+            if o["error_class"] != OUTRUN_CLASS:
+                window.insert(0, o)
+        rate = sum(1 for o in window if o["success"]) / len(window)
+        latency = sum(o["duration_ms"] for o in window) / len(window)
+        recent = window[-UNAVAILABLE_CONSECUTIVE_FAILURES:]
+        if len(recent) == UNAVAILABLE_CONSECUTIVE_FAILURES and not any(o["success"] for o in recent):
+            state, reason = "unavailable", f"last {len(recent)} outcomes failed"
+        elif rate < UNAVAILABLE_SUCCESS_RATE:
+            state, reason = "unavailable", f"success rate {rate:.0%}"
+        elif rate < DEGRADED_SUCCESS_RATE:
+            state, reason = "degraded", f"success rate {rate:.0%}"
+        else:
+            state, reason = "healthy", f"success rate {rate:.0%}"
+        
+        previous = self._backend_states.get(backend, {})
+        changed = previous.get("state") != state
+        if changed and previous:
+            logger.info(f"Backend {backend} is {state} (was {previous['state']}): {reason}")
+        self._backend_states[backend] = {
+            "state": state,
+            "previous_state": previous.get("state") if changed else previous.get("previous_state"),
+            "reason": reason,
+            "success_rate": rate,
+            "latency_ms": latency,
+            "samples": len(window),
+            "since": datetime.utcnow().isoformat() if changed else previous["since"],
+        }
     
+    @web.middleware
+    async def _correlation_middleware(self, request: Request, handler):
+        """Attach a correlation ID to the request, its logs and its response.
+
+        The ID comes from the X-Correlation-ID header when the client sent
+        one, so a decision can be traced from the client through the router
+        to the backend logs. Errors aiohttp raises as HTTPException, such
+        as 404 for unknown paths, get the header too.
+        """
+        correlation_id = request.headers.get(CORRELATION_HEADER) or uuid.uuid4().hex
+        request["correlation_id"] = correlation_id
+        try:
+            response = await handler(request)
+        except web.HTTPException as e:
+            e.headers[CORRELATION_HEADER] = correlation_id
+            logger.info(f"{request.method} {request.path} {e.status} correlation_id={correlation_id}")
+            raise
+        response.headers[CORRELATION_HEADER] = correlation_id
+        logger.info(f"{request.method} {request.path} {response.status} correlation_id={correlation_id}")
+        return response
+
     def _setup_routes(self):
         """Set up HTTP API routes."""
         # Core routing endpoints
         self.app.router.add_post("/api/v1/select-backend", self.select_backend)
         self.app.router.add_post("/api/v1/record-outcome", self.record_outcome)
+        self.app.router.add_post("/api/v1/record-outcomes", self.record_outcomes)
         self.app.router.add_get("/api/v1/insights", self.get_insights)
         self.app.router.add_get("/api/v1/metrics", self.get_metrics)
+        self.app.router.add_post("/api/v1/metrics/report", self.report_metrics)
+        self.app.router.add_get("/api/v1/backends", self.get_backends)
+        self.app.router.add_get("/api/v1/backends/{backend_id}/stats", self.get_backend_stats)
+        self.app.router.add_post("/api/v1/estimate-cost", self.estimate_cost)
+        self.app.router.add_get("/api/v1/history", self.get_history)
+        
+        # Admin endpoints
+        self.app.router.add_put("/api/v1/admin/factor-weights", self.set_factor_weights)
         
         # Health and status
         self.app.router.add_get("/health", self.health_check)
@@ -43,17 +301,37 @@ class HTTPRoutingServer:
         self.app.router.add_get("/api/v1/", self.api_documentation)
     
     async def select_backend(self, request: Request) -> Response:
-        """Select optimal backend for content storage/retrieval."""
-        self._request_count += 1
+        """Select optimal backend for content storage/retrieval.
         
+        With "dry_run": true the decision is made and explained as usual
+        but not counted, so tooling can ask what-if questions without
+        skewing request rates or learning state.
+        """
         try:
             data = await request.json()
+            dry_run = bool(data.get("dry_run", False))
+            if not dry_run:
+                self._request_count += 1
             
             # Extract parameters with defaults
             content_type = data.get("content_type", "application/octet-stream")
             content_size = data.get("content_size", 0)
-            strategy = data.get("strategy", "hybrid")
+            # Accept the proto enum names too ("ROUTING_STRATEGY_COST")
+            strategy = (data.get("strategy") or "hybrid").lower()
+            strategy = strategy.removeprefix("routing_strategy_")
+            content_category = (data.get("content_category") or "").lower()
+            content_category = content_category.removeprefix("content_category_")
             priority = data.get("priority", "balanced")
+            available = data.get("available_backends")
+            retention_days = int(data.get("retention_days") or 0)
+            
+            if strategy not in VALID_STRATEGIES:
+                return json_response({
+                    "success": False,
+                    "error": f"Unknown strategy: {strategy}",
+                    "error_type": "invalid_strategy",
+                    "timestamp": datetime.utcnow().isoformat()
+                }, status=400)
             
             # Backend selection logic (replaces gRPC implementation)
             backend = await self._select_optimal_backend(
@@ -63,6 +341,49 @@ class HTTPRoutingServer:
                 priority=priority
             )
             
+            # Honour the caller's backend list; fall back to its first entry
+            if available is not None:
+                if not available:
+                    return json_response({
+                        "success": False,
+                        "error": "No backend available: available_backends is empty",
+                        "error_type": "no_backend_available",
+                        "timestamp": datetime.utcnow().isoformat()
+                    }, status=503)
+                if backend["name"] not in available:
+                    backend = dict(backend, name=available[0],
+                                   reasoning=f"{backend['name']} not available; using {available[0]}")
+            
+            # Prefer a backend in the client's region when the strategy
+            # cares about distance
+            location = data.get("client_location") or {}
+            region = location.get("region") or ""
+            if region and strategy not in ("cost", "content_type"):
+                backend = self._prefer_nearby(backend, region, content_type, content_size, available)
+            if strategy in ("performance", "latency"):
+                backend = self._prefer_measured(backend, strategy, content_type, content_size, available)
+            providers = data.get("providers")
+            if providers is not None and strategy != "cost":
+                backend = self._weigh_providers(backend, int(providers.get("count") or 0),
+                                                content_type, content_size, available)
+            if retention_days > 0:
+                backend = self._prefer_retention(backend, retention_days, strategy,
+                                                 content_type, content_size, available)
+            
+            request_id = data.get("request_id") or request["correlation_id"]
+            if not dry_run:
+                self._keep({
+                    "kind": "decision",
+                    "request_id": request_id,
+                    "backend_id": backend["name"],
+                    "content_hash": data.get("content_hash") or "",
+                    "content_type": content_type,
+                    "content_size": int(content_size or 0),
+                    "strategy": strategy,
+                    "score": backend["confidence"],
+                    "reasoning": backend["reasoning"],
+                })
+            
             return json_response({
                 "success": True,
                 "backend": backend["name"],
@@ -71,7 +392,14 @@ class HTTPRoutingServer:
                 "estimated_time": backend["estimated_time"],
                 "cost_estimate": backend["cost_estimate"],
                 "timestamp": datetime.utcnow().isoformat(),
-                "request_id": self._request_count
+                "strategy": strategy,
+                "content_category": content_category or None,
+                "dry_run": dry_run,
+                "client_location": location or None,
+                "providers": providers,
+                "retention_days": retention_days or None,
+                "request_id": request_id,
+                "correlation_id": request["correlation_id"]
             })
             
         except Exception as e:
@@ -83,6 +411,162 @@ class HTTPRoutingServer:
                 "timestamp": datetime.utcnow().isoformat()
             }, status=500)
     
+    def _prefer_nearby(self, backend: Dict[str, Any], region: str, content_type: str,
+                       content_size: int, available: Optional[List[str]]) -> Dict[str, Any]:
+        """Switch to a backend in the client's region if the chosen one is elsewhere.
+        
+        Backends registered as "global" count as near everyone.
+        """
+        chosen = BACKEND_REGISTRY.get(backend["name"], {}).get("region", "global")
+        if chosen in ("global", region):
+            return backend
+        for name, info in BACKEND_REGISTRY.items():
+            if info["region"] != region or (available is not None and name not in available):
+                continue
+            too_large = info["max_object_size"] and content_size > info["max_object_size"]
+            if not too_large and self._accepts(info["content_types"], content_type):
+                return dict(backend, name=name,
+                            reasoning=f"{backend['reasoning']}; {name} preferred as it is in the client's region {region}")
+        return backend
+    
+    def _prefer_measured(self, backend: Dict[str, Any], strategy: str, content_type: str,
+                         content_size: int, available: Optional[List[str]]) -> Dict[str, Any]:
+        """Switch to the best backend by client probe measurements, if any.
+        
+        Large content under the performance strategy goes to the highest
+        median throughput; everything else to the lowest median latency.
+        Backends without recent successful samples are not considered.
+        """
+        by_throughput = strategy == "performance" and content_size >= THROUGHPUT_ROUTING_SIZE
+        best, best_value = None, None
+        for name, info in BACKEND_REGISTRY.items():
+            if available is not None and name not in available:
+                continue
+            if info["max_object_size"] and content_size > info["max_object_size"]:
+                continue
+            if not self._accepts(info["content_types"], content_type):
+                continue
+            summary = self._probe_summary(name)
+            if summary is None:
+                continue
+            value = -summary["throughput"] if by_throughput else summary["latency_ms"]
+            if best_value is None or value < best_value:
+                best, best_value = name, value
+        if best is None or best == backend["name"]:
+            return backend
+        measure = "throughput" if by_throughput else "latency"
+        return dict(backend, name=best,
+                    reasoning=f"{backend['reasoning']}; {best} preferred for its measured {measure}")
+    
+    def _weigh_providers(self, backend: Dict[str, Any], count: int, content_type: str,
+                         content_size: int, available: Optional[List[str]]) -> Dict[str, Any]:
+        """Steer a retrieval by how many DHT peers provide the content.
+        
+        Well-provided content is read over IPFS, where any of its providers
+        can serve it; content no peer provides is read from elsewhere, as
+        IPFS would have nowhere to fetch it from.
+        """
+        on_ipfs = self._is_ipfs(backend["name"])
+        if count >= WELL_PROVIDED_COUNT and not on_ipfs:
+            want, why = True, f"{count} DHT peers provide the content"
+        elif count == 0 and on_ipfs:
+            want, why = False, "no DHT peer provides the content"
+        else:
+            return backend
+        for name, info in BACKEND_REGISTRY.items():
+            if self._is_ipfs(name) != want or (available is not None and name not in available):
+                continue
+            too_large = info["max_object_size"] and content_size > info["max_object_size"]
+            if not too_large and self._accepts(info["content_types"], content_type):
+                return dict(backend, name=name,
+                            reasoning=f"{backend['reasoning']}; {name} preferred as {why}")
+        return backend
+    
+    def _prefer_retention(self, backend: Dict[str, Any], retention_days: int, strategy: str,
+                          content_type: str, content_size: int,
+                          available: Optional[List[str]]) -> Dict[str, Any]:
+        """Steer content away from backends that bill more retention than asked for.
+        
+        A backend whose minimum retention exceeds retention_days is swapped
+        for the one storing the content most cheaply for that long; under
+        the cost strategy the cheapest is preferred whatever was chosen.
+        """
+        chosen = backend["name"]
+        minimum = BACKEND_REGISTRY.get(chosen, {}).get("min_retention_days", 0)
+        if minimum <= retention_days and strategy != "cost":
+            return backend
+        best, best_cost = None, None
+        for name, info in BACKEND_REGISTRY.items():
+            if available is not None and name not in available:
+                continue
+            if info["max_object_size"] and content_size > info["max_object_size"]:
+                continue
+            if not self._accepts(info["content_types"], content_type):
+                continue
+            rate = BACKEND_COSTS.get(name, {}).get("storage_cost_per_gb", 0.0)
+            cost = content_size / 1024 ** 3 * self._billed_months(name, retention_days) * rate
+            if best_cost is None or cost < best_cost or (cost == best_cost and name == chosen):
+                best, best_cost = name, cost
+        if best is None or best == chosen:
+            return backend
+        if minimum > retention_days:
+            why = f"{chosen} bills at least {minimum} days of storage and {retention_days} were asked for"
+        else:
+            why = f"it stores the content most cheaply for {retention_days} days"
+        return dict(backend, name=best, reasoning=f"{backend['reasoning']}; {best} preferred as {why}")
+    
+    @staticmethod
+    def _billed_months(backend: str, retention_days: int) -> float:
+        """Months of storage a backend bills for keeping content retention_days."""
+        minimum = BACKEND_REGISTRY.get(backend, {}).get("min_retention_days", 0)
+        return max(retention_days, minimum) / 30
+    
+    @staticmethod
+    def _is_ipfs(backend: str) -> bool:
+        """Whether a backend ID is of the IPFS class, e.g. "ipfs" or "ipfs-eu"."""
+        return backend == "ipfs" or backend.startswith(("ipfs-", "ipfs_", "ipfs:"))
+    
+    def _probe_summary(self, backend: str) -> Optional[Dict[str, float]]:
+        """Median latency and throughput of a backend's recent successful probes.
+        
+        Throughput is taken from samples that moved data only, so an idle
+        node reporting its statistics does not drag it to zero.
+        """
+        cutoff = datetime.utcnow().timestamp() - PROBE_MAX_AGE_SECONDS
+        recent = [p for p in self._probe_samples.get(backend, ()) if p["time"] >= cutoff and p["success"]]
+        if not recent:
+            return None
+        latencies = sorted(p["latency_ms"] for p in recent)
+        throughputs = sorted(p["throughput"] for p in recent if p["throughput"] > 0)
+        return {
+            "latency_ms": latencies[len(latencies) // 2],
+            "throughput": throughputs[len(throughputs) // 2] if throughputs else 0.0,
+            "samples": len(recent),
+        }
+    
+    @staticmethod
+    def _node_stats(node: Dict[str, Any]) -> Dict[str, Any]:
+        """Validate the transfer statistics a client reported for its node."""
+        return {
+            "peers": int(node.get("peers") or 0),
+            "wantlist_length": int(node.get("wantlist_length") or 0),
+            "blocks_received": int(node.get("blocks_received") or 0),
+            "duplicate_blocks_received": int(node.get("duplicate_blocks_received") or 0),
+            "blocks_sent": int(node.get("blocks_sent") or 0),
+            "rate_in_bytes_per_second": float(node.get("rate_in_bytes_per_second") or 0),
+            "rate_out_bytes_per_second": float(node.get("rate_out_bytes_per_second") or 0),
+        }
+    
+    def _latest_node_stats(self, backend: str) -> Optional[Dict[str, Any]]:
+        """The most recent node statistics reported for a backend, if any."""
+        cutoff = datetime.utcnow().timestamp() - PROBE_MAX_AGE_SECONDS
+        for sample in reversed(self._probe_samples.get(backend, ())):
+            if sample["time"] < cutoff:
+                break
+            if "node" in sample:
+                return sample["node"]
+        return None
+    
     async def _select_optimal_backend(self, content_type: str, content_size: int, 
                                     strategy: str, priority: str) -> Dict[str, Any]:
         """Internal backend selection logic."""
@@ -118,6 +602,11 @@ class HTTPRoutingServer:
         """Record outcome of routing decision for analytics."""
         try:
             data = await request.json()
+            if not isinstance(data, dict):
+                return json_response({
+                    "success": False,
+                    "error": "Request body must be a JSON object"
+                }, status=400)
             
             # Required fields
             required_fields = ["backend", "success", "duration_ms"]
@@ -128,6 +617,17 @@ class HTTPRoutingServer:
                         "error": f"Missing required field: {field}"
                     }, status=400)
             
+            # A retried request carries the key of the original; don't count it twice
+            if self._seen(data.get("idempotency_key")):
+                logger.info(f"Duplicate routing outcome ignored: idempotency_key={data['idempotency_key']} "
+                            f"correlation_id={request['correlation_id']}")
+                return json_response({
+                    "success": True,
+                    "duplicate": True,
+                    "message": "Outcome already recorded",
+                    "timestamp": datetime.utcnow().isoformat()
+                })
+            
             # Log outcome for analytics
             outcome_data = {
                 "backend": data["backend"],
@@ -135,11 +635,17 @@ class HTTPRoutingServer:
                 "duration_ms": data["duration_ms"],
                 "content_type": data.get("content_type"),
                 "content_size": data.get("content_size"),
+                "bytes_transferred": data.get("bytes_transferred"),
                 "error_message": data.get("error_message"),
+                "error_class": data.get("error_class"),
+                "placement": data.get("placement"),
+                "idempotency_key": data.get("idempotency_key"),
+                "correlation_id": request["correlation_id"],
                 "timestamp": datetime.utcnow().isoformat()
             }
             
             logger.info(f"Recorded routing outcome: {json.dumps(outcome_data)}")
+            self._note_outcome(data)
             
             # In a full implementation, this would store to database
             # For now, just log for analytics
@@ -150,6 +656,11 @@ class HTTPRoutingServer:
                 "timestamp": datetime.utcnow().isoformat()
             })
             
+        except json.JSONDecodeError:
+            return json_response({
+                "success": False,
+                "error": "Request body must be JSON"
+            }, status=400)
         except Exception as e:
             logger.error(f"Error recording outcome: {e}")
             return json_response({
@@ -157,6 +668,57 @@ class HTTPRoutingServer:
                 "error": str(e)
             }, status=500)
     
+    async def record_outcomes(self, request: Request) -> Response:
+        """Record a batch of routing outcomes in one request."""
+        try:
+            data = await request.json()
+            outcomes = data.get("outcomes") if isinstance(data, dict) else None
+            if not isinstance(outcomes, list):
+                return json_response({
+                    "success": False,
+                    "error": "Missing required field: outcomes"
+                }, status=400)
+            
+            recorded = 0
+            duplicates = 0
+            errors = []
+            for i, outcome in enumerate(outcomes):
+                if not isinstance(outcome, dict):
+                    errors.append(f"outcome {i}: must be an object")
+                    continue
+                missing = [f for f in ("backend", "success", "duration_ms") if f not in outcome]
+                if missing:
+                    errors.append(f"outcome {i}: missing required field: {missing[0]}")
+                    continue
+                if self._seen(outcome.get("idempotency_key")):
+                    duplicates += 1
+                    continue
+                logger.info(f"Recorded routing outcome: {json.dumps(outcome)} "
+                            f"correlation_id={request['correlation_id']}")
+                self._note_outcome(outcome)
+                recorded += 1
+            
+            return json_response({
+                "success": not errors,
+                "batch_id": data.get("batch_id"),
+                "recorded": recorded,
+                "duplicates": duplicates,
+                "errors": errors,
+                "timestamp": datetime.utcnow().isoformat()
+            })
+            
+        except json.JSONDecodeError:
+            return json_response({
+                "success": False,
+                "error": "Request body must be JSON"
+            }, status=400)
+        except Exception as e:
+            logger.error(f"Error recording outcomes: {e}")
+            return json_response({
+                "success": False,
+                "error": str(e)
+            }, status=500)
+    
     async def get_insights(self, request: Request) -> Response:
         """Get routing insights and analytics."""
         uptime_seconds = (datetime.utcnow() - self._start_time).total_seconds()
@@ -172,6 +734,8 @@ class HTTPRoutingServer:
                     "s3": 0.25,
                     "filecoin": 0.10
                 },
+                "factor_weights": dict(self._factor_weights),
+                "placement": {backend: dict(counts) for backend, counts in self._placement.items()},
                 "average_response_time_ms": 120,
                 "success_rate": 0.99,
                 "most_common_content_types": [
@@ -184,6 +748,132 @@ class HTTPRoutingServer:
             "timestamp": datetime.utcnow().isoformat()
         })
     
+    def _authorize_admin(self, request: Request) -> Optional[Response]:
+        """Check the admin bearer token, returning an error response if it fails."""
+        token = os.environ.get(ADMIN_TOKEN_ENV)
+        if not token:
+            return json_response({
+                "success": False,
+                "error": f"Admin API disabled; set {ADMIN_TOKEN_ENV} to enable it",
+                "error_type": "unauthorized"
+            }, status=403)
+        scheme, _, given = request.headers.get("Authorization", "").partition(" ")
+        if scheme.lower() != "bearer" or not hmac.compare_digest(given.strip().encode(), token.encode()):
+            return json_response({
+                "success": False,
+                "error": "Missing or invalid admin token",
+                "error_type": "unauthorized"
+            }, status=401, headers={"WWW-Authenticate": "Bearer"})
+        return None
+    
+    async def set_factor_weights(self, request: Request) -> Response:
+        """Update scoring factor weights (admin only).
+        
+        Factors not named keep their weight; the result is normalised to
+        sum to 1.
+        """
+        denied = self._authorize_admin(request)
+        if denied is not None:
+            return denied
+        try:
+            data = await request.json()
+        except json.JSONDecodeError:
+            return json_response({
+                "success": False,
+                "error": "Request body must be JSON"
+            }, status=400)
+        if not isinstance(data, dict):
+            return json_response({
+                "success": False,
+                "error": "Request body must be a JSON object"
+            }, status=400)
+        
+        weights = data.get("weights")
+        if not isinstance(weights, dict) or not weights:
+            return json_response({
+                "success": False,
+                "error": "weights must be a non-empty object",
+                "error_type": "invalid_weights"
+            }, status=400)
+        for factor, weight in weights.items():
+            if factor not in DEFAULT_FACTOR_WEIGHTS:
+                return json_response({
+                    "success": False,
+                    "error": f"Unknown factor: {factor}; expected one of {', '.join(DEFAULT_FACTOR_WEIGHTS)}",
+                    "error_type": "invalid_weights"
+                }, status=400)
+            if isinstance(weight, bool) or not isinstance(weight, (int, float)) or weight < 0:
+                return json_response({
+                    "success": False,
+                    "error": f"Weight of {factor} must be a non-negative number",
+                    "error_type": "invalid_weights"
+                }, status=400)
+        
+        updated = {**self._factor_weights, **{f: float(w) for f, w in weights.items()}}
+        total = sum(updated.values())
+        if total <= 0:
+            return json_response({
+                "success": False,
+                "error": "At least one weight must be positive",
+                "error_type": "invalid_weights"
+            }, status=400)
+        
+        previous = self._factor_weights
+        self._factor_weights = {f: w / total for f, w in updated.items()}
+        logger.info(f"Factor weights updated by admin: {weights} "
+                    f"correlation_id={request['correlation_id']}")
+        return json_response({
+            "success": True,
+            "weights": self._factor_weights,
+            "previous_weights": previous,
+            "timestamp": datetime.utcnow().isoformat()
+        })
+    
+    async def report_metrics(self, request: Request) -> Response:
+        """Store client-side probe measurements of backend latency and throughput."""
+        try:
+            data = await request.json()
+            samples = data.get("samples") or []
+            if not isinstance(samples, list):
+                raise TypeError("samples must be an array")
+        except (json.JSONDecodeError, AttributeError, TypeError):
+            return json_response({
+                "success": False,
+                "error": "Request body must be a JSON object with samples"
+            }, status=400)
+        
+        accepted = rejected = 0
+        now = datetime.utcnow().timestamp()
+        for sample in samples:
+            backend = sample.get("backend_id") if isinstance(sample, dict) else None
+            if backend not in BACKEND_REGISTRY:
+                rejected += 1
+                continue
+            try:
+                record = {
+                    "time": now,
+                    "success": bool(sample.get("success")),
+                    "latency_ms": float(sample.get("latency_ms") or 0),
+                    "throughput": float(sample.get("throughput_bytes_per_second") or 0),
+                }
+                node = sample.get("node")
+                if node is not None:
+                    record["node"] = self._node_stats(node)
+            except (TypeError, ValueError, AttributeError):
+                rejected += 1
+                continue
+            self._probe_samples.setdefault(backend, deque(maxlen=PROBE_HISTORY)).append(record)
+            accepted += 1
+        
+        logger.debug(f"Stored {accepted} probe samples from {data.get('reporter_id') or 'anonymous'}, "
+                     f"rejected {rejected}")
+        return json_response({
+            "success": True,
+            "accepted": accepted,
+            "rejected": rejected,
+            "timestamp": datetime.utcnow().isoformat()
+        })
+    
     async def get_metrics(self, request: Request) -> Response:
         """Get real-time system metrics."""
         return json_response({
@@ -200,6 +890,248 @@ class HTTPRoutingServer:
             "timestamp": datetime.utcnow().isoformat()
         })
     
+    async def get_backends(self, request: Request) -> Response:
+        """List backends with their capabilities and health.
+        
+        Health is judged from recent outcomes; backends without any are
+        reported healthy. Optional filters: backends (comma-separated),
+        content_type and content_size.
+        """
+        wanted = [b for b in request.query.get("backends", "").split(",") if b]
+        content_type = request.query.get("content_type", "")
+        try:
+            content_size = int(request.query.get("content_size", "0"))
+        except ValueError:
+            return json_response({
+                "success": False,
+                "error": "content_size must be an integer"
+            }, status=400)
+        
+        backends = {}
+        for name in sorted(set(BACKEND_REGISTRY) | set(self._backend_states)):
+            if wanted and name not in wanted:
+                continue
+            info = dict(BACKEND_REGISTRY.get(name, {}))
+            if content_size and info.get("max_object_size") and content_size > info["max_object_size"]:
+                continue
+            if content_type and not self._accepts(info.get("content_types", []), content_type):
+                continue
+            info.update(self._backend_states.get(name, {
+                "state": "healthy",
+                "reason": "no outcomes recorded yet",
+                "samples": 0,
+            }))
+            backends[name] = info
+        return json_response({
+            "success": True,
+            "backends": backends,
+            "timestamp": datetime.utcnow().isoformat()
+        })
+    
+    async def get_backend_stats(self, request: Request) -> Response:
+        """Get statistics for one backend over a recent window."""
+        backend = request.match_info["backend_id"]
+        try:
+            window = int(request.query.get("window_minutes", "60"))
+        except ValueError:
+            return json_response({
+                "success": False,
+                "error": "window_minutes must be an integer"
+            }, status=400)
+        if window <= 0:
+            window = 60
+        if backend not in BACKEND_REGISTRY and backend not in self._backend_outcomes:
+            return json_response({
+                "success": False,
+                "error": f"Unknown backend: {backend}",
+                "error_type": "unknown_backend"
+            }, status=404)
+        
+        cutoff = datetime.utcnow().timestamp() - window * 60
+        outcomes = [o for o in self._backend_outcomes.get(backend, ()) if o["time"] >= cutoff]
+        outrun = sum(1 for o in outcomes if o["error_class"] == OUTRUN_CLASS)
+        outcomes = [o for o in outcomes if o["error_class"] != OUTRUN_CLASS]
+        successes = [o for o in outcomes if o["success"]]
+        durations = sorted(o["duration_ms"] for o in outcomes)
+        
+        def percentile(p: float) -> float:
+            if not durations:
+                return 0.0
+            return durations[min(len(durations) - 1, int(p * len(durations)))]
+        
+        errors: Dict[str, int] = {}
+        for o in outcomes:
+            if not o["success"]:
+                category = o["error_class"] or self._error_category(o["error"])
+                errors[category] = errors.get(category, 0) + 1
+        if outrun:
+            errors[OUTRUN_CLASS] = outrun
+        
+        return json_response({
+            "success": True,
+            "backend_id": backend,
+            "window_minutes": window,
+            "requests": len(outcomes),
+            "successes": len(successes),
+            "success_rate": len(successes) / len(outcomes) if outcomes else 0.0,
+            "latency_ms": {
+                "p50": percentile(0.50),
+                "p90": percentile(0.90),
+                "p99": percentile(0.99),
+                "max": durations[-1] if durations else 0.0,
+                "mean": sum(durations) / len(durations) if durations else 0.0,
+            },
+            "throughput_bytes_per_second": sum(o["content_size"] for o in successes) / (window * 60),
+            "requests_per_minute": len(outcomes) / window,
+            "errors": errors,
+            "state": self._backend_states.get(backend, {}).get("state", "healthy"),
+            "node": self._latest_node_stats(backend),
+            "timestamp": datetime.utcnow().isoformat()
+        })
+    
+    @staticmethod
+    def _query_time(value: Optional[str]) -> Optional[float]:
+        """Parse an ISO 8601 query time, UTC unless it names a zone."""
+        if not value:
+            return None
+        t = datetime.fromisoformat(value)
+        return (t if t.tzinfo else t.replace(tzinfo=timezone.utc)).timestamp()
+    
+    async def get_history(self, request: Request) -> Response:
+        """Page through kept decisions and outcomes, oldest first.
+        
+        page_token is the sequence number of the last record of the
+        previous page; records evicted since are skipped.
+        """
+        query = request.query
+        try:
+            since = self._query_time(query.get("since"))
+            until = self._query_time(query.get("until"))
+            after = int(query.get("page_token") or 0)
+            page_size = int(query.get("page_size") or HISTORY_PAGE_SIZE)
+        except ValueError as e:
+            return json_response({
+                "success": False,
+                "error": f"Invalid history query: {e}"
+            }, status=400)
+        if page_size <= 0:
+            page_size = HISTORY_PAGE_SIZE
+        page_size = min(page_size, HISTORY_MAX_PAGE_SIZE)
+        kind = (query.get("kind") or "").lower().removeprefix("history_record_kind_")
+        if kind not in ("", "decision", "outcome"):
+            return json_response({
+                "success": False,
+                "error": f"Unknown history kind: {kind}"
+            }, status=400)
+        backend = query.get("backend_id") or ""
+        
+        records = []
+        next_token = ""
+        for record in self._history:
+            if record["seq"] <= after:
+                continue
+            if (kind and record["kind"] != kind or backend and record["backend_id"] != backend
+                    or since is not None and record["time"] < since
+                    or until is not None and record["time"] >= until):
+                continue
+            if len(records) == page_size:
+                next_token = str(records[-1]["seq"])
+                break
+            records.append(record)
+        
+        return json_response({
+            "success": True,
+            "records": [
+                dict(record, timestamp=datetime.fromtimestamp(record["time"], timezone.utc).isoformat())
+                for record in records
+            ],
+            "next_page_token": next_token,
+            "timestamp": datetime.utcnow().isoformat()
+        })
+    
+    async def estimate_cost(self, request: Request) -> Response:
+        """Project storage and egress cost of content on each candidate backend."""
+        try:
+            data = await request.json()
+            content_type = data.get("content_type") or "application/octet-stream"
+            content_size = int(data.get("content_size") or 0)
+            retention_days = int(data.get("retention_days") or 30)
+            retrievals = float(data.get("expected_retrievals") or 0)
+        except (json.JSONDecodeError, AttributeError, TypeError, ValueError) as e:
+            return json_response({
+                "success": False,
+                "error": f"Invalid request: {e}"
+            }, status=400)
+        if content_size < 0 or retention_days < 0 or retrievals < 0:
+            return json_response({
+                "success": False,
+                "error": "content_size, retention_days and expected_retrievals must not be negative"
+            }, status=400)
+        
+        candidates = data.get("backends") or list(BACKEND_REGISTRY)
+        if not isinstance(candidates, list) or not all(isinstance(b, str) for b in candidates):
+            return json_response({
+                "success": False,
+                "error": "backends must be an array of backend IDs"
+            }, status=400)
+        unknown = [b for b in candidates if b not in BACKEND_REGISTRY]
+        if unknown:
+            return json_response({
+                "success": False,
+                "error": f"Unknown backend: {', '.join(unknown)}",
+                "error_type": "unknown_backend"
+            }, status=404)
+        
+        size_gb = content_size / 1024 ** 3
+        estimates = []
+        for backend in candidates:
+            info = BACKEND_REGISTRY[backend]
+            if info["max_object_size"] and content_size > info["max_object_size"]:
+                continue
+            if not self._accepts(info["content_types"], content_type):
+                continue
+            rates = BACKEND_COSTS.get(backend, {})
+            storage_rate = rates.get("storage_cost_per_gb", 0.0)
+            egress_rate = rates.get("retrieval_cost_per_gb", 0.0)
+            storage = size_gb * self._billed_months(backend, retention_days) * storage_rate
+            egress = size_gb * retrievals * egress_rate
+            estimates.append({
+                "backend_id": backend,
+                "storage_cost": storage,
+                "egress_cost": egress,
+                "total_cost": storage + egress,
+                "storage_cost_per_gb_month": storage_rate,
+                "egress_cost_per_gb": egress_rate,
+            })
+        estimates.sort(key=lambda e: e["total_cost"])
+        
+        return json_response({
+            "success": True,
+            "estimates": estimates,
+            "currency": COST_CURRENCY,
+            "timestamp": datetime.utcnow().isoformat()
+        })
+    
+    @staticmethod
+    def _error_category(message: str) -> str:
+        """Classify an outcome's error message for backend statistics."""
+        message = message.lower()
+        for category, needles in ERROR_CATEGORIES:
+            if any(needle in message for needle in needles):
+                return category
+        return "other"
+    
+    @staticmethod
+    def _accepts(patterns: List[str], content_type: str) -> bool:
+        """Whether content_type matches one of patterns such as "image/*"."""
+        if not patterns:
+            return True
+        mime = content_type.split(";")[0].strip().lower()
+        for pattern in patterns:
+            if pattern == mime or (pattern.endswith("/*") and mime.startswith(pattern[:-1])):
+                return True
+        return False
+    
     async def health_check(self, request: Request) -> Response:
         """Health check endpoint."""
         return json_response({
@@ -239,14 +1171,24 @@ class HTTPRoutingServer:
             "version": "1.0.0",
             "description": "HTTP REST API for IPFS Kit routing functionality (replaces deprecated gRPC)",
             "base_url": f"http://{self.host}:{self.port}",
+            "headers": {
+                CORRELATION_HEADER: "optional on requests; echoed on every response and logged with it"
+            },
             "endpoints": {
                 "POST /api/v1/select-backend": {
                     "description": "Select optimal storage backend",
                     "parameters": {
                         "content_type": "string (optional)",
                         "content_size": "integer (optional)",
-                        "strategy": "string (optional): hybrid|performance|cost",
-                        "priority": "string (optional): balanced|speed|storage"
+                        "strategy": "string (optional): hybrid|performance|cost|content_type|latency|geographic|reliability|balanced; others fail with error_type invalid_strategy",
+                        "content_category": "string (optional): image|video|audio|document|dataset|model|archive|binary",
+                        "available_backends": "array (optional): backends to choose from; an empty list fails with error_type no_backend_available",
+                        "priority": "string (optional): balanced|speed|storage",
+                        "dry_run": "boolean (optional): decide and explain without counting the request",
+                        "client_location": "object (optional): region, zone, network, provider, latitude, longitude; backends in the client's region are preferred unless the strategy is cost or content_type",
+                        "providers": "object (optional): count and limit of a DHT provider lookup, for retrievals; well-provided content is read over IPFS and unprovided content elsewhere, unless the strategy is cost",
+                        "retention_days": "integer (optional): how long the content is to be stored; backends billing a longer minimum are avoided, and the cost strategy picks the cheapest storage for that long",
+                        "request_id": "string (optional): echoed back, defaults to the correlation ID"
                     },
                     "example": {
                         "content_type": "image/jpeg",
@@ -261,7 +1203,18 @@ class HTTPRoutingServer:
                         "success": "boolean (required)", 
                         "duration_ms": "integer (required)",
                         "content_type": "string (optional)",
-                        "error_message": "string (optional)"
+                        "error_message": "string (optional)",
+                        "error_class": "string (optional): failure category, e.g. integrity for content that failed CID verification, or outrun for a read cancelled because another backend answered first (not counted against the backend); inferred from error_message when absent",
+                        "bytes_transferred": "integer (optional): bytes actually moved, used for throughput instead of content_size",
+                        "placement": "array of strings (optional): where within the backend the content landed, e.g. cluster peer IDs; tallied per backend in insights",
+                        "idempotency_key": "string (optional): outcomes with a key already recorded in the last 24h are ignored and reported as duplicate"
+                    }
+                },
+                "POST /api/v1/record-outcomes": {
+                    "description": "Record a batch of routing decision outcomes",
+                    "parameters": {
+                        "outcomes": "array (required) of record-outcome bodies",
+                        "batch_id": "string (optional)"
                     }
                 },
                 "GET /api/v1/insights": {
@@ -270,6 +1223,54 @@ class HTTPRoutingServer:
                 "GET /api/v1/metrics": {
                     "description": "Get real-time system metrics"
                 },
+                "POST /api/v1/metrics/report": {
+                    "description": "Report client-side probe measurements; recent ones steer the performance and latency strategies",
+                    "parameters": {
+                        "samples": "array (required) of {backend_id, endpoint, success, latency_ms, throughput_bytes_per_second, bytes, error, node}; node (optional) is {peers, wantlist_length, blocks_received, duplicate_blocks_received, blocks_sent, rate_in_bytes_per_second, rate_out_bytes_per_second} for a node the client runs",
+                        "reporter_id": "string (optional)"
+                    }
+                },
+                "GET /api/v1/backends": {
+                    "description": "List backends with capabilities and health (healthy|degraded|unavailable) from recent outcomes",
+                    "parameters": {
+                        "backends": "string (optional): comma-separated backends to include",
+                        "content_type": "string (optional): only backends accepting this MIME type",
+                        "content_size": "integer (optional): only backends accepting objects this large"
+                    }
+                },
+                "GET /api/v1/backends/{backend_id}/stats": {
+                    "description": "Success rate, latency percentiles, throughput and errors by category for one backend, with the latest node statistics a client reported for it",
+                    "parameters": {
+                        "window_minutes": "integer (optional): window to summarise (default: 60)"
+                    }
+                },
+                "GET /api/v1/history": {
+                    "description": f"Decisions and outcomes, oldest first, of the last {ROUTING_HISTORY} kept; dry runs are not kept",
+                    "parameters": {
+                        "since": "ISO 8601 time (optional, UTC unless zoned): only records at or after it",
+                        "until": "ISO 8601 time (optional, UTC unless zoned): only records before it",
+                        "kind": "string (optional): decision|outcome",
+                        "backend_id": "string (optional): only records for this backend",
+                        "page_size": f"integer (optional): default {HISTORY_PAGE_SIZE}, at most {HISTORY_MAX_PAGE_SIZE}",
+                        "page_token": "string (optional): next_page_token of the previous page, empty on the last"
+                    }
+                },
+                "POST /api/v1/estimate-cost": {
+                    "description": "Projected storage and egress cost per backend accepting the content, cheapest first",
+                    "parameters": {
+                        "content_type": "string (optional)",
+                        "content_size": "integer (required): bytes",
+                        "retention_days": "integer (optional): default 30; backends with a longer minimum retention are billed for the minimum",
+                        "expected_retrievals": "number (optional): full reads over the retention period",
+                        "backends": "array (optional): candidates, default all"
+                    }
+                },
+                "PUT /api/v1/admin/factor-weights": {
+                    "description": f"Update scoring factor weights; requires Authorization: Bearer <{ADMIN_TOKEN_ENV}>",
+                    "parameters": {
+                        "weights": "object (required): factor -> non-negative weight; factors: " + ", ".join(DEFAULT_FACTOR_WEIGHTS) + ". Others keep their weight and the result is normalised"
+                    }
+                },
                 "GET /health": {
                     "description": "Basic health check"
                 },
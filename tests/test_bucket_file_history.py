@@ -0,0 +1,67 @@
+import shutil
+import tempfile
+import unittest
+
+from fastapi.testclient import TestClient
+
+from ipfs_kit_py.mcp.dashboard.consolidated_mcp_dashboard import ConsolidatedMCPDashboard
+
+
+class TestBucketFileHistory(unittest.TestCase):
+    def setUp(self):
+        self.tmpdir = tempfile.mkdtemp(prefix='ipfs_kit_test_')
+        cfg = {'host': '127.0.0.1', 'port': 0, 'data_dir': self.tmpdir}
+        self.app = ConsolidatedMCPDashboard(cfg)
+        self.client = TestClient(self.app.app)
+        self.call_tool('create_bucket', {'name': 'docs'})
+
+    def tearDown(self):
+        shutil.rmtree(self.tmpdir, ignore_errors=True)
+
+    def call_tool(self, name, args=None):
+        r = self.client.post('/mcp/tools/call', json={'jsonrpc': '2.0', 'method': 'tools/call', 'id': 1, 'params': {'name': name, 'arguments': args or {}}})
+        self.assertEqual(r.status_code, 200)
+        body = r.json()
+        if 'error' in body:
+            return body
+        # tools answering with a JSON-RPC envelope pass through unwrapped
+        result = body['result']
+        return result.get('structuredContent', result)
+
+    def history(self, **args):
+        res = self.call_tool('bucket_file_history', dict(args, bucket='docs'))
+        return [(e['op'], e['path']) for e in res['events']]
+
+    def test_upload_rename_and_delete(self):
+        self.call_tool('bucket_upload_file', {'bucket': 'docs', 'path': 'a.txt', 'content': 'hello', 'actor': 'alice'})
+        self.call_tool('bucket_rename_file', {'bucket': 'docs', 'src': 'a.txt', 'dst': 'b.txt'})
+        self.assertEqual(self.history(path='b.txt'), [('upload', 'b.txt'), ('rename', 'b.txt')])
+        self.assertEqual(self.history(path='a.txt'), [])
+
+        res = self.call_tool('bucket_file_history', {'bucket': 'docs', 'path': 'b.txt', 'actor': 'alice'})
+        self.assertEqual(res['events'][0]['size'], 5)
+        rename = self.call_tool('bucket_file_history', {'bucket': 'docs', 'path': 'b.txt', 'op': 'rename'})['events'][0]
+        self.assertEqual(rename['from'], 'a.txt')
+
+        self.call_tool('bucket_delete_file', {'bucket': 'docs', 'path': 'b.txt'})
+        self.assertEqual(self.history(path='b.txt', limit=1), [('delete', 'b.txt')])
+
+    def test_metadata_includes_history(self):
+        self.call_tool('bucket_upload_file', {'bucket': 'docs', 'path': 'a.txt', 'content': 'hello'})
+        self.call_tool('generate_bucket_share_link', {'bucket': 'docs', 'shared_with': 'bob'})
+        res = self.call_tool('bucket_get_metadata', {'bucket': 'docs', 'path': 'a.txt'})
+        self.assertEqual([e['op'] for e in res['history']], ['upload', 'share'])
+        self.assertEqual(res['history'][1]['shared_with'], 'bob')
+
+    def test_record_external_event(self):
+        res = self.call_tool('bucket_record_file_event', {'bucket': 'docs', 'path': 'a.txt', 'op': 'migrate', 'actor': 'routing-cli', 'details': {'from': 'ipfs', 'to': 's3'}})
+        self.assertTrue(res['ok'])
+        event = self.call_tool('bucket_file_history', {'bucket': 'docs', 'path': 'a.txt'})['events'][0]
+        self.assertEqual((event['op'], event['actor'], event['to']), ('migrate', 'routing-cli', 's3'))
+
+        res = self.call_tool('bucket_record_file_event', {'bucket': 'docs', 'path': 'a.txt', 'op': 'explode'})
+        self.assertEqual(res['error']['code'], 400)
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,106 @@
+import base64
+import importlib.util
+import io
+import os
+import shutil
+import tempfile
+import unittest
+
+from fastapi.testclient import TestClient
+
+from ipfs_kit_py.mcp.dashboard.consolidated_mcp_dashboard import ConsolidatedMCPDashboard
+
+HAVE_PIL = importlib.util.find_spec('PIL') is not None
+HAVE_MARKDOWN = importlib.util.find_spec('markdown') is not None
+
+
+class TestBucketServeTransforms(unittest.TestCase):
+    def setUp(self):
+        self.tmpdir = tempfile.mkdtemp(prefix='ipfs_kit_test_')
+        cfg = {'host': '127.0.0.1', 'port': 0, 'data_dir': self.tmpdir}
+        self.app = ConsolidatedMCPDashboard(cfg)
+        self.client = TestClient(self.app.app)
+        r = self.client.post('/api/state/buckets', json={'name': 'site'})
+        self.assertEqual(r.status_code, 200)
+
+    def tearDown(self):
+        shutil.rmtree(self.tmpdir, ignore_errors=True)
+
+    def upload(self, path, content, mode='text'):
+        r = self.client.post('/mcp/tools/call', json={'jsonrpc': '2.0', 'method': 'tools/call', 'id': 1, 'params': {'name': 'bucket_upload_file', 'arguments': {'bucket': 'site', 'path': path, 'content': content, 'mode': mode}}})
+        self.assertNotIn('error', r.json())
+
+    def enable(self, *transforms):
+        r = self.client.post('/api/state/buckets/site/policy', json={'transforms': list(transforms)})
+        self.assertEqual(r.status_code, 200, r.text)
+
+    def derivatives(self):
+        root = self.app.paths.derivatives_dir
+        return sorted(os.listdir(root)) if root.exists() else []
+
+    def test_json_is_pretty_printed_through_the_cache(self):
+        self.upload('data.json', '{"b":1,"a":[1,2]}')
+        r = self.client.get('/api/buckets/site/download/data.json')
+        self.assertEqual(r.text, '{"b":1,"a":[1,2]}')
+
+        self.enable('json')
+        r = self.client.get('/api/buckets/site/download/data.json')
+        self.assertEqual(r.status_code, 200)
+        self.assertEqual(r.text, '{\n  "b": 1,\n  "a": [\n    1,\n    2\n  ]\n}\n')
+        self.assertEqual(len(self.derivatives()), 1)
+        etag = r.headers['etag']
+        self.assertEqual(self.client.get('/api/buckets/site/download/data.json', headers={'If-None-Match': etag}).status_code, 304)
+        self.assertEqual(self.client.get('/api/buckets/site/download/data.json', params={'raw': 'true'}).text, '{"b":1,"a":[1,2]}')
+
+        # an edited file gets a new derivative
+        self.upload('data.json', '[3]')
+        r = self.client.get('/api/buckets/site/download/data.json')
+        self.assertEqual(r.text, '[\n  3\n]\n')
+        self.assertNotEqual(r.headers['etag'], etag)
+        self.assertEqual(len(self.derivatives()), 2)
+
+    def test_invalid_json_is_served_as_stored(self):
+        self.enable('json')
+        self.upload('broken.json', '{"a":')
+        r = self.client.get('/api/buckets/site/download/broken.json')
+        self.assertEqual((r.status_code, r.text), (200, '{"a":'))
+        self.assertEqual(self.derivatives(), [])
+
+    def test_policy_validates_transforms(self):
+        r = self.client.post('/api/state/buckets/site/policy', json={'transforms': ['gif']})
+        self.assertEqual(r.status_code, 400)
+        self.enable('markdown', 'json')
+        pol = self.client.get('/api/state/buckets/site/policy').json()['policy']
+        self.assertEqual(pol['transforms'], ['json', 'markdown'])
+        self.enable()
+        pol = self.client.get('/api/state/buckets/site/policy').json()['policy']
+        self.assertNotIn('transforms', pol)
+
+    @unittest.skipUnless(HAVE_MARKDOWN, 'markdown is not installed')
+    def test_markdown_is_rendered(self):
+        self.enable('markdown')
+        self.upload('README.md', '# Title\n\n*hi*\n')
+        r = self.client.get('/api/buckets/site/download/README.md')
+        self.assertTrue(r.headers['content-type'].startswith('text/html'))
+        self.assertIn('<h1>Title</h1>', r.text)
+        self.assertIn("default-src 'none'", r.headers['content-security-policy'])
+
+    @unittest.skipUnless(HAVE_PIL, 'Pillow is not installed')
+    def test_image_is_resized(self):
+        from PIL import Image
+        buf = io.BytesIO()
+        Image.new('RGB', (40, 20), 'red').save(buf, format='PNG')
+        self.upload('red.png', base64.b64encode(buf.getvalue()).decode(), mode='base64')
+
+        self.assertEqual(self.client.get('/api/buckets/site/download/red.png', params={'w': 10}).content, buf.getvalue())
+        self.enable('image')
+        r = self.client.get('/api/buckets/site/download/red.png', params={'w': 10})
+        self.assertEqual(r.status_code, 200)
+        self.assertEqual(Image.open(io.BytesIO(r.content)).size, (10, 5))
+        self.assertEqual(self.client.get('/api/buckets/site/download/red.png').content, buf.getvalue())
+        self.assertEqual(self.client.get('/api/buckets/site/download/red.png', params={'w': 0}).status_code, 400)
+        self.assertEqual(self.client.get('/api/buckets/site/download/red.png', params={'h': 'x'}).status_code, 400)
+
+
+if __name__ == '__main__':
+    unittest.main()
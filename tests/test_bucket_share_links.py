@@ -0,0 +1,103 @@
+import base64
+import json
+import shutil
+import tempfile
+import unittest
+from urllib.parse import parse_qs, urlparse
+
+from cryptography.hazmat.primitives.asymmetric.ed25519 import Ed25519PublicKey
+from fastapi.testclient import TestClient
+
+from ipfs_kit_py.mcp.dashboard.consolidated_mcp_dashboard import ConsolidatedMCPDashboard
+
+# CIDv1 (raw, sha2-256) of b"hello", as `ipfs add --raw-leaves --cid-version=1` gives it
+HELLO_CID = 'bafkreibm6jg3ux5qumhcn2b3flc3tyu6dmlb4xa7u5bf44yegnrjhc4yeq'
+
+
+class TestBucketShareLinks(unittest.TestCase):
+    def setUp(self):
+        self.tmpdir = tempfile.mkdtemp(prefix='ipfs_kit_test_')
+        cfg = {'host': '127.0.0.1', 'port': 0, 'data_dir': self.tmpdir}
+        self.app = ConsolidatedMCPDashboard(cfg)
+        self.client = TestClient(self.app.app)
+        for bucket in ('docs', 'other'):
+            self.call_tool('create_bucket', {'name': bucket})
+        self.call_tool('bucket_upload_file', {'bucket': 'docs', 'path': 'data/a.txt', 'content': 'hello'})
+
+    def tearDown(self):
+        shutil.rmtree(self.tmpdir, ignore_errors=True)
+
+    def call_tool(self, name, args=None):
+        r = self.client.post('/mcp/tools/call', json={'jsonrpc': '2.0', 'method': 'tools/call', 'id': 1, 'params': {'name': name, 'arguments': args or {}}})
+        self.assertEqual(r.status_code, 200)
+        body = r.json()
+        if 'error' in body:
+            return body
+        # tools answering with a JSON-RPC envelope pass through unwrapped
+        result = body['result']
+        return result.get('structuredContent', result)
+
+    def share(self, bucket='docs', expiration='never'):
+        res = self.call_tool('generate_bucket_share_link', {'bucket': bucket, 'expiration': expiration})
+        return res['share_link'], res['token']
+
+    def test_link_lists_and_streams_files(self):
+        link, token = self.share()
+        r = self.client.get(link)
+        self.assertEqual(r.status_code, 200)
+        files = r.json()['files']
+        self.assertEqual([(f['path'], f['size']) for f in files], [('data/a.txt', 5)])
+        self.assertEqual(parse_qs(urlparse(files[0]['url']).query)['token'], [token])
+
+        r = self.client.get(files[0]['url'])
+        self.assertEqual(r.status_code, 200)
+        self.assertEqual(r.content, b'hello')
+        self.assertEqual(r.headers['etag'], f'"{HELLO_CID}"')
+
+        r = self.client.get(files[0]['url'], headers={'If-None-Match': f'"{HELLO_CID}"'})
+        self.assertEqual(r.status_code, 304)
+
+    def test_receipt_verifies(self):
+        _, token = self.share()
+        r = self.client.get('/shared/docs/data/a.txt', params={'token': token, 'receipt': 'true'})
+        self.assertEqual(r.status_code, 200)
+        receipt = r.json()
+        self.assertEqual((receipt['cid'], receipt['bytes'], receipt['path']), (HELLO_CID, 5, 'data/a.txt'))
+
+        key = self.client.get('/api/shares/receipt-key').json()['public_key']
+        self.assertEqual(receipt['public_key'], key)
+        signature = base64.b64decode(receipt.pop('signature'))
+        payload = json.dumps(receipt, sort_keys=True, separators=(',', ':')).encode()
+        public = Ed25519PublicKey.from_public_bytes(base64.b64decode(key))
+        public.verify(signature, payload)  # raises InvalidSignature
+
+        receipt['bytes'] = 6
+        tampered = json.dumps(receipt, sort_keys=True, separators=(',', ':')).encode()
+        with self.assertRaises(Exception):
+            public.verify(signature, tampered)
+
+    def test_token_is_checked(self):
+        _, token = self.share()
+        _, other = self.share('other')
+        self.assertEqual(self.client.get('/shared/docs/data/a.txt').status_code, 404)
+        self.assertEqual(self.client.get('/shared/docs/data/a.txt', params={'token': 'guess'}).status_code, 404)
+        self.assertEqual(self.client.get('/shared/docs/data/a.txt', params={'token': other}).status_code, 404)
+        self.assertEqual(self.client.get('/shared/docs/data/missing.txt', params={'token': token}).status_code, 404)
+
+    def test_expired_link_is_gone(self):
+        link, token = self.share(expiration='1h')
+        self.assertEqual(self.client.get(link).status_code, 200)
+
+        links_file = self.app.paths.share_links_file
+        links = json.loads(links_file.read_text())
+        links[token]['expires_at'] = '2000-01-01T00:00:00+00:00'
+        links_file.write_text(json.dumps(links))
+        self.assertEqual(self.client.get(link).status_code, 410)
+        self.assertEqual(self.client.get('/shared/docs/data/a.txt', params={'token': token}).status_code, 410)
+
+        res = self.call_tool('generate_bucket_share_link', {'bucket': 'docs', 'expiration': '1y'})
+        self.assertEqual(res['error']['code'], -32602)
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,154 @@
+import json
+import shutil
+import tempfile
+import unittest
+
+from fastapi import HTTPException
+from fastapi.testclient import TestClient
+
+from ipfs_kit_py.mcp.dashboard.consolidated_mcp_dashboard import (
+    ConsolidatedMCPDashboard,
+    _MAX_PAGE_SIZE,
+    _paginate,
+)
+
+
+FILES = [
+    {"name": "a.txt", "path": "a.txt", "size": 10, "mime_type": "text/plain", "modified": "2026-01-01T00:00:00Z"},
+    {"name": "b.png", "path": "b.png", "size": 300, "mime_type": "image/png", "modified": "2026-02-01T00:00:00Z"},
+    {"name": "c.txt", "path": "c.txt", "size": 20, "mime_type": "text/plain", "modified": "2026-03-01T00:00:00Z"},
+    {"name": "docs", "path": "docs", "size": 0, "is_dir": True, "modified": "2026-04-01T00:00:00Z"},
+    {"name": "d.png", "path": "d.png", "size": 300, "mime_type": "image/png", "modified": "2026-05-01T00:00:00"},
+]
+SORT_FIELDS = ("name", "size", "modified")
+
+
+def names(items):
+    return [it["name"] for it in items]
+
+
+class TestPaginate(unittest.TestCase):
+    def page(self, **args):
+        return _paginate(FILES, args, "path", SORT_FIELDS)
+
+    def test_unpaged(self):
+        page, cursor, total = self.page()
+        self.assertEqual(names(page), ["a.txt", "b.png", "c.txt", "d.png", "docs"])
+        self.assertIsNone(cursor)
+        self.assertEqual(total, 5)
+
+    def test_pages_cover_every_item_once(self):
+        for order in ("asc", "desc"):
+            for sort_by in SORT_FIELDS:
+                seen, cursor = [], None
+                while True:
+                    page, cursor, total = self.page(sort_by=sort_by, order=order, limit=2, cursor=cursor)
+                    self.assertLessEqual(len(page), 2)
+                    seen += names(page)
+                    if cursor is None:
+                        break
+                self.assertEqual(sorted(seen), sorted(names(FILES)), (sort_by, order))
+                self.assertEqual(total, 5)
+
+    def test_ties_break_on_id(self):
+        page, _, _ = self.page(sort_by="size", order="desc", limit=2)
+        self.assertEqual(names(page), ["d.png", "b.png"])
+
+    def test_pages_stay_stable_when_items_change(self):
+        items = list(FILES)
+        page, cursor, _ = _paginate(items, {"limit": 2}, "path", SORT_FIELDS)
+        self.assertEqual(names(page), ["a.txt", "b.png"])
+        # an item sorting before the cursor and the removal of one already
+        # returned must not shift the next page
+        items.insert(0, {"name": "0.txt", "path": "0.txt", "size": 1})
+        items = [it for it in items if it["name"] != "a.txt"]
+        page, _, _ = _paginate(items, {"limit": 2, "cursor": cursor}, "path", SORT_FIELDS)
+        self.assertEqual(names(page), ["c.txt", "d.png"])
+
+    def test_filters(self):
+        self.assertEqual(names(self.page(prefix="d")[0]), ["d.png", "docs"])
+        self.assertEqual(names(self.page(type="directory")[0]), ["docs"])
+        self.assertEqual(names(self.page(type="file")[0]), ["a.txt", "b.png", "c.txt", "d.png"])
+        self.assertEqual(names(self.page(type="image/")[0]), ["b.png", "d.png"])
+        self.assertEqual(names(self.page(min_size=20, max_size=300)[0]), ["b.png", "c.txt", "d.png"])
+        page, _, total = self.page(modified_after="2026-01-15T00:00:00Z", modified_before="2026-04-01T00:00:00Z")
+        self.assertEqual(names(page), ["b.png", "c.txt"])
+        self.assertEqual(total, 2)
+        # times without a zone are UTC
+        self.assertEqual(names(self.page(modified_after="2026-04-30T23:00:00+00:00")[0]), ["d.png"])
+
+    def test_limit_is_clamped(self):
+        page, cursor, _ = self.page(limit=-5)
+        self.assertEqual(len(page), 1)
+        self.assertIsNotNone(cursor)
+        items = [{"name": f"{i:05d}", "path": f"{i:05d}"} for i in range(_MAX_PAGE_SIZE + 5)]
+        page, cursor, total = _paginate(items, {"limit": _MAX_PAGE_SIZE * 2}, "path", SORT_FIELDS)
+        self.assertEqual(len(page), _MAX_PAGE_SIZE)
+        self.assertIsNotNone(cursor)
+        self.assertEqual(total, _MAX_PAGE_SIZE + 5)
+
+    def test_invalid_arguments(self):
+        _, name_cursor, _ = self.page(limit=1)
+        for args in (
+            {"sort_by": "owner"},
+            {"order": "sideways"},
+            {"limit": "ten"},
+            {"limit": True},
+            {"min_size": "big"},
+            {"max_size": [1]},
+            {"modified_after": "yesterday"},
+            {"cursor": "not a cursor"},
+            {"cursor": "WzFd"},  # [1]
+            {"sort_by": "size", "cursor": name_cursor},
+        ):
+            with self.assertRaises(HTTPException, msg=args) as cm:
+                self.page(**args)
+            self.assertEqual(cm.exception.status_code, 400, args)
+
+
+class TestPinPages(unittest.TestCase):
+    @classmethod
+    def setUpClass(cls):
+        cls.tmpdir = tempfile.mkdtemp(prefix='ipfs_kit_test_')
+        cfg = {'host': '127.0.0.1', 'port': 0, 'data_dir': cls.tmpdir}
+        cls.app = ConsolidatedMCPDashboard(cfg)
+        cls.client = TestClient(cls.app.app)
+        pins = [{"cid": f"bafy{i}", "name": f"pin-{i}"} for i in range(5)]
+        cls.app.paths.pins_file.write_text(json.dumps(pins), encoding='utf-8')
+
+    @classmethod
+    def tearDownClass(cls):
+        shutil.rmtree(cls.tmpdir, ignore_errors=True)
+
+    def call_tool(self, name, args=None):
+        r = self.client.post('/mcp/tools/call', json={'jsonrpc': '2.0', 'method': 'tools/call', 'id': 1, 'params': {'name': name, 'arguments': args or {}}})
+        self.assertEqual(r.status_code, 200)
+        body = r.json()
+        if 'error' in body:
+            return body
+        return body['result']['structuredContent']
+
+    def test_list_pins_stays_a_list(self):
+        res = self.call_tool('list_pins', {'limit': 2})
+        self.assertIsInstance(res, list)
+        self.assertEqual(len(res), 5)
+
+    def test_list_pins_page(self):
+        res = self.call_tool('list_pins_page', {'limit': 2, 'order': 'desc'})
+        self.assertEqual([p['cid'] for p in res['items']], ['bafy4', 'bafy3'])
+        self.assertEqual(res['total_count'], 5)
+        res = self.call_tool('list_pins_page', {'limit': 2, 'order': 'desc', 'cursor': res['next_cursor']})
+        self.assertEqual([p['cid'] for p in res['items']], ['bafy2', 'bafy1'])
+        res = self.call_tool('list_pins_page', {'prefix': 'pin-3'})
+        self.assertEqual([p['cid'] for p in res['items']], ['bafy3'])
+        self.assertIsNone(res['next_cursor'])
+
+    def test_list_pins_page_rejects_bad_arguments(self):
+        res = self.call_tool('list_pins_page', {'sort_by': 'size'})
+        self.assertEqual(res['error']['code'], 400)
+        res = self.call_tool('list_pins_page', {'cursor': '!!'})
+        self.assertEqual(res['error']['code'], 400)
+
+
+if __name__ == '__main__':
+    unittest.main()
@@ -0,0 +1,202 @@
+import json
+import tempfile
+import time
+import unittest
+from pathlib import Path
+
+from ipfs_kit_py.mcp.extensions.key_rotation import (
+    ACTIVE,
+    FINALIZED,
+    ROLLED_BACK,
+    KeyRotation,
+    KeyRotationError,
+)
+
+
+class FakeResponse:
+    def __init__(self, status_code=200):
+        self.status_code = status_code
+
+
+class FakePool:
+    def __init__(self):
+        self.posts = []
+
+    def post(self, path, params=None):
+        self.posts.append((path, params))
+        return FakeResponse()
+
+
+class FakeKeyManager:
+    """A keystore of name -> key ID mirroring KeyManager's results"""
+
+    def __init__(self, keys):
+        self.keys = dict(keys)
+        self.next_id = 0
+
+    def get_key(self, name):
+        if name not in self.keys:
+            return {"success": False, "error": "not found"}
+        return {"success": True, "key": {"Name": name, "Id": self.keys[name]}}
+
+    def rotate_key(self, name, new_key_type=None, size=None, preserve_old=True, options=None):
+        old_name = None
+        if preserve_old:
+            old_name = f"{name}-{int(time.time())}"
+            self.keys[old_name] = self.keys[name]
+        self.next_id += 1
+        self.keys[name] = f"k51new{self.next_id}"
+        return {"success": True, "new_key_id": self.keys[name], "old_key_name": old_name}
+
+    def rename_key(self, old_name, new_name, force=False):
+        self.keys[new_name] = self.keys.pop(old_name)
+        return {"success": True}
+
+    def remove_key(self, name):
+        if self.keys.pop(name, None) is None:
+            return {"success": False, "error": "not found"}
+        return {"success": True}
+
+
+class FakeIPNS:
+    def __init__(self, keys, records):
+        self.key_manager = FakeKeyManager(keys)
+        self.connection_pool = FakePool()
+        self.records = dict(records)
+
+    def resolve(self, name, recursive=True, nocache=False):
+        if name not in self.records:
+            return {"success": False, "error": "not found"}
+        return {"success": True, "value": self.records[name]}
+
+    def publish(self, cid, key_name="self", lifetime=None, ttl=None, resolve=True):
+        self.records[self.key_manager.keys[key_name]] = cid
+        return {"success": True, "name": self.key_manager.keys[key_name], "value": cid}
+
+
+class TestIPNSKeyRotation(unittest.TestCase):
+    def setUp(self):
+        self.tmpdir = tempfile.TemporaryDirectory()
+        self.ipns = FakeIPNS({"site": "k51old"}, {"k51old": "/ipfs/bafysite"})
+        self.rotation = KeyRotation(self.ipns, {"key_rotation_state": str(Path(self.tmpdir.name) / "rotations.json")})
+
+    def tearDown(self):
+        self.tmpdir.cleanup()
+
+    def test_rotate_republishes_and_reannounces(self):
+        result = self.rotation.rotate("site")
+        self.assertTrue(result["success"], result)
+        record = result["rotation"]
+        self.assertEqual(record["old_key_id"], "k51old")
+        self.assertEqual(record["new_key_id"], "k51new1")
+        self.assertTrue(record["republished"])
+        self.assertTrue(record["reannounced"])
+        self.assertEqual(self.ipns.records["k51new1"], "/ipfs/bafysite")
+        self.assertIn(("routing/provide", {"arg": "bafysite", "recursive": "true"}), self.ipns.connection_pool.posts)
+        self.assertEqual(self.rotation.list_rotations()["rotations"][0]["status"], ACTIVE)
+
+    def test_rollback_restores_old_key(self):
+        record = self.rotation.rotate("site")["rotation"]
+        result = self.rotation.rollback(record["id"])
+        self.assertTrue(result["success"], result)
+        self.assertEqual(self.ipns.key_manager.keys, {"site": "k51old"})
+        self.assertEqual(result["rotation"]["status"], ROLLED_BACK)
+        self.assertFalse(self.rotation.rollback(record["id"])["success"])
+
+    def test_rollback_window_keeps_old_key_until_it_closes(self):
+        record = self.rotation.rotate("site", preserve_old=False, options={"rollback_window": 60})["rotation"]
+        self.assertIn(record["old_key_name"], self.ipns.key_manager.keys)
+
+        # close the window
+        state = Path(self.rotation.state_file)
+        data = json.loads(state.read_text())
+        data["rotations"][0]["rollback_until"] = time.time() - 1
+        state.write_text(json.dumps(data))
+
+        self.assertEqual(self.rotation.list_rotations()["rotations"][0]["status"], FINALIZED)
+        self.assertNotIn(record["old_key_name"], self.ipns.key_manager.keys)
+        self.assertFalse(self.rotation.rollback(record["id"])["success"])
+
+    def test_no_rollback_window(self):
+        record = self.rotation.rotate("site", preserve_old=False, options={"rollback_window": 0})["rotation"]
+        self.assertIsNone(record["old_key_name"])
+        self.assertEqual(self.ipns.key_manager.keys, {"site": "k51new1"})
+        self.assertFalse(self.rotation.rollback(record["id"])["success"])
+
+    def test_unknown_rotation(self):
+        self.assertFalse(self.rotation.rollback("nope")["success"])
+
+
+class TestIdentityRotation(unittest.TestCase):
+    def setUp(self):
+        self.tmpdir = tempfile.TemporaryDirectory()
+        repo = Path(self.tmpdir.name) / "repo"
+        repo.mkdir()
+        (repo / "config").write_text(json.dumps({"Identity": {"PeerID": "12D3new", "PrivKey": "new"}}))
+        self.repo = repo
+        self.rotation = KeyRotation(FakeIPNS({}, {}), {
+            "key_rotation_state": str(Path(self.tmpdir.name) / "rotations.json"),
+            "ipfs_path": str(repo),
+            "peering_partners": ["http://partner:5001"],
+        })
+        self.peer_id = "12D3old"
+        self.commands = []
+        self.rotation._ipfs = self.fake_ipfs
+        self.partner_peers = [{"ID": "12D3old", "Addrs": ["/ip4/10.0.0.1/tcp/4001"]}]
+        self.partner_calls = []
+        self.rotation._partner_call = self.fake_partner_call
+
+    def tearDown(self):
+        self.tmpdir.cleanup()
+
+    def fake_ipfs(self, *args):
+        self.commands.append(args)
+        if args[0] == "id":
+            return self.peer_id + "\n"
+        if args[:2] == ("name", "resolve"):
+            return "/ipfs/bafyhome\n"
+        if args[:2] == ("key", "rotate"):
+            self.peer_id = "12D3new"
+        if args[:2] == ("key", "export"):
+            Path(args[args.index("-o") + 1]).write_bytes(b"old-private-key")
+        return ""
+
+    def fake_partner_call(self, partner, command, params):
+        self.partner_calls.append((command, params))
+        if command == "config" and len(params) == 1:
+            return {"Key": "Peering.Peers", "Value": json.loads(json.dumps(self.partner_peers))}
+        if command == "config":
+            self.partner_peers = json.loads(params[1][1])
+        return {}
+
+    def test_rotate_and_roll_back_identity(self):
+        result = self.rotation.rotate("self")
+        self.assertTrue(result["success"], result)
+        record = result["rotation"]
+        self.assertEqual((record["old_key_id"], record["new_key_id"]), ("12D3old", "12D3new"))
+        self.assertIn(("name", "publish", "--allow-offline", "--key=self", "/ipfs/bafyhome"), self.commands)
+        self.assertEqual(self.partner_peers[0]["ID"], "12D3new")
+        self.assertIn(("swarm/peering/add", [("arg", "/ip4/10.0.0.1/tcp/4001/p2p/12D3new")]), self.partner_calls)
+
+        result = self.rotation.rollback(record["id"])
+        self.assertTrue(result["success"], result)
+        identity = json.loads((self.repo / "config").read_text())["Identity"]
+        self.assertEqual(identity["PeerID"], "12D3old")
+        self.assertEqual(identity["PrivKey"], "b2xkLXByaXZhdGUta2V5")
+        self.assertIn(("key", "rm", record["old_key_name"]), self.commands)
+        self.assertEqual(self.partner_peers[0]["ID"], "12D3old")
+
+    def test_running_daemon_fails_cleanly(self):
+        def locked(*args):
+            if args[0] == "key":
+                raise KeyRotationError("ipfs key rotate: lock held (stop the IPFS daemon)")
+            return self.fake_ipfs(*args)
+        self.rotation._ipfs = locked
+        result = self.rotation.rotate("self")
+        self.assertFalse(result["success"])
+        self.assertFalse(result["rotated"])
+        self.assertEqual(self.rotation.list_rotations()["count"], 0)
+
+
+if __name__ == '__main__':
+    unittest.main()
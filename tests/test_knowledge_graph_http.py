@@ -0,0 +1,497 @@
+"""Tests for the knowledge graph HTTP API (ipfs_kit_py/knowledge_graph_http.py).
+
+The server runs on aiohttp's test server over an in-memory stand-in for
+IPLDGraphDB, so the tests need neither an IPFS daemon nor the graph's
+networkx and numpy dependencies.
+"""
+
+from types import SimpleNamespace
+
+import pytest
+
+pytest.importorskip("aiohttp")
+from aiohttp import test_utils
+
+from ipfs_kit_py.knowledge_graph_http import TOKEN_ENV, KnowledgeGraphHTTPServer
+
+pytestmark = pytest.mark.anyio
+
+
+class FakeGraph:
+    """In-memory IPLDGraphDB with the methods and indexes the server uses."""
+
+    def __init__(self):
+        self.entities = {}
+        self.relationships = {"relationship_cids": {}, "entity_rels": {}}
+        self.vectors = {"dimension": 0}
+        self.edges = {}
+        self.nodes = {}
+        self.ipfs = SimpleNamespace(dag_get=self._dag_get)
+
+    def _put(self, node):
+        cid = f"bafyfake{len(self.nodes)}"
+        self.nodes[cid] = node
+        return cid
+
+    def _dag_get(self, cid):
+        if cid not in self.nodes:
+            raise KeyError(cid)
+        return self.nodes[cid]
+
+    def add_entity(self, entity_id, entity_type, properties, vector=None):
+        if entity_id in self.entities:
+            return {"success": False, "error": f"Entity with ID '{entity_id}' already exists"}
+        if vector is not None and not self.vectors["dimension"]:
+            self.vectors["dimension"] = len(vector)
+        entity = {"id": entity_id, "type": entity_type, "properties": properties, "vector": vector}
+        cid = self._put(entity)
+        self.entities[entity_id] = {"cid": cid, "data": entity}
+        return {"success": True, "entity_id": entity_id, "cid": cid}
+
+    def update_entity(self, entity_id, properties=None, vector=None):
+        entity = dict(self.entities[entity_id]["data"])
+        entity["properties"] = {**entity["properties"], **(properties or {})}
+        if vector is not None:
+            entity["vector"] = vector
+        cid = self._put(entity)
+        self.entities[entity_id] = {"cid": cid, "data": entity}
+        return {"success": True, "entity_id": entity_id, "cid": cid}
+
+    def get_entity(self, entity_id):
+        entry = self.entities.get(entity_id)
+        return entry["data"] if entry else None
+
+    def add_relationship(self, from_entity, to_entity, relationship_type, properties=None):
+        for entity_id in (from_entity, to_entity):
+            if entity_id not in self.entities:
+                return {"success": False, "error": f"Entity '{entity_id}' not found"}
+        relationship_id = f"{from_entity}:{relationship_type}:{to_entity}"
+        if relationship_id in self.edges:
+            return {"success": False, "error": f"Relationship '{relationship_id}' already exists"}
+        relationship = {
+            "id": relationship_id,
+            "from": from_entity,
+            "to": to_entity,
+            "type": relationship_type,
+            "properties": properties or {},
+        }
+        cid = self._put(relationship)
+        self.edges[relationship_id] = relationship
+        self.relationships["relationship_cids"][relationship_id] = cid
+        return {"success": True, "relationship_id": relationship_id, "cid": cid}
+
+    def get_relationship(self, relationship_id):
+        return self.edges.get(relationship_id)
+
+    def query_related(self, entity_id, relationship_type=None, direction="outgoing"):
+        related = []
+        for rel in self.edges.values():
+            if relationship_type is not None and rel["type"] != relationship_type:
+                continue
+            if direction in ("outgoing", "both") and rel["from"] == entity_id:
+                related.append({"entity_id": rel["to"], "relationship_id": rel["id"],
+                                "relationship_type": rel["type"], "direction": "outgoing"})
+            if direction in ("incoming", "both") and rel["to"] == entity_id:
+                related.append({"entity_id": rel["from"], "relationship_id": rel["id"],
+                                "relationship_type": rel["type"], "direction": "incoming"})
+        return related
+
+    def path_between(self, source_id, target_id, max_depth=3, relationship_types=None):
+        paths = []
+
+        def walk(entity_id, path):
+            if entity_id == target_id:
+                paths.append(path + [(entity_id, None)])
+                return
+            if len(path) == max_depth:
+                return
+            for rel in self.query_related(entity_id):
+                if relationship_types and rel["relationship_type"] not in relationship_types:
+                    continue
+                if rel["entity_id"] in [e for e, _ in path] + [entity_id]:
+                    continue
+                walk(rel["entity_id"], path + [(entity_id, rel["relationship_id"])])
+
+        walk(source_id, [])
+        return paths
+
+    def vector_search(self, query_vector, top_k=10):
+        if self.vectors["dimension"] and len(query_vector) != self.vectors["dimension"]:
+            raise ValueError(f"Query vector has {len(query_vector)} dimensions, index has {self.vectors['dimension']}")
+        results = [
+            {"entity_id": entity_id, "score": float(sum(q * v for q, v in zip(query_vector, entry["data"]["vector"])))}
+            for entity_id, entry in self.entities.items() if entry["data"].get("vector")
+        ]
+        return sorted(results, key=lambda r: r["score"], reverse=True)[:top_k]
+
+    def graph_vector_search(self, query_vector, hop_count=2, top_k=10):
+        results = {r["entity_id"]: dict(r, distance=0) for r in self.vector_search(query_vector, top_k)}
+        frontier = list(results)
+        for hop in range(1, hop_count + 1):
+            reached = []
+            for entity_id in frontier:
+                for rel in self.query_related(entity_id, direction="both"):
+                    if rel["entity_id"] not in results:
+                        score = results[entity_id]["score"] / 2
+                        results[rel["entity_id"]] = {"entity_id": rel["entity_id"], "score": score, "distance": hop}
+                        reached.append(rel["entity_id"])
+            frontier = reached
+        return sorted(results.values(), key=lambda r: r["score"], reverse=True)
+
+    def get_statistics(self):
+        return {"entity_count": len(self.entities), "relationship_count": len(self.edges)}
+
+
+@pytest.fixture
+def graph():
+    return FakeGraph()
+
+
+@pytest.fixture
+async def client(graph, monkeypatch):
+    monkeypatch.delenv(TOKEN_ENV, raising=False)
+    server = KnowledgeGraphHTTPServer(graph)
+    async with test_utils.TestClient(test_utils.TestServer(server.app)) as client:
+        yield client
+
+
+async def _add(client, entity_id, entity_type="doc", **fields):
+    resp = await client.post("/api/v1/kg/entities", json={"id": entity_id, "type": entity_type, **fields})
+    assert resp.status == 200, await resp.text()
+    return await resp.json()
+
+
+async def _link(client, source, target, relationship_type="cites"):
+    resp = await client.post("/api/v1/kg/relationships",
+                             json={"from": source, "to": target, "type": relationship_type})
+    assert resp.status == 200, await resp.text()
+    return await resp.json()
+
+
+async def test_entity_round_trip(client):
+    added = await _add(client, "doc1", properties={"title": "Paper"})
+    resp = await client.get("/api/v1/kg/entities/doc1")
+    assert resp.status == 200
+    body = await resp.json()
+    assert body["entity"]["properties"] == {"title": "Paper"}
+    assert body["cid"] == added["cid"]
+
+    resp = await client.get(f"/api/v1/kg/nodes/{added['cid']}")
+    assert resp.status == 200
+    assert (await resp.json())["node"]["id"] == "doc1"
+
+
+async def test_entity_errors(client):
+    await _add(client, "doc1")
+    resp = await client.post("/api/v1/kg/entities", json={"id": "doc1", "type": "doc"})
+    assert resp.status == 409
+    resp = await client.get("/api/v1/kg/entities/missing")
+    assert resp.status == 404
+    resp = await client.get("/api/v1/kg/nodes/bafymissing")
+    assert resp.status == 404
+
+
+@pytest.mark.parametrize("body", [
+    "not json",
+    [1, 2],
+    {"type": "doc"},
+    {"id": ["doc1"], "type": "doc"},
+    {"id": "doc1", "type": "doc", "properties": ["title"]},
+])
+async def test_add_entity_rejects_malformed_bodies(client, body):
+    if isinstance(body, str):
+        resp = await client.post("/api/v1/kg/entities", data=body)
+    else:
+        resp = await client.post("/api/v1/kg/entities", json=body)
+    assert resp.status == 400
+    assert (await resp.json())["success"] is False
+
+
+async def test_relationship_round_trip(client):
+    await _add(client, "doc1")
+    await _add(client, "doc2")
+    added = await _link(client, "doc1", "doc2")
+    resp = await client.get(f"/api/v1/kg/relationships/{added['relationship_id']}")
+    assert resp.status == 200
+    body = await resp.json()
+    assert body["relationship"]["to"] == "doc2"
+    assert body["cid"] == added["cid"]
+
+
+async def test_relationship_errors(client):
+    await _add(client, "doc1")
+    resp = await client.post("/api/v1/kg/relationships", json={"from": "doc1", "to": "missing", "type": "cites"})
+    assert resp.status == 404
+    resp = await client.post("/api/v1/kg/relationships", json={"from": "doc1", "to": {"id": 1}, "type": "cites"})
+    assert resp.status == 400
+    resp = await client.post("/api/v1/kg/relationships", json="doc1")
+    assert resp.status == 400
+    resp = await client.get("/api/v1/kg/relationships/missing")
+    assert resp.status == 404
+
+
+@pytest.mark.parametrize("body", [
+    "not json",
+    ["neighbors"],
+    {"op": "neighbors", "entity_id": "doc1", "max_depth": "deep"},
+    {"op": "neighbors", "entity_id": "doc1", "max_depth": 99},
+    {"op": "neighbors", "entity_id": "doc1", "limit": "ten"},
+    {"op": "neighbors", "entity_id": ["doc1"]},
+    {"op": "neighbors", "entity_id": {"id": "doc1"}},
+    {"op": "neighbors", "entity_id": "doc1", "cursor": 5},
+    {"op": "neighbors", "entity_id": "doc1", "direction": ["both"]},
+    {"op": "traverse", "entity_id": "doc1", "relationship_types": "cites"},
+    {"op": "paths", "source": "doc1", "target": None},
+    {"op": "unknown"},
+])
+async def test_query_rejects_malformed_bodies(client, body):
+    await _add(client, "doc1")
+    if isinstance(body, str):
+        resp = await client.post("/api/v1/kg/query", data=body)
+    else:
+        resp = await client.post("/api/v1/kg/query", json=body)
+    assert resp.status == 400, await resp.text()
+    assert (await resp.json())["success"] is False
+
+
+async def test_query_unknown_entity(client):
+    resp = await client.post("/api/v1/kg/query", json={"op": "neighbors", "entity_id": "missing"})
+    assert resp.status == 404
+
+
+async def test_neighbors_are_paged(client):
+    for entity_id in ("doc1", "doc2", "doc3", "doc4"):
+        await _add(client, entity_id)
+    for target in ("doc4", "doc2", "doc3"):
+        await _link(client, "doc1", target)
+
+    out = await _query(client, op="neighbors", entity_id="doc1", limit=2)
+    assert [n["entity_id"] for n in out["neighbors"]] == ["doc2", "doc3"]
+    assert out["next_cursor"] == "doc1:cites:doc3"
+
+    # an edge sorting before the cursor does not shift the next page
+    await _add(client, "doc0")
+    await _link(client, "doc1", "doc0")
+    out = await _query(client, op="neighbors", entity_id="doc1", limit=2, cursor=out["next_cursor"])
+    assert [n["entity_id"] for n in out["neighbors"]] == ["doc4"]
+    assert out["next_cursor"] is None
+
+    out = await _query(client, op="neighbors", entity_id="doc1")
+    assert len(out["neighbors"]) == 4
+    assert out["next_cursor"] is None
+
+
+async def test_writes_need_token_off_loopback(graph, monkeypatch):
+    monkeypatch.delenv(TOKEN_ENV, raising=False)
+    server = KnowledgeGraphHTTPServer(graph, host="0.0.0.0")
+    async with test_utils.TestClient(test_utils.TestServer(server.app)) as client:
+        resp = await client.post("/api/v1/kg/entities", json={"id": "doc1", "type": "doc"})
+        assert resp.status == 403
+        assert "doc1" not in graph.entities
+        # reads stay open
+        resp = await client.get("/health")
+        assert resp.status == 200
+
+
+async def test_writes_check_token(graph, monkeypatch):
+    monkeypatch.setenv(TOKEN_ENV, "s3cret")
+    server = KnowledgeGraphHTTPServer(graph, host="0.0.0.0")
+    async with test_utils.TestClient(test_utils.TestServer(server.app)) as client:
+        body = {"id": "doc1", "type": "doc"}
+        resp = await client.post("/api/v1/kg/entities", json=body)
+        assert resp.status == 401
+        assert resp.headers["WWW-Authenticate"] == "Bearer"
+        resp = await client.post("/api/v1/kg/entities", json=body, headers={"Authorization": "Bearer wrong"})
+        assert resp.status == 401
+        resp = await client.post("/api/v1/kg/entities", json=body, headers={"Authorization": "Bearer s3cret"})
+        assert resp.status == 200
+        assert "doc1" in graph.entities
+
+
+async def test_default_host_is_loopback(graph):
+    assert KnowledgeGraphHTTPServer(graph).host == "127.0.0.1"
+
+
+async def _build(client):
+    """a -cites-> b -cites-> c, and a -tags-> c, a -tags-> d."""
+    for entity_id in ("a", "b", "c", "d"):
+        await _add(client, entity_id)
+    await _link(client, "a", "b")
+    await _link(client, "b", "c")
+    await _link(client, "a", "c", "tags")
+    await _link(client, "a", "d", "tags")
+
+
+async def _query(client, **body):
+    resp = await client.post("/api/v1/kg/query", json=body)
+    assert resp.status == 200, await resp.text()
+    return await resp.json()
+
+
+async def test_traverse(client):
+    await _build(client)
+    out = await _query(client, op="traverse", entity_id="a", max_depth=2)
+    depths = {n["entity_id"]: n["depth"] for n in out["nodes"]}
+    assert depths == {"a": 0, "b": 1, "c": 1, "d": 1}
+
+    out = await _query(client, op="traverse", entity_id="a", max_depth=2, relationship_types=["cites"])
+    nodes = {n["entity_id"]: n for n in out["nodes"]}
+    assert set(nodes) == {"a", "b", "c"}
+    assert nodes["c"]["depth"] == 2
+    assert nodes["c"]["parent"] == "b"
+    assert nodes["c"]["relationship_id"] == "b:cites:c"
+
+    out = await _query(client, op="traverse", entity_id="c", max_depth=2, direction="incoming",
+                       relationship_types=["cites"])
+    assert [n["entity_id"] for n in out["nodes"]] == ["c", "b", "a"]
+
+    out = await _query(client, op="traverse", entity_id="a", max_depth=2, limit=2)
+    assert len(out["nodes"]) == 2
+
+
+async def test_traverse_unknown_entity(client):
+    resp = await client.post("/api/v1/kg/query", json={"op": "traverse", "entity_id": "missing"})
+    assert resp.status == 404
+
+
+async def test_paths(client):
+    await _build(client)
+    out = await _query(client, op="paths", source="a", target="c", max_depth=2)
+    paths = sorted(out["paths"], key=len)
+    assert paths == [
+        [{"entity_id": "a", "relationship_id": "a:tags:c"}, {"entity_id": "c", "relationship_id": None}],
+        [{"entity_id": "a", "relationship_id": "a:cites:b"}, {"entity_id": "b", "relationship_id": "b:cites:c"},
+         {"entity_id": "c", "relationship_id": None}],
+    ]
+
+    out = await _query(client, op="paths", source="a", target="c", max_depth=2, relationship_types=["cites"])
+    assert len(out["paths"]) == 1
+
+    out = await _query(client, op="paths", source="a", target="c", max_depth=1, relationship_types=["cites"])
+    assert out["paths"] == []
+
+
+async def test_paths_errors(client):
+    await _build(client)
+    resp = await client.post("/api/v1/kg/query", json={"op": "paths", "source": "a", "target": "missing"})
+    assert resp.status == 404
+    resp = await client.post("/api/v1/kg/query",
+                             json={"op": "paths", "source": "a", "target": "c", "max_depth": 0})
+    assert resp.status == 400
+
+
+async def _search(client, **body):
+    resp = await client.post("/api/v1/kg/vector-search", json=body)
+    assert resp.status == 200, await resp.text()
+    return (await resp.json())["results"]
+
+
+async def test_vector_search(client):
+    await _add(client, "doc1", vector=[1.0, 0.0])
+    doc2 = await _add(client, "doc2", vector=[0.0, 1.0], properties={"cid": "bafycontent"})
+    await _add(client, "doc3", "topic")
+    await _link(client, "doc2", "doc3", "about")
+
+    results = await _search(client, vector=[0.0, 1.0], top_k=1)
+    assert [r["entity_id"] for r in results] == ["doc2"]
+    assert results[0]["cid"] == doc2["cid"]
+    assert results[0]["content_cid"] == "bafycontent"
+    assert results[0]["entity_type"] == "doc"
+
+    results = await _search(client, vector=[0.0, 1.0], top_k=1, hop_count=1)
+    hops = {r["entity_id"]: r["hops"] for r in results}
+    assert hops == {"doc2": 0, "doc3": 1}
+    assert "content_cid" not in next(r for r in results if r["entity_id"] == "doc3")
+
+
+@pytest.mark.parametrize("body", [
+    "not json",
+    [0.0, 1.0],
+    {},
+    {"vector": []},
+    {"vector": "0.0,1.0"},
+    {"vector": [0.0, 1.0], "top_k": "many"},
+    {"vector": [0.0, 1.0], "hop_count": -1},
+    {"vector": [0.0, 1.0], "hop_count": 7},
+    {"vector": ["x", "y"]},
+    {"vector": [0.0, 1.0, 0.0]},
+])
+async def test_vector_search_rejects_malformed_bodies(client, body):
+    await _add(client, "doc1", vector=[1.0, 0.0])
+    if isinstance(body, str):
+        resp = await client.post("/api/v1/kg/vector-search", data=body)
+    else:
+        resp = await client.post("/api/v1/kg/vector-search", json=body)
+    assert resp.status == 400, await resp.text()
+
+
+async def test_add_embeddings(client, graph):
+    resp = await client.post("/api/v1/kg/embeddings", json={"embeddings": [
+        {"cid": "bafyone", "vector": [1.0, 0.0], "model": "mini", "properties": {"name": "one.txt"}},
+        {"cid": "bafytwo", "vector": [0.0, 1.0], "model": "mini"},
+    ]})
+    assert resp.status == 200, await resp.text()
+    body = await resp.json()
+    assert body["success"] is True
+    assert [r["entity_id"] for r in body["results"]] == ["content:bafyone", "content:bafytwo"]
+    first_cid = body["results"][0]["node_cid"]
+    entity = graph.get_entity("content:bafyone")
+    assert entity["type"] == "content"
+    assert entity["properties"] == {"name": "one.txt", "cid": "bafyone", "embedding_model": "mini"}
+
+    # a second embedding of the same content updates its entity
+    resp = await client.post("/api/v1/kg/embeddings", json={"embeddings": [
+        {"cid": "bafyone", "vector": [0.6, 0.8], "model": "large"},
+    ]})
+    assert resp.status == 200
+    result = (await resp.json())["results"][0]
+    assert result["node_cid"] != first_cid
+    assert graph.get_entity("content:bafyone")["properties"]["embedding_model"] == "large"
+    assert graph.get_entity("content:bafyone")["properties"]["name"] == "one.txt"
+
+    results = await _search(client, vector=[0.0, 1.0], top_k=1)
+    assert results[0]["content_cid"] == "bafytwo"
+
+
+@pytest.mark.parametrize("body", [
+    "not json",
+    [{"cid": "bafyone", "vector": [1.0], "model": "mini"}],
+    {},
+    {"embeddings": []},
+    {"embeddings": ["bafyone"]},
+    {"embeddings": [{"cid": "bafyone", "vector": [1.0, 0.0]}]},
+    {"embeddings": [{"cid": ["bafyone"], "vector": [1.0, 0.0], "model": "mini"}]},
+    {"embeddings": [{"cid": "bafyone", "vector": [], "model": "mini"}]},
+    {"embeddings": [{"cid": "bafyone", "vector": [1.0, 0.0], "model": "mini", "properties": "x"}]},
+    {"embeddings": [
+        {"cid": "bafyone", "vector": [1.0, 0.0], "model": "mini"},
+        {"cid": "bafytwo", "vector": [1.0, 0.0, 0.0], "model": "mini"},
+    ]},
+])
+async def test_add_embeddings_rejects_malformed_bodies(client, graph, body):
+    if isinstance(body, str):
+        resp = await client.post("/api/v1/kg/embeddings", data=body)
+    else:
+        resp = await client.post("/api/v1/kg/embeddings", json=body)
+    assert resp.status == 400, await resp.text()
+    assert graph.entities == {}
+
+
+async def test_add_embeddings_checks_index_dimension(client, graph):
+    await _add(client, "doc1", vector=[1.0, 0.0])
+    resp = await client.post("/api/v1/kg/embeddings", json={"embeddings": [
+        {"cid": "bafyone", "vector": [1.0, 0.0, 0.0], "model": "mini"},
+    ]})
+    assert resp.status == 400
+    assert "content:bafyone" not in graph.entities
+
+
+async def test_add_embeddings_needs_token_off_loopback(graph, monkeypatch):
+    monkeypatch.delenv(TOKEN_ENV, raising=False)
+    server = KnowledgeGraphHTTPServer(graph, host="0.0.0.0")
+    async with test_utils.TestClient(test_utils.TestServer(server.app)) as client:
+        resp = await client.post("/api/v1/kg/embeddings", json={"embeddings": [
+            {"cid": "bafyone", "vector": [1.0, 0.0], "model": "mini"},
+        ]})
+        assert resp.status == 403
+        assert graph.entities == {}
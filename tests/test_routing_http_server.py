@@ -0,0 +1,461 @@
+"""Tests for the HTTP routing API (ipfs_kit_py/routing/http_server.py).
+
+The server runs on aiohttp's test server; it keeps all of its state in
+memory, so each test gets a fresh one.
+"""
+
+import pytest
+
+pytest.importorskip("aiohttp")
+from aiohttp import test_utils
+
+from ipfs_kit_py.routing import http_server
+from ipfs_kit_py.routing.http_server import CORRELATION_HEADER, HTTPRoutingServer
+
+pytestmark = pytest.mark.anyio
+
+
+@pytest.fixture
+def server():
+    return HTTPRoutingServer()
+
+
+@pytest.fixture
+async def client(server):
+    async with test_utils.TestClient(test_utils.TestServer(server.app)) as client:
+        yield client
+
+
+async def _post(client, path, body, status=200, **kwargs):
+    resp = await client.post(path, json=body, **kwargs)
+    assert resp.status == status, await resp.text()
+    return await resp.json()
+
+
+async def test_correlation_id_is_echoed(client):
+    resp = await client.get("/health", headers={CORRELATION_HEADER: "trace-1"})
+    assert resp.headers[CORRELATION_HEADER] == "trace-1"
+
+    resp = await client.post("/api/v1/select-backend", json={"content_size": 10},
+                             headers={CORRELATION_HEADER: "trace-2"})
+    assert resp.status == 200
+    assert resp.headers[CORRELATION_HEADER] == "trace-2"
+    body = await resp.json()
+    assert body["correlation_id"] == "trace-2"
+    assert body["request_id"] == "trace-2"
+
+
+async def test_correlation_id_is_generated(client):
+    first = await client.get("/health")
+    second = await client.get("/health")
+    assert first.headers[CORRELATION_HEADER]
+    assert first.headers[CORRELATION_HEADER] != second.headers[CORRELATION_HEADER]
+
+    resp = await client.post("/api/v1/select-backend", json={"content_size": 10})
+    body = await resp.json()
+    assert body["correlation_id"] == resp.headers[CORRELATION_HEADER]
+    assert body["request_id"] == body["correlation_id"]
+
+
+async def test_request_id_is_kept_apart_from_correlation_id(client):
+    body = await _post(client, "/api/v1/select-backend", {"content_size": 10, "request_id": "req-1"},
+                       headers={CORRELATION_HEADER: "trace-1"})
+    assert body["request_id"] == "req-1"
+    assert body["correlation_id"] == "trace-1"
+
+
+async def test_correlation_id_is_echoed_on_errors(client):
+    resp = await client.post("/api/v1/select-backend", json={"strategy": "fastest"},
+                             headers={CORRELATION_HEADER: "trace-1"})
+    assert resp.status == 400
+    assert resp.headers[CORRELATION_HEADER] == "trace-1"
+    resp = await client.post("/api/v1/record-outcome", json={}, headers={CORRELATION_HEADER: "trace-2"})
+    assert resp.status == 400
+    assert resp.headers[CORRELATION_HEADER] == "trace-2"
+
+
+async def test_correlation_id_is_echoed_on_not_found(client):
+    resp = await client.get("/api/v1/no-such-route", headers={CORRELATION_HEADER: "trace-1"})
+    assert resp.status == 404
+    assert resp.headers[CORRELATION_HEADER] == "trace-1"
+    resp = await client.get("/api/v1/no-such-route")
+    assert resp.status == 404
+    assert resp.headers[CORRELATION_HEADER]
+
+
+OUTCOME = {"backend": "ipfs", "success": True, "duration_ms": 12}
+
+
+async def test_record_outcome_ignores_retries(client, server):
+    body = await _post(client, "/api/v1/record-outcome", dict(OUTCOME, idempotency_key="k1"))
+    assert "duplicate" not in body
+    body = await _post(client, "/api/v1/record-outcome", dict(OUTCOME, idempotency_key="k1"))
+    assert body["duplicate"] is True
+    # outcomes without a key are always counted
+    await _post(client, "/api/v1/record-outcome", OUTCOME)
+    await _post(client, "/api/v1/record-outcome", OUTCOME)
+    assert len(server._backend_outcomes["ipfs"]) == 3
+
+
+async def test_record_outcomes_counts_duplicates(client, server):
+    await _post(client, "/api/v1/record-outcome", dict(OUTCOME, idempotency_key="k1"))
+    body = await _post(client, "/api/v1/record-outcomes", {"batch_id": "b1", "outcomes": [
+        dict(OUTCOME, idempotency_key="k1"),
+        dict(OUTCOME, idempotency_key="k2"),
+        dict(OUTCOME, idempotency_key="k2"),
+        dict(OUTCOME),
+    ]})
+    assert body["success"] is True
+    assert body["batch_id"] == "b1"
+    assert (body["recorded"], body["duplicates"]) == (2, 2)
+    assert len(server._backend_outcomes["ipfs"]) == 3
+
+
+async def test_record_outcomes_reports_bad_items(client, server):
+    body = await _post(client, "/api/v1/record-outcomes", {"outcomes": [
+        {"backend": "ipfs", "success": True},
+        "ipfs",
+        7,
+        OUTCOME,
+    ]})
+    assert body["success"] is False
+    assert body["recorded"] == 1
+    assert body["errors"] == [
+        "outcome 0: missing required field: duration_ms",
+        "outcome 1: must be an object",
+        "outcome 2: must be an object",
+    ]
+
+
+@pytest.mark.parametrize("path,body", [
+    ("/api/v1/record-outcome", {"backend": "ipfs", "success": True}),
+    ("/api/v1/record-outcome", {"success": True, "duration_ms": 1}),
+    ("/api/v1/record-outcome", [OUTCOME]),
+    ("/api/v1/record-outcome", 7),
+    ("/api/v1/record-outcome", "not json"),
+    ("/api/v1/record-outcomes", {}),
+    ("/api/v1/record-outcomes", {"outcomes": OUTCOME}),
+    ("/api/v1/record-outcomes", [OUTCOME]),
+    ("/api/v1/record-outcomes", "not json"),
+])
+async def test_record_outcome_rejects_malformed_bodies(client, path, body):
+    if body == "not json":
+        resp = await client.post(path, data=body)
+    else:
+        resp = await client.post(path, json=body)
+    assert resp.status == 400, await resp.text()
+    assert (await resp.json())["success"] is False
+
+
+def test_seen_keys_expire(server, monkeypatch):
+    assert server._seen("k1") is False
+    assert server._seen("k1") is True
+    assert server._seen(None) is False
+    assert server._seen("") is False
+    monkeypatch.setattr(http_server, "IDEMPOTENCY_TTL_SECONDS", 0)
+    assert server._seen("k1") is False
+
+
+def test_seen_keys_are_bounded(server, monkeypatch):
+    monkeypatch.setattr(http_server, "IDEMPOTENCY_MAX_KEYS", 2)
+    for key in ("k1", "k2", "k3"):
+        assert server._seen(key) is False
+    assert len(server._seen_keys) == 2
+    # the oldest key was evicted and counts as new again
+    assert server._seen("k3") is True
+    assert server._seen("k1") is False
+    assert list(server._seen_keys) == ["k3", "k1"]
+
+
+WEIGHTS = "/api/v1/admin/factor-weights"
+
+
+async def test_admin_api_is_disabled_without_token(client, monkeypatch):
+    monkeypatch.delenv(http_server.ADMIN_TOKEN_ENV, raising=False)
+    resp = await client.put(WEIGHTS, json={"weights": {"reliability": 1}},
+                            headers={"Authorization": "Bearer anything"})
+    assert resp.status == 403
+
+
+@pytest.mark.parametrize("authorization", [None, "Bearer wrong", "Basic secret", "secret", "Bearer "])
+async def test_admin_api_needs_token(client, monkeypatch, authorization):
+    monkeypatch.setenv(http_server.ADMIN_TOKEN_ENV, "secret")
+    headers = {"Authorization": authorization} if authorization is not None else {}
+    resp = await client.put(WEIGHTS, json={"weights": {"reliability": 1}}, headers=headers)
+    assert resp.status == 401
+    assert resp.headers["WWW-Authenticate"] == "Bearer"
+
+
+async def test_set_factor_weights(client, server, monkeypatch):
+    monkeypatch.setenv(http_server.ADMIN_TOKEN_ENV, "secret")
+    previous = dict(server._factor_weights)
+    resp = await client.put(WEIGHTS, json={"weights": {"reliability": 1.1, "cost_efficiency": 0}},
+                            headers={"Authorization": "Bearer secret"})
+    assert resp.status == 200, await resp.text()
+    body = await resp.json()
+    assert body["previous_weights"] == previous
+    weights = body["weights"]
+    assert weights == server._factor_weights
+    assert sum(weights.values()) == pytest.approx(1)
+    assert weights["cost_efficiency"] == 0
+    # factors not named keep their weight relative to each other
+    assert weights["network_quality"] / weights["content_match"] == pytest.approx(0.25 / 0.2)
+    assert weights["reliability"] > weights["network_quality"]
+
+
+@pytest.mark.parametrize("body", [
+    "not json",
+    [{"reliability": 1}],
+    {},
+    {"weights": {}},
+    {"weights": [0.5]},
+    {"weights": {"speed": 1}},
+    {"weights": {"reliability": -1}},
+    {"weights": {"reliability": "high"}},
+    {"weights": {"reliability": True}},
+    {"weights": {f: 0 for f in http_server.DEFAULT_FACTOR_WEIGHTS}},
+])
+async def test_set_factor_weights_rejects_malformed_bodies(client, server, monkeypatch, body):
+    monkeypatch.setenv(http_server.ADMIN_TOKEN_ENV, "secret")
+    previous = dict(server._factor_weights)
+    headers = {"Authorization": "Bearer secret"}
+    if body == "not json":
+        resp = await client.put(WEIGHTS, data=body, headers=headers)
+    else:
+        resp = await client.put(WEIGHTS, json=body, headers=headers)
+    assert resp.status == 400, await resp.text()
+    assert server._factor_weights == previous
+
+
+async def test_estimate_cost(client):
+    body = await _post(client, "/api/v1/estimate-cost", {
+        "content_size": 1024 ** 3, "retention_days": 60, "expected_retrievals": 10,
+    })
+    assert body["currency"] == "USD"
+    estimates = {e["backend_id"]: e for e in body["estimates"]}
+    assert set(estimates) == set(http_server.BACKEND_REGISTRY)
+    totals = [e["total_cost"] for e in body["estimates"]]
+    assert totals == sorted(totals)
+    assert estimates["s3"]["storage_cost"] == pytest.approx(0.023 * 2)
+    # filecoin bills its minimum retention however little is asked for
+    assert estimates["filecoin"]["storage_cost"] == pytest.approx(0.00002 * 180 / 30)
+    assert estimates["filecoin"]["egress_cost"] == pytest.approx(0.0001 * 10)
+
+
+async def test_estimate_cost_skips_backends_content_cannot_go_to(client):
+    body = await _post(client, "/api/v1/estimate-cost", {
+        "content_size": 64 * 1024 ** 3, "backends": ["filecoin", "s3"],
+    })
+    assert [e["backend_id"] for e in body["estimates"]] == ["s3"]
+
+
+async def test_estimate_cost_unknown_backend(client):
+    resp = await client.post("/api/v1/estimate-cost", json={"backends": ["ipfs", "tape"]})
+    assert resp.status == 404
+    body = await resp.json()
+    assert body["error_type"] == "unknown_backend"
+    assert "tape" in body["error"]
+
+
+@pytest.mark.parametrize("body", [
+    "not json",
+    [1],
+    {"content_size": "big"},
+    {"content_size": [1]},
+    {"content_size": -1},
+    {"retention_days": -30},
+    {"expected_retrievals": -2},
+    {"backends": "s3"},
+    {"backends": [["s3"]]},
+    {"backends": [{"id": "s3"}]},
+])
+async def test_estimate_cost_rejects_malformed_bodies(client, body):
+    if body == "not json":
+        resp = await client.post("/api/v1/estimate-cost", data=body)
+    else:
+        resp = await client.post("/api/v1/estimate-cost", json=body)
+    assert resp.status == 400, await resp.text()
+    assert (await resp.json())["success"] is False
+
+
+def _sample(backend_id, latency_ms, throughput=0, success=True, **fields):
+    return {"backend_id": backend_id, "success": success, "latency_ms": latency_ms,
+            "throughput_bytes_per_second": throughput, **fields}
+
+
+async def _select(client, **body):
+    return (await _post(client, "/api/v1/select-backend", body))["backend"]
+
+
+async def test_dry_run_is_not_counted(client, server):
+    body = await _post(client, "/api/v1/select-backend", {"content_size": 1024, "dry_run": True})
+    assert body["dry_run"] is True and body["backend"] == "ipfs"
+    assert server._request_count == 0
+    assert (await _history(client))["records"] == []
+
+
+async def test_client_location_prefers_nearby_backend(client, monkeypatch):
+    monkeypatch.setitem(http_server.BACKEND_REGISTRY, "s3-eu",
+                        dict(http_server.BACKEND_REGISTRY["s3"], region="eu-west-1"))
+    size = 200 * 1024 ** 2
+    assert await _select(client, content_size=size) == "s3"
+    assert await _select(client, content_size=size, client_location={"region": "eu-west-1"}) == "s3-eu"
+    assert await _select(client, content_size=size, client_location={"region": "us-east-1"}) == "s3"
+    # the cost strategy does not care about distance
+    assert await _select(client, content_size=size, strategy="cost",
+                         client_location={"region": "eu-west-1"}) == "s3"
+
+
+async def test_strategy_is_validated(client):
+    body = await _post(client, "/api/v1/select-backend", {"strategy": "ROUTING_STRATEGY_COST"})
+    assert body["strategy"] == "cost"
+    body = await _post(client, "/api/v1/select-backend", {"strategy": "fastest"}, status=400)
+    assert body["error_type"] == "invalid_strategy"
+
+
+async def test_providers_steer_retrievals(client):
+    assert await _select(client, content_size=1024, providers={"count": 0}) == "s3"
+    assert await _select(client, content_size=1024, providers={"count": 1}) == "ipfs"
+    assert await _select(client, content_size=1024, strategy="cost", providers={"count": 0}) == "ipfs"
+    size = 200 * 1024 ** 2
+    assert await _select(client, content_size=size, providers={"count": 5}) == "ipfs"
+
+
+async def test_retention_days_avoid_minimum_retention(client):
+    size = 200 * 1024 ** 2
+    assert await _select(client, content_type="video/mp4", content_size=size) == "filecoin"
+    assert await _select(client, content_type="video/mp4", content_size=size, retention_days=365) == "filecoin"
+    assert await _select(client, content_type="video/mp4", content_size=size, retention_days=30) == "ipfs"
+
+
+async def test_report_metrics(client):
+    body = await _post(client, "/api/v1/metrics/report", {"reporter_id": "r1", "samples": [
+        _sample("ipfs", 40),
+        _sample("s3", 20, node={"peers": 3}),
+        _sample("tape", 1),
+        _sample("s3", "fast"),
+        _sample("s3", 20, node="busy"),
+        "s3",
+    ]})
+    assert (body["accepted"], body["rejected"]) == (2, 4)
+    stats = await (await client.get("/api/v1/backends/s3/stats")).json()
+    assert stats["node"]["peers"] == 3
+
+
+async def test_performance_strategy_prefers_measured_latency(client):
+    assert await _select(client, content_size=1024, strategy="performance") == "ipfs"
+    await _post(client, "/api/v1/metrics/report", {"samples": [
+        _sample("ipfs", 400), _sample("s3", 30), _sample("s3", 30, success=False),
+        _sample("filecoin", 5, success=False),
+    ]})
+    assert await _select(client, content_size=1024, strategy="performance") == "s3"
+    assert await _select(client, content_size=1024, strategy="latency") == "s3"
+    # failed probes do not count, and backends outside the caller's list
+    # are not considered
+    assert await _select(client, content_size=1024, strategy="performance",
+                         available_backends=["ipfs", "filecoin"]) == "ipfs"
+    # other strategies ignore the measurements
+    assert await _select(client, content_size=1024, strategy="cost") == "ipfs"
+
+
+async def test_performance_strategy_prefers_measured_throughput_for_large_content(client):
+    await _post(client, "/api/v1/metrics/report", {"samples": [
+        _sample("ipfs", 10, throughput=1e6), _sample("s3", 50, throughput=5e7),
+    ]})
+    size = http_server.THROUGHPUT_ROUTING_SIZE
+    assert await _select(client, content_size=size, strategy="performance") == "s3"
+    assert await _select(client, content_size=size, strategy="latency") == "ipfs"
+
+
+@pytest.mark.parametrize("body", [
+    "not json",
+    [_sample("s3", 20)],
+    {"samples": _sample("s3", 20)},
+    {"samples": "s3"},
+])
+async def test_report_metrics_rejects_malformed_bodies(client, body):
+    if body == "not json":
+        resp = await client.post("/api/v1/metrics/report", data=body)
+    else:
+        resp = await client.post("/api/v1/metrics/report", json=body)
+    assert resp.status == 400, await resp.text()
+    assert (await resp.json())["success"] is False
+
+
+async def _history(client, status=200, **query):
+    resp = await client.get("/api/v1/history", params=query)
+    assert resp.status == status, await resp.text()
+    return await resp.json()
+
+
+async def _fill_history(client):
+    await _select(client, content_size=1024, request_id="d1")
+    await _select(client, content_size=1024, dry_run=True)
+    await _post(client, "/api/v1/record-outcome", dict(OUTCOME, content_hash="bafy1"))
+    await _select(client, content_size=200 * 1024 ** 2, request_id="d2")
+    await _post(client, "/api/v1/record-outcome", {"backend": "s3", "success": False, "duration_ms": 90,
+                                                   "error_message": "timeout"})
+    await _select(client, content_size=1024, request_id="d3")
+
+
+async def test_history_pages(client):
+    await _fill_history(client)
+    records, token = [], None
+    while token != "":
+        query = {"page_size": 2}
+        if token:
+            query["page_token"] = token
+        body = await _history(client, **query)
+        assert len(body["records"]) <= 2
+        records += body["records"]
+        token = body["next_page_token"]
+    # dry runs are not kept
+    assert [r["kind"] for r in records] == ["decision", "outcome", "decision", "outcome", "decision"]
+    assert [r["seq"] for r in records] == sorted(r["seq"] for r in records)
+    assert [r.get("request_id") for r in records if r["kind"] == "decision"] == ["d1", "d2", "d3"]
+    assert records[1]["content_hash"] == "bafy1"
+    assert records[3]["error"] == "timeout"
+    assert all(r["timestamp"] for r in records)
+
+    body = await _history(client)
+    assert body["records"] == records
+    assert body["next_page_token"] == ""
+
+
+async def test_history_filters(client):
+    await _fill_history(client)
+    body = await _history(client, kind="outcome")
+    assert [r["backend_id"] for r in body["records"]] == ["ipfs", "s3"]
+    body = await _history(client, kind="HISTORY_RECORD_KIND_DECISION", backend_id="ipfs")
+    assert [r["request_id"] for r in body["records"]] == ["d1", "d3"]
+    body = await _history(client, backend_id="s3")
+    assert [r["kind"] for r in body["records"]] == ["decision", "outcome"]
+
+    assert len((await _history(client, since="2000-01-01T00:00:00"))["records"]) == 5
+    assert len((await _history(client, until="2999-01-01T00:00:00Z"))["records"]) == 5
+    assert (await _history(client, since="2999-01-01T00:00:00+02:00"))["records"] == []
+    assert (await _history(client, until="2000-01-01T00:00:00"))["records"] == []
+
+
+async def test_history_skips_evicted_records(monkeypatch):
+    monkeypatch.setattr(http_server, "ROUTING_HISTORY", 3)
+    server = HTTPRoutingServer()
+    async with test_utils.TestClient(test_utils.TestServer(server.app)) as client:
+        await _select(client, content_size=1024, request_id="d1")
+        body = await _history(client, page_size=1)
+        token = str(body["records"][-1]["seq"])
+        await _fill_history(client)
+        body = await _history(client, page_token=token)
+        assert [r["seq"] for r in body["records"]] == [4, 5, 6]
+
+
+@pytest.mark.parametrize("query", [
+    {"kind": "probe"},
+    {"page_token": "abc"},
+    {"page_size": "many"},
+    {"since": "yesterday"},
+    {"until": "2026-13-01"},
+])
+async def test_history_rejects_malformed_queries(client, query):
+    body = await _history(client, status=400, **query)
+    assert body["success"] is False